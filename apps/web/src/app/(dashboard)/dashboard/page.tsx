@@ -72,7 +72,7 @@ export default function DashboardPage() {
   const activeConnections = connections?.filter((c) => c.status === 'active') ?? []
   const todayExecutions = executions?.length ?? 0
   const successRate = executions && executions.length > 0
-    ? ((executions.filter((e) => e.status === 'completed').length / executions.length) * 100).toFixed(1)
+    ? ((executions.filter((e) => e.status === 'succeeded').length / executions.length) * 100).toFixed(1)
     : '0'
 
   const statsLoading = helpersLoading || connectionsLoading || executionsLoading
@@ -156,13 +156,13 @@ export default function DashboardPage() {
                   className="flex items-center gap-4 px-5 py-3 transition-colors hover:bg-accent/50"
                 >
                   <div className="flex-shrink-0">
-                    {exec.status === 'completed' && (
+                    {exec.status === 'succeeded' && (
                       <CheckCircle className="h-4 w-4 text-success" />
                     )}
                     {exec.status === 'failed' && (
                       <XCircle className="h-4 w-4 text-destructive" />
                     )}
-                    {(exec.status === 'running' || exec.status === 'pending') && (
+                    {(exec.status === 'running' || exec.status === 'queued') && (
                       <Clock className="h-4 w-4 animate-spin text-info" />
                     )}
                   </div>
@@ -28,10 +28,10 @@ export default function ExecutionDetailPage() {
 
   const getStatusBadge = (status: string) => {
     const styles: Record<string, string> = {
-      completed: 'bg-success/10 text-success',
+      succeeded: 'bg-success/10 text-success',
       failed: 'bg-destructive/10 text-destructive',
       running: 'bg-info/10 text-info',
-      pending: 'bg-warning/10 text-warning',
+      queued: 'bg-warning/10 text-warning',
     }
     return (
       <span className={cn('rounded-full px-2.5 py-0.5 text-xs font-medium capitalize', styles[status] || '')}>
@@ -40,13 +40,13 @@ export default function ExecutionsPage() {
 
   const getStatusIcon = (status: string) => {
     switch (status) {
-      case 'completed':
+      case 'succeeded':
         return <CheckCircle className="h-4 w-4 text-success" />
       case 'failed':
         return <XCircle className="h-4 w-4 text-destructive" />
       case 'running':
         return <RefreshCw className="h-4 w-4 animate-spin text-info animate-status-pulse" />
-      case 'pending':
+      case 'queued':
         return <Clock className="h-4 w-4 text-warning" />
       default:
         return <Clock className="h-4 w-4 text-muted-foreground" />
@@ -68,7 +68,7 @@ export default function ExecutionsPage() {
     if (!executions || executions.length === 0) {
       return { total: 0, successRate: '0', avgDuration: 0, failed: 0 }
     }
-    const completed = executions.filter((e) => e.status === 'completed').length
+    const completed = executions.filter((e) => e.status === 'succeeded').length
     const failed = executions.filter((e) => e.status === 'failed').length
     const withDuration = executions.filter((e) => e.durationMs && e.durationMs > 0)
     const avgDuration = withDuration.length > 0
@@ -151,10 +151,10 @@ export default function ExecutionsPage() {
           className="h-10 rounded-md border border-input bg-background px-3 text-sm ring-offset-background focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-ring"
         >
           <option value="all">All Status</option>
-          <option value="completed">Completed</option>
+          <option value="succeeded">Succeeded</option>
           <option value="failed">Failed</option>
           <option value="running">Running</option>
-          <option value="pending">Pending</option>
+          <option value="queued">Queued</option>
         </select>
       </div>
 
@@ -200,10 +200,10 @@ export default function ExecutionsPage() {
                       <span
                         className={cn(
                           'text-xs font-medium capitalize',
-                          execution.status === 'completed' && 'text-success',
+                          execution.status === 'succeeded' && 'text-success',
                           execution.status === 'failed' && 'text-destructive',
                           execution.status === 'running' && 'text-info',
-                          execution.status === 'pending' && 'text-warning'
+                          execution.status === 'queued' && 'text-warning'
                         )}
                       >
                         {execution.status}
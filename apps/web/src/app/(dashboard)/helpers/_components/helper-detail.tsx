@@ -522,7 +522,7 @@ export function HelperDetail({ helperId, onBack }: HelperDetailProps) {
                     className="flex items-center gap-4 px-5 py-3 hover:bg-accent/50 transition-colors"
                   >
                     <div className="flex-shrink-0">
-                      {exec.status === 'completed' ? (
+                      {exec.status === 'succeeded' ? (
                         <CheckCircle className="h-4 w-4 text-success" />
                       ) : exec.status === 'failed' ? (
                         <XCircle className="h-4 w-4 text-destructive" />
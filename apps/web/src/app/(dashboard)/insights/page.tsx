@@ -184,7 +184,7 @@ export default function InsightsPage() {
     const totalHelpers = helpers?.length ?? 0
     const activeHelpers = helpers?.filter((h) => h.status === 'active' && h.enabled)?.length ?? 0
     const totalExec = executions?.length ?? 0
-    const completed = executions?.filter((e) => e.status === 'completed')?.length ?? 0
+    const completed = executions?.filter((e) => e.status === 'succeeded')?.length ?? 0
     const failed = executions?.filter((e) => e.status === 'failed')?.length ?? 0
     const successRate = totalExec > 0 ? ((completed / totalExec) * 100).toFixed(1) : '0'
     const withDuration = executions?.filter((e) => e.durationMs > 0) ?? []
@@ -211,7 +211,7 @@ export default function InsightsPage() {
       const bucket = buckets.get(key)
       if (bucket) {
         bucket.total++
-        if (exec.status === 'completed') bucket.completed++
+        if (exec.status === 'succeeded') bucket.completed++
         if (exec.status === 'failed') bucket.failed++
       }
     }
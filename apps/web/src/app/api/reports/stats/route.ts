@@ -69,10 +69,10 @@ async function fetchAllExecutions(token: string, limit = 200): Promise<BackendEx
 
 function computeStats(executions: BackendExecution[]) {
   const total = executions.length
-  const completed = executions.filter((e) => e.status === 'completed').length
+  const completed = executions.filter((e) => e.status === 'succeeded').length
   const failed = executions.filter((e) => e.status === 'failed').length
   const running = executions.filter((e) => e.status === 'running').length
-  const pending = executions.filter((e) => e.status === 'pending').length
+  const pending = executions.filter((e) => e.status === 'queued').length
 
   const withDuration = executions.filter((e) => e.duration_ms && e.duration_ms > 0)
   const avgDurationMs =
@@ -103,7 +103,7 @@ function computeStats(executions: BackendExecution[]) {
     const bucket = dailyMap.get(dateKey)
     if (bucket) {
       bucket.total++
-      if (exec.status === 'completed') bucket.completed++
+      if (exec.status === 'succeeded') bucket.completed++
       if (exec.status === 'failed') bucket.failed++
     }
   }
@@ -119,7 +119,7 @@ function computeStats(executions: BackendExecution[]) {
       helperMap.set(exec.helper_id, entry)
     }
     entry.total++
-    if (exec.status === 'completed') entry.completed++
+    if (exec.status === 'succeeded') entry.completed++
     if (exec.status === 'failed') entry.failed++
     if (exec.duration_ms && exec.duration_ms > 0) {
       entry.totalDuration += exec.duration_ms
@@ -221,7 +221,7 @@ function generateHelperEfficiency(
       helperMap.set(exec.helperId, entry)
     }
     entry.total++
-    if (exec.status === 'completed') entry.completed++
+    if (exec.status === 'succeeded') entry.completed++
     if (exec.durationMs > 0) {
       entry.totalDuration += exec.durationMs
       entry.withDuration++
@@ -269,7 +269,7 @@ export function useInsights(
       const totalHelpers = h.length
       const totalExec = e.length
       const successRate = totalExec > 0
-        ? e.filter((x) => x.status === 'completed').length / totalExec
+        ? e.filter((x) => x.status === 'succeeded').length / totalExec
         : 0
       const activeConns = c.filter((x) => x.status === 'active').length
 
@@ -281,7 +281,7 @@ export function useInsights(
       ))
 
       // Automation ROI
-      const completedExec = e.filter((x) => x.status === 'completed').length
+      const completedExec = e.filter((x) => x.status === 'succeeded').length
       const avgDuration = e.filter((x) => x.durationMs > 0)
       const avgMs = avgDuration.length > 0
         ? avgDuration.reduce((s, x) => s + x.durationMs, 0) / avgDuration.length
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// childEnvVar marks the re-executed server process
+const childEnvVar = "MFH_DEVSERVER_CHILD"
+
+// Config is the devserver's local settings file
+type Config struct {
+	// Addr is the address the API listens on
+	Addr string `json:"addr"`
+	// DynamoDBEndpoint is the DynamoDB Local (or other DynamoDB-compatible) URL
+	DynamoDBEndpoint string `json:"dynamodb_endpoint"`
+	// Env holds the environment the deployed Lambdas get from serverless.yml:
+	// table names, STAGE, COGNITO_REGION, INTERNAL_SECRETS and so on
+	Env map[string]string `json:"env"`
+	// Parameters are served in place of SSM parameters, keyed by name. String
+	// values are used as-is; objects are stored as their JSON encoding.
+	Parameters map[string]json.RawMessage `json:"parameters"`
+	// DevUserSub is the Cognito sub used for requests without a bearer token.
+	// When empty, Cognito-protected routes require one.
+	DevUserSub string `json:"dev_user_sub"`
+	// WorkerTimeoutSeconds is the time each worker invocation gets, like the
+	// helper worker Lambdas' timeout
+	WorkerTimeoutSeconds int `json:"worker_timeout_seconds"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	if cfg.DynamoDBEndpoint == "" {
+		cfg.DynamoDBEndpoint = "http://localhost:8000"
+	}
+	if cfg.WorkerTimeoutSeconds <= 0 {
+		cfg.WorkerTimeoutSeconds = 300
+	}
+	return cfg, nil
+}
+
+// parameter returns the value of a local SSM parameter
+func (c *Config) parameter(name string) (string, bool) {
+	raw, ok := c.Parameters[name]
+	if !ok {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+	return string(raw), true
+}
+
+// localURL is the base URL the server's own clients reach it on
+func (c *Config) localURL() string {
+	host, port, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return "http://" + c.Addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// environ builds the server process's environment. Variables already set in
+// the shell win over the file, so single settings can be overridden per run.
+// The AWS endpoint variables always point the SDK at the server's local
+// stand-ins for DynamoDB, SSM, EventBridge, Lambda and SQS.
+func (c *Config) environ() []string {
+	env := os.Environ()
+	set := make(map[string]bool, len(env))
+	for _, kv := range env {
+		set[strings.SplitN(kv, "=", 2)[0]] = true
+	}
+
+	keys := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !set[k] {
+			env = append(env, k+"="+c.Env[k])
+		}
+	}
+
+	base := c.localURL() + awsPathPrefix
+	return append(env,
+		"AWS_ENDPOINT_URL_DYNAMODB="+base+"dynamodb",
+		"AWS_ENDPOINT_URL_SSM="+base+"ssm",
+		"AWS_ENDPOINT_URL_EVENTBRIDGE="+base+"eventbridge",
+		"AWS_ENDPOINT_URL_LAMBDA="+base+"lambda",
+		"AWS_ENDPOINT_URL_SQS="+base+"sqs",
+		childEnvVar+"=1",
+	)
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+
+	"github.com/myfusionhelper/api/internal/execution"
+)
+
+// awsPathPrefix is where the local AWS stand-ins are served
+const awsPathPrefix = "/_aws/"
+
+// localAWS serves the AWS APIs the handlers call, through the SDK's
+// AWS_ENDPOINT_URL_<SERVICE> overrides:
+//   - DynamoDB is proxied to DynamoDB Local. Items put into the executions
+//     table, and executions updated back to queued, are published on the
+//     stream, as DynamoDB Streams would.
+//   - SSM GetParameter reads the config file's parameters.
+//   - EventBridge schedule rules go to the local scheduler.
+//   - Lambda permission calls made alongside EventBridge rules succeed.
+//   - SQS sends are accepted and logged. Helper executions reach the worker
+//     through the stream, so nothing else consumes queues locally.
+type localAWS struct {
+	config    *Config
+	stream    chan<- events.DynamoDBEventRecord
+	scheduler *scheduler
+	client    *http.Client
+}
+
+func (a *localAWS) register(mux *http.ServeMux) {
+	for service, handler := range map[string]http.HandlerFunc{
+		"dynamodb":    a.dynamodb,
+		"ssm":         a.ssm,
+		"eventbridge": a.eventbridge,
+		"lambda":      a.lambda,
+		"sqs":         a.sqs,
+	} {
+		mux.HandleFunc(awsPathPrefix+service, handler)
+		mux.HandleFunc(awsPathPrefix+service+"/", handler)
+	}
+}
+
+// dynamodb forwards a request to DynamoDB Local unchanged and taps
+// successful PutItem and UpdateItem calls on the executions table
+func (a *localAWS) dynamodb(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAWSError(w, http.StatusBadRequest, "SerializationException", err.Error())
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, a.config.DynamoDBEndpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		writeAWSError(w, http.StatusInternalServerError, "InternalFailure", err.Error())
+		return
+	}
+	req.Header = r.Header.Clone()
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		log.Printf("DynamoDB Local unreachable at %s: %v", a.config.DynamoDBEndpoint, err)
+		writeAWSError(w, http.StatusServiceUnavailable, "ServiceUnavailable", err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	switch r.Header.Get("X-Amz-Target") {
+	case "DynamoDB_20120810.PutItem":
+		a.publishPutItem(body)
+	case "DynamoDB_20120810.UpdateItem":
+		a.publishUpdateItem(r, body)
+	}
+}
+
+// publishPutItem publishes an executions table PutItem as an INSERT record.
+// Executions are always new items, so the MODIFY a DynamoDB Stream reports
+// for an overwritten item does not come up.
+func (a *localAWS) publishPutItem(body []byte) {
+	var put struct {
+		TableName string                                   `json:"TableName"`
+		Item      map[string]events.DynamoDBAttributeValue `json:"Item"`
+	}
+	if err := json.Unmarshal(body, &put); err != nil {
+		log.Printf("Failed to decode PutItem for stream: %v", err)
+		return
+	}
+	if put.TableName != os.Getenv("EXECUTIONS_TABLE") {
+		return
+	}
+
+	a.stream <- events.DynamoDBEventRecord{
+		EventName:   "INSERT",
+		EventSource: "aws:dynamodb",
+		Change: events.DynamoDBStreamRecord{
+			NewImage:       put.Item,
+			StreamViewType: "NEW_IMAGE",
+		},
+	}
+}
+
+// publishUpdateItem publishes an executions table UpdateItem that moved an
+// execution back to queued as a MODIFY record, so executions drained from a
+// paused connection are routed again. Only those matter to the stream
+// router, so other updates are not published. The update request carries
+// no image, so the item is read back from DynamoDB Local.
+func (a *localAWS) publishUpdateItem(r *http.Request, body []byte) {
+	var update struct {
+		TableName                 string                                   `json:"TableName"`
+		Key                       map[string]events.DynamoDBAttributeValue `json:"Key"`
+		ExpressionAttributeValues map[string]events.DynamoDBAttributeValue `json:"ExpressionAttributeValues"`
+	}
+	if err := json.Unmarshal(body, &update); err != nil {
+		log.Printf("Failed to decode UpdateItem for stream: %v", err)
+		return
+	}
+	if update.TableName != os.Getenv("EXECUTIONS_TABLE") {
+		return
+	}
+	if to, ok := update.ExpressionAttributeValues[":to"]; !ok || to.DataType() != events.DataTypeString || to.String() != execution.StatusQueued {
+		return
+	}
+
+	getBody, err := json.Marshal(map[string]interface{}{
+		"TableName":      update.TableName,
+		"Key":            update.Key,
+		"ConsistentRead": true,
+	})
+	if err != nil {
+		log.Printf("Failed to encode GetItem for stream: %v", err)
+		return
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, a.config.DynamoDBEndpoint+"/", bytes.NewReader(getBody))
+	if err != nil {
+		log.Printf("Failed to read updated execution for stream: %v", err)
+		return
+	}
+	req.Header = r.Header.Clone()
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810.GetItem")
+	req.Header.Del("Content-Length")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		log.Printf("Failed to read updated execution for stream: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var got struct {
+		Item map[string]events.DynamoDBAttributeValue `json:"Item"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Failed to read updated execution for stream: DynamoDB Local returned %d", resp.StatusCode)
+		return
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil || got.Item == nil {
+		log.Printf("Failed to read updated execution for stream: %v", err)
+		return
+	}
+
+	a.stream <- events.DynamoDBEventRecord{
+		EventName:   "MODIFY",
+		EventSource: "aws:dynamodb",
+		Change: events.DynamoDBStreamRecord{
+			Keys:           update.Key,
+			NewImage:       got.Item,
+			StreamViewType: "NEW_IMAGE",
+		},
+	}
+}
+
+func (a *localAWS) ssm(w http.ResponseWriter, r *http.Request) {
+	operation, body, ok := readJSONRequest(w, r, "AmazonSSM.")
+	if !ok {
+		return
+	}
+	if operation != "GetParameter" {
+		writeAWSError(w, http.StatusBadRequest, "UnknownOperationException", "operation "+operation+" is not served locally")
+		return
+	}
+
+	var req struct {
+		Name string `json:"Name"`
+	}
+	_ = json.Unmarshal(body, &req)
+
+	value, found := a.config.parameter(req.Name)
+	if !found {
+		writeAWSError(w, http.StatusBadRequest, "ParameterNotFound", "parameter "+req.Name+" is not in the devserver config")
+		return
+	}
+	writeAWSJSON(w, map[string]interface{}{
+		"Parameter": map[string]interface{}{
+			"Name":    req.Name,
+			"Type":    "SecureString",
+			"Value":   value,
+			"Version": 1,
+		},
+	})
+}
+
+func (a *localAWS) eventbridge(w http.ResponseWriter, r *http.Request) {
+	operation, body, ok := readJSONRequest(w, r, "AWSEvents.")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name               string `json:"Name"`
+		Rule               string `json:"Rule"`
+		ScheduleExpression string `json:"ScheduleExpression"`
+		State              string `json:"State"`
+		Targets            []struct {
+			Input string `json:"Input"`
+		} `json:"Targets"`
+	}
+	_ = json.Unmarshal(body, &req)
+
+	var err error
+	switch operation {
+	case "PutRule":
+		err = a.scheduler.putRule(req.Name, req.ScheduleExpression, req.State != "DISABLED")
+		if err == nil {
+			writeAWSJSON(w, map[string]string{"RuleArn": "arn:aws:events:local:000000000000:rule/" + req.Name})
+			return
+		}
+	case "PutTargets":
+		// The helpers API sets a single target, the scheduler Lambda
+		for _, target := range req.Targets {
+			if err = a.scheduler.putTarget(req.Rule, target.Input); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			writeAWSJSON(w, map[string]interface{}{"FailedEntryCount": 0, "FailedEntries": []interface{}{}})
+			return
+		}
+	case "EnableRule", "DisableRule":
+		err = a.scheduler.setEnabled(req.Name, operation == "EnableRule")
+	case "RemoveTargets":
+		a.scheduler.removeTargets(req.Rule)
+	case "DeleteRule":
+		a.scheduler.deleteRule(req.Name)
+	default:
+		writeAWSError(w, http.StatusBadRequest, "UnknownOperationException", "operation "+operation+" is not served locally")
+		return
+	}
+
+	if err != nil {
+		writeAWSError(w, http.StatusBadRequest, "ValidationException", err.Error())
+		return
+	}
+	writeAWSJSON(w, map[string]interface{}{})
+}
+
+// lambda accepts the AddPermission and RemovePermission calls made when
+// schedule rules change; the local scheduler needs no permissions
+func (a *localAWS) lambda(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/policy"):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"Statement": "{}"})
+	case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/policy/"):
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeAWSError(w, http.StatusNotFound, "ResourceNotFoundException", "Lambda "+r.Method+" "+r.URL.Path+" is not served locally")
+	}
+}
+
+// sqs accepts SendMessage and SendMessageBatch, logging what was sent
+func (a *localAWS) sqs(w http.ResponseWriter, r *http.Request) {
+	operation, body, ok := readJSONRequest(w, r, "AmazonSQS.")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		QueueUrl    string `json:"QueueUrl"`
+		MessageBody string `json:"MessageBody"`
+		Entries     []struct {
+			Id          string `json:"Id"`
+			MessageBody string `json:"MessageBody"`
+		} `json:"Entries"`
+	}
+	_ = json.Unmarshal(body, &req)
+
+	switch operation {
+	case "SendMessage":
+		log.Printf("SQS message to %s (not consumed locally): %s", req.QueueUrl, req.MessageBody)
+		writeAWSJSON(w, map[string]string{
+			"MessageId":        uuid.New().String(),
+			"MD5OfMessageBody": md5Hex(req.MessageBody),
+		})
+	case "SendMessageBatch":
+		successful := make([]map[string]string, 0, len(req.Entries))
+		for _, entry := range req.Entries {
+			log.Printf("SQS message to %s (not consumed locally): %s", req.QueueUrl, entry.MessageBody)
+			successful = append(successful, map[string]string{
+				"Id":               entry.Id,
+				"MessageId":        uuid.New().String(),
+				"MD5OfMessageBody": md5Hex(entry.MessageBody),
+			})
+		}
+		writeAWSJSON(w, map[string]interface{}{"Successful": successful, "Failed": []interface{}{}})
+	default:
+		writeAWSError(w, http.StatusBadRequest, "UnsupportedOperation", "operation "+operation+" is not served locally")
+	}
+}
+
+// md5Hex is the body checksum the SDK verifies on SQS sends
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// readJSONRequest reads an AWS JSON 1.1 request, returning the operation
+// named by its X-Amz-Target header
+func readJSONRequest(w http.ResponseWriter, r *http.Request, targetPrefix string) (string, []byte, bool) {
+	target := r.Header.Get("X-Amz-Target")
+	if !strings.HasPrefix(target, targetPrefix) {
+		writeAWSError(w, http.StatusBadRequest, "UnknownOperationException", fmt.Sprintf("unexpected target %q", target))
+		return "", nil, false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAWSError(w, http.StatusBadRequest, "SerializationException", err.Error())
+		return "", nil, false
+	}
+	return strings.TrimPrefix(target, targetPrefix), body, true
+}
+
+func writeAWSJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAWSError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Header().Set("X-Amzn-ErrorType", errorType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"__type": errorType, "message": message})
+}
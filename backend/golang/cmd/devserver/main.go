@@ -0,0 +1,136 @@
+// Command devserver runs the API, the helper worker and the schedulers in one
+// process for offline development.
+//
+// API routes are served by the same handler packages the Lambdas use, behind
+// a router that builds API Gateway events and authorizer contexts. The
+// DynamoDB Stream and SQS queues are replaced by in-process channels and
+// EventBridge by an in-process scheduler. Data lives in DynamoDB Local;
+// configuration and SSM parameters come from a local JSON file (see
+// devserver.example.json).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	authorizerHandler "github.com/myfusionhelper/api/cmd/handlers/api-key-authorizer/handler"
+	"github.com/myfusionhelper/api/internal/batch"
+	"github.com/myfusionhelper/api/internal/breaker"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/delayed"
+	"github.com/myfusionhelper/api/internal/worker"
+)
+
+func main() {
+	configPath := flag.String("config", "devserver.json", "path to the devserver config file")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// The handler packages read their environment when they are initialized,
+	// before main runs, so the server itself runs in a child process started
+	// with the config's environment
+	if os.Getenv(childEnvVar) == "" {
+		os.Exit(runChild(cfg))
+	}
+
+	if err := serve(cfg); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// runChild re-executes the devserver with the server environment and returns
+// its exit code
+func runChild(cfg *Config) int {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Printf("Failed to locate devserver executable: %v", err)
+		return 1
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = cfg.environ()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		log.Printf("Failed to start devserver: %v", err)
+		return 1
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 1
+	}
+	return 0
+}
+
+func serve(cfg *Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return err
+	}
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(awsCfg))
+
+	pipe := newPipeline(worker.NewWorker(stores, sqs.NewFromConfig(awsCfg)), time.Duration(cfg.WorkerTimeoutSeconds)*time.Second)
+	sched := newScheduler()
+
+	mux := http.NewServeMux()
+	gw := &gateway{devUserSub: cfg.DevUserSub, authorize: authorizerHandler.Handle}
+	gw.register(mux, routes)
+	local := &localAWS{config: cfg, stream: pipe.records, scheduler: sched, client: &http.Client{}}
+	local.register(mux)
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: withCORS(mux)}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Server stopped: %v", err)
+			stop()
+		}
+	}()
+	log.Printf("devserver listening on %s (DynamoDB at %s)", cfg.localURL(), cfg.DynamoDBEndpoint)
+
+	go pipe.run(ctx)
+	// Data explorer segments are snapshotted to S3, which is not served
+	// locally, so batches over them fail with a reason instead of running
+	batches := batch.NewRunner(stores, nil)
+	go sched.run(ctx, stores, batches, delayed.NewReleaser(stores), breaker.NewProber(stores))
+
+	<-ctx.Done()
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/worker"
+)
+
+// queueDepth bounds each helper type's in-process queue
+const queueDepth = 1000
+
+// queuedMessage is an SQS message waiting on an in-process queue
+type queuedMessage struct {
+	id           string
+	body         string
+	groupID      string
+	receiveCount int
+}
+
+// pipeline stands in for the executions table's DynamoDB Stream, the stream
+// router and the per-helper-type SQS queues. Records inserted into the
+// executions table, or updated back to queued, arrive on records; each
+// helper type gets a queue drained by one worker, so executions of a type
+// run in order, one at a time.
+type pipeline struct {
+	records chan events.DynamoDBEventRecord
+	worker  *worker.Worker
+	timeout time.Duration
+
+	mu     sync.Mutex
+	queues map[string]chan queuedMessage
+}
+
+func newPipeline(w *worker.Worker, timeout time.Duration) *pipeline {
+	return &pipeline{
+		records: make(chan events.DynamoDBEventRecord, queueDepth),
+		worker:  w,
+		timeout: timeout,
+		queues:  make(map[string]chan queuedMessage),
+	}
+}
+
+// run routes stream records until ctx is done
+func (p *pipeline) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case record := <-p.records:
+			p.route(ctx, record)
+		}
+	}
+}
+
+// route does the stream router's job: new executions, and executions queued
+// again, are marked dispatched and go to their helper type's queue with the
+// stream image as the message body
+func (p *pipeline) route(ctx context.Context, record events.DynamoDBEventRecord) {
+	switch record.EventName {
+	case "INSERT":
+	case "MODIFY":
+		if record.Change.NewImage["status"].String() != execution.StatusQueued {
+			return
+		}
+	default:
+		return
+	}
+
+	executionID := record.Change.NewImage["execution_id"].String()
+	helperType := record.Change.NewImage["helper_type"].String()
+	if executionID == "" || helperType == "" {
+		log.Printf("Missing execution_id or helper_type in record")
+		return
+	}
+
+	body, err := worker.StreamImageToJSON(record.Change.NewImage)
+	if err != nil {
+		log.Printf("Failed to convert stream image to JSON for execution %s: %v", executionID, err)
+		return
+	}
+
+	dispatched, err := execution.Dispatch(ctx, p.worker.Stores.Executions, executionID, time.Now())
+	if err != nil {
+		log.Printf("Failed to mark execution %s dispatched: %v", executionID, err)
+		return
+	}
+	if !dispatched {
+		log.Printf("Execution %s is no longer queued, not routing it", executionID)
+		return
+	}
+
+	p.enqueue(ctx, helperType, queuedMessage{
+		id:           uuid.New().String(),
+		body:         body,
+		groupID:      worker.MessageGroupID(record.Change.NewImage),
+		receiveCount: 1,
+	})
+	log.Printf("Routed execution %s (helper_type=%s) to local queue", executionID, helperType)
+}
+
+func (p *pipeline) enqueue(ctx context.Context, helperType string, msg queuedMessage) {
+	p.mu.Lock()
+	queue, ok := p.queues[helperType]
+	if !ok {
+		queue = make(chan queuedMessage, queueDepth)
+		p.queues[helperType] = queue
+		go p.consume(ctx, helperType, queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case queue <- msg:
+	case <-ctx.Done():
+	}
+}
+
+// consume runs a helper type's worker on each message, one-record batches
+// like the worker Lambdas' batchSize: 1
+func (p *pipeline) consume(ctx context.Context, helperType string, queue chan queuedMessage) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-queue:
+			if p.invoke(ctx, msg) {
+				continue
+			}
+			// Handed back: redeliver after the helper type's retry delay, the
+			// way the worker's visibility change would on SQS
+			delay := worker.RetryPolicyFor(helperType).Delay(msg.receiveCount)
+			log.Printf("Message %s handed back, redelivering in %v", msg.id, delay)
+			msg.receiveCount++
+			time.AfterFunc(delay, func() {
+				if ctx.Err() == nil {
+					p.enqueue(ctx, helperType, msg)
+				}
+			})
+		}
+	}
+}
+
+// invoke runs the worker on msg and reports whether it was consumed
+func (p *pipeline) invoke(ctx context.Context, msg queuedMessage) bool {
+	invokeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	response, err := p.worker.HandleSQSEvent(invokeCtx, events.SQSEvent{
+		Records: []events.SQSMessage{{
+			MessageId:   msg.id,
+			Body:        msg.body,
+			EventSource: "aws:sqs",
+			Attributes: map[string]string{
+				"MessageGroupId":          msg.groupID,
+				"ApproximateReceiveCount": strconv.Itoa(msg.receiveCount),
+			},
+		}},
+	})
+	if err != nil {
+		log.Printf("Worker invocation failed: %v", err)
+		return false
+	}
+	return len(response.BatchItemFailures) == 0
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	accountsHandler "github.com/myfusionhelper/api/cmd/handlers/accounts/handler"
+	alexaHandler "github.com/myfusionhelper/api/cmd/handlers/alexa-webhook/handler"
+	apiKeysHandler "github.com/myfusionhelper/api/cmd/handlers/api-keys/handler"
+	authHandler "github.com/myfusionhelper/api/cmd/handlers/auth/handler"
+	billingHandler "github.com/myfusionhelper/api/cmd/handlers/billing/handler"
+	chatHandler "github.com/myfusionhelper/api/cmd/handlers/chat/handler"
+	dataExplorerHandler "github.com/myfusionhelper/api/cmd/handlers/data-explorer/handler"
+	emailsHandler "github.com/myfusionhelper/api/cmd/handlers/emails/handler"
+	googleAssistantHandler "github.com/myfusionhelper/api/cmd/handlers/google-assistant-webhook/handler"
+	helpersHandler "github.com/myfusionhelper/api/cmd/handlers/helpers/handler"
+	internalEmailHandler "github.com/myfusionhelper/api/cmd/handlers/internal-email/handler"
+	platformsHandler "github.com/myfusionhelper/api/cmd/handlers/platforms/handler"
+	smsChatHandler "github.com/myfusionhelper/api/cmd/handlers/sms-chat-webhook/handler"
+	zoomHandler "github.com/myfusionhelper/api/cmd/handlers/zoom-webhook/handler"
+)
+
+// integrationTimeout is API Gateway's maximum integration timeout
+const integrationTimeout = 29 * time.Second
+
+// apiHandler is an HTTP API Lambda handler
+type apiHandler func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+
+// authorizerFunc is the API-key Lambda authorizer
+type authorizerFunc func(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error)
+
+type authKind int
+
+const (
+	authNone    authKind = iota
+	authCognito          // cognitoAuthorizer (JWT)
+	authAPIKey           // apiKeyAuthorizer (Lambda, simple responses)
+)
+
+// route is an httpApi event from one of the services' serverless.yml
+type route struct {
+	method string
+	path   string
+	auth   authKind
+	handle apiHandler
+}
+
+// routes mirrors the httpApi events of services/api/*/serverless.yml and the
+// webhook workers. Keep it in step when endpoints are added.
+var routes = []route{
+	// accounts
+	{"GET", "/accounts", authCognito, accountsHandler.Handle},
+	{"GET", "/accounts/health", authNone, accountsHandler.Handle},
+	{"POST", "/accounts/switch", authCognito, accountsHandler.Handle},
+	{"GET", "/accounts/preferences", authCognito, accountsHandler.Handle},
+	{"PUT", "/accounts/preferences", authCognito, accountsHandler.Handle},
+	{"GET", "/accounts/{account_id}", authCognito, accountsHandler.Handle},
+	{"PUT", "/accounts/{account_id}", authCognito, accountsHandler.Handle},
+	{"GET", "/accounts/{account_id}/team", authCognito, accountsHandler.Handle},
+	{"POST", "/accounts/{account_id}/team", authCognito, accountsHandler.Handle},
+	{"PUT", "/accounts/{account_id}/team/{user_id}", authCognito, accountsHandler.Handle},
+	{"DELETE", "/accounts/{account_id}/team/{user_id}", authCognito, accountsHandler.Handle},
+
+	// api-keys
+	{"GET", "/api-keys", authCognito, apiKeysHandler.Handle},
+	{"POST", "/api-keys", authCognito, apiKeysHandler.Handle},
+	{"GET", "/api-keys/health", authNone, apiKeysHandler.Handle},
+	{"DELETE", "/api-keys/{key_id}", authCognito, apiKeysHandler.Handle},
+
+	// auth
+	{"GET", "/auth/status", authCognito, authHandler.Handle},
+	{"PUT", "/auth/profile", authCognito, authHandler.Handle},
+	{"PATCH", "/auth/onboarding-complete", authCognito, authHandler.Handle},
+	{"POST", "/auth/logout", authCognito, authHandler.Handle},
+	{"PUT", "/auth/password", authCognito, authHandler.Handle},
+	{"POST", "/auth/refresh", authNone, authHandler.Handle},
+	{"GET", "/auth/health", authNone, authHandler.Handle},
+	{"POST", "/auth/login", authNone, authHandler.Handle},
+	{"POST", "/auth/register", authNone, authHandler.Handle},
+	{"POST", "/auth/forgot-password", authNone, authHandler.Handle},
+	{"POST", "/auth/reset-password", authNone, authHandler.Handle},
+
+	// billing
+	{"GET", "/billing", authCognito, billingHandler.Handle},
+	{"POST", "/billing/portal-session", authCognito, billingHandler.Handle},
+	{"GET", "/billing/invoices", authCognito, billingHandler.Handle},
+	{"POST", "/billing/checkout/sessions", authCognito, billingHandler.Handle},
+	{"POST", "/billing/webhook", authNone, billingHandler.Handle},
+
+	// chat
+	{"GET", "/chat/health", authNone, chatHandler.Handle},
+	{"POST", "/chat/conversations", authCognito, chatHandler.Handle},
+	{"GET", "/chat/conversations", authCognito, chatHandler.Handle},
+	{"GET", "/chat/conversations/{id}", authCognito, chatHandler.Handle},
+	{"DELETE", "/chat/conversations/{id}", authCognito, chatHandler.Handle},
+	{"POST", "/chat/conversations/{id}/messages", authCognito, chatHandler.Handle},
+	{"GET", "/chat/conversations/{id}/messages", authCognito, chatHandler.Handle},
+
+	// data-explorer
+	{"GET", "/data/catalog", authCognito, dataExplorerHandler.Handle},
+	{"POST", "/data/query", authCognito, dataExplorerHandler.Handle},
+	{"GET", "/data/record/{connectionId}/{objectType}/{recordId}", authCognito, dataExplorerHandler.Handle},
+	{"POST", "/data/export", authCognito, dataExplorerHandler.Handle},
+	{"POST", "/data/sync", authCognito, dataExplorerHandler.Handle},
+	{"POST", "/data/batches", authCognito, dataExplorerHandler.Handle},
+	{"GET", "/data/health", authNone, dataExplorerHandler.Handle},
+
+	// emails
+	{"GET", "/emails/health", authNone, emailsHandler.Handle},
+	{"GET", "/emails", authCognito, emailsHandler.Handle},
+	{"POST", "/emails", authCognito, emailsHandler.Handle},
+	{"DELETE", "/emails/{id}", authCognito, emailsHandler.Handle},
+	{"GET", "/emails/templates", authCognito, emailsHandler.Handle},
+	{"POST", "/emails/templates", authCognito, emailsHandler.Handle},
+	{"GET", "/emails/templates/{id}", authCognito, emailsHandler.Handle},
+	{"PUT", "/emails/templates/{id}", authCognito, emailsHandler.Handle},
+	{"DELETE", "/emails/templates/{id}", authCognito, emailsHandler.Handle},
+
+	// helpers
+	{"GET", "/helpers", authCognito, helpersHandler.Handle},
+	{"POST", "/helpers", authCognito, helpersHandler.Handle},
+	{"GET", "/helpers/health", authNone, helpersHandler.Handle},
+	{"GET", "/helpers/types", authCognito, helpersHandler.Handle},
+	// ServeMux cannot prefer the literal "types" segment over {helper_id} the
+	// way API Gateway does, so GET /helpers/types/{type} and
+	// GET /helpers/{helper_id}/versions share one pattern here. The helpers
+	// handler routes on the path either way.
+	{"GET", "/helpers/{helper_id}/{resource}", authCognito, helpersHandler.Handle},
+	{"GET", "/helpers/{helper_id}", authCognito, helpersHandler.Handle},
+	{"PUT", "/helpers/{helper_id}", authCognito, helpersHandler.Handle},
+	{"DELETE", "/helpers/{helper_id}", authCognito, helpersHandler.Handle},
+	{"POST", "/helpers/{helper_id}/execute", authCognito, helpersHandler.Handle},
+	{"GET", "/helpers/{helper_id}/versions/diff", authCognito, helpersHandler.Handle},
+	{"GET", "/helpers/{helper_id}/versions/{version}", authCognito, helpersHandler.Handle},
+	{"POST", "/helpers/{helper_id}/versions/{version}/rollback", authCognito, helpersHandler.Handle},
+	{"GET", "/executions", authCognito, helpersHandler.Handle},
+	{"GET", "/executions/{execution_id}", authCognito, helpersHandler.Handle},
+	{"POST", "/executions/{execution_id}/replay", authCognito, helpersHandler.Handle},
+	{"POST", "/executions/{execution_id}/cancel", authCognito, helpersHandler.Handle},
+	{"GET", "/workflow-runs", authCognito, helpersHandler.Handle},
+	{"GET", "/workflow-runs/{run_id}", authCognito, helpersHandler.Handle},
+	{"POST", "/workflow-runs/{run_id}/cancel", authCognito, helpersHandler.Handle},
+	{"POST", "/workflow-runs/{run_id}/resume", authCognito, helpersHandler.Handle},
+	{"GET", "/batches", authCognito, helpersHandler.Handle},
+	{"POST", "/batches", authCognito, helpersHandler.Handle},
+	{"GET", "/batches/{batch_id}", authCognito, helpersHandler.Handle},
+	{"GET", "/batches/{batch_id}/items", authCognito, helpersHandler.Handle},
+	{"POST", "/batches/{batch_id}/pause", authCognito, helpersHandler.Handle},
+	{"POST", "/batches/{batch_id}/resume", authCognito, helpersHandler.Handle},
+	{"POST", "/batches/{batch_id}/cancel", authCognito, helpersHandler.Handle},
+	{"GET", "/delayed-executions", authCognito, helpersHandler.Handle},
+	{"POST", "/delayed-executions/cancel", authCognito, helpersHandler.Handle},
+	{"GET", "/delayed-executions/{execution_id}", authCognito, helpersHandler.Handle},
+	{"DELETE", "/delayed-executions/{execution_id}", authCognito, helpersHandler.Handle},
+	{"POST", "/helper/{identifier}/execute", authAPIKey, helpersHandler.Handle},
+	{"POST", "/helper/{api_key}/{identifier}", authAPIKey, helpersHandler.Handle},
+	{"GET", "/helper/{api_key}/{identifier}", authAPIKey, helpersHandler.Handle},
+
+	// internal-email
+	{"GET", "/internal/emails/health", authNone, internalEmailHandler.Handle},
+	{"POST", "/internal/emails/send", authNone, internalEmailHandler.Handle},
+	{"GET", "/internal/emails/history", authNone, internalEmailHandler.Handle},
+
+	// platforms
+	{"GET", "/platforms", authCognito, platformsHandler.Handle},
+	{"GET", "/platforms/health", authNone, platformsHandler.Handle},
+	{"GET", "/platforms/oauth/callback", authNone, platformsHandler.Handle},
+	{"GET", "/platforms/{platform_id}", authCognito, platformsHandler.Handle},
+	{"GET", "/platforms/{platform_id}/connections", authCognito, platformsHandler.Handle},
+	{"POST", "/platforms/{platform_id}/connections", authCognito, platformsHandler.Handle},
+	{"GET", "/platforms/{platform_id}/connections/{connection_id}", authCognito, platformsHandler.Handle},
+	{"PUT", "/platforms/{platform_id}/connections/{connection_id}", authCognito, platformsHandler.Handle},
+	{"DELETE", "/platforms/{platform_id}/connections/{connection_id}", authCognito, platformsHandler.Handle},
+	{"GET", "/platforms/{platform_id}/connections/{connection_id}/fields", authCognito, platformsHandler.Handle},
+	{"GET", "/platforms/{platform_id}/connections/{connection_id}/tags", authCognito, platformsHandler.Handle},
+	{"POST", "/platforms/{platform_id}/connections/{connection_id}/test", authCognito, platformsHandler.Handle},
+	{"POST", "/platforms/{platform_id}/oauth/start", authCognito, platformsHandler.Handle},
+	{"GET", "/platform-connections", authCognito, platformsHandler.Handle},
+
+	// webhook workers
+	{"POST", "/alexa-webhook", authNone, alexaHandler.Handle},
+	{"POST", "/google-assistant-webhook", authNone, googleAssistantHandler.Handle},
+	{"POST", "/sms-webhook", authNone, smsChatHandler.Handle},
+	{"POST", "/webhooks/zoom", authNone, zoomHandler.Handle},
+}
+
+// corsAllowedHeaders and corsAllowedMethods match the gateway's httpApi cors
+// settings
+const (
+	corsAllowedHeaders = "content-type,authorization,x-amz-date,x-api-key,x-amz-security-token,x-platform,x-app-version"
+	corsAllowedMethods = "GET,POST,PUT,DELETE,OPTIONS"
+)
+
+// gateway plays API Gateway's part: it turns HTTP requests into payload
+// format 2.0 events, runs the route's authorizer and writes the Lambda's
+// response back
+type gateway struct {
+	// devUserSub is the Cognito sub used when a request has no bearer token
+	devUserSub string
+	authorize  authorizerFunc
+}
+
+// register mounts routes on mux along with the gateway's own health check
+// and 404 for unknown routes
+func (g *gateway) register(mux *http.ServeMux, routes []route) {
+	for _, rt := range routes {
+		mux.Handle(rt.method+" "+rt.path, g.handler(rt))
+	}
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeGatewayJSON(w, http.StatusOK, map[string]string{"status": "healthy", "service": "devserver"})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeGatewayJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	})
+}
+
+// withCORS answers CORS preflight requests the way the gateway's cors
+// settings do, before they reach any route
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" && !strings.HasPrefix(r.URL.Path, awsPathPrefix) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			w.Header().Set("Access-Control-Max-Age", "86400")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (g *gateway) handler(rt route) http.Handler {
+	routeKey := rt.method + " " + rt.path
+	paramNames := pathParamNames(rt.path)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, err := newRequestEvent(r, routeKey, paramNames)
+		if err != nil {
+			writeGatewayJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad Request"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), integrationTimeout)
+		defer cancel()
+
+		switch rt.auth {
+		case authCognito:
+			claims, ok := g.cognitoClaims(event.Headers["authorization"])
+			if !ok {
+				writeGatewayJSON(w, http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+				return
+			}
+			event.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{Claims: claims},
+			}
+		case authAPIKey:
+			authContext, ok := g.authorizeAPIKey(ctx, event)
+			if !ok {
+				writeGatewayJSON(w, http.StatusForbidden, map[string]string{"message": "Forbidden"})
+				return
+			}
+			event.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				Lambda: authContext,
+			}
+		}
+
+		response, err := rt.handle(ctx, event)
+		if err != nil {
+			log.Printf("%s failed: %v", routeKey, err)
+			if errors.Is(err, context.DeadlineExceeded) {
+				writeGatewayJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "Service Unavailable"})
+				return
+			}
+			writeGatewayJSON(w, http.StatusInternalServerError, map[string]string{"message": "Internal Server Error"})
+			return
+		}
+		writeLambdaResponse(w, response)
+	})
+}
+
+// cognitoClaims returns the claims of the request's bearer token. The token
+// is decoded without verifying it against Cognito; requests without one get
+// the configured dev user.
+func (g *gateway) cognitoClaims(authorization string) (map[string]string, bool) {
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	if token == "" {
+		if g.devUserSub == "" {
+			return nil, false
+		}
+		return map[string]string{"sub": g.devUserSub}, true
+	}
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		log.Printf("Rejecting malformed bearer token: %v", err)
+		return nil, false
+	}
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, false
+	}
+
+	claims := make(map[string]string, len(mapClaims))
+	for k, v := range mapClaims {
+		claims[k] = fmt.Sprint(v)
+	}
+	if claims["sub"] == "" {
+		return nil, false
+	}
+	return claims, true
+}
+
+// authorizeAPIKey runs the API-key authorizer and returns its context
+func (g *gateway) authorizeAPIKey(ctx context.Context, event events.APIGatewayV2HTTPRequest) (map[string]interface{}, bool) {
+	response, err := g.authorize(ctx, events.APIGatewayV2CustomAuthorizerV2Request{
+		Version:               "2.0",
+		Type:                  "REQUEST",
+		RouteArn:              "arn:aws:execute-api:local:000000000000:devserver/$default/" + strings.Replace(event.RouteKey, " ", "", 1),
+		IdentitySource:        []string{event.Headers["x-api-key"]},
+		RouteKey:              event.RouteKey,
+		RawPath:               event.RawPath,
+		RawQueryString:        event.RawQueryString,
+		Cookies:               event.Cookies,
+		Headers:               event.Headers,
+		QueryStringParameters: event.QueryStringParameters,
+		RequestContext:        event.RequestContext,
+		PathParameters:        event.PathParameters,
+	})
+	if err != nil {
+		log.Printf("API key authorizer failed: %v", err)
+		return nil, false
+	}
+	return response.Context, response.IsAuthorized
+}
+
+// newRequestEvent builds the payload format 2.0 event API Gateway would send
+// for r
+func newRequestEvent(r *http.Request, routeKey string, paramNames []string) (events.APIGatewayV2HTTPRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, err
+	}
+
+	event := events.APIGatewayV2HTTPRequest{
+		Version:        "2.0",
+		RouteKey:       routeKey,
+		RawPath:        r.URL.Path,
+		RawQueryString: r.URL.RawQuery,
+		Headers:        make(map[string]string, len(r.Header)),
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:   routeKey,
+			AccountID:  "000000000000",
+			Stage:      "$default",
+			RequestID:  uuid.New().String(),
+			APIID:      "devserver",
+			DomainName: r.Host,
+			Time:       time.Now().UTC().Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:  time.Now().UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIP(r),
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+
+	// Header names are lowercased and repeated values comma-joined; cookies
+	// move to their own field
+	for name, values := range r.Header {
+		name = strings.ToLower(name)
+		if name == "cookie" {
+			for _, v := range values {
+				for _, c := range strings.Split(v, ";") {
+					if c = strings.TrimSpace(c); c != "" {
+						event.Cookies = append(event.Cookies, c)
+					}
+				}
+			}
+			continue
+		}
+		event.Headers[name] = strings.Join(values, ",")
+	}
+	event.Headers["host"] = r.Host
+
+	if query := r.URL.Query(); len(query) > 0 {
+		event.QueryStringParameters = make(map[string]string, len(query))
+		for name, values := range query {
+			event.QueryStringParameters[name] = strings.Join(values, ",")
+		}
+	}
+
+	if len(paramNames) > 0 {
+		event.PathParameters = make(map[string]string, len(paramNames))
+		for _, name := range paramNames {
+			event.PathParameters[name] = r.PathValue(name)
+		}
+	}
+
+	if len(body) > 0 {
+		if utf8.Valid(body) {
+			event.Body = string(body)
+		} else {
+			event.Body = base64.StdEncoding.EncodeToString(body)
+			event.IsBase64Encoded = true
+		}
+	}
+	return event, nil
+}
+
+// pathParamNames returns the {name} segments of a route path
+func pathParamNames(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+		}
+	}
+	return names
+}
+
+func sourceIP(r *http.Request) string {
+	host := r.RemoteAddr
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.Trim(host, "[]")
+}
+
+// writeLambdaResponse writes a payload format 2.0 response
+func writeLambdaResponse(w http.ResponseWriter, response events.APIGatewayV2HTTPResponse) {
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for _, cookie := range response.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			log.Printf("Failed to decode base64 response body: %v", err)
+			writeGatewayJSON(w, http.StatusInternalServerError, map[string]string{"message": "Internal Server Error"})
+			return
+		}
+		body = decoded
+	}
+
+	status := response.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeGatewayJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
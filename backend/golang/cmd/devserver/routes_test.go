@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRoutes_Register(t *testing.T) {
+	// ServeMux panics on conflicting patterns
+	mux := http.NewServeMux()
+	g := &gateway{}
+	g.register(mux, routes)
+	(&localAWS{}).register(mux)
+
+	seen := make(map[string]bool)
+	for _, rt := range routes {
+		key := rt.method + " " + rt.path
+		if seen[key] {
+			t.Errorf("Expected unique routes, got %s twice", key)
+		}
+		seen[key] = true
+		if rt.handle == nil {
+			t.Errorf("Expected a handler for %s", key)
+		}
+	}
+}
+
+// newTestServer serves a single route whose handler records the event it got
+func newTestServer(t *testing.T, g *gateway, rt route) (*httptest.Server, *events.APIGatewayV2HTTPRequest) {
+	t.Helper()
+	var got events.APIGatewayV2HTTPRequest
+	rt.handle = func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		got = event
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: 201,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Cookies:    []string{"session=abc"},
+			Body:       `{"ok":true}`,
+		}, nil
+	}
+	mux := http.NewServeMux()
+	g.register(mux, []route{rt})
+	server := httptest.NewServer(withCORS(mux))
+	t.Cleanup(server.Close)
+	return server, &got
+}
+
+func TestGateway_RequestEvent(t *testing.T) {
+	server, got := newTestServer(t, &gateway{}, route{method: "POST", path: "/helpers/{helper_id}/execute", auth: authNone})
+
+	req, _ := http.NewRequest("POST", server.URL+"/helpers/helper:abc/execute?dry_run=true&tag=a&tag=b", strings.NewReader(`{"contact_id":"1"}`))
+	req.Header.Set("X-Custom", "value")
+	req.Header.Add("Cookie", "a=1; b=2")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 201 {
+		t.Errorf("Expected status 201, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Set-Cookie") != "session=abc" {
+		t.Errorf("Expected Set-Cookie 'session=abc', got %q", resp.Header.Get("Set-Cookie"))
+	}
+
+	if got.RouteKey != "POST /helpers/{helper_id}/execute" {
+		t.Errorf("Expected route key 'POST /helpers/{helper_id}/execute', got %q", got.RouteKey)
+	}
+	if got.RequestContext.HTTP.Path != "/helpers/helper:abc/execute" {
+		t.Errorf("Expected path '/helpers/helper:abc/execute', got %q", got.RequestContext.HTTP.Path)
+	}
+	if got.RequestContext.HTTP.Method != "POST" {
+		t.Errorf("Expected method POST, got %q", got.RequestContext.HTTP.Method)
+	}
+	if got.PathParameters["helper_id"] != "helper:abc" {
+		t.Errorf("Expected helper_id 'helper:abc', got %q", got.PathParameters["helper_id"])
+	}
+	if got.QueryStringParameters["dry_run"] != "true" || got.QueryStringParameters["tag"] != "a,b" {
+		t.Errorf("Expected query dry_run=true and tag=a,b, got %v", got.QueryStringParameters)
+	}
+	if got.Headers["x-custom"] != "value" {
+		t.Errorf("Expected lowercased header x-custom, got %v", got.Headers)
+	}
+	if len(got.Cookies) != 2 || got.Cookies[0] != "a=1" || got.Cookies[1] != "b=2" {
+		t.Errorf("Expected cookies [a=1 b=2], got %v", got.Cookies)
+	}
+	if got.Body != `{"contact_id":"1"}` || got.IsBase64Encoded {
+		t.Errorf("Expected plain JSON body, got %q (base64=%t)", got.Body, got.IsBase64Encoded)
+	}
+}
+
+func TestGateway_BinaryBody(t *testing.T) {
+	server, got := newTestServer(t, &gateway{}, route{method: "POST", path: "/upload", auth: authNone})
+
+	binary := []byte{0xff, 0xfe, 0x00, 0x01}
+	resp, err := http.Post(server.URL+"/upload", "application/octet-stream", strings.NewReader(string(binary)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if !got.IsBase64Encoded {
+		t.Fatal("Expected a base64-encoded body")
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(got.Body)
+	if string(decoded) != string(binary) {
+		t.Errorf("Expected body %v, got %v", binary, decoded)
+	}
+}
+
+func TestGateway_CognitoAuth(t *testing.T) {
+	t.Run("dev user without token", func(t *testing.T) {
+		server, got := newTestServer(t, &gateway{devUserSub: "dev-sub"}, route{method: "GET", path: "/helpers", auth: authCognito})
+		resp, err := http.Get(server.URL + "/helpers")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if got.RequestContext.Authorizer == nil || got.RequestContext.Authorizer.JWT.Claims["sub"] != "dev-sub" {
+			t.Errorf("Expected sub 'dev-sub', got %+v", got.RequestContext.Authorizer)
+		}
+	})
+
+	t.Run("bearer token claims", func(t *testing.T) {
+		server, got := newTestServer(t, &gateway{devUserSub: "dev-sub"}, route{method: "GET", path: "/helpers", auth: authCognito})
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user-123", "email": "a@example.com"}).SignedString([]byte("any"))
+
+		req, _ := http.NewRequest("GET", server.URL+"/helpers", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		claims := got.RequestContext.Authorizer.JWT.Claims
+		if claims["sub"] != "user-123" || claims["email"] != "a@example.com" {
+			t.Errorf("Expected token claims, got %v", claims)
+		}
+	})
+
+	t.Run("no token and no dev user", func(t *testing.T) {
+		server, _ := newTestServer(t, &gateway{}, route{method: "GET", path: "/helpers", auth: authCognito})
+		resp, err := http.Get(server.URL + "/helpers")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestGateway_APIKeyAuth(t *testing.T) {
+	authorize := func(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+		if event.PathParameters["api_key"] != "good" {
+			return events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}, nil
+		}
+		return events.APIGatewayV2CustomAuthorizerSimpleResponse{
+			IsAuthorized: true,
+			Context:      map[string]interface{}{"account_id": "acc-1"},
+		}, nil
+	}
+	server, got := newTestServer(t, &gateway{authorize: authorize}, route{method: "GET", path: "/helper/{api_key}/{identifier}", auth: authAPIKey})
+
+	resp, err := http.Get(server.URL + "/helper/bad/h1")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/helper/good/h1")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 201 {
+		t.Errorf("Expected status 201, got %d", resp.StatusCode)
+	}
+	if got.RequestContext.Authorizer == nil || got.RequestContext.Authorizer.Lambda["account_id"] != "acc-1" {
+		t.Errorf("Expected authorizer context account_id 'acc-1', got %+v", got.RequestContext.Authorizer)
+	}
+}
+
+func TestGateway_NotFoundAndPreflight(t *testing.T) {
+	server, _ := newTestServer(t, &gateway{}, route{method: "GET", path: "/helpers", auth: authNone})
+
+	resp, err := http.Get(server.URL + "/nope")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || body["message"] != "Not Found" {
+		t.Errorf("Expected 404 Not Found, got %d %v", resp.StatusCode, body)
+	}
+
+	req, _ := http.NewRequest("OPTIONS", server.URL+"/helpers", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected Access-Control-Allow-Origin '*', got %q", resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed EventBridge schedule expression
+type schedule interface {
+	// next returns the first run time after t, or the zero time if there is none
+	next(t time.Time) time.Time
+}
+
+// parseSchedule parses rate(value unit) and cron(fields) expressions. Cron
+// times are UTC, as they are on EventBridge. The L, W and # day modifiers
+// are not supported.
+func parseSchedule(expr string) (schedule, error) {
+	expr = strings.TrimSpace(expr)
+	switch {
+	case strings.HasPrefix(expr, "rate(") && strings.HasSuffix(expr, ")"):
+		return parseRate(expr[len("rate(") : len(expr)-1])
+	case strings.HasPrefix(expr, "cron(") && strings.HasSuffix(expr, ")"):
+		return parseCron(expr[len("cron(") : len(expr)-1])
+	}
+	return nil, fmt.Errorf("schedule expression must be rate(...) or cron(...): %q", expr)
+}
+
+type rateSchedule struct {
+	every time.Duration
+}
+
+func (r rateSchedule) next(t time.Time) time.Time {
+	return t.Add(r.every)
+}
+
+var rateUnits = map[string]time.Duration{
+	"minute": time.Minute, "minutes": time.Minute,
+	"hour": time.Hour, "hours": time.Hour,
+	"day": 24 * time.Hour, "days": 24 * time.Hour,
+}
+
+func parseRate(s string) (schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("rate must be \"value unit\": %q", s)
+	}
+	value, err := strconv.Atoi(fields[0])
+	if err != nil || value <= 0 {
+		return nil, fmt.Errorf("rate value must be a positive integer: %q", fields[0])
+	}
+	unit, ok := rateUnits[fields[1]]
+	if !ok {
+		return nil, fmt.Errorf("rate unit must be minutes, hours or days: %q", fields[1])
+	}
+	return rateSchedule{every: time.Duration(value) * unit}, nil
+}
+
+// cronSchedule holds the allowed values of each field. Weekdays are indexed
+// 1 (SUN) to 7 (SAT); a nil years set allows any year.
+type cronSchedule struct {
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+	years    map[int]bool
+}
+
+var (
+	monthNames   = map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}
+	weekdayNames = map[string]int{"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7}
+)
+
+// cronSearchYears bounds how far ahead next looks for a matching time
+const cronSearchYears = 5
+
+func parseCron(s string) (schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("cron needs 6 fields (minutes hours day-of-month month day-of-week year), got %d", len(fields))
+	}
+
+	c := &cronSchedule{}
+	var err error
+	if c.minutes, err = parseCronField(fields[0], 0, 59, nil, false); err != nil {
+		return nil, fmt.Errorf("minutes: %w", err)
+	}
+	if c.hours, err = parseCronField(fields[1], 0, 23, nil, false); err != nil {
+		return nil, fmt.Errorf("hours: %w", err)
+	}
+	if c.days, err = parseCronField(fields[2], 1, 31, nil, true); err != nil {
+		return nil, fmt.Errorf("day-of-month: %w", err)
+	}
+	if c.months, err = parseCronField(fields[3], 1, 12, monthNames, false); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if c.weekdays, err = parseCronField(fields[4], 1, 7, weekdayNames, true); err != nil {
+		return nil, fmt.Errorf("day-of-week: %w", err)
+	}
+	if fields[5] != "*" {
+		years, err := parseCronField(fields[5], 1970, 2199, nil, false)
+		if err != nil {
+			return nil, fmt.Errorf("year: %w", err)
+		}
+		c.years = make(map[int]bool)
+		for year, ok := range years {
+			if ok {
+				c.years[year] = true
+			}
+		}
+	}
+	return c, nil
+}
+
+// parseCronField parses a comma-separated list of *, values, ranges and
+// /steps into a set indexed by value
+func parseCronField(s string, min, max int, names map[string]int, allowAny bool) ([]bool, error) {
+	set := make([]bool, max+1)
+	if s == "?" {
+		if !allowAny {
+			return nil, fmt.Errorf("? is only allowed in day-of-month and day-of-week")
+		}
+		s = "*"
+	}
+
+	value := func(v string) (int, error) {
+		if n, ok := names[strings.ToUpper(v)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("unsupported value %q", v)
+		}
+		if n < min || n > max {
+			return 0, fmt.Errorf("%d is outside %d-%d", n, min, max)
+		}
+		return n, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = value(bounds[0]); err != nil {
+				return nil, err
+			}
+			if hi, err = value(bounds[1]); err != nil {
+				return nil, err
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := value(part)
+			if err != nil {
+				return nil, err
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (c *cronSchedule) next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + cronSearchYears
+
+	for t.Year() <= limit {
+		switch {
+		case c.years != nil && !c.years[t.Year()]:
+			t = time.Date(t.Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+		case !c.months[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.days[t.Day()] || !c.weekdays[int(t.Weekday())+1]:
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !c.hours[t.Hour()]:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !c.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// Friday 2026-01-02 10:17:30 UTC
+	from := time.Date(2026, time.January, 2, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"rate(5 minutes)", from.Add(5 * time.Minute)},
+		{"rate(1 hour)", from.Add(time.Hour)},
+		{"rate(2 days)", from.Add(48 * time.Hour)},
+		{"cron(0 * * * ? *)", time.Date(2026, time.January, 2, 11, 0, 0, 0, time.UTC)},
+		{"cron(*/15 * * * ? *)", time.Date(2026, time.January, 2, 10, 30, 0, 0, time.UTC)},
+		{"cron(0 9 * * ? *)", time.Date(2026, time.January, 3, 9, 0, 0, 0, time.UTC)},
+		{"cron(30 8 ? * MON-FRI *)", time.Date(2026, time.January, 5, 8, 30, 0, 0, time.UTC)},
+		{"cron(0 12 1 * ? *)", time.Date(2026, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{"cron(0 0 1 JAN ? 2027)", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"cron(0 10,18 ? * 1 *)", time.Date(2026, time.January, 4, 10, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			sched, err := parseSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := sched.next(from); !got.Equal(tt.want) {
+				t.Errorf("Expected next %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_NoMatch(t *testing.T) {
+	sched, err := parseSchedule("cron(0 0 1 JAN ? 2020)")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := sched.next(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Expected zero time for a past-only schedule, got %s", got)
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"every 5 minutes",
+		"rate(0 minutes)",
+		"rate(5 weeks)",
+		"rate(minutes)",
+		"cron(0 9 * * *)",
+		"cron(60 * * * ? *)",
+		"cron(0 9 ? * FOO *)",
+		"cron(0 ? * * ? *)",
+		"cron(0 9 10-5 * ? *)",
+		"cron(*/0 * * * ? *)",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := parseSchedule(expr); err == nil {
+				t.Errorf("Expected an error for %q", expr)
+			}
+		})
+	}
+}
+
+func TestScheduler_Due(t *testing.T) {
+	now := time.Date(2026, time.January, 2, 10, 0, 0, 0, time.UTC)
+	s := newScheduler()
+	s.now = func() time.Time { return now }
+
+	if err := s.putRule("rule-a", "rate(1 minute)", true); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := s.putTarget("rule-a", `{"helper_id":"a"}`); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := s.putRule("rule-b", "rate(1 minute)", false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := s.putTarget("rule-b", `{"helper_id":"b"}`); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := s.putTarget("missing", "{}"); err == nil {
+		t.Error("Expected an error targeting a missing rule")
+	}
+
+	if due := s.due(now.Add(30 * time.Second)); len(due) != 0 {
+		t.Errorf("Expected no due rules before the first run, got %v", due)
+	}
+
+	due := s.due(now.Add(time.Minute))
+	if len(due) != 1 || due[0] != `{"helper_id":"a"}` {
+		t.Errorf("Expected only rule-a to be due, got %v", due)
+	}
+	if due := s.due(now.Add(time.Minute)); len(due) != 0 {
+		t.Errorf("Expected rule-a to advance after firing, got %v", due)
+	}
+
+	s.removeTargets("rule-a")
+	if due := s.due(now.Add(2 * time.Minute)); len(due) != 0 {
+		t.Errorf("Expected a rule without targets to do nothing, got %v", due)
+	}
+
+	s.deleteRule("rule-a")
+	if err := s.setEnabled("rule-a", true); err == nil {
+		t.Error("Expected an error enabling a deleted rule")
+	}
+}
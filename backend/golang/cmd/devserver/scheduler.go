@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	schedulerHandler "github.com/myfusionhelper/api/cmd/handlers/scheduler/handler"
+	"github.com/myfusionhelper/api/internal/batch"
+	"github.com/myfusionhelper/api/internal/breaker"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/delayed"
+	"github.com/myfusionhelper/api/internal/workflow"
+)
+
+// wakeInterval matches the workflow waker's, batch runner's and connection
+// prober's rate(1 minute) schedules
+const wakeInterval = time.Minute
+
+// rule is a local EventBridge schedule rule
+type rule struct {
+	expression string
+	schedule   schedule
+	enabled    bool
+	input      string // the scheduler target's input; rules without one do nothing
+	next       time.Time
+}
+
+// scheduler stands in for EventBridge: it keeps the rules the helpers API
+// manages and invokes the scheduler handler with each rule's target input
+// when it is due. It also runs the workflow waker, the batch runner, the
+// delayed execution releaser and the connection prober.
+type scheduler struct {
+	mu    sync.Mutex
+	rules map[string]*rule
+	now   func() time.Time
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{rules: make(map[string]*rule), now: time.Now}
+}
+
+func (s *scheduler) putRule(name, expression string, enabled bool) error {
+	sched, err := parseSchedule(expression)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rules[name]
+	if !ok {
+		r = &rule{}
+		s.rules[name] = r
+	}
+	r.expression = expression
+	r.schedule = sched
+	r.enabled = enabled
+	r.next = sched.next(s.now())
+	log.Printf("Schedule rule %s: %s (enabled=%t, next run %s)", name, expression, enabled, r.next.Format(time.RFC3339))
+	return nil
+}
+
+func (s *scheduler) putTarget(name, input string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rules[name]
+	if !ok {
+		return fmt.Errorf("rule %s does not exist", name)
+	}
+	r.input = input
+	return nil
+}
+
+func (s *scheduler) setEnabled(name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rules[name]
+	if !ok {
+		return fmt.Errorf("rule %s does not exist", name)
+	}
+	r.enabled = enabled
+	if enabled {
+		r.next = r.schedule.next(s.now())
+	}
+	return nil
+}
+
+func (s *scheduler) removeTargets(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.rules[name]; ok {
+		r.input = ""
+	}
+}
+
+func (s *scheduler) deleteRule(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rules, name)
+}
+
+// due returns the inputs of rules due at now and advances them
+func (s *scheduler) due(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var inputs []string
+	for _, r := range s.rules {
+		if !r.enabled || r.next.IsZero() || now.Before(r.next) {
+			continue
+		}
+		if r.input != "" {
+			inputs = append(inputs, r.input)
+		}
+		r.next = r.schedule.next(now)
+	}
+	return inputs
+}
+
+// run fires due rules, releases due delayed executions, wakes workflow runs,
+// dispatches running batches and probes paused connections until ctx is done
+func (s *scheduler) run(ctx context.Context, stores *database.Stores, batches *batch.Runner, releaser *delayed.Releaser, prober *breaker.Prober) {
+	if err := s.loadHelperSchedules(ctx, stores.Helpers); err != nil {
+		log.Printf("Failed to load helper schedules: %v", err)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	lastWake := time.Time{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := s.now()
+		for _, input := range s.due(now) {
+			go func(input string) {
+				if err := schedulerHandler.Handle(ctx, json.RawMessage(input)); err != nil {
+					log.Printf("Scheduled execution failed: %v", err)
+				}
+			}(input)
+		}
+
+		// The Lambda releaser polls every second too, so one pass per tick
+		// matches its precision
+		if _, err := releaser.ReleaseDue(ctx, 0); err != nil {
+			log.Printf("Failed to release delayed executions: %v", err)
+		}
+
+		if now.Sub(lastWake) >= wakeInterval {
+			lastWake = now
+			if woken, err := workflow.WakeDue(ctx, stores, now); err != nil {
+				log.Printf("Failed to wake due workflow runs: %v", err)
+			} else if woken > 0 {
+				log.Printf("Woke %d workflow run(s)", woken)
+			}
+			if dispatched, err := batches.RunDue(ctx, now); err != nil {
+				log.Printf("Failed to run due batches: %v", err)
+			} else if dispatched > 0 {
+				log.Printf("Dispatched %d batch execution(s)", dispatched)
+			}
+			if closed, err := prober.ProbeDue(ctx); err != nil {
+				log.Printf("Failed to probe connections: %v", err)
+			} else if closed > 0 {
+				log.Printf("Closed %d connection breaker(s)", closed)
+			}
+		}
+	}
+}
+
+// loadHelperSchedules recreates the rules of scheduled helpers. Unlike
+// EventBridge's, local rules are lost when the server stops.
+func (s *scheduler) loadHelperSchedules(ctx context.Context, helpers database.HelperStore) error {
+	scheduled, err := helpers.ListScheduled(ctx)
+	if err != nil {
+		return err
+	}
+
+	loaded := 0
+	for _, helper := range scheduled {
+		if helper.CronExpression == "" {
+			continue
+		}
+		name := scheduleRuleName(helper.HelperID)
+		if err := s.putRule(name, helper.CronExpression, true); err != nil {
+			log.Printf("Skipping schedule of helper %s: %v", helper.HelperID, err)
+			continue
+		}
+		targetInput, _ := json.Marshal(map[string]string{
+			"helper_id":  helper.HelperID,
+			"account_id": helper.AccountID,
+		})
+		_ = s.putTarget(name, string(targetInput))
+		loaded++
+	}
+
+	log.Printf("Loaded %d helper schedule(s)", loaded)
+	return nil
+}
+
+// scheduleRuleName matches the rule names the helpers API gives EventBridge
+func scheduleRuleName(helperID string) string {
+	return fmt.Sprintf("mfh-%s-helper-schedule-%s", os.Getenv("STAGE"), strings.ReplaceAll(helperID, ":", "-"))
+}
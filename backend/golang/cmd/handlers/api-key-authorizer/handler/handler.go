@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/myfusionhelper/api/internal/database"
+	helperResolve "github.com/myfusionhelper/api/internal/helpers"
+)
+
+// Handle authorizes API-key requests to the helper execute endpoints
+func Handle(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		log.Printf("Failed to load AWS config: %v", err)
+		return events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}, nil
+	}
+	return handle(ctx, event, database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg)))
+}
+
+func handle(ctx context.Context, event events.APIGatewayV2CustomAuthorizerV2Request, stores *database.Stores) (events.APIGatewayV2CustomAuthorizerSimpleResponse, error) {
+	denied := events.APIGatewayV2CustomAuthorizerSimpleResponse{IsAuthorized: false}
+
+	// 1. Extract API key from header or path parameter
+	rawKey := extractAPIKey(event)
+	if rawKey == "" {
+		log.Printf("No API key found in request")
+		return denied, nil
+	}
+
+	// 2. Hash and look up by KeyHashIndex
+	keyHash := hashAPIKey(rawKey)
+
+	apiKey, err := stores.APIKeys.GetByHash(ctx, keyHash)
+	if err != nil || apiKey == nil {
+		log.Printf("API key not found")
+		return denied, nil
+	}
+
+	// 3. Validate key status and expiry
+	if apiKey.Status != "active" {
+		log.Printf("API key %s is not active (status: %s)", apiKey.KeyID, apiKey.Status)
+		return denied, nil
+	}
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now().UTC()) {
+		log.Printf("API key %s has expired", apiKey.KeyID)
+		return denied, nil
+	}
+
+	// 4. Validate account subscription
+	account, err := stores.Accounts.GetByID(ctx, apiKey.AccountID)
+	if err != nil || account == nil {
+		log.Printf("Account %s not found", apiKey.AccountID)
+		return denied, nil
+	}
+
+	if account.Status != "active" {
+		log.Printf("Account %s is not active (status: %s)", account.AccountID, account.Status)
+		return denied, nil
+	}
+
+	// 5. Resolve helper from path and verify ownership
+	identifier := extractHelperIdentifier(event)
+	if identifier == "" {
+		log.Printf("No helper identifier found in path")
+		return denied, nil
+	}
+
+	helper, err := helperResolve.ResolveHelper(ctx, stores.Helpers, identifier)
+	if err != nil {
+		log.Printf("Failed to resolve helper %s: %v", identifier, err)
+		return denied, nil
+	}
+
+	if helper.AccountID != apiKey.AccountID {
+		log.Printf("Helper %s does not belong to account %s", helper.HelperID, apiKey.AccountID)
+		return denied, nil
+	}
+
+	if helper.Status != "active" || !helper.Enabled {
+		log.Printf("Helper %s is not active/enabled", helper.HelperID)
+		return denied, nil
+	}
+
+	// 6. Fire-and-forget: update LastUsedAt on the API key
+	go func() {
+		_ = stores.APIKeys.UpdateLastUsed(context.Background(), apiKey.KeyID, time.Now().UTC())
+	}()
+
+	// 7. Return authorized with context for downstream handler
+	return events.APIGatewayV2CustomAuthorizerSimpleResponse{
+		IsAuthorized: true,
+		Context: map[string]interface{}{
+			"accountId":  apiKey.AccountID,
+			"apiKeyId":   apiKey.KeyID,
+			"helperId":   helper.HelperID,
+			"helperType": helper.HelperType,
+			"permissions": strings.Join(apiKey.Permissions, ","),
+		},
+	}, nil
+}
+
+// extractAPIKey gets the API key from x-api-key header or {api_key} path param.
+func extractAPIKey(event events.APIGatewayV2CustomAuthorizerV2Request) string {
+	if key, ok := event.Headers["x-api-key"]; ok && key != "" {
+		return key
+	}
+	if key, ok := event.PathParameters["api_key"]; ok && key != "" {
+		return key
+	}
+	return ""
+}
+
+// extractHelperIdentifier gets the {identifier} path param.
+func extractHelperIdentifier(event events.APIGatewayV2CustomAuthorizerV2Request) string {
+	if id, ok := event.PathParameters["identifier"]; ok && id != "" {
+		return id
+	}
+	return ""
+}
+
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/myfusionhelper/api/internal/batch"
+	"github.com/myfusionhelper/api/internal/database"
+)
+
+func main() {
+	lambda.Start(handleScheduleEvent)
+}
+
+// handleScheduleEvent dispatches the next contacts of every running batch
+func handleScheduleEvent(ctx context.Context) error {
+	log.Println("Batch runner triggered")
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		log.Printf("Failed to load AWS config: %v", err)
+		return err
+	}
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+	snapshots := batch.NewS3Snapshots(s3.NewFromConfig(cfg), os.Getenv("ANALYTICS_BUCKET"))
+
+	dispatched, err := batch.NewRunner(stores, snapshots).RunDue(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to run due batches: %v", err)
+		return err
+	}
+
+	log.Printf("Dispatched %d batch execution(s)", dispatched)
+	return nil
+}
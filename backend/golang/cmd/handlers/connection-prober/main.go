@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/myfusionhelper/api/internal/breaker"
+	"github.com/myfusionhelper/api/internal/database"
+)
+
+func main() {
+	lambda.Start(handleScheduleEvent)
+}
+
+// handleScheduleEvent probes the connections of open circuit breakers that
+// are due and queues the executions they held once a connection recovers
+func handleScheduleEvent(ctx context.Context) error {
+	log.Println("Connection prober triggered")
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		log.Printf("Failed to load AWS config: %v", err)
+		return err
+	}
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+
+	closed, err := breaker.NewProber(stores).ProbeDue(ctx)
+	if err != nil {
+		log.Printf("Failed to probe connections: %v", err)
+		return err
+	}
+
+	log.Printf("Closed %d connection breaker(s)", closed)
+	return nil
+}
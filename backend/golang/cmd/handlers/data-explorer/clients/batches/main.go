@@ -0,0 +1,200 @@
+package batches
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	_ "github.com/marcboeker/go-duckdb"
+
+	"github.com/myfusionhelper/api/internal/apiutil"
+	"github.com/myfusionhelper/api/internal/batch"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/helpers"
+	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
+	"github.com/myfusionhelper/api/internal/services/parquet"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+var analyticsBucket = os.Getenv("ANALYTICS_BUCKET")
+
+// maxSegmentContacts caps the number of contacts in one data explorer batch
+const maxSegmentContacts = 250000
+
+// BatchRequest is the expected POST body for data explorer batches. The
+// filters are the same as a contacts query's.
+type BatchRequest struct {
+	HelperID         string                 `json:"helper_id"`
+	ConnectionID     string                 `json:"connectionId"`
+	FilterConditions []apitypes.DataFilter  `json:"filterConditions"`
+	Search           string                 `json:"search"`
+	Input            map[string]interface{} `json:"input"`
+	MaxPerMinute     int                    `json:"max_per_minute"`
+}
+
+// HandleWithAuth handles POST /data/batches. It snapshots the contacts that
+// match the filters in the synced contacts parquet and starts a batch run of
+// the helper over them.
+func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	if !authCtx.Permissions.CanExecuteHelpers {
+		return authMiddleware.CreateErrorResponse(403, "Permission denied: cannot execute helpers"), nil
+	}
+
+	var req BatchRequest
+	if err := json.Unmarshal([]byte(apiutil.GetBody(event)), &req); err != nil {
+		return authMiddleware.CreateErrorResponse(400, "Invalid request body"), nil
+	}
+	if req.HelperID == "" || req.ConnectionID == "" {
+		return authMiddleware.CreateErrorResponse(400, "helper_id and connectionId are required"), nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		log.Printf("Failed to load AWS config: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+
+	// Verify account owns connection
+	conn, err := stores.Connections.GetByID(ctx, req.ConnectionID)
+	if err != nil || conn == nil {
+		return authMiddleware.CreateErrorResponse(404, "Connection not found"), nil
+	}
+	if conn.AccountID != authCtx.AccountID {
+		return authMiddleware.CreateErrorResponse(403, "Access denied"), nil
+	}
+
+	// The synced record IDs are only meaningful to the helper's own CRM
+	helper, err := helpers.ResolveHelper(ctx, stores.Helpers, req.HelperID)
+	if err != nil || helper == nil || helper.AccountID != authCtx.AccountID {
+		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
+	}
+	if helper.ConnectionID != req.ConnectionID {
+		return authMiddleware.CreateErrorResponse(400, "Helper does not use this connection"), nil
+	}
+
+	s3Client := s3.NewFromConfig(cfg)
+	schemaKey := authCtx.AccountID + "/" + req.ConnectionID + "/contacts/schema.json"
+	schema, err := parquet.ReadSchema(ctx, s3Client, analyticsBucket, schemaKey)
+	if err != nil {
+		log.Printf("Failed to read schema: %v", err)
+		return authMiddleware.CreateErrorResponse(404, "No contact data available for this connection"), nil
+	}
+
+	var stringColumns []string
+	for colName, colInfo := range schema.Columns {
+		if colInfo.Type == "string" {
+			stringColumns = append(stringColumns, colName)
+		}
+	}
+	for _, f := range req.FilterConditions {
+		if _, ok := schema.Columns[f.Column]; f.Column != "" && !ok {
+			return authMiddleware.CreateErrorResponse(400, fmt.Sprintf("Unknown column: %s", f.Column)), nil
+		}
+	}
+
+	parquetPath := fmt.Sprintf("s3://%s/%s/%s/contacts/data.parquet", analyticsBucket, authCtx.AccountID, req.ConnectionID)
+	contactIDs, err := segmentContacts(ctx, parquetPath, req, stringColumns)
+	if err != nil {
+		log.Printf("Segment query failed: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Query execution error"), nil
+	}
+	if len(contactIDs) == 0 {
+		return authMiddleware.CreateErrorResponse(400, "No contacts match the filters"), nil
+	}
+	if len(contactIDs) > maxSegmentContacts {
+		return authMiddleware.CreateErrorResponse(400, fmt.Sprintf("Segment matches more than %d contacts", maxSegmentContacts)), nil
+	}
+
+	batchID := batch.NewID()
+	snapshotKey := batch.SnapshotKey(authCtx.AccountID, batchID)
+	if err := batch.NewS3Snapshots(s3Client, analyticsBucket).Write(ctx, snapshotKey, contactIDs); err != nil {
+		log.Printf("Failed to write segment snapshot: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to save segment"), nil
+	}
+
+	log.Printf("Create data explorer batch: helper=%s contacts=%d account=%s", helper.HelperID, len(contactIDs), authCtx.AccountID)
+
+	created, err := batch.Create(ctx, stores, batch.Request{
+		BatchID:   batchID,
+		AccountID: authCtx.AccountID,
+		UserID:    authCtx.UserID,
+		HelperID:  helper.HelperID,
+		Source: apitypes.BatchSource{
+			Type:        batch.SourceDataExplorer,
+			DataFilters: req.FilterConditions,
+			Search:      req.Search,
+			SnapshotKey: snapshotKey,
+		},
+		Input:        req.Input,
+		MaxPerMinute: req.MaxPerMinute,
+		Total:        len(contactIDs),
+	}, time.Now())
+	switch {
+	case errors.Is(err, batch.ErrHelperNotFound):
+		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
+	case errors.Is(err, batch.ErrInvalidRequest):
+		return authMiddleware.CreateErrorResponse(400, err.Error()), nil
+	case err != nil:
+		log.Printf("Failed to create batch: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to create batch"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(201, "Batch created", created), nil
+}
+
+// segmentContacts returns the CRM IDs of the synced contacts matching the
+// request's filters, one more than maxSegmentContacts at most
+func segmentContacts(ctx context.Context, parquetPath string, req BatchRequest, stringColumns []string) ([]string, error) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DuckDB: %w", err)
+	}
+	defer db.Close()
+
+	setupStatements := []string{
+		"INSTALL httpfs",
+		"LOAD httpfs",
+		"SET s3_region='us-west-2'",
+		"SET s3_use_ssl=true",
+	}
+	for _, stmt := range setupStatements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("DuckDB setup failed (%s): %w", stmt, err)
+		}
+	}
+
+	whereClause, params := parquet.BuildWhereClause(req.FilterConditions, req.Search, stringColumns)
+	query := fmt.Sprintf("SELECT DISTINCT \"_record_id\" FROM read_parquet('%s')", parquetPath)
+	if whereClause != "" {
+		query += " WHERE " + whereClause
+	}
+	query += fmt.Sprintf(" ORDER BY \"_record_id\" LIMIT %d", maxSegmentContacts+1)
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id sql.NullString
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if id.Valid && id.String != "" {
+			ids = append(ids, id.String)
+		}
+	}
+	return ids, rows.Err()
+}
@@ -44,12 +44,7 @@ type QueryRequest struct {
 }
 
 // FilterCondition represents a single filter clause.
-type FilterCondition struct {
-	Column   string      `json:"column"`
-	Operator string      `json:"operator"`
-	Value    interface{} `json:"value"`
-	Value2   interface{} `json:"value2"`
-}
+type FilterCondition = apitypes.DataFilter
 
 // SchemaColumn is returned in the response for the frontend to understand column types.
 type SchemaColumn struct {
@@ -184,7 +179,7 @@ func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, a
 	}
 
 	// Build WHERE clause
-	whereClause, params := buildWhereClause(req, stringColumns)
+	whereClause, params := parquet.BuildWhereClause(req.FilterConditions, req.Search, stringColumns)
 
 	// Count query
 	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM read_parquet('%s')", parquetPath)
@@ -240,70 +235,6 @@ func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, a
 	}), nil
 }
 
-// buildWhereClause constructs a parameterised WHERE clause from the request filters and search term.
-func buildWhereClause(req QueryRequest, stringColumns []string) (string, []interface{}) {
-	var clauses []string
-	var params []interface{}
-
-	for _, f := range req.FilterConditions {
-		if f.Column == "" {
-			continue
-		}
-		col := fmt.Sprintf("\"%s\"", f.Column)
-
-		switch f.Operator {
-		case "eq":
-			clauses = append(clauses, col+" = ?")
-			params = append(params, f.Value)
-		case "neq":
-			clauses = append(clauses, col+" <> ?")
-			params = append(params, f.Value)
-		case "contains":
-			clauses = append(clauses, col+" ILIKE '%' || ? || '%'")
-			params = append(params, f.Value)
-		case "startswith":
-			clauses = append(clauses, col+" ILIKE ? || '%'")
-			params = append(params, f.Value)
-		case "gt":
-			clauses = append(clauses, col+" > ?")
-			params = append(params, f.Value)
-		case "gte":
-			clauses = append(clauses, col+" >= ?")
-			params = append(params, f.Value)
-		case "lt":
-			clauses = append(clauses, col+" < ?")
-			params = append(params, f.Value)
-		case "lte":
-			clauses = append(clauses, col+" <= ?")
-			params = append(params, f.Value)
-		case "between":
-			clauses = append(clauses, col+" BETWEEN ? AND ?")
-			params = append(params, f.Value, f.Value2)
-		case "in":
-			if values, ok := f.Value.([]interface{}); ok && len(values) > 0 {
-				placeholders := make([]string, len(values))
-				for i, v := range values {
-					placeholders[i] = "?"
-					params = append(params, v)
-				}
-				clauses = append(clauses, col+" IN ("+strings.Join(placeholders, ", ")+")")
-			}
-		}
-	}
-
-	// Global search across all string columns
-	if req.Search != "" && len(stringColumns) > 0 {
-		var searchParts []string
-		for _, sc := range stringColumns {
-			searchParts = append(searchParts, fmt.Sprintf("\"%s\" ILIKE '%%' || ? || '%%'", sc))
-			params = append(params, req.Search)
-		}
-		clauses = append(clauses, "("+strings.Join(searchParts, " OR ")+")")
-	}
-
-	return strings.Join(clauses, " AND "), params
-}
-
 // scanRows reads all rows from the result set into a slice of maps.
 func scanRows(rows *sql.Rows) ([]map[string]interface{}, []string, error) {
 	cols, err := rows.Columns()
@@ -19,6 +19,7 @@ import (
 
 	"github.com/myfusionhelper/api/internal/connectors"
 	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
 	"github.com/myfusionhelper/api/internal/services/parquet"
 
 	// Register all connectors via init()
@@ -77,7 +78,7 @@ func processSync(ctx context.Context, db *dynamodb.Client, s3Client *s3.Client,
 	updateSyncStatus(ctx, db, msg.ConnectionID, "syncing", nil, nil)
 
 	// Load the CRM connector with field translation
-	connector, err := loader.LoadConnectorWithTranslation(ctx, db, msg.ConnectionID, msg.AccountID)
+	connector, err := loader.LoadConnectorWithTranslation(ctx, database.NewDynamoStoresFromEnv(db), msg.ConnectionID, msg.AccountID)
 	if err != nil {
 		return fmt.Errorf("failed to load connector: %w", err)
 	}
@@ -141,28 +142,18 @@ func processSync(ctx context.Context, db *dynamodb.Client, s3Client *s3.Client,
 
 func syncContacts(ctx context.Context, connector connectors.CRMConnector, s3Client *s3.Client, accountID, connectionID string) (int, error) {
 	var allContacts []connectors.NormalizedContact
-	cursor := ""
 
-	for {
-		opts := connectors.QueryOptions{
-			Limit:  200,
-			Cursor: cursor,
+	err := connectors.IterateContacts(ctx, connector, connectors.QueryOptions{Limit: 200}, func(contact connectors.NormalizedContact) error {
+		allContacts = append(allContacts, contact)
+		if len(allContacts)%1000 == 0 {
+			log.Printf("Fetched %d contacts so far", len(allContacts))
 		}
-
-		result, err := connector.GetContacts(ctx, opts)
-		if err != nil {
-			return 0, fmt.Errorf("failed to get contacts page: %w", err)
-		}
-
-		allContacts = append(allContacts, result.Contacts...)
-		log.Printf("Fetched %d contacts (total so far: %d, has_more: %v)",
-			len(result.Contacts), len(allContacts), result.HasMore)
-
-		if !result.HasMore || result.NextCursor == "" {
-			break
-		}
-		cursor = result.NextCursor
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list contacts: %w", err)
 	}
+	log.Printf("Fetched %d contacts", len(allContacts))
 
 	// Write contacts parquet file to S3 (writer also builds SchemaInfo)
 	s3Key := fmt.Sprintf("%s/%s/contacts/data.parquet", accountID, connectionID)
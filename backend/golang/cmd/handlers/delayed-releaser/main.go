@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/delayed"
+)
+
+// releaseWindow matches the rate(1 minute) schedule, so one invocation
+// polls until the next one starts
+const releaseWindow = time.Minute
+
+func main() {
+	lambda.Start(handleScheduleEvent)
+}
+
+// handleScheduleEvent releases delayed executions as they fall due over the
+// next minute
+func handleScheduleEvent(ctx context.Context) error {
+	log.Println("Delayed execution releaser triggered")
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		log.Printf("Failed to load AWS config: %v", err)
+		return err
+	}
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+
+	released, err := delayed.NewReleaser(stores).ReleaseDue(ctx, releaseWindow)
+	if err != nil {
+		log.Printf("Failed to release delayed executions: %v", err)
+		return err
+	}
+
+	log.Printf("Released %d delayed execution(s)", released)
+	return nil
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/worker"
 )
 
 var queueURL = os.Getenv("HELPER_EXECUTION_QUEUE_URL")
@@ -34,7 +37,7 @@ func handler(ctx context.Context, event events.DynamoDBEvent) error {
 
 		img := record.Change.NewImage
 		status, ok := img["status"]
-		if !ok || status.DataType() != events.DataTypeString || status.String() != "queued" {
+		if !ok || status.DataType() != events.DataTypeString || status.String() != execution.StatusQueued {
 			continue
 		}
 
@@ -65,11 +68,9 @@ func handler(ctx context.Context, event events.DynamoDBEvent) error {
 			continue
 		}
 
-		// MessageGroupId = helper_id:contact_id for per-helper-per-contact ordering
-		groupID := helperID
-		if contactID != "" {
-			groupID = helperID + ":" + contactID
-		}
+		// Group by account and contact, so a contact's executions run in order
+		// whichever helper they belong to
+		groupID := worker.MessageGroupID(img)
 
 		_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
 			QueueUrl:               aws.String(queueURL),
@@ -18,6 +18,8 @@ import (
 
 	"github.com/myfusionhelper/api/internal/connectors"
 	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
 	"github.com/myfusionhelper/api/internal/google"
 	helperEngine "github.com/myfusionhelper/api/internal/helpers"
 	stripeusage "github.com/myfusionhelper/api/internal/stripe"
@@ -80,8 +82,11 @@ func handleSQSEvent(ctx context.Context, event events.SQSEvent) error {
 
 		log.Printf("Processing execution %s (helper: %s, type: %s)", job.ExecutionID, job.HelperID, job.HelperType)
 
-		// Update execution status to running
-		updateExecutionStatus(ctx, db, job.ExecutionID, "running", "", 0)
+		// Update execution status to running; cancelled and finished
+		// executions are not run
+		if !transitionExecution(ctx, db, job.ExecutionID, execution.StatusRunning, "") {
+			continue
+		}
 
 		// Execute the helper
 		result, execErr := processJob(ctx, db, job)
@@ -90,20 +95,20 @@ func handleSQSEvent(ctx context.Context, event events.SQSEvent) error {
 		now := time.Now().UTC()
 		if execErr != nil {
 			log.Printf("Execution %s failed: %v", job.ExecutionID, execErr)
-			updateExecutionResult(ctx, db, job.ExecutionID, "failed", execErr.Error(), result, &now)
+			updateExecutionResult(ctx, db, job.ExecutionID, execution.StatusFailed, execErr.Error(), result, &now)
 			sendFailureNotification(ctx, sqsClient, job, execErr.Error())
 		} else if result != nil && result.Success {
 			log.Printf("Execution %s completed successfully", job.ExecutionID)
-			updateExecutionResult(ctx, db, job.ExecutionID, "completed", "", result, &now)
+			updateExecutionResult(ctx, db, job.ExecutionID, execution.StatusSucceeded, "", result, &now)
 			// Report usage to Stripe (best-effort, non-blocking)
-			go stripeusage.ReportExecution(ctx, db, job.ExecutionID, job.AccountID, now.Unix())
+			go stripeusage.ReportExecution(ctx, database.NewDynamoStoresFromEnv(db), job.ExecutionID, job.AccountID, now.Unix())
 		} else {
 			errMsg := "execution returned unsuccessful result"
 			if result != nil && result.Error != "" {
 				errMsg = result.Error
 			}
 			log.Printf("Execution %s completed with errors: %s", job.ExecutionID, errMsg)
-			updateExecutionResult(ctx, db, job.ExecutionID, "failed", errMsg, result, &now)
+			updateExecutionResult(ctx, db, job.ExecutionID, execution.StatusFailed, errMsg, result, &now)
 			sendFailureNotification(ctx, sqsClient, job, errMsg)
 		}
 
@@ -115,18 +120,20 @@ func handleSQSEvent(ctx context.Context, event events.SQSEvent) error {
 }
 
 func processJob(ctx context.Context, db *dynamodb.Client, job HelperExecutionJob) (*helperEngine.ExecutionResult, error) {
+	stores := database.NewDynamoStoresFromEnv(db)
+
 	// Load CRM connector if connection ID is specified
 	var connector connectors.CRMConnector
 	if job.ConnectionID != "" {
 		var err error
-		connector, err = loader.LoadConnectorWithTranslation(ctx, db, job.ConnectionID, job.AccountID)
+		connector, err = loader.LoadConnectorWithTranslation(ctx, stores, job.ConnectionID, job.AccountID)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	// Pre-load service connection credentials (for non-CRM integrations like Zoom, Trello, etc.)
-	serviceAuths := loadServiceAuths(ctx, db, job.Config, job.AccountID)
+	serviceAuths := loadServiceAuths(ctx, stores, job.Config, job.AccountID)
 
 	// Execute via the helper engine
 	executor := helperEngine.NewExecutor()
@@ -140,6 +147,7 @@ func processJob(ctx context.Context, db *dynamodb.Client, job HelperExecutionJob
 		ConnectionID: job.ConnectionID,
 		ServiceAuths: serviceAuths,
 		APIKey:       job.APIKey,
+		Stores:       stores,
 	}
 
 	result, err := executor.Execute(ctx, execReq, connector)
@@ -154,7 +162,7 @@ func processJob(ctx context.Context, db *dynamodb.Client, job HelperExecutionJob
 
 // loadServiceAuths reads service_connection_ids from helper config and pre-loads
 // auth credentials for each service. Returns a map keyed by platform slug.
-func loadServiceAuths(ctx context.Context, db *dynamodb.Client, config map[string]interface{}, accountID string) map[string]*connectors.ConnectorConfig {
+func loadServiceAuths(ctx context.Context, stores *database.Stores, config map[string]interface{}, accountID string) map[string]*connectors.ConnectorConfig {
 	raw, ok := config["service_connection_ids"]
 	if !ok {
 		return nil
@@ -172,7 +180,7 @@ func loadServiceAuths(ctx context.Context, db *dynamodb.Client, config map[strin
 			continue
 		}
 
-		auth, err := loader.LoadServiceAuth(ctx, db, connID, accountID)
+		auth, err := loader.LoadServiceAuth(ctx, stores, connID, accountID)
 		if err != nil {
 			log.Printf("Warning: failed to load service auth for %s (connection %s): %v", slug, connID, err)
 			continue
@@ -186,41 +194,24 @@ func loadServiceAuths(ctx context.Context, db *dynamodb.Client, config map[strin
 	return auths
 }
 
-func updateExecutionStatus(ctx context.Context, db *dynamodb.Client, executionID, status, errorMsg string, durationMs int64) {
-	updateExpr := "SET #s = :status"
-	exprNames := map[string]string{"#s": "status"}
-	exprValues := map[string]ddbtypes.AttributeValue{
-		":status": &ddbtypes.AttributeValueMemberS{Value: status},
-	}
-
-	if errorMsg != "" {
-		updateExpr += ", error_message = :error"
-		exprValues[":error"] = &ddbtypes.AttributeValueMemberS{Value: errorMsg}
-	}
-	if durationMs > 0 {
-		updateExpr += ", duration_ms = :duration"
-		exprValues[":duration"] = &ddbtypes.AttributeValueMemberN{Value: fmt.Sprintf("%d", durationMs)}
-	}
-
-	_, err := db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(executionsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"execution_id": &ddbtypes.AttributeValueMemberS{Value: executionID},
-		},
-		UpdateExpression:          aws.String(updateExpr),
-		ExpressionAttributeNames:  exprNames,
-		ExpressionAttributeValues: exprValues,
-	})
-	if err != nil {
+// transitionExecution moves the execution to status through the state
+// machine and reports whether it did
+func transitionExecution(ctx context.Context, db *dynamodb.Client, executionID, status, reason string) bool {
+	executions := database.NewExecutionsRepository(db, executionsTable)
+	if err := execution.Transition(ctx, executions, executionID, status, reason, time.Now().UTC()); err != nil {
 		log.Printf("Failed to update execution status: %v", err)
+		return false
 	}
+	return true
 }
 
 func updateExecutionResult(ctx context.Context, db *dynamodb.Client, executionID, status, errorMsg string, result *helperEngine.ExecutionResult, completedAt *time.Time) {
-	updateExpr := "SET #s = :status, completed_at = :completed_at"
-	exprNames := map[string]string{"#s": "status"}
+	if !transitionExecution(ctx, db, executionID, status, "") {
+		return
+	}
+
+	updateExpr := "SET completed_at = :completed_at"
 	exprValues := map[string]ddbtypes.AttributeValue{
-		":status":       &ddbtypes.AttributeValueMemberS{Value: status},
 		":completed_at": &ddbtypes.AttributeValueMemberS{Value: completedAt.Format(time.RFC3339)},
 	}
 
@@ -248,7 +239,6 @@ func updateExecutionResult(ctx context.Context, db *dynamodb.Client, executionID
 			"execution_id": &ddbtypes.AttributeValueMemberS{Value: executionID},
 		},
 		UpdateExpression:          aws.String(updateExpr),
-		ExpressionAttributeNames:  exprNames,
 		ExpressionAttributeValues: exprValues,
 	})
 	if err != nil {
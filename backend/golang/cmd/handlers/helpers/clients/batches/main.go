@@ -0,0 +1,234 @@
+package batches
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/myfusionhelper/api/internal/apiutil"
+	"github.com/myfusionhelper/api/internal/batch"
+	"github.com/myfusionhelper/api/internal/database"
+	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+// CreateRequest is the POST /batches body
+type CreateRequest struct {
+	HelperID     string                 `json:"helper_id"`
+	Source       apitypes.BatchSource   `json:"source"`
+	Input        map[string]interface{} `json:"input"`
+	MaxPerMinute int                    `json:"max_per_minute"`
+}
+
+// HandleWithAuth routes batch requests
+func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	return handle(ctx, event, authCtx, database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg)))
+}
+
+func handle(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	path := event.RequestContext.HTTP.Path
+	method := event.RequestContext.HTTP.Method
+
+	switch {
+	case path == "/batches" && method == "POST":
+		return createBatch(ctx, event, authCtx, stores)
+	case path == "/batches" && method == "GET":
+		return listBatches(ctx, event, authCtx, stores)
+	case strings.HasPrefix(path, "/batches/") && strings.HasSuffix(path, "/items") && method == "GET":
+		return listItems(ctx, event, authCtx, stores)
+	case strings.HasPrefix(path, "/batches/") && method == "POST":
+		return changeBatch(ctx, event, authCtx, stores)
+	case strings.HasPrefix(path, "/batches/") && method == "GET":
+		return getBatch(ctx, event, authCtx, stores)
+	default:
+		return authMiddleware.CreateErrorResponse(404, "Not Found"), nil
+	}
+}
+
+func createBatch(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	if !authCtx.Permissions.CanExecuteHelpers {
+		return authMiddleware.CreateErrorResponse(403, "Permission denied: cannot execute helpers"), nil
+	}
+
+	var req CreateRequest
+	if err := json.Unmarshal([]byte(apiutil.GetBody(event)), &req); err != nil {
+		return authMiddleware.CreateErrorResponse(400, "Invalid request body"), nil
+	}
+	if req.HelperID == "" {
+		return authMiddleware.CreateErrorResponse(400, "helper_id is required"), nil
+	}
+	if req.Source.Type == batch.SourceDataExplorer {
+		return authMiddleware.CreateErrorResponse(400, "Data explorer segments are created with POST /data/batches"), nil
+	}
+
+	log.Printf("Create batch: helper=%s source=%s account=%s", req.HelperID, req.Source.Type, authCtx.AccountID)
+
+	created, err := batch.Create(ctx, stores, batch.Request{
+		AccountID:    authCtx.AccountID,
+		UserID:       authCtx.UserID,
+		HelperID:     req.HelperID,
+		Source:       req.Source,
+		Input:        req.Input,
+		MaxPerMinute: req.MaxPerMinute,
+	}, time.Now())
+	switch {
+	case errors.Is(err, batch.ErrHelperNotFound):
+		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
+	case errors.Is(err, batch.ErrInvalidRequest):
+		return authMiddleware.CreateErrorResponse(400, err.Error()), nil
+	case err != nil:
+		log.Printf("Failed to create batch: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to create batch"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(201, "Batch created", created), nil
+}
+
+func listBatches(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	log.Printf("List batches for account: %s", authCtx.AccountID)
+
+	limit, cursor, err := pageParams(event)
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(400, "Invalid next_token"), nil
+	}
+
+	batches, next, err := stores.Batches.ListByAccount(ctx, authCtx.AccountID, limit, cursor)
+	if err != nil {
+		log.Printf("Failed to list batches: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to list batches"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, "Batches retrieved successfully", map[string]interface{}{
+		"batches":     batches,
+		"total_count": len(batches),
+		"next_token":  encodePageToken(next),
+		"has_more":    next != "",
+	}), nil
+}
+
+func getBatch(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	batchID := batchIDFromPath(event.RequestContext.HTTP.Path)
+	if batchID == "" {
+		return authMiddleware.CreateErrorResponse(400, "Batch ID is required"), nil
+	}
+
+	found, err := batch.Get(ctx, stores.Batches, authCtx.AccountID, batchID)
+	if errors.Is(err, batch.ErrNotFound) {
+		return authMiddleware.CreateErrorResponse(404, "Batch not found"), nil
+	} else if err != nil {
+		log.Printf("Failed to get batch %s: %v", batchID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to get batch"), nil
+	}
+	return authMiddleware.CreateSuccessResponse(200, "Batch retrieved successfully", found), nil
+}
+
+// listItems returns the per-contact report of a batch, optionally filtered by
+// item status (queued, completed or failed)
+func listItems(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	batchID := batchIDFromPath(event.RequestContext.HTTP.Path)
+	if batchID == "" {
+		return authMiddleware.CreateErrorResponse(400, "Batch ID is required"), nil
+	}
+	if _, err := batch.Get(ctx, stores.Batches, authCtx.AccountID, batchID); errors.Is(err, batch.ErrNotFound) {
+		return authMiddleware.CreateErrorResponse(404, "Batch not found"), nil
+	} else if err != nil {
+		log.Printf("Failed to get batch %s: %v", batchID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to get batch"), nil
+	}
+
+	limit, cursor, err := pageParams(event)
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(400, "Invalid next_token"), nil
+	}
+	items, next, err := stores.Batches.ListItems(ctx, batchID, event.QueryStringParameters["status"], limit, cursor)
+	if err != nil {
+		log.Printf("Failed to list batch %s items: %v", batchID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to list batch items"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, "Batch items retrieved successfully", map[string]interface{}{
+		"items":       items,
+		"total_count": len(items),
+		"next_token":  encodePageToken(next),
+		"has_more":    next != "",
+	}), nil
+}
+
+// changeBatch handles POST /batches/{batch_id}/pause, /resume and /cancel
+func changeBatch(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	if !authCtx.Permissions.CanExecuteHelpers {
+		return authMiddleware.CreateErrorResponse(403, "Permission denied: cannot execute helpers"), nil
+	}
+
+	path := event.RequestContext.HTTP.Path
+	batchID := batchIDFromPath(path)
+	if batchID == "" {
+		return authMiddleware.CreateErrorResponse(400, "Batch ID is required"), nil
+	}
+
+	var change func(context.Context, database.BatchStore, string, string) (*apitypes.Batch, error)
+	var message string
+	switch path[strings.LastIndex(path, "/"):] {
+	case "/pause":
+		change, message = batch.Pause, "Batch paused"
+	case "/resume":
+		change, message = batch.Resume, "Batch resumed"
+	case "/cancel":
+		change, message = batch.Cancel, "Batch cancelled"
+	default:
+		return authMiddleware.CreateErrorResponse(404, "Not Found"), nil
+	}
+
+	log.Printf("%s: %s for account: %s", message, batchID, authCtx.AccountID)
+
+	updated, err := change(ctx, stores.Batches, authCtx.AccountID, batchID)
+	switch {
+	case errors.Is(err, batch.ErrNotFound):
+		return authMiddleware.CreateErrorResponse(404, "Batch not found"), nil
+	case errors.Is(err, batch.ErrInvalidTransition):
+		return authMiddleware.CreateErrorResponse(409, err.Error()), nil
+	case err != nil:
+		log.Printf("Failed to update batch %s: %v", batchID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to update batch"), nil
+	}
+	return authMiddleware.CreateSuccessResponse(200, message, updated), nil
+}
+
+// batchIDFromPath extracts the ID from /batches/{batch_id}[/action]
+func batchIDFromPath(path string) string {
+	return strings.Split(strings.TrimPrefix(path, "/batches/"), "/")[0]
+}
+
+// pageParams parses limit (default 20, max 100) and next_token
+func pageParams(event events.APIGatewayV2HTTPRequest) (int, string, error) {
+	limit := 20
+	if l, err := strconv.Atoi(event.QueryStringParameters["limit"]); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	token := event.QueryStringParameters["next_token"]
+	if token == "" {
+		return limit, "", nil
+	}
+	cursor, err := base64.URLEncoding.DecodeString(token)
+	return limit, string(cursor), err
+}
+
+func encodePageToken(cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString([]byte(cursor))
+}
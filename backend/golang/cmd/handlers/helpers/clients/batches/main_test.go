@@ -0,0 +1,120 @@
+package batches
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+func newTestStores(t *testing.T) *database.Stores {
+	t.Helper()
+	stores := memory.NewStores()
+	stores.Helpers.Create(context.Background(), &types.Helper{
+		HelperID:   "helper:123",
+		AccountID:  "account-123",
+		HelperType: "format_it",
+		Enabled:    true,
+	})
+	return stores
+}
+
+func testAuth(accountID string) *types.AuthContext {
+	return &types.AuthContext{
+		UserID:      "user-1",
+		AccountID:   accountID,
+		Permissions: types.Permissions{CanExecuteHelpers: true},
+	}
+}
+
+func request(method, path, body string) events.APIGatewayV2HTTPRequest {
+	event := events.APIGatewayV2HTTPRequest{Body: body}
+	event.RequestContext.HTTP.Method = method
+	event.RequestContext.HTTP.Path = path
+	return event
+}
+
+func decode(t *testing.T, response events.APIGatewayV2HTTPResponse, data interface{}) {
+	t.Helper()
+	body := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %v", err)
+	}
+}
+
+func TestHandle_CreateAndControl(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	auth := testAuth("account-123")
+
+	response, _ := handle(ctx, request("POST", "/batches",
+		`{"helper_id":"helper:123","source":{"type":"contact_ids","contact_ids":["1","2","3"]},"max_per_minute":10}`), auth, stores)
+	if response.StatusCode != 201 {
+		t.Fatalf("Expected status 201, got %d: %s", response.StatusCode, response.Body)
+	}
+	var created types.Batch
+	decode(t, response, &created)
+	if created.BatchID == "" || created.Status != "running" || created.Total != 3 || created.RatePerMinute != 10 {
+		t.Errorf("Expected a running batch of 3 at 10/min, got %+v", created)
+	}
+
+	response, _ = handle(ctx, request("POST", "/batches/"+created.BatchID+"/pause", ""), auth, stores)
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", response.StatusCode, response.Body)
+	}
+	response, _ = handle(ctx, request("POST", "/batches/"+created.BatchID+"/pause", ""), auth, stores)
+	if response.StatusCode != 409 {
+		t.Errorf("Expected status 409 pausing a paused batch, got %d", response.StatusCode)
+	}
+
+	response, _ = handle(ctx, request("GET", "/batches/"+created.BatchID, ""), testAuth("account-999"), stores)
+	if response.StatusCode != 404 {
+		t.Errorf("Expected status 404 for another account, got %d", response.StatusCode)
+	}
+
+	response, _ = handle(ctx, request("GET", "/batches", ""), auth, stores)
+	var list struct {
+		Batches []types.Batch `json:"batches"`
+	}
+	decode(t, response, &list)
+	if len(list.Batches) != 1 || list.Batches[0].Status != "paused" {
+		t.Errorf("Expected the paused batch listed, got %+v", list.Batches)
+	}
+
+	response, _ = handle(ctx, request("GET", "/batches/"+created.BatchID+"/items", ""), auth, stores)
+	if response.StatusCode != 200 {
+		t.Errorf("Expected status 200 for items, got %d: %s", response.StatusCode, response.Body)
+	}
+}
+
+func TestHandle_CreateRejects(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+
+	tests := []struct {
+		name   string
+		auth   *types.AuthContext
+		body   string
+		expect int
+	}{
+		{"no permission", &types.AuthContext{AccountID: "account-123"}, `{"helper_id":"helper:123","source":{"type":"tag","tag_id":"1"}}`, 403},
+		{"missing helper", testAuth("account-123"), `{"source":{"type":"tag","tag_id":"1"}}`, 400},
+		{"unknown helper", testAuth("account-123"), `{"helper_id":"helper:404","source":{"type":"contact_ids","contact_ids":["1"]}}`, 404},
+		{"empty source", testAuth("account-123"), `{"helper_id":"helper:123","source":{"type":"contact_ids"}}`, 400},
+		{"data explorer", testAuth("account-123"), `{"helper_id":"helper:123","source":{"type":"data_explorer"}}`, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, _ := handle(ctx, request("POST", "/batches", tt.body), tt.auth, stores)
+			if response.StatusCode != tt.expect {
+				t.Errorf("Expected status %d, got %d: %s", tt.expect, response.StatusCode, response.Body)
+			}
+		})
+	}
+}
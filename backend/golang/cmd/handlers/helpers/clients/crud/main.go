@@ -3,6 +3,7 @@ package crud
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -12,26 +13,24 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
 	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
 	lambdasvc "github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/google/uuid"
 	"github.com/myfusionhelper/api/internal/apiutil"
 	"github.com/myfusionhelper/api/internal/billing"
+	"github.com/myfusionhelper/api/internal/configversion"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/delayed"
+	"github.com/myfusionhelper/api/internal/execution"
 	helperEngine "github.com/myfusionhelper/api/internal/helpers"
 	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
 	"github.com/myfusionhelper/api/internal/nanoid"
 	apitypes "github.com/myfusionhelper/api/internal/types"
 )
 
-var (
-	helpersTable        = os.Getenv("HELPERS_TABLE")
-	executionsTable     = os.Getenv("EXECUTIONS_TABLE")
-	schedulerFunctionARN = os.Getenv("SCHEDULER_FUNCTION_ARN")
-)
+var schedulerFunctionARN = os.Getenv("SCHEDULER_FUNCTION_ARN")
 
 type CreateHelperRequest struct {
 	Name         string                 `json:"name"`
@@ -55,62 +54,56 @@ type UpdateHelperRequest struct {
 type ExecuteHelperRequest struct {
 	ContactID string                 `json:"contact_id"`
 	Input     map[string]interface{} `json:"input"`
+	DryRun    bool                   `json:"dry_run"`
+	RunAt     string                 `json:"run_at"`
+	Delay     interface{}            `json:"delay"`
 }
 
 // HandleWithAuth routes to the appropriate operation based on path and method
 func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	return handle(ctx, event, authCtx, database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg)), cfg)
+}
+
+// handle serves the request from stores. cfg is only used for the
+// EventBridge rules of helper schedules.
+func handle(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores, cfg aws.Config) (events.APIGatewayV2HTTPResponse, error) {
 	path := event.RequestContext.HTTP.Path
 	method := event.RequestContext.HTTP.Method
 
 	switch {
 	case path == "/helpers" && method == "GET":
-		return listHelpers(ctx, event, authCtx)
+		return listHelpers(ctx, event, authCtx, stores)
 	case path == "/helpers" && method == "POST":
-		return createHelper(ctx, event, authCtx)
+		return createHelper(ctx, event, authCtx, stores)
 	case strings.HasSuffix(path, "/execute") && method == "POST":
-		return executeHelper(ctx, event, authCtx)
+		return executeHelper(ctx, event, authCtx, stores)
 	case strings.HasPrefix(path, "/helpers/") && method == "GET":
-		return getHelper(ctx, event, authCtx)
+		return getHelper(ctx, event, authCtx, stores)
 	case strings.HasPrefix(path, "/helpers/") && method == "PUT":
-		return updateHelper(ctx, event, authCtx)
+		return updateHelper(ctx, event, authCtx, stores, cfg)
 	case strings.HasPrefix(path, "/helpers/") && method == "DELETE":
-		return deleteHelper(ctx, event, authCtx)
+		return deleteHelper(ctx, event, authCtx, stores, cfg)
 	default:
 		return authMiddleware.CreateErrorResponse(404, "Not Found"), nil
 	}
 }
 
-func listHelpers(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+func listHelpers(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
 	log.Printf("List helpers for account: %s", authCtx.AccountID)
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
-	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
-	}
-	db := dynamodb.NewFromConfig(cfg)
-
-	result, err := db.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String(helpersTable),
-		IndexName:              aws.String("AccountIdIndex"),
-		KeyConditionExpression: aws.String("account_id = :account_id"),
-		FilterExpression:       aws.String("#s <> :deleted_status"),
-		ExpressionAttributeNames: map[string]string{
-			"#s": "status",
-		},
-		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
-			":account_id":      &ddbtypes.AttributeValueMemberS{Value: authCtx.AccountID},
-			":deleted_status":  &ddbtypes.AttributeValueMemberS{Value: "deleted"},
-		},
-	})
+	all, err := stores.Helpers.ListByAccount(ctx, authCtx.AccountID)
 	if err != nil {
 		log.Printf("Failed to query helpers: %v", err)
 		return authMiddleware.CreateErrorResponse(500, "Failed to list helpers"), nil
 	}
 
 	var helpers []map[string]interface{}
-	for _, item := range result.Items {
-		var helper apitypes.Helper
-		if err := attributevalue.UnmarshalMap(item, &helper); err != nil {
+	for _, helper := range all {
+		if helper.Status == "deleted" {
 			continue
 		}
 		helpers = append(helpers, map[string]interface{}{
@@ -122,6 +115,7 @@ func listHelpers(ctx context.Context, event events.APIGatewayV2HTTPRequest, auth
 			"category":         helper.Category,
 			"status":           helper.Status,
 			"enabled":          helper.Enabled,
+			"config_version":   helper.ConfigVersion,
 			"execution_count":  helper.ExecutionCount,
 			"last_executed_at": helper.LastExecutedAt,
 			"created_at":       helper.CreatedAt,
@@ -135,34 +129,18 @@ func listHelpers(ctx context.Context, event events.APIGatewayV2HTTPRequest, auth
 	}), nil
 }
 
-func getHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+func getHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
 	helperID := event.PathParameters["helper_id"]
 	if helperID == "" {
 		return authMiddleware.CreateErrorResponse(400, "Helper ID is required"), nil
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	helper, err := loadHelper(ctx, stores, authCtx.AccountID, helperID)
 	if err != nil {
+		log.Printf("Failed to get helper %s: %v", helperID, err)
 		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
 	}
-	db := dynamodb.NewFromConfig(cfg)
-
-	result, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(helpersTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"helper_id": &ddbtypes.AttributeValueMemberS{Value: helperID},
-		},
-	})
-	if err != nil || result.Item == nil {
-		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
-	}
-
-	var helper apitypes.Helper
-	if err := attributevalue.UnmarshalMap(result.Item, &helper); err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
-	}
-
-	if helper.AccountID != authCtx.AccountID {
+	if helper == nil {
 		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
 	}
 
@@ -176,6 +154,7 @@ func getHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCt
 		"status":           helper.Status,
 		"enabled":          helper.Enabled,
 		"config":           helper.Config,
+		"config_version":   helper.ConfigVersion,
 		"config_schema":    helper.ConfigSchema,
 		"connection_id":    helper.ConnectionID,
 		"execution_count":  helper.ExecutionCount,
@@ -185,7 +164,7 @@ func getHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCt
 	}), nil
 }
 
-func createHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+func createHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
 	log.Printf("Create helper for account: %s", authCtx.AccountID)
 
 	if !authCtx.Permissions.CanManageHelpers {
@@ -193,16 +172,9 @@ func createHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 	}
 
 	// Check plan limit before creating
-	accountsTable := os.Getenv("ACCOUNTS_TABLE")
-	if accountsTable != "" {
-		cfg, cfgErr := config.LoadDefaultConfig(ctx)
-		if cfgErr == nil {
-			db := dynamodb.NewFromConfig(cfg)
-			if err := billing.CheckHelperLimit(ctx, db, accountsTable, authCtx.AccountID); err != nil {
-				if limitErr, ok := err.(*billing.LimitExceededError); ok {
-					return authMiddleware.CreateErrorResponse(403, limitErr.Message), nil
-				}
-			}
+	if err := billing.CheckHelperLimit(ctx, stores.Accounts, authCtx.AccountID); err != nil {
+		if limitErr, ok := err.(*billing.LimitExceededError); ok {
+			return authMiddleware.CreateErrorResponse(403, limitErr.Message), nil
 		}
 	}
 
@@ -229,7 +201,7 @@ func createHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 		return authMiddleware.CreateErrorResponse(400, fmt.Sprintf("Invalid helper type: %s", req.HelperType)), nil
 	}
 	if req.Config != nil {
-		if err := helperInstance.ValidateConfig(req.Config); err != nil {
+		if err := helperEngine.ValidateHelperConfig(helperInstance, req.Config); err != nil {
 			return authMiddleware.CreateErrorResponse(400, fmt.Sprintf("Invalid config: %v", err)), nil
 		}
 	}
@@ -267,24 +239,15 @@ func createHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 		UpdatedAt:    now,
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
-	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
-	}
-	db := dynamodb.NewFromConfig(cfg)
-
-	item, err := attributevalue.MarshalMap(helper)
-	if err != nil {
+	if err := stores.Helpers.Create(ctx, &helper); err != nil {
+		log.Printf("Failed to store helper: %v", err)
 		return authMiddleware.CreateErrorResponse(500, "Failed to create helper"), nil
 	}
 
-	_, err = db.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(helpersTable),
-		Item:      item,
-	})
-	if err != nil {
-		log.Printf("Failed to store helper: %v", err)
-		return authMiddleware.CreateErrorResponse(500, "Failed to create helper"), nil
+	// Start the config history; the helper works without it, and its first
+	// update records the initial config if this fails
+	if err := configversion.Record(ctx, stores.HelperVersions, &helper, now); err != nil {
+		log.Printf("Failed to record initial config version of helper %s: %v", helperID, err)
 	}
 
 	return authMiddleware.CreateSuccessResponse(201, "Helper created successfully", map[string]interface{}{
@@ -297,7 +260,7 @@ func createHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 	}), nil
 }
 
-func updateHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+func updateHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores, cfg aws.Config) (events.APIGatewayV2HTTPResponse, error) {
 	helperID := event.PathParameters["helper_id"]
 	if helperID == "" {
 		return authMiddleware.CreateErrorResponse(400, "Helper ID is required"), nil
@@ -312,74 +275,53 @@ func updateHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 		return authMiddleware.CreateErrorResponse(400, "Invalid request format"), nil
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
-	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
-	}
-	db := dynamodb.NewFromConfig(cfg)
-
 	// Verify ownership
-	existing, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(helpersTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"helper_id": &ddbtypes.AttributeValueMemberS{Value: helperID},
-		},
-	})
-	if err != nil || existing.Item == nil {
-		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
+	helper, err := loadHelper(ctx, stores, authCtx.AccountID, helperID)
+	if err != nil {
+		log.Printf("Failed to get helper %s: %v", helperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to update helper"), nil
 	}
-
-	var existingHelper apitypes.Helper
-	if err := attributevalue.UnmarshalMap(existing.Item, &existingHelper); err != nil || existingHelper.AccountID != authCtx.AccountID {
+	if helper == nil {
 		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
 	}
 
-	// Build update expression
-	updateParts := []string{"updated_at = :updated_at"}
-	exprValues := map[string]ddbtypes.AttributeValue{
-		":updated_at": &ddbtypes.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
-	}
-	exprNames := map[string]string{}
-
 	if req.Name != "" {
-		updateParts = append(updateParts, "#n = :name")
-		exprValues[":name"] = &ddbtypes.AttributeValueMemberS{Value: req.Name}
-		exprNames["#n"] = "name"
+		helper.Name = req.Name
 	}
 	if req.Description != "" {
-		updateParts = append(updateParts, "description = :description")
-		exprValues[":description"] = &ddbtypes.AttributeValueMemberS{Value: req.Description}
+		helper.Description = req.Description
 	}
 	if req.Config != nil {
 		// Validate config against helper schema
-		if helperEngine.IsRegistered(existingHelper.HelperType) {
-			helperInstance, err := helperEngine.NewHelper(existingHelper.HelperType)
+		if helperEngine.IsRegistered(helper.HelperType) {
+			helperInstance, err := helperEngine.NewHelper(helper.HelperType)
 			if err == nil {
-				if err := helperInstance.ValidateConfig(req.Config); err != nil {
+				if err := helperEngine.ValidateHelperConfig(helperInstance, req.Config); err != nil {
 					return authMiddleware.CreateErrorResponse(400, fmt.Sprintf("Invalid config: %v", err)), nil
 				}
 			}
 		}
-		configAV, err := attributevalue.MarshalMap(req.Config)
-		if err != nil {
-			return authMiddleware.CreateErrorResponse(500, "Failed to process config"), nil
+		// Config changes are committed as a new immutable version, which
+		// also moves the helper's config to it
+		_, err := configversion.Update(ctx, stores.HelperVersions, helper, req.Config, authCtx.UserID, time.Now())
+		if errors.Is(err, configversion.ErrConflict) {
+			return authMiddleware.CreateErrorResponse(409, "Helper config was changed by someone else, reload and try again"), nil
+		} else if err != nil {
+			log.Printf("Failed to version helper config: %v", err)
+			return authMiddleware.CreateErrorResponse(500, "Failed to update helper"), nil
 		}
-		updateParts = append(updateParts, "config = :config")
-		exprValues[":config"] = &ddbtypes.AttributeValueMemberM{Value: configAV}
 	}
 	if req.Enabled != nil {
-		updateParts = append(updateParts, "enabled = :enabled")
-		exprValues[":enabled"] = &ddbtypes.AttributeValueMemberBOOL{Value: *req.Enabled}
+		helper.Enabled = *req.Enabled
 	}
 	if req.ConnectionID != "" {
-		updateParts = append(updateParts, "connection_id = :connection_id")
-		exprValues[":connection_id"] = &ddbtypes.AttributeValueMemberS{Value: req.ConnectionID}
+		helper.ConnectionID = req.ConnectionID
 	}
 
 	// Handle schedule changes
 	if req.ScheduleEnabled != nil || req.CronExpression != "" {
-		scheduleEnabled := existingHelper.ScheduleEnabled
-		cronExpr := existingHelper.CronExpression
+		scheduleEnabled := helper.ScheduleEnabled
+		cronExpr := helper.CronExpression
 		if req.ScheduleEnabled != nil {
 			scheduleEnabled = *req.ScheduleEnabled
 		}
@@ -389,49 +331,37 @@ func updateHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 
 		if scheduleEnabled && cronExpr != "" {
 			// Create or update EventBridge rule
-			ruleARN, err := upsertScheduleRule(ctx, cfg, helperID, existingHelper.AccountID, cronExpr)
+			ruleARN, err := upsertScheduleRule(ctx, cfg, helperID, helper.AccountID, cronExpr)
 			if err != nil {
 				log.Printf("Failed to manage schedule rule: %v", err)
 				return authMiddleware.CreateErrorResponse(500, "Failed to update schedule"), nil
 			}
-			updateParts = append(updateParts, "schedule_enabled = :sched_enabled, cron_expression = :cron, schedule_rule_arn = :rule_arn")
-			exprValues[":sched_enabled"] = &ddbtypes.AttributeValueMemberBOOL{Value: true}
-			exprValues[":cron"] = &ddbtypes.AttributeValueMemberS{Value: cronExpr}
-			exprValues[":rule_arn"] = &ddbtypes.AttributeValueMemberS{Value: ruleARN}
+			helper.ScheduleEnabled = true
+			helper.CronExpression = cronExpr
+			helper.ScheduleRuleARN = ruleARN
 		} else if !scheduleEnabled {
 			// Disable schedule rule
-			if existingHelper.ScheduleRuleARN != "" {
+			if helper.ScheduleRuleARN != "" {
 				disableScheduleRule(ctx, cfg, helperID)
 			}
-			updateParts = append(updateParts, "schedule_enabled = :sched_enabled")
-			exprValues[":sched_enabled"] = &ddbtypes.AttributeValueMemberBOOL{Value: false}
+			helper.ScheduleEnabled = false
 		}
 	}
 
-	updateInput := &dynamodb.UpdateItemInput{
-		TableName: aws.String(helpersTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"helper_id": &ddbtypes.AttributeValueMemberS{Value: helperID},
-		},
-		UpdateExpression:          aws.String("SET " + strings.Join(updateParts, ", ")),
-		ExpressionAttributeValues: exprValues,
-	}
-	if len(exprNames) > 0 {
-		updateInput.ExpressionAttributeNames = exprNames
-	}
-
-	_, err = db.UpdateItem(ctx, updateInput)
-	if err != nil {
+	// Only the settings are written; the config moved with its version above
+	helper.UpdatedAt = time.Now().UTC()
+	if err := stores.Helpers.UpdateSettings(ctx, helper); err != nil {
 		log.Printf("Failed to update helper: %v", err)
 		return authMiddleware.CreateErrorResponse(500, "Failed to update helper"), nil
 	}
 
 	return authMiddleware.CreateSuccessResponse(200, "Helper updated successfully", map[string]interface{}{
-		"helper_id": helperID,
+		"helper_id":      helperID,
+		"config_version": helper.ConfigVersion,
 	}), nil
 }
 
-func deleteHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+func deleteHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores, cfg aws.Config) (events.APIGatewayV2HTTPResponse, error) {
 	helperID := event.PathParameters["helper_id"]
 	if helperID == "" {
 		return authMiddleware.CreateErrorResponse(400, "Helper ID is required"), nil
@@ -441,51 +371,27 @@ func deleteHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 		return authMiddleware.CreateErrorResponse(403, "Permission denied"), nil
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
-	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
-	}
-	db := dynamodb.NewFromConfig(cfg)
-
 	// Verify ownership
-	existing, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(helpersTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"helper_id": &ddbtypes.AttributeValueMemberS{Value: helperID},
-		},
-	})
-	if err != nil || existing.Item == nil {
-		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
+	helper, err := loadHelper(ctx, stores, authCtx.AccountID, helperID)
+	if err != nil {
+		log.Printf("Failed to get helper %s: %v", helperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to delete helper"), nil
 	}
-
-	var existingHelper apitypes.Helper
-	if err := attributevalue.UnmarshalMap(existing.Item, &existingHelper); err != nil || existingHelper.AccountID != authCtx.AccountID {
+	if helper == nil {
 		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
 	}
 
 	// Clean up EventBridge schedule rule if one exists
-	if existingHelper.ScheduleRuleARN != "" {
+	if helper.ScheduleRuleARN != "" {
 		deleteScheduleRule(ctx, cfg, helperID)
 	}
 
 	// Soft delete by setting status to deleted
-	_, err = db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(helpersTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"helper_id": &ddbtypes.AttributeValueMemberS{Value: helperID},
-		},
-		UpdateExpression: aws.String("SET #s = :status, updated_at = :updated_at, enabled = :enabled, schedule_enabled = :sched"),
-		ExpressionAttributeNames: map[string]string{
-			"#s": "status",
-		},
-		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
-			":status":     &ddbtypes.AttributeValueMemberS{Value: "deleted"},
-			":updated_at": &ddbtypes.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
-			":enabled":    &ddbtypes.AttributeValueMemberBOOL{Value: false},
-			":sched":      &ddbtypes.AttributeValueMemberBOOL{Value: false},
-		},
-	})
-	if err != nil {
+	helper.Status = "deleted"
+	helper.Enabled = false
+	helper.ScheduleEnabled = false
+	helper.UpdatedAt = time.Now().UTC()
+	if err := stores.Helpers.UpdateSettings(ctx, helper); err != nil {
 		log.Printf("Failed to delete helper: %v", err)
 		return authMiddleware.CreateErrorResponse(500, "Failed to delete helper"), nil
 	}
@@ -495,7 +401,7 @@ func deleteHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 	}), nil
 }
 
-func executeHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+func executeHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
 	// Extract helper_id from path (e.g., /helpers/{helper_id}/execute)
 	path := event.RequestContext.HTTP.Path
 	parts := strings.Split(strings.TrimPrefix(path, "/helpers/"), "/")
@@ -517,66 +423,77 @@ func executeHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, au
 		}
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	// Verify helper exists and belongs to account
+	helper, err := loadHelper(ctx, stores, authCtx.AccountID, helperID)
 	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+		log.Printf("Failed to get helper %s: %v", helperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to create execution"), nil
 	}
-	db := dynamodb.NewFromConfig(cfg)
-
-	// Verify helper exists and belongs to account
-	result, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(helpersTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"helper_id": &ddbtypes.AttributeValueMemberS{Value: helperID},
-		},
-	})
-	if err != nil || result.Item == nil {
+	if helper == nil {
 		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
 	}
 
-	var helper apitypes.Helper
-	if err := attributevalue.UnmarshalMap(result.Item, &helper); err != nil || helper.AccountID != authCtx.AccountID {
-		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
+	// Dry runs are allowed on disabled helpers so they can be checked before enabling
+	if req.DryRun || event.QueryStringParameters["dry_run"] == "true" {
+		return dryRunHelper(ctx, stores, helper, req, authCtx)
 	}
 
 	if !helper.Enabled {
 		return authMiddleware.CreateErrorResponse(400, "Helper is disabled"), nil
 	}
 
+	now := time.Now().UTC()
+	runAt, err := delayed.ParseRunAt(req.RunAt, req.Delay, now.Truncate(time.Second))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(400, err.Error()), nil
+	}
+	if !runAt.IsZero() {
+		pending := &apitypes.DelayedExecution{
+			AccountID:   authCtx.AccountID,
+			UserID:      authCtx.UserID,
+			HelperID:    helperID,
+			ContactID:   req.ContactID,
+			Input:       req.Input,
+			TriggerType: "manual",
+		}
+		if err := delayed.Schedule(ctx, stores.Delayed, pending, runAt, now); err != nil {
+			log.Printf("Failed to store delayed execution: %v", err)
+			return authMiddleware.CreateErrorResponse(500, "Failed to create execution"), nil
+		}
+
+		return authMiddleware.CreateSuccessResponse(202, "Helper execution delayed", map[string]interface{}{
+			"execution_id": pending.ExecutionID,
+			"helper_id":    helperID,
+			"status":       delayed.ExecutionStatus,
+			"run_at":       pending.RunAt,
+		}), nil
+	}
+
 	// Create execution record with ALL helper data frozen at this point.
 	// connection_id and config come from the helper record.
 	// DynamoDB Streams will auto-dispatch to SQS FIFO for async processing.
-	now := time.Now().UTC()
 	executionID := "exec:" + uuid.Must(uuid.NewV7()).String()
 	ttl := now.Add(7 * 24 * time.Hour).Unix()
 
-	execution := apitypes.Execution{
-		ExecutionID:  executionID,
-		HelperID:     helperID,
-		HelperType:   helper.HelperType,
-		AccountID:    authCtx.AccountID,
-		UserID:       authCtx.UserID,
-		ConnectionID: helper.ConnectionID,
-		ContactID:    req.ContactID,
-		Config:       helper.Config,
-		Status:       "queued",
-		TriggerType:  "manual",
-		Input:        req.Input,
-		CreatedAt:    now.Format(time.RFC3339),
-		StartedAt:    now,
-		TTL:          &ttl,
-	}
-
-	execItem, err := attributevalue.MarshalMap(execution)
-	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Failed to create execution"), nil
-	}
-
-	_, err = db.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(executionsTable),
-		Item:      execItem,
-	})
-	if err != nil {
+	exec := &apitypes.Execution{
+		ExecutionID:   executionID,
+		HelperID:      helperID,
+		HelperType:    helper.HelperType,
+		AccountID:     authCtx.AccountID,
+		UserID:        authCtx.UserID,
+		ConnectionID:  helper.ConnectionID,
+		ContactID:     req.ContactID,
+		Config:        helper.Config,
+		ConfigVersion: helper.ConfigVersion,
+		Status:        execution.StatusQueued,
+		TriggerType:   "manual",
+		Input:         req.Input,
+		CreatedAt:     now.Format(time.RFC3339),
+		StartedAt:     now,
+		TTL:           &ttl,
+	}
+
+	if err := stores.Executions.Create(ctx, exec); err != nil {
 		log.Printf("Failed to store execution: %v", err)
 		return authMiddleware.CreateErrorResponse(500, "Failed to create execution"), nil
 	}
@@ -584,11 +501,37 @@ func executeHelper(ctx context.Context, event events.APIGatewayV2HTTPRequest, au
 	return authMiddleware.CreateSuccessResponse(202, "Helper execution queued", map[string]interface{}{
 		"execution_id": executionID,
 		"helper_id":    helperID,
-		"status":       "queued",
+		"status":       execution.StatusQueued,
 		"started_at":   now,
 	}), nil
 }
 
+// loadHelper returns the account's helper, or nil if it does not exist or
+// belongs to another account
+func loadHelper(ctx context.Context, stores *database.Stores, accountID, helperID string) (*apitypes.Helper, error) {
+	helper, err := stores.Helpers.GetByID(ctx, helperID)
+	if err != nil || helper == nil || helper.AccountID != accountID {
+		return nil, err
+	}
+	return helper, nil
+}
+
+// dryRunHelper executes the helper synchronously with CRM writes recorded
+// instead of performed. No execution record is created and usage is not counted.
+func dryRunHelper(ctx context.Context, stores *database.Stores, helper *apitypes.Helper, req ExecuteHelperRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	log.Printf("Dry run helper %s for account: %s", helper.HelperID, authCtx.AccountID)
+
+	result, err := helperEngine.DryRunHelper(ctx, stores, helper, req.ContactID, req.Input, nil, authCtx.UserID)
+	if err != nil {
+		log.Printf("Dry run of helper %s failed: %v", helper.HelperID, err)
+		return authMiddleware.CreateErrorResponse(502, "Failed to load CRM connection"), nil
+	}
+
+	data := helperEngine.DryRunResponse(result)
+	data["helper_id"] = helper.HelperID
+	return authMiddleware.CreateSuccessResponse(200, "Helper dry run completed", data), nil
+}
+
 // scheduleRuleName returns the EventBridge rule name for a helper
 func scheduleRuleName(helperID string) string {
 	// Replace colons with dashes for valid rule names
@@ -0,0 +1,170 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/myfusionhelper/api/internal/configversion"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/execution"
+	_ "github.com/myfusionhelper/api/internal/helpers/tagging"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// newTestStores returns an account with helper:123 at config version 1,
+// which has run 5 times
+func newTestStores(t *testing.T) *database.Stores {
+	t.Helper()
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Accounts.Create(ctx, &types.Account{AccountID: "account-123", Plan: "start"})
+	helper := &types.Helper{
+		HelperID:       "helper:123",
+		AccountID:      "account-123",
+		Name:           "Tag leads",
+		HelperType:     "clear_tags",
+		Status:         "active",
+		Enabled:        true,
+		Config:         map[string]interface{}{"mode": "all"},
+		ExecutionCount: 5,
+	}
+	stores.Helpers.Create(ctx, helper)
+	if err := configversion.Record(ctx, stores.HelperVersions, helper, time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return stores
+}
+
+func testAuth(accountID string) *types.AuthContext {
+	return &types.AuthContext{
+		UserID:      "user-1",
+		AccountID:   accountID,
+		Permissions: types.Permissions{CanManageHelpers: true, CanExecuteHelpers: true},
+	}
+}
+
+func request(method, path, helperID, body string) events.APIGatewayV2HTTPRequest {
+	event := events.APIGatewayV2HTTPRequest{Body: body}
+	if helperID != "" {
+		event.PathParameters = map[string]string{"helper_id": helperID}
+	}
+	event.RequestContext.HTTP.Method = method
+	event.RequestContext.HTTP.Path = path
+	return event
+}
+
+func decode(t *testing.T, response events.APIGatewayV2HTTPResponse, data interface{}) {
+	t.Helper()
+	body := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %v", err)
+	}
+}
+
+func TestHandle_CreateAndList(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	auth := testAuth("account-123")
+	stores.Helpers.Create(ctx, &types.Helper{HelperID: "helper:deleted", AccountID: "account-123", Status: "deleted"})
+
+	response, _ := handle(ctx, request("POST", "/helpers", "", `{"name":"Clear","helper_type":"clear_tags"}`), auth, stores, aws.Config{})
+	if response.StatusCode != 201 {
+		t.Fatalf("Expected status 201, got %d: %s", response.StatusCode, response.Body)
+	}
+	var created struct {
+		HelperID string `json:"helper_id"`
+	}
+	decode(t, response, &created)
+	if version, _ := stores.HelperVersions.Get(ctx, created.HelperID, 1); version == nil {
+		t.Errorf("Expected the initial config version to be recorded")
+	}
+
+	response, _ = handle(ctx, request("GET", "/helpers", "", ""), auth, stores, aws.Config{})
+	var list struct {
+		Helpers []types.Helper `json:"helpers"`
+	}
+	decode(t, response, &list)
+	if len(list.Helpers) != 2 {
+		t.Errorf("Expected the two helpers that are not deleted, got %+v", list.Helpers)
+	}
+}
+
+func TestHandle_CreateOverHelperLimit(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Accounts.Create(ctx, &types.Account{
+		AccountID: "account-123",
+		Plan:      "start",
+		Settings:  types.AccountSettings{MaxHelpers: 1},
+		Usage:     types.AccountUsage{Helpers: 1},
+	})
+
+	response, _ := handle(ctx, request("POST", "/helpers", "", `{"name":"Clear","helper_type":"clear_tags"}`), testAuth("account-123"), stores, aws.Config{})
+	if response.StatusCode != 403 {
+		t.Errorf("Expected status 403, got %d: %s", response.StatusCode, response.Body)
+	}
+}
+
+func TestHandle_UpdateKeepsStats(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+
+	body := `{"name":"Tag customers","config":{"mode":"prefix","prefix":"lead-"},"enabled":false}`
+	response, _ := handle(ctx, request("PUT", "/helpers/helper:123", "helper:123", body), testAuth("account-123"), stores, aws.Config{})
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", response.StatusCode, response.Body)
+	}
+
+	helper, _ := stores.Helpers.GetByID(ctx, "helper:123")
+	if helper.Name != "Tag customers" || helper.Enabled || helper.ConfigVersion != 2 || helper.Config["mode"] != "prefix" {
+		t.Errorf("Expected the new name, config at version 2 and the helper disabled, got %+v", helper)
+	}
+	if helper.ExecutionCount != 5 {
+		t.Errorf("Expected the execution count to be kept, got %d", helper.ExecutionCount)
+	}
+
+	response, _ = handle(ctx, request("PUT", "/helpers/helper:123", "helper:123", body), testAuth("account-456"), stores, aws.Config{})
+	if response.StatusCode != 404 {
+		t.Errorf("Expected status 404 for another account, got %d", response.StatusCode)
+	}
+}
+
+func TestHandle_ExecuteAndDelete(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	auth := testAuth("account-123")
+
+	response, _ := handle(ctx, request("POST", "/helpers/helper:123/execute", "", `{"contact_id":"789"}`), auth, stores, aws.Config{})
+	if response.StatusCode != 202 {
+		t.Fatalf("Expected status 202, got %d: %s", response.StatusCode, response.Body)
+	}
+	var queued struct {
+		ExecutionID string `json:"execution_id"`
+	}
+	decode(t, response, &queued)
+	exec, _ := stores.Executions.GetByID(ctx, queued.ExecutionID)
+	if exec == nil || exec.Status != execution.StatusQueued || exec.ContactID != "789" || exec.ConfigVersion != 1 {
+		t.Fatalf("Expected a queued execution at config version 1, got %+v", exec)
+	}
+
+	response, _ = handle(ctx, request("DELETE", "/helpers/helper:123", "helper:123", ""), auth, stores, aws.Config{})
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", response.StatusCode, response.Body)
+	}
+	helper, _ := stores.Helpers.GetByID(ctx, "helper:123")
+	if helper.Status != "deleted" || helper.Enabled {
+		t.Errorf("Expected the helper deleted and disabled, got %+v", helper)
+	}
+
+	response, _ = handle(ctx, request("POST", "/helpers/helper:123/execute", "", ""), auth, stores, aws.Config{})
+	if response.StatusCode != 400 {
+		t.Errorf("Expected a deleted helper not to run, got %d: %s", response.StatusCode, response.Body)
+	}
+}
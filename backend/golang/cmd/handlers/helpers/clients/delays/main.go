@@ -0,0 +1,211 @@
+package delays
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/myfusionhelper/api/internal/apiutil"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/delayed"
+	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+// CancelRequest selects the pending executions to cancel: those of a contact
+// or those of a helper
+type CancelRequest struct {
+	ContactID string `json:"contact_id"`
+	HelperID  string `json:"helper_id"`
+}
+
+// HandleWithAuth routes delayed execution requests
+func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	return handle(ctx, event, authCtx, database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg)))
+}
+
+func handle(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	method := event.RequestContext.HTTP.Method
+	path := strings.TrimSuffix(event.RequestContext.HTTP.Path, "/")
+
+	switch {
+	case path == "/delayed-executions" && method == "GET":
+		return listDelayed(ctx, event, authCtx, stores)
+	case path == "/delayed-executions/cancel" && method == "POST":
+		return cancelMatching(ctx, event, authCtx, stores)
+	case strings.HasPrefix(path, "/delayed-executions/") && method == "GET":
+		return getDelayed(ctx, strings.TrimPrefix(path, "/delayed-executions/"), authCtx, stores)
+	case strings.HasPrefix(path, "/delayed-executions/") && method == "DELETE":
+		return cancelOne(ctx, strings.TrimPrefix(path, "/delayed-executions/"), authCtx, stores)
+	default:
+		return authMiddleware.CreateErrorResponse(404, "Not Found"), nil
+	}
+}
+
+// listDelayed lists the delayed executions of a contact or a helper, pending
+// ones by default
+func listDelayed(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	contactID := event.QueryStringParameters["contact_id"]
+	helperID := event.QueryStringParameters["helper_id"]
+	if (contactID == "") == (helperID == "") {
+		return authMiddleware.CreateErrorResponse(400, "Either contact_id or helper_id is required"), nil
+	}
+
+	status := event.QueryStringParameters["status"]
+	switch status {
+	case "":
+		status = delayed.StatusPending
+	case "all":
+		status = ""
+	case delayed.StatusPending, delayed.StatusReleased, delayed.StatusCancelled, delayed.StatusFailed:
+	default:
+		return authMiddleware.CreateErrorResponse(400, "status must be pending, released, cancelled, failed or all"), nil
+	}
+
+	limit, cursor, err := pageParams(event)
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(400, "Invalid next_token"), nil
+	}
+
+	var items []apitypes.DelayedExecution
+	var next string
+	if contactID != "" {
+		items, next, err = stores.Delayed.ListByContact(ctx, authCtx.AccountID, contactID, status, limit, cursor)
+	} else {
+		if resp, ok := checkHelper(ctx, helperID, authCtx, stores); !ok {
+			return resp, nil
+		}
+		items, next, err = stores.Delayed.ListByHelper(ctx, helperID, status, limit, cursor)
+	}
+	if err != nil {
+		log.Printf("Failed to list delayed executions: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to list delayed executions"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, "Delayed executions retrieved successfully", map[string]interface{}{
+		"delayed_executions": items,
+		"total_count":        len(items),
+		"next_token":         encodePageToken(next),
+		"has_more":           next != "",
+	}), nil
+}
+
+func getDelayed(ctx context.Context, executionID string, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	d, err := stores.Delayed.Get(ctx, executionID)
+	if err != nil {
+		log.Printf("Failed to get delayed execution %s: %v", executionID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to get delayed execution"), nil
+	}
+	if d == nil || d.AccountID != authCtx.AccountID {
+		return authMiddleware.CreateErrorResponse(404, "Delayed execution not found"), nil
+	}
+	return authMiddleware.CreateSuccessResponse(200, "Delayed execution retrieved successfully", d), nil
+}
+
+func cancelOne(ctx context.Context, executionID string, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	if !authCtx.Permissions.CanExecuteHelpers {
+		return authMiddleware.CreateErrorResponse(403, "Permission denied"), nil
+	}
+
+	log.Printf("Cancel delayed execution %s for account: %s", executionID, authCtx.AccountID)
+
+	d, err := delayed.Cancel(ctx, stores.Delayed, authCtx.AccountID, executionID, cancelReason(authCtx), time.Now())
+	switch {
+	case errors.Is(err, delayed.ErrNotFound):
+		return authMiddleware.CreateErrorResponse(404, "Delayed execution not found"), nil
+	case errors.Is(err, delayed.ErrNotPending):
+		return authMiddleware.CreateErrorResponse(409, err.Error()), nil
+	case err != nil:
+		log.Printf("Failed to cancel delayed execution %s: %v", executionID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to cancel delayed execution"), nil
+	}
+	return authMiddleware.CreateSuccessResponse(200, "Delayed execution cancelled", d), nil
+}
+
+// cancelMatching cancels every pending execution of a contact or a helper
+func cancelMatching(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	if !authCtx.Permissions.CanExecuteHelpers {
+		return authMiddleware.CreateErrorResponse(403, "Permission denied"), nil
+	}
+
+	var req CancelRequest
+	if err := json.Unmarshal([]byte(apiutil.GetBody(event)), &req); err != nil {
+		return authMiddleware.CreateErrorResponse(400, "Invalid request format"), nil
+	}
+	if (req.ContactID == "") == (req.HelperID == "") {
+		return authMiddleware.CreateErrorResponse(400, "Either contact_id or helper_id is required"), nil
+	}
+
+	log.Printf("Cancel delayed executions (contact=%q helper=%q) for account: %s", req.ContactID, req.HelperID, authCtx.AccountID)
+
+	var cancelled int
+	var err error
+	if req.ContactID != "" {
+		cancelled, err = delayed.CancelForContact(ctx, stores.Delayed, authCtx.AccountID, req.ContactID, cancelReason(authCtx), time.Now())
+	} else {
+		if resp, ok := checkHelper(ctx, req.HelperID, authCtx, stores); !ok {
+			return resp, nil
+		}
+		cancelled, err = delayed.CancelForHelper(ctx, stores.Delayed, req.HelperID, cancelReason(authCtx), time.Now())
+	}
+	if err != nil {
+		log.Printf("Failed to cancel delayed executions: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to cancel delayed executions"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, "Delayed executions cancelled", map[string]interface{}{
+		"cancelled": cancelled,
+	}), nil
+}
+
+// checkHelper reports whether the helper belongs to the account, with the
+// response to return if it does not
+func checkHelper(ctx context.Context, helperID string, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, bool) {
+	helper, err := stores.Helpers.GetByID(ctx, helperID)
+	if err != nil {
+		log.Printf("Failed to get helper %s: %v", helperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), false
+	}
+	if helper == nil || helper.AccountID != authCtx.AccountID {
+		return authMiddleware.CreateErrorResponse(404, "Helper not found"), false
+	}
+	return events.APIGatewayV2HTTPResponse{}, true
+}
+
+func cancelReason(authCtx *apitypes.AuthContext) string {
+	return "cancelled by " + authCtx.UserID
+}
+
+// pageParams parses limit (default 20, max 100) and next_token
+func pageParams(event events.APIGatewayV2HTTPRequest) (int, string, error) {
+	limit := 20
+	if l, err := strconv.Atoi(event.QueryStringParameters["limit"]); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	token := event.QueryStringParameters["next_token"]
+	if token == "" {
+		return limit, "", nil
+	}
+	cursor, err := base64.URLEncoding.DecodeString(token)
+	return limit, string(cursor), err
+}
+
+func encodePageToken(cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString([]byte(cursor))
+}
@@ -0,0 +1,75 @@
+package execute
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// defaultIdempotencyWindow is how long a key is remembered when
+// IDEMPOTENCY_WINDOW is unset. CRM webhook retries arrive within hours.
+const defaultIdempotencyWindow = 24 * time.Hour
+
+// controlParams steer the request rather than the helper, so they are left
+// out of auto-derived keys.
+var controlParams = map[string]bool{
+	"dry_run":         true,
+	"idempotency":     true,
+	"idempotency_key": true,
+}
+
+// idempotencyWindow reads IDEMPOTENCY_WINDOW as a Go duration ("24h", "90m")
+func idempotencyWindow() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_WINDOW")); err == nil && d > 0 {
+		return d
+	}
+	return defaultIdempotencyWindow
+}
+
+// idempotencyKey returns the caller's key for this request, or "" when the
+// request should not be deduplicated. An explicit Idempotency-Key header or
+// idempotency_key body field wins; otherwise idempotency=auto derives a key
+// from the helper, contact and payload so identical webhook retries collapse.
+func idempotencyKey(event events.APIGatewayV2HTTPRequest, body map[string]interface{}, helperID, contactID string, input map[string]interface{}) string {
+	if key := strings.TrimSpace(event.Headers["idempotency-key"]); key != "" {
+		return key
+	}
+	if key, ok := body["idempotency_key"].(string); ok && strings.TrimSpace(key) != "" {
+		return strings.TrimSpace(key)
+	}
+
+	mode := event.QueryStringParameters["idempotency"]
+	if v, ok := body["idempotency"].(string); ok {
+		mode = v
+	}
+	if mode != "auto" {
+		return ""
+	}
+
+	query := make(map[string]string, len(event.QueryStringParameters))
+	for name, value := range event.QueryStringParameters {
+		if !controlParams[name] && name != "contact_id" {
+			query[name] = value
+		}
+	}
+	// encoding/json sorts map keys, so equal payloads hash equally
+	payload, _ := json.Marshal(map[string]interface{}{
+		"helper_id":  helperID,
+		"contact_id": contactID,
+		"input":      input,
+		"query":      query,
+	})
+	sum := sha256.Sum256(payload)
+	return "auto:" + hex.EncodeToString(sum[:])
+}
+
+// idempotencyStoreKey scopes a caller's key to the account and helper, so
+// keys chosen by different callers cannot collide
+func idempotencyStoreKey(accountID, helperID, key string) string {
+	return "idem:" + accountID + ":" + helperID + ":" + key
+}
@@ -8,29 +8,30 @@ import (
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
 	"github.com/myfusionhelper/api/internal/apiutil"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/delayed"
+	"github.com/myfusionhelper/api/internal/execution"
 	helperResolve "github.com/myfusionhelper/api/internal/helpers"
 	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
 	"github.com/myfusionhelper/api/internal/ratelimit"
 	apitypes "github.com/myfusionhelper/api/internal/types"
 )
 
-var (
-	executionsTable = os.Getenv("EXECUTIONS_TABLE")
-	accountsTable   = os.Getenv("ACCOUNTS_TABLE")
-	helpersTable    = os.Getenv("HELPERS_TABLE")
-	rateLimitsTable = os.Getenv("RATE_LIMITS_TABLE")
-)
-
 // Handle processes API-key-authenticated execute requests.
 // Auth context comes from the Lambda authorizer (not Cognito JWT).
 func Handle(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	return handle(ctx, event, database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg)))
+}
+
+func handle(ctx context.Context, event events.APIGatewayV2HTTPRequest, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
 	// Extract auth context from Lambda authorizer
 	lambdaCtx := event.RequestContext.Authorizer.Lambda
 	if lambdaCtx == nil {
@@ -47,62 +48,27 @@ func Handle(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.A
 
 	log.Printf("API key execute: helper=%s account=%s apiKey=%s", helperID, accountID, apiKeyID)
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
-	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
-	}
-	db := dynamodb.NewFromConfig(cfg)
-
 	// Rate limit checks before creating execution
-	limiter := ratelimit.New(db, accountsTable, rateLimitsTable)
+	limiter := ratelimit.New(stores.Accounts, stores.Counters)
 
 	// 1. Get account to check plan limits
-	accountResult, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(accountsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"account_id": &ddbtypes.AttributeValueMemberS{Value: accountID},
-		},
-	})
-	if err != nil || accountResult.Item == nil {
+	account, err := stores.Accounts.GetByID(ctx, accountID)
+	if err != nil || account == nil {
 		return authMiddleware.CreateErrorResponse(500, "Failed to load account"), nil
 	}
 
-	var account apitypes.Account
-	if err := attributevalue.UnmarshalMap(accountResult.Item, &account); err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
-	}
-
-	// 2. Check monthly execution limit
-	monthlyResult, err := limiter.CheckMonthlyLimit(ctx, accountID, account.Settings.MaxExecutions)
-	if err != nil {
-		log.Printf("Failed to check monthly limit: %v", err)
-		// Don't block on rate limit errors — allow execution
-	} else if !monthlyResult.Allowed {
-		return createRateLimitResponse(monthlyResult, account.Plan), nil
-	}
-
-	// 3. Check per-helper burst limit
-	burstResult, err := limiter.CheckBurstLimit(ctx, helperID, 100)
-	if err != nil {
-		log.Printf("Failed to check burst limit: %v", err)
-	} else if !burstResult.Allowed {
-		return createRateLimitResponse(burstResult, account.Plan), nil
-	}
-
-	// Look up the helper to freeze its config at execution time.
-	// The authorizer already validated ownership and status, so this is a simple read.
-	helper, err := helperResolve.ResolveHelper(ctx, db, helpersTable, helperID)
-	if err != nil {
-		log.Printf("Failed to resolve helper %s: %v", helperID, err)
-		return authMiddleware.CreateErrorResponse(500, "Failed to load helper"), nil
-	}
-
 	// Parse POST body for per-execution data (contact_id, input)
 	var body map[string]interface{}
 	if reqBody := apiutil.GetBody(event); reqBody != "" {
 		_ = json.Unmarshal([]byte(reqBody), &body)
 	}
 
+	// Dry runs make no CRM changes and must not count toward monthly_executions
+	dryRun := event.QueryStringParameters["dry_run"] == "true"
+	if v, ok := body["dry_run"].(bool); ok && v {
+		dryRun = true
+	}
+
 	contactID := ""
 	var input map[string]interface{}
 
@@ -126,56 +92,186 @@ func Handle(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.A
 		queryParams = event.QueryStringParameters
 	}
 
+	// Executions can be held until a run_at time or for a delay; the query
+	// string is used when the body sets neither
+	runAtValue, _ := body["run_at"].(string)
+	delayValue := body["delay"]
+	if runAtValue == "" && delayValue == nil {
+		runAtValue = event.QueryStringParameters["run_at"]
+		if v := event.QueryStringParameters["delay"]; v != "" {
+			delayValue = v
+		}
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	runAt, err := delayed.ParseRunAt(runAtValue, delayValue, now)
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(400, err.Error()), nil
+	}
+
+	// Retried webhooks with a known idempotency key get the original
+	// execution back instead of a new one
+	executionID := "exec:" + uuid.Must(uuid.NewV7()).String()
+	claimKey := ""
+	if !dryRun {
+		if key := idempotencyKey(event, body, helperID, contactID, input); key != "" {
+			storeKey := idempotencyStoreKey(accountID, helperID, key)
+			existingID, claimed, err := stores.Idempotency.Claim(ctx, storeKey, executionID, now.Add(idempotencyWindow()))
+			if err != nil {
+				log.Printf("Failed to claim idempotency key: %v", err)
+				// Don't block on idempotency errors — allow execution
+			} else if !claimed {
+				return replayResponse(ctx, stores, existingID, helperID), nil
+			} else {
+				claimKey = storeKey
+			}
+		}
+	}
+	// Release the claim unless the execution gets created, so a retry after
+	// a rate limit or failure is not answered with an execution that never existed
+	created := false
+	defer func() {
+		if claimKey != "" && !created {
+			if err := stores.Idempotency.Release(ctx, claimKey, executionID); err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+			}
+		}
+	}()
+
+	// 2. Check monthly execution limit
+	if !dryRun {
+		monthlyResult, err := limiter.CheckMonthlyLimit(ctx, accountID, account.Settings.MaxExecutions)
+		if err != nil {
+			log.Printf("Failed to check monthly limit: %v", err)
+			// Don't block on rate limit errors — allow execution
+		} else if !monthlyResult.Allowed {
+			return createRateLimitResponse(monthlyResult, account.Plan), nil
+		}
+	}
+
+	// 3. Check per-helper burst limit
+	burstResult, err := limiter.CheckBurstLimit(ctx, helperID, 100)
+	if err != nil {
+		log.Printf("Failed to check burst limit: %v", err)
+	} else if !burstResult.Allowed {
+		return createRateLimitResponse(burstResult, account.Plan), nil
+	}
+
+	// Look up the helper to freeze its config at execution time.
+	// The authorizer already validated ownership and status, so this is a simple read.
+	helper, err := helperResolve.ResolveHelper(ctx, stores.Helpers, helperID)
+	if err != nil {
+		log.Printf("Failed to resolve helper %s: %v", helperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to load helper"), nil
+	}
+
+	if dryRun {
+		log.Printf("API key dry run: helper=%s account=%s", helperID, accountID)
+		result, err := helperResolve.DryRunHelper(ctx, stores, helper, contactID, input, queryParams, "")
+		if err != nil {
+			log.Printf("Dry run of helper %s failed: %v", helperID, err)
+			return authMiddleware.CreateErrorResponse(502, "Failed to load CRM connection"), nil
+		}
+		data := helperResolve.DryRunResponse(result)
+		data["helper_id"] = helperID
+		return authMiddleware.CreateSuccessResponse(200, "Helper dry run completed", data), nil
+	}
+
 	// Extract x-api-key header for relay helpers (chain_it, etc.)
 	apiKey := event.Headers["x-api-key"]
 
+	// Delayed executions are created by the releaser when due, with the
+	// helper's config at that time
+	if !runAt.IsZero() {
+		pending := &apitypes.DelayedExecution{
+			ExecutionID: executionID,
+			AccountID:   accountID,
+			APIKeyID:    apiKeyID,
+			APIKey:      apiKey,
+			HelperID:    helperID,
+			ContactID:   contactID,
+			Input:       input,
+			QueryParams: queryParams,
+			TriggerType: "api",
+		}
+		if err := delayed.Schedule(ctx, stores.Delayed, pending, runAt, now); err != nil {
+			log.Printf("Failed to store delayed execution: %v", err)
+			return authMiddleware.CreateErrorResponse(500, "Failed to create execution"), nil
+		}
+		created = true
+
+		return authMiddleware.CreateSuccessResponse(202, "Helper execution delayed", map[string]interface{}{
+			"execution_id": executionID,
+			"helper_id":    helperID,
+			"status":       delayed.ExecutionStatus,
+			"run_at":       pending.RunAt,
+		}), nil
+	}
+
 	// Create execution record with ALL helper data frozen at this point.
 	// connection_id and config come from the helper record, NOT the POST body.
 	// DynamoDB Streams auto-dispatches to SQS FIFO via stream-router.
-	now := time.Now().UTC()
-	executionID := "exec:" + uuid.Must(uuid.NewV7()).String()
 	ttl := now.Add(7 * 24 * time.Hour).Unix()
 
-	execution := map[string]interface{}{
-		"execution_id":  executionID,
-		"helper_id":     helperID,
-		"helper_type":   helper.HelperType,
-		"account_id":    accountID,
-		"api_key_id":    apiKeyID,
-		"api_key":       apiKey,
-		"connection_id": helper.ConnectionID,
-		"contact_id":    contactID,
-		"config":        helper.Config,
-		"status":        "queued",
-		"trigger_type":  "api",
-		"input":         input,
-		"query_params":  queryParams,
-		"created_at":    now.Format(time.RFC3339),
-		"started_at":    now.Format(time.RFC3339),
-		"ttl":           ttl,
-	}
-
-	item, err := attributevalue.MarshalMap(execution)
-	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Failed to create execution"), nil
+	exec := &apitypes.Execution{
+		ExecutionID:   executionID,
+		HelperID:      helperID,
+		HelperType:    helper.HelperType,
+		AccountID:     accountID,
+		APIKeyID:      apiKeyID,
+		APIKey:        apiKey,
+		ConnectionID:  helper.ConnectionID,
+		ContactID:     contactID,
+		Config:        helper.Config,
+		ConfigVersion: helper.ConfigVersion,
+		Status:        execution.StatusQueued,
+		TriggerType:   "api",
+		Input:         input,
+		QueryParams:   queryParams,
+		CreatedAt:     now.Format(time.RFC3339),
+		StartedAt:     now,
+		TTL:           &ttl,
 	}
 
-	_, err = db.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(executionsTable),
-		Item:      item,
-	})
-	if err != nil {
+	if err := stores.Executions.Create(ctx, exec); err != nil {
 		log.Printf("Failed to store execution: %v", err)
 		return authMiddleware.CreateErrorResponse(500, "Failed to create execution"), nil
 	}
+	created = true
 
 	return authMiddleware.CreateSuccessResponse(202, "Helper execution queued", map[string]interface{}{
 		"execution_id": executionID,
 		"helper_id":    helperID,
-		"status":       "queued",
+		"status":       execution.StatusQueued,
 	}), nil
 }
 
+// replayResponse answers a duplicate request with the execution its key
+// created. The execution may not be stored yet if the first request is still
+// in flight, in which case it is reported as queued, or if it is delayed.
+func replayResponse(ctx context.Context, stores *database.Stores, executionID, helperID string) events.APIGatewayV2HTTPResponse {
+	status := execution.StatusQueued
+	if exec, err := stores.Executions.GetByID(ctx, executionID); err != nil {
+		log.Printf("Failed to load execution %s for idempotent replay: %v", executionID, err)
+	} else if exec != nil {
+		status = exec.Status
+	} else if pending, err := stores.Delayed.Get(ctx, executionID); err == nil && pending != nil {
+		switch pending.Status {
+		case delayed.StatusPending:
+			status = delayed.ExecutionStatus
+		case delayed.StatusCancelled, delayed.StatusFailed:
+			status = pending.Status
+		}
+	}
+
+	log.Printf("Idempotent replay: helper=%s execution=%s", helperID, executionID)
+	return authMiddleware.CreateSuccessResponse(200, "Helper execution already queued", map[string]interface{}{
+		"execution_id":      executionID,
+		"helper_id":         helperID,
+		"status":            status,
+		"idempotent_replay": true,
+	})
+}
+
 func createRateLimitResponse(result *ratelimit.Result, plan string) events.APIGatewayV2HTTPResponse {
 	body := map[string]interface{}{
 		"success": false,
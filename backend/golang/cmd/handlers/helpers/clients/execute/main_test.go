@@ -0,0 +1,271 @@
+package execute
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+func newTestStores(t *testing.T, maxExecutions int) *database.Stores {
+	t.Helper()
+	ctx := context.Background()
+	stores := memory.NewStores()
+
+	stores.Accounts.Create(ctx, &types.Account{
+		AccountID: "account-123",
+		Plan:      "start",
+		Settings:  types.AccountSettings{MaxExecutions: maxExecutions},
+	})
+	stores.Helpers.Create(ctx, &types.Helper{
+		HelperID:     "helper:123",
+		AccountID:    "account-123",
+		ShortKey:     "abc123",
+		HelperType:   "tag_it",
+		ConnectionID: "conn:123",
+		Status:       "active",
+		Config:       map[string]interface{}{"tag_ids": []interface{}{"42"}},
+	})
+	return stores
+}
+
+func executeEvent(body string) events.APIGatewayV2HTTPRequest {
+	return events.APIGatewayV2HTTPRequest{
+		Headers: map[string]string{"x-api-key": "mfh_live_test"},
+		Body:    body,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				Lambda: map[string]interface{}{
+					"accountId": "account-123",
+					"apiKeyId":  "key:123",
+					"helperId":  "helper:123",
+				},
+			},
+		},
+	}
+}
+
+func TestHandle_QueuesExecution(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t, 10)
+
+	response, err := handle(ctx, executeEvent(`{"contact_id":"789","input":{"source":"form"}}`), stores)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != 202 {
+		t.Fatalf("Expected status 202, got %d: %s", response.StatusCode, response.Body)
+	}
+
+	var body struct {
+		Data struct {
+			ExecutionID string `json:"execution_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %v", err)
+	}
+
+	execution, err := stores.Executions.GetByID(ctx, body.Data.ExecutionID)
+	if err != nil || execution == nil {
+		t.Fatalf("Expected stored execution %s, got %v, %v", body.Data.ExecutionID, execution, err)
+	}
+	if execution.Status != "queued" {
+		t.Errorf("Expected status queued, got %s", execution.Status)
+	}
+	if execution.ContactID != "789" || execution.Input["source"] != "form" {
+		t.Errorf("Expected contact 789 with form input, got %s, %v", execution.ContactID, execution.Input)
+	}
+	if execution.ConnectionID != "conn:123" || execution.HelperType != "tag_it" {
+		t.Errorf("Expected helper data frozen on the execution, got %s, %s", execution.ConnectionID, execution.HelperType)
+	}
+	if execution.APIKey != "mfh_live_test" {
+		t.Errorf("Expected API key to be forwarded, got %q", execution.APIKey)
+	}
+
+	account, _ := stores.Accounts.GetByID(ctx, "account-123")
+	if account.Usage.MonthlyExecutions != 1 {
+		t.Errorf("Expected 1 monthly execution, got %d", account.Usage.MonthlyExecutions)
+	}
+}
+
+func TestHandle_MonthlyLimit(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t, 1)
+
+	if response, _ := handle(ctx, executeEvent(`{"contact_id":"789"}`), stores); response.StatusCode != 202 {
+		t.Fatalf("Expected first execution to be queued, got %d: %s", response.StatusCode, response.Body)
+	}
+
+	response, err := handle(ctx, executeEvent(`{"contact_id":"789"}`), stores)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.StatusCode != 429 {
+		t.Errorf("Expected status 429, got %d", response.StatusCode)
+	}
+
+	executions, _, _ := stores.Executions.ListByAccount(ctx, "account-123", "", 10, "")
+	if len(executions) != 1 {
+		t.Errorf("Expected 1 stored execution, got %d", len(executions))
+	}
+}
+
+func TestHandle_Unauthorized(t *testing.T) {
+	event := executeEvent("")
+	event.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{}
+
+	response, _ := handle(context.Background(), event, memory.NewStores())
+	if response.StatusCode != 401 {
+		t.Errorf("Expected status 401, got %d", response.StatusCode)
+	}
+}
+
+func executionIDOf(t *testing.T, response events.APIGatewayV2HTTPResponse) string {
+	t.Helper()
+	var body struct {
+		Data struct {
+			ExecutionID string `json:"execution_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %v", err)
+	}
+	return body.Data.ExecutionID
+}
+
+func TestHandle_IdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t, 10)
+
+	event := executeEvent(`{"contact_id":"789"}`)
+	event.Headers["idempotency-key"] = "keap-retry-1"
+	first, _ := handle(ctx, event, stores)
+	if first.StatusCode != 202 {
+		t.Fatalf("Expected status 202, got %d: %s", first.StatusCode, first.Body)
+	}
+
+	stores.Executions.UpdateResult(ctx, executionIDOf(t, first), "completed", nil, 120)
+	second, _ := handle(ctx, event, stores)
+	if second.StatusCode != 200 {
+		t.Fatalf("Expected status 200 for a duplicate, got %d: %s", second.StatusCode, second.Body)
+	}
+	if executionIDOf(t, second) != executionIDOf(t, first) {
+		t.Errorf("Expected the original execution %s, got %s", executionIDOf(t, first), executionIDOf(t, second))
+	}
+	var replay struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal([]byte(second.Body), &replay)
+	if replay.Data["status"] != "completed" || replay.Data["idempotent_replay"] != true {
+		t.Errorf("Expected the original status and a replay flag, got %v", replay.Data)
+	}
+
+	// The same key in the body dedups too
+	third, _ := handle(ctx, executeEvent(`{"contact_id":"789","idempotency_key":"keap-retry-1"}`), stores)
+	if executionIDOf(t, third) != executionIDOf(t, first) {
+		t.Errorf("Expected a body key to match the header key, got %s", executionIDOf(t, third))
+	}
+
+	executions, _, _ := stores.Executions.ListByAccount(ctx, "account-123", "", 10, "")
+	if len(executions) != 1 {
+		t.Errorf("Expected 1 stored execution, got %d", len(executions))
+	}
+	account, _ := stores.Accounts.GetByID(ctx, "account-123")
+	if account.Usage.MonthlyExecutions != 1 {
+		t.Errorf("Expected 1 monthly execution, got %d", account.Usage.MonthlyExecutions)
+	}
+}
+
+func TestHandle_AutoIdempotency(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t, 10)
+
+	first, _ := handle(ctx, executeEvent(`{"contact_id":"789","input":{"a":1,"b":2},"idempotency":"auto"}`), stores)
+	second, _ := handle(ctx, executeEvent(`{"idempotency":"auto","input":{"b":2,"a":1},"contact_id":"789"}`), stores)
+	if second.StatusCode != 200 || executionIDOf(t, second) != executionIDOf(t, first) {
+		t.Errorf("Expected the same payload to replay %s, got %d %s", executionIDOf(t, first), second.StatusCode, second.Body)
+	}
+
+	other, _ := handle(ctx, executeEvent(`{"contact_id":"790","input":{"a":1,"b":2},"idempotency":"auto"}`), stores)
+	if other.StatusCode != 202 || executionIDOf(t, other) == executionIDOf(t, first) {
+		t.Errorf("Expected another contact to get a new execution, got %d %s", other.StatusCode, other.Body)
+	}
+
+	plain, _ := handle(ctx, executeEvent(`{"contact_id":"789","input":{"a":1,"b":2}}`), stores)
+	if plain.StatusCode != 202 {
+		t.Errorf("Expected requests without a key to always queue, got %d", plain.StatusCode)
+	}
+}
+
+func TestHandle_IdempotencyKeyReleasedOnLimit(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t, 1)
+
+	if response, _ := handle(ctx, executeEvent(`{"contact_id":"1"}`), stores); response.StatusCode != 202 {
+		t.Fatalf("Expected first execution to be queued, got %d", response.StatusCode)
+	}
+
+	event := executeEvent(`{"contact_id":"789"}`)
+	event.Headers["idempotency-key"] = "ghl-1"
+	if response, _ := handle(ctx, event, stores); response.StatusCode != 429 {
+		t.Fatalf("Expected status 429, got %d", response.StatusCode)
+	}
+
+	account, _ := stores.Accounts.GetByID(ctx, "account-123")
+	account.Settings.MaxExecutions = 10
+	stores.Accounts.Update(ctx, account)
+
+	response, _ := handle(ctx, event, stores)
+	if response.StatusCode != 202 {
+		t.Errorf("Expected a retry after the limit to be queued, got %d: %s", response.StatusCode, response.Body)
+	}
+}
+
+func TestHandle_DelayedExecution(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t, 10)
+
+	event := executeEvent(`{"contact_id":"789","delay":"3d"}`)
+	event.Headers["idempotency-key"] = "drip-1"
+	response, _ := handle(ctx, event, stores)
+	if response.StatusCode != 202 {
+		t.Fatalf("Expected status 202, got %d: %s", response.StatusCode, response.Body)
+	}
+	executionID := executionIDOf(t, response)
+
+	if execution, _ := stores.Executions.GetByID(ctx, executionID); execution != nil {
+		t.Errorf("Expected no execution before run_at, got %+v", execution)
+	}
+	pending, _ := stores.Delayed.Get(ctx, executionID)
+	if pending == nil || pending.Status != "pending" || pending.ContactID != "789" || pending.APIKey != "mfh_live_test" {
+		t.Fatalf("Expected a pending delayed execution for contact 789, got %+v", pending)
+	}
+	if runAt, _ := time.Parse(time.RFC3339, pending.RunAt); time.Until(runAt) < 71*time.Hour {
+		t.Errorf("Expected run_at 3 days out, got %s", pending.RunAt)
+	}
+
+	replay, _ := handle(ctx, event, stores)
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal([]byte(replay.Body), &body)
+	if replay.StatusCode != 200 || body.Data["status"] != "delayed" {
+		t.Errorf("Expected the replay to report the execution delayed, got %d %v", replay.StatusCode, body.Data)
+	}
+
+	for _, payload := range []string{
+		`{"contact_id":"789","delay":"soon"}`,
+		`{"contact_id":"789","run_at":"2020-01-01T00:00:00Z"}`,
+		`{"contact_id":"789","run_at":"2099-01-01T00:00:00Z","delay":60}`,
+	} {
+		if response, _ := handle(ctx, executeEvent(payload), stores); response.StatusCode != 400 {
+			t.Errorf("Expected status 400 for %s, got %d", payload, response.StatusCode)
+		}
+	}
+}
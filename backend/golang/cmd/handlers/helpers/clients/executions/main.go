@@ -4,126 +4,78 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"log"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/myfusionhelper/api/internal/apiutil"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
 	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
 	apitypes "github.com/myfusionhelper/api/internal/types"
-)
-
-var (
-	executionsTable = os.Getenv("EXECUTIONS_TABLE")
+	"github.com/myfusionhelper/api/internal/worker"
 )
 
 // HandleWithAuth routes execution requests
 func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	return handle(ctx, event, authCtx, database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg)))
+}
+
+func handle(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
 	path := event.RequestContext.HTTP.Path
 	method := event.RequestContext.HTTP.Method
 
 	switch {
 	case path == "/executions" && method == "GET":
-		return listExecutions(ctx, event, authCtx)
+		return listExecutions(ctx, event, authCtx, stores)
+	case path == "/executions/dead-lettered" && method == "GET":
+		return listDeadLettered(ctx, event, authCtx, stores)
+	case strings.HasPrefix(path, "/executions/") && strings.HasSuffix(path, "/replay") && method == "POST":
+		return replayExecution(ctx, event, authCtx, stores)
+	case strings.HasPrefix(path, "/executions/") && strings.HasSuffix(path, "/cancel") && method == "POST":
+		return cancelExecution(ctx, event, authCtx, stores)
 	case strings.HasPrefix(path, "/executions/") && method == "GET":
-		return getExecution(ctx, event, authCtx)
+		return getExecution(ctx, event, authCtx, stores)
 	default:
 		return authMiddleware.CreateErrorResponse(404, "Not Found"), nil
 	}
 }
 
-func listExecutions(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+func listExecutions(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
 	log.Printf("List executions for account: %s", authCtx.AccountID)
 
 	helperID := event.QueryStringParameters["helper_id"]
 	statusFilter := event.QueryStringParameters["status"]
-	limitStr := event.QueryStringParameters["limit"]
-	nextToken := event.QueryStringParameters["next_token"]
-
-	// Parse limit (default 20, max 100)
-	limit := int32(20)
-	if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-		limit = int32(l)
-	}
-
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	limit, cursor, err := pageParams(event)
 	if err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+		return authMiddleware.CreateErrorResponse(400, "Invalid next_token"), nil
 	}
-	db := dynamodb.NewFromConfig(cfg)
-
-	// Build query based on parameters
-	var indexName string
-	var keyExpr string
-	exprValues := map[string]ddbtypes.AttributeValue{}
-	exprNames := map[string]string{}
-	var filterParts []string
 
+	var execs []apitypes.Execution
+	var next string
 	if helperID != "" {
-		// Query by helper_id using HelperIdCreatedAtIndex
-		indexName = "HelperIdCreatedAtIndex"
-		keyExpr = "helper_id = :helper_id"
-		exprValues[":helper_id"] = &ddbtypes.AttributeValueMemberS{Value: helperID}
-		// Must also filter by account_id for security
-		filterParts = append(filterParts, "account_id = :account_id")
-		exprValues[":account_id"] = &ddbtypes.AttributeValueMemberS{Value: authCtx.AccountID}
+		execs, next, err = stores.Executions.ListByHelper(ctx, helperID, statusFilter, limit, cursor)
 	} else {
-		// Query by account_id using AccountIdCreatedAtIndex
-		indexName = "AccountIdCreatedAtIndex"
-		keyExpr = "account_id = :account_id"
-		exprValues[":account_id"] = &ddbtypes.AttributeValueMemberS{Value: authCtx.AccountID}
-	}
-
-	// Optional status filter
-	if statusFilter != "" {
-		filterParts = append(filterParts, "#s = :status_filter")
-		exprNames["#s"] = "status"
-		exprValues[":status_filter"] = &ddbtypes.AttributeValueMemberS{Value: statusFilter}
-	}
-
-	queryInput := &dynamodb.QueryInput{
-		TableName:                 aws.String(executionsTable),
-		IndexName:                 aws.String(indexName),
-		KeyConditionExpression:    aws.String(keyExpr),
-		ExpressionAttributeValues: exprValues,
-		ScanIndexForward:          aws.Bool(false), // newest first
-		Limit:                     aws.Int32(limit),
-	}
-
-	if len(filterParts) > 0 {
-		queryInput.FilterExpression = aws.String(strings.Join(filterParts, " AND "))
-	}
-	if len(exprNames) > 0 {
-		queryInput.ExpressionAttributeNames = exprNames
-	}
-
-	// Cursor-based pagination
-	if nextToken != "" {
-		startKey, err := decodePageToken(nextToken)
-		if err == nil && startKey != nil {
-			queryInput.ExclusiveStartKey = startKey
-		}
+		execs, next, err = stores.Executions.ListByAccount(ctx, authCtx.AccountID, statusFilter, limit, cursor)
 	}
-
-	result, err := db.Query(ctx, queryInput)
 	if err != nil {
 		log.Printf("Failed to query executions: %v", err)
 		return authMiddleware.CreateErrorResponse(500, "Failed to list executions"), nil
 	}
 
-	// Unmarshal results
-	executionItems := make([]map[string]interface{}, 0, len(result.Items))
-	for _, item := range result.Items {
-		var exec apitypes.Execution
-		if err := attributevalue.UnmarshalMap(item, &exec); err != nil {
-			continue
-		}
+	executionItems := make([]map[string]interface{}, 0, len(execs))
+	for _, exec := range execs {
 		// Double-check account ownership when querying by helper_id
 		if exec.AccountID != authCtx.AccountID {
 			continue
@@ -138,30 +90,24 @@ func listExecutions(ctx context.Context, event events.APIGatewayV2HTTPRequest, a
 			"status":        exec.Status,
 			"trigger_type":  exec.TriggerType,
 			"error_message": exec.ErrorMessage,
+			"error_code":    exec.ErrorCode,
 			"duration_ms":   exec.DurationMs,
+			"attempts":      exec.Attempts,
 			"created_at":    exec.CreatedAt,
 			"started_at":    exec.StartedAt,
 			"completed_at":  exec.CompletedAt,
 		})
 	}
 
-	// Build next_token if there are more results
-	var responseNextToken string
-	hasMore := false
-	if result.LastEvaluatedKey != nil {
-		hasMore = true
-		responseNextToken = encodePageToken(result.LastEvaluatedKey)
-	}
-
 	return authMiddleware.CreateSuccessResponse(200, "Executions retrieved successfully", map[string]interface{}{
 		"executions": executionItems,
 		"total_count": len(executionItems),
-		"next_token":  responseNextToken,
-		"has_more":    hasMore,
+		"next_token":  encodePageToken(next),
+		"has_more":    next != "",
 	}), nil
 }
 
-func getExecution(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+func getExecution(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
 	// Extract execution_id from path: /executions/{execution_id}
 	path := event.RequestContext.HTTP.Path
 	parts := strings.Split(strings.TrimPrefix(path, "/executions/"), "/")
@@ -172,27 +118,14 @@ func getExecution(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 
 	log.Printf("Get execution %s for account: %s", executionID, authCtx.AccountID)
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	exec, err := stores.Executions.GetByID(ctx, executionID)
 	if err != nil {
 		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
 	}
-	db := dynamodb.NewFromConfig(cfg)
-
-	result, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(executionsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"execution_id": &ddbtypes.AttributeValueMemberS{Value: executionID},
-		},
-	})
-	if err != nil || result.Item == nil {
+	if exec == nil {
 		return authMiddleware.CreateErrorResponse(404, "Execution not found"), nil
 	}
 
-	var exec apitypes.Execution
-	if err := attributevalue.UnmarshalMap(result.Item, &exec); err != nil {
-		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
-	}
-
 	// Verify account ownership
 	if exec.AccountID != authCtx.AccountID {
 		return authMiddleware.CreateErrorResponse(404, "Execution not found"), nil
@@ -210,42 +143,194 @@ func getExecution(ctx context.Context, event events.APIGatewayV2HTTPRequest, aut
 		"input":         exec.Input,
 		"output":        exec.Output,
 		"error_message": exec.ErrorMessage,
+		"error_code":    exec.ErrorCode,
 		"duration_ms":   exec.DurationMs,
 		"created_at":    exec.CreatedAt,
 		"started_at":    exec.StartedAt,
 		"completed_at":  exec.CompletedAt,
+		"action_deliveries": exec.ActionDeliveries,
+		"attempts":          exec.Attempts,
+		"retry_attempts":    exec.RetryAttempts,
+		"replay_of":         exec.ReplayOf,
+		"replayed_as":       exec.ReplayedAs,
+		"workflow_run_id":   exec.WorkflowRunID,
+		"workflow_step_id":  exec.WorkflowStepID,
+		"config_version":    exec.ConfigVersion,
+		"connector_trace":   exec.ConnectorTrace,
+		"connector_calls_dropped": exec.ConnectorCallsDropped,
+		"status_history":          exec.StatusHistory,
 	}), nil
 }
 
-// encodePageToken encodes a DynamoDB LastEvaluatedKey as a base64 JSON string
-func encodePageToken(key map[string]ddbtypes.AttributeValue) string {
-	// Convert to a simpler format for encoding
-	simpleKey := make(map[string]string)
-	for k, v := range key {
-		if sv, ok := v.(*ddbtypes.AttributeValueMemberS); ok {
-			simpleKey[k] = sv.Value
-		}
+// listDeadLettered lists executions that exhausted their retries and can be replayed
+func listDeadLettered(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	params := make(map[string]string, len(event.QueryStringParameters)+1)
+	for k, v := range event.QueryStringParameters {
+		params[k] = v
 	}
-	data, err := json.Marshal(simpleKey)
+	params["status"] = execution.StatusDeadLettered
+	event.QueryStringParameters = params
+	return listExecutions(ctx, event, authCtx, stores)
+}
+
+// replayExecution re-queues a dead-lettered execution as a new execution with
+// the same frozen helper config and input. The new record is dispatched to the
+// worker queue by the executions stream like any other queued execution.
+func replayExecution(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	// Extract execution_id from path: /executions/{execution_id}/replay
+	path := event.RequestContext.HTTP.Path
+	executionID := strings.TrimSuffix(strings.TrimPrefix(path, "/executions/"), "/replay")
+	if executionID == "" || strings.Contains(executionID, "/") {
+		return authMiddleware.CreateErrorResponse(400, "Execution ID is required"), nil
+	}
+
+	log.Printf("Replay execution %s for account: %s", executionID, authCtx.AccountID)
+
+	exec, err := stores.Executions.GetByID(ctx, executionID)
 	if err != nil {
-		return ""
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
 	}
-	return base64.URLEncoding.EncodeToString(data)
+	if exec == nil {
+		return authMiddleware.CreateErrorResponse(404, "Execution not found"), nil
+	}
+
+	// Verify account ownership
+	if exec.AccountID != authCtx.AccountID {
+		return authMiddleware.CreateErrorResponse(404, "Execution not found"), nil
+	}
+	if exec.Status != execution.StatusDeadLettered {
+		return authMiddleware.CreateErrorResponse(409, "Only dead-lettered executions can be replayed"), nil
+	}
+	if exec.ReplayedAs != "" {
+		return authMiddleware.CreateErrorResponse(409, "Execution was already replayed as "+exec.ReplayedAs), nil
+	}
+
+	now := time.Now().UTC()
+	replayID := "exec:" + uuid.Must(uuid.NewV7()).String()
+
+	// Claim the original first so concurrent replays cannot both enqueue
+	if err := stores.Executions.ClaimReplay(ctx, executionID, replayID); err != nil {
+		if errors.Is(err, database.ErrConditionFailed) {
+			return authMiddleware.CreateErrorResponse(409, "Execution was already replayed"), nil
+		}
+		log.Printf("Failed to claim execution %s for replay: %v", executionID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to replay execution"), nil
+	}
+
+	// Copy the fields frozen at execution time; results and retry history start fresh
+	ttl := now.Add(7 * 24 * time.Hour).Unix()
+	replay := &apitypes.Execution{
+		ExecutionID:   replayID,
+		HelperID:      exec.HelperID,
+		HelperType:    exec.HelperType,
+		AccountID:     exec.AccountID,
+		UserID:        exec.UserID,
+		APIKeyID:      exec.APIKeyID,
+		APIKey:        exec.APIKey,
+		ConnectionID:  exec.ConnectionID,
+		ContactID:     exec.ContactID,
+		Config:        exec.Config,
+		ConfigVersion: exec.ConfigVersion,
+		Input:         exec.Input,
+		QueryParams:   exec.QueryParams,
+		Status:        execution.StatusQueued,
+		TriggerType:   "replay",
+		ReplayOf:      executionID,
+		CreatedAt:     now.Format(time.RFC3339),
+		StartedAt:     now,
+		TTL:           &ttl,
+	}
+	if err := stores.Executions.Create(ctx, replay); err != nil {
+		log.Printf("Failed to store replay of execution %s: %v", executionID, err)
+		// Release the claim so the replay can be attempted again
+		if err := stores.Executions.ReleaseReplay(ctx, executionID); err != nil {
+			log.Printf("Failed to release replay claim on execution %s: %v", executionID, err)
+		}
+		return authMiddleware.CreateErrorResponse(500, "Failed to replay execution"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(202, "Execution replay queued", map[string]interface{}{
+		"execution_id": replayID,
+		"replay_of":    executionID,
+		"helper_id":    exec.HelperID,
+		"status":       execution.StatusQueued,
+	}), nil
 }
 
-// decodePageToken decodes a base64 page token back to a DynamoDB ExclusiveStartKey
-func decodePageToken(token string) (map[string]ddbtypes.AttributeValue, error) {
-	data, err := base64.URLEncoding.DecodeString(token)
-	if err != nil {
-		return nil, err
+// CancelRequest optionally says why an execution is cancelled
+type CancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+// cancelExecution cancels an execution that has not started running, is
+// waiting to retry or is paused on its connection's breaker. The worker
+// checks the status before running a job, so a message already on the worker
+// queue is acknowledged without running. The workflow step or batch item the
+// execution ran for is failed.
+func cancelExecution(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	if !authCtx.Permissions.CanExecuteHelpers {
+		return authMiddleware.CreateErrorResponse(403, "Permission denied"), nil
 	}
-	var simpleKey map[string]string
-	if err := json.Unmarshal(data, &simpleKey); err != nil {
-		return nil, err
+
+	// Extract execution_id from path: /executions/{execution_id}/cancel
+	path := event.RequestContext.HTTP.Path
+	executionID := strings.TrimSuffix(strings.TrimPrefix(path, "/executions/"), "/cancel")
+	if executionID == "" || strings.Contains(executionID, "/") {
+		return authMiddleware.CreateErrorResponse(400, "Execution ID is required"), nil
+	}
+
+	var req CancelRequest
+	if body := apiutil.GetBody(event); body != "" {
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			return authMiddleware.CreateErrorResponse(400, "Invalid request format"), nil
+		}
+	}
+	reason := "cancelled by " + authCtx.UserID
+	if req.Reason != "" {
+		reason += ": " + req.Reason
+	}
+
+	log.Printf("Cancel execution %s for account: %s", executionID, authCtx.AccountID)
+
+	exec, err := execution.Cancel(ctx, stores.Executions, authCtx.AccountID, executionID, reason, time.Now())
+	switch {
+	case errors.Is(err, execution.ErrNotFound):
+		return authMiddleware.CreateErrorResponse(404, "Execution not found"), nil
+	case errors.Is(err, execution.ErrIllegalTransition):
+		return authMiddleware.CreateErrorResponse(409, "Only delayed, queued, dispatched, retrying or paused executions can be cancelled"), nil
+	case err != nil:
+		log.Printf("Failed to cancel execution %s: %v", executionID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to cancel execution"), nil
 	}
-	key := make(map[string]ddbtypes.AttributeValue)
-	for k, v := range simpleKey {
-		key[k] = &ddbtypes.AttributeValueMemberS{Value: v}
+
+	// The worker will not run it, so its workflow step or batch item fails now
+	worker.ReportCancelled(ctx, stores, exec, reason)
+
+	return authMiddleware.CreateSuccessResponse(200, "Execution cancelled", map[string]interface{}{
+		"execution_id":   exec.ExecutionID,
+		"helper_id":      exec.HelperID,
+		"status":         exec.Status,
+		"status_history": exec.StatusHistory,
+	}), nil
+}
+
+// pageParams parses limit (default 20, max 100) and next_token
+func pageParams(event events.APIGatewayV2HTTPRequest) (int, string, error) {
+	limit := 20
+	if l, err := strconv.Atoi(event.QueryStringParameters["limit"]); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	token := event.QueryStringParameters["next_token"]
+	if token == "" {
+		return limit, "", nil
+	}
+	cursor, err := base64.URLEncoding.DecodeString(token)
+	return limit, string(cursor), err
+}
+
+func encodePageToken(cursor string) string {
+	if cursor == "" {
+		return ""
 	}
-	return key, nil
+	return base64.URLEncoding.EncodeToString([]byte(cursor))
 }
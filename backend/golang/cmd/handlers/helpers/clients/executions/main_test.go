@@ -0,0 +1,145 @@
+package executions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// newTestStores returns three executions of helper:123, the oldest of them
+// dead-lettered, and one of another account on the same helper ID
+func newTestStores(t *testing.T) *database.Stores {
+	t.Helper()
+	ctx := context.Background()
+	stores := memory.NewStores()
+	base := time.Date(2026, time.January, 2, 10, 0, 0, 0, time.UTC)
+	statuses := []string{execution.StatusDeadLettered, execution.StatusSucceeded, execution.StatusQueued}
+	for i, status := range statuses {
+		stores.Executions.Create(ctx, &types.Execution{
+			ExecutionID: fmt.Sprintf("exec:%d", i),
+			AccountID:   "account-123",
+			HelperID:    "helper:123",
+			Status:      status,
+			CreatedAt:   base.Add(time.Duration(i) * time.Minute).Format(time.RFC3339),
+		})
+	}
+	stores.Executions.Create(ctx, &types.Execution{
+		ExecutionID: "exec:other",
+		AccountID:   "account-456",
+		HelperID:    "helper:123",
+		CreatedAt:   base.Add(time.Hour).Format(time.RFC3339),
+	})
+	return stores
+}
+
+func testAuth(accountID string) *types.AuthContext {
+	return &types.AuthContext{
+		UserID:      "user-1",
+		AccountID:   accountID,
+		Permissions: types.Permissions{CanExecuteHelpers: true},
+	}
+}
+
+func request(method, path string, query map[string]string) events.APIGatewayV2HTTPRequest {
+	event := events.APIGatewayV2HTTPRequest{QueryStringParameters: query}
+	event.RequestContext.HTTP.Method = method
+	event.RequestContext.HTTP.Path = path
+	return event
+}
+
+type listResponse struct {
+	Executions []types.Execution `json:"executions"`
+	NextToken  string            `json:"next_token"`
+	HasMore    bool              `json:"has_more"`
+}
+
+func decode(t *testing.T, response events.APIGatewayV2HTTPResponse, data interface{}) {
+	t.Helper()
+	body := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %v", err)
+	}
+}
+
+func TestHandle_ListPages(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	auth := testAuth("account-123")
+
+	var ids []string
+	query := map[string]string{"limit": "2"}
+	for page := 0; page < 3; page++ {
+		response, _ := handle(ctx, request("GET", "/executions", query), auth, stores)
+		if response.StatusCode != 200 {
+			t.Fatalf("Expected status 200, got %d: %s", response.StatusCode, response.Body)
+		}
+		var list listResponse
+		decode(t, response, &list)
+		for _, e := range list.Executions {
+			ids = append(ids, e.ExecutionID)
+		}
+		if !list.HasMore {
+			break
+		}
+		query = map[string]string{"limit": "2", "next_token": list.NextToken}
+	}
+	if got := fmt.Sprint(ids); got != "[exec:2 exec:1 exec:0]" {
+		t.Errorf("Expected the account's executions newest first, got %s", got)
+	}
+
+	response, _ := handle(ctx, request("GET", "/executions", map[string]string{"next_token": "%%"}), auth, stores)
+	if response.StatusCode != 400 {
+		t.Errorf("Expected status 400 for a bad next_token, got %d", response.StatusCode)
+	}
+}
+
+func TestHandle_ListByHelperAndStatus(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+
+	response, _ := handle(ctx, request("GET", "/executions", map[string]string{"helper_id": "helper:123"}), testAuth("account-123"), stores)
+	var list listResponse
+	decode(t, response, &list)
+	if len(list.Executions) != 3 {
+		t.Errorf("Expected only the account's 3 executions of the helper, got %+v", list.Executions)
+	}
+
+	response, _ = handle(ctx, request("GET", "/executions/dead-lettered", nil), testAuth("account-123"), stores)
+	list = listResponse{}
+	decode(t, response, &list)
+	if len(list.Executions) != 1 || list.Executions[0].ExecutionID != "exec:0" {
+		t.Errorf("Expected only exec:0, got %+v", list.Executions)
+	}
+}
+
+func TestHandle_GetAndCancel(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+
+	if response, _ := handle(ctx, request("GET", "/executions/exec:other", nil), testAuth("account-123"), stores); response.StatusCode != 404 {
+		t.Errorf("Expected status 404 for another account's execution, got %d", response.StatusCode)
+	}
+
+	response, _ := handle(ctx, request("POST", "/executions/exec:2/cancel", nil), testAuth("account-123"), stores)
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", response.StatusCode, response.Body)
+	}
+	if exec, _ := stores.Executions.GetByID(ctx, "exec:2"); exec.Status != execution.StatusCancelled {
+		t.Errorf("Expected exec:2 cancelled, got %s", exec.Status)
+	}
+
+	response, _ = handle(ctx, request("POST", "/executions/exec:1/cancel", nil), testAuth("account-123"), stores)
+	if response.StatusCode != 409 {
+		t.Errorf("Expected status 409 for a finished execution, got %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,176 @@
+package versions
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/myfusionhelper/api/internal/configversion"
+	"github.com/myfusionhelper/api/internal/database"
+	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+// HandleWithAuth routes helper config version requests
+func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	return handle(ctx, event, authCtx, database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg)))
+}
+
+func handle(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	method := event.RequestContext.HTTP.Method
+
+	// /helpers/{helper_id}/versions[/{version}[/rollback]] or /versions/diff
+	parts := strings.Split(strings.TrimPrefix(event.RequestContext.HTTP.Path, "/helpers/"), "/")
+	if len(parts) < 2 || parts[0] == "" || parts[1] != "versions" {
+		return authMiddleware.CreateErrorResponse(404, "Not Found"), nil
+	}
+
+	helper, err := stores.Helpers.GetByID(ctx, parts[0])
+	if err != nil {
+		log.Printf("Failed to get helper %s: %v", parts[0], err)
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	if helper == nil || helper.AccountID != authCtx.AccountID || helper.Status == "deleted" {
+		return authMiddleware.CreateErrorResponse(404, "Helper not found"), nil
+	}
+
+	switch {
+	case len(parts) == 2 && method == "GET":
+		return listVersions(ctx, event, helper, stores)
+	case len(parts) == 3 && parts[2] == "diff" && method == "GET":
+		return diffVersions(ctx, event, helper, stores)
+	case len(parts) == 3 && method == "GET":
+		return getVersion(ctx, parts[2], helper, stores)
+	case len(parts) == 4 && parts[3] == "rollback" && method == "POST":
+		return rollback(ctx, parts[2], helper, authCtx, stores)
+	default:
+		return authMiddleware.CreateErrorResponse(404, "Not Found"), nil
+	}
+}
+
+func listVersions(ctx context.Context, event events.APIGatewayV2HTTPRequest, helper *apitypes.Helper, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	limit, cursor, err := pageParams(event)
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(400, "Invalid next_token"), nil
+	}
+
+	versions, next, err := stores.HelperVersions.List(ctx, helper.HelperID, limit, cursor)
+	if err != nil {
+		log.Printf("Failed to list versions of helper %s: %v", helper.HelperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to list versions"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, "Versions retrieved successfully", map[string]interface{}{
+		"versions":        versions,
+		"current_version": helper.ConfigVersion,
+		"total_count":     len(versions),
+		"next_token":      encodePageToken(next),
+		"has_more":        next != "",
+	}), nil
+}
+
+func getVersion(ctx context.Context, number string, helper *apitypes.Helper, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	version, err := strconv.Atoi(number)
+	if err != nil || version < 1 {
+		return authMiddleware.CreateErrorResponse(400, "Invalid version"), nil
+	}
+
+	found, err := stores.HelperVersions.Get(ctx, helper.HelperID, version)
+	if err != nil {
+		log.Printf("Failed to get version %d of helper %s: %v", version, helper.HelperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to get version"), nil
+	}
+	if found == nil {
+		return authMiddleware.CreateErrorResponse(404, "Version not found"), nil
+	}
+	return authMiddleware.CreateSuccessResponse(200, "Version retrieved successfully", found), nil
+}
+
+// diffVersions compares version from with version to, which defaults to the
+// helper's current version
+func diffVersions(ctx context.Context, event events.APIGatewayV2HTTPRequest, helper *apitypes.Helper, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	from, err := strconv.Atoi(event.QueryStringParameters["from"])
+	if err != nil || from < 1 {
+		return authMiddleware.CreateErrorResponse(400, "from must be a version number"), nil
+	}
+	to := helper.ConfigVersion
+	if raw := event.QueryStringParameters["to"]; raw != "" {
+		if to, err = strconv.Atoi(raw); err != nil || to < 1 {
+			return authMiddleware.CreateErrorResponse(400, "to must be a version number"), nil
+		}
+	}
+
+	changes, err := configversion.Compare(ctx, stores.HelperVersions, helper.HelperID, from, to)
+	if errors.Is(err, configversion.ErrNotFound) {
+		return authMiddleware.CreateErrorResponse(404, "Version not found"), nil
+	} else if err != nil {
+		log.Printf("Failed to diff versions of helper %s: %v", helper.HelperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to diff versions"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, "Versions compared successfully", map[string]interface{}{
+		"from":    from,
+		"to":      to,
+		"changes": changes,
+	}), nil
+}
+
+// rollback restores the config of an earlier version as a new version
+func rollback(ctx context.Context, number string, helper *apitypes.Helper, authCtx *apitypes.AuthContext, stores *database.Stores) (events.APIGatewayV2HTTPResponse, error) {
+	if !authCtx.Permissions.CanManageHelpers {
+		return authMiddleware.CreateErrorResponse(403, "Permission denied"), nil
+	}
+	target, err := strconv.Atoi(number)
+	if err != nil || target < 1 {
+		return authMiddleware.CreateErrorResponse(400, "Invalid version"), nil
+	}
+
+	log.Printf("Rollback helper %s to version %d for account: %s", helper.HelperID, target, authCtx.AccountID)
+
+	version, err := configversion.Rollback(ctx, stores.HelperVersions, helper, target, authCtx.UserID, time.Now())
+	switch {
+	case errors.Is(err, configversion.ErrNotFound):
+		return authMiddleware.CreateErrorResponse(404, "Version not found"), nil
+	case errors.Is(err, configversion.ErrUnchanged):
+		return authMiddleware.CreateErrorResponse(400, err.Error()), nil
+	case errors.Is(err, configversion.ErrConflict):
+		return authMiddleware.CreateErrorResponse(409, "Helper config was changed by someone else, reload and try again"), nil
+	case err != nil:
+		log.Printf("Failed to roll back helper %s: %v", helper.HelperID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to roll back helper"), nil
+	}
+	return authMiddleware.CreateSuccessResponse(200, "Helper rolled back", version), nil
+}
+
+// pageParams parses limit (default 20, max 100) and next_token
+func pageParams(event events.APIGatewayV2HTTPRequest) (int, string, error) {
+	limit := 20
+	if l, err := strconv.Atoi(event.QueryStringParameters["limit"]); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	token := event.QueryStringParameters["next_token"]
+	if token == "" {
+		return limit, "", nil
+	}
+	cursor, err := base64.URLEncoding.DecodeString(token)
+	return limit, string(cursor), err
+}
+
+func encodePageToken(cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString([]byte(cursor))
+}
@@ -0,0 +1,136 @@
+package versions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/myfusionhelper/api/internal/configversion"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// newTestStores returns a helper at version 2, whose config changed tag_id
+// from 1 to 2
+func newTestStores(t *testing.T) *database.Stores {
+	t.Helper()
+	ctx := context.Background()
+	stores := memory.NewStores()
+	helper := &types.Helper{
+		HelperID:  "helper:123",
+		AccountID: "account-123",
+		Status:    "active",
+		Config:    map[string]interface{}{"tag_id": "1"},
+	}
+	stores.Helpers.Create(ctx, helper)
+	if err := configversion.Record(ctx, stores.HelperVersions, helper, time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := configversion.Update(ctx, stores.HelperVersions, helper, map[string]interface{}{"tag_id": "2"}, "user-1", time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return stores
+}
+
+func testAuth(accountID string) *types.AuthContext {
+	return &types.AuthContext{
+		UserID:      "user-2",
+		AccountID:   accountID,
+		Permissions: types.Permissions{CanManageHelpers: true},
+	}
+}
+
+func request(method, path string, query map[string]string) events.APIGatewayV2HTTPRequest {
+	event := events.APIGatewayV2HTTPRequest{QueryStringParameters: query}
+	event.RequestContext.HTTP.Method = method
+	event.RequestContext.HTTP.Path = path
+	return event
+}
+
+func decode(t *testing.T, response events.APIGatewayV2HTTPResponse, data interface{}) {
+	t.Helper()
+	body := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %v", err)
+	}
+}
+
+func TestHandle_ListAndDiff(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	auth := testAuth("account-123")
+
+	response, _ := handle(ctx, request("GET", "/helpers/helper:123/versions", nil), auth, stores)
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", response.StatusCode, response.Body)
+	}
+	var list struct {
+		Versions       []types.HelperConfigVersion `json:"versions"`
+		CurrentVersion int                         `json:"current_version"`
+	}
+	decode(t, response, &list)
+	if len(list.Versions) != 2 || list.Versions[0].Version != 2 || list.Versions[0].CreatedBy != "user-1" || list.CurrentVersion != 2 {
+		t.Errorf("Expected versions 2 and 1 with 2 current, got %+v", list)
+	}
+
+	response, _ = handle(ctx, request("GET", "/helpers/helper:123/versions/diff", map[string]string{"from": "1"}), auth, stores)
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", response.StatusCode, response.Body)
+	}
+	var diff struct {
+		To      int                  `json:"to"`
+		Changes []types.ConfigChange `json:"changes"`
+	}
+	decode(t, response, &diff)
+	if diff.To != 2 || len(diff.Changes) != 1 || diff.Changes[0].Old != "1" || diff.Changes[0].New != "2" {
+		t.Errorf("Expected tag_id to change from 1 to 2, got %+v", diff)
+	}
+
+	response, _ = handle(ctx, request("GET", "/helpers/helper:123/versions/7", nil), auth, stores)
+	if response.StatusCode != 404 {
+		t.Errorf("Expected status 404 for a missing version, got %d", response.StatusCode)
+	}
+
+	response, _ = handle(ctx, request("GET", "/helpers/helper:123/versions", nil), testAuth("account-999"), stores)
+	if response.StatusCode != 404 {
+		t.Errorf("Expected status 404 for another account, got %d", response.StatusCode)
+	}
+}
+
+func TestHandle_Rollback(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	auth := testAuth("account-123")
+
+	readOnly := testAuth("account-123")
+	readOnly.Permissions.CanManageHelpers = false
+	response, _ := handle(ctx, request("POST", "/helpers/helper:123/versions/1/rollback", nil), readOnly, stores)
+	if response.StatusCode != 403 {
+		t.Errorf("Expected status 403 without manage permission, got %d", response.StatusCode)
+	}
+
+	response, _ = handle(ctx, request("POST", "/helpers/helper:123/versions/1/rollback", nil), auth, stores)
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", response.StatusCode, response.Body)
+	}
+	var version types.HelperConfigVersion
+	decode(t, response, &version)
+	if version.Version != 3 || version.RollbackOf != 1 || version.CreatedBy != "user-2" {
+		t.Errorf("Expected version 3 rolling back to 1 by user-2, got %+v", version)
+	}
+
+	helper, _ := stores.Helpers.GetByID(ctx, "helper:123")
+	if helper.ConfigVersion != 3 || helper.Config["tag_id"] != "1" {
+		t.Errorf("Expected the helper back on tag_id 1 at version 3, got %d %v", helper.ConfigVersion, helper.Config)
+	}
+
+	response, _ = handle(ctx, request("POST", "/helpers/helper:123/versions/1/rollback", nil), auth, stores)
+	if response.StatusCode != 400 {
+		t.Errorf("Expected status 400 rolling back to the current config, got %d", response.StatusCode)
+	}
+}
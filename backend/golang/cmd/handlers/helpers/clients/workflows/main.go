@@ -0,0 +1,228 @@
+package workflows
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/myfusionhelper/api/internal/database"
+	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+	"github.com/myfusionhelper/api/internal/workflow"
+)
+
+var (
+	workflowRunsTable = os.Getenv("WORKFLOW_RUNS_TABLE")
+)
+
+// HandleWithAuth routes workflow run requests
+func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	path := event.RequestContext.HTTP.Path
+	method := event.RequestContext.HTTP.Method
+
+	switch {
+	case path == "/workflow-runs" && method == "GET":
+		return listRuns(ctx, event, authCtx)
+	case strings.HasPrefix(path, "/workflow-runs/") && strings.HasSuffix(path, "/cancel") && method == "POST":
+		return changeRun(ctx, event, authCtx, "/cancel")
+	case strings.HasPrefix(path, "/workflow-runs/") && strings.HasSuffix(path, "/resume") && method == "POST":
+		return changeRun(ctx, event, authCtx, "/resume")
+	case strings.HasPrefix(path, "/workflow-runs/") && method == "GET":
+		return getRun(ctx, event, authCtx)
+	default:
+		return authMiddleware.CreateErrorResponse(404, "Not Found"), nil
+	}
+}
+
+func listRuns(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	log.Printf("List workflow runs for account: %s", authCtx.AccountID)
+
+	statusFilter := event.QueryStringParameters["status"]
+	limitStr := event.QueryStringParameters["limit"]
+	nextToken := event.QueryStringParameters["next_token"]
+
+	// Parse limit (default 20, max 100)
+	limit := int32(20)
+	if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+		limit = int32(l)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	db := dynamodb.NewFromConfig(cfg)
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(workflowRunsTable),
+		IndexName:              aws.String("AccountIdCreatedAtIndex"),
+		KeyConditionExpression: aws.String("account_id = :account_id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":account_id": &ddbtypes.AttributeValueMemberS{Value: authCtx.AccountID},
+		},
+		ScanIndexForward: aws.Bool(false), // newest first
+		Limit:            aws.Int32(limit),
+	}
+
+	// Optional status filter
+	if statusFilter != "" {
+		queryInput.FilterExpression = aws.String("#s = :status_filter")
+		queryInput.ExpressionAttributeNames = map[string]string{"#s": "status"}
+		queryInput.ExpressionAttributeValues[":status_filter"] = &ddbtypes.AttributeValueMemberS{Value: statusFilter}
+	}
+
+	// Cursor-based pagination
+	if nextToken != "" {
+		startKey, err := decodePageToken(nextToken)
+		if err == nil && startKey != nil {
+			queryInput.ExclusiveStartKey = startKey
+		}
+	}
+
+	result, err := db.Query(ctx, queryInput)
+	if err != nil {
+		log.Printf("Failed to query workflow runs: %v", err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to list workflow runs"), nil
+	}
+
+	runItems := make([]map[string]interface{}, 0, len(result.Items))
+	for _, item := range result.Items {
+		var run workflow.Run
+		if err := attributevalue.UnmarshalMap(item, &run); err != nil {
+			continue
+		}
+		runItems = append(runItems, map[string]interface{}{
+			"run_id":              run.RunID,
+			"helper_id":           run.HelperID,
+			"parent_execution_id": run.ParentExecutionID,
+			"contact_id":          run.ContactID,
+			"status":              run.Status,
+			"step_count":          len(run.Definition.Steps),
+			"next_wake_at":        run.NextWakeAt,
+			"created_at":          run.CreatedAt,
+			"updated_at":          run.UpdatedAt,
+			"completed_at":        run.CompletedAt,
+		})
+	}
+
+	// Build next_token if there are more results
+	var responseNextToken string
+	hasMore := false
+	if result.LastEvaluatedKey != nil {
+		hasMore = true
+		responseNextToken = encodePageToken(result.LastEvaluatedKey)
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, "Workflow runs retrieved successfully", map[string]interface{}{
+		"runs":        runItems,
+		"total_count": len(runItems),
+		"next_token":  responseNextToken,
+		"has_more":    hasMore,
+	}), nil
+}
+
+func getRun(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext) (events.APIGatewayV2HTTPResponse, error) {
+	// Extract run_id from path: /workflow-runs/{run_id}
+	runID := strings.TrimPrefix(event.RequestContext.HTTP.Path, "/workflow-runs/")
+	if runID == "" || strings.Contains(runID, "/") {
+		return authMiddleware.CreateErrorResponse(400, "Run ID is required"), nil
+	}
+
+	log.Printf("Get workflow run %s for account: %s", runID, authCtx.AccountID)
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+
+	run, err := workflow.GetRun(ctx, stores, runID)
+	if err != nil || run.AccountID != authCtx.AccountID {
+		return authMiddleware.CreateErrorResponse(404, "Workflow run not found"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, "Workflow run retrieved successfully", run), nil
+}
+
+// changeRun cancels or resumes a run, depending on the path suffix
+func changeRun(ctx context.Context, event events.APIGatewayV2HTTPRequest, authCtx *apitypes.AuthContext, suffix string) (events.APIGatewayV2HTTPResponse, error) {
+	// Extract run_id from path: /workflow-runs/{run_id}/cancel|resume
+	runID := strings.TrimSuffix(strings.TrimPrefix(event.RequestContext.HTTP.Path, "/workflow-runs/"), suffix)
+	if runID == "" || strings.Contains(runID, "/") {
+		return authMiddleware.CreateErrorResponse(400, "Run ID is required"), nil
+	}
+
+	log.Printf("Workflow run %s %s for account: %s", runID, strings.TrimPrefix(suffix, "/"), authCtx.AccountID)
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		return authMiddleware.CreateErrorResponse(500, "Internal server error"), nil
+	}
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+
+	// Verify account ownership
+	run, err := workflow.GetRun(ctx, stores, runID)
+	if err != nil || run.AccountID != authCtx.AccountID {
+		return authMiddleware.CreateErrorResponse(404, "Workflow run not found"), nil
+	}
+
+	message := "Workflow run cancelled"
+	if suffix == "/resume" {
+		message = "Workflow run resumed"
+		run, err = workflow.Resume(ctx, stores, runID)
+	} else {
+		run, err = workflow.Cancel(ctx, stores, runID)
+	}
+	if err != nil {
+		if errors.Is(err, workflow.ErrInvalidTransition) {
+			return authMiddleware.CreateErrorResponse(409, err.Error()), nil
+		}
+		log.Printf("Failed to update workflow run %s: %v", runID, err)
+		return authMiddleware.CreateErrorResponse(500, "Failed to update workflow run"), nil
+	}
+
+	return authMiddleware.CreateSuccessResponse(200, message, run), nil
+}
+
+// encodePageToken encodes a DynamoDB LastEvaluatedKey as a base64 JSON string
+func encodePageToken(key map[string]ddbtypes.AttributeValue) string {
+	simpleKey := make(map[string]string)
+	for k, v := range key {
+		if sv, ok := v.(*ddbtypes.AttributeValueMemberS); ok {
+			simpleKey[k] = sv.Value
+		}
+	}
+	data, err := json.Marshal(simpleKey)
+	if err != nil {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString(data)
+}
+
+// decodePageToken decodes a base64 page token back to a DynamoDB ExclusiveStartKey
+func decodePageToken(token string) (map[string]ddbtypes.AttributeValue, error) {
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var simpleKey map[string]string
+	if err := json.Unmarshal(data, &simpleKey); err != nil {
+		return nil, err
+	}
+	key := make(map[string]ddbtypes.AttributeValue)
+	for k, v := range simpleKey {
+		key[k] = &ddbtypes.AttributeValueMemberS{Value: v}
+	}
+	return key, nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/myfusionhelper/api/internal/connectors"
 	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
 	authMiddleware "github.com/myfusionhelper/api/internal/middleware/auth"
 	apitypes "github.com/myfusionhelper/api/internal/types"
 )
@@ -39,7 +40,7 @@ func HandleWithAuth(ctx context.Context, event events.APIGatewayV2HTTPRequest, a
 	}
 	db := dynamodb.NewFromConfig(cfg)
 
-	connector, err := loader.LoadConnector(ctx, db, connectionID, authCtx.AccountID)
+	connector, err := loader.LoadConnector(ctx, database.NewDynamoStoresFromEnv(db), connectionID, authCtx.AccountID)
 	if err != nil {
 		log.Printf("Failed to load connector: %v", err)
 		if connErr, ok := err.(*connectors.ConnectorError); ok {
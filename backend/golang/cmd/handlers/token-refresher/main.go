@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+var (
+	notificationQueueURL = os.Getenv("NOTIFICATION_QUEUE_URL")
+)
+
+const (
+	// refreshWindow spans several 15 minute schedule periods so a token gets
+	// more than one refresh attempt before it actually expires.
+	refreshWindow = 45 * time.Minute
+
+	// maxRefreshAttempts is how many consecutive transient failures are
+	// tolerated before the connection is marked expired.
+	maxRefreshAttempts = 5
+)
+
+func main() {
+	lambda.Start(handleScheduleEvent)
+}
+
+func handleScheduleEvent(ctx context.Context) error {
+	log.Println("Token refresher triggered")
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		log.Printf("Failed to load AWS config: %v", err)
+		return err
+	}
+
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+	sqsClient := sqs.NewFromConfig(cfg)
+
+	auths, err := stores.ConnectionAuths.ListExpiring(ctx, time.Now().Add(refreshWindow))
+	if err != nil {
+		log.Printf("Failed to scan expiring credentials: %v", err)
+		return err
+	}
+
+	log.Printf("Found %d OAuth credentials expiring within %s", len(auths), refreshWindow)
+
+	platforms := make(map[string]*apitypes.Platform)
+	refreshed, expired := 0, 0
+
+	for i := range auths {
+		auth := &auths[i]
+
+		connection, err := getConnection(ctx, stores.Connections, auth.ConnectionID)
+		if err != nil {
+			log.Printf("Skipping auth %s: %v", auth.AuthID, err)
+			continue
+		}
+		if connection.Status != "active" {
+			continue
+		}
+
+		platform, ok := platforms[connection.PlatformID]
+		if !ok {
+			platform, err = getPlatform(ctx, stores.Platforms, connection.PlatformID)
+			if err != nil {
+				log.Printf("Skipping auth %s: %v", auth.AuthID, err)
+				continue
+			}
+			platforms[connection.PlatformID] = platform
+		}
+
+		if _, err := loader.RefreshAuth(ctx, stores.ConnectionAuths, auth, platform); err != nil {
+			log.Printf("Failed to refresh auth %s for connection %s: %v", auth.AuthID, connection.ConnectionID, err)
+
+			// RefreshAuth has already counted this failure on the auth record
+			if loader.IsPermanentRefreshError(err) || auth.RefreshAttempts+1 >= maxRefreshAttempts {
+				markConnectionExpired(ctx, stores, connection, auth, err)
+				sendConnectionIssue(ctx, sqsClient, connection, platform, err)
+				expired++
+			}
+			continue
+		}
+
+		refreshed++
+	}
+
+	log.Printf("Token refresher finished: %d refreshed, %d marked expired", refreshed, expired)
+	return nil
+}
+
+func getConnection(ctx context.Context, connections database.ConnectionStore, connectionID string) (*apitypes.PlatformConnection, error) {
+	connection, err := connections.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if connection == nil {
+		return nil, fmt.Errorf("connection %s not found", connectionID)
+	}
+	return connection, nil
+}
+
+func getPlatform(ctx context.Context, platforms database.PlatformStore, platformID string) (*apitypes.Platform, error) {
+	platform, err := platforms.GetByID(ctx, platformID)
+	if err != nil {
+		return nil, err
+	}
+	if platform == nil {
+		return nil, fmt.Errorf("platform %s not found", platformID)
+	}
+	return platform, nil
+}
+
+// markConnectionExpired flags the connection and its credentials so the UI
+// prompts the user to reconnect and the refresher stops retrying.
+func markConnectionExpired(ctx context.Context, stores *database.Stores, connection *apitypes.PlatformConnection, auth *apitypes.PlatformConnectionAuth, refreshErr error) {
+	if err := stores.Connections.SetStatus(ctx, connection.ConnectionID, "expired"); err != nil {
+		log.Printf("Failed to mark connection %s expired: %v", connection.ConnectionID, err)
+	}
+	if err := stores.ConnectionAuths.MarkExpired(ctx, auth.AuthID, refreshErr.Error()); err != nil {
+		log.Printf("Failed to mark auth %s expired: %v", auth.AuthID, err)
+	}
+}
+
+func sendConnectionIssue(ctx context.Context, sqsClient *sqs.Client, connection *apitypes.PlatformConnection, platform *apitypes.Platform, refreshErr error) {
+	if notificationQueueURL == "" {
+		log.Printf("NOTIFICATION_QUEUE_URL not set, skipping connection issue notification")
+		return
+	}
+
+	notification := map[string]interface{}{
+		"type":       "connection_issue",
+		"user_id":    connection.UserID,
+		"account_id": connection.AccountID,
+		"data": map[string]interface{}{
+			"connection_name": connection.Name,
+			"connection_id":   connection.ConnectionID,
+			"platform_name":   platform.Name,
+			"reason":          "token_refresh_failed",
+			"error_message":   refreshErr.Error(),
+		},
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Failed to marshal notification: %v", err)
+		return
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:       aws.String(notificationQueueURL),
+		MessageBody:    aws.String(string(body)),
+		MessageGroupId: aws.String(connection.AccountID),
+	})
+	if err != nil {
+		log.Printf("Failed to send connection issue notification: %v", err)
+	} else {
+		log.Printf("Sent connection issue notification for connection %s", connection.ConnectionID)
+	}
+}
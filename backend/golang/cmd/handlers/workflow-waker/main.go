@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/workflow"
+)
+
+func main() {
+	lambda.Start(handleScheduleEvent)
+}
+
+// handleScheduleEvent dispatches workflow steps whose wait_seconds have elapsed
+// and steps whose executions were never created
+func handleScheduleEvent(ctx context.Context) error {
+	log.Println("Workflow waker triggered")
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	if err != nil {
+		log.Printf("Failed to load AWS config: %v", err)
+		return err
+	}
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+
+	woken, err := workflow.WakeDue(ctx, stores, time.Now())
+	if err != nil {
+		log.Printf("Failed to wake due workflow runs: %v", err)
+		return err
+	}
+
+	log.Printf("Woke %d workflow run(s)", woken)
+	return nil
+}
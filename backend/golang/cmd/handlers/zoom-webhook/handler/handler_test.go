@@ -1,4 +1,4 @@
-package main
+package handler
 
 import (
 	"context"
@@ -140,9 +140,9 @@ func TestHandleRequest_InvalidJSON(t *testing.T) {
 		},
 	}
 
-	resp, err := handleRequest(context.Background(), event)
+	resp, err := Handle(context.Background(), event)
 	if err != nil {
-		t.Fatalf("handleRequest failed: %v", err)
+		t.Fatalf("Handle failed: %v", err)
 	}
 
 	if resp.StatusCode != 400 {
@@ -169,9 +169,9 @@ func TestHandleRequest_UnhandledEvent(t *testing.T) {
 		Headers: map[string]string{},
 	}
 
-	resp, err := handleRequest(context.Background(), event)
+	resp, err := Handle(context.Background(), event)
 	if err != nil {
-		t.Fatalf("handleRequest failed: %v", err)
+		t.Fatalf("Handle failed: %v", err)
 	}
 
 	if resp.StatusCode != 200 {
@@ -0,0 +1,310 @@
+// Package batch runs one helper over a set of contacts. A batch is created
+// with a contact source; the runner reads the source page by page and
+// dispatches one execution per contact at a rate derived from the CRM
+// connection's API limits, and the helper workers report each outcome back.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Batch statuses
+const (
+	StatusRunning   = "running"
+	StatusPaused    = "paused"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// Item statuses
+const (
+	ItemQueued    = "queued"
+	ItemCompleted = "completed"
+	ItemFailed    = "failed"
+)
+
+// Source types
+const (
+	SourceContactIDs   = "contact_ids"
+	SourceTag          = "tag"
+	SourceQuery        = "query"
+	SourceDataExplorer = "data_explorer"
+)
+
+// TriggerType is recorded on executions dispatched for batches
+const TriggerType = "batch"
+
+// MaxContactIDs caps explicit contact ID lists, which are stored on the batch
+// record; larger segments should use a tag, query or data explorer source
+const MaxContactIDs = 10000
+
+// DefaultRatePerMinute is the dispatch rate for connections whose platform
+// publishes no API limits
+const DefaultRatePerMinute = 120
+
+// callsPerExecution estimates the CRM requests one helper execution makes
+const callsPerExecution = 4
+
+// maxSaveAttempts bounds optimistic-lock retries when a batch is changed concurrently
+const maxSaveAttempts = 5
+
+// itemTTL is how long per-contact results are kept
+const itemTTL = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidRequest is returned for batch requests that cannot be run
+	ErrInvalidRequest = errors.New("invalid batch request")
+	// ErrHelperNotFound is returned when the helper does not exist in the account
+	ErrHelperNotFound = errors.New("helper not found")
+	// ErrNotFound is returned when a batch does not exist in the account
+	ErrNotFound = errors.New("batch not found")
+	// ErrInvalidTransition is returned when a batch cannot be paused, resumed
+	// or cancelled from its current status
+	ErrInvalidTransition = errors.New("invalid batch transition")
+)
+
+// Request describes a batch to create
+type Request struct {
+	// BatchID is optional; callers that must know the ID before the batch
+	// exists, such as snapshot writers, get one from NewID
+	BatchID      string
+	AccountID    string
+	UserID       string
+	HelperID     string
+	Source       types.BatchSource
+	Input        map[string]interface{}
+	MaxPerMinute int
+	// Total is the number of contacts in a data explorer snapshot
+	Total int
+}
+
+// NewID returns a new batch ID
+func NewID() string {
+	return "batch:" + uuid.Must(uuid.NewV7()).String()
+}
+
+// Create validates the request and stores a running batch. The runner
+// dispatches its first contacts on its next tick.
+func Create(ctx context.Context, stores *database.Stores, req Request, now time.Time) (*types.Batch, error) {
+	helper, err := helpers.ResolveHelper(ctx, stores.Helpers, req.HelperID)
+	if err != nil || helper == nil || helper.AccountID != req.AccountID {
+		return nil, ErrHelperNotFound
+	}
+	if !helper.Enabled {
+		return nil, fmt.Errorf("%w: helper is disabled", ErrInvalidRequest)
+	}
+
+	source, err := normalizeSource(req.Source)
+	if err != nil {
+		return nil, err
+	}
+	if source.Type != SourceContactIDs && helper.ConnectionID == "" {
+		return nil, fmt.Errorf("%w: a %s source needs a helper with a CRM connection", ErrInvalidRequest, source.Type)
+	}
+
+	limits := types.RateLimitConfig{}
+	if helper.ConnectionID != "" {
+		limits = connectionLimits(ctx, stores, helper.ConnectionID)
+	}
+
+	timestamp := now.UTC().Format(time.RFC3339)
+	batch := &types.Batch{
+		BatchID:       req.BatchID,
+		AccountID:     req.AccountID,
+		UserID:        req.UserID,
+		HelperID:      helper.HelperID,
+		ConnectionID:  helper.ConnectionID,
+		Source:        source,
+		Input:         req.Input,
+		Status:        StatusRunning,
+		RatePerMinute: RateFor(limits, req.MaxPerMinute),
+		Total:         req.Total,
+		CreatedAt:     timestamp,
+		UpdatedAt:     timestamp,
+	}
+	if batch.BatchID == "" {
+		batch.BatchID = NewID()
+	}
+	if source.Type == SourceContactIDs {
+		batch.Total = len(source.ContactIDs)
+	}
+
+	if err := stores.Batches.Create(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+	return batch, nil
+}
+
+// normalizeSource checks that the source names its contacts and drops blank
+// and repeated contact IDs
+func normalizeSource(source types.BatchSource) (types.BatchSource, error) {
+	switch source.Type {
+	case SourceContactIDs:
+		seen := make(map[string]bool, len(source.ContactIDs))
+		ids := make([]string, 0, len(source.ContactIDs))
+		for _, id := range source.ContactIDs {
+			id = strings.TrimSpace(id)
+			if id == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		if len(ids) == 0 {
+			return source, fmt.Errorf("%w: contact_ids is empty", ErrInvalidRequest)
+		}
+		if len(ids) > MaxContactIDs {
+			return source, fmt.Errorf("%w: at most %d contact_ids are allowed, use a tag or query source for larger segments", ErrInvalidRequest, MaxContactIDs)
+		}
+		return types.BatchSource{Type: source.Type, ContactIDs: ids}, nil
+	case SourceTag:
+		if strings.TrimSpace(source.TagID) == "" {
+			return source, fmt.Errorf("%w: tag_id is required", ErrInvalidRequest)
+		}
+		return types.BatchSource{Type: source.Type, TagID: strings.TrimSpace(source.TagID)}, nil
+	case SourceQuery:
+		if source.Query == nil {
+			return source, fmt.Errorf("%w: query is required", ErrInvalidRequest)
+		}
+		return types.BatchSource{Type: source.Type, Query: source.Query}, nil
+	case SourceDataExplorer:
+		if source.SnapshotKey == "" {
+			return source, fmt.Errorf("%w: data explorer segments are created through /data/batches", ErrInvalidRequest)
+		}
+		return source, nil
+	default:
+		return source, fmt.Errorf("%w: unknown source type %q", ErrInvalidRequest, source.Type)
+	}
+}
+
+// connectionLimits returns the API limits of the connection's platform, or
+// none if they cannot be read
+func connectionLimits(ctx context.Context, stores *database.Stores, connectionID string) types.RateLimitConfig {
+	conn, err := stores.Connections.GetByID(ctx, connectionID)
+	if err != nil || conn == nil {
+		return types.RateLimitConfig{}
+	}
+	platform, err := stores.Platforms.GetByID(ctx, conn.PlatformID)
+	if err != nil || platform == nil {
+		return types.RateLimitConfig{}
+	}
+	return platform.APIConfig.RateLimits
+}
+
+// RateFor returns the executions per minute a batch may dispatch on a
+// connection with the given API limits. A batch uses at most half of the
+// tightest limit, so live helper traffic keeps the rest. A requested rate can
+// only lower the result.
+func RateFor(limits types.RateLimitConfig, requested int) int {
+	budget := 0
+	for _, perMinute := range []int{limits.RequestsPerMinute, limits.RequestsPerSecond * 60, limits.RequestsPerHour / 60} {
+		if perMinute > 0 && (budget == 0 || perMinute < budget) {
+			budget = perMinute
+		}
+	}
+
+	rate := DefaultRatePerMinute
+	// An hourly limit under 60 rounds to no budget at all and gets the minimum rate
+	if budget > 0 || limits.RequestsPerHour > 0 {
+		rate = budget / 2 / callsPerExecution
+	}
+	if requested > 0 && requested < rate {
+		rate = requested
+	}
+	if rate < 1 {
+		rate = 1
+	}
+	return rate
+}
+
+// Get returns a batch of the account
+func Get(ctx context.Context, batches database.BatchStore, accountID, batchID string) (*types.Batch, error) {
+	batch, err := batches.GetByID(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	if batch == nil || batch.AccountID != accountID {
+		return nil, ErrNotFound
+	}
+	return batch, nil
+}
+
+// Pause stops dispatching; executions already queued still finish
+func Pause(ctx context.Context, batches database.BatchStore, accountID, batchID string) (*types.Batch, error) {
+	return update(ctx, batches, accountID, batchID, func(batch *types.Batch, now time.Time) error {
+		if batch.Status != StatusRunning {
+			return fmt.Errorf("%w: only running batches can be paused, batch is %s", ErrInvalidTransition, batch.Status)
+		}
+		batch.Status = StatusPaused
+		batch.StatusReason = "paused by user"
+		return nil
+	})
+}
+
+// Resume restarts dispatching for a paused or failed batch from where it stopped
+func Resume(ctx context.Context, batches database.BatchStore, accountID, batchID string) (*types.Batch, error) {
+	return update(ctx, batches, accountID, batchID, func(batch *types.Batch, now time.Time) error {
+		if batch.Status != StatusPaused && batch.Status != StatusFailed {
+			return fmt.Errorf("%w: only paused or failed batches can be resumed, batch is %s", ErrInvalidTransition, batch.Status)
+		}
+		batch.Status = StatusRunning
+		batch.StatusReason = ""
+		batch.LastDispatchAt = ""
+		batch.CompletedAt = ""
+		return nil
+	})
+}
+
+// Cancel stops the batch for good; contacts not yet dispatched are dropped
+func Cancel(ctx context.Context, batches database.BatchStore, accountID, batchID string) (*types.Batch, error) {
+	return update(ctx, batches, accountID, batchID, func(batch *types.Batch, now time.Time) error {
+		if isTerminal(batch.Status) {
+			return fmt.Errorf("%w: batch is already %s", ErrInvalidTransition, batch.Status)
+		}
+		batch.Status = StatusCancelled
+		batch.StatusReason = "cancelled by user"
+		batch.Pending = nil
+		batch.CompletedAt = now.UTC().Format(time.RFC3339)
+		return nil
+	})
+}
+
+func isTerminal(status string) bool {
+	return status == StatusCompleted || status == StatusCancelled
+}
+
+// update applies change to the latest batch state and saves it under the
+// batch's optimistic lock, retrying when the runner saved in between
+func update(ctx context.Context, batches database.BatchStore, accountID, batchID string, change func(batch *types.Batch, now time.Time) error) (*types.Batch, error) {
+	for attempt := 1; ; attempt++ {
+		batch, err := Get(ctx, batches, accountID, batchID)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		if err := change(batch, now); err != nil {
+			return nil, err
+		}
+		batch.UpdatedAt = now.Format(time.RFC3339)
+
+		if err := batches.Save(ctx, batch); err != nil {
+			if errors.Is(err, database.ErrConditionFailed) && attempt < maxSaveAttempts {
+				continue
+			}
+			return nil, fmt.Errorf("failed to save batch: %w", err)
+		}
+		return batch, nil
+	}
+}
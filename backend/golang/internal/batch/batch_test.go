@@ -0,0 +1,134 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+var testNow = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+func newTestStores(t *testing.T) *database.Stores {
+	t.Helper()
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Accounts.Create(ctx, &types.Account{AccountID: "acc-1"})
+	helpers := []types.Helper{
+		{HelperID: "helper:score", AccountID: "acc-1", HelperType: "score_it", ConnectionID: "conn-1", Enabled: true,
+			Config: map[string]interface{}{"points": float64(5)}},
+		{HelperID: "helper:local", AccountID: "acc-1", HelperType: "format_it", Enabled: true},
+		{HelperID: "helper:off", AccountID: "acc-1", HelperType: "format_it", Enabled: false},
+		{HelperID: "helper:other", AccountID: "acc-2", HelperType: "format_it", Enabled: true},
+	}
+	for i := range helpers {
+		stores.Helpers.Create(ctx, &helpers[i])
+	}
+	return stores
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+
+	batch, err := Create(ctx, stores, Request{
+		AccountID: "acc-1",
+		HelperID:  "helper:score",
+		Source:    types.BatchSource{Type: SourceContactIDs, ContactIDs: []string{"1", " 2 ", "1", ""}},
+	}, testNow)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if batch.Status != StatusRunning || batch.Total != 2 || batch.ConnectionID != "conn-1" {
+		t.Errorf("Expected a running batch of 2 contacts on conn-1, got %+v", batch)
+	}
+	if got := batch.Source.ContactIDs; len(got) != 2 || got[1] != "2" {
+		t.Errorf("Expected deduplicated contact IDs, got %v", got)
+	}
+	if batch.RatePerMinute != DefaultRatePerMinute {
+		t.Errorf("Expected the default rate without platform limits, got %d", batch.RatePerMinute)
+	}
+
+	tests := []struct {
+		name   string
+		req    Request
+		expect error
+	}{
+		{"empty contact list", Request{HelperID: "helper:score", Source: types.BatchSource{Type: SourceContactIDs}}, ErrInvalidRequest},
+		{"missing tag", Request{HelperID: "helper:score", Source: types.BatchSource{Type: SourceTag}}, ErrInvalidRequest},
+		{"tag without connection", Request{HelperID: "helper:local", Source: types.BatchSource{Type: SourceTag, TagID: "101"}}, ErrInvalidRequest},
+		{"data explorer without snapshot", Request{HelperID: "helper:score", Source: types.BatchSource{Type: SourceDataExplorer}}, ErrInvalidRequest},
+		{"unknown source", Request{HelperID: "helper:score", Source: types.BatchSource{Type: "everyone"}}, ErrInvalidRequest},
+		{"disabled helper", Request{HelperID: "helper:off", Source: types.BatchSource{Type: SourceContactIDs, ContactIDs: []string{"1"}}}, ErrInvalidRequest},
+		{"other account's helper", Request{HelperID: "helper:other", Source: types.BatchSource{Type: SourceContactIDs, ContactIDs: []string{"1"}}}, ErrHelperNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.AccountID = "acc-1"
+			if _, err := Create(ctx, stores, tt.req, testNow); !errors.Is(err, tt.expect) {
+				t.Errorf("Expected %v, got %v", tt.expect, err)
+			}
+		})
+	}
+}
+
+func TestRateFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		limits    types.RateLimitConfig
+		requested int
+		expect    int
+	}{
+		{"no limits", types.RateLimitConfig{}, 0, DefaultRatePerMinute},
+		{"per minute", types.RateLimitConfig{RequestsPerMinute: 240}, 0, 30},
+		{"per second", types.RateLimitConfig{RequestsPerSecond: 4}, 0, 30},
+		{"tightest wins", types.RateLimitConfig{RequestsPerSecond: 25, RequestsPerHour: 3600}, 0, 7},
+		{"tiny hourly limit", types.RateLimitConfig{RequestsPerHour: 30}, 0, 1},
+		{"requested lowers", types.RateLimitConfig{RequestsPerMinute: 240}, 10, 10},
+		{"requested cannot raise", types.RateLimitConfig{RequestsPerMinute: 240}, 500, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RateFor(tt.limits, tt.requested); got != tt.expect {
+				t.Errorf("Expected %d, got %d", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	batch, _ := Create(ctx, stores, Request{
+		AccountID: "acc-1",
+		HelperID:  "helper:local",
+		Source:    types.BatchSource{Type: SourceContactIDs, ContactIDs: []string{"1", "2"}},
+	}, testNow)
+
+	if _, err := Resume(ctx, stores.Batches, "acc-1", batch.BatchID); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected resuming a running batch to fail, got %v", err)
+	}
+	if _, err := Pause(ctx, stores.Batches, "acc-2", batch.BatchID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another account's batch to be not found, got %v", err)
+	}
+
+	paused, err := Pause(ctx, stores.Batches, "acc-1", batch.BatchID)
+	if err != nil || paused.Status != StatusPaused {
+		t.Fatalf("Expected paused, got %v %v", paused, err)
+	}
+	resumed, err := Resume(ctx, stores.Batches, "acc-1", batch.BatchID)
+	if err != nil || resumed.Status != StatusRunning || resumed.StatusReason != "" {
+		t.Fatalf("Expected running again, got %v %v", resumed, err)
+	}
+	cancelled, err := Cancel(ctx, stores.Batches, "acc-1", batch.BatchID)
+	if err != nil || cancelled.Status != StatusCancelled || cancelled.CompletedAt == "" {
+		t.Fatalf("Expected cancelled, got %v %v", cancelled, err)
+	}
+	if _, err := Cancel(ctx, stores.Batches, "acc-1", batch.BatchID); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected cancelling twice to fail, got %v", err)
+	}
+}
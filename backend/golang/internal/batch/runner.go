@@ -0,0 +1,265 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/ratelimit"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// maxPagesPerTick bounds the source pages read for one batch per tick, so a
+// source that keeps returning already dispatched contacts cannot stall the runner
+const maxPagesPerTick = 20
+
+// ConnectFunc loads the CRM connector of a connection
+type ConnectFunc func(ctx context.Context, stores *database.Stores, connectionID, accountID string) (connectors.CRMConnector, error)
+
+// Runner dispatches the contacts of running batches. It is called once a
+// minute and dispatches at most a minute's worth of each batch's rate.
+type Runner struct {
+	Stores    *database.Stores
+	Snapshots Snapshots
+	Connect   ConnectFunc
+}
+
+// NewRunner creates a runner that reads CRM sources through the connection loader
+func NewRunner(stores *database.Stores, snapshots Snapshots) *Runner {
+	return &Runner{Stores: stores, Snapshots: snapshots, Connect: loader.LoadConnectorWithTranslation}
+}
+
+// RunDue advances every running batch and returns how many executions were
+// dispatched. Batches on the same connection split its rate between them.
+func (r *Runner) RunDue(ctx context.Context, now time.Time) (int, error) {
+	running, err := r.Stores.Batches.ListRunning(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list running batches: %w", err)
+	}
+
+	sharing := make(map[string]int, len(running))
+	for _, batch := range running {
+		sharing[batch.ConnectionID]++
+	}
+
+	dispatched := 0
+	for _, batch := range running {
+		n, err := r.run(ctx, batch.BatchID, sharing[batch.ConnectionID], now)
+		if err != nil {
+			log.Printf("Failed to run batch %s: %v", batch.BatchID, err)
+		}
+		dispatched += n
+	}
+	return dispatched, nil
+}
+
+// run dispatches one batch's share of its rate and saves its progress
+func (r *Runner) run(ctx context.Context, batchID string, share int, now time.Time) (int, error) {
+	// The running index is eventually consistent; work from the latest state
+	batch, err := r.Stores.Batches.GetByID(ctx, batchID)
+	if err != nil {
+		return 0, err
+	}
+	if batch == nil || batch.Status != StatusRunning {
+		return 0, nil
+	}
+
+	dispatched := r.dispatch(ctx, batch, share, now)
+	if dispatched > 0 {
+		log.Printf("Batch %s dispatched %d executions (%d of %d)", batch.BatchID, dispatched, batch.Dispatched, batch.Total)
+	}
+	if batch.Status == StatusRunning && batch.SourceDone && len(batch.Pending) == 0 &&
+		batch.Succeeded+batch.Failed >= batch.Dispatched {
+		batch.Status = StatusCompleted
+		batch.CompletedAt = now.UTC().Format(time.RFC3339)
+	}
+	batch.UpdatedAt = now.UTC().Format(time.RFC3339)
+	return dispatched, r.save(ctx, batch)
+}
+
+// budget is the number of executions the batch may dispatch now: its share of
+// the rate for the time since it last dispatched, at most one minute's worth
+func budget(batch *types.Batch, share int, now time.Time) int {
+	perMinute := batch.RatePerMinute
+	if share > 1 {
+		perMinute /= share
+	}
+	if perMinute < 1 {
+		perMinute = 1
+	}
+	last, err := time.Parse(time.RFC3339, batch.LastDispatchAt)
+	if err != nil || now.Sub(last) >= time.Minute {
+		return perMinute
+	}
+	return int(float64(perMinute) * now.Sub(last).Minutes())
+}
+
+// dispatch creates executions for pending contacts, reading more from the
+// source as needed, until the budget is spent or the source is exhausted
+func (r *Runner) dispatch(ctx context.Context, batch *types.Batch, share int, now time.Time) int {
+	allowed := budget(batch, share, now)
+	if allowed < 1 || (batch.SourceDone && len(batch.Pending) == 0) {
+		return 0
+	}
+
+	helper, err := r.Stores.Helpers.GetByID(ctx, batch.HelperID)
+	if err != nil {
+		log.Printf("Failed to load helper %s for batch %s: %v", batch.HelperID, batch.BatchID, err)
+		return 0
+	}
+	if helper == nil || helper.AccountID != batch.AccountID || !helper.Enabled {
+		batch.Status = StatusPaused
+		batch.StatusReason = "helper is disabled or deleted"
+		return 0
+	}
+	account, err := r.Stores.Accounts.GetByID(ctx, batch.AccountID)
+	if err != nil || account == nil {
+		log.Printf("Failed to load account %s for batch %s: %v", batch.AccountID, batch.BatchID, err)
+		return 0
+	}
+	limiter := ratelimit.New(r.Stores.Accounts, r.Stores.Counters)
+
+	src := &source{runner: r, batch: batch}
+	dispatched, pages := 0, 0
+	for dispatched < allowed {
+		if len(batch.Pending) == 0 {
+			if batch.SourceDone || pages == maxPagesPerTick {
+				break
+			}
+			pages++
+			if err := src.next(ctx); err != nil {
+				// Missing connections, revoked credentials and unknown tags
+				// won't fix themselves; other errors are retried next tick
+				var connErr *connectors.ConnectorError
+				if (errors.As(err, &connErr) && !connErr.Retryable) || errors.Is(err, ErrInvalidRequest) {
+					batch.Status = StatusFailed
+					batch.StatusReason = "failed to read contacts: " + err.Error()
+				} else {
+					log.Printf("Failed to read contacts for batch %s: %v", batch.BatchID, err)
+				}
+				break
+			}
+			continue
+		}
+
+		monthly, err := limiter.CheckMonthlyLimit(ctx, batch.AccountID, account.Settings.MaxExecutions)
+		if err != nil {
+			log.Printf("Failed to check monthly limit for batch %s: %v", batch.BatchID, err)
+			// Don't block on rate limit errors — allow execution
+		} else if !monthly.Allowed {
+			batch.Status = StatusPaused
+			batch.StatusReason = "monthly execution limit reached"
+			break
+		}
+
+		added, err := r.dispatchContact(ctx, batch, helper, batch.Pending[0], now)
+		if !added && account.Settings.MaxExecutions > 0 {
+			// Nothing was executed; give back the count CheckMonthlyLimit took
+			r.Stores.Accounts.IncrementMonthlyExecutions(ctx, batch.AccountID, -1)
+		}
+		if err != nil {
+			// The contact stays pending for the next tick
+			log.Printf("Failed to dispatch batch %s contact %s: %v", batch.BatchID, batch.Pending[0], err)
+			break
+		}
+		batch.Pending = batch.Pending[1:]
+		if added {
+			dispatched++
+		}
+	}
+
+	if dispatched > 0 {
+		batch.LastDispatchAt = now.UTC().Format(time.RFC3339)
+	}
+	return dispatched
+}
+
+// dispatchContact records the contact's item and creates its execution with
+// the helper's current config. It reports false for contacts the batch has
+// already dispatched.
+func (r *Runner) dispatchContact(ctx context.Context, batch *types.Batch, helper *types.Helper, contactID string, now time.Time) (bool, error) {
+	timestamp := now.UTC().Format(time.RFC3339)
+	expires := now.Add(itemTTL).Unix()
+	item := &types.BatchItem{
+		BatchID:     batch.BatchID,
+		ContactID:   contactID,
+		ExecutionID: "exec:" + uuid.Must(uuid.NewV7()).String(),
+		Status:      ItemQueued,
+		CreatedAt:   timestamp,
+		TTL:         &expires,
+	}
+	if err := r.Stores.Batches.AddItem(ctx, item); errors.Is(err, database.ErrConditionFailed) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to record item: %w", err)
+	}
+	batch.Dispatched++
+
+	ttl := now.Add(7 * 24 * time.Hour).Unix()
+	exec := &types.Execution{
+		ExecutionID:   item.ExecutionID,
+		HelperID:      helper.HelperID,
+		HelperType:    helper.HelperType,
+		AccountID:     batch.AccountID,
+		UserID:        batch.UserID,
+		ConnectionID:  helper.ConnectionID,
+		ContactID:     contactID,
+		Config:        helper.Config,
+		ConfigVersion: helper.ConfigVersion,
+		Status:        execution.StatusQueued,
+		TriggerType:   TriggerType,
+		Input:         batch.Input,
+		BatchID:       batch.BatchID,
+		CreatedAt:     timestamp,
+		StartedAt:     now.UTC(),
+		TTL:           &ttl,
+	}
+	if err := r.Stores.Executions.Create(ctx, exec); err != nil {
+		log.Printf("Failed to create execution for batch %s contact %s: %v", batch.BatchID, contactID, err)
+		if _, err := r.Stores.Batches.CompleteItem(ctx, batch.BatchID, contactID, ItemFailed, "failed to create execution", now); err != nil {
+			log.Printf("Failed to record batch %s item %s failure: %v", batch.BatchID, contactID, err)
+		}
+	}
+	return true, nil
+}
+
+// save stores the runner's progress. If the batch was paused or cancelled in
+// the meantime, the progress is merged into the latest state, which keeps
+// its status.
+func (r *Runner) save(ctx context.Context, batch *types.Batch) error {
+	for attempt := 1; ; attempt++ {
+		err := r.Stores.Batches.Save(ctx, batch)
+		if err == nil || !errors.Is(err, database.ErrConditionFailed) || attempt == maxSaveAttempts {
+			return err
+		}
+
+		latest, err := r.Stores.Batches.GetByID(ctx, batch.BatchID)
+		if err != nil || latest == nil {
+			return fmt.Errorf("failed to reload batch: %w", err)
+		}
+		latest.Dispatched = batch.Dispatched
+		latest.Cursor = batch.Cursor
+		latest.SourceDone = batch.SourceDone
+		latest.LastDispatchAt = batch.LastDispatchAt
+		latest.UpdatedAt = batch.UpdatedAt
+		if latest.Status == StatusCancelled {
+			latest.Pending = nil
+		} else {
+			latest.Pending = batch.Pending
+		}
+		if latest.Status == StatusRunning {
+			latest.Status = batch.Status
+			latest.StatusReason = batch.StatusReason
+			latest.CompletedAt = batch.CompletedAt
+		}
+		batch = latest
+	}
+}
@@ -0,0 +1,265 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/connectors/sandbox"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+func newTestRunner(t *testing.T, stores *database.Stores) *Runner {
+	t.Helper()
+	crm, err := sandbox.New(sandbox.NewMemoryStore(), "batch-test", "")
+	if err != nil {
+		t.Fatalf("Expected sandbox connector, got %v", err)
+	}
+	runner := NewRunner(stores, NewMemorySnapshots())
+	runner.Connect = func(ctx context.Context, stores *database.Stores, connectionID, accountID string) (connectors.CRMConnector, error) {
+		return crm, nil
+	}
+	return runner
+}
+
+// dispatchedContacts returns the contacts with an execution for the batch
+func dispatchedContacts(t *testing.T, stores *database.Stores, batchID string) []string {
+	t.Helper()
+	items, _, err := stores.Batches.ListItems(context.Background(), batchID, "", 0, "")
+	if err != nil {
+		t.Fatalf("Expected no error listing items, got %v", err)
+	}
+	var ids []string
+	for _, item := range items {
+		exec, _ := stores.Executions.GetByID(context.Background(), item.ExecutionID)
+		if exec == nil || exec.BatchID != batchID || exec.ContactID != item.ContactID || exec.TriggerType != TriggerType {
+			t.Errorf("Expected a batch execution for contact %s, got %+v", item.ContactID, exec)
+			continue
+		}
+		ids = append(ids, item.ContactID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// completeAll reports every queued item of the batch as the workers would
+func completeAll(t *testing.T, stores *database.Stores, batchID string) {
+	t.Helper()
+	items, _, _ := stores.Batches.ListItems(context.Background(), batchID, ItemQueued, 0, "")
+	for _, item := range items {
+		stores.Batches.CompleteItem(context.Background(), batchID, item.ContactID, ItemCompleted, "", testNow)
+	}
+}
+
+func TestRunner_ThrottlesContactList(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	runner := newTestRunner(t, stores)
+
+	batch, _ := Create(ctx, stores, Request{
+		AccountID:    "acc-1",
+		HelperID:     "helper:score",
+		Source:       types.BatchSource{Type: SourceContactIDs, ContactIDs: []string{"1", "2", "3", "4", "5"}},
+		Input:        map[string]interface{}{"reason": "backfill"},
+		MaxPerMinute: 2,
+	}, testNow)
+
+	if n, _ := runner.RunDue(ctx, testNow); n != 2 {
+		t.Fatalf("Expected 2 dispatched in the first minute, got %d", n)
+	}
+	if n, _ := runner.RunDue(ctx, testNow.Add(30*time.Second)); n != 1 {
+		t.Errorf("Expected 1 dispatched after half a minute, got %d", n)
+	}
+	if n, _ := runner.RunDue(ctx, testNow.Add(5*time.Minute)); n != 2 {
+		t.Errorf("Expected at most a minute's worth after a gap, got %d", n)
+	}
+	if got := dispatchedContacts(t, stores, batch.BatchID); !reflect.DeepEqual(got, []string{"1", "2", "3", "4", "5"}) {
+		t.Errorf("Expected every contact dispatched once, got %v", got)
+	}
+
+	items, _, _ := stores.Batches.ListItems(ctx, batch.BatchID, "", 1, "")
+	exec, _ := stores.Executions.GetByID(ctx, items[0].ExecutionID)
+	if exec.Config["points"] != float64(5) || exec.Input["reason"] != "backfill" || exec.HelperType != "score_it" {
+		t.Errorf("Expected the helper config and batch input on the execution, got %+v", exec)
+	}
+
+	latest, _ := stores.Batches.GetByID(ctx, batch.BatchID)
+	if latest.Status != StatusRunning || latest.Dispatched != 5 || !latest.SourceDone {
+		t.Fatalf("Expected a running batch with 5 dispatched while results are outstanding, got %+v", latest)
+	}
+
+	completeAll(t, stores, batch.BatchID)
+	runner.RunDue(ctx, testNow.Add(6*time.Minute))
+	latest, _ = stores.Batches.GetByID(ctx, batch.BatchID)
+	if latest.Status != StatusCompleted || latest.Succeeded != 5 || latest.CompletedAt == "" {
+		t.Errorf("Expected the batch completed with 5 succeeded, got %+v", latest)
+	}
+}
+
+func TestRunner_CRMSources(t *testing.T) {
+	tests := []struct {
+		name   string
+		source types.BatchSource
+		expect []string
+	}{
+		{"tag", types.BatchSource{Type: SourceTag, TagID: "101"}, []string{"1", "2", "5"}},
+		{"query", types.BatchSource{Type: SourceQuery, Query: &types.BatchQuery{TagID: "110"}}, []string{"2", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			stores := newTestStores(t)
+			runner := newTestRunner(t, stores)
+
+			batch, err := Create(ctx, stores, Request{AccountID: "acc-1", HelperID: "helper:score", Source: tt.source}, testNow)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			runner.RunDue(ctx, testNow)
+			if got := dispatchedContacts(t, stores, batch.BatchID); !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("Expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestRunner_DataExplorerSnapshot(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	runner := newTestRunner(t, stores)
+
+	var ids []string
+	for i := 0; i < 250; i++ {
+		ids = append(ids, fmt.Sprintf("c%03d", i))
+	}
+	batchID := NewID()
+	key := SnapshotKey("acc-1", batchID)
+	runner.Snapshots.Write(ctx, key, ids)
+
+	batch, err := Create(ctx, stores, Request{
+		BatchID:   batchID,
+		AccountID: "acc-1",
+		HelperID:  "helper:score",
+		Source:    types.BatchSource{Type: SourceDataExplorer, SnapshotKey: key},
+		Total:     len(ids),
+	}, testNow)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for minute := 0; minute < 3; minute++ {
+		runner.RunDue(ctx, testNow.Add(time.Duration(minute)*time.Minute))
+	}
+	if got := dispatchedContacts(t, stores, batch.BatchID); !reflect.DeepEqual(got, ids) {
+		t.Errorf("Expected all %d snapshot contacts dispatched, got %d", len(ids), len(got))
+	}
+	latest, _ := stores.Batches.GetByID(ctx, batch.BatchID)
+	if !latest.SourceDone || len(latest.Pending) != 0 || latest.Total != 250 {
+		t.Errorf("Expected the snapshot read to the end, got %+v", latest)
+	}
+}
+
+func TestRunner_PausesAndStops(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("paused by user", func(t *testing.T) {
+		stores := newTestStores(t)
+		runner := newTestRunner(t, stores)
+		batch, _ := Create(ctx, stores, Request{AccountID: "acc-1", HelperID: "helper:score",
+			Source: types.BatchSource{Type: SourceContactIDs, ContactIDs: []string{"1", "2", "3"}}, MaxPerMinute: 1}, testNow)
+
+		runner.RunDue(ctx, testNow)
+		Pause(ctx, stores.Batches, "acc-1", batch.BatchID)
+		if n, _ := runner.RunDue(ctx, testNow.Add(time.Minute)); n != 0 {
+			t.Errorf("Expected nothing dispatched while paused, got %d", n)
+		}
+		Resume(ctx, stores.Batches, "acc-1", batch.BatchID)
+		if n, _ := runner.RunDue(ctx, testNow.Add(2*time.Minute)); n != 1 {
+			t.Errorf("Expected dispatching to continue after resume, got %d", n)
+		}
+		Cancel(ctx, stores.Batches, "acc-1", batch.BatchID)
+		if n, _ := runner.RunDue(ctx, testNow.Add(3*time.Minute)); n != 0 {
+			t.Errorf("Expected nothing dispatched once cancelled, got %d", n)
+		}
+		if got := dispatchedContacts(t, stores, batch.BatchID); !reflect.DeepEqual(got, []string{"1", "2"}) {
+			t.Errorf("Expected [1 2] dispatched, got %v", got)
+		}
+	})
+
+	t.Run("monthly limit", func(t *testing.T) {
+		stores := newTestStores(t)
+		runner := newTestRunner(t, stores)
+		account, _ := stores.Accounts.GetByID(ctx, "acc-1")
+		account.Settings.MaxExecutions = 2
+		stores.Accounts.Update(ctx, account)
+
+		batch, _ := Create(ctx, stores, Request{AccountID: "acc-1", HelperID: "helper:score",
+			Source: types.BatchSource{Type: SourceContactIDs, ContactIDs: []string{"1", "2", "3"}}}, testNow)
+		if n, _ := runner.RunDue(ctx, testNow); n != 2 {
+			t.Errorf("Expected 2 dispatched within the limit, got %d", n)
+		}
+		latest, _ := stores.Batches.GetByID(ctx, batch.BatchID)
+		if latest.Status != StatusPaused || latest.StatusReason != "monthly execution limit reached" || len(latest.Pending) != 1 {
+			t.Errorf("Expected the batch paused with one contact pending, got %+v", latest)
+		}
+	})
+
+	t.Run("helper disabled", func(t *testing.T) {
+		stores := newTestStores(t)
+		runner := newTestRunner(t, stores)
+		batch, _ := Create(ctx, stores, Request{AccountID: "acc-1", HelperID: "helper:score",
+			Source: types.BatchSource{Type: SourceTag, TagID: "101"}}, testNow)
+		helper, _ := stores.Helpers.GetByID(ctx, "helper:score")
+		helper.Enabled = false
+		stores.Helpers.Update(ctx, helper)
+
+		runner.RunDue(ctx, testNow)
+		latest, _ := stores.Batches.GetByID(ctx, batch.BatchID)
+		if latest.Status != StatusPaused || latest.Dispatched != 0 {
+			t.Errorf("Expected the batch paused before dispatching, got %+v", latest)
+		}
+	})
+
+	t.Run("unknown connection", func(t *testing.T) {
+		stores := newTestStores(t)
+		runner := NewRunner(stores, nil)
+		batch, _ := Create(ctx, stores, Request{AccountID: "acc-1", HelperID: "helper:score",
+			Source: types.BatchSource{Type: SourceTag, TagID: "101"}}, testNow)
+
+		runner.RunDue(ctx, testNow)
+		latest, _ := stores.Batches.GetByID(ctx, batch.BatchID)
+		if latest.Status != StatusFailed || latest.StatusReason == "" {
+			t.Errorf("Expected the batch failed with a reason, got %+v", latest)
+		}
+	})
+}
+
+func TestMemorySnapshots_Read(t *testing.T) {
+	ctx := context.Background()
+	snapshots := NewMemorySnapshots()
+	snapshots.Write(ctx, "s", []string{"a", "b", "c"})
+
+	var got []string
+	offset := int64(0)
+	for done := false; !done; {
+		var ids []string
+		var err error
+		ids, offset, done, err = snapshots.Read(ctx, "s", offset, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		got = append(got, ids...)
+	}
+	if !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Expected [a b c], got %v", got)
+	}
+
+	if ids, consumed := decodeSnapshot([]byte("a\nbc"), 10, false); !reflect.DeepEqual(ids, []string{"a"}) || consumed != 2 {
+		t.Errorf("Expected a partial last line to wait for the next chunk, got %v %d", ids, consumed)
+	}
+}
@@ -0,0 +1,162 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// snapshotChunk is the number of bytes read from a snapshot per page; it
+// holds well over a page of contact IDs
+const snapshotChunk = 64 * 1024
+
+// Snapshots stores the contact IDs of data explorer segments, resolved when
+// the batch is created, as newline-separated text. Sources read them by byte
+// offset so a large segment is never loaded at once.
+type Snapshots interface {
+	Write(ctx context.Context, key string, contactIDs []string) error
+	// Read returns up to limit contact IDs starting at offset, the offset
+	// after them, and whether the snapshot has been read to the end.
+	Read(ctx context.Context, key string, offset int64, limit int) ([]string, int64, bool, error)
+}
+
+// SnapshotKey returns the object key of a batch's snapshot
+func SnapshotKey(accountID, batchID string) string {
+	return "batches/" + accountID + "/" + strings.TrimPrefix(batchID, "batch:") + ".txt"
+}
+
+// S3Snapshots keeps snapshots in an S3 bucket
+type S3Snapshots struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3Snapshots creates snapshot storage in the given bucket
+func NewS3Snapshots(client *s3.Client, bucket string) *S3Snapshots {
+	return &S3Snapshots{client: client, bucket: bucket}
+}
+
+// Write uploads the contact IDs
+func (s *S3Snapshots) Write(ctx context.Context, key string, contactIDs []string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(encodeSnapshot(contactIDs)),
+		ContentType: aws.String("text/plain"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Read fetches one chunk of the snapshot with a ranged GET
+func (s *S3Snapshots) Read(ctx context.Context, key string, offset int64, limit int) ([]string, int64, bool, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+snapshotChunk-1)),
+	})
+	if err != nil {
+		return nil, offset, false, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, offset, false, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	// Content-Range is "bytes start-end/size"
+	size := offset + int64(len(data))
+	if contentRange := aws.ToString(result.ContentRange); strings.Contains(contentRange, "/") {
+		if n, err := strconv.ParseInt(contentRange[strings.LastIndex(contentRange, "/")+1:], 10, 64); err == nil {
+			size = n
+		}
+	}
+
+	ids, consumed := decodeSnapshot(data, limit, offset+int64(len(data)) >= size)
+	next := offset + int64(consumed)
+	return ids, next, next >= size, nil
+}
+
+// MemorySnapshots keeps snapshots in memory, for tests and local runs
+type MemorySnapshots struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// NewMemorySnapshots creates empty in-memory snapshot storage
+func NewMemorySnapshots() *MemorySnapshots {
+	return &MemorySnapshots{objects: make(map[string][]byte)}
+}
+
+// Write stores the contact IDs
+func (s *MemorySnapshots) Write(ctx context.Context, key string, contactIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = encodeSnapshot(contactIDs)
+	return nil
+}
+
+// Read returns contact IDs the way S3Snapshots does, one chunk at a time
+func (s *MemorySnapshots) Read(ctx context.Context, key string, offset int64, limit int) ([]string, int64, bool, error) {
+	s.mu.Lock()
+	data, ok := s.objects[key]
+	s.mu.Unlock()
+	if !ok {
+		return nil, offset, false, fmt.Errorf("snapshot %s not found", key)
+	}
+
+	size := int64(len(data))
+	if offset >= size {
+		return nil, offset, true, nil
+	}
+	end := offset + snapshotChunk
+	if end > size {
+		end = size
+	}
+	ids, consumed := decodeSnapshot(data[offset:end], limit, end == size)
+	next := offset + int64(consumed)
+	return ids, next, next >= size, nil
+}
+
+func encodeSnapshot(contactIDs []string) []byte {
+	var buf bytes.Buffer
+	for _, id := range contactIDs {
+		buf.WriteString(id)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// decodeSnapshot returns up to limit IDs from the complete lines in data and
+// the number of bytes they took. A final line without a newline only counts
+// when data runs to the end of the snapshot.
+func decodeSnapshot(data []byte, limit int, final bool) ([]string, int) {
+	var ids []string
+	consumed := 0
+	for len(ids) < limit && consumed < len(data) {
+		line := data[consumed:]
+		n := bytes.IndexByte(line, '\n')
+		if n < 0 {
+			if !final {
+				break
+			}
+			n = len(line)
+			consumed += n
+		} else {
+			consumed += n + 1
+		}
+		if id := strings.TrimSpace(string(line[:n])); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, consumed
+}
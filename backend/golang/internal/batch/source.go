@@ -0,0 +1,89 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// sourcePageSize is the number of contacts read from a source at a time
+const sourcePageSize = 100
+
+// source reads a batch's contacts into its pending list. The position in the
+// source is kept in batch.Cursor: an offset into explicit ID lists and
+// snapshots, the connector's page cursor for CRM sources.
+type source struct {
+	runner    *Runner
+	batch     *types.Batch
+	connector connectors.CRMConnector
+}
+
+// next appends the next page of contacts to the pending list and marks the
+// source done after its last page
+func (s *source) next(ctx context.Context) error {
+	batch := s.batch
+	switch batch.Source.Type {
+	case SourceContactIDs:
+		offset, _ := strconv.Atoi(batch.Cursor)
+		ids := batch.Source.ContactIDs
+		if offset > len(ids) {
+			offset = len(ids)
+		}
+		end := offset + sourcePageSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		s.advance(ids[offset:end], strconv.Itoa(end), end == len(ids))
+		return nil
+
+	case SourceTag, SourceQuery:
+		opts := connectors.QueryOptions{Limit: sourcePageSize, Cursor: batch.Cursor, TagID: batch.Source.TagID}
+		if q := batch.Source.Query; q != nil {
+			opts.Filters = q.Filters
+			opts.TagID = q.TagID
+			opts.Email = q.Email
+			opts.OrderBy = q.OrderBy
+		}
+		if s.connector == nil {
+			connector, err := s.runner.Connect(ctx, s.runner.Stores, batch.ConnectionID, batch.AccountID)
+			if err != nil {
+				return err
+			}
+			s.connector = connector
+		}
+		list, err := s.connector.GetContacts(ctx, opts)
+		if err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(list.Contacts))
+		for _, contact := range list.Contacts {
+			ids = append(ids, contact.ID)
+		}
+		s.advance(ids, list.NextCursor, !list.HasMore || list.NextCursor == "")
+		return nil
+
+	case SourceDataExplorer:
+		if s.runner.Snapshots == nil {
+			return fmt.Errorf("%w: no snapshot storage configured", ErrInvalidRequest)
+		}
+		offset, _ := strconv.ParseInt(batch.Cursor, 10, 64)
+		ids, next, done, err := s.runner.Snapshots.Read(ctx, batch.Source.SnapshotKey, offset, sourcePageSize)
+		if err != nil {
+			return err
+		}
+		s.advance(ids, strconv.FormatInt(next, 10), done)
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown source type %q", ErrInvalidRequest, batch.Source.Type)
+	}
+}
+
+func (s *source) advance(ids []string, cursor string, done bool) {
+	s.batch.Pending = append(s.batch.Pending, ids...)
+	s.batch.Cursor = cursor
+	s.batch.SourceDone = done
+}
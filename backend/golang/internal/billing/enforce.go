@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/myfusionhelper/api/internal/database"
 	"github.com/myfusionhelper/api/internal/types"
 )
 
@@ -48,8 +49,11 @@ func fetchAccount(ctx context.Context, db *dynamodb.Client, accountsTable, accou
 }
 
 // CheckHelperLimit verifies the account hasn't exceeded its helper limit.
-func CheckHelperLimit(ctx context.Context, db *dynamodb.Client, accountsTable, accountID string) error {
-	account, err := fetchAccount(ctx, db, accountsTable, accountID)
+func CheckHelperLimit(ctx context.Context, accounts database.AccountStore, accountID string) error {
+	account, err := accounts.GetByID(ctx, accountID)
+	if err == nil && account == nil {
+		err = fmt.Errorf("account not found: %s", accountID)
+	}
 	if err != nil {
 		log.Printf("Billing check failed (allowing): %v", err)
 		return nil // fail open
@@ -113,8 +117,11 @@ func CheckAPIKeyLimit(ctx context.Context, db *dynamodb.Client, accountsTable, a
 // CheckExecutionLimit verifies the account hasn't exceeded its monthly execution limit.
 // For paid plans, this returns nil (overage is metered by Stripe).
 // For sandbox (free) plans, this blocks execution at the limit.
-func CheckExecutionLimit(ctx context.Context, db *dynamodb.Client, accountsTable, accountID string) error {
-	account, err := fetchAccount(ctx, db, accountsTable, accountID)
+func CheckExecutionLimit(ctx context.Context, accounts database.AccountStore, accountID string) error {
+	account, err := accounts.GetByID(ctx, accountID)
+	if err == nil && account == nil {
+		err = fmt.Errorf("account not found: %s", accountID)
+	}
 	if err != nil {
 		log.Printf("Billing check failed (allowing): %v", err)
 		return nil
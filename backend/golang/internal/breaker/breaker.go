@@ -0,0 +1,128 @@
+// Package breaker is the per-connection circuit breaker of helper
+// executions. When a CRM connection keeps failing with auth or server
+// errors, say because its token was revoked or the CRM is down, its breaker
+// opens: the workers hold the connection's executions as paused instead of
+// failing each one, and the account is notified once. The prober then tests
+// the connection on a backoff schedule; once a test succeeds the breaker is
+// half-open while the held executions are queued again, and closes when
+// none are left.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Breaker states
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// Policy controls when a breaker opens and how often an open breaker probes
+// its connection
+type Policy struct {
+	// Threshold is the number of consecutive failures that opens the breaker
+	Threshold int
+	// BaseDelay is the wait before the first probe, doubled after each
+	// failed probe up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultPolicy applies to every connection
+var DefaultPolicy = Policy{
+	Threshold: 5,
+	BaseDelay: time.Minute,
+	MaxDelay:  time.Hour,
+}
+
+// ProbeDelay returns the wait before probing again after attempts failed
+// probes
+func (p Policy) ProbeDelay(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Counts reports whether err counts against a connection's breaker:
+// connector errors with an auth (401, 403) or server (5xx) status, and token
+// refreshes that failed the same way or for good. Bad input, missing records
+// and throttling say nothing about the connection's health.
+func Counts(err error) bool {
+	var connErr *connectors.ConnectorError
+	if errors.As(err, &connErr) {
+		return unhealthy(connErr.StatusCode)
+	}
+	var refreshErr *loader.RefreshError
+	if errors.As(err, &refreshErr) {
+		return refreshErr.Permanent || unhealthy(refreshErr.StatusCode)
+	}
+	return false
+}
+
+// unhealthy reports whether an HTTP status means the connection itself is
+// failing
+func unhealthy(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden || status >= http.StatusInternalServerError
+}
+
+// Holds reports whether b holds its connection's executions
+func Holds(b *types.ConnectionBreaker) bool {
+	return b != nil && b.State == StateOpen
+}
+
+// RecordFailure counts failure against the connection's breaker and opens it
+// once the policy's threshold is reached. held reports whether the breaker
+// is open, so the failed execution should be paused rather than failed;
+// opened reports whether this failure opened a closed breaker, which
+// happens once per outage. A breaker that fails again while half-open opens
+// without counting as a new outage.
+func (p Policy) RecordFailure(ctx context.Context, store database.ConnectionBreakerStore, connectionID, accountID string, failure error, now time.Time) (held, opened bool, err error) {
+	b, err := store.RecordFailure(ctx, connectionID, accountID, failure.Error(), now)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to record connection failure: %w", err)
+	}
+	if b.State == StateOpen {
+		return true, false, nil
+	}
+	if b.ConsecutiveFailures < p.Threshold {
+		return false, false, nil
+	}
+
+	err = store.Transition(ctx, connectionID, []string{StateClosed, StateHalfOpen}, StateOpen, now.Add(p.ProbeDelay(0)), now)
+	if errors.Is(err, database.ErrConditionFailed) {
+		// Opened concurrently by another worker
+		return true, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to open connection breaker: %w", err)
+	}
+	return true, b.State == StateClosed, nil
+}
+
+// RecordSuccess resets the consecutive failures of b, the breaker read
+// before the execution ran, if it had any
+func RecordSuccess(ctx context.Context, store database.ConnectionBreakerStore, b *types.ConnectionBreaker, now time.Time) error {
+	if b == nil || b.ConsecutiveFailures == 0 {
+		return nil
+	}
+	if err := store.ResetFailures(ctx, b.ConnectionID, now); err != nil {
+		return fmt.Errorf("failed to reset connection failures: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+var testNow = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+var testPolicy = Policy{Threshold: 3, BaseDelay: time.Minute, MaxDelay: 10 * time.Minute}
+
+func TestCounts(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unauthorized", err: connectors.NewConnectorError("keap", 401, "token revoked", false), want: true},
+		{name: "forbidden", err: connectors.NewConnectorError("keap", 403, "forbidden", false), want: true},
+		{name: "server error", err: connectors.NewConnectorError("hubspot", 503, "unavailable", true), want: true},
+		{name: "wrapped", err: fmt.Errorf("apply tag: %w", connectors.NewConnectorError("keap", 500, "boom", true)), want: true},
+		{name: "not found", err: connectors.NewConnectorError("keap", 404, "no contact", false)},
+		{name: "throttled", err: connectors.NewConnectorError("keap", 429, "slow down", true)},
+		{name: "revoked refresh token", err: &loader.RefreshError{StatusCode: 400, Message: "invalid_grant", Permanent: true}, want: true},
+		{name: "token endpoint down", err: &loader.RefreshError{StatusCode: 502, Message: "bad gateway"}, want: true},
+		{name: "helper error", err: errors.New("missing config")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Counts(tt.err); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPolicy_ProbeDelay(t *testing.T) {
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for attempts, delay := range want {
+		if got := testPolicy.ProbeDelay(attempts); got != delay {
+			t.Errorf("Expected %v after %d failed probes, got %v", delay, attempts, got)
+		}
+	}
+}
+
+func TestPolicy_RecordFailure(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewBreakers()
+	failure := connectors.NewConnectorError("keap", 401, "token revoked", false)
+
+	for i := 1; i < testPolicy.Threshold; i++ {
+		held, opened, err := testPolicy.RecordFailure(ctx, store, "conn:1", "acc-1", failure, testNow)
+		if err != nil || held || opened {
+			t.Fatalf("Expected failure %d to be counted only, got %v, %v, %v", i, held, opened, err)
+		}
+	}
+	held, opened, err := testPolicy.RecordFailure(ctx, store, "conn:1", "acc-1", failure, testNow)
+	if err != nil || !held || !opened {
+		t.Fatalf("Expected the threshold to open the breaker, got %v, %v, %v", held, opened, err)
+	}
+	held, opened, _ = testPolicy.RecordFailure(ctx, store, "conn:1", "acc-1", failure, testNow)
+	if !held || opened {
+		t.Errorf("Expected later failures to be held without opening again, got %v, %v", held, opened)
+	}
+
+	b, _ := store.Get(ctx, "conn:1")
+	if !Holds(b) || b.NextProbeAt != "2026-03-02T09:01:00Z" {
+		t.Errorf("Expected an open breaker probing in a minute, got %+v", b)
+	}
+}
+
+func TestPolicy_RecordFailure_HalfOpenReopensQuietly(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewBreakers()
+	failure := connectors.NewConnectorError("hubspot", 502, "bad gateway", true)
+	store.RecordFailure(ctx, "conn:1", "acc-1", "", testNow)
+	store.Transition(ctx, "conn:1", []string{StateClosed}, StateHalfOpen, testNow, testNow)
+
+	var opened bool
+	for i := 0; i < testPolicy.Threshold; i++ {
+		_, opened, _ = testPolicy.RecordFailure(ctx, store, "conn:1", "acc-1", failure, testNow)
+	}
+	if b, _ := store.Get(ctx, "conn:1"); !Holds(b) || opened {
+		t.Errorf("Expected the half-open breaker to open again without a new outage, got %+v, opened=%v", b, opened)
+	}
+}
+
+func TestRecordSuccess(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewBreakers()
+	b, _ := store.RecordFailure(ctx, "conn:1", "acc-1", "boom", testNow)
+
+	if err := RecordSuccess(ctx, store, b, testNow); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if b, _ := store.Get(ctx, "conn:1"); b.ConsecutiveFailures != 0 {
+		t.Errorf("Expected failures reset, got %d", b.ConsecutiveFailures)
+	}
+	if err := RecordSuccess(ctx, store, nil, testNow); err != nil {
+		t.Errorf("Expected connections without a breaker to be left alone, got %v", err)
+	}
+}
+
+// newOpenBreaker opens conn:1 with paused executions exec:1 and exec:2
+func newOpenBreaker(t *testing.T) (*Prober, *time.Time, *error) {
+	t.Helper()
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Connections.Create(ctx, &types.PlatformConnection{ConnectionID: "conn:1", AccountID: "acc-1"})
+	for _, id := range []string{"exec:1", "exec:2"} {
+		stores.Executions.Create(ctx, &types.Execution{ExecutionID: id, AccountID: "acc-1", ConnectionID: "conn:1", Status: execution.StatusRunning, CreatedAt: id})
+		if err := execution.Transition(ctx, stores.Executions, id, execution.StatusPaused, "connection circuit open", testNow); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	stores.Breakers.RecordFailure(ctx, "conn:1", "acc-1", "token revoked", testNow)
+	stores.Breakers.Transition(ctx, "conn:1", []string{StateClosed}, StateOpen, testNow.Add(time.Minute), testNow)
+
+	now := testNow
+	var testErr error
+	prober := &Prober{
+		Stores: stores,
+		Policy: testPolicy,
+		Now:    func() time.Time { return now },
+		Test:   func(ctx context.Context, connectionID, accountID string) error { return testErr },
+	}
+	return prober, &now, &testErr
+}
+
+func TestProber_BacksOffWhileFailing(t *testing.T) {
+	ctx := context.Background()
+	prober, now, testErr := newOpenBreaker(t)
+	*testErr = errors.New("401 unauthorized")
+
+	*now = testNow.Add(30 * time.Second)
+	prober.ProbeDue(ctx)
+	b, _ := prober.Stores.Breakers.Get(ctx, "conn:1")
+	if b.ProbeAttempts != 0 {
+		t.Errorf("Expected no probe before it is due, got %+v", b)
+	}
+
+	*now = testNow.Add(time.Minute)
+	prober.ProbeDue(ctx)
+	b, _ = prober.Stores.Breakers.Get(ctx, "conn:1")
+	if b.State != StateOpen || b.ProbeAttempts != 1 || b.NextProbeAt != "2026-03-02T09:03:00Z" || b.LastError != "401 unauthorized" {
+		t.Errorf("Expected the next probe two minutes later, got %+v", b)
+	}
+	if exec, _ := prober.Stores.Executions.GetByID(ctx, "exec:1"); exec.Status != execution.StatusPaused {
+		t.Errorf("Expected executions to stay paused, got %s", exec.Status)
+	}
+}
+
+func TestProber_DrainsOnceHealthy(t *testing.T) {
+	ctx := context.Background()
+	prober, now, _ := newOpenBreaker(t)
+	prober.Stores.Executions.Create(ctx, &types.Execution{ExecutionID: "exec:3", ConnectionID: "conn:2", Status: execution.StatusRunning})
+	execution.Transition(ctx, prober.Stores.Executions, "exec:3", execution.StatusPaused, "", testNow)
+
+	*now = testNow.Add(time.Minute)
+	if closed, err := prober.ProbeDue(ctx); closed != 0 || err != nil {
+		t.Fatalf("Expected the breaker to stay half-open while draining, got %d, %v", closed, err)
+	}
+	if b, _ := prober.Stores.Breakers.Get(ctx, "conn:1"); b.State != StateHalfOpen || Holds(b) {
+		t.Errorf("Expected a half-open breaker, got %+v", b)
+	}
+	for _, id := range []string{"exec:1", "exec:2"} {
+		exec, _ := prober.Stores.Executions.GetByID(ctx, id)
+		if exec.Status != execution.StatusQueued || exec.StatusHistory[len(exec.StatusHistory)-1].Reason != "connection recovered" {
+			t.Errorf("Expected %s queued again, got %+v", id, exec)
+		}
+	}
+	if exec, _ := prober.Stores.Executions.GetByID(ctx, "exec:3"); exec.Status != execution.StatusPaused {
+		t.Errorf("Expected another connection's execution to stay paused, got %s", exec.Status)
+	}
+
+	*now = testNow.Add(2 * time.Minute)
+	if closed, err := prober.ProbeDue(ctx); closed != 1 || err != nil {
+		t.Fatalf("Expected the drained breaker to close, got %d, %v", closed, err)
+	}
+	if b, _ := prober.Stores.Breakers.Get(ctx, "conn:1"); b.State != StateClosed || b.NextProbeAt != "" {
+		t.Errorf("Expected a closed, unscheduled breaker, got %+v", b)
+	}
+}
+
+func TestProber_CancelsForDeletedConnection(t *testing.T) {
+	ctx := context.Background()
+	prober, now, _ := newOpenBreaker(t)
+	prober.Stores.Connections.Delete(ctx, "conn:1")
+
+	*now = testNow.Add(time.Minute)
+	prober.ProbeDue(ctx)
+	if exec, _ := prober.Stores.Executions.GetByID(ctx, "exec:1"); exec.Status != execution.StatusCancelled {
+		t.Errorf("Expected executions of a deleted connection cancelled, got %s", exec.Status)
+	}
+	prober.ProbeDue(ctx)
+	if b, _ := prober.Stores.Breakers.Get(ctx, "conn:1"); b.State != StateClosed {
+		t.Errorf("Expected the breaker closed, got %+v", b)
+	}
+}
@@ -0,0 +1,152 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// maxPerPass bounds the breakers probed by one pass
+const maxPerPass = 100
+
+// drainPageSize is how many paused executions are queued again at a time
+const drainPageSize = 100
+
+// Prober tests the connections of open breakers and queues the executions
+// they held again once the connection is healthy
+type Prober struct {
+	Stores *database.Stores
+	Policy Policy
+	Now    func() time.Time
+	// Test checks that the connection works
+	Test func(ctx context.Context, connectionID, accountID string) error
+}
+
+// NewProber creates a prober on the wall clock that tests connections with
+// the connector's TestConnection
+func NewProber(stores *database.Stores) *Prober {
+	return &Prober{
+		Stores: stores,
+		Policy: DefaultPolicy,
+		Now:    time.Now,
+		Test: func(ctx context.Context, connectionID, accountID string) error {
+			connector, err := loader.LoadConnectorWithTranslation(ctx, stores, connectionID, accountID)
+			if err != nil {
+				return err
+			}
+			return connector.TestConnection(ctx)
+		},
+	}
+}
+
+// ProbeDue probes the open breakers whose next probe is due and finishes
+// draining half-open ones, and returns how many breakers closed.
+//
+// Runs may overlap: a breaker is only half-opened and closed by the run
+// whose conditional write moves it.
+func (p *Prober) ProbeDue(ctx context.Context) (int, error) {
+	now := p.Now()
+	due, err := p.Stores.Breakers.ListDue(ctx, now, maxPerPass)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due connection breakers: %w", err)
+	}
+
+	closed := 0
+	for i := range due {
+		ok, err := p.probe(ctx, &due[i], now)
+		if err != nil {
+			log.Printf("Failed to probe connection %s: %v", due[i].ConnectionID, err)
+			continue
+		}
+		if ok {
+			closed++
+		}
+	}
+	return closed, nil
+}
+
+// probe tests an open breaker's connection, half-opening the breaker if it
+// works, and drains a half-open breaker. It reports whether the breaker
+// closed.
+func (p *Prober) probe(ctx context.Context, b *types.ConnectionBreaker, now time.Time) (bool, error) {
+	conn, err := p.Stores.Connections.GetByID(ctx, b.ConnectionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load connection: %w", err)
+	}
+	if conn == nil || conn.AccountID != b.AccountID {
+		// Nothing will run on a deleted connection again
+		return p.drain(ctx, b, execution.StatusCancelled, "connection deleted", now)
+	}
+
+	if b.State == StateOpen {
+		if testErr := p.Test(ctx, b.ConnectionID, b.AccountID); testErr != nil {
+			next := now.Add(p.Policy.ProbeDelay(b.ProbeAttempts + 1))
+			err := p.Stores.Breakers.RecordProbeFailure(ctx, b.ConnectionID, testErr.Error(), next, now)
+			if err != nil && !errors.Is(err, database.ErrConditionFailed) {
+				return false, fmt.Errorf("failed to record probe failure: %w", err)
+			}
+			log.Printf("Connection %s is still failing, probing again at %s: %v", b.ConnectionID, next.Format(time.RFC3339), testErr)
+			return false, nil
+		}
+
+		err := p.Stores.Breakers.Transition(ctx, b.ConnectionID, []string{StateOpen}, StateHalfOpen, now, now)
+		if errors.Is(err, database.ErrConditionFailed) {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("failed to half-open breaker: %w", err)
+		}
+		log.Printf("Connection %s recovered, queueing its paused executions", b.ConnectionID)
+	}
+
+	return p.drain(ctx, b, execution.StatusQueued, "connection recovered", now)
+}
+
+// drain moves the breaker's paused executions to status to and reports
+// whether it closed the breaker. Executions queued again are dispatched by
+// the stream router like new ones. The breaker closes on the first pass that
+// finds nothing left to move, so executions paused by workers that saw the
+// breaker open just before it half-opened are not stranded.
+func (p *Prober) drain(ctx context.Context, b *types.ConnectionBreaker, to, reason string, now time.Time) (bool, error) {
+	moved := 0
+	for {
+		paused, err := p.Stores.Executions.ListPaused(ctx, b.ConnectionID, drainPageSize)
+		if err != nil {
+			return false, fmt.Errorf("failed to list paused executions: %w", err)
+		}
+		settled := 0
+		for _, exec := range paused {
+			err := execution.Transition(ctx, p.Stores.Executions, exec.ExecutionID, to, reason, now)
+			if errors.Is(err, execution.ErrIllegalTransition) {
+				// Cancelled meanwhile
+				continue
+			} else if err != nil {
+				return false, err
+			}
+			settled++
+		}
+		moved += settled
+		if len(paused) < drainPageSize || settled == 0 {
+			break
+		}
+	}
+	if moved > 0 {
+		log.Printf("Moved %d paused execution(s) of connection %s to %s", moved, b.ConnectionID, to)
+		return false, nil
+	}
+
+	err := p.Stores.Breakers.Transition(ctx, b.ConnectionID, []string{StateOpen, StateHalfOpen}, StateClosed, time.Time{}, now)
+	if errors.Is(err, database.ErrConditionFailed) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to close breaker: %w", err)
+	}
+	log.Printf("Closed breaker of connection %s", b.ConnectionID)
+	return true, nil
+}
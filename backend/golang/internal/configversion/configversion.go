@@ -0,0 +1,205 @@
+// Package configversion keeps the history of helper configs. Every config
+// change is committed as an immutable, numbered version together with its
+// author and its diff against the previous version, and the helper record
+// carries the number of the version it is at. A rollback restores an old
+// config by committing it as a new version.
+package configversion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Change operations
+const (
+	OpAdded   = "added"
+	OpRemoved = "removed"
+	OpChanged = "changed"
+)
+
+var (
+	// ErrNotFound is returned when a version does not exist
+	ErrNotFound = errors.New("config version not found")
+	// ErrConflict is returned when the helper's config was changed since it
+	// was read
+	ErrConflict = errors.New("helper config was changed concurrently")
+	// ErrUnchanged is returned by Rollback when the version is already the
+	// helper's config
+	ErrUnchanged = errors.New("config is unchanged")
+)
+
+// Record starts the history of a new helper with its initial config as
+// version 1
+func Record(ctx context.Context, versions database.HelperVersionStore, helper *types.Helper, now time.Time) error {
+	_, err := commit(ctx, versions, helper, types.HelperConfigVersion{
+		Config:    helper.Config,
+		Changes:   Diff(nil, helper.Config),
+		CreatedBy: helper.CreatedBy,
+	}, false, now)
+	return err
+}
+
+// Update commits config as the helper's next version and updates helper to
+// match. It returns nil, committing nothing, if config equals the current
+// one.
+func Update(ctx context.Context, versions database.HelperVersionStore, helper *types.Helper, config map[string]interface{}, author string, now time.Time) (*types.HelperConfigVersion, error) {
+	changes := Diff(helper.Config, config)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return commit(ctx, versions, helper, types.HelperConfigVersion{
+		Config:    config,
+		Changes:   changes,
+		CreatedBy: author,
+	}, helper.ConfigVersion == 0, now)
+}
+
+// Rollback commits the config of an earlier version as the helper's next
+// version
+func Rollback(ctx context.Context, versions database.HelperVersionStore, helper *types.Helper, target int, author string, now time.Time) (*types.HelperConfigVersion, error) {
+	restored, err := versions.Get(ctx, helper.HelperID, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config version: %w", err)
+	}
+	if restored == nil {
+		return nil, fmt.Errorf("%w: version %d", ErrNotFound, target)
+	}
+
+	changes := Diff(helper.Config, restored.Config)
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("%w: version %d matches the current config", ErrUnchanged, target)
+	}
+	return commit(ctx, versions, helper, types.HelperConfigVersion{
+		Config:     restored.Config,
+		Changes:    changes,
+		CreatedBy:  author,
+		RollbackOf: target,
+	}, false, now)
+}
+
+// Compare returns the changes that turn version from into version to
+func Compare(ctx context.Context, versions database.HelperVersionStore, helperID string, from, to int) ([]types.ConfigChange, error) {
+	var configs [2]map[string]interface{}
+	for i, number := range []int{from, to} {
+		version, err := versions.Get(ctx, helperID, number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config version: %w", err)
+		}
+		if version == nil {
+			return nil, fmt.Errorf("%w: version %d", ErrNotFound, number)
+		}
+		configs[i] = version.Config
+	}
+	return Diff(configs[0], configs[1]), nil
+}
+
+// commit numbers version after the helper's current one, commits it and
+// moves helper to it. With baseline set, the helper's current config, which
+// predates versioning, is first recorded as version 1 so it can be rolled
+// back to.
+func commit(ctx context.Context, versions database.HelperVersionStore, helper *types.Helper, version types.HelperConfigVersion, baseline bool, now time.Time) (*types.HelperConfigVersion, error) {
+	var pending []types.HelperConfigVersion
+	if baseline {
+		baselineAt := helper.UpdatedAt
+		if baselineAt.IsZero() {
+			baselineAt = now
+		}
+		pending = append(pending, types.HelperConfigVersion{
+			HelperID:  helper.HelperID,
+			Version:   helper.ConfigVersion + 1,
+			AccountID: helper.AccountID,
+			Config:    helper.Config,
+			Changes:   Diff(nil, helper.Config),
+			CreatedAt: baselineAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	version.HelperID = helper.HelperID
+	version.AccountID = helper.AccountID
+	version.Version = helper.ConfigVersion + len(pending) + 1
+	version.CreatedAt = now.UTC().Format(time.RFC3339)
+	pending = append(pending, version)
+
+	err := versions.Commit(ctx, helper.ConfigVersion, pending...)
+	if errors.Is(err, database.ErrConditionFailed) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit config version: %w", err)
+	}
+
+	helper.Config = version.Config
+	helper.ConfigVersion = version.Version
+	helper.UpdatedAt = now.UTC()
+	return &version, nil
+}
+
+// Diff returns the changes that turn config from into config to, ordered by
+// path. Nested objects are compared key by key; any other values, lists
+// included, are compared whole.
+func Diff(from, to map[string]interface{}) []types.ConfigChange {
+	changes := []types.ConfigChange{}
+	diffMaps("", normalize(from), normalize(to), &changes)
+	return changes
+}
+
+func diffMaps(prefix string, from, to map[string]interface{}, changes *[]types.ConfigChange) {
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		oldValue, hadOld := from[key]
+		newValue, hasNew := to[key]
+		switch {
+		case !hadOld:
+			*changes = append(*changes, types.ConfigChange{Path: path, Op: OpAdded, New: newValue})
+		case !hasNew:
+			*changes = append(*changes, types.ConfigChange{Path: path, Op: OpRemoved, Old: oldValue})
+		default:
+			oldMap, oldIsMap := oldValue.(map[string]interface{})
+			newMap, newIsMap := newValue.(map[string]interface{})
+			if oldIsMap && newIsMap {
+				diffMaps(path, oldMap, newMap, changes)
+			} else if !reflect.DeepEqual(oldValue, newValue) {
+				*changes = append(*changes, types.ConfigChange{Path: path, Op: OpChanged, Old: oldValue, New: newValue})
+			}
+		}
+	}
+}
+
+// normalize gives a config the shape it has after a JSON round trip, so
+// configs read from the API and from DynamoDB compare equal
+func normalize(config map[string]interface{}) map[string]interface{} {
+	if config == nil {
+		return nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return config
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return config
+	}
+	return normalized
+}
@@ -0,0 +1,136 @@
+package configversion
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+func TestDiff(t *testing.T) {
+	from := map[string]interface{}{
+		"tag_id":  123,
+		"mode":    "add",
+		"mapping": map[string]interface{}{"email": "Email", "phone": "Phone1"},
+		"fields":  []interface{}{"a", "b"},
+	}
+	to := map[string]interface{}{
+		"tag_id":  float64(123),
+		"mapping": map[string]interface{}{"email": "Email2", "city": "City"},
+		"fields":  []interface{}{"a", "b", "c"},
+		"notify":  true,
+	}
+
+	changes := Diff(from, to)
+	expected := []types.ConfigChange{
+		{Path: "fields", Op: OpChanged},
+		{Path: "mapping.city", Op: OpAdded},
+		{Path: "mapping.email", Op: OpChanged},
+		{Path: "mapping.phone", Op: OpRemoved},
+		{Path: "mode", Op: OpRemoved},
+		{Path: "notify", Op: OpAdded},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.Path != expected[i].Path || change.Op != expected[i].Op {
+			t.Errorf("Expected change %d to be %s %s, got %s %s", i, expected[i].Op, expected[i].Path, change.Op, change.Path)
+		}
+	}
+	if changes[2].Old != "Email" || changes[2].New != "Email2" {
+		t.Errorf("Expected mapping.email to change from Email to Email2, got %v to %v", changes[2].Old, changes[2].New)
+	}
+
+	if changes := Diff(from, from); len(changes) != 0 {
+		t.Errorf("Expected no changes between equal configs, got %+v", changes)
+	}
+}
+
+func TestUpdateAndRollback(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := created.Add(24 * time.Hour)
+
+	helper := &types.Helper{HelperID: "helper:1", AccountID: "account:1", Config: map[string]interface{}{"tag_id": "1"}, UpdatedAt: created}
+	stores.Helpers.Create(ctx, helper)
+
+	t.Run("first update records the unversioned config as version 1", func(t *testing.T) {
+		version, err := Update(ctx, stores.HelperVersions, helper, map[string]interface{}{"tag_id": "2"}, "user:1", now)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if version.Version != 2 || version.CreatedBy != "user:1" || helper.ConfigVersion != 2 {
+			t.Errorf("Expected version 2 by user:1, got %+v (helper at %d)", version, helper.ConfigVersion)
+		}
+		baseline, _ := stores.HelperVersions.Get(ctx, "helper:1", 1)
+		if baseline == nil || baseline.Config["tag_id"] != "1" || baseline.CreatedAt != "2026-03-01T09:00:00Z" {
+			t.Errorf("Expected a baseline version with the old config, got %+v", baseline)
+		}
+	})
+
+	t.Run("unchanged config commits nothing", func(t *testing.T) {
+		version, err := Update(ctx, stores.HelperVersions, helper, map[string]interface{}{"tag_id": "2"}, "user:1", now)
+		if err != nil || version != nil {
+			t.Errorf("Expected no version and no error, got %+v, %v", version, err)
+		}
+	})
+
+	t.Run("stale helper conflicts", func(t *testing.T) {
+		stale := *helper
+		stale.ConfigVersion = 1
+		if _, err := Update(ctx, stores.HelperVersions, &stale, map[string]interface{}{"tag_id": "3"}, "user:2", now); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("rollback restores an old config as a new version", func(t *testing.T) {
+		version, err := Rollback(ctx, stores.HelperVersions, helper, 1, "user:2", now)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if version.Version != 3 || version.RollbackOf != 1 || helper.Config["tag_id"] != "1" {
+			t.Errorf("Expected version 3 rolling back to 1, got %+v", version)
+		}
+		stored, _ := stores.Helpers.GetByID(ctx, "helper:1")
+		if stored.ConfigVersion != 3 || stored.Config["tag_id"] != "1" {
+			t.Errorf("Expected the stored helper at version 3, got %d %v", stored.ConfigVersion, stored.Config)
+		}
+
+		if _, err := Rollback(ctx, stores.HelperVersions, helper, 1, "user:2", now); !errors.Is(err, ErrUnchanged) {
+			t.Errorf("Expected ErrUnchanged, got %v", err)
+		}
+		if _, err := Rollback(ctx, stores.HelperVersions, helper, 9, "user:2", now); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("compare", func(t *testing.T) {
+		changes, err := Compare(ctx, stores.HelperVersions, "helper:1", 1, 2)
+		if err != nil || len(changes) != 1 || changes[0].Path != "tag_id" {
+			t.Errorf("Expected tag_id to change, got %+v, %v", changes, err)
+		}
+		if _, err := Compare(ctx, stores.HelperVersions, "helper:1", 1, 7); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRecord(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	helper := &types.Helper{HelperID: "helper:1", CreatedBy: "user:1", Config: map[string]interface{}{"tag_id": "1"}}
+	stores.Helpers.Create(ctx, helper)
+
+	if err := Record(ctx, stores.HelperVersions, helper, time.Now()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	versions, _, _ := stores.HelperVersions.List(ctx, "helper:1", 10, "")
+	if len(versions) != 1 || versions[0].Version != 1 || versions[0].CreatedBy != "user:1" || helper.ConfigVersion != 1 {
+		t.Errorf("Expected a single version 1 by user:1, got %+v", versions)
+	}
+}
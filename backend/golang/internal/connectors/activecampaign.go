@@ -2,9 +2,7 @@ package connectors
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -24,6 +22,7 @@ type ActiveCampaignConnector struct {
 	apiKey  string
 	baseURL string
 	client  *http.Client
+	limiter *rateLimiter
 }
 
 // NewActiveCampaignConnector creates a new ActiveCampaign CRM connector
@@ -45,28 +44,73 @@ func NewActiveCampaignConnector(config ConnectorConfig) (CRMConnector, error) {
 		apiKey:  config.APIKey,
 		baseURL: baseURL,
 		client:  &http.Client{Timeout: 30 * time.Second},
+		limiter: limiterFor(acSlug, config),
 	}, nil
 }
 
 // ========== CONTACTS ==========
 
+// acContactOrderFields maps the common OrderBy values onto ActiveCampaign's orders[] parameter
+var acContactOrderFields = map[string]string{
+	OrderByCreated:   "cdate",
+	OrderByEmail:     "email",
+	OrderByFirstName: "first_name",
+	OrderByLastName:  "last_name",
+}
+
 func (a *ActiveCampaignConnector) GetContacts(ctx context.Context, opts QueryOptions) (*ContactList, error) {
+	q, err := parseContactQuery(acSlug, opts)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := offsetCursor(acSlug, opts)
+	if err != nil {
+		return nil, err
+	}
+	order, err := q.orderField(acSlug, acContactOrderFields)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
 	params := url.Values{}
-	if opts.Limit > 0 {
-		params.Set("limit", fmt.Sprintf("%d", opts.Limit))
-	} else {
-		params.Set("limit", "20")
+	params.Set("limit", fmt.Sprintf("%d", limit))
+	if offset > 0 {
+		params.Set("offset", fmt.Sprintf("%d", offset))
+	}
+	if q.Email != "" {
+		params.Set("email", q.Email)
 	}
-	if opts.Offset > 0 {
-		params.Set("offset", fmt.Sprintf("%d", opts.Offset))
+	if q.TagID != "" {
+		params.Set("tagid", q.TagID)
+	}
+	if q.UpdatedSince != nil {
+		params.Set("filters[updated_after]", q.UpdatedSince.UTC().Format("2006-01-02T15:04:05-07:00"))
+	}
+	if order != "" {
+		direction := "ASC"
+		if q.Descending {
+			direction = "DESC"
+		}
+		params.Set("orders["+order+"]", direction)
 	}
-	if opts.Email != "" {
-		params.Set("email", opts.Email)
+	if len(q.Fields) > 0 {
+		// Field values are only returned when sideloaded
+		params.Set("include", "fieldValues")
 	}
 
 	var result struct {
-		Contacts []acContact `json:"contacts"`
-		Meta     struct {
+		Contacts    []acContact `json:"contacts"`
+		FieldValues []struct {
+			Contact string `json:"contact"`
+			Field   string `json:"field"`
+			Value   string `json:"value"`
+		} `json:"fieldValues"`
+		Meta struct {
 			Total string `json:"total"`
 		} `json:"meta"`
 	}
@@ -76,29 +120,58 @@ func (a *ActiveCampaignConnector) GetContacts(ctx context.Context, opts QueryOpt
 	}
 
 	contacts := make([]NormalizedContact, 0, len(result.Contacts))
+	index := make(map[string]int, len(result.Contacts))
 	for _, ac := range result.Contacts {
+		index[ac.ID] = len(contacts)
 		contacts = append(contacts, ac.toNormalized())
 	}
+	for _, fv := range result.FieldValues {
+		if i, ok := index[fv.Contact]; ok {
+			contacts[i].CustomFields[fv.Field] = fv.Value
+		}
+	}
 
 	total := 0
 	fmt.Sscanf(result.Meta.Total, "%d", &total)
 
-	return &ContactList{
-		Contacts: contacts,
+	pushed := []string{FilterEmail, FilterTag, FilterUpdatedSince}
+	cl := &ContactList{
+		Contacts: q.filter(contacts, pushed...),
 		Total:    total,
-		HasMore:  len(result.Contacts) == opts.Limit || (opts.Limit == 0 && len(result.Contacts) == 20),
-	}, nil
+		HasMore:  offset+len(result.Contacts) < total || (total == 0 && len(result.Contacts) == limit),
+	}
+	if q.clientSide(pushed...) {
+		cl.Total = len(cl.Contacts)
+	}
+	if cl.HasMore {
+		cl.NextCursor = fmt.Sprintf("%d", offset+len(result.Contacts))
+	}
+	return cl, nil
 }
 
 func (a *ActiveCampaignConnector) GetContact(ctx context.Context, contactID string) (*NormalizedContact, error) {
+	// The single-contact endpoint sideloads the contact's field values and tags
 	var result struct {
-		Contact acContact `json:"contact"`
+		Contact     acContact `json:"contact"`
+		FieldValues []struct {
+			Field string `json:"field"`
+			Value string `json:"value"`
+		} `json:"fieldValues"`
+		ContactTags []struct {
+			Tag string `json:"tag"`
+		} `json:"contactTags"`
 	}
 	if err := a.doRequest(ctx, "GET", "/contacts/"+contactID, nil, &result); err != nil {
 		return nil, err
 	}
 
 	contact := result.Contact.toNormalized()
+	for _, fv := range result.FieldValues {
+		contact.CustomFields[fv.Field] = fv.Value
+	}
+	for _, ct := range result.ContactTags {
+		contact.Tags = append(contact.Tags, TagRef{ID: ct.Tag})
+	}
 	return &contact, nil
 }
 
@@ -249,7 +322,8 @@ func (a *ActiveCampaignConnector) RemoveTag(ctx context.Context, contactID strin
 		}
 	}
 
-	return NewConnectorError(acSlug, 404, "tag not found on contact", false)
+	// The tag is not on the contact, so there is nothing to remove
+	return nil
 }
 
 // ========== CUSTOM FIELDS ==========
@@ -456,47 +530,12 @@ func (ac *acContact) toNormalized() NormalizedContact {
 // ========== HTTP HELPER ==========
 
 func (a *ActiveCampaignConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	var bodyReader io.Reader
-	if body != nil {
-		bodyJSON, err := json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = strings.NewReader(string(bodyJSON))
-	}
-
-	apiURL := a.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, apiURL, bodyReader)
+	req, err := newJSONRequest(method, a.baseURL+path, body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
-
 	req.Header.Set("Api-Token", a.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
 
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return NewConnectorError(acSlug, 0, fmt.Sprintf("request failed: %v", err), true)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return NewConnectorError(acSlug, resp.StatusCode, "failed to read response", true)
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		retryable := resp.StatusCode == 429 || resp.StatusCode >= 500
-		return NewConnectorError(acSlug, resp.StatusCode,
-			fmt.Sprintf("ActiveCampaign API error (%d): %s", resp.StatusCode, string(respBody)), retryable)
-	}
-
-	if result != nil && len(respBody) > 0 {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to parse response: %w", err)
-		}
-	}
-
-	return nil
+	t := transport{platform: acSlug, name: "ActiveCampaign", client: a.client, limiter: a.limiter}
+	return t.do(ctx, req, result)
 }
@@ -0,0 +1,515 @@
+// Package conformance checks CRMConnector implementations against the
+// interface contract. Each scenario runs a connector against an httptest
+// server that replays recorded vendor responses, so a connector is certified
+// by adding its fixtures under testdata/<platform> and a Suite for it.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+// Scenarios run by the suite, each recorded in testdata/<platform>/<scenario>.json
+const (
+	ScenarioGetContact   = "get_contact"
+	ScenarioListContacts = "list_contacts"
+	ScenarioErrors       = "errors"
+	ScenarioTags         = "tags"
+	ScenarioCustomFields = "custom_fields"
+)
+
+// scenarioTimeout bounds each scenario, so a connector that sleeps through a
+// long Retry-After or keeps paging fails instead of hanging the test run
+const scenarioTimeout = 10 * time.Second
+
+// Suite describes one connector under test
+type Suite struct {
+	// Platform is the registry slug of the connector
+	Platform string
+	// Config holds the credentials the connector requires. BaseURL is
+	// replaced with the replay server's URL.
+	Config connectors.ConnectorConfig
+	// Dir holds the scenario fixtures; it defaults to testdata/<Platform>
+	Dir string
+}
+
+// Fixture is one recorded scenario: the vendor interactions to replay and the
+// inputs and expectations for the contract checks
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+
+	ContactID string `json:"contact_id,omitempty"`
+
+	// Contact is the expected result of GetContact in get_contact. Only the
+	// custom fields it lists are compared.
+	Contact *connectors.NormalizedContact `json:"contact,omitempty"`
+
+	// PageSize and ContactIDs drive list_contacts: iterating every contact
+	// with that page size must yield exactly these IDs in order
+	PageSize   int      `json:"page_size,omitempty"`
+	ContactIDs []string `json:"contact_ids,omitempty"`
+
+	// Errors lists the GetContact calls the vendor rejects in errors
+	Errors []ErrorCase `json:"errors,omitempty"`
+
+	// TagID is applied and removed twice in tags
+	TagID string `json:"tag_id,omitempty"`
+
+	// FieldKey and FieldValue are written and read back in custom_fields
+	FieldKey   string      `json:"field_key,omitempty"`
+	FieldValue interface{} `json:"field_value,omitempty"`
+}
+
+// ErrorCase is a GetContact call the vendor answers with an error status
+type ErrorCase struct {
+	ContactID string `json:"contact_id"`
+	Status    int    `json:"status"`
+	Retryable bool   `json:"retryable"`
+}
+
+// Interaction is one recorded vendor request and its response. Interactions
+// are consumed in order of first match.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+	// Repeat lets the interaction answer any number of matching requests
+	// instead of exactly one
+	Repeat bool `json:"repeat,omitempty"`
+}
+
+// RecordedRequest matches incoming requests. Query, Body and Form only need
+// to contain the recorded values; anything else in the request is ignored.
+type RecordedRequest struct {
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Query  map[string]string `json:"query,omitempty"`
+	Body   json.RawMessage   `json:"body,omitempty"`
+	Form   map[string]string `json:"form,omitempty"`
+}
+
+// RecordedResponse is replayed verbatim
+type RecordedResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type scenario struct {
+	name       string
+	capability connectors.Capability
+	check      func(t *testing.T, ctx context.Context, suite Suite, conn connectors.CRMConnector, f *Fixture)
+}
+
+var scenarios = []scenario{
+	{ScenarioGetContact, connectors.CapContacts, checkGetContact},
+	{ScenarioListContacts, connectors.CapContacts, checkListContacts},
+	{ScenarioErrors, connectors.CapContacts, checkErrors},
+	{ScenarioTags, connectors.CapTags, checkTags},
+	{ScenarioCustomFields, connectors.CapCustomFields, checkCustomFields},
+}
+
+// Run checks the connector against every scenario. A scenario is skipped when
+// the connector does not report its capability; otherwise its fixture is
+// required.
+func Run(t *testing.T, suite Suite) {
+	t.Helper()
+	dir := suite.Dir
+	if dir == "" {
+		dir = filepath.Join("testdata", suite.Platform)
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(dir, sc.name+".json"))
+			if errors.Is(err, fs.ErrNotExist) {
+				data = nil
+			} else if err != nil {
+				t.Fatalf("Expected readable fixture, got %v", err)
+			}
+			var fixture Fixture
+			if data != nil {
+				if err := json.Unmarshal(data, &fixture); err != nil {
+					t.Fatalf("Expected valid %s fixture, got %v", sc.name, err)
+				}
+			}
+
+			server := newReplayServer(t, fixture.Interactions)
+			defer server.Close()
+
+			config := suite.Config
+			config.BaseURL = server.URL
+			// A connection per scenario keeps state and rate limits from leaking between them
+			config.ConnectionID = fmt.Sprintf("conformance:%s:%s", suite.Platform, sc.name)
+
+			conn, err := connectors.NewConnector(suite.Platform, config)
+			if err != nil {
+				t.Fatalf("Expected %s connector, got %v", suite.Platform, err)
+			}
+			if !hasCapability(conn, sc.capability) {
+				t.Skipf("%s does not report %s", suite.Platform, sc.capability)
+			}
+			if data == nil {
+				t.Fatalf("Expected a %s fixture in %s", sc.name, dir)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), scenarioTimeout)
+			defer cancel()
+			sc.check(t, ctx, suite, conn, &fixture)
+			server.assertConsumed(t)
+		})
+	}
+}
+
+func hasCapability(conn connectors.CRMConnector, capability connectors.Capability) bool {
+	for _, c := range conn.GetCapabilities() {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// ========== CONTRACT CHECKS ==========
+
+// checkGetContact verifies normalization into NormalizedContact
+func checkGetContact(t *testing.T, ctx context.Context, suite Suite, conn connectors.CRMConnector, f *Fixture) {
+	if f.Contact == nil {
+		t.Fatal("Expected the fixture to describe the contact")
+	}
+	want := f.Contact
+
+	got, err := conn.GetContact(ctx, f.ContactID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	fields := []struct {
+		name      string
+		got, want string
+	}{
+		{"id", got.ID, want.ID},
+		{"first_name", got.FirstName, want.FirstName},
+		{"last_name", got.LastName, want.LastName},
+		{"email", got.Email, want.Email},
+		{"phone", got.Phone, want.Phone},
+		{"company", got.Company, want.Company},
+		{"job_title", got.JobTitle, want.JobTitle},
+	}
+	for _, field := range fields {
+		if field.got != field.want {
+			t.Errorf("Expected %s %q, got %q", field.name, field.want, field.got)
+		}
+	}
+	if got.SourceCRM != suite.Platform {
+		t.Errorf("Expected source_crm %s, got %q", suite.Platform, got.SourceCRM)
+	}
+	if got.SourceID != got.ID {
+		t.Errorf("Expected source_id %q, got %q", got.ID, got.SourceID)
+	}
+
+	if wantTags, gotTags := tagIDs(want.Tags), tagIDs(got.Tags); !reflect.DeepEqual(wantTags, gotTags) {
+		t.Errorf("Expected tags %v, got %v", wantTags, gotTags)
+	}
+	for _, wantTag := range want.Tags {
+		if wantTag.Name == "" {
+			continue
+		}
+		for _, gotTag := range got.Tags {
+			if gotTag.ID == wantTag.ID && gotTag.Name != wantTag.Name {
+				t.Errorf("Expected tag %s named %q, got %q", wantTag.ID, wantTag.Name, gotTag.Name)
+			}
+		}
+	}
+
+	if got.CustomFields == nil {
+		t.Error("Expected custom fields to be a non-nil map")
+	}
+	for key, value := range want.CustomFields {
+		if actual, ok := got.CustomFields[key]; !ok || fmt.Sprint(actual) != fmt.Sprint(value) {
+			t.Errorf("Expected custom field %s = %v, got %v", key, value, actual)
+		}
+	}
+
+	checkTime(t, "created_at", want.CreatedAt, got.CreatedAt)
+	checkTime(t, "updated_at", want.UpdatedAt, got.UpdatedAt)
+}
+
+func tagIDs(tags []connectors.TagRef) []string {
+	ids := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func checkTime(t *testing.T, name string, want, got *time.Time) {
+	t.Helper()
+	switch {
+	case want == nil:
+	case got == nil:
+		t.Errorf("Expected %s %s, got none", name, want.Format(time.RFC3339))
+	case !got.Equal(*want):
+		t.Errorf("Expected %s %s, got %s", name, want.Format(time.RFC3339), got.Format(time.RFC3339))
+	}
+}
+
+// checkListContacts verifies that paging through every contact terminates
+// and visits each contact once
+func checkListContacts(t *testing.T, ctx context.Context, suite Suite, conn connectors.CRMConnector, f *Fixture) {
+	var ids []string
+	err := connectors.IterateContacts(ctx, conn, connectors.QueryOptions{Limit: f.PageSize}, func(c connectors.NormalizedContact) error {
+		if c.SourceCRM != suite.Platform {
+			t.Errorf("Expected source_crm %s on contact %s, got %q", suite.Platform, c.ID, c.SourceCRM)
+		}
+		ids = append(ids, c.ID)
+		if len(ids) > len(f.ContactIDs) {
+			return fmt.Errorf("listed %d contacts, expected %d", len(ids), len(f.ContactIDs))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected pagination to terminate cleanly, got %v", err)
+	}
+	if !reflect.DeepEqual(ids, f.ContactIDs) {
+		t.Errorf("Expected contacts %v, got %v", f.ContactIDs, ids)
+	}
+}
+
+// checkErrors verifies vendor errors surface as ConnectorErrors with the
+// vendor status and the right retryability
+func checkErrors(t *testing.T, ctx context.Context, suite Suite, conn connectors.CRMConnector, f *Fixture) {
+	if len(f.Errors) == 0 {
+		t.Fatal("Expected the fixture to list error cases")
+	}
+	for _, c := range f.Errors {
+		_, err := conn.GetContact(ctx, c.ContactID)
+		var connErr *connectors.ConnectorError
+		if !errors.As(err, &connErr) {
+			t.Errorf("Expected a ConnectorError for contact %s, got %v", c.ContactID, err)
+			continue
+		}
+		if connErr.StatusCode != c.Status {
+			t.Errorf("Expected status %d for contact %s, got %d", c.Status, c.ContactID, connErr.StatusCode)
+		}
+		if connErr.Retryable != c.Retryable {
+			t.Errorf("Expected retryable %v for status %d, got %v", c.Retryable, c.Status, connErr.Retryable)
+		}
+		if connErr.Code == "" {
+			t.Errorf("Expected an error code for status %d", c.Status)
+		}
+		if connErr.Platform != suite.Platform {
+			t.Errorf("Expected platform %s, got %q", suite.Platform, connErr.Platform)
+		}
+	}
+}
+
+// checkTags verifies the tag is listed and that applying and removing it are
+// idempotent
+func checkTags(t *testing.T, ctx context.Context, suite Suite, conn connectors.CRMConnector, f *Fixture) {
+	tags, err := conn.GetTags(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error listing tags, got %v", err)
+	}
+	if len(tags) > 0 && !containsTag(tags, f.TagID) {
+		t.Errorf("Expected tag %s among the listed tag IDs", f.TagID)
+	}
+
+	for i := 1; i <= 2; i++ {
+		if err := conn.ApplyTag(ctx, f.ContactID, f.TagID); err != nil {
+			t.Errorf("Expected apply %d to succeed, got %v", i, err)
+		}
+	}
+	for i := 1; i <= 2; i++ {
+		if err := conn.RemoveTag(ctx, f.ContactID, f.TagID); err != nil {
+			t.Errorf("Expected remove %d to succeed, got %v", i, err)
+		}
+	}
+}
+
+func containsTag(tags []connectors.Tag, tagID string) bool {
+	for _, tag := range tags {
+		if tag.ID == tagID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCustomFields verifies a custom field value survives a write and read.
+// The field is addressed by the ID GetCustomFields reports, as the translation
+// layer does.
+func checkCustomFields(t *testing.T, ctx context.Context, suite Suite, conn connectors.CRMConnector, f *Fixture) {
+	fields, err := conn.GetCustomFields(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error listing custom fields, got %v", err)
+	}
+	if len(fields) > 0 {
+		found := false
+		for _, field := range fields {
+			found = found || field.ID == f.FieldKey
+		}
+		if !found {
+			t.Errorf("Expected field %s among the listed field IDs", f.FieldKey)
+		}
+	}
+
+	if err := conn.SetContactFieldValue(ctx, f.ContactID, f.FieldKey, f.FieldValue); err != nil {
+		t.Fatalf("Expected no error setting %s, got %v", f.FieldKey, err)
+	}
+	value, err := conn.GetContactFieldValue(ctx, f.ContactID, f.FieldKey)
+	if err != nil {
+		t.Fatalf("Expected no error reading %s, got %v", f.FieldKey, err)
+	}
+	if fmt.Sprint(value) != fmt.Sprint(f.FieldValue) {
+		t.Errorf("Expected %s = %v, got %v", f.FieldKey, f.FieldValue, value)
+	}
+}
+
+// ========== REPLAY SERVER ==========
+
+// replayServer answers requests from recorded interactions and fails the test
+// on requests nothing was recorded for
+type replayServer struct {
+	*httptest.Server
+	t *testing.T
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+func newReplayServer(t *testing.T, interactions []Interaction) *replayServer {
+	s := &replayServer{t: t, interactions: interactions, used: make([]bool, len(interactions))}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *replayServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	match := -1
+	for i, interaction := range s.interactions {
+		if (s.used[i] && !interaction.Repeat) || !matches(interaction.Request, r, body) {
+			continue
+		}
+		s.used[i] = true
+		match = i
+		break
+	}
+	s.mu.Unlock()
+
+	if match < 0 {
+		s.t.Errorf("Unexpected request %s %s %s", r.Method, r.URL.RequestURI(), body)
+		// 501 is never retried, so the connector fails fast
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	resp := s.interactions[match].Response
+	for key, value := range resp.Headers {
+		w.Header().Set(key, value)
+	}
+	if w.Header().Get("Content-Type") == "" && len(resp.Body) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(resp.Body)
+}
+
+// assertConsumed fails the test for every one-shot interaction that was not requested
+func (s *replayServer) assertConsumed(t *testing.T) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, interaction := range s.interactions {
+		if !s.used[i] && !interaction.Repeat {
+			t.Errorf("Expected request %s %s was never made", interaction.Request.Method, interaction.Request.Path)
+		}
+	}
+}
+
+func matches(want RecordedRequest, r *http.Request, body []byte) bool {
+	if want.Method != r.Method || want.Path != r.URL.Path {
+		return false
+	}
+	query := r.URL.Query()
+	for key, value := range want.Query {
+		if query.Get(key) != value {
+			return false
+		}
+	}
+	if len(want.Body) > 0 {
+		var wantBody, gotBody interface{}
+		if json.Unmarshal(want.Body, &wantBody) != nil || json.Unmarshal(body, &gotBody) != nil {
+			return false
+		}
+		if !contains(wantBody, gotBody) {
+			return false
+		}
+	}
+	if len(want.Form) > 0 {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return false
+		}
+		for key, value := range want.Form {
+			if form.Get(key) != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// contains reports whether got holds everything in want. Objects may carry
+// extra keys; arrays must match element by element.
+func contains(want, got interface{}) bool {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range w {
+			if !contains(value, g[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !contains(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(want, got)
+	}
+}
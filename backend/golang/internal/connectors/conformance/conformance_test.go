@@ -0,0 +1,63 @@
+package conformance
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	_ "github.com/myfusionhelper/api/internal/connectors/sandbox"
+)
+
+var suites = []Suite{
+	{Platform: "keap", Config: connectors.ConnectorConfig{AccessToken: "test-token"}},
+	{Platform: "gohighlevel", Config: connectors.ConnectorConfig{AccessToken: "test-token", AccountID: "loc123"}},
+	{Platform: "hubspot", Config: connectors.ConnectorConfig{AccessToken: "test-token"}},
+	{Platform: "activecampaign", Config: connectors.ConnectorConfig{APIKey: "test-key"}},
+	{Platform: "ontraport", Config: connectors.ConnectorConfig{APIKey: "test-key", APISecret: "test-app"}},
+	{Platform: "stripe", Config: connectors.ConnectorConfig{APIKey: "sk_test_123"}},
+	{Platform: "sandbox", Config: connectors.ConnectorConfig{APIKey: "demo"}},
+}
+
+func TestConnectors(t *testing.T) {
+	for _, suite := range suites {
+		t.Run(suite.Platform, func(t *testing.T) {
+			Run(t, suite)
+		})
+	}
+}
+
+func TestEveryConnectorHasASuite(t *testing.T) {
+	covered := make(map[string]bool, len(suites))
+	for _, suite := range suites {
+		covered[suite.Platform] = true
+	}
+	for _, slug := range connectors.ListRegistered() {
+		if !covered[slug] {
+			t.Errorf("Expected a conformance suite for %s", slug)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		expected  bool
+	}{
+		{"extra keys", `{"tags":["1"]}`, `{"tags":["1"],"id":2}`, true},
+		{"nested", `{"contact":{"tag":"5"}}`, `{"contact":{"contact":"1","tag":"5"}}`, true},
+		{"different value", `{"tags":["1"]}`, `{"tags":["2"]}`, false},
+		{"array length", `{"tags":["1"]}`, `{"tags":["1","2"]}`, false},
+		{"missing key", `{"id":"1"}`, `{}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want, got interface{}
+			json.Unmarshal([]byte(tt.want), &want)
+			json.Unmarshal([]byte(tt.got), &got)
+			if result := contains(want, got); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -2,9 +2,7 @@ package connectors
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -23,10 +21,11 @@ func init() {
 
 // GoHighLevelConnector implements CRMConnector for GoHighLevel
 type GoHighLevelConnector struct {
-	accessToken string
-	baseURL     string
-	locationID  string
-	client      *http.Client
+	token      *oauthToken
+	baseURL    string
+	locationID string
+	client     *http.Client
+	limiter    *rateLimiter
 }
 
 // NewGoHighLevelConnector creates a new GoHighLevel CRM connector
@@ -41,16 +40,26 @@ func NewGoHighLevelConnector(config ConnectorConfig) (CRMConnector, error) {
 	}
 
 	return &GoHighLevelConnector{
-		accessToken: config.AccessToken,
-		baseURL:     baseURL,
-		locationID:  config.AccountID, // GHL uses locationId
-		client:      &http.Client{Timeout: 30 * time.Second},
+		token:      &oauthToken{access: config.AccessToken, refresh: config.TokenRefresher},
+		baseURL:    baseURL,
+		locationID: config.AccountID, // GHL uses locationId
+		client:     &http.Client{Timeout: 30 * time.Second},
+		limiter:    limiterFor(ghlSlug, config),
 	}, nil
 }
 
 // ========== CONTACTS ==========
 
 func (g *GoHighLevelConnector) GetContacts(ctx context.Context, opts QueryOptions) (*ContactList, error) {
+	q, err := parseContactQuery(ghlSlug, opts)
+	if err != nil {
+		return nil, err
+	}
+	// The contacts list endpoint has a fixed order
+	if q.OrderBy != "" {
+		return nil, invalidQuery(ghlSlug, "gohighlevel does not support ordering contacts")
+	}
+
 	params := url.Values{}
 	if opts.Limit > 0 {
 		params.Set("limit", fmt.Sprintf("%d", opts.Limit))
@@ -58,10 +67,16 @@ func (g *GoHighLevelConnector) GetContacts(ctx context.Context, opts QueryOption
 		params.Set("limit", "20")
 	}
 	if opts.Cursor != "" {
-		params.Set("startAfterId", opts.Cursor)
+		// Cursors are "<startAfterId>:<startAfter>"; older cursors carry only the ID
+		parts := strings.SplitN(opts.Cursor, ":", 2)
+		params.Set("startAfterId", parts[0])
+		if len(parts) == 2 {
+			params.Set("startAfter", parts[1])
+		}
 	}
-	if opts.Email != "" {
-		params.Set("email", opts.Email)
+	if q.Email != "" {
+		// query is a fuzzy search, so the exact match is checked below
+		params.Set("query", q.Email)
 	}
 	if g.locationID != "" {
 		params.Set("locationId", g.locationID)
@@ -75,6 +90,7 @@ func (g *GoHighLevelConnector) GetContacts(ctx context.Context, opts QueryOption
 			Total        int    `json:"total"`
 			NextPageUrl  string `json:"nextPageUrl"`
 			StartAfterId string `json:"startAfterId"`
+			StartAfter   int64  `json:"startAfter"`
 		} `json:"meta"`
 	}
 
@@ -87,12 +103,21 @@ func (g *GoHighLevelConnector) GetContacts(ctx context.Context, opts QueryOption
 		contacts = append(contacts, gc.toNormalized())
 	}
 
-	return &ContactList{
-		Contacts:   contacts,
-		Total:      result.Meta.Total,
-		NextCursor: result.Meta.StartAfterId,
-		HasMore:    result.Meta.NextPageUrl != "",
-	}, nil
+	cl := &ContactList{
+		Contacts: q.filter(contacts),
+		Total:    result.Meta.Total,
+		HasMore:  result.Meta.NextPageUrl != "",
+	}
+	if q.clientSide() {
+		cl.Total = len(cl.Contacts)
+	}
+	if result.Meta.StartAfterId != "" {
+		cl.NextCursor = result.Meta.StartAfterId
+		if result.Meta.StartAfter != 0 {
+			cl.NextCursor += fmt.Sprintf(":%d", result.Meta.StartAfter)
+		}
+	}
+	return cl, nil
 }
 
 func (g *GoHighLevelConnector) GetContact(ctx context.Context, contactID string) (*NormalizedContact, error) {
@@ -201,7 +226,6 @@ func (g *GoHighLevelConnector) GetTags(ctx context.Context) ([]Tag, error) {
 
 	var result struct {
 		Tags []struct {
-			ID   string `json:"id"`
 			Name string `json:"name"`
 		} `json:"tags"`
 	}
@@ -210,10 +234,11 @@ func (g *GoHighLevelConnector) GetTags(ctx context.Context) ([]Tag, error) {
 		return nil, err
 	}
 
+	// Contacts carry tag names and the tag endpoints take names, so the name is the tag ID
 	tags := make([]Tag, 0, len(result.Tags))
 	for _, t := range result.Tags {
 		tags = append(tags, Tag{
-			ID:   t.ID,
+			ID:   t.Name,
 			Name: t.Name,
 		})
 	}
@@ -454,49 +479,31 @@ func (gc *ghlContact) toNormalized() NormalizedContact {
 
 // ========== HTTP HELPER ==========
 
+// doRequest sends an API request, refreshing the access token and retrying once
+// if the token was rejected
 func (g *GoHighLevelConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	var bodyReader io.Reader
-	if body != nil {
-		bodyJSON, err := json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = strings.NewReader(string(bodyJSON))
+	token := g.token.get()
+	err := g.sendRequest(ctx, token, method, path, body, result)
+	if g.token.refresh == nil || !isUnauthorized(err) {
+		return err
 	}
 
-	apiURL := g.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, apiURL, bodyReader)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	token, refreshErr := g.token.refreshAfter(ctx, token)
+	if refreshErr != nil {
+		return tokenRefreshError(ghlSlug, refreshErr)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+g.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Version", ghlAPIVersion)
-
-	resp, err := g.client.Do(req)
-	if err != nil {
-		return NewConnectorError(ghlSlug, 0, fmt.Sprintf("request failed: %v", err), true)
-	}
-	defer resp.Body.Close()
+	return g.sendRequest(ctx, token, method, path, body, result)
+}
 
-	respBody, err := io.ReadAll(resp.Body)
+func (g *GoHighLevelConnector) sendRequest(ctx context.Context, token, method, path string, body interface{}, result interface{}) error {
+	req, err := newJSONRequest(method, g.baseURL+path, body)
 	if err != nil {
-		return NewConnectorError(ghlSlug, resp.StatusCode, "failed to read response", true)
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		retryable := resp.StatusCode == 429 || resp.StatusCode >= 500
-		return NewConnectorError(ghlSlug, resp.StatusCode,
-			fmt.Sprintf("GoHighLevel API error (%d): %s", resp.StatusCode, string(respBody)), retryable)
-	}
-
-	if result != nil && len(respBody) > 0 {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to parse response: %w", err)
-		}
+		return err
 	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Version", ghlAPIVersion)
 
-	return nil
+	t := transport{platform: ghlSlug, name: "GoHighLevel", client: g.client, limiter: g.limiter}
+	return t.do(ctx, req, result)
 }
@@ -32,8 +32,8 @@ func TestNewGoHighLevelConnector(t *testing.T) {
 			t.Fatal("Expected GoHighLevelConnector type")
 		}
 
-		if ghl.accessToken != "test-token" {
-			t.Errorf("Expected access token 'test-token', got '%s'", ghl.accessToken)
+		if ghl.token.get() != "test-token" {
+			t.Errorf("Expected access token 'test-token', got '%s'", ghl.token.get())
 		}
 
 		if ghl.baseURL != "https://custom-api.example.com" {
@@ -148,10 +148,10 @@ func TestGoHighLevelConnector_GetContacts(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			locationID:  "location-123",
-			client:      server.Client(),
+			token:      &oauthToken{access: "test-token"},
+			baseURL:    server.URL,
+			locationID: "location-123",
+			client:     server.Client(),
 		}
 
 		opts := QueryOptions{Limit: 10}
@@ -214,9 +214,9 @@ func TestGoHighLevelConnector_GetContacts(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		result, err := connector.GetContacts(context.Background(), QueryOptions{})
@@ -242,9 +242,9 @@ func TestGoHighLevelConnector_GetContacts(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "invalid-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "invalid-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		_, err := connector.GetContacts(context.Background(), QueryOptions{})
@@ -295,9 +295,9 @@ func TestGoHighLevelConnector_GetContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		contact, err := connector.GetContact(context.Background(), "contact-123")
@@ -327,9 +327,9 @@ func TestGoHighLevelConnector_GetContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		_, err := connector.GetContact(context.Background(), "contact-999")
@@ -382,10 +382,10 @@ func TestGoHighLevelConnector_CreateContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			locationID:  "location-123",
-			client:      server.Client(),
+			token:      &oauthToken{access: "test-token"},
+			baseURL:    server.URL,
+			locationID: "location-123",
+			client:     server.Client(),
 		}
 
 		input := CreateContactInput{
@@ -465,9 +465,9 @@ func TestGoHighLevelConnector_CreateContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		input := CreateContactInput{
@@ -524,9 +524,9 @@ func TestGoHighLevelConnector_UpdateContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		firstName := "UpdatedFirst"
@@ -570,9 +570,9 @@ func TestGoHighLevelConnector_DeleteContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		err := connector.DeleteContact(context.Background(), "contact-123")
@@ -589,9 +589,9 @@ func TestGoHighLevelConnector_DeleteContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		err := connector.DeleteContact(context.Background(), "contact-999")
@@ -655,10 +655,10 @@ func TestGoHighLevelConnector_TestConnection(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			locationID:  "location-123",
-			client:      server.Client(),
+			token:      &oauthToken{access: "test-token"},
+			baseURL:    server.URL,
+			locationID: "location-123",
+			client:     server.Client(),
 		}
 
 		err := connector.TestConnection(context.Background())
@@ -675,10 +675,10 @@ func TestGoHighLevelConnector_TestConnection(t *testing.T) {
 		defer server.Close()
 
 		connector := &GoHighLevelConnector{
-			accessToken: "invalid-token",
-			baseURL:     server.URL,
-			locationID:  "location-123",
-			client:      server.Client(),
+			token:      &oauthToken{access: "invalid-token"},
+			baseURL:    server.URL,
+			locationID: "location-123",
+			client:     server.Client(),
 		}
 
 		err := connector.TestConnection(context.Background())
@@ -4,8 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,9 +22,10 @@ func init() {
 
 // HubSpotConnector implements CRMConnector for HubSpot
 type HubSpotConnector struct {
-	accessToken string
-	baseURL     string
-	client      *http.Client
+	token   *oauthToken
+	baseURL string
+	client  *http.Client
+	limiter *rateLimiter
 }
 
 // NewHubSpotConnector creates a new HubSpot CRM connector
@@ -42,15 +44,32 @@ func NewHubSpotConnector(config ConnectorConfig) (CRMConnector, error) {
 	}
 
 	return &HubSpotConnector{
-		accessToken: token,
-		baseURL:     strings.TrimRight(baseURL, "/"),
-		client:      &http.Client{Timeout: 30 * time.Second},
+		token:   &oauthToken{access: token, refresh: config.TokenRefresher},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		limiter: limiterFor(hubspotSlug, config),
 	}, nil
 }
 
 // ========== CONTACTS ==========
 
+// hubspotContactOrderFields maps the common OrderBy values onto HubSpot contact properties
+var hubspotContactOrderFields = map[string]string{
+	OrderByCreated:   "createdate",
+	OrderByUpdated:   "lastmodifieddate",
+	OrderByEmail:     "email",
+	OrderByFirstName: "firstname",
+	OrderByLastName:  "lastname",
+}
+
+var hubspotContactProperties = []string{"firstname", "lastname", "email", "phone", "company", "jobtitle", "createdate", "lastmodifieddate"}
+
 func (h *HubSpotConnector) GetContacts(ctx context.Context, opts QueryOptions) (*ContactList, error) {
+	q, err := parseContactQuery(hubspotSlug, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	limit := opts.Limit
 	if limit <= 0 {
 		limit = 25
@@ -59,9 +78,17 @@ func (h *HubSpotConnector) GetContacts(ctx context.Context, opts QueryOptions) (
 		limit = 100
 	}
 
-	path := fmt.Sprintf("/crm/v3/objects/contacts?limit=%d&properties=firstname,lastname,email,phone,company,jobtitle,createdate,lastmodifieddate", limit)
+	// Tags are HubSpot static lists, which the search API cannot filter on
+	if q.TagID != "" {
+		return h.getListContacts(ctx, q, limit, opts.Cursor)
+	}
+	if q.Email != "" || q.UpdatedSince != nil || len(q.Fields) > 0 || q.OrderBy != "" {
+		return h.searchContacts(ctx, q, limit, opts.Cursor)
+	}
+
+	path := fmt.Sprintf("/crm/v3/objects/contacts?limit=%d&properties=%s", limit, strings.Join(hubspotContactProperties, ","))
 	if opts.Cursor != "" {
-		path += "&after=" + opts.Cursor
+		path += "&after=" + url.QueryEscape(opts.Cursor)
 	}
 
 	var result hubspotContactsResponse
@@ -85,6 +112,132 @@ func (h *HubSpotConnector) GetContacts(ctx context.Context, opts QueryOptions) (
 	return cl, nil
 }
 
+// searchContacts runs the filters and ordering through the CRM search API
+func (h *HubSpotConnector) searchContacts(ctx context.Context, q *contactQuery, limit int, cursor string) (*ContactList, error) {
+	var filters []map[string]string
+	if q.Email != "" {
+		filters = append(filters, map[string]string{"propertyName": "email", "operator": "EQ", "value": q.Email})
+	}
+	if q.UpdatedSince != nil {
+		filters = append(filters, map[string]string{
+			"propertyName": "lastmodifieddate",
+			"operator":     "GTE",
+			"value":        strconv.FormatInt(q.UpdatedSince.UnixMilli(), 10),
+		})
+	}
+	for key, value := range q.Fields {
+		filters = append(filters, map[string]string{"propertyName": key, "operator": "EQ", "value": value})
+	}
+
+	body := map[string]interface{}{
+		"properties": hubspotContactProperties,
+		"limit":      limit,
+	}
+	if len(filters) > 0 {
+		body["filterGroups"] = []map[string]interface{}{{"filters": filters}}
+	}
+	if cursor != "" {
+		body["after"] = cursor
+	}
+
+	order, err := q.orderField(hubspotSlug, hubspotContactOrderFields)
+	if err != nil {
+		return nil, err
+	}
+	if order != "" {
+		direction := "ASCENDING"
+		if q.Descending {
+			direction = "DESCENDING"
+		}
+		body["sorts"] = []map[string]string{{"propertyName": order, "direction": direction}}
+	}
+
+	var result hubspotContactsResponse
+	if err := h.doRequest(ctx, "POST", "/crm/v3/objects/contacts/search", body, &result); err != nil {
+		return nil, err
+	}
+
+	contacts := make([]NormalizedContact, 0, len(result.Results))
+	for _, hc := range result.Results {
+		contacts = append(contacts, hc.toNormalized())
+	}
+
+	return &ContactList{
+		Contacts:   contacts,
+		Total:      result.Total,
+		NextCursor: result.Paging.Next.After,
+		HasMore:    result.Paging.Next.After != "",
+	}, nil
+}
+
+// getListContacts pages through a static list's members. The remaining
+// filters are applied to each page; ordering is not available.
+func (h *HubSpotConnector) getListContacts(ctx context.Context, q *contactQuery, limit int, cursor string) (*ContactList, error) {
+	if q.OrderBy != "" {
+		return nil, invalidQuery(hubspotSlug, "hubspot cannot order contacts filtered by tag")
+	}
+
+	params := url.Values{}
+	params.Set("count", fmt.Sprintf("%d", limit))
+	if cursor != "" {
+		params.Set("vidOffset", cursor)
+	}
+	for _, property := range hubspotContactProperties {
+		params.Add("property", property)
+	}
+	for key := range q.Fields {
+		params.Add("property", key)
+	}
+
+	var result struct {
+		Contacts []struct {
+			VID        int64 `json:"vid"`
+			Properties map[string]struct {
+				Value string `json:"value"`
+			} `json:"properties"`
+		} `json:"contacts"`
+		HasMore   bool  `json:"has-more"`
+		VIDOffset int64 `json:"vid-offset"`
+	}
+
+	path := "/contacts/v1/lists/" + url.PathEscape(q.TagID) + "/contacts/all?" + params.Encode()
+	if err := h.doRequest(ctx, "GET", path, nil, &result); err != nil {
+		return nil, err
+	}
+
+	contacts := make([]NormalizedContact, 0, len(result.Contacts))
+	for _, lc := range result.Contacts {
+		hc := hubspotContact{
+			ID:         fmt.Sprintf("%d", lc.VID),
+			Properties: make(map[string]string, len(lc.Properties)),
+		}
+		for key, property := range lc.Properties {
+			hc.Properties[key] = property.Value
+		}
+
+		contact := hc.toNormalized()
+		contact.Tags = []TagRef{{ID: q.TagID}}
+		contact.CreatedAt = parseHubSpotTime(hc.Properties["createdate"])
+		contact.UpdatedAt = parseHubSpotTime(hc.Properties["lastmodifieddate"])
+		for key := range q.Fields {
+			if value, ok := hc.Properties[key]; ok {
+				contact.CustomFields[key] = value
+			}
+		}
+		contacts = append(contacts, contact)
+	}
+
+	cl := &ContactList{
+		Contacts: q.filter(contacts, FilterTag),
+		HasMore:  result.HasMore,
+	}
+	cl.Total = len(cl.Contacts)
+	if result.HasMore {
+		cl.NextCursor = fmt.Sprintf("%d", result.VIDOffset)
+	}
+	return cl, nil
+}
+
 func (h *HubSpotConnector) GetContact(ctx context.Context, contactID string) (*NormalizedContact, error) {
 	path := fmt.Sprintf("/crm/v3/objects/contacts/%s?properties=firstname,lastname,email,phone,company,jobtitle,createdate,lastmodifieddate", contactID)
 
@@ -215,19 +368,21 @@ func (h *HubSpotConnector) RemoveTag(ctx context.Context, contactID string, tagI
 // ========== CUSTOM FIELDS ==========
 
 func (h *HubSpotConnector) GetCustomFields(ctx context.Context) ([]CustomField, error) {
-	var result []struct {
-		Name      string `json:"name"`
-		Label     string `json:"label"`
-		Type      string `json:"type"`
-		GroupName string `json:"groupName"`
+	var result struct {
+		Results []struct {
+			Name      string `json:"name"`
+			Label     string `json:"label"`
+			Type      string `json:"type"`
+			GroupName string `json:"groupName"`
+		} `json:"results"`
 	}
 
 	if err := h.doRequest(ctx, "GET", "/crm/v3/properties/contacts", nil, &result); err != nil {
 		return nil, err
 	}
 
-	fields := make([]CustomField, 0, len(result))
-	for _, f := range result {
+	fields := make([]CustomField, 0, len(result.Results))
+	for _, f := range result.Results {
 		fields = append(fields, CustomField{
 			ID:        f.Name,
 			Key:       f.Name,
@@ -300,6 +455,153 @@ func (h *HubSpotConnector) SetOptInStatus(ctx context.Context, contactID string,
 	return h.doRequest(ctx, "PATCH", "/crm/v3/objects/contacts/"+contactID, updates, nil)
 }
 
+// ========== RELATED RECORDS ==========
+
+var (
+	hubspotInvoiceProperties      = []string{"hs_number", "hs_invoice_status", "hs_currency", "hs_amount_billed", "hs_amount_paid", "hs_balance_due", "hs_createdate", "hs_due_date"}
+	hubspotOrderProperties        = []string{"hs_order_name", "hs_pipeline_stage", "hs_currency_code", "hs_total_price", "hs_createdate"}
+	hubspotSubscriptionProperties = []string{"hs_name", "hs_status", "hs_currency_code", "hs_recurring_billing_amount", "hs_recurring_billing_frequency", "hs_recurring_billing_start_date", "hs_next_payment_due_date", "hs_recurring_billing_end_date"}
+)
+
+func (h *HubSpotConnector) ListInvoices(ctx context.Context, contactID string) ([]Invoice, error) {
+	objects, err := h.listAssociatedObjects(ctx, contactID, "invoices", hubspotInvoiceProperties)
+	if err != nil {
+		return nil, err
+	}
+
+	invoices := make([]Invoice, 0, len(objects))
+	for _, obj := range objects {
+		invoices = append(invoices, Invoice{
+			ID:         obj.ID,
+			ContactID:  contactID,
+			Number:     obj.Properties["hs_number"],
+			Status:     obj.Properties["hs_invoice_status"],
+			Currency:   obj.Properties["hs_currency"],
+			Total:      parseHubSpotNumber(obj.Properties["hs_amount_billed"]),
+			AmountPaid: parseHubSpotNumber(obj.Properties["hs_amount_paid"]),
+			AmountDue:  parseHubSpotNumber(obj.Properties["hs_balance_due"]),
+			CreatedAt:  obj.createdAt(),
+			DueAt:      parseHubSpotTime(obj.Properties["hs_due_date"]),
+			SourceCRM:  hubspotSlug,
+		})
+	}
+	sortByCreated(invoices, func(i Invoice) *time.Time { return i.CreatedAt })
+	return invoices, nil
+}
+
+func (h *HubSpotConnector) ListOrders(ctx context.Context, contactID string) ([]Order, error) {
+	objects, err := h.listAssociatedObjects(ctx, contactID, "orders", hubspotOrderProperties)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]Order, 0, len(objects))
+	for _, obj := range objects {
+		orders = append(orders, Order{
+			ID:        obj.ID,
+			ContactID: contactID,
+			Title:     obj.Properties["hs_order_name"],
+			Status:    obj.Properties["hs_pipeline_stage"],
+			Currency:  obj.Properties["hs_currency_code"],
+			Total:     parseHubSpotNumber(obj.Properties["hs_total_price"]),
+			CreatedAt: obj.createdAt(),
+			SourceCRM: hubspotSlug,
+		})
+	}
+	sortByCreated(orders, func(o Order) *time.Time { return o.CreatedAt })
+	return orders, nil
+}
+
+func (h *HubSpotConnector) ListSubscriptions(ctx context.Context, contactID string) ([]Subscription, error) {
+	objects, err := h.listAssociatedObjects(ctx, contactID, "subscriptions", hubspotSubscriptionProperties)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]Subscription, 0, len(objects))
+	for _, obj := range objects {
+		createdAt := parseHubSpotTime(obj.Properties["hs_recurring_billing_start_date"])
+		if createdAt == nil {
+			createdAt = obj.createdAt()
+		}
+		subscriptions = append(subscriptions, Subscription{
+			ID:         obj.ID,
+			ContactID:  contactID,
+			Name:       obj.Properties["hs_name"],
+			Status:     obj.Properties["hs_status"],
+			Currency:   obj.Properties["hs_currency_code"],
+			Amount:     parseHubSpotNumber(obj.Properties["hs_recurring_billing_amount"]),
+			Interval:   obj.Properties["hs_recurring_billing_frequency"],
+			CreatedAt:  createdAt,
+			NextBillAt: parseHubSpotTime(obj.Properties["hs_next_payment_due_date"]),
+			EndedAt:    parseHubSpotTime(obj.Properties["hs_recurring_billing_end_date"]),
+			SourceCRM:  hubspotSlug,
+		})
+	}
+	sortByCreated(subscriptions, func(s Subscription) *time.Time { return s.CreatedAt })
+	return subscriptions, nil
+}
+
+// listAssociatedObjects reads every object of the given type associated with
+// a contact: association IDs first, then batch reads of their properties.
+func (h *HubSpotConnector) listAssociatedObjects(ctx context.Context, contactID, objectType string, properties []string) ([]hubspotObject, error) {
+	var ids []string
+	after := ""
+	for {
+		path := fmt.Sprintf("/crm/v4/objects/contacts/%s/associations/%s?limit=500", contactID, objectType)
+		if after != "" {
+			path += "&after=" + url.QueryEscape(after)
+		}
+
+		var assoc struct {
+			Results []struct {
+				ToObjectID json.Number `json:"toObjectId"`
+			} `json:"results"`
+			Paging struct {
+				Next struct {
+					After string `json:"after"`
+				} `json:"next"`
+			} `json:"paging"`
+		}
+		if err := h.doRequest(ctx, "GET", path, nil, &assoc); err != nil {
+			return nil, err
+		}
+
+		for _, r := range assoc.Results {
+			ids = append(ids, r.ToObjectID.String())
+		}
+		if assoc.Paging.Next.After == "" {
+			break
+		}
+		after = assoc.Paging.Next.After
+	}
+
+	objects := make([]hubspotObject, 0, len(ids))
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		inputs := make([]map[string]string, 0, end-start)
+		for _, id := range ids[start:end] {
+			inputs = append(inputs, map[string]string{"id": id})
+		}
+
+		var batch hubspotContactsResponse
+		body := map[string]interface{}{
+			"properties": properties,
+			"inputs":     inputs,
+		}
+		if err := h.doRequest(ctx, "POST", "/crm/v3/objects/"+objectType+"/batch/read", body, &batch); err != nil {
+			return nil, err
+		}
+		objects = append(objects, batch.Results...)
+	}
+
+	return objects, nil
+}
+
 // ========== HEALTH ==========
 
 func (h *HubSpotConnector) TestConnection(ctx context.Context) error {
@@ -336,6 +638,7 @@ type hubspotContact struct {
 }
 
 type hubspotContactsResponse struct {
+	Total   int              `json:"total"`
 	Results []hubspotContact `json:"results"`
 	Paging  struct {
 		Next struct {
@@ -372,50 +675,67 @@ func (hc *hubspotContact) toNormalized() NormalizedContact {
 	return contact
 }
 
-// ========== HTTP HELPER ==========
+// hubspotObject is the generic CRM object shape shared by contacts and the
+// commerce objects (invoices, orders, subscriptions)
+type hubspotObject = hubspotContact
 
-func (h *HubSpotConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	var bodyReader io.Reader
-	if body != nil {
-		bodyJSON, err := json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = strings.NewReader(string(bodyJSON))
+// createdAt returns the record's hs_createdate, falling back to the object timestamp
+func (hc *hubspotObject) createdAt() *time.Time {
+	if t := parseHubSpotTime(hc.Properties["hs_createdate"]); t != nil {
+		return t
 	}
+	return parseHubSpotTime(hc.CreatedAt)
+}
 
-	apiURL := h.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, apiURL, bodyReader)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+func parseHubSpotNumber(value string) float64 {
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
+
+// parseHubSpotTime parses the ISO timestamps, plain dates and epoch
+// milliseconds HubSpot uses for date properties
+func parseHubSpotTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
 	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		t := time.UnixMilli(ms).UTC()
+		return &t
+	}
+	return nil
+}
 
-	req.Header.Set("Authorization", "Bearer "+h.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+// ========== HTTP HELPER ==========
 
-	resp, err := h.client.Do(req)
-	if err != nil {
-		return NewConnectorError(hubspotSlug, 0, fmt.Sprintf("request failed: %v", err), true)
+// doRequest sends an API request, refreshing the access token and retrying once
+// if the token was rejected
+func (h *HubSpotConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	token := h.token.get()
+	err := h.sendRequest(ctx, token, method, path, body, result)
+	if h.token.refresh == nil || !isUnauthorized(err) {
+		return err
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return NewConnectorError(hubspotSlug, resp.StatusCode, "failed to read response", true)
+	token, refreshErr := h.token.refreshAfter(ctx, token)
+	if refreshErr != nil {
+		return tokenRefreshError(hubspotSlug, refreshErr)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		retryable := resp.StatusCode == 429 || resp.StatusCode >= 500
-		return NewConnectorError(hubspotSlug, resp.StatusCode,
-			fmt.Sprintf("HubSpot API error (%d): %s", resp.StatusCode, string(respBody)), retryable)
-	}
+	return h.sendRequest(ctx, token, method, path, body, result)
+}
 
-	if result != nil && len(respBody) > 0 {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to parse response: %w", err)
-		}
+func (h *HubSpotConnector) sendRequest(ctx context.Context, token, method, path string, body interface{}, result interface{}) error {
+	req, err := newJSONRequest(method, h.baseURL+path, body)
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Authorization", "Bearer "+token)
 
-	return nil
+	t := transport{platform: hubspotSlug, name: "HubSpot", client: h.client, limiter: h.limiter}
+	return t.do(ctx, req, result)
 }
@@ -38,7 +38,27 @@ type CRMConnector interface {
 	GetCapabilities() []Capability
 }
 
-// QueryOptions provides filtering and pagination for list operations
+// NoteConnector is an optional interface for connectors that can attach notes
+// to a contact record. Callers should type-assert before use.
+type NoteConnector interface {
+	CreateNote(ctx context.Context, contactID string, note NoteInput) error
+}
+
+// RelatedRecordsConnector is an optional interface for connectors that expose
+// commerce records attached to a contact. Results are sorted oldest first.
+// Callers should type-assert before use.
+type RelatedRecordsConnector interface {
+	ListInvoices(ctx context.Context, contactID string) ([]Invoice, error)
+	ListOrders(ctx context.Context, contactID string) ([]Order, error)
+	ListSubscriptions(ctx context.Context, contactID string) ([]Subscription, error)
+}
+
+// QueryOptions provides filtering and pagination for list operations.
+// Cursor is opaque: pass back the NextCursor of the previous page. Filters
+// uses the common vocabulary in query.go (FilterEmail, FilterTag,
+// FilterUpdatedSince, FilterFieldPrefix); Email and TagID are shortcuts for
+// the email and tag filters. OrderBy takes one of the OrderBy* values,
+// prefixed with "-" for descending order.
 type QueryOptions struct {
 	Limit    int               `json:"limit"`
 	Offset   int               `json:"offset"`
@@ -49,7 +69,9 @@ type QueryOptions struct {
 	Email    string            `json:"email,omitempty"`
 }
 
-// ContactList represents a paginated list of contacts
+// ContactList represents a paginated list of contacts. When a filter has to be
+// applied client-side a page may hold fewer contacts than the limit, or none,
+// while HasMore is still true; Total then counts only this page's matches.
 type ContactList struct {
 	Contacts   []NormalizedContact `json:"contacts"`
 	Total      int                 `json:"total"`
@@ -78,6 +100,13 @@ type UpdateContactInput struct {
 	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
 }
 
+// NoteInput represents data for creating a note on a contact
+type NoteInput struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Type  string `json:"type,omitempty"`
+}
+
 // ConnectorMetadata provides information about the CRM connector
 type ConnectorMetadata struct {
 	PlatformSlug string `json:"platform_slug"`
@@ -108,4 +137,21 @@ type ConnectorConfig struct {
 	APISecret    string `json:"api_secret,omitempty"`
 	BaseURL      string `json:"base_url,omitempty"`
 	AccountID    string `json:"account_id,omitempty"`
+
+	// TokenRefresher, when set, is called once after a 401 response to obtain a
+	// fresh access token before the request is retried
+	TokenRefresher TokenRefresher `json:"-"`
+
+	// ConnectionID lets connector instances for the same connection share one
+	// rate limiter within a process
+	ConnectionID string `json:"-"`
+
+	// RateLimit is the client-side request budget enforced by the transport
+	RateLimit RateLimit `json:"-"`
 }
+
+// TokenRefresher exchanges the connection's refresh token for a new access token
+// and returns it. Implementations are responsible for persisting the new credentials.
+// rejected is the access token the platform refused; when the refresher has
+// already moved past it, it returns the current token without refreshing again.
+type TokenRefresher func(ctx context.Context, rejected string) (string, error)
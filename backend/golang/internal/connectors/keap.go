@@ -2,9 +2,7 @@ package connectors
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -22,9 +20,10 @@ func init() {
 
 // KeapConnector implements CRMConnector for Keap (Infusionsoft)
 type KeapConnector struct {
-	accessToken string
-	baseURL     string
-	client      *http.Client
+	token   *oauthToken
+	baseURL string
+	client  *http.Client
+	limiter *rateLimiter
 }
 
 // NewKeapConnector creates a new Keap CRM connector
@@ -39,31 +38,67 @@ func NewKeapConnector(config ConnectorConfig) (CRMConnector, error) {
 	}
 
 	return &KeapConnector{
-		accessToken: config.AccessToken,
-		baseURL:     baseURL,
-		client:      &http.Client{Timeout: 30 * time.Second},
+		token:   &oauthToken{access: config.AccessToken, refresh: config.TokenRefresher},
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		limiter: limiterFor(keapSlug, config),
 	}, nil
 }
 
 // ========== CONTACTS ==========
 
+// keapContactOrderFields maps the common OrderBy values onto Keap's order parameter
+var keapContactOrderFields = map[string]string{
+	OrderByCreated:   "date_created",
+	OrderByUpdated:   "last_updated",
+	OrderByEmail:     "email",
+	OrderByFirstName: "firstName",
+}
+
 func (k *KeapConnector) GetContacts(ctx context.Context, opts QueryOptions) (*ContactList, error) {
-	params := url.Values{}
-	if opts.Limit > 0 {
-		params.Set("limit", fmt.Sprintf("%d", opts.Limit))
-	} else {
-		params.Set("limit", "25")
+	q, err := parseContactQuery(keapSlug, opts)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := offsetCursor(keapSlug, opts)
+	if err != nil {
+		return nil, err
 	}
-	if opts.Offset > 0 {
-		params.Set("offset", fmt.Sprintf("%d", opts.Offset))
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 25
 	}
-	if opts.Email != "" {
-		params.Set("email", opts.Email)
+
+	// Keap only lists tag members through the tag itself
+	if q.TagID != "" {
+		return k.getTaggedContacts(ctx, q, limit, offset)
 	}
 
-	endpoint := "/contacts"
-	if len(params) > 0 {
-		endpoint += "?" + params.Encode()
+	order, err := q.orderField(keapSlug, keapContactOrderFields)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", limit))
+	if offset > 0 {
+		params.Set("offset", fmt.Sprintf("%d", offset))
+	}
+	if q.Email != "" {
+		params.Set("email", q.Email)
+	}
+	if q.UpdatedSince != nil {
+		params.Set("since", q.UpdatedSince.UTC().Format(time.RFC3339))
+	}
+	if order != "" {
+		params.Set("order", order)
+		params.Set("order_direction", "ASCENDING")
+		if q.Descending {
+			params.Set("order_direction", "DESCENDING")
+		}
+	}
+	if len(q.Fields) > 0 {
+		params.Set("optional_properties", "custom_fields")
 	}
 
 	var result struct {
@@ -72,7 +107,7 @@ func (k *KeapConnector) GetContacts(ctx context.Context, opts QueryOptions) (*Co
 		Next     string        `json:"next,omitempty"`
 	}
 
-	if err := k.doRequest(ctx, "GET", endpoint, nil, &result); err != nil {
+	if err := k.doRequest(ctx, "GET", "/contacts?"+params.Encode(), nil, &result); err != nil {
 		return nil, err
 	}
 
@@ -81,12 +116,79 @@ func (k *KeapConnector) GetContacts(ctx context.Context, opts QueryOptions) (*Co
 		contacts = append(contacts, kc.toNormalized())
 	}
 
-	return &ContactList{
-		Contacts:   contacts,
-		Total:      result.Count,
-		NextCursor: result.Next,
-		HasMore:    result.Next != "",
-	}, nil
+	pushed := []string{FilterEmail, FilterUpdatedSince}
+	cl := &ContactList{
+		Contacts: q.filter(contacts, pushed...),
+		Total:    result.Count,
+		// Keap keeps returning a next link past the last page, so an empty page ends the list
+		HasMore: result.Next != "" && len(result.Contacts) > 0,
+	}
+	if q.clientSide(pushed...) {
+		cl.Total = len(cl.Contacts)
+	}
+	if cl.HasMore {
+		cl.NextCursor = fmt.Sprintf("%d", offset+len(result.Contacts))
+	}
+	return cl, nil
+}
+
+// getTaggedContacts lists the contacts carrying a tag. The tag listing only
+// returns names and email, so other filters and ordering are rejected.
+func (k *KeapConnector) getTaggedContacts(ctx context.Context, q *contactQuery, limit, offset int) (*ContactList, error) {
+	if q.UpdatedSince != nil || len(q.Fields) > 0 || q.OrderBy != "" {
+		return nil, invalidQuery(keapSlug, "keap cannot combine the tag filter with updated_since, custom field filters or order_by")
+	}
+
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", limit))
+	if offset > 0 {
+		params.Set("offset", fmt.Sprintf("%d", offset))
+	}
+
+	var result struct {
+		Contacts []struct {
+			Contact struct {
+				ID        int    `json:"id"`
+				Email     string `json:"email"`
+				FirstName string `json:"first_name"`
+				LastName  string `json:"last_name"`
+			} `json:"contact"`
+		} `json:"contacts"`
+		Count int    `json:"count"`
+		Next  string `json:"next,omitempty"`
+	}
+
+	if err := k.doRequest(ctx, "GET", "/tags/"+url.PathEscape(q.TagID)+"/contacts?"+params.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+
+	contacts := make([]NormalizedContact, 0, len(result.Contacts))
+	for _, tc := range result.Contacts {
+		id := fmt.Sprintf("%d", tc.Contact.ID)
+		contacts = append(contacts, NormalizedContact{
+			ID:           id,
+			FirstName:    tc.Contact.FirstName,
+			LastName:     tc.Contact.LastName,
+			Email:        tc.Contact.Email,
+			Tags:         []TagRef{{ID: q.TagID}},
+			CustomFields: make(map[string]interface{}),
+			SourceCRM:    keapSlug,
+			SourceID:     id,
+		})
+	}
+
+	cl := &ContactList{
+		Contacts: q.filter(contacts, FilterTag),
+		Total:    result.Count,
+		HasMore:  result.Next != "" && len(result.Contacts) > 0,
+	}
+	if q.Email != "" {
+		cl.Total = len(cl.Contacts)
+	}
+	if cl.HasMore {
+		cl.NextCursor = fmt.Sprintf("%d", offset+len(result.Contacts))
+	}
+	return cl, nil
 }
 
 func (k *KeapConnector) GetContact(ctx context.Context, contactID string) (*NormalizedContact, error) {
@@ -331,6 +433,98 @@ func (k *KeapConnector) AchieveGoal(ctx context.Context, contactID string, goalN
 	return k.doRequest(ctx, "POST", "/funnel/achieve?"+params.Encode(), nil, nil)
 }
 
+// ========== NOTES ==========
+
+func (k *KeapConnector) CreateNote(ctx context.Context, contactID string, note NoteInput) error {
+	noteType := note.Type
+	if noteType == "" {
+		noteType = "Other"
+	}
+	body := map[string]interface{}{
+		"title": note.Title,
+		"text":  note.Body,
+		"type":  noteType,
+	}
+	return k.doRequest(ctx, "POST", "/contacts/"+contactID+"/notes", body, nil)
+}
+
+// ========== RELATED RECORDS ==========
+
+// keapRelatedPageSize is the page size used when listing a contact's orders
+const keapRelatedPageSize = 1000
+
+// ListInvoices returns the contact's invoices. Keap issues one invoice per
+// order, so invoices are derived from the order's payment totals.
+func (k *KeapConnector) ListInvoices(ctx context.Context, contactID string) ([]Invoice, error) {
+	orders, err := k.listOrders(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	invoices := make([]Invoice, 0, len(orders))
+	for _, ko := range orders {
+		invoices = append(invoices, ko.toInvoice(contactID))
+	}
+	sortByCreated(invoices, func(i Invoice) *time.Time { return i.CreatedAt })
+	return invoices, nil
+}
+
+func (k *KeapConnector) ListOrders(ctx context.Context, contactID string) ([]Order, error) {
+	orders, err := k.listOrders(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Order, 0, len(orders))
+	for _, ko := range orders {
+		result = append(result, ko.toOrder(contactID))
+	}
+	sortByCreated(result, func(o Order) *time.Time { return o.CreatedAt })
+	return result, nil
+}
+
+func (k *KeapConnector) ListSubscriptions(ctx context.Context, contactID string) ([]Subscription, error) {
+	params := url.Values{}
+	params.Set("contact_id", contactID)
+
+	var result struct {
+		Subscriptions []keapSubscription `json:"subscriptions"`
+	}
+	if err := k.doRequest(ctx, "GET", "/subscriptions?"+params.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]Subscription, 0, len(result.Subscriptions))
+	for _, ks := range result.Subscriptions {
+		subscriptions = append(subscriptions, ks.toSubscription(contactID))
+	}
+	sortByCreated(subscriptions, func(s Subscription) *time.Time { return s.CreatedAt })
+	return subscriptions, nil
+}
+
+func (k *KeapConnector) listOrders(ctx context.Context, contactID string) ([]keapOrder, error) {
+	var orders []keapOrder
+	for offset := 0; ; offset += keapRelatedPageSize {
+		params := url.Values{}
+		params.Set("contact_id", contactID)
+		params.Set("limit", fmt.Sprintf("%d", keapRelatedPageSize))
+		params.Set("offset", fmt.Sprintf("%d", offset))
+
+		var result struct {
+			Orders []keapOrder `json:"orders"`
+			Count  int         `json:"count"`
+		}
+		if err := k.doRequest(ctx, "GET", "/orders?"+params.Encode(), nil, &result); err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, result.Orders...)
+		if len(result.Orders) < keapRelatedPageSize || len(orders) >= result.Count {
+			return orders, nil
+		}
+	}
+}
+
 // ========== MARKETING ==========
 
 func (k *KeapConnector) SetOptInStatus(ctx context.Context, contactID string, optIn bool, reason string) error {
@@ -455,50 +649,143 @@ func (kc *keapContact) toNormalized() NormalizedContact {
 	return contact
 }
 
-// ========== HTTP HELPER ==========
+type keapOrder struct {
+	ID           int     `json:"id"`
+	Title        string  `json:"title"`
+	Status       string  `json:"status"`
+	Total        float64 `json:"total"`
+	TotalPaid    float64 `json:"total_paid"`
+	TotalDue     float64 `json:"total_due"`
+	CreationDate string  `json:"creation_date"`
+	OrderDate    string  `json:"order_date"`
+	OrderItems   []struct {
+		Name     string  `json:"name"`
+		Quantity int     `json:"quantity"`
+		Price    float64 `json:"price"`
+		Product  struct {
+			ID int `json:"id"`
+		} `json:"product"`
+	} `json:"order_items"`
+}
 
-func (k *KeapConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	var bodyReader io.Reader
-	if body != nil {
-		bodyJSON, err := json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = strings.NewReader(string(bodyJSON))
+func (ko *keapOrder) createdAt() *time.Time {
+	if t := parseKeapDate(ko.CreationDate); t != nil {
+		return t
 	}
+	return parseKeapDate(ko.OrderDate)
+}
 
-	apiURL := k.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, apiURL, bodyReader)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+func (ko *keapOrder) toInvoice(contactID string) Invoice {
+	// Keap's total_due is the balance still owed on the order
+	return Invoice{
+		ID:         fmt.Sprintf("%d", ko.ID),
+		ContactID:  contactID,
+		Status:     strings.ToLower(ko.Status),
+		Total:      ko.Total,
+		AmountPaid: ko.TotalPaid,
+		AmountDue:  ko.TotalDue,
+		CreatedAt:  ko.createdAt(),
+		SourceCRM:  keapSlug,
+	}
+}
+
+func (ko *keapOrder) toOrder(contactID string) Order {
+	order := Order{
+		ID:        fmt.Sprintf("%d", ko.ID),
+		ContactID: contactID,
+		Title:     ko.Title,
+		Status:    strings.ToLower(ko.Status),
+		Total:     ko.Total,
+		CreatedAt: ko.createdAt(),
+		SourceCRM: keapSlug,
+	}
+	for _, item := range ko.OrderItems {
+		orderItem := OrderItem{
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Price:    item.Price,
+		}
+		if item.Product.ID != 0 {
+			orderItem.ProductID = fmt.Sprintf("%d", item.Product.ID)
+		}
+		order.Items = append(order.Items, orderItem)
 	}
+	return order
+}
 
-	req.Header.Set("Authorization", "Bearer "+k.accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+type keapSubscription struct {
+	ID               int     `json:"id"`
+	ProductID        int     `json:"product_id"`
+	BillingAmount    float64 `json:"billing_amount"`
+	BillingCycle     string  `json:"billing_cycle"`
+	BillingFrequency int     `json:"billing_frequency"`
+	StartDate        string  `json:"start_date"`
+	NextBillDate     string  `json:"next_bill_date"`
+	EndDate          string  `json:"end_date"`
+	Active           bool    `json:"active"`
+}
 
-	resp, err := k.client.Do(req)
-	if err != nil {
-		return NewConnectorError(keapSlug, 0, fmt.Sprintf("request failed: %v", err), true)
+func (ks *keapSubscription) toSubscription(contactID string) Subscription {
+	sub := Subscription{
+		ID:            fmt.Sprintf("%d", ks.ID),
+		ContactID:     contactID,
+		Status:        "inactive",
+		Amount:        ks.BillingAmount,
+		Interval:      strings.ToLower(ks.BillingCycle),
+		IntervalCount: ks.BillingFrequency,
+		CreatedAt:     parseKeapDate(ks.StartDate),
+		NextBillAt:    parseKeapDate(ks.NextBillDate),
+		EndedAt:       parseKeapDate(ks.EndDate),
+		SourceCRM:     keapSlug,
+	}
+	if ks.Active {
+		sub.Status = "active"
+	}
+	if ks.ProductID != 0 {
+		sub.ProductID = fmt.Sprintf("%d", ks.ProductID)
+	}
+	return sub
+}
+
+// parseKeapDate parses the RFC3339 timestamps and plain dates Keap returns
+func parseKeapDate(value string) *time.Time {
+	if value == "" {
+		return nil
 	}
-	defer resp.Body.Close()
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return NewConnectorError(keapSlug, resp.StatusCode, "failed to read response", true)
+// ========== HTTP HELPER ==========
+
+// doRequest sends an API request, refreshing the access token and retrying once
+// if the token was rejected
+func (k *KeapConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	token := k.token.get()
+	err := k.sendRequest(ctx, token, method, path, body, result)
+	if k.token.refresh == nil || !isUnauthorized(err) {
+		return err
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		retryable := resp.StatusCode == 429 || resp.StatusCode >= 500
-		return NewConnectorError(keapSlug, resp.StatusCode,
-			fmt.Sprintf("Keap API error (%d): %s", resp.StatusCode, string(respBody)), retryable)
+	token, refreshErr := k.token.refreshAfter(ctx, token)
+	if refreshErr != nil {
+		return tokenRefreshError(keapSlug, refreshErr)
 	}
 
-	if result != nil && len(respBody) > 0 {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to parse response: %w", err)
-		}
+	return k.sendRequest(ctx, token, method, path, body, result)
+}
+
+func (k *KeapConnector) sendRequest(ctx context.Context, token, method, path string, body interface{}, result interface{}) error {
+	req, err := newJSONRequest(method, k.baseURL+path, body)
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Authorization", "Bearer "+token)
 
-	return nil
+	t := transport{platform: keapSlug, name: "Keap", client: k.client, limiter: k.limiter}
+	return t.do(ctx, req, result)
 }
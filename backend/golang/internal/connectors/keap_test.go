@@ -3,10 +3,14 @@ package connectors
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // TestNewKeapConnector tests connector initialization
@@ -31,8 +35,8 @@ func TestNewKeapConnector(t *testing.T) {
 			t.Fatal("Expected KeapConnector type")
 		}
 
-		if keap.accessToken != "test-token" {
-			t.Errorf("Expected access token 'test-token', got '%s'", keap.accessToken)
+		if keap.token.get() != "test-token" {
+			t.Errorf("Expected access token 'test-token', got '%s'", keap.token.get())
 		}
 
 		if keap.baseURL != "https://custom-api.example.com" {
@@ -128,9 +132,9 @@ func TestKeapConnector_GetContacts(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		opts := QueryOptions{Limit: 10}
@@ -182,9 +186,9 @@ func TestKeapConnector_GetContacts(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		result, err := connector.GetContacts(context.Background(), QueryOptions{})
@@ -206,9 +210,9 @@ func TestKeapConnector_GetContacts(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "invalid-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "invalid-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		_, err := connector.GetContacts(context.Background(), QueryOptions{})
@@ -228,6 +232,174 @@ func TestKeapConnector_GetContacts(t *testing.T) {
 	})
 }
 
+// TestKeapConnector_TokenRefresh tests the refresh-and-retry on 401 responses
+func TestKeapConnector_TokenRefresh(t *testing.T) {
+	t.Run("retries once with refreshed token", func(t *testing.T) {
+		var authHeaders []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+			if r.Header.Get("Authorization") != "Bearer fresh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id": 123, "given_name": "John"}`))
+		}))
+		defer server.Close()
+
+		refreshCalls := 0
+		connector := &KeapConnector{
+			baseURL: server.URL,
+			client:  server.Client(),
+			token: &oauthToken{access: "expired-token", refresh: func(ctx context.Context, rejected string) (string, error) {
+				refreshCalls++
+				return "fresh-token", nil
+			}},
+		}
+
+		contact, err := connector.GetContact(context.Background(), "123")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if contact.FirstName != "John" {
+			t.Errorf("Expected first name 'John', got '%s'", contact.FirstName)
+		}
+		if refreshCalls != 1 {
+			t.Errorf("Expected 1 refresh, got %d", refreshCalls)
+		}
+		if len(authHeaders) != 2 || authHeaders[0] != "Bearer expired-token" {
+			t.Errorf("Expected original then refreshed token, got %v", authHeaders)
+		}
+		if connector.token.get() != "fresh-token" {
+			t.Errorf("Expected connector to keep refreshed token, got '%s'", connector.token.get())
+		}
+	})
+
+	t.Run("refresh failure returns unauthorized", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		connector := &KeapConnector{
+			baseURL: server.URL,
+			client:  server.Client(),
+			token: &oauthToken{access: "expired-token", refresh: func(ctx context.Context, rejected string) (string, error) {
+				return "", fmt.Errorf("invalid_grant")
+			}},
+		}
+
+		_, err := connector.GetContact(context.Background(), "123")
+		connErr, ok := err.(*ConnectorError)
+		if !ok {
+			t.Fatalf("Expected ConnectorError, got %T", err)
+		}
+		if connErr.Code != "TOKEN_REFRESH_FAILED" || connErr.StatusCode != 401 {
+			t.Errorf("Expected TOKEN_REFRESH_FAILED/401, got %s/%d", connErr.Code, connErr.StatusCode)
+		}
+		if requests != 1 {
+			t.Errorf("Expected no retry after failed refresh, got %d requests", requests)
+		}
+	})
+
+	t.Run("concurrent 401s refresh once", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer fresh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id": 123, "given_name": "John"}`))
+		}))
+		defer server.Close()
+
+		var refreshCalls int32
+		release := make(chan struct{})
+		connector := &KeapConnector{
+			baseURL: server.URL,
+			client:  server.Client(),
+			token: &oauthToken{access: "expired-token", refresh: func(ctx context.Context, rejected string) (string, error) {
+				atomic.AddInt32(&refreshCalls, 1)
+				<-release
+				return "fresh-token", nil
+			}},
+		}
+
+		const callers = 5
+		var wg sync.WaitGroup
+		errs := make(chan error, callers)
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := connector.GetContact(context.Background(), "123")
+				errs <- err
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}
+		if n := atomic.LoadInt32(&refreshCalls); n != 1 {
+			t.Errorf("Expected 1 refresh, got %d", n)
+		}
+	})
+}
+
+// TestKeapConnector_ListInvoices tests invoices derived from a contact's orders
+func TestKeapConnector_ListInvoices(t *testing.T) {
+	t.Run("sorted oldest first", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/orders" {
+				t.Errorf("Expected path /orders, got %s", r.URL.Path)
+			}
+			if r.URL.Query().Get("contact_id") != "123" {
+				t.Errorf("Expected contact_id 123, got %s", r.URL.Query().Get("contact_id"))
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{
+				"orders": [
+					{"id": 2, "status": "PAID", "total": 50, "total_paid": 50, "total_due": 0, "creation_date": "2024-03-01T10:00:00.000Z"},
+					{"id": 1, "status": "UNPAID", "total": 100, "total_paid": 25, "total_due": 75, "creation_date": "2024-01-15T10:00:00.000Z"}
+				],
+				"count": 2
+			}`))
+		}))
+		defer server.Close()
+
+		connector := &KeapConnector{
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
+		}
+
+		invoices, err := connector.ListInvoices(context.Background(), "123")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(invoices) != 2 {
+			t.Fatalf("Expected 2 invoices, got %d", len(invoices))
+		}
+		if invoices[0].ID != "1" || invoices[1].ID != "2" {
+			t.Errorf("Expected invoices sorted oldest first, got %s, %s", invoices[0].ID, invoices[1].ID)
+		}
+		if invoices[0].AmountPaid != 25 || invoices[0].AmountDue != 75 || invoices[0].Status != "unpaid" {
+			t.Errorf("Unexpected invoice: %+v", invoices[0])
+		}
+		if invoices[0].SourceCRM != "keap" {
+			t.Errorf("Expected source CRM 'keap', got '%s'", invoices[0].SourceCRM)
+		}
+	})
+}
+
 // TestKeapConnector_GetContact tests single contact retrieval
 func TestKeapConnector_GetContact(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
@@ -256,9 +428,9 @@ func TestKeapConnector_GetContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		contact, err := connector.GetContact(context.Background(), "123")
@@ -290,9 +462,9 @@ func TestKeapConnector_GetContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		_, err := connector.GetContact(context.Background(), "999")
@@ -343,9 +515,9 @@ func TestKeapConnector_CreateContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		input := CreateContactInput{
@@ -421,9 +593,9 @@ func TestKeapConnector_CreateContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		input := CreateContactInput{
@@ -477,9 +649,9 @@ func TestKeapConnector_UpdateContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		firstName := "UpdatedFirst"
@@ -523,9 +695,9 @@ func TestKeapConnector_DeleteContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		err := connector.DeleteContact(context.Background(), "123")
@@ -542,9 +714,9 @@ func TestKeapConnector_DeleteContact(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		err := connector.DeleteContact(context.Background(), "999")
@@ -598,9 +770,9 @@ func TestKeapConnector_TestConnection(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "test-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "test-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		err := connector.TestConnection(context.Background())
@@ -617,9 +789,9 @@ func TestKeapConnector_TestConnection(t *testing.T) {
 		defer server.Close()
 
 		connector := &KeapConnector{
-			accessToken: "invalid-token",
-			baseURL:     server.URL,
-			client:      server.Client(),
+			token:   &oauthToken{access: "invalid-token"},
+			baseURL: server.URL,
+			client:  server.Client(),
 		}
 
 		err := connector.TestConnection(context.Background())
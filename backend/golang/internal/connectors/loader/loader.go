@@ -2,46 +2,26 @@ package loader
 
 import (
 	"context"
-	"os"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/myfusionhelper/api/internal/connectors"
+	_ "github.com/myfusionhelper/api/internal/connectors/sandbox"
 	"github.com/myfusionhelper/api/internal/connectors/translate"
+	"github.com/myfusionhelper/api/internal/database"
 	apitypes "github.com/myfusionhelper/api/internal/types"
 )
 
-var (
-	connectionsTable     = os.Getenv("CONNECTIONS_TABLE")
-	connectionAuthsTable = os.Getenv("PLATFORM_CONNECTION_AUTHS_TABLE")
-	platformsTable       = os.Getenv("PLATFORMS_TABLE")
-)
-
 // LoadConnector loads a CRM connector by looking up the connection, auth credentials,
-// and platform definition from DynamoDB. It verifies account ownership.
-func LoadConnector(ctx context.Context, db *dynamodb.Client, connectionID, accountID string) (connectors.CRMConnector, error) {
+// and platform definition in the stores. It verifies account ownership.
+func LoadConnector(ctx context.Context, stores *database.Stores, connectionID, accountID string) (connectors.CRMConnector, error) {
 	// Get the connection record
-	connResult, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(connectionsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"connection_id": &ddbtypes.AttributeValueMemberS{Value: connectionID},
-		},
-	})
-	if err != nil || connResult.Item == nil {
+	connection, err := stores.Connections.GetByID(ctx, connectionID)
+	if err != nil || connection == nil {
 		return nil, &connectors.ConnectorError{
 			Code: "CONNECTION_NOT_FOUND", Message: "connection not found",
 			StatusCode: 404, Platform: "unknown",
 		}
 	}
 
-	var connection apitypes.PlatformConnection
-	if err := attributevalue.UnmarshalMap(connResult.Item, &connection); err != nil {
-		return nil, err
-	}
-
 	// Verify account ownership
 	if connection.AccountID != accountID {
 		return nil, &connectors.ConnectorError{
@@ -58,50 +38,46 @@ func LoadConnector(ctx context.Context, db *dynamodb.Client, connectionID, accou
 		}
 	}
 
-	authResult, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(connectionAuthsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"auth_id": &ddbtypes.AttributeValueMemberS{Value: *connection.AuthID},
-		},
-	})
-	if err != nil || authResult.Item == nil {
+	auth, err := stores.ConnectionAuths.GetByID(ctx, *connection.AuthID)
+	if err != nil || auth == nil {
 		return nil, &connectors.ConnectorError{
 			Code: "AUTH_NOT_FOUND", Message: "auth credentials not found",
 			StatusCode: 404, Platform: "unknown",
 		}
 	}
 
-	var auth apitypes.PlatformConnectionAuth
-	if err := attributevalue.UnmarshalMap(authResult.Item, &auth); err != nil {
-		return nil, err
-	}
-
 	// Get the platform to determine slug
-	platformResult, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(platformsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"platform_id": &ddbtypes.AttributeValueMemberS{Value: connection.PlatformID},
-		},
-	})
-	if err != nil || platformResult.Item == nil {
+	platform, err := stores.Platforms.GetByID(ctx, connection.PlatformID)
+	if err != nil || platform == nil {
 		return nil, &connectors.ConnectorError{
 			Code: "PLATFORM_NOT_FOUND", Message: "platform not found",
 			StatusCode: 404, Platform: "unknown",
 		}
 	}
 
-	var platform apitypes.Platform
-	if err := attributevalue.UnmarshalMap(platformResult.Item, &platform); err != nil {
+	// Refresh OAuth credentials that are expired or about to expire
+	auth, err = refreshIfExpiring(ctx, stores.ConnectionAuths, auth, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := rateLimit(platform)
+	if err != nil {
 		return nil, err
 	}
 
 	// Build connector config
 	connConfig := connectors.ConnectorConfig{
-		AccessToken: auth.AccessToken,
-		APIKey:      auth.APIKey,
-		APISecret:   auth.APISecret,
-		BaseURL:     platform.APIConfig.BaseURL,
-		AccountID:   connection.ExternalAppID,
+		AccessToken:  auth.AccessToken,
+		APIKey:       auth.APIKey,
+		APISecret:    auth.APISecret,
+		BaseURL:      platform.APIConfig.BaseURL,
+		AccountID:    connection.ExternalAppID,
+		ConnectionID: connection.ConnectionID,
+		RateLimit:    limit,
+	}
+	if auth.RefreshToken != "" {
+		connConfig.TokenRefresher = refreshingTokenSource(stores.ConnectionAuths, auth, platform)
 	}
 
 	return connectors.NewConnector(platform.Slug, connConfig)
@@ -109,38 +85,30 @@ func LoadConnector(ctx context.Context, db *dynamodb.Client, connectionID, accou
 
 // LoadConnectorWithTranslation loads a connector and wraps it with the translation
 // layer for field name standardization, custom field resolution, and data normalization.
-func LoadConnectorWithTranslation(ctx context.Context, db *dynamodb.Client, connectionID, accountID string) (connectors.CRMConnector, error) {
-	connector, err := LoadConnector(ctx, db, connectionID, accountID)
+// Calls are traced beneath the translation layer, so a connectors.Trace on the
+// call context records the resolved field keys and tag IDs sent to the CRM.
+func LoadConnectorWithTranslation(ctx context.Context, stores *database.Stores, connectionID, accountID string) (connectors.CRMConnector, error) {
+	connector, err := LoadConnector(ctx, stores, connectionID, accountID)
 	if err != nil {
 		return nil, err
 	}
-	return translate.NewTranslatingConnector(connector), nil
+	return translate.NewTranslatingConnector(connectors.NewTracingConnector(connector)), nil
 }
 
 // LoadServiceAuth loads auth credentials for a non-CRM service connection.
 // Returns the raw ConnectorConfig (access_token, api_key, etc.) without
 // creating a CRMConnector. Used by helpers that integrate with external
 // services like Zoom, Google Sheets, Trello, etc.
-func LoadServiceAuth(ctx context.Context, db *dynamodb.Client, connectionID, accountID string) (*connectors.ConnectorConfig, error) {
+func LoadServiceAuth(ctx context.Context, stores *database.Stores, connectionID, accountID string) (*connectors.ConnectorConfig, error) {
 	// Get the connection record
-	connResult, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(connectionsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"connection_id": &ddbtypes.AttributeValueMemberS{Value: connectionID},
-		},
-	})
-	if err != nil || connResult.Item == nil {
+	connection, err := stores.Connections.GetByID(ctx, connectionID)
+	if err != nil || connection == nil {
 		return nil, &connectors.ConnectorError{
 			Code: "CONNECTION_NOT_FOUND", Message: "service connection not found",
 			StatusCode: 404, Platform: "unknown",
 		}
 	}
 
-	var connection apitypes.PlatformConnection
-	if err := attributevalue.UnmarshalMap(connResult.Item, &connection); err != nil {
-		return nil, err
-	}
-
 	// Verify account ownership
 	if connection.AccountID != accountID {
 		return nil, &connectors.ConnectorError{
@@ -157,49 +125,66 @@ func LoadServiceAuth(ctx context.Context, db *dynamodb.Client, connectionID, acc
 		}
 	}
 
-	authResult, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(connectionAuthsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"auth_id": &ddbtypes.AttributeValueMemberS{Value: *connection.AuthID},
-		},
-	})
-	if err != nil || authResult.Item == nil {
+	auth, err := stores.ConnectionAuths.GetByID(ctx, *connection.AuthID)
+	if err != nil || auth == nil {
 		return nil, &connectors.ConnectorError{
 			Code: "AUTH_NOT_FOUND", Message: "service auth credentials not found",
 			StatusCode: 404, Platform: "unknown",
 		}
 	}
 
-	var auth apitypes.PlatformConnectionAuth
-	if err := attributevalue.UnmarshalMap(authResult.Item, &auth); err != nil {
-		return nil, err
-	}
-
 	// Get the platform for base URL
-	platformResult, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(platformsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"platform_id": &ddbtypes.AttributeValueMemberS{Value: connection.PlatformID},
-		},
-	})
-	if err != nil || platformResult.Item == nil {
+	platform, err := stores.Platforms.GetByID(ctx, connection.PlatformID)
+	if err != nil || platform == nil {
 		return nil, &connectors.ConnectorError{
 			Code: "PLATFORM_NOT_FOUND", Message: "platform not found",
 			StatusCode: 404, Platform: "unknown",
 		}
 	}
 
-	var platform apitypes.Platform
-	if err := attributevalue.UnmarshalMap(platformResult.Item, &platform); err != nil {
+	// Refresh OAuth credentials that are expired or about to expire
+	auth, err = refreshIfExpiring(ctx, stores.ConnectionAuths, auth, platform)
+	if err != nil {
 		return nil, err
 	}
 
-	return &connectors.ConnectorConfig{
+	limit, err := rateLimit(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	connConfig := &connectors.ConnectorConfig{
 		AccessToken:  auth.AccessToken,
 		RefreshToken: auth.RefreshToken,
 		APIKey:       auth.APIKey,
 		APISecret:    auth.APISecret,
 		BaseURL:      platform.APIConfig.BaseURL,
 		AccountID:    connection.ExternalAppID,
-	}, nil
+		ConnectionID: connection.ConnectionID,
+		RateLimit:    limit,
+	}
+	if auth.RefreshToken != "" {
+		connConfig.TokenRefresher = refreshingTokenSource(stores.ConnectionAuths, auth, platform)
+	}
+
+	return connConfig, nil
+}
+
+// rateLimit converts the platform's seeded API limits into the connector
+// transport's budget, rejecting limits the transport would not apply
+func rateLimit(platform *apitypes.Platform) (connectors.RateLimit, error) {
+	limits := platform.APIConfig.RateLimits
+	limit := connectors.RateLimit{
+		RequestsPerSecond: limits.RequestsPerSecond,
+		RequestsPerMinute: limits.RequestsPerMinute,
+		RequestsPerHour:   limits.RequestsPerHour,
+		Burst:             limits.BurstLimit,
+	}
+	if err := limit.Validate(); err != nil {
+		return limit, &connectors.ConnectorError{
+			Code: "INVALID_RATE_LIMIT", Message: "platform rate limits are invalid: " + err.Error(),
+			StatusCode: 500, Platform: platform.Slug,
+		}
+	}
+	return limit, nil
 }
@@ -0,0 +1,236 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/config"
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/database"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+// TokenExpiryLeeway is how long before expires_at a token is treated as expired,
+// so a request never starts with a token that dies mid-execution.
+const TokenExpiryLeeway = 5 * time.Minute
+
+var tokenHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// RefreshError is returned when the platform's token endpoint rejects or fails
+// a refresh. Permanent is set when the refresh token itself is no longer valid
+// and retrying will not help; the user has to reconnect.
+type RefreshError struct {
+	StatusCode int
+	Message    string
+	Permanent  bool
+}
+
+func (e *RefreshError) Error() string {
+	return e.Message
+}
+
+// IsPermanentRefreshError reports whether err means the connection must be re-authorized
+func IsPermanentRefreshError(err error) bool {
+	var refreshErr *RefreshError
+	return errors.As(err, &refreshErr) && refreshErr.Permanent
+}
+
+// NeedsRefresh reports whether an OAuth credential expires within the leeway
+// and can be refreshed
+func NeedsRefresh(auth *apitypes.PlatformConnectionAuth, leeway time.Duration) bool {
+	if auth.RefreshToken == "" || auth.ExpiresAt == 0 {
+		return false
+	}
+	return time.Now().Add(leeway).Unix() >= auth.ExpiresAt
+}
+
+// RefreshAuth exchanges the stored refresh token for new credentials using the
+// platform's token URL and persists them. The write is conditioned on the
+// auth record's version, so when two invocations race only one refresh wins
+// and the loser adopts the credentials the winner stored.
+func RefreshAuth(ctx context.Context, auths database.ConnectionAuthStore, auth *apitypes.PlatformConnectionAuth, platform *apitypes.Platform) (*apitypes.PlatformConnectionAuth, error) {
+	if auth.RefreshToken == "" {
+		return nil, &RefreshError{Message: "connection has no refresh token", Permanent: true}
+	}
+
+	oauthConfig, err := config.GetPlatformOAuth(ctx, platform.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OAuth config for %s: %w", platform.Slug, err)
+	}
+
+	tokenURL := oauthConfig.TokenURL
+	if tokenURL == "" && platform.OAuth != nil {
+		tokenURL = platform.OAuth.TokenURL
+	}
+	if tokenURL == "" {
+		return nil, fmt.Errorf("no token URL configured for platform: %s", platform.Slug)
+	}
+
+	tokens, err := requestTokenRefresh(ctx, tokenURL, oauthConfig.ClientID, oauthConfig.ClientSecret, auth.RefreshToken)
+	if err != nil {
+		// Another invocation may have rotated the refresh token underneath us
+		if latest, loadErr := loadAuth(ctx, auths, auth.AuthID); loadErr == nil && latest.Version > auth.Version {
+			return latest, nil
+		}
+		if recordErr := auths.RecordRefreshFailure(ctx, auth.AuthID, err.Error()); recordErr != nil {
+			log.Printf("Failed to record refresh failure for auth %s: %v", auth.AuthID, recordErr)
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC().Unix()
+	refreshed := *auth
+	refreshed.AccessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		refreshed.RefreshToken = tokens.RefreshToken
+	}
+	refreshed.ExpiresAt = 0
+	if tokens.ExpiresIn > 0 {
+		refreshed.ExpiresAt = now + int64(tokens.ExpiresIn)
+	}
+	refreshed.Version = auth.Version + 1
+	refreshed.Status = "active"
+	refreshed.RefreshAttempts = 0
+	refreshed.LastRefreshAt = &now
+	refreshed.LastRefreshError = nil
+	refreshed.UpdatedAt = now
+
+	if err := auths.UpdateCredentials(ctx, &refreshed, auth.Version); err != nil {
+		if errors.Is(err, database.ErrConditionFailed) {
+			// Lost the race; the winner's credentials are the current ones
+			latest, loadErr := loadAuth(ctx, auths, auth.AuthID)
+			if loadErr != nil {
+				return nil, fmt.Errorf("failed to reload refreshed credentials: %w", loadErr)
+			}
+			return latest, nil
+		}
+		// The platform already issued the new tokens, so use them for this
+		// invocation even though they could not be stored
+		log.Printf("Failed to persist refreshed credentials for auth %s: %v", auth.AuthID, err)
+		return &refreshed, nil
+	}
+
+	log.Printf("Refreshed OAuth token for auth %s (%s), version %d", auth.AuthID, platform.Slug, refreshed.Version)
+	return &refreshed, nil
+}
+
+// refreshingTokenSource returns a TokenRefresher bound to one auth record. It
+// serializes refreshes, and a caller whose rejected token is older than the
+// current one gets the current one, so concurrent 401s refresh once.
+func refreshingTokenSource(auths database.ConnectionAuthStore, auth *apitypes.PlatformConnectionAuth, platform *apitypes.Platform) connectors.TokenRefresher {
+	var mu sync.Mutex
+	current := auth
+
+	return func(ctx context.Context, rejected string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if current.AccessToken != rejected {
+			return current.AccessToken, nil
+		}
+		refreshed, err := RefreshAuth(ctx, auths, current, platform)
+		if err != nil {
+			return "", err
+		}
+		current = refreshed
+		return refreshed.AccessToken, nil
+	}
+}
+
+// refreshIfExpiring refreshes credentials that expire within TokenExpiryLeeway.
+// A failed refresh is only fatal once the stored token has actually expired.
+func refreshIfExpiring(ctx context.Context, auths database.ConnectionAuthStore, auth *apitypes.PlatformConnectionAuth, platform *apitypes.Platform) (*apitypes.PlatformConnectionAuth, error) {
+	if !NeedsRefresh(auth, TokenExpiryLeeway) {
+		return auth, nil
+	}
+
+	refreshed, err := RefreshAuth(ctx, auths, auth, platform)
+	if err == nil {
+		return refreshed, nil
+	}
+
+	log.Printf("Token refresh failed for auth %s (%s): %v", auth.AuthID, platform.Slug, err)
+	if time.Now().Unix() < auth.ExpiresAt {
+		return auth, nil
+	}
+	return nil, &connectors.ConnectorError{
+		Code:       "TOKEN_EXPIRED",
+		Message:    fmt.Sprintf("%s access token expired and could not be refreshed: %v", platform.Name, err),
+		StatusCode: http.StatusUnauthorized,
+		Platform:   platform.Slug,
+	}
+}
+
+type tokenRefreshResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+func requestTokenRefresh(ctx context.Context, tokenURL, clientID, clientSecret, refreshToken string) (*tokenRefreshResponse, error) {
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", refreshToken)
+	data.Set("client_id", clientID)
+	data.Set("client_secret", clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := tokenHTTPClient.Do(req)
+	if err != nil {
+		return nil, &RefreshError{Message: fmt.Sprintf("token refresh request failed: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &RefreshError{StatusCode: resp.StatusCode, Message: "failed to read token refresh response"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// 400/401 from a token endpoint means invalid_grant or revoked client:
+		// the refresh token will never work again
+		permanent := resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized
+		return nil, &RefreshError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("token refresh failed: %s - %s", resp.Status, string(body)),
+			Permanent:  permanent,
+		}
+	}
+
+	var tokens tokenRefreshResponse
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse token refresh response: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return nil, &RefreshError{StatusCode: resp.StatusCode, Message: "token refresh response did not include an access token"}
+	}
+
+	return &tokens, nil
+}
+
+func loadAuth(ctx context.Context, auths database.ConnectionAuthStore, authID string) (*apitypes.PlatformConnectionAuth, error) {
+	auth, err := auths.GetByID(ctx, authID)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil {
+		return nil, fmt.Errorf("auth %s not found", authID)
+	}
+	return auth, nil
+}
@@ -0,0 +1,105 @@
+package loader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+func TestNeedsRefresh(t *testing.T) {
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name string
+		auth apitypes.PlatformConnectionAuth
+		want bool
+	}{
+		{"no refresh token", apitypes.PlatformConnectionAuth{ExpiresAt: now - 60}, false},
+		{"no expiry", apitypes.PlatformConnectionAuth{RefreshToken: "rt"}, false},
+		{"expired", apitypes.PlatformConnectionAuth{RefreshToken: "rt", ExpiresAt: now - 60}, true},
+		{"inside leeway", apitypes.PlatformConnectionAuth{RefreshToken: "rt", ExpiresAt: now + 60}, true},
+		{"still valid", apitypes.PlatformConnectionAuth{RefreshToken: "rt", ExpiresAt: now + 3600}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRefresh(&tt.auth, TokenExpiryLeeway); got != tt.want {
+				t.Errorf("NeedsRefresh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestTokenRefresh(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "refresh_token" {
+				t.Errorf("Expected grant_type refresh_token, got %s", r.Form.Get("grant_type"))
+			}
+			if r.Form.Get("refresh_token") != "old-refresh" {
+				t.Errorf("Expected refresh_token old-refresh, got %s", r.Form.Get("refresh_token"))
+			}
+			if r.Form.Get("client_id") != "client" || r.Form.Get("client_secret") != "secret" {
+				t.Errorf("Expected client credentials in form")
+			}
+			w.Write([]byte(`{"access_token": "new-access", "refresh_token": "new-refresh", "expires_in": 3600}`))
+		}))
+		defer server.Close()
+
+		tokens, err := requestTokenRefresh(context.Background(), server.URL, "client", "secret", "old-refresh")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if tokens.AccessToken != "new-access" || tokens.RefreshToken != "new-refresh" || tokens.ExpiresIn != 3600 {
+			t.Errorf("Unexpected tokens: %+v", tokens)
+		}
+	})
+
+	t.Run("invalid grant is permanent", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant"}`))
+		}))
+		defer server.Close()
+
+		_, err := requestTokenRefresh(context.Background(), server.URL, "client", "secret", "revoked")
+		if !IsPermanentRefreshError(err) {
+			t.Errorf("Expected permanent refresh error, got %v", err)
+		}
+	})
+
+	t.Run("server error is transient", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		_, err := requestTokenRefresh(context.Background(), server.URL, "client", "secret", "old-refresh")
+		if err == nil {
+			t.Fatal("Expected error for server failure")
+		}
+		if IsPermanentRefreshError(err) {
+			t.Errorf("Expected transient refresh error, got permanent")
+		}
+	})
+}
+
+func TestRefreshingTokenSource_StaleRejectedToken(t *testing.T) {
+	// The auth already holds a token newer than the one the caller sent, so
+	// the source hands it back without touching the store or token endpoint
+	auth := &apitypes.PlatformConnectionAuth{AuthID: "a1", AccessToken: "new-token", RefreshToken: "rt", Version: 2}
+	source := refreshingTokenSource(nil, auth, &apitypes.Platform{Slug: "keap"})
+
+	token, err := source(context.Background(), "old-token")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if token != "new-token" {
+		t.Errorf("Expected current token, got %q", token)
+	}
+}
@@ -69,3 +69,58 @@ func NewConnectorError(platform string, statusCode int, message string, retryabl
 		Retryable:  retryable,
 	}
 }
+
+// Invoice represents a billing invoice attached to a contact. Amounts are in
+// major currency units (dollars, not cents).
+type Invoice struct {
+	ID         string     `json:"id"`
+	ContactID  string     `json:"contact_id"`
+	Number     string     `json:"number,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	Currency   string     `json:"currency,omitempty"`
+	Total      float64    `json:"total"`
+	AmountPaid float64    `json:"amount_paid"`
+	AmountDue  float64    `json:"amount_due"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	DueAt      *time.Time `json:"due_at,omitempty"`
+	PaidAt     *time.Time `json:"paid_at,omitempty"`
+	SourceCRM  string     `json:"source_crm"`
+}
+
+// Order represents a purchase made by a contact
+type Order struct {
+	ID        string      `json:"id"`
+	ContactID string      `json:"contact_id"`
+	Title     string      `json:"title,omitempty"`
+	Status    string      `json:"status,omitempty"`
+	Currency  string      `json:"currency,omitempty"`
+	Total     float64     `json:"total"`
+	Items     []OrderItem `json:"items,omitempty"`
+	CreatedAt *time.Time  `json:"created_at,omitempty"`
+	SourceCRM string      `json:"source_crm"`
+}
+
+// OrderItem represents a line item on an order
+type OrderItem struct {
+	ProductID string  `json:"product_id,omitempty"`
+	Name      string  `json:"name"`
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+// Subscription represents a recurring billing subscription for a contact
+type Subscription struct {
+	ID            string     `json:"id"`
+	ContactID     string     `json:"contact_id"`
+	Name          string     `json:"name,omitempty"`
+	ProductID     string     `json:"product_id,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	Currency      string     `json:"currency,omitempty"`
+	Amount        float64    `json:"amount"`
+	Interval      string     `json:"interval,omitempty"`
+	IntervalCount int        `json:"interval_count,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	NextBillAt    *time.Time `json:"next_bill_at,omitempty"`
+	EndedAt       *time.Time `json:"ended_at,omitempty"`
+	SourceCRM     string     `json:"source_crm"`
+}
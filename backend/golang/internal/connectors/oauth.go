@@ -0,0 +1,58 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// isUnauthorized reports whether err is a connector error for a rejected access token
+func isUnauthorized(err error) bool {
+	var connErr *ConnectorError
+	return errors.As(err, &connErr) && connErr.StatusCode == http.StatusUnauthorized
+}
+
+// tokenRefreshError reports a 401 that could not be recovered by refreshing the token
+func tokenRefreshError(platform string, err error) *ConnectorError {
+	return &ConnectorError{
+		Code:       "TOKEN_REFRESH_FAILED",
+		Message:    fmt.Sprintf("access token rejected and refresh failed: %v", err),
+		StatusCode: http.StatusUnauthorized,
+		Platform:   platform,
+	}
+}
+
+// oauthToken holds a connector's access token. Requests on one connector run
+// concurrently, so the token is only read and replaced under the mutex.
+type oauthToken struct {
+	mu      sync.Mutex
+	access  string
+	refresh TokenRefresher
+}
+
+func (t *oauthToken) get() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.access
+}
+
+// refreshAfter returns the token to retry with after rejected was refused.
+// Only the first caller to report a token refreshes it; callers that sent the
+// same token wait for that refresh and reuse its result, so a rotating refresh
+// token is spent once.
+func (t *oauthToken) refreshAfter(ctx context.Context, rejected string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.access != rejected {
+		return t.access, nil
+	}
+	token, err := t.refresh(ctx, rejected)
+	if err != nil {
+		return "", err
+	}
+	t.access = token
+	return token, nil
+}
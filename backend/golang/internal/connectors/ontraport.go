@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -29,6 +28,7 @@ type OntraportConnector struct {
 	apiKey  string
 	baseURL string
 	client  *http.Client
+	limiter *rateLimiter
 }
 
 // NewOntraportConnector creates a new Ontraport CRM connector
@@ -50,24 +50,85 @@ func NewOntraportConnector(config ConnectorConfig) (CRMConnector, error) {
 		apiKey:  config.APIKey,
 		baseURL: strings.TrimRight(baseURL, "/"),
 		client:  &http.Client{Timeout: 30 * time.Second},
+		limiter: limiterFor(ontraportSlug, config),
 	}, nil
 }
 
 // ========== CONTACTS ==========
 
+// ontraportContactOrderFields maps the common OrderBy values onto Ontraport contact fields
+var ontraportContactOrderFields = map[string]string{
+	OrderByCreated:   "date",
+	OrderByUpdated:   "dlm",
+	OrderByEmail:     "email",
+	OrderByFirstName: "firstname",
+	OrderByLastName:  "lastname",
+}
+
 func (o *OntraportConnector) GetContacts(ctx context.Context, opts QueryOptions) (*ContactList, error) {
+	q, err := parseContactQuery(ontraportSlug, opts)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := offsetCursor(ontraportSlug, opts)
+	if err != nil {
+		return nil, err
+	}
+	order, err := q.orderField(ontraportSlug, ontraportContactOrderFields)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 25
+	}
+
 	params := url.Values{}
 	params.Set("objectID", ontraportContactObjectID)
-	if opts.Limit > 0 {
-		params.Set("range", fmt.Sprintf("%d", opts.Limit))
-	} else {
-		params.Set("range", "25")
+	params.Set("range", fmt.Sprintf("%d", limit))
+	if offset > 0 {
+		params.Set("start", fmt.Sprintf("%d", offset))
+	}
+	if order != "" {
+		params.Set("sort", order)
+		params.Set("sortDir", "asc")
+		if q.Descending {
+			params.Set("sortDir", "desc")
+		}
 	}
-	if opts.Offset > 0 {
-		params.Set("start", fmt.Sprintf("%d", opts.Offset))
+
+	// Every filter maps onto an Ontraport condition, joined with AND
+	var conditions []interface{}
+	addCondition := func(field, op string, value interface{}) {
+		if len(conditions) > 0 {
+			conditions = append(conditions, "AND")
+		}
+		conditions = append(conditions, map[string]interface{}{
+			"field": map[string]string{"field": field},
+			"op":    op,
+			"value": map[string]interface{}{"value": value},
+		})
+	}
+	if q.Email != "" {
+		addCondition("email", "=", q.Email)
 	}
-	if opts.Email != "" {
-		params.Set("condition", fmt.Sprintf(`[{"field":{"field":"email"},"op":"=","value":{"value":"%s"}}]`, opts.Email))
+	if q.TagID != "" {
+		// contact_cat holds the contact's tag IDs as "*/*1*/*2*/*"
+		addCondition("contact_cat", "LIKE", "%*/*"+q.TagID+"*/*%")
+	}
+	if q.UpdatedSince != nil {
+		addCondition("dlm", ">=", q.UpdatedSince.Unix())
+	}
+	for key, value := range q.Fields {
+		addCondition(key, "=", value)
+	}
+	if len(conditions) > 0 {
+		condition, err := json.Marshal(conditions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode contact conditions: %w", err)
+		}
+		params.Set("condition", string(condition))
 	}
 
 	var result struct {
@@ -84,11 +145,15 @@ func (o *OntraportConnector) GetContacts(ctx context.Context, opts QueryOptions)
 		contacts = append(contacts, oc.toNormalized())
 	}
 
-	return &ContactList{
+	cl := &ContactList{
 		Contacts: contacts,
 		Total:    len(result.Data),
-		HasMore:  len(result.Data) == opts.Limit || (opts.Limit == 0 && len(result.Data) == 25),
-	}, nil
+		HasMore:  len(result.Data) == limit,
+	}
+	if cl.HasMore {
+		cl.NextCursor = fmt.Sprintf("%d", offset+len(result.Data))
+	}
+	return cl, nil
 }
 
 func (o *OntraportConnector) GetContact(ctx context.Context, contactID string) (*NormalizedContact, error) {
@@ -256,8 +321,7 @@ func (o *OntraportConnector) GetCustomFields(ctx context.Context) ([]CustomField
 
 	fields := make([]CustomField, 0)
 	for key, f := range result.Data {
-		// Only include custom fields (f_ prefix)
-		if strings.HasPrefix(key, "f") {
+		if isOntraportCustomField(key) {
 			fields = append(fields, CustomField{
 				ID:        key,
 				Key:       key,
@@ -407,7 +471,45 @@ type ontraportContact struct {
 	Phone     string `json:"office_phone"`
 	Company   string `json:"company"`
 	DateAdded string `json:"date"`
-	LastActivity string `json:"dla"`
+	LastModified string `json:"dlm"`
+	// ContactCat holds the contact's tag IDs as "*/*1*/*2*/*"
+	ContactCat string `json:"contact_cat"`
+
+	// CustomFields collects the f<number> fields, which vary per account
+	CustomFields map[string]interface{} `json:"-"`
+}
+
+func (oc *ontraportContact) UnmarshalJSON(data []byte) error {
+	type plain ontraportContact
+	if err := json.Unmarshal(data, (*plain)(oc)); err != nil {
+		return err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	oc.CustomFields = make(map[string]interface{})
+	for key, value := range fields {
+		if isOntraportCustomField(key) {
+			oc.CustomFields[key] = value
+		}
+	}
+	return nil
+}
+
+// isOntraportCustomField reports whether a contact field key is an
+// account-defined field such as f1234
+func isOntraportCustomField(key string) bool {
+	if len(key) < 2 || key[0] != 'f' {
+		return false
+	}
+	for _, r := range key[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func (oc *ontraportContact) toNormalized() NormalizedContact {
@@ -431,62 +533,36 @@ func (oc *ontraportContact) toNormalized() NormalizedContact {
 			contact.CreatedAt = &t
 		}
 	}
-	if oc.LastActivity != "" {
+	if oc.LastModified != "" {
 		var ts int64
-		if _, err := fmt.Sscanf(oc.LastActivity, "%d", &ts); err == nil && ts > 0 {
+		if _, err := fmt.Sscanf(oc.LastModified, "%d", &ts); err == nil && ts > 0 {
 			t := time.Unix(ts, 0).UTC()
 			contact.UpdatedAt = &t
 		}
 	}
 
+	for key, value := range oc.CustomFields {
+		contact.CustomFields[key] = value
+	}
+	for _, tagID := range strings.Split(oc.ContactCat, "*/*") {
+		if tagID != "" {
+			contact.Tags = append(contact.Tags, TagRef{ID: tagID})
+		}
+	}
+
 	return contact
 }
 
 // ========== HTTP HELPER ==========
 
 func (o *OntraportConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	var bodyReader io.Reader
-	if body != nil {
-		bodyJSON, err := json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = strings.NewReader(string(bodyJSON))
-	}
-
-	apiURL := o.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, apiURL, bodyReader)
+	req, err := newJSONRequest(method, o.baseURL+path, body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
-
 	req.Header.Set("Api-Appid", o.appID)
 	req.Header.Set("Api-Key", o.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := o.client.Do(req)
-	if err != nil {
-		return NewConnectorError(ontraportSlug, 0, fmt.Sprintf("request failed: %v", err), true)
-	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return NewConnectorError(ontraportSlug, resp.StatusCode, "failed to read response", true)
-	}
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		retryable := resp.StatusCode == 429 || resp.StatusCode >= 500
-		return NewConnectorError(ontraportSlug, resp.StatusCode,
-			fmt.Sprintf("Ontraport API error (%d): %s", resp.StatusCode, string(respBody)), retryable)
-	}
-
-	if result != nil && len(respBody) > 0 {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to parse response: %w", err)
-		}
-	}
-
-	return nil
+	t := transport{platform: ontraportSlug, name: "Ontraport", client: o.client, limiter: o.limiter}
+	return t.do(ctx, req, result)
 }
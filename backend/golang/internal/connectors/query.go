@@ -0,0 +1,307 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter keys understood by every connector's GetContacts. Connectors push each
+// filter down to the platform's native query where they can and apply the rest
+// to the fetched page.
+const (
+	// FilterEmail matches the contact's primary email, case-insensitively
+	FilterEmail = "email"
+	// FilterTag matches contacts that carry the tag ID
+	FilterTag = "tag"
+	// FilterUpdatedSince matches contacts updated at or after an RFC3339 timestamp
+	FilterUpdatedSince = "updated_since"
+	// FilterFieldPrefix prefixes a custom field key for an equality match,
+	// e.g. "field.lead_source"
+	FilterFieldPrefix = "field."
+)
+
+// OrderBy values. Prefix with "-" for descending order, e.g. "-updated_at".
+const (
+	OrderByCreated   = "created_at"
+	OrderByUpdated   = "updated_at"
+	OrderByEmail     = "email"
+	OrderByFirstName = "first_name"
+	OrderByLastName  = "last_name"
+)
+
+// ErrStopIteration can be returned from an IterateContacts callback to end the
+// iteration early without an error.
+var ErrStopIteration = errors.New("stop iteration")
+
+// IterateContacts pages through every contact matching opts and calls fn for
+// each one in order. opts.Limit sets the page size.
+func IterateContacts(ctx context.Context, conn CRMConnector, opts QueryOptions, fn func(NormalizedContact) error) error {
+	seen := make(map[string]bool)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page, err := conn.GetContacts(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, contact := range page.Contacts {
+			if err := fn(contact); err != nil {
+				if errors.Is(err, ErrStopIteration) {
+					return nil
+				}
+				return err
+			}
+		}
+
+		if !page.HasMore || page.NextCursor == "" {
+			return nil
+		}
+		// A connector that hands back a cursor it already returned would loop forever
+		if seen[page.NextCursor] || page.NextCursor == opts.Cursor {
+			return fmt.Errorf("connector %s returned repeated cursor %q", conn.GetMetadata().PlatformSlug, page.NextCursor)
+		}
+		seen[page.NextCursor] = true
+
+		opts.Cursor = page.NextCursor
+		opts.Offset = 0
+	}
+}
+
+// contactQuery is the parsed, validated form of a QueryOptions filter set
+type contactQuery struct {
+	Email        string
+	TagID        string
+	UpdatedSince *time.Time
+	Fields       map[string]string
+	OrderBy      string
+	Descending   bool
+}
+
+// parseContactQuery merges the Email and TagID shortcuts with Filters and
+// validates every key against the common vocabulary.
+func parseContactQuery(platform string, opts QueryOptions) (*contactQuery, error) {
+	q := &contactQuery{
+		Email:  opts.Email,
+		TagID:  opts.TagID,
+		Fields: make(map[string]string),
+	}
+
+	for key, value := range opts.Filters {
+		switch {
+		case key == FilterEmail:
+			q.Email = value
+		case key == FilterTag:
+			q.TagID = value
+		case key == FilterUpdatedSince:
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, invalidQuery(platform, "filter %s must be an RFC3339 timestamp, got %q", key, value)
+			}
+			q.UpdatedSince = &t
+		case strings.HasPrefix(key, FilterFieldPrefix) && len(key) > len(FilterFieldPrefix):
+			q.Fields[strings.TrimPrefix(key, FilterFieldPrefix)] = value
+		default:
+			return nil, invalidQuery(platform, "unknown filter %q", key)
+		}
+	}
+
+	if opts.OrderBy != "" {
+		q.OrderBy = strings.TrimPrefix(opts.OrderBy, "-")
+		q.Descending = strings.HasPrefix(opts.OrderBy, "-")
+		switch q.OrderBy {
+		case OrderByCreated, OrderByUpdated, OrderByEmail, OrderByFirstName, OrderByLastName:
+		default:
+			return nil, invalidQuery(platform, "unknown order_by %q", opts.OrderBy)
+		}
+	}
+
+	return q, nil
+}
+
+// orderField maps OrderBy onto the platform's native sort field. It fails when
+// the platform cannot sort by the requested field.
+func (q *contactQuery) orderField(platform string, fields map[string]string) (string, error) {
+	if q.OrderBy == "" {
+		return "", nil
+	}
+	field, ok := fields[q.OrderBy]
+	if !ok {
+		return "", invalidQuery(platform, "%s does not support ordering by %s", platform, q.OrderBy)
+	}
+	return field, nil
+}
+
+// filter drops contacts that fail any filter the platform could not apply
+// itself. pushed lists the filter keys already applied server-side;
+// FilterFieldPrefix covers every custom field filter.
+func (q *contactQuery) filter(contacts []NormalizedContact, pushed ...string) []NormalizedContact {
+	skip := make(map[string]bool, len(pushed))
+	for _, key := range pushed {
+		skip[key] = true
+	}
+
+	matched := contacts[:0]
+	for _, contact := range contacts {
+		if q.matches(contact, skip) {
+			matched = append(matched, contact)
+		}
+	}
+	return matched
+}
+
+// clientSide reports whether any filter is left after the pushed ones
+func (q *contactQuery) clientSide(pushed ...string) bool {
+	skip := make(map[string]bool, len(pushed))
+	for _, key := range pushed {
+		skip[key] = true
+	}
+	return (q.Email != "" && !skip[FilterEmail]) ||
+		(q.TagID != "" && !skip[FilterTag]) ||
+		(q.UpdatedSince != nil && !skip[FilterUpdatedSince]) ||
+		(len(q.Fields) > 0 && !skip[FilterFieldPrefix])
+}
+
+func (q *contactQuery) matches(contact NormalizedContact, skip map[string]bool) bool {
+	if q.Email != "" && !skip[FilterEmail] && !strings.EqualFold(contact.Email, q.Email) {
+		return false
+	}
+
+	if q.TagID != "" && !skip[FilterTag] {
+		found := false
+		for _, tag := range contact.Tags {
+			if tag.ID == q.TagID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if q.UpdatedSince != nil && !skip[FilterUpdatedSince] {
+		if contact.UpdatedAt == nil || contact.UpdatedAt.Before(*q.UpdatedSince) {
+			return false
+		}
+	}
+
+	if !skip[FilterFieldPrefix] {
+		for key, want := range q.Fields {
+			value, ok := contact.CustomFields[key]
+			if !ok || value == nil {
+				if want != "" {
+					return false
+				}
+				continue
+			}
+			if fmt.Sprintf("%v", value) != want {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// QueryContacts applies opts to a complete in-process contact set: it filters,
+// sorts and returns the requested page, with decimal offset cursors. It is for
+// connectors that hold every contact locally rather than querying a platform.
+func QueryContacts(platform string, contacts []NormalizedContact, opts QueryOptions) (*ContactList, error) {
+	q, err := parseContactQuery(platform, opts)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := offsetCursor(platform, opts)
+	if err != nil {
+		return nil, err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 25
+	}
+
+	matched := q.filter(append([]NormalizedContact(nil), contacts...))
+	if q.OrderBy != "" {
+		sort.SliceStable(matched, func(i, j int) bool {
+			if q.Descending {
+				return contactLess(q.OrderBy, matched[j], matched[i])
+			}
+			return contactLess(q.OrderBy, matched[i], matched[j])
+		})
+	}
+
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	end := offset + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	list := &ContactList{
+		Contacts: matched[offset:end],
+		Total:    len(matched),
+	}
+	if end < len(matched) {
+		list.HasMore = true
+		list.NextCursor = strconv.Itoa(end)
+	}
+	return list, nil
+}
+
+// contactLess orders two contacts by one of the OrderBy fields. Contacts
+// without a timestamp sort first.
+func contactLess(orderBy string, a, b NormalizedContact) bool {
+	switch orderBy {
+	case OrderByCreated:
+		return timeLess(a.CreatedAt, b.CreatedAt)
+	case OrderByUpdated:
+		return timeLess(a.UpdatedAt, b.UpdatedAt)
+	case OrderByEmail:
+		return strings.ToLower(a.Email) < strings.ToLower(b.Email)
+	case OrderByFirstName:
+		return strings.ToLower(a.FirstName) < strings.ToLower(b.FirstName)
+	case OrderByLastName:
+		return strings.ToLower(a.LastName) < strings.ToLower(b.LastName)
+	}
+	return false
+}
+
+func timeLess(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	return a.Before(*b)
+}
+
+// offsetCursor returns the starting offset for offset-paginated platforms.
+// Their cursors are the decimal offset of the next page.
+func offsetCursor(platform string, opts QueryOptions) (int, error) {
+	if opts.Cursor == "" {
+		return opts.Offset, nil
+	}
+	offset, err := strconv.Atoi(opts.Cursor)
+	if err != nil || offset < 0 {
+		return 0, invalidQuery(platform, "invalid cursor %q", opts.Cursor)
+	}
+	return offset, nil
+}
+
+func invalidQuery(platform, format string, args ...interface{}) *ConnectorError {
+	return &ConnectorError{
+		Code:       "INVALID_QUERY",
+		Message:    fmt.Sprintf(format, args...),
+		StatusCode: http.StatusBadRequest,
+		Platform:   platform,
+	}
+}
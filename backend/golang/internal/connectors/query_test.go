@@ -0,0 +1,139 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestIterateContacts tests paging through a Keap contact list with offset cursors
+func TestIterateContacts(t *testing.T) {
+	const total = 5
+
+	newServer := func(offsets *[]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*offsets = append(*offsets, r.URL.Query().Get("offset"))
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+			contacts := ""
+			for id := offset + 1; id <= total && id <= offset+limit; id++ {
+				if contacts != "" {
+					contacts += ","
+				}
+				contacts += fmt.Sprintf(`{"id": %d, "given_name": "Contact %d"}`, id, id)
+			}
+			// Keap always sends a next link, even past the last page
+			fmt.Fprintf(w, `{"contacts": [%s], "count": %d, "next": "https://api.infusionsoft.com/crm/rest/v2/contacts?offset=%d"}`, contacts, total, offset+limit)
+		}))
+	}
+
+	t.Run("visits every contact once", func(t *testing.T) {
+		var offsets []string
+		server := newServer(&offsets)
+		defer server.Close()
+
+		connector := &KeapConnector{token: &oauthToken{access: "test-token"}, baseURL: server.URL, client: server.Client()}
+
+		var ids []string
+		err := IterateContacts(context.Background(), connector, QueryOptions{Limit: 2}, func(c NormalizedContact) error {
+			ids = append(ids, c.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if fmt.Sprint(ids) != "[1 2 3 4 5]" {
+			t.Errorf("Expected contacts 1-5 in order, got %v", ids)
+		}
+		if fmt.Sprint(offsets) != "[ 2 4 5]" {
+			t.Errorf("Expected offsets advanced by page size, got %q", offsets)
+		}
+	})
+
+	t.Run("stops early on ErrStopIteration", func(t *testing.T) {
+		var offsets []string
+		server := newServer(&offsets)
+		defer server.Close()
+
+		connector := &KeapConnector{token: &oauthToken{access: "test-token"}, baseURL: server.URL, client: server.Client()}
+
+		count := 0
+		err := IterateContacts(context.Background(), connector, QueryOptions{Limit: 2}, func(c NormalizedContact) error {
+			count++
+			if count == 3 {
+				return ErrStopIteration
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if count != 3 || len(offsets) != 2 {
+			t.Errorf("Expected 3 contacts from 2 pages, got %d from %d", count, len(offsets))
+		}
+	})
+}
+
+// TestParseContactQuery tests the common filter vocabulary
+func TestParseContactQuery(t *testing.T) {
+	t.Run("merges shortcuts and filters", func(t *testing.T) {
+		q, err := parseContactQuery("test", QueryOptions{
+			TagID:   "42",
+			OrderBy: "-updated_at",
+			Filters: map[string]string{
+				FilterEmail:         "jane@example.com",
+				FilterUpdatedSince:  "2024-01-01T00:00:00Z",
+				"field.lead_source": "webinar",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if q.Email != "jane@example.com" || q.TagID != "42" || q.Fields["lead_source"] != "webinar" {
+			t.Errorf("Unexpected query: %+v", q)
+		}
+		if q.UpdatedSince == nil || !q.UpdatedSince.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected updated_since 2024-01-01, got %v", q.UpdatedSince)
+		}
+		if q.OrderBy != OrderByUpdated || !q.Descending {
+			t.Errorf("Expected descending updated_at order, got %s desc=%v", q.OrderBy, q.Descending)
+		}
+	})
+
+	invalid := []QueryOptions{
+		{Filters: map[string]string{"unknown": "x"}},
+		{Filters: map[string]string{FilterUpdatedSince: "yesterday"}},
+		{OrderBy: "score"},
+	}
+	for _, opts := range invalid {
+		_, err := parseContactQuery("test", opts)
+		connErr, ok := err.(*ConnectorError)
+		if !ok || connErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 ConnectorError for %+v, got %v", opts, err)
+		}
+	}
+
+	t.Run("filters client-side", func(t *testing.T) {
+		updated := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		contacts := []NormalizedContact{
+			{ID: "1", Email: "JANE@example.com", UpdatedAt: &updated, CustomFields: map[string]interface{}{"lead_source": "webinar"}},
+			{ID: "2", Email: "jane@example.com", CustomFields: map[string]interface{}{"lead_source": "ads"}},
+		}
+
+		q, _ := parseContactQuery("test", QueryOptions{Filters: map[string]string{
+			FilterEmail:         "jane@example.com",
+			FilterUpdatedSince:  "2024-01-01T00:00:00Z",
+			"field.lead_source": "webinar",
+		}})
+
+		matched := q.filter(contacts)
+		if len(matched) != 1 || matched[0].ID != "1" {
+			t.Errorf("Expected only contact 1 to match, got %+v", matched)
+		}
+	})
+}
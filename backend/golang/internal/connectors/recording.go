@@ -0,0 +1,236 @@
+package connectors
+
+import (
+	"context"
+	"sync"
+)
+
+// Operations recorded by RecordingConnector
+const (
+	ChangeCreateContact     = "create_contact"
+	ChangeUpdateContact     = "update_contact"
+	ChangeDeleteContact     = "delete_contact"
+	ChangeSetField          = "set_field"
+	ChangeApplyTag          = "apply_tag"
+	ChangeRemoveTag         = "remove_tag"
+	ChangeTriggerAutomation = "trigger_automation"
+	ChangeAchieveGoal       = "achieve_goal"
+	ChangeSetOptIn          = "set_opt_in"
+	ChangeCreateNote        = "create_note"
+)
+
+// RecordedChange is one write a helper attempted during a dry run. Target is
+// the field key, tag, automation or goal the write applies to. Before is read
+// from the CRM when the connector can report it.
+type RecordedChange struct {
+	Operation string      `json:"operation"`
+	ContactID string      `json:"contact_id,omitempty"`
+	Target    string      `json:"target,omitempty"`
+	Before    interface{} `json:"before,omitempty"`
+	After     interface{} `json:"after,omitempty"`
+}
+
+// RecordingConnector wraps a CRMConnector for dry runs. Reads go to the real
+// CRM; writes are recorded and never sent, so reads do not reflect them.
+type RecordingConnector struct {
+	inner CRMConnector
+
+	mu      sync.Mutex
+	changes []RecordedChange
+}
+
+// NewRecordingConnector wraps inner so its writes are captured instead of performed
+func NewRecordingConnector(inner CRMConnector) *RecordingConnector {
+	return &RecordingConnector{inner: inner}
+}
+
+// Changes returns the writes recorded so far, in call order
+func (r *RecordingConnector) Changes() []RecordedChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changes := make([]RecordedChange, len(r.changes))
+	copy(changes, r.changes)
+	return changes
+}
+
+func (r *RecordingConnector) record(change RecordedChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, change)
+}
+
+// ========== READS ==========
+
+func (r *RecordingConnector) GetContacts(ctx context.Context, opts QueryOptions) (*ContactList, error) {
+	return r.inner.GetContacts(ctx, opts)
+}
+
+func (r *RecordingConnector) GetContact(ctx context.Context, contactID string) (*NormalizedContact, error) {
+	return r.inner.GetContact(ctx, contactID)
+}
+
+func (r *RecordingConnector) GetTags(ctx context.Context) ([]Tag, error) {
+	return r.inner.GetTags(ctx)
+}
+
+func (r *RecordingConnector) GetCustomFields(ctx context.Context) ([]CustomField, error) {
+	return r.inner.GetCustomFields(ctx)
+}
+
+func (r *RecordingConnector) GetContactFieldValue(ctx context.Context, contactID string, fieldKey string) (interface{}, error) {
+	return r.inner.GetContactFieldValue(ctx, contactID, fieldKey)
+}
+
+func (r *RecordingConnector) TestConnection(ctx context.Context) error {
+	return r.inner.TestConnection(ctx)
+}
+
+func (r *RecordingConnector) GetMetadata() ConnectorMetadata {
+	return r.inner.GetMetadata()
+}
+
+func (r *RecordingConnector) GetCapabilities() []Capability {
+	return r.inner.GetCapabilities()
+}
+
+func (r *RecordingConnector) ListInvoices(ctx context.Context, contactID string) ([]Invoice, error) {
+	rc, err := r.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	return rc.ListInvoices(ctx, contactID)
+}
+
+func (r *RecordingConnector) ListOrders(ctx context.Context, contactID string) ([]Order, error) {
+	rc, err := r.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	return rc.ListOrders(ctx, contactID)
+}
+
+func (r *RecordingConnector) ListSubscriptions(ctx context.Context, contactID string) ([]Subscription, error) {
+	rc, err := r.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	return rc.ListSubscriptions(ctx, contactID)
+}
+
+func (r *RecordingConnector) relatedRecordsConnector() (RelatedRecordsConnector, error) {
+	rc, ok := r.inner.(RelatedRecordsConnector)
+	if !ok {
+		slug := r.inner.GetMetadata().PlatformSlug
+		return nil, NewConnectorError(slug, 501, slug+" does not support related records", false)
+	}
+	return rc, nil
+}
+
+// ========== WRITES ==========
+
+// CreateContact records the contact and returns it with a placeholder ID
+func (r *RecordingConnector) CreateContact(ctx context.Context, contact CreateContactInput) (*NormalizedContact, error) {
+	r.record(RecordedChange{Operation: ChangeCreateContact, After: contact})
+
+	created := &NormalizedContact{
+		ID:           "dry-run",
+		FirstName:    contact.FirstName,
+		LastName:     contact.LastName,
+		Email:        contact.Email,
+		Phone:        contact.Phone,
+		Company:      contact.Company,
+		CustomFields: contact.CustomFields,
+		SourceCRM:    r.inner.GetMetadata().PlatformSlug,
+	}
+	return created, nil
+}
+
+// UpdateContact records one change per updated field and returns the current
+// contact with the updates applied
+func (r *RecordingConnector) UpdateContact(ctx context.Context, contactID string, updates UpdateContactInput) (*NormalizedContact, error) {
+	current, err := r.inner.GetContact(ctx, contactID)
+	if err != nil {
+		current = &NormalizedContact{ID: contactID}
+	}
+	updated := *current
+
+	standard := []struct {
+		key    string
+		value  *string
+		target *string
+	}{
+		{"first_name", updates.FirstName, &updated.FirstName},
+		{"last_name", updates.LastName, &updated.LastName},
+		{"email", updates.Email, &updated.Email},
+		{"phone", updates.Phone, &updated.Phone},
+		{"company", updates.Company, &updated.Company},
+	}
+	for _, field := range standard {
+		if field.value == nil {
+			continue
+		}
+		r.record(RecordedChange{Operation: ChangeUpdateContact, ContactID: contactID, Target: field.key, Before: *field.target, After: *field.value})
+		*field.target = *field.value
+	}
+
+	if len(updates.CustomFields) > 0 {
+		customFields := make(map[string]interface{}, len(current.CustomFields)+len(updates.CustomFields))
+		for key, value := range current.CustomFields {
+			customFields[key] = value
+		}
+		for key, value := range updates.CustomFields {
+			r.record(RecordedChange{Operation: ChangeUpdateContact, ContactID: contactID, Target: key, Before: current.CustomFields[key], After: value})
+			customFields[key] = value
+		}
+		updated.CustomFields = customFields
+	}
+
+	return &updated, nil
+}
+
+func (r *RecordingConnector) DeleteContact(ctx context.Context, contactID string) error {
+	r.record(RecordedChange{Operation: ChangeDeleteContact, ContactID: contactID})
+	return nil
+}
+
+func (r *RecordingConnector) ApplyTag(ctx context.Context, contactID string, tagID string) error {
+	r.record(RecordedChange{Operation: ChangeApplyTag, ContactID: contactID, Target: tagID})
+	return nil
+}
+
+func (r *RecordingConnector) RemoveTag(ctx context.Context, contactID string, tagID string) error {
+	r.record(RecordedChange{Operation: ChangeRemoveTag, ContactID: contactID, Target: tagID})
+	return nil
+}
+
+func (r *RecordingConnector) SetContactFieldValue(ctx context.Context, contactID string, fieldKey string, value interface{}) error {
+	// The current value is best-effort; a failed read still records the write
+	before, _ := r.inner.GetContactFieldValue(ctx, contactID, fieldKey)
+	r.record(RecordedChange{Operation: ChangeSetField, ContactID: contactID, Target: fieldKey, Before: before, After: value})
+	return nil
+}
+
+func (r *RecordingConnector) TriggerAutomation(ctx context.Context, contactID string, automationID string) error {
+	r.record(RecordedChange{Operation: ChangeTriggerAutomation, ContactID: contactID, Target: automationID})
+	return nil
+}
+
+func (r *RecordingConnector) AchieveGoal(ctx context.Context, contactID string, goalName string, integration string) error {
+	r.record(RecordedChange{Operation: ChangeAchieveGoal, ContactID: contactID, Target: goalName, After: integration})
+	return nil
+}
+
+func (r *RecordingConnector) SetOptInStatus(ctx context.Context, contactID string, optIn bool, reason string) error {
+	r.record(RecordedChange{Operation: ChangeSetOptIn, ContactID: contactID, Target: reason, After: optIn})
+	return nil
+}
+
+// CreateNote records the note when the wrapped connector supports notes
+func (r *RecordingConnector) CreateNote(ctx context.Context, contactID string, note NoteInput) error {
+	if _, ok := r.inner.(NoteConnector); !ok {
+		slug := r.inner.GetMetadata().PlatformSlug
+		return NewConnectorError(slug, 501, slug+" does not support contact notes", false)
+	}
+	r.record(RecordedChange{Operation: ChangeCreateNote, ContactID: contactID, Target: note.Title, After: note})
+	return nil
+}
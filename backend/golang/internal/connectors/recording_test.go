@@ -0,0 +1,75 @@
+package connectors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRecordingConnector tests that reads reach the CRM and writes are only recorded
+func TestRecordingConnector(t *testing.T) {
+	writes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes++
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": 123,
+			"given_name": "John",
+			"email_addresses": [{"email": "john@example.com", "field": "EMAIL1"}]
+		}`))
+	}))
+	defer server.Close()
+
+	keap := &KeapConnector{token: &oauthToken{access: "test-token"}, baseURL: server.URL, client: server.Client()}
+	recorder := NewRecordingConnector(keap)
+	ctx := context.Background()
+
+	contact, err := recorder.GetContact(ctx, "123")
+	if err != nil || contact.FirstName != "John" {
+		t.Fatalf("Expected read from CRM, got %+v (%v)", contact, err)
+	}
+
+	firstName := "Jane"
+	updated, err := recorder.UpdateContact(ctx, "123", UpdateContactInput{FirstName: &firstName})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updated.FirstName != "Jane" || updated.Email != "john@example.com" {
+		t.Errorf("Expected current contact with update applied, got %+v", updated)
+	}
+
+	if err := recorder.SetContactFieldValue(ctx, "123", "email", "jane@example.com"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	recorder.ApplyTag(ctx, "123", "42")
+	recorder.RemoveTag(ctx, "123", "7")
+	recorder.AchieveGoal(ctx, "123", "purchased", "myfusionhelper")
+	recorder.TriggerAutomation(ctx, "123", "99")
+
+	if writes != 0 {
+		t.Errorf("Expected no writes sent to the CRM, got %d", writes)
+	}
+
+	changes := recorder.Changes()
+	expected := []RecordedChange{
+		{Operation: ChangeUpdateContact, ContactID: "123", Target: "first_name", Before: "John", After: "Jane"},
+		{Operation: ChangeSetField, ContactID: "123", Target: "email", Before: "john@example.com", After: "jane@example.com"},
+		{Operation: ChangeApplyTag, ContactID: "123", Target: "42"},
+		{Operation: ChangeRemoveTag, ContactID: "123", Target: "7"},
+		{Operation: ChangeAchieveGoal, ContactID: "123", Target: "purchased", After: "myfusionhelper"},
+		{Operation: ChangeTriggerAutomation, ContactID: "123", Target: "99"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d: %+v", len(expected), len(changes), changes)
+	}
+	for i, want := range expected {
+		if changes[i] != want {
+			t.Errorf("Change %d: expected %+v, got %+v", i, want, changes[i])
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package connectors
+
+import (
+	"sort"
+	"time"
+)
+
+// sortByCreated orders related records oldest first. Records without a
+// creation time sort before dated ones so "last" always means most recent.
+func sortByCreated[T any](records []T, createdAt func(T) *time.Time) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := createdAt(records[i]), createdAt(records[j])
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+}
@@ -0,0 +1,493 @@
+// Package sandbox implements a stateful fake CRM under the "sandbox" platform
+// slug. Contacts, tags, custom fields, automations and opt-in status live in a
+// Store, seeded from a JSON fixture the first time a sandbox is used, and goal
+// achievements and automation triggers are recorded as events. It backs demo
+// connections for accounts without a real CRM and lets tests exercise helpers
+// against realistic CRM behavior.
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+const (
+	sandboxSlug = "sandbox"
+
+	// maxSaveAttempts bounds retries when concurrent writers conflict
+	maxSaveAttempts = 5
+)
+
+func init() {
+	connectors.Register(sandboxSlug, NewSandboxConnector)
+}
+
+var (
+	defaultStoreOnce sync.Once
+	defaultStore     Store
+)
+
+// SetDefaultStore replaces the store used by connectors created through the
+// registry. Call it before the first sandbox connector is created.
+func SetDefaultStore(store Store) {
+	defaultStoreOnce.Do(func() {})
+	defaultStore = store
+}
+
+// getDefaultStore uses the SANDBOX_TABLE DynamoDB table when it is set and
+// process memory otherwise
+func getDefaultStore() Store {
+	defaultStoreOnce.Do(func() {
+		defaultStore = NewMemoryStore()
+		table := os.Getenv("SANDBOX_TABLE")
+		if table == "" {
+			return
+		}
+		cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(os.Getenv("COGNITO_REGION")))
+		if err != nil {
+			log.Printf("Sandbox store falling back to memory: %v", err)
+			return
+		}
+		defaultStore = NewDynamoStore(dynamodb.NewFromConfig(cfg), table)
+	})
+	return defaultStore
+}
+
+// SandboxConnector implements CRMConnector against a sandbox State
+type SandboxConnector struct {
+	store     Store
+	sandboxID string
+	fixture   string
+	now       func() time.Time
+}
+
+// NewSandboxConnector creates a sandbox connector for a connection. Each
+// connection gets its own sandbox; the connection's API key names the fixture
+// it is seeded from.
+func NewSandboxConnector(config connectors.ConnectorConfig) (connectors.CRMConnector, error) {
+	sandboxID := config.ConnectionID
+	if sandboxID == "" {
+		sandboxID = "default"
+	}
+	return New(getDefaultStore(), sandboxID, config.APIKey)
+}
+
+// New creates a sandbox connector over an explicit store. An empty fixture
+// name uses DefaultFixture.
+func New(store Store, sandboxID, fixture string) (*SandboxConnector, error) {
+	fixture = strings.TrimSpace(fixture)
+	if fixture == "" {
+		fixture = DefaultFixture
+	}
+	if _, err := LoadFixture(fixture); err != nil {
+		return nil, err
+	}
+	return &SandboxConnector{
+		store:     store,
+		sandboxID: sandboxID,
+		fixture:   fixture,
+		now:       func() time.Time { return time.Now().UTC() },
+	}, nil
+}
+
+// Seed replaces the sandbox contents with state, e.g. a fixture parsed with
+// ParseFixture.
+func (s *SandboxConnector) Seed(ctx context.Context, state *State) error {
+	return s.update(ctx, func(current *State) error {
+		version := current.Version
+		*current = *state
+		current.Version = version
+		return nil
+	})
+}
+
+// Snapshot returns a copy of the sandbox contents
+func (s *SandboxConnector) Snapshot(ctx context.Context) (*State, error) {
+	return s.load(ctx)
+}
+
+// Events returns the recorded goal and automation events, oldest first
+func (s *SandboxConnector) Events(ctx context.Context) ([]Event, error) {
+	state, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return state.Events, nil
+}
+
+// ========== CONTACTS ==========
+
+func (s *SandboxConnector) GetContacts(ctx context.Context, opts connectors.QueryOptions) (*connectors.ContactList, error) {
+	state, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contacts := make([]connectors.NormalizedContact, 0, len(state.Contacts))
+	for i := range state.Contacts {
+		contacts = append(contacts, state.normalized(&state.Contacts[i]))
+	}
+	return connectors.QueryContacts(sandboxSlug, contacts, opts)
+}
+
+func (s *SandboxConnector) GetContact(ctx context.Context, contactID string) (*connectors.NormalizedContact, error) {
+	state, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := state.contact(contactID)
+	if c == nil {
+		return nil, notFound("contact", contactID)
+	}
+	contact := state.normalized(c)
+	return &contact, nil
+}
+
+func (s *SandboxConnector) CreateContact(ctx context.Context, input connectors.CreateContactInput) (*connectors.NormalizedContact, error) {
+	var created connectors.NormalizedContact
+	err := s.update(ctx, func(state *State) error {
+		for _, tagID := range input.Tags {
+			if state.tag(tagID) == nil {
+				return notFound("tag", tagID)
+			}
+		}
+		for key := range input.CustomFields {
+			if state.customField(key) == nil {
+				return unknownField(key)
+			}
+		}
+
+		now := s.now()
+		contact := Contact{
+			ID:           state.newID(),
+			FirstName:    input.FirstName,
+			LastName:     input.LastName,
+			Email:        input.Email,
+			Phone:        input.Phone,
+			Company:      input.Company,
+			TagIDs:       append([]string(nil), input.Tags...),
+			CustomFields: make(map[string]interface{}, len(input.CustomFields)),
+			CreatedAt:    &now,
+			UpdatedAt:    &now,
+		}
+		for key, value := range input.CustomFields {
+			contact.CustomFields[key] = value
+		}
+		state.Contacts = append(state.Contacts, contact)
+		created = state.normalized(&contact)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *SandboxConnector) UpdateContact(ctx context.Context, contactID string, updates connectors.UpdateContactInput) (*connectors.NormalizedContact, error) {
+	var updated connectors.NormalizedContact
+	err := s.updateContact(ctx, contactID, func(state *State, c *Contact) error {
+		for key := range updates.CustomFields {
+			if state.customField(key) == nil {
+				return unknownField(key)
+			}
+		}
+		if updates.FirstName != nil {
+			c.FirstName = *updates.FirstName
+		}
+		if updates.LastName != nil {
+			c.LastName = *updates.LastName
+		}
+		if updates.Email != nil {
+			c.Email = *updates.Email
+		}
+		if updates.Phone != nil {
+			c.Phone = *updates.Phone
+		}
+		if updates.Company != nil {
+			c.Company = *updates.Company
+		}
+		for key, value := range updates.CustomFields {
+			if c.CustomFields == nil {
+				c.CustomFields = make(map[string]interface{})
+			}
+			c.CustomFields[key] = value
+		}
+		updated = state.normalized(c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (s *SandboxConnector) DeleteContact(ctx context.Context, contactID string) error {
+	return s.update(ctx, func(state *State) error {
+		for i := range state.Contacts {
+			if state.Contacts[i].ID == contactID {
+				state.Contacts = append(state.Contacts[:i], state.Contacts[i+1:]...)
+				return nil
+			}
+		}
+		return notFound("contact", contactID)
+	})
+}
+
+// ========== TAGS ==========
+
+func (s *SandboxConnector) GetTags(ctx context.Context) ([]connectors.Tag, error) {
+	state, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return state.Tags, nil
+}
+
+func (s *SandboxConnector) ApplyTag(ctx context.Context, contactID string, tagID string) error {
+	return s.updateContact(ctx, contactID, func(state *State, c *Contact) error {
+		if state.tag(tagID) == nil {
+			return notFound("tag", tagID)
+		}
+		for _, id := range c.TagIDs {
+			if id == tagID {
+				return nil
+			}
+		}
+		c.TagIDs = append(c.TagIDs, tagID)
+		return nil
+	})
+}
+
+func (s *SandboxConnector) RemoveTag(ctx context.Context, contactID string, tagID string) error {
+	return s.updateContact(ctx, contactID, func(state *State, c *Contact) error {
+		for i, id := range c.TagIDs {
+			if id == tagID {
+				c.TagIDs = append(c.TagIDs[:i], c.TagIDs[i+1:]...)
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// ========== CUSTOM FIELDS ==========
+
+func (s *SandboxConnector) GetCustomFields(ctx context.Context) ([]connectors.CustomField, error) {
+	state, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return state.CustomFields, nil
+}
+
+func (s *SandboxConnector) GetContactFieldValue(ctx context.Context, contactID string, fieldKey string) (interface{}, error) {
+	contact, err := s.GetContact(ctx, contactID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch fieldKey {
+	case "first_name":
+		return contact.FirstName, nil
+	case "last_name":
+		return contact.LastName, nil
+	case "email":
+		return contact.Email, nil
+	case "phone":
+		return contact.Phone, nil
+	case "company":
+		return contact.Company, nil
+	case "job_title":
+		return contact.JobTitle, nil
+	}
+
+	if val, ok := contact.CustomFields[fieldKey]; ok {
+		return val, nil
+	}
+	return nil, nil
+}
+
+func (s *SandboxConnector) SetContactFieldValue(ctx context.Context, contactID string, fieldKey string, value interface{}) error {
+	return s.updateContact(ctx, contactID, func(state *State, c *Contact) error {
+		str := fmt.Sprintf("%v", value)
+		switch fieldKey {
+		case "first_name":
+			c.FirstName = str
+		case "last_name":
+			c.LastName = str
+		case "email":
+			c.Email = str
+		case "phone":
+			c.Phone = str
+		case "company":
+			c.Company = str
+		case "job_title":
+			c.JobTitle = str
+		default:
+			if state.customField(fieldKey) == nil {
+				return unknownField(fieldKey)
+			}
+			if c.CustomFields == nil {
+				c.CustomFields = make(map[string]interface{})
+			}
+			c.CustomFields[fieldKey] = value
+		}
+		return nil
+	})
+}
+
+// ========== AUTOMATIONS ==========
+
+func (s *SandboxConnector) TriggerAutomation(ctx context.Context, contactID string, automationID string) error {
+	return s.updateContact(ctx, contactID, func(state *State, c *Contact) error {
+		if state.automation(automationID) == nil {
+			return notFound("automation", automationID)
+		}
+		state.Events = append(state.Events, Event{
+			Type:      EventAutomationTriggered,
+			ContactID: contactID,
+			Target:    automationID,
+			At:        s.now(),
+		})
+		return nil
+	})
+}
+
+func (s *SandboxConnector) AchieveGoal(ctx context.Context, contactID string, goalName string, integration string) error {
+	if integration == "" {
+		integration = "mfh"
+	}
+	return s.updateContact(ctx, contactID, func(state *State, c *Contact) error {
+		state.Events = append(state.Events, Event{
+			Type:        EventGoalAchieved,
+			ContactID:   contactID,
+			Target:      goalName,
+			Integration: integration,
+			At:          s.now(),
+		})
+		return nil
+	})
+}
+
+// ========== MARKETING ==========
+
+func (s *SandboxConnector) SetOptInStatus(ctx context.Context, contactID string, optIn bool, reason string) error {
+	return s.updateContact(ctx, contactID, func(state *State, c *Contact) error {
+		c.OptIn = &optIn
+		c.OptInReason = reason
+		return nil
+	})
+}
+
+// ========== NOTES ==========
+
+func (s *SandboxConnector) CreateNote(ctx context.Context, contactID string, note connectors.NoteInput) error {
+	return s.updateContact(ctx, contactID, func(state *State, c *Contact) error {
+		c.Notes = append(c.Notes, note)
+		return nil
+	})
+}
+
+// ========== HEALTH ==========
+
+func (s *SandboxConnector) TestConnection(ctx context.Context) error {
+	_, err := s.load(ctx)
+	return err
+}
+
+func (s *SandboxConnector) GetMetadata() connectors.ConnectorMetadata {
+	return connectors.ConnectorMetadata{
+		PlatformSlug: sandboxSlug,
+		PlatformName: "Sandbox CRM",
+		APIVersion:   "v1",
+		BaseURL:      "sandbox://" + s.sandboxID,
+	}
+}
+
+func (s *SandboxConnector) GetCapabilities() []connectors.Capability {
+	return []connectors.Capability{
+		connectors.CapContacts,
+		connectors.CapTags,
+		connectors.CapCustomFields,
+		connectors.CapAutomations,
+		connectors.CapGoals,
+	}
+}
+
+// ========== STATE ==========
+
+// load returns the stored state, or the fixture if the sandbox is unused
+func (s *SandboxConnector) load(ctx context.Context) (*State, error) {
+	state, err := s.store.Load(ctx, s.sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return LoadFixture(s.fixture)
+	}
+	return state, nil
+}
+
+// update applies fn to the current state and saves it, retrying from a fresh
+// load when another writer got there first. An error from fn discards the
+// change.
+func (s *SandboxConnector) update(ctx context.Context, fn func(state *State) error) error {
+	for attempt := 0; attempt < maxSaveAttempts; attempt++ {
+		state, err := s.load(ctx)
+		if err != nil {
+			return err
+		}
+		if err := fn(state); err != nil {
+			return err
+		}
+		err = s.store.Save(ctx, s.sandboxID, state)
+		if !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+	return &connectors.ConnectorError{
+		Code: "CONFLICT", Message: "sandbox is busy, try again",
+		StatusCode: http.StatusConflict, Platform: sandboxSlug, Retryable: true,
+	}
+}
+
+// updateContact is update for a change to one contact; it stamps updated_at
+func (s *SandboxConnector) updateContact(ctx context.Context, contactID string, fn func(state *State, c *Contact) error) error {
+	return s.update(ctx, func(state *State) error {
+		c := state.contact(contactID)
+		if c == nil {
+			return notFound("contact", contactID)
+		}
+		if err := fn(state, c); err != nil {
+			return err
+		}
+		now := s.now()
+		c.UpdatedAt = &now
+		return nil
+	})
+}
+
+func notFound(kind, id string) *connectors.ConnectorError {
+	return &connectors.ConnectorError{
+		Code: "NOT_FOUND", Message: fmt.Sprintf("%s %s not found", kind, id),
+		StatusCode: http.StatusNotFound, Platform: sandboxSlug,
+	}
+}
+
+func unknownField(key string) *connectors.ConnectorError {
+	return &connectors.ConnectorError{
+		Code: "INVALID_FIELD", Message: fmt.Sprintf("unknown custom field %s", key),
+		StatusCode: http.StatusBadRequest, Platform: sandboxSlug,
+	}
+}
@@ -0,0 +1,230 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+func newTestConnector(t *testing.T) *SandboxConnector {
+	t.Helper()
+	conn, err := New(NewMemoryStore(), "connection:test", DefaultFixture)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return conn
+}
+
+func TestRegistry(t *testing.T) {
+	conn, err := connectors.NewConnector("sandbox", connectors.ConnectorConfig{ConnectionID: "connection:registry", APIKey: "empty"})
+	if err != nil {
+		t.Fatalf("Expected sandbox to be registered, got %v", err)
+	}
+	list, err := conn.GetContacts(context.Background(), connectors.QueryOptions{})
+	if err != nil || list.Total != 0 {
+		t.Errorf("Expected the empty fixture, got %+v, %v", list, err)
+	}
+
+	if _, err := connectors.NewConnector("sandbox", connectors.ConnectorConfig{APIKey: "missing"}); err == nil {
+		t.Error("Expected an error for an unknown fixture")
+	}
+}
+
+func TestContacts(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConnector(t)
+
+	contact, err := conn.GetContact(ctx, "1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contact.Email != "ada@example.com" || len(contact.Tags) != 3 || contact.Tags[0].Name != "Customer" {
+		t.Errorf("Expected seeded contact Ada with named tags, got %+v", contact)
+	}
+
+	created, err := conn.CreateContact(ctx, connectors.CreateContactInput{
+		FirstName:    "Margaret",
+		Email:        "margaret@example.com",
+		Tags:         []string{"100"},
+		CustomFields: map[string]interface{}{"lead_source": "Ads"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.ID != "6" {
+		t.Errorf("Expected the next ID after the fixture, got %s", created.ID)
+	}
+
+	name := "Maggie"
+	if _, err := conn.UpdateContact(ctx, created.ID, connectors.UpdateContactInput{FirstName: &name}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value, _ := conn.GetContactFieldValue(ctx, created.ID, "first_name"); value != "Maggie" {
+		t.Errorf("Expected first_name Maggie, got %v", value)
+	}
+
+	if err := conn.DeleteContact(ctx, created.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err = conn.GetContact(ctx, created.ID)
+	if connErr, ok := err.(*connectors.ConnectorError); !ok || connErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted contact, got %v", err)
+	}
+}
+
+func TestGetContacts(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConnector(t)
+
+	list, err := conn.GetContacts(ctx, connectors.QueryOptions{
+		TagID:   "100",
+		OrderBy: "-" + connectors.OrderByFirstName,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if list.Total != 2 || list.Contacts[0].FirstName != "Katherine" || list.Contacts[1].FirstName != "Alan" {
+		t.Errorf("Expected leads Katherine then Alan, got %+v", list.Contacts)
+	}
+
+	var ids []string
+	err = connectors.IterateContacts(ctx, conn, connectors.QueryOptions{Limit: 2}, func(c connectors.NormalizedContact) error {
+		ids = append(ids, c.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := fmt.Sprint(ids); got != "[1 2 3 4 5]" {
+		t.Errorf("Expected every contact once, got %s", got)
+	}
+
+	list, _ = conn.GetContacts(ctx, connectors.QueryOptions{Filters: map[string]string{"field.lead_source": "Webinar"}})
+	if list.Total != 2 {
+		t.Errorf("Expected 2 webinar leads, got %d", list.Total)
+	}
+}
+
+func TestTagsAndFields(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConnector(t)
+
+	if err := conn.ApplyTag(ctx, "3", "101"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := conn.ApplyTag(ctx, "3", "101"); err != nil {
+		t.Fatalf("Expected reapplying a tag to succeed, got %v", err)
+	}
+	if err := conn.RemoveTag(ctx, "3", "100"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	contact, _ := conn.GetContact(ctx, "3")
+	if got := fmt.Sprint(contact.Tags); got != "[{110 Webinar Registered} {101 Customer}]" {
+		t.Errorf("Expected Webinar Registered and Customer, got %s", got)
+	}
+
+	if err := conn.ApplyTag(ctx, "3", "999"); err == nil {
+		t.Error("Expected an error applying an unknown tag")
+	}
+
+	if err := conn.SetContactFieldValue(ctx, "3", "lead_score", 60); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if value, _ := conn.GetContactFieldValue(ctx, "3", "lead_score"); value != float64(60) {
+		t.Errorf("Expected lead_score 60, got %v (%T)", value, value)
+	}
+
+	err := conn.SetContactFieldValue(ctx, "3", "favorite_color", "blue")
+	if connErr, ok := err.(*connectors.ConnectorError); !ok || connErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown field, got %v", err)
+	}
+}
+
+func TestEventsAndOptIn(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConnector(t)
+
+	if err := conn.AchieveGoal(ctx, "2", "purchased", ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := conn.TriggerAutomation(ctx, "2", "10"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := conn.TriggerAutomation(ctx, "2", "99"); err == nil {
+		t.Error("Expected an error triggering an unknown automation")
+	}
+	if err := conn.AchieveGoal(ctx, "99", "purchased", "mfh"); err == nil {
+		t.Error("Expected an error achieving a goal for an unknown contact")
+	}
+
+	events, err := conn.Events(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].Type != EventGoalAchieved || events[0].Target != "purchased" || events[0].Integration != "mfh" {
+		t.Errorf("Expected goal purchased via mfh, got %+v", events[0])
+	}
+	if events[1].Type != EventAutomationTriggered || events[1].ContactID != "2" || events[1].Target != "10" {
+		t.Errorf("Expected automation 10 for contact 2, got %+v", events[1])
+	}
+
+	if err := conn.SetOptInStatus(ctx, "2", false, "requested"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	state, _ := conn.Snapshot(ctx)
+	contact := state.contact("2")
+	if contact.OptIn == nil || *contact.OptIn || contact.OptInReason != "requested" {
+		t.Errorf("Expected opted out with reason, got %v %q", contact.OptIn, contact.OptInReason)
+	}
+}
+
+func TestSharedStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	first, _ := New(store, "connection:shared", "")
+	second, _ := New(store, "connection:shared", "")
+	other, _ := New(store, "connection:other", "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(conn *SandboxConnector) {
+			defer wg.Done()
+			if err := conn.AchieveGoal(ctx, "1", "clicked", "mfh"); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}([]*SandboxConnector{first, second}[i%2])
+	}
+	wg.Wait()
+
+	events, _ := second.Events(ctx)
+	if len(events) != 4 {
+		t.Errorf("Expected 4 events across connectors, got %d", len(events))
+	}
+	if events, _ := other.Events(ctx); len(events) != 0 {
+		t.Errorf("Expected other sandboxes to be unaffected, got %d events", len(events))
+	}
+
+	fixture, err := ParseFixture([]byte(`{"tags":[{"id":"1","name":"A"}],"contacts":[{"id":"7","email":"x@example.com","tag_ids":["1"]}]}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := other.Seed(ctx, fixture); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	created, _ := other.CreateContact(ctx, connectors.CreateContactInput{Email: "y@example.com"})
+	if created == nil || created.ID != "8" {
+		t.Errorf("Expected ID 8 after seeding, got %+v", created)
+	}
+
+	if _, err := ParseFixture([]byte(`{"contacts":[{"id":"1","tag_ids":["9"]}]}`)); err == nil {
+		t.Error("Expected an error for a fixture referencing an unknown tag")
+	}
+}
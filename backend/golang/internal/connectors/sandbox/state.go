@@ -0,0 +1,186 @@
+package sandbox
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// DefaultFixture seeds sandboxes whose connection does not name a fixture
+const DefaultFixture = "demo"
+
+// Event types recorded by the sandbox
+const (
+	EventGoalAchieved        = "goal_achieved"
+	EventAutomationTriggered = "automation_triggered"
+)
+
+// State is the complete contents of one sandbox CRM. Fixtures use the same
+// JSON form, so a fixture is just the state a sandbox starts from.
+type State struct {
+	Contacts     []Contact                `json:"contacts"`
+	Tags         []connectors.Tag         `json:"tags"`
+	CustomFields []connectors.CustomField `json:"custom_fields"`
+	Automations  []Automation             `json:"automations"`
+	Events       []Event                  `json:"events,omitempty"`
+	NextID       int                      `json:"next_id,omitempty"`
+
+	// Version counts saves, for optimistic locking in the store
+	Version int `json:"version,omitempty"`
+}
+
+// Contact is a sandbox contact record
+type Contact struct {
+	ID           string                 `json:"id"`
+	FirstName    string                 `json:"first_name"`
+	LastName     string                 `json:"last_name"`
+	Email        string                 `json:"email"`
+	Phone        string                 `json:"phone,omitempty"`
+	Company      string                 `json:"company,omitempty"`
+	JobTitle     string                 `json:"job_title,omitempty"`
+	TagIDs       []string               `json:"tag_ids,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	OptIn        *bool                  `json:"opt_in,omitempty"`
+	OptInReason  string                 `json:"opt_in_reason,omitempty"`
+	Notes        []connectors.NoteInput `json:"notes,omitempty"`
+	CreatedAt    *time.Time             `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time             `json:"updated_at,omitempty"`
+}
+
+// Automation is a sequence or campaign contacts can be added to
+type Automation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Event records a goal achievement or automation trigger
+type Event struct {
+	Type        string    `json:"type"`
+	ContactID   string    `json:"contact_id"`
+	Target      string    `json:"target"`                // goal name or automation ID
+	Integration string    `json:"integration,omitempty"` // goal integration name
+	At          time.Time `json:"at"`
+}
+
+// ParseFixture reads a sandbox state from fixture JSON and checks that
+// contacts only reference tags that exist.
+func ParseFixture(data []byte) (*State, error) {
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid sandbox fixture: %w", err)
+	}
+
+	seen := make(map[string]bool, len(state.Contacts))
+	maxID := 0
+	for _, contact := range state.Contacts {
+		if contact.ID == "" || seen[contact.ID] {
+			return nil, fmt.Errorf("invalid sandbox fixture: missing or duplicate contact id %q", contact.ID)
+		}
+		seen[contact.ID] = true
+		for _, tagID := range contact.TagIDs {
+			if state.tag(tagID) == nil {
+				return nil, fmt.Errorf("invalid sandbox fixture: contact %s has unknown tag %s", contact.ID, tagID)
+			}
+		}
+		if n, err := strconv.Atoi(contact.ID); err == nil && n > maxID {
+			maxID = n
+		}
+	}
+	if state.NextID <= maxID {
+		state.NextID = maxID + 1
+	}
+	return &state, nil
+}
+
+// LoadFixture parses one of the embedded fixtures by name
+func LoadFixture(name string) (*State, error) {
+	data, err := fixtures.ReadFile("fixtures/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown sandbox fixture %q", name)
+	}
+	return ParseFixture(data)
+}
+
+func (s *State) contact(contactID string) *Contact {
+	for i := range s.Contacts {
+		if s.Contacts[i].ID == contactID {
+			return &s.Contacts[i]
+		}
+	}
+	return nil
+}
+
+func (s *State) tag(tagID string) *connectors.Tag {
+	for i := range s.Tags {
+		if s.Tags[i].ID == tagID {
+			return &s.Tags[i]
+		}
+	}
+	return nil
+}
+
+// customField looks a field up by ID, which is what callers pass as the field
+// key. Fixtures give each field an ID equal to its key.
+func (s *State) customField(fieldID string) *connectors.CustomField {
+	for i := range s.CustomFields {
+		if s.CustomFields[i].ID == fieldID {
+			return &s.CustomFields[i]
+		}
+	}
+	return nil
+}
+
+func (s *State) automation(automationID string) *Automation {
+	for i := range s.Automations {
+		if s.Automations[i].ID == automationID {
+			return &s.Automations[i]
+		}
+	}
+	return nil
+}
+
+// newID hands out the next numeric record ID
+func (s *State) newID() string {
+	if s.NextID <= 0 {
+		s.NextID = 1
+	}
+	id := strconv.Itoa(s.NextID)
+	s.NextID++
+	return id
+}
+
+// normalized converts a contact to the connector model, resolving tag names
+func (s *State) normalized(c *Contact) connectors.NormalizedContact {
+	contact := connectors.NormalizedContact{
+		ID:           c.ID,
+		FirstName:    c.FirstName,
+		LastName:     c.LastName,
+		Email:        c.Email,
+		Phone:        c.Phone,
+		Company:      c.Company,
+		JobTitle:     c.JobTitle,
+		CustomFields: make(map[string]interface{}, len(c.CustomFields)),
+		SourceCRM:    sandboxSlug,
+		SourceID:     c.ID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+	for key, value := range c.CustomFields {
+		contact.CustomFields[key] = value
+	}
+	for _, tagID := range c.TagIDs {
+		ref := connectors.TagRef{ID: tagID}
+		if tag := s.tag(tagID); tag != nil {
+			ref.Name = tag.Name
+		}
+		contact.Tags = append(contact.Tags, ref)
+	}
+	return contact
+}
@@ -0,0 +1,155 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrConflict is returned by Store.Save when the stored state changed since
+// it was loaded
+var ErrConflict = errors.New("sandbox state was modified concurrently")
+
+// Store persists sandbox states by sandbox ID
+type Store interface {
+	// Load returns the stored state, or nil if the sandbox has none yet
+	Load(ctx context.Context, sandboxID string) (*State, error)
+	// Save stores state if the stored version still equals state.Version,
+	// then increments state.Version. It fails with ErrConflict otherwise.
+	Save(ctx context.Context, sandboxID string, state *State) error
+}
+
+// MemoryStore keeps sandbox states in process memory
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string][]byte)}
+}
+
+func (m *MemoryStore) Load(ctx context.Context, sandboxID string) (*State, error) {
+	m.mu.Lock()
+	data, ok := m.states[sandboxID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (m *MemoryStore) Save(ctx context.Context, sandboxID string, state *State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := 0
+	if data, ok := m.states[sandboxID]; ok {
+		var current State
+		if err := json.Unmarshal(data, &current); err != nil {
+			return err
+		}
+		stored = current.Version
+	}
+	if stored != state.Version {
+		return ErrConflict
+	}
+
+	state.Version++
+	data, err := json.Marshal(state)
+	if err != nil {
+		state.Version--
+		return err
+	}
+	m.states[sandboxID] = data
+	return nil
+}
+
+// DynamoStore keeps each sandbox state as a JSON document in a DynamoDB item
+// keyed by sandbox_id, so sandboxes survive across Lambda invocations.
+type DynamoStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDynamoStore creates a store backed by the given table
+func NewDynamoStore(client *dynamodb.Client, tableName string) *DynamoStore {
+	return &DynamoStore{client: client, tableName: tableName}
+}
+
+func (d *DynamoStore) Load(ctx context.Context, sandboxID string) (*State, error) {
+	result, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key: map[string]ddbtypes.AttributeValue{
+			"sandbox_id": &ddbtypes.AttributeValueMemberS{Value: sandboxID},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sandbox %s: %w", sandboxID, err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	data, ok := result.Item["state"].(*ddbtypes.AttributeValueMemberS)
+	if !ok {
+		return nil, fmt.Errorf("sandbox %s has no state", sandboxID)
+	}
+	var state State
+	if err := json.Unmarshal([]byte(data.Value), &state); err != nil {
+		return nil, fmt.Errorf("failed to parse sandbox %s: %w", sandboxID, err)
+	}
+	return &state, nil
+}
+
+func (d *DynamoStore) Save(ctx context.Context, sandboxID string, state *State) error {
+	expected := state.Version
+	state.Version++
+	data, err := json.Marshal(state)
+	if err != nil {
+		state.Version = expected
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item: map[string]ddbtypes.AttributeValue{
+			"sandbox_id": &ddbtypes.AttributeValueMemberS{Value: sandboxID},
+			"state":      &ddbtypes.AttributeValueMemberS{Value: string(data)},
+			"version":    &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(state.Version)},
+			"updated_at": &ddbtypes.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	}
+	if expected == 0 {
+		input.ConditionExpression = aws.String("attribute_not_exists(sandbox_id)")
+	} else {
+		input.ConditionExpression = aws.String("version = :expected")
+		input.ExpressionAttributeValues = map[string]ddbtypes.AttributeValue{
+			":expected": &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(expected)},
+		}
+	}
+
+	if _, err := d.client.PutItem(ctx, input); err != nil {
+		state.Version = expected
+		var ccf *ddbtypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrConflict
+		}
+		return fmt.Errorf("failed to save sandbox %s: %w", sandboxID, err)
+	}
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -27,6 +26,7 @@ type StripeConnector struct {
 	apiKey  string
 	baseURL string
 	client  *http.Client
+	limiter *rateLimiter
 }
 
 // NewStripeConnector creates a new Stripe connector
@@ -48,12 +48,25 @@ func NewStripeConnector(config ConnectorConfig) (CRMConnector, error) {
 		apiKey:  key,
 		baseURL: strings.TrimRight(baseURL, "/"),
 		client:  &http.Client{Timeout: 30 * time.Second},
+		limiter: limiterFor(stripeSlug, config),
 	}, nil
 }
 
 // ========== CONTACTS (Stripe Customers) ==========
 
 func (s *StripeConnector) GetContacts(ctx context.Context, opts QueryOptions) (*ContactList, error) {
+	q, err := parseContactQuery(stripeSlug, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Customers carry no modification time and always list newest first
+	if q.UpdatedSince != nil {
+		return nil, invalidQuery(stripeSlug, "stripe does not support the updated_since filter")
+	}
+	if q.OrderBy != "" && !(q.OrderBy == OrderByCreated && q.Descending) {
+		return nil, invalidQuery(stripeSlug, "stripe only supports ordering by -created_at")
+	}
+
 	limit := opts.Limit
 	if limit <= 0 {
 		limit = 25
@@ -67,8 +80,8 @@ func (s *StripeConnector) GetContacts(ctx context.Context, opts QueryOptions) (*
 	if opts.Cursor != "" {
 		params.Set("starting_after", opts.Cursor)
 	}
-	if opts.Email != "" {
-		params.Set("email", opts.Email)
+	if q.Email != "" {
+		params.Set("email", q.Email)
 	}
 
 	var result stripeCustomerList
@@ -81,11 +94,12 @@ func (s *StripeConnector) GetContacts(ctx context.Context, opts QueryOptions) (*
 		contacts = append(contacts, sc.toNormalized())
 	}
 
+	// Tags and custom fields live in metadata, which the list endpoint cannot filter on
 	cl := &ContactList{
-		Contacts: contacts,
-		Total:    len(result.Data),
+		Contacts: q.filter(contacts, FilterEmail),
 		HasMore:  result.HasMore,
 	}
+	cl.Total = len(cl.Contacts)
 	if result.HasMore && len(result.Data) > 0 {
 		cl.NextCursor = result.Data[len(result.Data)-1].ID
 	}
@@ -269,6 +283,102 @@ func (s *StripeConnector) SetOptInStatus(_ context.Context, _ string, _ bool, _
 	return NewConnectorError(stripeSlug, 501, "Stripe does not support email opt-in management", false)
 }
 
+// ========== RELATED RECORDS ==========
+
+// stripeRelatedMaxPages bounds how many 100-record pages are read per list
+const stripeRelatedMaxPages = 10
+
+func (s *StripeConnector) ListInvoices(ctx context.Context, contactID string) ([]Invoice, error) {
+	var invoices []Invoice
+	err := s.listAll(ctx, "/invoices", url.Values{"customer": {contactID}}, func(raw json.RawMessage) error {
+		var si stripeInvoice
+		if err := json.Unmarshal(raw, &si); err != nil {
+			return err
+		}
+		invoices = append(invoices, si.toInvoice(contactID))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortByCreated(invoices, func(i Invoice) *time.Time { return i.CreatedAt })
+	return invoices, nil
+}
+
+// ListOrders returns the customer's Checkout Sessions. Stripe has no order
+// object, so each completed checkout is treated as an order.
+func (s *StripeConnector) ListOrders(ctx context.Context, contactID string) ([]Order, error) {
+	var orders []Order
+	err := s.listAll(ctx, "/checkout/sessions", url.Values{"customer": {contactID}}, func(raw json.RawMessage) error {
+		var cs stripeCheckoutSession
+		if err := json.Unmarshal(raw, &cs); err != nil {
+			return err
+		}
+		if cs.Status != "complete" {
+			return nil
+		}
+		orders = append(orders, cs.toOrder(contactID))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortByCreated(orders, func(o Order) *time.Time { return o.CreatedAt })
+	return orders, nil
+}
+
+func (s *StripeConnector) ListSubscriptions(ctx context.Context, contactID string) ([]Subscription, error) {
+	var subscriptions []Subscription
+	params := url.Values{"customer": {contactID}, "status": {"all"}}
+	err := s.listAll(ctx, "/subscriptions", params, func(raw json.RawMessage) error {
+		var ss stripeSubscription
+		if err := json.Unmarshal(raw, &ss); err != nil {
+			return err
+		}
+		subscriptions = append(subscriptions, ss.toSubscription(contactID))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortByCreated(subscriptions, func(s Subscription) *time.Time { return s.CreatedAt })
+	return subscriptions, nil
+}
+
+// listAll pages through a Stripe list endpoint with starting_after cursors
+func (s *StripeConnector) listAll(ctx context.Context, path string, params url.Values, fn func(json.RawMessage) error) error {
+	params.Set("limit", "100")
+
+	for page := 0; page < stripeRelatedMaxPages; page++ {
+		var result struct {
+			Data    []json.RawMessage `json:"data"`
+			HasMore bool              `json:"has_more"`
+		}
+		if err := s.doRequest(ctx, "GET", path+"?"+params.Encode(), nil, &result); err != nil {
+			return err
+		}
+
+		lastID := ""
+		for _, raw := range result.Data {
+			if err := fn(raw); err != nil {
+				return fmt.Errorf("failed to parse %s record: %w", path, err)
+			}
+			var ref struct {
+				ID string `json:"id"`
+			}
+			json.Unmarshal(raw, &ref)
+			lastID = ref.ID
+		}
+
+		if !result.HasMore || lastID == "" {
+			return nil
+		}
+		params.Set("starting_after", lastID)
+	}
+
+	return nil
+}
+
 // ========== HEALTH ==========
 
 func (s *StripeConnector) TestConnection(ctx context.Context) error {
@@ -336,76 +446,179 @@ func (sc *stripeCustomer) toNormalized() NormalizedContact {
 		contact.CreatedAt = &t
 	}
 
-	// Map metadata to custom fields
+	// Map metadata to custom fields; ApplyTag stores tags as tag_<id> entries
 	for key, value := range sc.Metadata {
 		contact.CustomFields[key] = value
+		if strings.HasPrefix(key, "tag_") && value != "" {
+			contact.Tags = append(contact.Tags, TagRef{ID: value, Name: value})
+		}
 	}
 
 	return contact
 }
 
-// ========== HTTP HELPERS ==========
+type stripeInvoice struct {
+	ID                string `json:"id"`
+	Number            string `json:"number"`
+	Status            string `json:"status"`
+	Currency          string `json:"currency"`
+	Total             int64  `json:"total"`
+	AmountPaid        int64  `json:"amount_paid"`
+	AmountRemaining   int64  `json:"amount_remaining"`
+	Created           int64  `json:"created"`
+	DueDate           int64  `json:"due_date"`
+	StatusTransitions struct {
+		PaidAt int64 `json:"paid_at"`
+	} `json:"status_transitions"`
+}
 
-func (s *StripeConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	var bodyReader io.Reader
-	if body != nil {
-		bodyJSON, err := json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		bodyReader = strings.NewReader(string(bodyJSON))
+func (si *stripeInvoice) toInvoice(contactID string) Invoice {
+	return Invoice{
+		ID:         si.ID,
+		ContactID:  contactID,
+		Number:     si.Number,
+		Status:     si.Status,
+		Currency:   si.Currency,
+		Total:      stripeAmount(si.Total, si.Currency),
+		AmountPaid: stripeAmount(si.AmountPaid, si.Currency),
+		AmountDue:  stripeAmount(si.AmountRemaining, si.Currency),
+		CreatedAt:  stripeTime(si.Created),
+		DueAt:      stripeTime(si.DueDate),
+		PaidAt:     stripeTime(si.StatusTransitions.PaidAt),
+		SourceCRM:  stripeSlug,
 	}
+}
 
-	apiURL := s.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, apiURL, bodyReader)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
+type stripeCheckoutSession struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	PaymentStatus string `json:"payment_status"`
+	Currency      string `json:"currency"`
+	AmountTotal   int64  `json:"amount_total"`
+	Created       int64  `json:"created"`
+}
 
-	req.Header.Set("Authorization", "Bearer "+s.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+func (cs *stripeCheckoutSession) toOrder(contactID string) Order {
+	return Order{
+		ID:        cs.ID,
+		ContactID: contactID,
+		Status:    cs.PaymentStatus,
+		Currency:  cs.Currency,
+		Total:     stripeAmount(cs.AmountTotal, cs.Currency),
+		CreatedAt: stripeTime(cs.Created),
+		SourceCRM: stripeSlug,
+	}
+}
 
-	return s.executeRequest(req, result)
+type stripeSubscription struct {
+	ID               string `json:"id"`
+	Status           string `json:"status"`
+	Currency         string `json:"currency"`
+	Created          int64  `json:"created"`
+	StartDate        int64  `json:"start_date"`
+	CurrentPeriodEnd int64  `json:"current_period_end"`
+	EndedAt          int64  `json:"ended_at"`
+	Items            struct {
+		Data []struct {
+			Quantity int64 `json:"quantity"`
+			Price    struct {
+				Nickname   string `json:"nickname"`
+				Product    string `json:"product"`
+				UnitAmount int64  `json:"unit_amount"`
+				Recurring  struct {
+					Interval      string `json:"interval"`
+					IntervalCount int    `json:"interval_count"`
+				} `json:"recurring"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
 }
 
-func (s *StripeConnector) doFormRequest(ctx context.Context, method, path string, form url.Values, result interface{}) error {
-	apiURL := s.baseURL + path
-	req, err := http.NewRequestWithContext(ctx, method, apiURL, strings.NewReader(form.Encode()))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+func (ss *stripeSubscription) toSubscription(contactID string) Subscription {
+	sub := Subscription{
+		ID:        ss.ID,
+		ContactID: contactID,
+		Status:    ss.Status,
+		Currency:  ss.Currency,
+		CreatedAt: stripeTime(ss.StartDate),
+		EndedAt:   stripeTime(ss.EndedAt),
+		SourceCRM: stripeSlug,
+	}
+	if sub.CreatedAt == nil {
+		sub.CreatedAt = stripeTime(ss.Created)
+	}
+	if ss.Status == "active" || ss.Status == "trialing" || ss.Status == "past_due" {
+		sub.NextBillAt = stripeTime(ss.CurrentPeriodEnd)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.apiKey)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Accept", "application/json")
+	var amount int64
+	for i, item := range ss.Items.Data {
+		quantity := item.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		amount += item.Price.UnitAmount * quantity
+		if i == 0 {
+			sub.Name = item.Price.Nickname
+			sub.ProductID = item.Price.Product
+			sub.Interval = item.Price.Recurring.Interval
+			sub.IntervalCount = item.Price.Recurring.IntervalCount
+		}
+	}
+	sub.Amount = stripeAmount(amount, ss.Currency)
 
-	return s.executeRequest(req, result)
+	return sub
 }
 
-func (s *StripeConnector) executeRequest(req *http.Request, result interface{}) error {
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return NewConnectorError(stripeSlug, 0, fmt.Sprintf("request failed: %v", err), true)
+// stripeZeroDecimalCurrencies are charged in whole units rather than cents
+var stripeZeroDecimalCurrencies = map[string]bool{
+	"bif": true, "clp": true, "djf": true, "gnf": true, "jpy": true, "kmf": true,
+	"krw": true, "mga": true, "pyg": true, "rwf": true, "ugx": true, "vnd": true,
+	"vuv": true, "xaf": true, "xof": true, "xpf": true,
+}
+
+// stripeAmount converts a Stripe minor-unit amount to major units
+func stripeAmount(amount int64, currency string) float64 {
+	if stripeZeroDecimalCurrencies[strings.ToLower(currency)] {
+		return float64(amount)
 	}
-	defer resp.Body.Close()
+	return float64(amount) / 100
+}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return NewConnectorError(stripeSlug, resp.StatusCode, "failed to read response", true)
+func stripeTime(unix int64) *time.Time {
+	if unix <= 0 {
+		return nil
 	}
+	t := time.Unix(unix, 0).UTC()
+	return &t
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		retryable := resp.StatusCode == 429 || resp.StatusCode >= 500
-		return NewConnectorError(stripeSlug, resp.StatusCode,
-			fmt.Sprintf("Stripe API error (%d): %s", resp.StatusCode, string(respBody)), retryable)
+// ========== HTTP HELPERS ==========
+
+func (s *StripeConnector) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	req, err := newJSONRequest(method, s.baseURL+path, body)
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
 
-	if result != nil && len(respBody) > 0 {
-		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("failed to parse response: %w", err)
-		}
+	return s.transport().do(ctx, req, result)
+}
+
+func (s *StripeConnector) doFormRequest(ctx context.Context, method, path string, form url.Values, result interface{}) error {
+	req := apiRequest{
+		Method: method,
+		URL:    s.baseURL + path,
+		Body:   []byte(form.Encode()),
+		Header: make(http.Header),
 	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
 
-	return nil
+	return s.transport().do(ctx, req, result)
+}
+
+func (s *StripeConnector) transport() *transport {
+	return &transport{platform: stripeSlug, name: "Stripe", client: s.client, limiter: s.limiter}
 }
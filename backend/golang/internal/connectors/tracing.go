@@ -0,0 +1,335 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// maxTraceCalls bounds how many calls one trace keeps; later calls are
+	// counted in Dropped
+	maxTraceCalls = 100
+
+	// maxTraceErrorLen bounds the error text kept per call
+	maxTraceErrorLen = 300
+)
+
+// Call is one CRM connector call made during an execution. Target is the
+// field key, tag ID, automation or goal as sent to the CRM, i.e. after
+// translation. StatusCode is the last HTTP status the call received and
+// Requests counts every HTTP request it sent, Retries the resent ones.
+type Call struct {
+	Method     string `json:"method" dynamodbav:"method"`
+	ContactID  string `json:"contact_id,omitempty" dynamodbav:"contact_id,omitempty"`
+	Target     string `json:"target,omitempty" dynamodbav:"target,omitempty"`
+	StatusCode int    `json:"status_code,omitempty" dynamodbav:"status_code,omitempty"`
+	Requests   int    `json:"requests,omitempty" dynamodbav:"requests,omitempty"`
+	Retries    int    `json:"retries,omitempty" dynamodbav:"retries,omitempty"`
+	LatencyMs  int64  `json:"latency_ms" dynamodbav:"latency_ms"`
+	Error      string `json:"error,omitempty" dynamodbav:"error,omitempty"`
+}
+
+// Trace collects the connector calls of one execution. It is carried on the
+// context so the connector loaded for a job records into that job's trace.
+type Trace struct {
+	mu      sync.Mutex
+	calls   []Call
+	dropped int
+}
+
+// NewTrace creates an empty trace
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+// Calls returns the recorded calls in call order
+func (t *Trace) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	calls := make([]Call, len(t.calls))
+	copy(calls, t.calls)
+	return calls
+}
+
+// Dropped returns how many calls were not kept because the trace was full
+func (t *Trace) Dropped() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+func (t *Trace) add(call Call) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.calls) >= maxTraceCalls {
+		t.dropped++
+		return
+	}
+	t.calls = append(t.calls, call)
+}
+
+type traceKey struct{}
+type callStatsKey struct{}
+
+// WithTrace returns a context whose connector calls are recorded into trace
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceFromContext returns the trace carried on ctx, or nil
+func TraceFromContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// callStats accumulates the HTTP requests sent for one traced call. The shared
+// transport reports into it.
+type callStats struct {
+	requests   int
+	retries    int
+	statusCode int
+}
+
+// observeRequest records one HTTP attempt of the call traced on ctx, if any.
+// statusCode is 0 when no response was received.
+func observeRequest(ctx context.Context, attempt, statusCode int) {
+	stats, ok := ctx.Value(callStatsKey{}).(*callStats)
+	if !ok {
+		return
+	}
+	stats.requests++
+	if attempt > 1 {
+		stats.retries++
+	}
+	stats.statusCode = statusCode
+}
+
+// TracingConnector wraps a CRMConnector and records every call into the
+// Trace carried on the call's context. Calls without a trace pass straight
+// through.
+type TracingConnector struct {
+	inner CRMConnector
+}
+
+// NewTracingConnector wraps inner so its calls are traced
+func NewTracingConnector(inner CRMConnector) *TracingConnector {
+	return &TracingConnector{inner: inner}
+}
+
+// begin starts tracing a call. The returned context carries the call's HTTP
+// stats; done records the call with its outcome.
+func (c *TracingConnector) begin(ctx context.Context, method, contactID, target string) (context.Context, func(err error)) {
+	trace := TraceFromContext(ctx)
+	if trace == nil {
+		return ctx, func(error) {}
+	}
+
+	stats := &callStats{}
+	start := time.Now()
+	return context.WithValue(ctx, callStatsKey{}, stats), func(err error) {
+		call := Call{
+			Method:     method,
+			ContactID:  contactID,
+			Target:     target,
+			StatusCode: stats.statusCode,
+			Requests:   stats.requests,
+			Retries:    stats.retries,
+			LatencyMs:  time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			call.Error = err.Error()
+			if len(call.Error) > maxTraceErrorLen {
+				call.Error = call.Error[:maxTraceErrorLen] + "..."
+			}
+			var connErr *ConnectorError
+			if call.StatusCode == 0 && errors.As(err, &connErr) {
+				call.StatusCode = connErr.StatusCode
+			}
+		}
+		trace.add(call)
+	}
+}
+
+// ========== CONTACTS ==========
+
+func (c *TracingConnector) GetContacts(ctx context.Context, opts QueryOptions) (*ContactList, error) {
+	ctx, done := c.begin(ctx, "get_contacts", "", "")
+	list, err := c.inner.GetContacts(ctx, opts)
+	done(err)
+	return list, err
+}
+
+func (c *TracingConnector) GetContact(ctx context.Context, contactID string) (*NormalizedContact, error) {
+	ctx, done := c.begin(ctx, "get_contact", contactID, "")
+	contact, err := c.inner.GetContact(ctx, contactID)
+	done(err)
+	return contact, err
+}
+
+func (c *TracingConnector) CreateContact(ctx context.Context, contact CreateContactInput) (*NormalizedContact, error) {
+	ctx, done := c.begin(ctx, ChangeCreateContact, "", "")
+	created, err := c.inner.CreateContact(ctx, contact)
+	done(err)
+	return created, err
+}
+
+func (c *TracingConnector) UpdateContact(ctx context.Context, contactID string, updates UpdateContactInput) (*NormalizedContact, error) {
+	ctx, done := c.begin(ctx, ChangeUpdateContact, contactID, "")
+	updated, err := c.inner.UpdateContact(ctx, contactID, updates)
+	done(err)
+	return updated, err
+}
+
+func (c *TracingConnector) DeleteContact(ctx context.Context, contactID string) error {
+	ctx, done := c.begin(ctx, ChangeDeleteContact, contactID, "")
+	err := c.inner.DeleteContact(ctx, contactID)
+	done(err)
+	return err
+}
+
+// ========== TAGS ==========
+
+func (c *TracingConnector) GetTags(ctx context.Context) ([]Tag, error) {
+	ctx, done := c.begin(ctx, "get_tags", "", "")
+	tags, err := c.inner.GetTags(ctx)
+	done(err)
+	return tags, err
+}
+
+func (c *TracingConnector) ApplyTag(ctx context.Context, contactID string, tagID string) error {
+	ctx, done := c.begin(ctx, ChangeApplyTag, contactID, tagID)
+	err := c.inner.ApplyTag(ctx, contactID, tagID)
+	done(err)
+	return err
+}
+
+func (c *TracingConnector) RemoveTag(ctx context.Context, contactID string, tagID string) error {
+	ctx, done := c.begin(ctx, ChangeRemoveTag, contactID, tagID)
+	err := c.inner.RemoveTag(ctx, contactID, tagID)
+	done(err)
+	return err
+}
+
+// ========== CUSTOM FIELDS ==========
+
+func (c *TracingConnector) GetCustomFields(ctx context.Context) ([]CustomField, error) {
+	ctx, done := c.begin(ctx, "get_custom_fields", "", "")
+	fields, err := c.inner.GetCustomFields(ctx)
+	done(err)
+	return fields, err
+}
+
+func (c *TracingConnector) GetContactFieldValue(ctx context.Context, contactID string, fieldKey string) (interface{}, error) {
+	ctx, done := c.begin(ctx, "get_field", contactID, fieldKey)
+	value, err := c.inner.GetContactFieldValue(ctx, contactID, fieldKey)
+	done(err)
+	return value, err
+}
+
+func (c *TracingConnector) SetContactFieldValue(ctx context.Context, contactID string, fieldKey string, value interface{}) error {
+	ctx, done := c.begin(ctx, ChangeSetField, contactID, fieldKey)
+	err := c.inner.SetContactFieldValue(ctx, contactID, fieldKey, value)
+	done(err)
+	return err
+}
+
+// ========== AUTOMATIONS ==========
+
+func (c *TracingConnector) TriggerAutomation(ctx context.Context, contactID string, automationID string) error {
+	ctx, done := c.begin(ctx, ChangeTriggerAutomation, contactID, automationID)
+	err := c.inner.TriggerAutomation(ctx, contactID, automationID)
+	done(err)
+	return err
+}
+
+func (c *TracingConnector) AchieveGoal(ctx context.Context, contactID string, goalName string, integration string) error {
+	ctx, done := c.begin(ctx, ChangeAchieveGoal, contactID, goalName)
+	err := c.inner.AchieveGoal(ctx, contactID, goalName, integration)
+	done(err)
+	return err
+}
+
+// ========== MARKETING ==========
+
+func (c *TracingConnector) SetOptInStatus(ctx context.Context, contactID string, optIn bool, reason string) error {
+	ctx, done := c.begin(ctx, ChangeSetOptIn, contactID, reason)
+	err := c.inner.SetOptInStatus(ctx, contactID, optIn, reason)
+	done(err)
+	return err
+}
+
+// ========== OPTIONAL CAPABILITIES ==========
+
+// CreateNote traces the note when the wrapped connector supports notes
+func (c *TracingConnector) CreateNote(ctx context.Context, contactID string, note NoteInput) error {
+	nc, ok := c.inner.(NoteConnector)
+	if !ok {
+		slug := c.inner.GetMetadata().PlatformSlug
+		return NewConnectorError(slug, 501, slug+" does not support contact notes", false)
+	}
+	ctx, done := c.begin(ctx, ChangeCreateNote, contactID, note.Title)
+	err := nc.CreateNote(ctx, contactID, note)
+	done(err)
+	return err
+}
+
+func (c *TracingConnector) ListInvoices(ctx context.Context, contactID string) ([]Invoice, error) {
+	rc, err := c.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := c.begin(ctx, "list_invoices", contactID, "")
+	invoices, err := rc.ListInvoices(ctx, contactID)
+	done(err)
+	return invoices, err
+}
+
+func (c *TracingConnector) ListOrders(ctx context.Context, contactID string) ([]Order, error) {
+	rc, err := c.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := c.begin(ctx, "list_orders", contactID, "")
+	orders, err := rc.ListOrders(ctx, contactID)
+	done(err)
+	return orders, err
+}
+
+func (c *TracingConnector) ListSubscriptions(ctx context.Context, contactID string) ([]Subscription, error) {
+	rc, err := c.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := c.begin(ctx, "list_subscriptions", contactID, "")
+	subscriptions, err := rc.ListSubscriptions(ctx, contactID)
+	done(err)
+	return subscriptions, err
+}
+
+func (c *TracingConnector) relatedRecordsConnector() (RelatedRecordsConnector, error) {
+	rc, ok := c.inner.(RelatedRecordsConnector)
+	if !ok {
+		slug := c.inner.GetMetadata().PlatformSlug
+		return nil, NewConnectorError(slug, 501, slug+" does not support related records", false)
+	}
+	return rc, nil
+}
+
+// ========== HEALTH & METADATA ==========
+
+func (c *TracingConnector) TestConnection(ctx context.Context) error {
+	ctx, done := c.begin(ctx, "test_connection", "", "")
+	err := c.inner.TestConnection(ctx)
+	done(err)
+	return err
+}
+
+func (c *TracingConnector) GetMetadata() ConnectorMetadata {
+	return c.inner.GetMetadata()
+}
+
+func (c *TracingConnector) GetCapabilities() []Capability {
+	return c.inner.GetCapabilities()
+}
@@ -0,0 +1,81 @@
+package connectors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracingConnector(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = 500 * time.Millisecond }()
+
+	tagAttempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tags"):
+			tagAttempts++
+			if tagAttempts == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": 123, "given_name": "John"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message": "bad field"}`))
+		}
+	}))
+	defer server.Close()
+
+	keap := &KeapConnector{token: &oauthToken{access: "test-token"}, baseURL: server.URL, client: server.Client()}
+	tracer := NewTracingConnector(keap)
+
+	// Without a trace on the context calls pass straight through
+	if _, err := tracer.GetContact(context.Background(), "123"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	trace := NewTrace()
+	ctx := WithTrace(context.Background(), trace)
+	tracer.GetContact(ctx, "123")
+	tracer.ApplyTag(ctx, "123", "42")
+	tracer.SetContactFieldValue(ctx, "123", "_LeadScore", 10)
+
+	calls := trace.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 traced calls, got %d: %+v", len(calls), calls)
+	}
+
+	if calls[0].Method != "get_contact" || calls[0].StatusCode != 200 || calls[0].Requests != 1 || calls[0].Error != "" {
+		t.Errorf("Expected successful get_contact, got %+v", calls[0])
+	}
+
+	tag := calls[1]
+	if tag.Method != ChangeApplyTag || tag.Target != "42" || tag.ContactID != "123" {
+		t.Errorf("Expected apply_tag 42 on contact 123, got %+v", tag)
+	}
+	if tag.Requests != 2 || tag.Retries != 1 || tag.StatusCode != 200 {
+		t.Errorf("Expected apply_tag retried once then 200, got %+v", tag)
+	}
+
+	field := calls[2]
+	if field.Method != ChangeSetField || field.Target != "_LeadScore" || field.StatusCode != 400 || field.Error == "" {
+		t.Errorf("Expected failed set_field with status 400, got %+v", field)
+	}
+}
+
+func TestTrace_Truncates(t *testing.T) {
+	trace := NewTrace()
+	for i := 0; i < maxTraceCalls+5; i++ {
+		trace.add(Call{Method: "get_contact"})
+	}
+	if len(trace.Calls()) != maxTraceCalls || trace.Dropped() != 5 {
+		t.Errorf("Expected %d calls and 5 dropped, got %d and %d", maxTraceCalls, len(trace.Calls()), trace.Dropped())
+	}
+}
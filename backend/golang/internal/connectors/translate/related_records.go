@@ -0,0 +1,228 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+// relatedKeyPrefix marks a synthetic field key that aggregates records
+// attached to the contact, e.g. "_related.invoice.sum.TotalPaid".
+const relatedKeyPrefix = "_related."
+
+// relatedTypeAliases maps the record type segment of a _related key to the
+// RelatedRecordsConnector list it reads from. "job" is Keap's legacy name for orders.
+var relatedTypeAliases = map[string]string{
+	"invoice":       "invoice",
+	"invoices":      "invoice",
+	"order":         "order",
+	"orders":        "order",
+	"job":           "order",
+	"subscription":  "subscription",
+	"subscriptions": "subscription",
+}
+
+// relatedFieldAliases maps the legacy Keap table field names used by helper
+// configs onto the normalized record fields, per record type.
+var relatedFieldAliases = map[string]map[string]string{
+	"invoice": {
+		"id":           "id",
+		"datecreated":  "created_at",
+		"invoicetotal": "total",
+		"totaldue":     "total",
+		"totalpaid":    "amount_paid",
+		"paystatus":    "status",
+		"duedate":      "due_at",
+	},
+	"order": {
+		"id":          "id",
+		"datecreated": "created_at",
+		"jobtitle":    "title",
+		"ordertotal":  "total",
+		"jobstatus":   "status",
+	},
+	"subscription": {
+		"id":           "id",
+		"startdate":    "created_at",
+		"nextbilldate": "next_bill_at",
+		"enddate":      "ended_at",
+		"billingamt":   "amount",
+		"billingcycle": "interval",
+		"frequency":    "interval_count",
+		"productid":    "product_id",
+	},
+}
+
+// relatedRecords caches the records loaded for each contact and record type
+// so helpers that read several aggregates only list them once.
+type relatedRecords struct {
+	mu    sync.Mutex
+	cache map[string][]map[string]interface{}
+}
+
+func newRelatedRecords() *relatedRecords {
+	return &relatedRecords{cache: make(map[string][]map[string]interface{})}
+}
+
+// resolveRelated evaluates a "_related.<type>.<op>[.<field>]" key where op is
+// count, sum, first or last. "count.nonzero" counts records with a non-zero total.
+func (t *TranslatingConnector) resolveRelated(ctx context.Context, contactID, key string) (interface{}, error) {
+	slug := t.inner.GetMetadata().PlatformSlug
+
+	parts := strings.SplitN(strings.TrimPrefix(key, relatedKeyPrefix), ".", 3)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid related key %q: expected _related.<type>.<count|sum|first|last>.<field>", key)
+	}
+
+	recordType, ok := relatedTypeAliases[strings.ToLower(parts[0])]
+	if !ok {
+		return nil, connectors.NewConnectorError(slug, 501, fmt.Sprintf("related record type %q is not supported", parts[0]), false)
+	}
+	op := strings.ToLower(parts[1])
+	field := ""
+	if len(parts) == 3 {
+		field = parts[2]
+	}
+
+	records, err := t.loadRelated(ctx, contactID, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "count":
+		if strings.EqualFold(field, "nonzero") {
+			count := 0
+			for _, record := range records {
+				if toFloat(recordField(record, recordType, "total")) != 0 {
+					count++
+				}
+			}
+			return count, nil
+		}
+		return len(records), nil
+
+	case "sum":
+		if field == "" {
+			return nil, fmt.Errorf("invalid related key %q: sum requires a field", key)
+		}
+		sum := 0.0
+		for _, record := range records {
+			sum += toFloat(recordField(record, recordType, field))
+		}
+		return sum, nil
+
+	case "first", "last":
+		if field == "" {
+			return nil, fmt.Errorf("invalid related key %q: %s requires a field", key, op)
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+		// Connectors return records oldest first
+		record := records[0]
+		if op == "last" {
+			record = records[len(records)-1]
+		}
+		return recordField(record, recordType, field), nil
+	}
+
+	return nil, fmt.Errorf("invalid related key %q: unknown aggregation %q", key, op)
+}
+
+// loadRelated lists the contact's records of one type as generic maps keyed
+// by the normalized JSON field names.
+func (t *TranslatingConnector) loadRelated(ctx context.Context, contactID, recordType string) ([]map[string]interface{}, error) {
+	cacheKey := contactID + "|" + recordType
+
+	t.related.mu.Lock()
+	defer t.related.mu.Unlock()
+
+	if records, ok := t.related.cache[cacheKey]; ok {
+		return records, nil
+	}
+
+	rc, err := t.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+
+	var list interface{}
+	switch recordType {
+	case "invoice":
+		list, err = rc.ListInvoices(ctx, contactID)
+	case "order":
+		list, err = rc.ListOrders(ctx, contactID)
+	case "subscription":
+		list, err = rc.ListSubscriptions(ctx, contactID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip through JSON so every record type can be addressed by field name
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode related records: %w", err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode related records: %w", err)
+	}
+
+	t.related.cache[cacheKey] = records
+	return records, nil
+}
+
+// recordField looks a field up by legacy alias, normalized name, or the
+// snake_case form of a CamelCase name, in that order.
+func recordField(record map[string]interface{}, recordType, field string) interface{} {
+	if normalized, ok := relatedFieldAliases[recordType][strings.ToLower(field)]; ok {
+		return record[normalized]
+	}
+	if value, ok := record[field]; ok {
+		return value
+	}
+	if value, ok := record[toSnakeCase(field)]; ok {
+		return value
+	}
+	for key, value := range record {
+		if strings.EqualFold(key, field) {
+			return value
+		}
+	}
+	return nil
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
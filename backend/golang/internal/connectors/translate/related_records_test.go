@@ -0,0 +1,233 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+// fakeRelatedConnector serves fixed related records. Methods the tests do not
+// reach are left to the embedded nil connector.
+type fakeRelatedConnector struct {
+	connectors.CRMConnector
+	invoices      []connectors.Invoice
+	orders        []connectors.Order
+	subscriptions []connectors.Subscription
+	listCalls     int
+	fieldsSet     map[string]interface{}
+}
+
+func (f *fakeRelatedConnector) GetMetadata() connectors.ConnectorMetadata {
+	return connectors.ConnectorMetadata{PlatformSlug: "keap"}
+}
+
+func (f *fakeRelatedConnector) ListInvoices(ctx context.Context, contactID string) ([]connectors.Invoice, error) {
+	f.listCalls++
+	return f.invoices, nil
+}
+
+func (f *fakeRelatedConnector) ListOrders(ctx context.Context, contactID string) ([]connectors.Order, error) {
+	f.listCalls++
+	return f.orders, nil
+}
+
+func (f *fakeRelatedConnector) ListSubscriptions(ctx context.Context, contactID string) ([]connectors.Subscription, error) {
+	f.listCalls++
+	return f.subscriptions, nil
+}
+
+func (f *fakeRelatedConnector) SetContactFieldValue(ctx context.Context, contactID, fieldKey string, value interface{}) error {
+	if f.fieldsSet == nil {
+		f.fieldsSet = make(map[string]interface{})
+	}
+	f.fieldsSet[fieldKey] = value
+	return nil
+}
+
+func newFakeRelatedConnector() *fakeRelatedConnector {
+	jan := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	return &fakeRelatedConnector{
+		invoices: []connectors.Invoice{
+			{ID: "1", Total: 100, AmountPaid: 100, Status: "paid", CreatedAt: &jan},
+			{ID: "2", Total: 0, AmountPaid: 0, Status: "void"},
+			{ID: "3", Total: 50.5, AmountPaid: 20, Status: "partial", CreatedAt: &mar},
+		},
+		orders: []connectors.Order{
+			{ID: "10", Title: "Starter kit", Total: 30},
+			{ID: "11", Title: "Refill", Total: 12},
+		},
+		subscriptions: []connectors.Subscription{
+			{ID: "20", ProductID: "p1", Amount: 9.99, Interval: "month", IntervalCount: 1},
+		},
+	}
+}
+
+func TestResolveRelated(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want interface{}
+	}{
+		{"count", "_related.invoice.count", 3},
+		{"count nonzero", "_related.invoice.count.nonzero", 2},
+		{"count nonzero is case-insensitive", "_related.invoice.count.NonZero", 2},
+		{"count plural type", "_related.invoices.count", 3},
+		{"sum by legacy alias", "_related.invoice.sum.TotalPaid", 120.0},
+		{"sum by normalized name", "_related.invoice.sum.total", 150.5},
+		{"first by legacy alias", "_related.invoice.first.InvoiceTotal", 100.0},
+		{"last by legacy alias", "_related.invoice.last.PayStatus", "partial"},
+		{"last by normalized name", "_related.invoice.last.id", "3"},
+		{"job is an order", "_related.job.first.JobTitle", "Starter kit"},
+		{"order sum", "_related.order.sum.OrderTotal", 42.0},
+		{"subscription by snake case", "_related.subscription.last.IntervalCount", 1.0},
+		{"subscription by legacy alias", "_related.subscription.first.BillingAmt", 9.99},
+		{"type is case-insensitive", "_related.Invoice.count", 3},
+		{"unknown field", "_related.invoice.first.Nope", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := NewTranslatingConnector(newFakeRelatedConnector())
+			got, err := tc.resolveRelated(context.Background(), "c1", tt.key)
+			if err != nil {
+				t.Fatalf("resolveRelated(%q): %v", tt.key, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRelated(%q) = %#v, want %#v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRelated_NoRecords(t *testing.T) {
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{"_related.order.count", 0},
+		{"_related.order.sum.total", 0.0},
+		{"_related.order.first.total", nil},
+		{"_related.order.last.total", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			tc := NewTranslatingConnector(&fakeRelatedConnector{})
+			got, err := tc.resolveRelated(context.Background(), "c1", tt.key)
+			if err != nil {
+				t.Fatalf("resolveRelated(%q): %v", tt.key, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRelated(%q) = %#v, want %#v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRelated_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		unsupported bool
+	}{
+		{"missing op", "_related.invoice", false},
+		{"sum without field", "_related.invoice.sum", false},
+		{"first without field", "_related.invoice.first", false},
+		{"unknown op", "_related.invoice.avg.total", false},
+		{"lead", "_related.lead.stage.5", true},
+		{"creditcard", "_related.creditcard.first.last_four", true},
+		{"payment", "_related.payment.last.amount", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := NewTranslatingConnector(newFakeRelatedConnector())
+			_, err := tc.resolveRelated(context.Background(), "c1", tt.key)
+			if err == nil {
+				t.Fatalf("resolveRelated(%q): expected error", tt.key)
+			}
+			var connErr *connectors.ConnectorError
+			if got := errors.As(err, &connErr) && connErr.StatusCode == 501; got != tt.unsupported {
+				t.Errorf("resolveRelated(%q) error %v: unsupported = %v, want %v", tt.key, err, got, tt.unsupported)
+			}
+		})
+	}
+}
+
+func TestResolveRelated_ListsOncePerType(t *testing.T) {
+	inner := newFakeRelatedConnector()
+	tc := NewTranslatingConnector(inner)
+	for _, key := range []string{"_related.invoice.count", "_related.invoice.sum.total", "_related.invoice.last.id"} {
+		if _, err := tc.resolveRelated(context.Background(), "c1", key); err != nil {
+			t.Fatalf("resolveRelated(%q): %v", key, err)
+		}
+	}
+	if inner.listCalls != 1 {
+		t.Errorf("Expected invoices to be listed once, got %d", inner.listCalls)
+	}
+}
+
+func TestSetContactFieldValue_RejectsRelatedKeys(t *testing.T) {
+	inner := newFakeRelatedConnector()
+	tc := NewTranslatingConnector(inner)
+	err := tc.SetContactFieldValue(context.Background(), "c1", "_related.lead.stage.5.update_all", "6")
+	if err == nil {
+		t.Fatal("Expected error writing a related key")
+	}
+	if len(inner.fieldsSet) != 0 {
+		t.Errorf("Related key reached the inner connector: %v", inner.fieldsSet)
+	}
+}
+
+func TestRecordField(t *testing.T) {
+	record := map[string]interface{}{
+		"id":          "7",
+		"total":       25.0,
+		"amount_paid": 10.0,
+		"status":      "paid",
+		"created_at":  "2026-01-10T00:00:00Z",
+		"SourceCRM":   "keap",
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		want  interface{}
+	}{
+		{"legacy alias", "TotalPaid", 10.0},
+		{"legacy alias is case-insensitive", "totalpaid", 10.0},
+		{"legacy alias for another field", "DateCreated", "2026-01-10T00:00:00Z"},
+		{"normalized name", "status", "paid"},
+		{"snake case of CamelCase", "AmountPaid", 10.0},
+		{"case-insensitive fallback", "SOURCECRM", "keap"},
+		{"missing", "Currency", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordField(record, "invoice", tt.field); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recordField(%q) = %#v, want %#v", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordField_AliasesArePerType(t *testing.T) {
+	record := map[string]interface{}{"total": 12.0, "amount": 9.0}
+
+	if got := recordField(record, "order", "OrderTotal"); got != 12.0 {
+		t.Errorf("order OrderTotal = %#v, want 12", got)
+	}
+	if got := recordField(record, "subscription", "BillingAmt"); got != 9.0 {
+		t.Errorf("subscription BillingAmt = %#v, want 9", got)
+	}
+	// BillingAmt is a subscription alias only; invoices fall through to name lookups
+	if got := recordField(record, "invoice", "BillingAmt"); got != nil {
+		t.Errorf("invoice BillingAmt = %#v, want nil", got)
+	}
+}
@@ -2,7 +2,9 @@ package translate
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/myfusionhelper/api/internal/connectors"
 )
@@ -19,6 +21,7 @@ type TranslatingConnector struct {
 	customFields *CustomFieldResolver
 	tagResolver  *TagResolver
 	normalizer   *DataNormalizer
+	related      *relatedRecords
 }
 
 // NewTranslatingConnector wraps a raw CRMConnector with the translation layer.
@@ -32,6 +35,7 @@ func NewTranslatingConnector(inner connectors.CRMConnector) *TranslatingConnecto
 		customFields: NewCustomFieldResolver(inner),
 		tagResolver:  NewTagResolver(inner),
 		normalizer:   NewDataNormalizer(platformSlug),
+		related:      newRelatedRecords(),
 	}
 }
 
@@ -67,6 +71,31 @@ func (t *TranslatingConnector) resolveFieldKey(ctx context.Context, fieldKey str
 // ========== CONTACTS ==========
 
 func (t *TranslatingConnector) GetContacts(ctx context.Context, opts connectors.QueryOptions) (*connectors.ContactList, error) {
+	// Resolve tag names and custom field labels in filters to CRM IDs
+	if opts.TagID != "" {
+		resolvedID, err := t.tagResolver.Resolve(ctx, opts.TagID)
+		if err != nil {
+			return nil, err
+		}
+		opts.TagID = resolvedID
+	}
+	if len(opts.Filters) > 0 {
+		filters := make(map[string]string, len(opts.Filters))
+		for key, value := range opts.Filters {
+			switch {
+			case key == connectors.FilterTag:
+				resolvedID, err := t.tagResolver.Resolve(ctx, value)
+				if err != nil {
+					return nil, err
+				}
+				value = resolvedID
+			case strings.HasPrefix(key, connectors.FilterFieldPrefix):
+				key = connectors.FilterFieldPrefix + t.resolveFieldKey(ctx, strings.TrimPrefix(key, connectors.FilterFieldPrefix))
+			}
+			filters[key] = value
+		}
+		opts.Filters = filters
+	}
 	return t.inner.GetContacts(ctx, opts)
 }
 
@@ -89,6 +118,11 @@ func (t *TranslatingConnector) DeleteContact(ctx context.Context, contactID stri
 // ========== FIELD ACCESS (INTERCEPTED) ==========
 
 func (t *TranslatingConnector) GetContactFieldValue(ctx context.Context, contactID string, fieldKey string) (interface{}, error) {
+	// Aggregate over related records (invoices, orders, subscriptions)
+	if strings.HasPrefix(fieldKey, relatedKeyPrefix) {
+		return t.resolveRelated(ctx, contactID, fieldKey)
+	}
+
 	// Translate field key
 	resolvedKey := t.resolveFieldKey(ctx, fieldKey)
 
@@ -108,6 +142,12 @@ func (t *TranslatingConnector) GetContactFieldValue(ctx context.Context, contact
 }
 
 func (t *TranslatingConnector) SetContactFieldValue(ctx context.Context, contactID string, fieldKey string, value interface{}) error {
+	// Related record aggregates are read-only; never pass them on as a field name
+	if strings.HasPrefix(fieldKey, relatedKeyPrefix) {
+		slug := t.inner.GetMetadata().PlatformSlug
+		return connectors.NewConnectorError(slug, 501, fmt.Sprintf("related record key %q cannot be written", fieldKey), false)
+	}
+
 	// Translate field key
 	resolvedKey := t.resolveFieldKey(ctx, fieldKey)
 
@@ -165,6 +205,56 @@ func (t *TranslatingConnector) SetOptInStatus(ctx context.Context, contactID str
 	return t.inner.SetOptInStatus(ctx, contactID, optIn, reason)
 }
 
+// ========== NOTES ==========
+
+// CreateNote forwards to the inner connector when it supports notes.
+func (t *TranslatingConnector) CreateNote(ctx context.Context, contactID string, note connectors.NoteInput) error {
+	nc, ok := t.inner.(connectors.NoteConnector)
+	if !ok {
+		slug := t.inner.GetMetadata().PlatformSlug
+		return connectors.NewConnectorError(slug, 501, slug+" does not support contact notes", false)
+	}
+	return nc.CreateNote(ctx, contactID, note)
+}
+
+// ========== RELATED RECORDS ==========
+
+// ListInvoices forwards to the inner connector when it supports related records.
+func (t *TranslatingConnector) ListInvoices(ctx context.Context, contactID string) ([]connectors.Invoice, error) {
+	rc, err := t.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	return rc.ListInvoices(ctx, contactID)
+}
+
+// ListOrders forwards to the inner connector when it supports related records.
+func (t *TranslatingConnector) ListOrders(ctx context.Context, contactID string) ([]connectors.Order, error) {
+	rc, err := t.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	return rc.ListOrders(ctx, contactID)
+}
+
+// ListSubscriptions forwards to the inner connector when it supports related records.
+func (t *TranslatingConnector) ListSubscriptions(ctx context.Context, contactID string) ([]connectors.Subscription, error) {
+	rc, err := t.relatedRecordsConnector()
+	if err != nil {
+		return nil, err
+	}
+	return rc.ListSubscriptions(ctx, contactID)
+}
+
+func (t *TranslatingConnector) relatedRecordsConnector() (connectors.RelatedRecordsConnector, error) {
+	rc, ok := t.inner.(connectors.RelatedRecordsConnector)
+	if !ok {
+		slug := t.inner.GetMetadata().PlatformSlug
+		return nil, connectors.NewConnectorError(slug, 501, slug+" does not support related records", false)
+	}
+	return rc, nil
+}
+
 // ========== HEALTH & METADATA ==========
 
 func (t *TranslatingConnector) TestConnection(ctx context.Context) error {
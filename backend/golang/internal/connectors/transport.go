@@ -0,0 +1,394 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRequestAttempts bounds how many times one API call is sent
+	maxRequestAttempts = 4
+
+	// maxRetryAfter is the longest Retry-After the transport will sleep through.
+	// Longer throttles are returned to the caller as retryable errors.
+	maxRetryAfter = time.Minute
+
+	// maxLimiters bounds how many connections' limiters a process keeps
+	maxLimiters = 1000
+
+	// limiterIdleTTL is how long an unused limiter is kept. By then every
+	// bucket has refilled and any pause has passed, so dropping it loses no
+	// budget.
+	limiterIdleTTL = time.Hour
+)
+
+// retryBaseDelay is the first backoff delay; each retry doubles it up to retryMaxDelay
+var (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// RateLimit is the client-side request budget for one connection. Zero fields
+// are unlimited.
+type RateLimit struct {
+	RequestsPerSecond int
+	RequestsPerMinute int
+	RequestsPerHour   int
+	// Burst sizes the per-second bucket, so it needs RequestsPerSecond;
+	// longer windows allow their whole budget at once
+	Burst int
+}
+
+// Validate rejects a budget the limiter would not apply as written
+func (r RateLimit) Validate() error {
+	if r.Burst > 0 && r.RequestsPerSecond <= 0 {
+		return fmt.Errorf("burst of %d needs a requests per second limit", r.Burst)
+	}
+	return nil
+}
+
+// apiRequest is one vendor API call made through the shared transport
+type apiRequest struct {
+	Method string
+	URL    string
+	Body   []byte
+	Header http.Header
+}
+
+// newJSONRequest builds a request with a JSON-encoded body and JSON headers
+func newJSONRequest(method, url string, body interface{}) (apiRequest, error) {
+	req := apiRequest{Method: method, URL: url, Header: make(http.Header)}
+	if body != nil {
+		bodyJSON, err := json.Marshal(body)
+		if err != nil {
+			return req, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		req.Body = bodyJSON
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// transport is the HTTP layer shared by every connector. It waits on the
+// connection's rate limiter before each attempt, honors Retry-After, and
+// retries throttled and transient failures with jittered backoff.
+type transport struct {
+	platform string // slug reported on ConnectorError
+	name     string // display name used in error messages
+	client   *http.Client
+	limiter  *rateLimiter
+}
+
+// do sends req and decodes a 2xx JSON response into result. A 429 is retried
+// for any method because the vendor did not process the request; network
+// errors and 5xx responses are only retried for idempotent methods.
+func (t *transport) do(ctx context.Context, req apiRequest, result interface{}) error {
+	for attempt := 1; ; attempt++ {
+		delay, err := t.attempt(ctx, req, result, attempt)
+		if err == nil {
+			return nil
+		}
+		if delay < 0 || attempt >= maxRequestAttempts {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends req once. On failure it returns how long to wait before
+// retrying, or a negative delay when the error should not be retried.
+func (t *transport) attempt(ctx context.Context, req apiRequest, result interface{}, n int) (time.Duration, error) {
+	if t.limiter != nil {
+		if err := t.limiter.wait(ctx); err != nil {
+			return -1, NewConnectorError(t.platform, http.StatusTooManyRequests, fmt.Sprintf("rate limit wait aborted: %v", err), true)
+		}
+	}
+
+	var bodyReader io.Reader
+	if req.Body != nil {
+		bodyReader = bytes.NewReader(req.Body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bodyReader)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range req.Header {
+		httpReq.Header[key] = values
+	}
+
+	retryDelay := time.Duration(-1)
+	if isIdempotent(req.Method) {
+		retryDelay = backoff(n)
+	}
+
+	client := t.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		observeRequest(ctx, n, 0)
+		return retryDelay, NewConnectorError(t.platform, 0, fmt.Sprintf("request failed: %v", err), true)
+	}
+	defer resp.Body.Close()
+	observeRequest(ctx, n, resp.StatusCode)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryDelay, NewConnectorError(t.platform, resp.StatusCode, "failed to read response", true)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		connErr := NewConnectorError(t.platform, resp.StatusCode,
+			fmt.Sprintf("%s API error (%d): %s", t.name, resp.StatusCode, string(respBody)), retryable)
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			retryDelay = backoff(n)
+			if after, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				if after > maxRetryAfter {
+					return -1, connErr
+				}
+				retryDelay = after
+				// Hold back every request on this connection, not just this one
+				if t.limiter != nil {
+					t.limiter.pause(time.Now().Add(after))
+				}
+			}
+		case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+			// retryDelay already reflects whether the method is safe to resend
+		default:
+			retryDelay = -1
+		}
+		return retryDelay, connErr
+	}
+
+	if result != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return -1, fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+
+	return 0, nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff returns an exponential delay with equal jitter after the nth attempt
+func backoff(n int) time.Duration {
+	delay := retryBaseDelay << (n - 1)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		after := time.Until(at)
+		if after < 0 {
+			after = 0
+		}
+		return after, true
+	}
+	return 0, false
+}
+
+// ========== RATE LIMITER ==========
+
+// rateLimiter is a token bucket per configured window. A request needs a token
+// from every bucket.
+type rateLimiter struct {
+	mu          sync.Mutex
+	limit       RateLimit
+	buckets     []*tokenBucket
+	pausedUntil time.Time
+	lastUsed    time.Time
+}
+
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	rate     float64 // tokens added per second
+	last     time.Time
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*rateLimiter)
+)
+
+// limiterFor returns the rate limiter for a connection. Connector instances
+// for the same connection in one process share a limiter so concurrent jobs
+// draw from the same budget. At most maxLimiters are kept; idle ones are
+// dropped first.
+func limiterFor(platform string, config ConnectorConfig) *rateLimiter {
+	if config.RateLimit == (RateLimit{}) {
+		return nil
+	}
+	if config.ConnectionID == "" {
+		return newRateLimiter(config.RateLimit)
+	}
+
+	key := platform + "/" + config.ConnectionID
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	if limiter, ok := limiters[key]; ok && limiter.limit == config.RateLimit {
+		limiter.touch(time.Now())
+		return limiter
+	}
+	if _, ok := limiters[key]; !ok && len(limiters) >= maxLimiters {
+		evictLimiters(time.Now())
+	}
+	limiter := newRateLimiter(config.RateLimit)
+	limiters[key] = limiter
+	return limiter
+}
+
+// evictLimiters drops idle limiters and, if that frees no room, the least
+// recently used one. The caller holds limitersMu.
+func evictLimiters(now time.Time) {
+	oldestKey, oldest := "", time.Time{}
+	for key, limiter := range limiters {
+		limiter.mu.Lock()
+		used := limiter.lastUsed
+		limiter.mu.Unlock()
+
+		if now.Sub(used) >= limiterIdleTTL {
+			delete(limiters, key)
+			continue
+		}
+		if oldestKey == "" || used.Before(oldest) {
+			oldestKey, oldest = key, used
+		}
+	}
+	if len(limiters) >= maxLimiters {
+		delete(limiters, oldestKey)
+	}
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	now := time.Now()
+	l := &rateLimiter{limit: limit, lastUsed: now}
+
+	addBucket := func(requests int, window time.Duration, capacity int) {
+		if requests <= 0 {
+			return
+		}
+		if capacity <= 0 {
+			capacity = requests
+		}
+		l.buckets = append(l.buckets, &tokenBucket{
+			capacity: float64(capacity),
+			tokens:   float64(capacity),
+			rate:     float64(requests) / window.Seconds(),
+			last:     now,
+		})
+	}
+	// Burst sizes the per-second bucket; longer windows allow their whole
+	// budget at once
+	addBucket(limit.RequestsPerSecond, time.Second, limit.Burst)
+	addBucket(limit.RequestsPerMinute, time.Minute, 0)
+	addBucket(limit.RequestsPerHour, time.Hour, 0)
+	return l
+}
+
+// wait blocks until a token is available in every bucket or ctx is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.lastUsed = now
+		delay := l.pausedUntil.Sub(now)
+		if delay <= 0 {
+			delay = 0
+			for _, b := range l.buckets {
+				b.refill(now)
+				if b.tokens < 1 {
+					if need := time.Duration((1 - b.tokens) / b.rate * float64(time.Second)); need > delay {
+						delay = need
+					}
+				}
+			}
+			if delay == 0 {
+				for _, b := range l.buckets {
+					b.tokens--
+				}
+				l.mu.Unlock()
+				return nil
+			}
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// touch records that the limiter is in use
+func (l *rateLimiter) touch(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastUsed = now
+}
+
+// pause holds every request until the given time, e.g. after a Retry-After
+func (l *rateLimiter) pause(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
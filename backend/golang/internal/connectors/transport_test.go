@@ -0,0 +1,181 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestTransport_Retries tests Retry-After handling and which failures are retried
+func TestTransport_Retries(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = 500 * time.Millisecond }()
+
+	t.Run("honors Retry-After on 429", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"ok": true}`))
+		}))
+		defer server.Close()
+
+		tr := transport{platform: "test", name: "Test", client: server.Client()}
+		req, _ := newJSONRequest("POST", server.URL+"/contacts", map[string]string{"a": "b"})
+
+		var result struct {
+			OK bool `json:"ok"`
+		}
+		if err := tr.do(context.Background(), req, &result); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if requests != 2 || !result.OK {
+			t.Errorf("Expected throttled POST to be retried once, got %d requests", requests)
+		}
+	})
+
+	t.Run("retries idempotent requests on 5xx", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		tr := transport{platform: "test", name: "Test", client: server.Client()}
+		req, _ := newJSONRequest("GET", server.URL+"/contacts", nil)
+
+		err := tr.do(context.Background(), req, nil)
+		connErr, ok := err.(*ConnectorError)
+		if !ok || connErr.StatusCode != http.StatusServiceUnavailable || !connErr.Retryable {
+			t.Fatalf("Expected retryable 503 ConnectorError, got %v", err)
+		}
+		if requests != maxRequestAttempts {
+			t.Errorf("Expected %d attempts, got %d", maxRequestAttempts, requests)
+		}
+	})
+
+	t.Run("does not resend non-idempotent requests on 5xx", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		tr := transport{platform: "test", name: "Test", client: server.Client()}
+		req, _ := newJSONRequest("POST", server.URL+"/contacts", nil)
+
+		if err := tr.do(context.Background(), req, nil); err == nil {
+			t.Fatal("Expected error for server failure")
+		}
+		if requests != 1 {
+			t.Errorf("Expected a single attempt, got %d", requests)
+		}
+	})
+
+	t.Run("gives up when Retry-After is too long", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		tr := transport{platform: "test", name: "Test", client: server.Client()}
+		req, _ := newJSONRequest("GET", server.URL+"/contacts", nil)
+
+		err := tr.do(context.Background(), req, nil)
+		connErr, ok := err.(*ConnectorError)
+		if !ok || connErr.StatusCode != http.StatusTooManyRequests || !connErr.Retryable {
+			t.Fatalf("Expected retryable 429 ConnectorError, got %v", err)
+		}
+		if requests != 1 {
+			t.Errorf("Expected a single attempt, got %d", requests)
+		}
+	})
+}
+
+// TestRateLimiter tests the token bucket budget and connection sharing
+func TestRateLimiter(t *testing.T) {
+	t.Run("spaces requests beyond the burst", func(t *testing.T) {
+		limiter := newRateLimiter(RateLimit{RequestsPerSecond: 20, Burst: 2})
+
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			if err := limiter.wait(context.Background()); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+		// Two requests use the burst, the next two wait ~50ms each
+		if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+			t.Errorf("Expected limiter to delay requests past the burst, took %v", elapsed)
+		}
+	})
+
+	t.Run("cancelled wait returns context error", func(t *testing.T) {
+		limiter := newRateLimiter(RateLimit{RequestsPerHour: 1})
+		limiter.wait(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := limiter.wait(ctx); err != context.DeadlineExceeded {
+			t.Errorf("Expected deadline exceeded, got %v", err)
+		}
+	})
+
+	t.Run("shared per connection", func(t *testing.T) {
+		config := ConnectorConfig{ConnectionID: "conn-1", RateLimit: RateLimit{RequestsPerSecond: 5}}
+		if limiterFor(keapSlug, config) != limiterFor(keapSlug, config) {
+			t.Error("Expected connectors for the same connection to share a limiter")
+		}
+		if limiterFor(keapSlug, ConnectorConfig{ConnectionID: "conn-1"}) != nil {
+			t.Error("Expected no limiter without a rate limit")
+		}
+	})
+
+	t.Run("evicts idle and least recently used limiters", func(t *testing.T) {
+		saved := limiters
+		limiters = make(map[string]*rateLimiter)
+		defer func() { limiters = saved }()
+
+		limit := RateLimit{RequestsPerSecond: 5}
+		idle := limiterFor(keapSlug, ConnectorConfig{ConnectionID: "idle", RateLimit: limit})
+		idle.lastUsed = time.Now().Add(-limiterIdleTTL)
+		for i := 1; i < maxLimiters; i++ {
+			limiterFor(keapSlug, ConnectorConfig{ConnectionID: fmt.Sprintf("conn-%d", i), RateLimit: limit})
+		}
+
+		limiterFor(keapSlug, ConnectorConfig{ConnectionID: "new-1", RateLimit: limit})
+		if _, ok := limiters[keapSlug+"/idle"]; ok || len(limiters) != maxLimiters {
+			t.Fatalf("Expected the idle limiter to make room, got %d limiters", len(limiters))
+		}
+
+		limiters[keapSlug+"/conn-1"].lastUsed = time.Now().Add(-time.Minute)
+		limiterFor(keapSlug, ConnectorConfig{ConnectionID: "new-2", RateLimit: limit})
+		if _, ok := limiters[keapSlug+"/conn-1"]; ok || len(limiters) != maxLimiters {
+			t.Errorf("Expected the least recently used limiter to make room, got %d limiters", len(limiters))
+		}
+	})
+}
+
+// TestRateLimitValidate tests that a burst needs a per-second limit
+func TestRateLimitValidate(t *testing.T) {
+	if err := (RateLimit{RequestsPerSecond: 10, Burst: 20}).Validate(); err != nil {
+		t.Errorf("Expected a per-second burst to be valid, got %v", err)
+	}
+	if err := (RateLimit{RequestsPerMinute: 100}).Validate(); err != nil {
+		t.Errorf("Expected a limit without burst to be valid, got %v", err)
+	}
+	if err := (RateLimit{RequestsPerMinute: 100, Burst: 20}).Validate(); err == nil {
+		t.Error("Expected a burst without a per-second limit to be rejected")
+	}
+}
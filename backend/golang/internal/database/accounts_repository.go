@@ -2,8 +2,11 @@ package database
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/myfusionhelper/api/internal/types"
@@ -47,3 +50,29 @@ func (r *AccountsRepository) Create(ctx context.Context, account *types.Account)
 func (r *AccountsRepository) Update(ctx context.Context, account *types.Account) error {
 	return putItem(ctx, r.client, r.tableName, account)
 }
+
+// IncrementMonthlyExecutions atomically adds delta to usage.monthly_executions
+// and returns the new value.
+func (r *AccountsRepository) IncrementMonthlyExecutions(ctx context.Context, accountID string, delta int) (int, error) {
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        &r.tableName,
+		Key:              stringKey("account_id", accountID),
+		UpdateExpression: aws.String("SET usage.monthly_executions = if_not_exists(usage.monthly_executions, :zero) + :delta"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":zero":  numVal("0"),
+			":delta": numVal(strconv.Itoa(delta)),
+		},
+		ReturnValues: ddbtypes.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("increment monthly executions: %w", err)
+	}
+
+	var updated struct {
+		Usage types.AccountUsage `dynamodbav:"usage"`
+	}
+	if err := attributevalue.UnmarshalMap(result.Attributes, &updated); err != nil {
+		return 0, fmt.Errorf("unmarshal monthly executions: %w", err)
+	}
+	return updated.Usage.MonthlyExecutions, nil
+}
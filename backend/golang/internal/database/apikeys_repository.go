@@ -75,7 +75,23 @@ func (r *APIKeysRepository) Revoke(ctx context.Context, keyID string) error {
 		ConditionExpression: aws.String("attribute_exists(key_id)"),
 	})
 	if err != nil {
-		return fmt.Errorf("revoke api key: %w", err)
+		return fmt.Errorf("revoke api key: %w", conditionFailed(err))
+	}
+	return nil
+}
+
+// UpdateLastUsed records when the API key last authorized a request.
+func (r *APIKeysRepository) UpdateLastUsed(ctx context.Context, keyID string, at time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        &r.tableName,
+		Key:              stringKey("key_id", keyID),
+		UpdateExpression: aws.String("SET last_used_at = :now"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":now": stringVal(at.UTC().Format(time.RFC3339)),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update api key last used: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// batchSavedAttributes are the batch attributes Save writes. succeeded and
+// failed are only ever incremented by CompleteItem, so concurrent results
+// from the workers are not overwritten by the runner.
+var batchSavedAttributes = []string{
+	"status", "status_reason", "rate_per_minute", "total", "dispatched",
+	"cursor", "pending", "source_done", "last_dispatch_at", "updated_at", "completed_at",
+}
+
+// BatchesRepository provides access to the batches and batch items DynamoDB tables.
+type BatchesRepository struct {
+	client         *dynamodb.Client
+	tableName      string
+	itemsTableName string
+}
+
+// NewBatchesRepository creates a new BatchesRepository.
+func NewBatchesRepository(client *dynamodb.Client, tableName, itemsTableName string) *BatchesRepository {
+	return &BatchesRepository{client: client, tableName: tableName, itemsTableName: itemsTableName}
+}
+
+// GetByID fetches a batch by its batch_id with a consistent read, so the
+// runner always sees the latest version.
+func (r *BatchesRepository) GetByID(ctx context.Context, batchID string) (*types.Batch, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.tableName,
+		Key:            stringKey("batch_id", batchID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var batch types.Batch
+	if err := attributevalue.UnmarshalMap(result.Item, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListByAccount fetches batches for an account using the AccountIdCreatedAtIndex GSI
+// with cursor-based pagination.
+func (r *BatchesRepository) ListByAccount(ctx context.Context, accountID string, limit int, cursor string) ([]types.Batch, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              aws.String("AccountIdCreatedAtIndex"),
+		KeyConditionExpression: aws.String("account_id = :account_id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":account_id": stringVal(accountID),
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(int32(limit)),
+	}
+	return queryPage[types.Batch](ctx, r.client, input, cursor)
+}
+
+// ListRunning fetches running batches from the sparse ActiveIndex GSI, which
+// only holds batches whose active attribute is set.
+func (r *BatchesRepository) ListRunning(ctx context.Context) ([]types.Batch, error) {
+	var batches []types.Batch
+	var startKey map[string]ddbtypes.AttributeValue
+	for {
+		result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              &r.tableName,
+			IndexName:              aws.String("ActiveIndex"),
+			KeyConditionExpression: aws.String("active = :active"),
+			ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+				":active": stringVal("1"),
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list running batches: %w", err)
+		}
+		for _, item := range result.Items {
+			var batch types.Batch
+			if err := attributevalue.UnmarshalMap(item, &batch); err != nil {
+				return nil, err
+			}
+			batches = append(batches, batch)
+		}
+		if result.LastEvaluatedKey == nil {
+			return batches, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
+// Create inserts a new batch record.
+func (r *BatchesRepository) Create(ctx context.Context, batch *types.Batch) error {
+	av, err := attributevalue.MarshalMap(batch)
+	if err != nil {
+		return err
+	}
+	if batch.Status == "running" {
+		av["active"] = stringVal("1")
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(batch_id)"),
+	})
+	return conditionFailed(err)
+}
+
+// Save updates the runner-owned attributes of a batch under an optimistic
+// lock on its version. Empty attributes are removed, and the active attribute
+// is kept only while the batch is running.
+func (r *BatchesRepository) Save(ctx context.Context, batch *types.Batch) error {
+	av, err := attributevalue.MarshalMap(batch)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]string{"#v": "version", "#active": "active"}
+	values := map[string]ddbtypes.AttributeValue{
+		":version": numVal(strconv.Itoa(batch.Version)),
+		":next":    numVal(strconv.Itoa(batch.Version + 1)),
+	}
+	set := "SET #v = :next"
+	remove := ""
+	for i, attr := range batchSavedAttributes {
+		name := "#a" + strconv.Itoa(i)
+		names[name] = attr
+		if value, ok := av[attr]; ok {
+			set += ", " + name + " = :a" + strconv.Itoa(i)
+			values[":a"+strconv.Itoa(i)] = value
+		} else {
+			remove += ", " + name
+		}
+	}
+	if batch.Status == "running" {
+		set += ", #active = :active"
+		values[":active"] = stringVal("1")
+	} else {
+		remove += ", #active"
+	}
+	expr := set
+	if remove != "" {
+		expr += " REMOVE " + remove[2:]
+	}
+
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.tableName,
+		Key:                       stringKey("batch_id", batch.BatchID),
+		UpdateExpression:          &expr,
+		ConditionExpression:       aws.String("#v = :version"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		return conditionFailed(err)
+	}
+	batch.Version++
+	return nil
+}
+
+// AddItem inserts a batch item unless the contact already has one.
+func (r *BatchesRepository) AddItem(ctx context.Context, item *types.BatchItem) error {
+	return putItemWithCondition(ctx, r.client, r.itemsTableName, item, "attribute_not_exists(contact_id)")
+}
+
+// CompleteItem marks a queued item completed or failed and increments the
+// matching count on the batch in one transaction, so a redelivered result is
+// counted once.
+func (r *BatchesRepository) CompleteItem(ctx context.Context, batchID, contactID, status, errMsg string, completedAt time.Time) (bool, error) {
+	counter := "succeeded"
+	if status != "completed" {
+		counter = "failed"
+	}
+
+	itemUpdate := "SET #s = :status, completed_at = :completed_at"
+	itemNames := map[string]string{"#s": "status"}
+	itemValues := map[string]ddbtypes.AttributeValue{
+		":status":       stringVal(status),
+		":queued":       stringVal("queued"),
+		":completed_at": stringVal(completedAt.UTC().Format(time.RFC3339)),
+	}
+	if errMsg != "" {
+		itemUpdate += ", #e = :error"
+		itemNames["#e"] = "error"
+		itemValues[":error"] = stringVal(errMsg)
+	}
+
+	_, err := r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []ddbtypes.TransactWriteItem{
+			{Update: &ddbtypes.Update{
+				TableName: &r.itemsTableName,
+				Key: map[string]ddbtypes.AttributeValue{
+					"batch_id":   stringVal(batchID),
+					"contact_id": stringVal(contactID),
+				},
+				UpdateExpression:          &itemUpdate,
+				ConditionExpression:       aws.String("#s = :queued"),
+				ExpressionAttributeNames:  itemNames,
+				ExpressionAttributeValues: itemValues,
+			}},
+			{Update: &ddbtypes.Update{
+				TableName:                &r.tableName,
+				Key:                      stringKey("batch_id", batchID),
+				UpdateExpression:         aws.String("ADD #c :one"),
+				ConditionExpression:      aws.String("attribute_exists(batch_id)"),
+				ExpressionAttributeNames: map[string]string{"#c": counter},
+				ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+					":one": numVal("1"),
+				},
+			}},
+		},
+	})
+	if err == nil {
+		return true, nil
+	}
+
+	var cancelled *ddbtypes.TransactionCanceledException
+	if errors.As(err, &cancelled) && len(cancelled.CancellationReasons) > 0 &&
+		aws.ToString(cancelled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+		return false, nil
+	}
+	return false, fmt.Errorf("complete batch item: %w", err)
+}
+
+// ListItems fetches a batch's items with cursor-based pagination, filtered
+// by status when one is given.
+func (r *BatchesRepository) ListItems(ctx context.Context, batchID, status string, limit int, cursor string) ([]types.BatchItem, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              &r.itemsTableName,
+		KeyConditionExpression: aws.String("batch_id = :batch_id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":batch_id": stringVal(batchID),
+		},
+		Limit: aws.Int32(int32(limit)),
+	}
+	if status != "" {
+		input.FilterExpression = aws.String("#s = :status")
+		input.ExpressionAttributeNames = map[string]string{"#s": "status"}
+		input.ExpressionAttributeValues[":status"] = stringVal(status)
+	}
+	return queryPage[types.BatchItem](ctx, r.client, input, cursor)
+}
+
+// queryPage runs one page of a query starting after cursor and returns the
+// cursor for the next page.
+func queryPage[T any](ctx context.Context, client *dynamodb.Client, input *dynamodb.QueryInput, cursor string) ([]T, string, error) {
+	if cursor != "" {
+		startKey, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor: %w", err)
+		}
+		input.ExclusiveStartKey = startKey
+	}
+
+	result, err := client.Query(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items := make([]T, 0, len(result.Items))
+	for _, item := range result.Items {
+		var t T
+		if err := attributevalue.UnmarshalMap(item, &t); err != nil {
+			return nil, "", err
+		}
+		items = append(items, t)
+	}
+
+	var nextCursor string
+	if result.LastEvaluatedKey != nil {
+		nextCursor, err = encodeCursor(result.LastEvaluatedKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("encode cursor: %w", err)
+		}
+	}
+	return items, nextCursor, nil
+}
@@ -38,6 +38,14 @@ type TableNames struct {
 	Executions               string
 	Platforms                string
 	OAuthStates              string
+	RateLimits               string
+	EmailLogs                string
+	Batches                  string
+	BatchItems               string
+	HelperVersions           string
+	DelayedExecutions        string
+	ConnectionBreakers       string
+	WorkflowRuns             string
 }
 
 // NewTableNames reads table names from environment variables.
@@ -53,6 +61,14 @@ func NewTableNames() TableNames {
 		Executions:              os.Getenv("EXECUTIONS_TABLE"),
 		Platforms:               os.Getenv("PLATFORMS_TABLE"),
 		OAuthStates:             os.Getenv("OAUTH_STATES_TABLE"),
+		RateLimits:              os.Getenv("RATE_LIMITS_TABLE"),
+		EmailLogs:               os.Getenv("EMAIL_LOGS_TABLE"),
+		Batches:                 os.Getenv("BATCHES_TABLE"),
+		BatchItems:              os.Getenv("BATCH_ITEMS_TABLE"),
+		HelperVersions:          os.Getenv("HELPER_VERSIONS_TABLE"),
+		DelayedExecutions:       os.Getenv("DELAYED_EXECUTIONS_TABLE"),
+		ConnectionBreakers:      os.Getenv("CONNECTION_BREAKERS_TABLE"),
+		WorkflowRuns:            os.Getenv("WORKFLOW_RUNS_TABLE"),
 	}
 }
 
@@ -92,6 +108,7 @@ func putItem(ctx context.Context, client *dynamodb.Client, tableName string, ite
 }
 
 // putItemWithCondition marshals an item and writes it with a condition expression.
+// Returns ErrConditionFailed when the condition does not hold.
 func putItemWithCondition(ctx context.Context, client *dynamodb.Client, tableName string, item interface{}, condition string) error {
 	av, err := attributevalue.MarshalMap(item)
 	if err != nil {
@@ -103,7 +120,10 @@ func putItemWithCondition(ctx context.Context, client *dynamodb.Client, tableNam
 		Item:                av,
 		ConditionExpression: &condition,
 	})
-	return err
+	if err != nil {
+		return conditionFailed(err)
+	}
+	return nil
 }
 
 // queryIndex queries a GSI and unmarshals the results into a slice of T.
@@ -124,6 +144,29 @@ func queryIndex[T any](ctx context.Context, client *dynamodb.Client, input *dyna
 	return items, nil
 }
 
+// scanAll scans the whole table, following pages, and unmarshals the items
+// that pass the input's filter into a slice of T.
+func scanAll[T any](ctx context.Context, client *dynamodb.Client, input *dynamodb.ScanInput) ([]T, error) {
+	items := make([]T, 0)
+	for {
+		result, err := client.Scan(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			var t T
+			if err := attributevalue.UnmarshalMap(item, &t); err != nil {
+				return nil, err
+			}
+			items = append(items, t)
+		}
+		if result.LastEvaluatedKey == nil {
+			return items, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
 // querySingleItem queries and returns the first result, or nil if none found.
 func querySingleItem[T any](ctx context.Context, client *dynamodb.Client, input *dynamodb.QueryInput) (*T, error) {
 	limit := int32(1)
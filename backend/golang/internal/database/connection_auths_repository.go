@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/myfusionhelper/api/internal/types"
@@ -22,9 +23,27 @@ func NewConnectionAuthsRepository(client *dynamodb.Client, tableName string) *Co
 	return &ConnectionAuthsRepository{client: client, tableName: tableName}
 }
 
-// GetByID fetches a connection auth by its auth_id (primary key).
+// GetByID fetches a connection auth by its auth_id (primary key). The read is
+// strongly consistent so a token refresh that lost a race sees the winner's
+// credentials.
 func (r *ConnectionAuthsRepository) GetByID(ctx context.Context, authID string) (*types.PlatformConnectionAuth, error) {
-	return getItem[types.PlatformConnectionAuth](ctx, r.client, r.tableName, stringKey("auth_id", authID))
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.tableName,
+		Key:            stringKey("auth_id", authID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var auth types.PlatformConnectionAuth
+	if err := attributevalue.UnmarshalMap(result.Item, &auth); err != nil {
+		return nil, err
+	}
+	return &auth, nil
 }
 
 // GetByConnectionID fetches a connection auth by connection_id using the ConnectionIdIndex GSI.
@@ -73,3 +92,101 @@ func (r *ConnectionAuthsRepository) Revoke(ctx context.Context, authID string) e
 	}
 	return nil
 }
+
+// UpdateCredentials stores refreshed OAuth credentials, conditioned on the
+// stored version still being expectedVersion. Records written before
+// versioning was introduced have no version attribute and match version 0.
+// Returns ErrConditionFailed when another writer got there first.
+func (r *ConnectionAuthsRepository) UpdateCredentials(ctx context.Context, auth *types.PlatformConnectionAuth, expectedVersion int) error {
+	condition := "#v = :expected"
+	if expectedVersion == 0 {
+		condition = "(attribute_not_exists(#v) OR #v = :expected)"
+	}
+
+	var lastRefreshAt int64
+	if auth.LastRefreshAt != nil {
+		lastRefreshAt = *auth.LastRefreshAt
+	}
+
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           &r.tableName,
+		Key:                 stringKey("auth_id", auth.AuthID),
+		UpdateExpression:    aws.String("SET access_token = :at, refresh_token = :rt, expires_at = :ea, #v = :next, #s = :status, refresh_attempts = :attempts, last_refresh_at = :last_refresh_at, updated_at = :updated_at REMOVE last_refresh_error"),
+		ConditionExpression: aws.String(condition),
+		ExpressionAttributeNames: map[string]string{
+			"#v": "version",
+			"#s": "status",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":at":              stringVal(auth.AccessToken),
+			":rt":              stringVal(auth.RefreshToken),
+			":ea":              numVal(fmt.Sprintf("%d", auth.ExpiresAt)),
+			":next":            numVal(fmt.Sprintf("%d", auth.Version)),
+			":expected":        numVal(fmt.Sprintf("%d", expectedVersion)),
+			":status":          stringVal(auth.Status),
+			":attempts":        numVal(fmt.Sprintf("%d", auth.RefreshAttempts)),
+			":last_refresh_at": numVal(fmt.Sprintf("%d", lastRefreshAt)),
+			":updated_at":      numVal(fmt.Sprintf("%d", auth.UpdatedAt)),
+		},
+	})
+	if err != nil {
+		return conditionFailed(err)
+	}
+	return nil
+}
+
+// RecordRefreshFailure increments refresh_attempts and stores the error of a
+// failed token refresh.
+func (r *ConnectionAuthsRepository) RecordRefreshFailure(ctx context.Context, authID, message string) error {
+	nowStr := fmt.Sprintf("%d", time.Now().UTC().Unix())
+
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        &r.tableName,
+		Key:              stringKey("auth_id", authID),
+		UpdateExpression: aws.String("SET refresh_attempts = if_not_exists(refresh_attempts, :zero) + :one, last_refresh_at = :now, last_refresh_error = :err, updated_at = :now"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":zero": numVal("0"),
+			":one":  numVal("1"),
+			":now":  numVal(nowStr),
+			":err":  stringVal(message),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("record refresh failure: %w", err)
+	}
+	return nil
+}
+
+// ListExpiring scans for active auths with a refresh_token whose expires_at
+// is set and before the given time.
+func (r *ConnectionAuthsRepository) ListExpiring(ctx context.Context, before time.Time) ([]types.PlatformConnectionAuth, error) {
+	return scanAll[types.PlatformConnectionAuth](ctx, r.client, &dynamodb.ScanInput{
+		TableName:                &r.tableName,
+		FilterExpression:         aws.String("#s = :active AND attribute_exists(refresh_token) AND expires_at > :zero AND expires_at < :before"),
+		ExpressionAttributeNames: map[string]string{"#s": "status"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":active": stringVal("active"),
+			":zero":   numVal("0"),
+			":before": numVal(fmt.Sprintf("%d", before.Unix())),
+		},
+	})
+}
+
+// MarkExpired sets the status to "expired" and stores the refresh error.
+func (r *ConnectionAuthsRepository) MarkExpired(ctx context.Context, authID, message string) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                &r.tableName,
+		Key:                      stringKey("auth_id", authID),
+		UpdateExpression:         aws.String("SET #s = :expired, last_refresh_error = :err, updated_at = :updated_at"),
+		ExpressionAttributeNames: map[string]string{"#s": "status"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":expired":    stringVal("expired"),
+			":err":        stringVal(message),
+			":updated_at": numVal(fmt.Sprintf("%d", time.Now().UTC().Unix())),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("mark connection auth expired: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// probedBreakerStates are the partitions of the sparse ProbeIndex GSI: only
+// breakers in these states carry next_probe_at
+var probedBreakerStates = []string{"open", "half_open"}
+
+// ConnectionBreakersRepository provides access to the connection breakers DynamoDB table.
+type ConnectionBreakersRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewConnectionBreakersRepository creates a new ConnectionBreakersRepository.
+func NewConnectionBreakersRepository(client *dynamodb.Client, tableName string) *ConnectionBreakersRepository {
+	return &ConnectionBreakersRepository{client: client, tableName: tableName}
+}
+
+// Get fetches a breaker by its connection_id.
+func (r *ConnectionBreakersRepository) Get(ctx context.Context, connectionID string) (*types.ConnectionBreaker, error) {
+	return getItem[types.ConnectionBreaker](ctx, r.client, r.tableName, stringKey("connection_id", connectionID))
+}
+
+// RecordFailure increments consecutive_failures, creating the breaker in
+// the closed state if it does not exist.
+func (r *ConnectionBreakersRepository) RecordFailure(ctx context.Context, connectionID, accountID, errMsg string, at time.Time) (*types.ConnectionBreaker, error) {
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key:       stringKey("connection_id", connectionID),
+		UpdateExpression: aws.String("SET account_id = :account_id, #st = if_not_exists(#st, :closed), " +
+			"probe_attempts = if_not_exists(probe_attempts, :zero), last_error = :error, updated_at = :at " +
+			"ADD consecutive_failures :one"),
+		ExpressionAttributeNames: map[string]string{"#st": "state"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":account_id": stringVal(accountID),
+			":closed":     stringVal("closed"),
+			":zero":       numVal("0"),
+			":one":        numVal("1"),
+			":error":      stringVal(errMsg),
+			":at":         stringVal(at.UTC().Format(time.RFC3339)),
+		},
+		ReturnValues: ddbtypes.ReturnValueAllNew,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("record connection failure: %w", err)
+	}
+
+	var breaker types.ConnectionBreaker
+	if err := attributevalue.UnmarshalMap(result.Attributes, &breaker); err != nil {
+		return nil, err
+	}
+	return &breaker, nil
+}
+
+// ResetFailures zeroes consecutive_failures of an existing breaker.
+func (r *ConnectionBreakersRepository) ResetFailures(ctx context.Context, connectionID string, at time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           &r.tableName,
+		Key:                 stringKey("connection_id", connectionID),
+		UpdateExpression:    aws.String("SET consecutive_failures = :zero, updated_at = :at"),
+		ConditionExpression: aws.String("attribute_exists(connection_id)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":zero": numVal("0"),
+			":at":   stringVal(at.UTC().Format(time.RFC3339)),
+		},
+	})
+	if err := conditionFailed(err); err != nil && !errors.Is(err, ErrConditionFailed) {
+		return fmt.Errorf("reset connection failures: %w", err)
+	}
+	return nil
+}
+
+// Transition updates the state under a condition on the current one.
+// next_probe_at is kept only while the breaker is scheduled, which keeps
+// closed breakers out of the ProbeIndex GSI; opened_at is set on opening.
+func (r *ConnectionBreakersRepository) Transition(ctx context.Context, connectionID string, from []string, to string, nextProbeAt, at time.Time) error {
+	values := map[string]ddbtypes.AttributeValue{
+		":to":   stringVal(to),
+		":zero": numVal("0"),
+		":at":   stringVal(at.UTC().Format(time.RFC3339)),
+	}
+	placeholders := make([]string, len(from))
+	for i, state := range from {
+		placeholders[i] = fmt.Sprintf(":from%d", i)
+		values[placeholders[i]] = stringVal(state)
+	}
+
+	expr := "SET #st = :to, consecutive_failures = :zero, probe_attempts = :zero, updated_at = :at"
+	if to == "open" {
+		expr += ", opened_at = :at"
+	}
+	if nextProbeAt.IsZero() {
+		expr += " REMOVE next_probe_at"
+	} else {
+		expr += ", next_probe_at = :next"
+		values[":next"] = stringVal(nextProbeAt.UTC().Format(time.RFC3339))
+	}
+
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.tableName,
+		Key:                       stringKey("connection_id", connectionID),
+		UpdateExpression:          &expr,
+		ConditionExpression:       aws.String("#st IN (" + strings.Join(placeholders, ", ") + ")"),
+		ExpressionAttributeNames:  map[string]string{"#st": "state"},
+		ExpressionAttributeValues: values,
+	})
+	return conditionFailed(err)
+}
+
+// RecordProbeFailure increments probe_attempts of an open breaker and moves
+// next_probe_at.
+func (r *ConnectionBreakersRepository) RecordProbeFailure(ctx context.Context, connectionID, errMsg string, nextProbeAt, at time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                &r.tableName,
+		Key:                      stringKey("connection_id", connectionID),
+		UpdateExpression:         aws.String("SET last_error = :error, next_probe_at = :next, updated_at = :at ADD probe_attempts :one"),
+		ConditionExpression:      aws.String("#st = :open"),
+		ExpressionAttributeNames: map[string]string{"#st": "state"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":error": stringVal(errMsg),
+			":next":  stringVal(nextProbeAt.UTC().Format(time.RFC3339)),
+			":at":    stringVal(at.UTC().Format(time.RFC3339)),
+			":one":   numVal("1"),
+			":open":  stringVal("open"),
+		},
+	})
+	return conditionFailed(err)
+}
+
+// ListDue queries both partitions of the sparse ProbeIndex GSI and returns
+// the earliest scheduled first.
+func (r *ConnectionBreakersRepository) ListDue(ctx context.Context, before time.Time, limit int) ([]types.ConnectionBreaker, error) {
+	var due []types.ConnectionBreaker
+	for _, state := range probedBreakerStates {
+		result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                &r.tableName,
+			IndexName:                aws.String("ProbeIndex"),
+			KeyConditionExpression:   aws.String("#st = :state AND next_probe_at <= :before"),
+			ExpressionAttributeNames: map[string]string{"#st": "state"},
+			ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+				":state":  stringVal(state),
+				":before": stringVal(before.UTC().Format(time.RFC3339)),
+			},
+			Limit: aws.Int32(int32(limit)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list due connection breakers: %w", err)
+		}
+		for _, item := range result.Items {
+			var breaker types.ConnectionBreaker
+			if err := attributevalue.UnmarshalMap(item, &breaker); err != nil {
+				return nil, err
+			}
+			due = append(due, breaker)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextProbeAt < due[j].NextProbeAt })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
@@ -91,3 +91,21 @@ func (r *ConnectionsRepository) UpdateSyncStatus(ctx context.Context, connection
 	}
 	return nil
 }
+
+// SetStatus sets status and updated_at.
+func (r *ConnectionsRepository) SetStatus(ctx context.Context, connectionID, status string) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                &r.tableName,
+		Key:                      stringKey("connection_id", connectionID),
+		UpdateExpression:         aws.String("SET #s = :status, updated_at = :updated_at"),
+		ExpressionAttributeNames: map[string]string{"#s": "status"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":status":     stringVal(status),
+			":updated_at": stringVal(time.Now().UTC().Format(time.RFC3339)),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("set connection status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// CountersRepository provides access to the rate-limits DynamoDB table, which
+// holds counters keyed by "key" and expired through the "ttl" attribute.
+type CountersRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewCountersRepository creates a new CountersRepository.
+func NewCountersRepository(client *dynamodb.Client, tableName string) *CountersRepository {
+	return &CountersRepository{client: client, tableName: tableName}
+}
+
+// Get returns the value of a counter, or 0 if it does not exist or its TTL
+// has passed but DynamoDB has not removed it yet.
+func (r *CountersRepository) Get(ctx context.Context, key string) (int64, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key:       stringKey("key", key),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if result.Item == nil {
+		return 0, nil
+	}
+
+	if ttl, ok := result.Item["ttl"].(*ddbtypes.AttributeValueMemberN); ok {
+		if expiresAt, _ := strconv.ParseInt(ttl.Value, 10, 64); expiresAt > 0 && expiresAt <= time.Now().Unix() {
+			return 0, nil
+		}
+	}
+
+	count, ok := result.Item["count"].(*ddbtypes.AttributeValueMemberN)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(count.Value, 10, 64)
+}
+
+// Increment atomically adds delta to a counter and returns the new value. A
+// non-zero expiresAt replaces the counter's TTL; a zero one leaves it as is.
+func (r *CountersRepository) Increment(ctx context.Context, key string, delta int64, expiresAt time.Time) (int64, error) {
+	updateExpr := "SET #c = if_not_exists(#c, :zero) + :delta"
+	exprNames := map[string]string{
+		"#c": "count",
+	}
+	exprValues := map[string]ddbtypes.AttributeValue{
+		":zero":  numVal("0"),
+		":delta": numVal(strconv.FormatInt(delta, 10)),
+	}
+	if !expiresAt.IsZero() {
+		updateExpr += ", #t = :ttl"
+		exprNames["#t"] = "ttl"
+		exprValues[":ttl"] = numVal(strconv.FormatInt(expiresAt.Unix(), 10))
+	}
+
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.tableName,
+		Key:                       stringKey("key", key),
+		UpdateExpression:          &updateExpr,
+		ExpressionAttributeNames:  exprNames,
+		ExpressionAttributeValues: exprValues,
+		ReturnValues:              ddbtypes.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("increment counter: %w", err)
+	}
+
+	count, ok := result.Attributes["count"].(*ddbtypes.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("increment counter: missing count in response")
+	}
+	return strconv.ParseInt(count.Value, 10, 64)
+}
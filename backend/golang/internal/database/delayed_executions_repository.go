@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// delayedDueShards is the number of partitions of the sparse DueIndex GSI;
+// pending executions are spread across them by execution ID
+const delayedDueShards = 4
+
+// DelayedExecutionsRepository provides access to the delayed executions DynamoDB table.
+type DelayedExecutionsRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewDelayedExecutionsRepository creates a new DelayedExecutionsRepository.
+func NewDelayedExecutionsRepository(client *dynamodb.Client, tableName string) *DelayedExecutionsRepository {
+	return &DelayedExecutionsRepository{client: client, tableName: tableName}
+}
+
+// Get fetches a delayed execution by its execution_id.
+func (r *DelayedExecutionsRepository) Get(ctx context.Context, executionID string) (*types.DelayedExecution, error) {
+	return getItem[types.DelayedExecution](ctx, r.client, r.tableName, stringKey("execution_id", executionID))
+}
+
+// Create inserts a new delayed execution. The account_contact key of the
+// ContactIndex GSI is added, and due_shard while it is pending.
+func (r *DelayedExecutionsRepository) Create(ctx context.Context, delayed *types.DelayedExecution) error {
+	av, err := attributevalue.MarshalMap(delayed)
+	if err != nil {
+		return err
+	}
+	if delayed.ContactID != "" {
+		av["account_contact"] = stringVal(accountContactKey(delayed.AccountID, delayed.ContactID))
+	}
+	if delayed.Status == "pending" {
+		av["due_shard"] = stringVal(delayedDueShard(delayed.ExecutionID))
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                av,
+		ConditionExpression: aws.String("attribute_not_exists(execution_id)"),
+	})
+	return conditionFailed(err)
+}
+
+// ListDue queries every shard of the sparse DueIndex GSI, which only holds
+// pending executions, and returns the earliest due first.
+func (r *DelayedExecutionsRepository) ListDue(ctx context.Context, before time.Time, limit int) ([]types.DelayedExecution, error) {
+	var due []types.DelayedExecution
+	for shard := 0; shard < delayedDueShards; shard++ {
+		input := &dynamodb.QueryInput{
+			TableName:              &r.tableName,
+			IndexName:              aws.String("DueIndex"),
+			KeyConditionExpression: aws.String("due_shard = :shard AND run_at <= :before"),
+			ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+				":shard":  stringVal(strconv.Itoa(shard)),
+				":before": stringVal(before.UTC().Format(time.RFC3339)),
+			},
+			Limit: aws.Int32(int32(limit)),
+		}
+		result, err := r.client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("list due delayed executions: %w", err)
+		}
+		for _, item := range result.Items {
+			var delayed types.DelayedExecution
+			if err := attributevalue.UnmarshalMap(item, &delayed); err != nil {
+				return nil, err
+			}
+			due = append(due, delayed)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].RunAt < due[j].RunAt })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// ListByContact fetches a contact's delayed executions using the
+// ContactIndex GSI with cursor-based pagination.
+func (r *DelayedExecutionsRepository) ListByContact(ctx context.Context, accountID, contactID, status string, limit int, cursor string) ([]types.DelayedExecution, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              aws.String("ContactIndex"),
+		KeyConditionExpression: aws.String("account_contact = :account_contact"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":account_contact": stringVal(accountContactKey(accountID, contactID)),
+		},
+		Limit: aws.Int32(int32(limit)),
+	}
+	filterStatus(input, status)
+	return queryPage[types.DelayedExecution](ctx, r.client, input, cursor)
+}
+
+// ListByHelper fetches a helper's delayed executions using the HelperIndex
+// GSI with cursor-based pagination.
+func (r *DelayedExecutionsRepository) ListByHelper(ctx context.Context, helperID, status string, limit int, cursor string) ([]types.DelayedExecution, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              aws.String("HelperIndex"),
+		KeyConditionExpression: aws.String("helper_id = :helper_id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":helper_id": stringVal(helperID),
+		},
+		Limit: aws.Int32(int32(limit)),
+	}
+	filterStatus(input, status)
+	return queryPage[types.DelayedExecution](ctx, r.client, input, cursor)
+}
+
+// Transition updates the status under a condition on the current one. The
+// due_shard attribute is kept only while the execution is pending.
+func (r *DelayedExecutionsRepository) Transition(ctx context.Context, executionID, from, to, reason string, at time.Time) error {
+	names := map[string]string{"#s": "status", "#r": "status_reason", "#d": "due_shard"}
+	values := map[string]ddbtypes.AttributeValue{
+		":from": stringVal(from),
+		":to":   stringVal(to),
+		":at":   stringVal(at.UTC().Format(time.RFC3339)),
+	}
+	set := "SET #s = :to, updated_at = :at"
+	var remove []string
+	if reason != "" {
+		set += ", #r = :reason"
+		values[":reason"] = stringVal(reason)
+	} else {
+		remove = append(remove, "#r")
+	}
+	if to == "pending" {
+		set += ", #d = :shard"
+		values[":shard"] = stringVal(delayedDueShard(executionID))
+	} else {
+		remove = append(remove, "#d")
+	}
+	expr := set
+	if len(remove) > 0 {
+		expr += " REMOVE " + remove[0]
+		for _, name := range remove[1:] {
+			expr += ", " + name
+		}
+	}
+
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.tableName,
+		Key:                       stringKey("execution_id", executionID),
+		UpdateExpression:          &expr,
+		ConditionExpression:       aws.String("#s = :from"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return conditionFailed(err)
+}
+
+// filterStatus limits a query to items with the given status, if any
+func filterStatus(input *dynamodb.QueryInput, status string) {
+	if status == "" {
+		return
+	}
+	input.FilterExpression = aws.String("#s = :status")
+	input.ExpressionAttributeNames = map[string]string{"#s": "status"}
+	input.ExpressionAttributeValues[":status"] = stringVal(status)
+}
+
+// accountContactKey scopes a contact ID, which is only unique within a CRM
+// connection's account, to the account
+func accountContactKey(accountID, contactID string) string {
+	return accountID + "#" + contactID
+}
+
+func delayedDueShard(executionID string) string {
+	h := fnv.New32a()
+	h.Write([]byte(executionID))
+	return strconv.Itoa(int(h.Sum32() % delayedDueShards))
+}
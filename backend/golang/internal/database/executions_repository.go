@@ -2,7 +2,10 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -29,12 +32,11 @@ func (r *ExecutionsRepository) GetByID(ctx context.Context, executionID string)
 }
 
 // ListByAccount fetches executions for an account using the AccountIdCreatedAtIndex GSI
-// with cursor-based pagination. The cursor is the created_at value of the last item.
-func (r *ExecutionsRepository) ListByAccount(ctx context.Context, accountID string, limit int, cursor string) ([]types.Execution, string, error) {
-	indexName := "AccountIdCreatedAtIndex"
+// with cursor-based pagination.
+func (r *ExecutionsRepository) ListByAccount(ctx context.Context, accountID, status string, limit int, cursor string) ([]types.Execution, string, error) {
 	input := &dynamodb.QueryInput{
 		TableName:              &r.tableName,
-		IndexName:              &indexName,
+		IndexName:              aws.String("AccountIdCreatedAtIndex"),
 		KeyConditionExpression: aws.String("account_id = :account_id"),
 		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
 			":account_id": stringVal(accountID),
@@ -42,47 +44,16 @@ func (r *ExecutionsRepository) ListByAccount(ctx context.Context, accountID stri
 		ScanIndexForward: aws.Bool(false),
 		Limit:            aws.Int32(int32(limit)),
 	}
-
-	if cursor != "" {
-		startKey, err := decodeCursor(cursor)
-		if err != nil {
-			return nil, "", fmt.Errorf("invalid cursor: %w", err)
-		}
-		input.ExclusiveStartKey = startKey
-	}
-
-	result, err := r.client.Query(ctx, input)
-	if err != nil {
-		return nil, "", err
-	}
-
-	items := make([]types.Execution, 0, len(result.Items))
-	for _, item := range result.Items {
-		var exec types.Execution
-		if err := attributevalue.UnmarshalMap(item, &exec); err != nil {
-			return nil, "", err
-		}
-		items = append(items, exec)
-	}
-
-	var nextCursor string
-	if result.LastEvaluatedKey != nil {
-		nextCursor, err = encodeCursor(result.LastEvaluatedKey)
-		if err != nil {
-			return nil, "", fmt.Errorf("encode cursor: %w", err)
-		}
-	}
-
-	return items, nextCursor, nil
+	filterStatus(input, status)
+	return queryPage[types.Execution](ctx, r.client, input, cursor)
 }
 
 // ListByHelper fetches executions for a helper using the HelperIdCreatedAtIndex GSI
 // with cursor-based pagination.
-func (r *ExecutionsRepository) ListByHelper(ctx context.Context, helperID string, limit int, cursor string) ([]types.Execution, string, error) {
-	indexName := "HelperIdCreatedAtIndex"
+func (r *ExecutionsRepository) ListByHelper(ctx context.Context, helperID, status string, limit int, cursor string) ([]types.Execution, string, error) {
 	input := &dynamodb.QueryInput{
 		TableName:              &r.tableName,
-		IndexName:              &indexName,
+		IndexName:              aws.String("HelperIdCreatedAtIndex"),
 		KeyConditionExpression: aws.String("helper_id = :helper_id"),
 		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
 			":helper_id": stringVal(helperID),
@@ -90,43 +61,13 @@ func (r *ExecutionsRepository) ListByHelper(ctx context.Context, helperID string
 		ScanIndexForward: aws.Bool(false),
 		Limit:            aws.Int32(int32(limit)),
 	}
-
-	if cursor != "" {
-		startKey, err := decodeCursor(cursor)
-		if err != nil {
-			return nil, "", fmt.Errorf("invalid cursor: %w", err)
-		}
-		input.ExclusiveStartKey = startKey
-	}
-
-	result, err := r.client.Query(ctx, input)
-	if err != nil {
-		return nil, "", err
-	}
-
-	items := make([]types.Execution, 0, len(result.Items))
-	for _, item := range result.Items {
-		var exec types.Execution
-		if err := attributevalue.UnmarshalMap(item, &exec); err != nil {
-			return nil, "", err
-		}
-		items = append(items, exec)
-	}
-
-	var nextCursor string
-	if result.LastEvaluatedKey != nil {
-		nextCursor, err = encodeCursor(result.LastEvaluatedKey)
-		if err != nil {
-			return nil, "", fmt.Errorf("encode cursor: %w", err)
-		}
-	}
-
-	return items, nextCursor, nil
+	filterStatus(input, status)
+	return queryPage[types.Execution](ctx, r.client, input, cursor)
 }
 
-// Create inserts a new execution record.
+// Create inserts a new execution record unless its execution_id is taken.
 func (r *ExecutionsRepository) Create(ctx context.Context, exec *types.Execution) error {
-	return putItem(ctx, r.client, r.tableName, exec)
+	return putItemWithCondition(ctx, r.client, r.tableName, exec, "attribute_not_exists(execution_id)")
 }
 
 // UpdateResult updates the status, output, and duration of a completed execution.
@@ -163,3 +104,215 @@ func (r *ExecutionsRepository) UpdateResult(ctx context.Context, executionID, st
 	}
 	return nil
 }
+
+// Transition updates the status under a condition on the current one and
+// appends t to status_history. paused_connection_id, the key of the sparse
+// PausedConnectionIndex GSI, is copied from connection_id while the
+// execution is paused and removed otherwise.
+func (r *ExecutionsRepository) Transition(ctx context.Context, executionID string, from []string, t types.StatusTransition) error {
+	entry, err := attributevalue.Marshal([]types.StatusTransition{t})
+	if err != nil {
+		return fmt.Errorf("marshal status transition: %w", err)
+	}
+
+	values := map[string]ddbtypes.AttributeValue{
+		":to":    stringVal(t.Status),
+		":entry": entry,
+		":empty": &ddbtypes.AttributeValueMemberL{Value: []ddbtypes.AttributeValue{}},
+	}
+	placeholders := make([]string, len(from))
+	for i, status := range from {
+		placeholders[i] = fmt.Sprintf(":from%d", i)
+		values[placeholders[i]] = stringVal(status)
+	}
+
+	update := "SET #s = :to, status_history = list_append(if_not_exists(status_history, :empty), :entry)"
+	if t.Status == "paused" {
+		update += ", paused_connection_id = connection_id"
+	} else {
+		update += " REMOVE paused_connection_id"
+	}
+
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.tableName,
+		Key:                       stringKey("execution_id", executionID),
+		UpdateExpression:          aws.String(update),
+		ConditionExpression:       aws.String("#s IN (" + strings.Join(placeholders, ", ") + ")"),
+		ExpressionAttributeNames:  map[string]string{"#s": "status"},
+		ExpressionAttributeValues: values,
+	})
+	return conditionFailed(err)
+}
+
+// ListPaused queries the sparse PausedConnectionIndex GSI, which only holds
+// paused executions, oldest first.
+func (r *ExecutionsRepository) ListPaused(ctx context.Context, connectionID string, limit int) ([]types.Execution, error) {
+	execs, _, err := queryPage[types.Execution](ctx, r.client, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              aws.String("PausedConnectionIndex"),
+		KeyConditionExpression: aws.String("paused_connection_id = :connection_id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":connection_id": stringVal(connectionID),
+		},
+		Limit: aws.Int32(int32(limit)),
+	}, "")
+	if err != nil {
+		return nil, fmt.Errorf("list paused executions: %w", err)
+	}
+	return execs, nil
+}
+
+// RecordHandback adds one to handbacks while handback_message_id is the
+// message, and starts the count over for a new message.
+func (r *ExecutionsRepository) RecordHandback(ctx context.Context, executionID, messageID string) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           &r.tableName,
+		Key:                 stringKey("execution_id", executionID),
+		UpdateExpression:    aws.String("ADD handbacks :one"),
+		ConditionExpression: aws.String("handback_message_id = :message_id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":one":        numVal("1"),
+			":message_id": stringVal(messageID),
+		},
+	})
+	if err = conditionFailed(err); !errors.Is(err, ErrConditionFailed) {
+		return err
+	}
+
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           &r.tableName,
+		Key:                 stringKey("execution_id", executionID),
+		UpdateExpression:    aws.String("SET handbacks = :one, handback_message_id = :message_id"),
+		ConditionExpression: aws.String("attribute_exists(execution_id)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":one":        numVal("1"),
+			":message_id": stringVal(messageID),
+		},
+	})
+	return conditionFailed(err)
+}
+
+// RecordAttempt sets attempts.
+func (r *ExecutionsRepository) RecordAttempt(ctx context.Context, executionID string, attempt int) error {
+	return r.set(ctx, executionID, "SET attempts = :attempt", nil, map[string]ddbtypes.AttributeValue{
+		":attempt": numVal(strconv.Itoa(attempt)),
+	})
+}
+
+// RecordError sets error_message.
+func (r *ExecutionsRepository) RecordError(ctx context.Context, executionID, message string) error {
+	return r.set(ctx, executionID, "SET error_message = :error", nil, map[string]ddbtypes.AttributeValue{
+		":error": stringVal(message),
+	})
+}
+
+// RecordOutcome sets completed_at, duration_ms and whichever of the error
+// fields and output the outcome has. The output is stored as a map, the way
+// UpdateResult stores it.
+func (r *ExecutionsRepository) RecordOutcome(ctx context.Context, executionID string, outcome ExecutionOutcome) error {
+	update := "SET completed_at = :completed_at, duration_ms = :duration"
+	values := map[string]ddbtypes.AttributeValue{
+		":completed_at": stringVal(outcome.CompletedAt.UTC().Format(time.RFC3339)),
+		":duration":     numVal(strconv.FormatInt(outcome.DurationMs, 10)),
+	}
+	for _, field := range []struct{ name, value string }{
+		{"error_message", outcome.ErrorMessage},
+		{"error_code", outcome.ErrorCode},
+		{"error_stack", outcome.ErrorStack},
+	} {
+		if field.value != "" {
+			update += fmt.Sprintf(", %s = :%s", field.name, field.name)
+			values[":"+field.name] = stringVal(field.value)
+		}
+	}
+	if outcome.Output != nil {
+		outputAV, err := attributevalue.MarshalMap(outcome.Output)
+		if err != nil {
+			return fmt.Errorf("marshal output: %w", err)
+		}
+		update += ", output = :output"
+		values[":output"] = &ddbtypes.AttributeValueMemberM{Value: outputAV}
+	}
+	return r.set(ctx, executionID, update, nil, values)
+}
+
+// RecordTrace sets connector_trace and connector_calls_dropped, and ttl if
+// the execution has none.
+func (r *ExecutionsRepository) RecordTrace(ctx context.Context, executionID string, calls []types.ConnectorCall, dropped int, expiresAt time.Time) error {
+	trace, err := attributevalue.Marshal(calls)
+	if err != nil {
+		return fmt.Errorf("marshal connector trace: %w", err)
+	}
+	return r.set(ctx, executionID,
+		"SET connector_trace = :trace, connector_calls_dropped = :dropped, #ttl = if_not_exists(#ttl, :ttl)",
+		map[string]string{"#ttl": "ttl"},
+		map[string]ddbtypes.AttributeValue{
+			":trace":   trace,
+			":dropped": numVal(strconv.Itoa(dropped)),
+			":ttl":     numVal(strconv.FormatInt(expiresAt.Unix(), 10)),
+		})
+}
+
+// AppendRetryAttempt appends to retry_attempts.
+func (r *ExecutionsRepository) AppendRetryAttempt(ctx context.Context, executionID string, attempt types.RetryAttempt) error {
+	entry, err := attributevalue.Marshal([]types.RetryAttempt{attempt})
+	if err != nil {
+		return fmt.Errorf("marshal retry attempt: %w", err)
+	}
+	return r.set(ctx, executionID, "SET retry_attempts = list_append(if_not_exists(retry_attempts, :empty), :attempt)", nil, map[string]ddbtypes.AttributeValue{
+		":empty":   &ddbtypes.AttributeValueMemberL{Value: []ddbtypes.AttributeValue{}},
+		":attempt": entry,
+	})
+}
+
+// RecordDeliveries sets action_deliveries.
+func (r *ExecutionsRepository) RecordDeliveries(ctx context.Context, executionID string, deliveries []types.ActionDelivery) error {
+	av, err := attributevalue.Marshal(deliveries)
+	if err != nil {
+		return fmt.Errorf("marshal action deliveries: %w", err)
+	}
+	return r.set(ctx, executionID, "SET action_deliveries = :deliveries", nil, map[string]ddbtypes.AttributeValue{
+		":deliveries": av,
+	})
+}
+
+// RecordStripeUsage sets stripe_reported and stripe_usage_record_id.
+func (r *ExecutionsRepository) RecordStripeUsage(ctx context.Context, executionID, usageRecordID string) error {
+	return r.set(ctx, executionID, "SET stripe_reported = :reported, stripe_usage_record_id = :record_id", nil, map[string]ddbtypes.AttributeValue{
+		":reported":  &ddbtypes.AttributeValueMemberBOOL{Value: true},
+		":record_id": stringVal(usageRecordID),
+	})
+}
+
+// ClaimReplay sets replayed_as on a dead-lettered execution that has none.
+func (r *ExecutionsRepository) ClaimReplay(ctx context.Context, executionID, replayID string) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                &r.tableName,
+		Key:                      stringKey("execution_id", executionID),
+		UpdateExpression:         aws.String("SET replayed_as = :replay_id"),
+		ConditionExpression:      aws.String("#s = :dead_lettered AND attribute_not_exists(replayed_as)"),
+		ExpressionAttributeNames: map[string]string{"#s": "status"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":replay_id":     stringVal(replayID),
+			":dead_lettered": stringVal("dead_lettered"),
+		},
+	})
+	return conditionFailed(err)
+}
+
+// ReleaseReplay removes replayed_as.
+func (r *ExecutionsRepository) ReleaseReplay(ctx context.Context, executionID string) error {
+	return r.set(ctx, executionID, "REMOVE replayed_as", nil, nil)
+}
+
+// set runs an unconditional update of one execution
+func (r *ExecutionsRepository) set(ctx context.Context, executionID, update string, names map[string]string, values map[string]ddbtypes.AttributeValue) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 &r.tableName,
+		Key:                       stringKey("execution_id", executionID),
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
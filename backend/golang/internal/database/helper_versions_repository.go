@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// HelperVersionsRepository provides access to the helper config versions
+// DynamoDB table. Commits also update the helper record, so it needs the
+// helpers table too.
+type HelperVersionsRepository struct {
+	client           *dynamodb.Client
+	tableName        string
+	helpersTableName string
+}
+
+// NewHelperVersionsRepository creates a new helper versions repository.
+func NewHelperVersionsRepository(client *dynamodb.Client, tableName, helpersTableName string) *HelperVersionsRepository {
+	return &HelperVersionsRepository{client: client, tableName: tableName, helpersTableName: helpersTableName}
+}
+
+func versionKey(helperID string, version int) map[string]ddbtypes.AttributeValue {
+	return map[string]ddbtypes.AttributeValue{
+		"helper_id": stringVal(helperID),
+		"version":   numVal(strconv.Itoa(version)),
+	}
+}
+
+// Get fetches one config version of a helper.
+func (r *HelperVersionsRepository) Get(ctx context.Context, helperID string, version int) (*types.HelperConfigVersion, error) {
+	return getItem[types.HelperConfigVersion](ctx, r.client, r.tableName, versionKey(helperID, version))
+}
+
+// List fetches a helper's config versions, newest first, with cursor-based
+// pagination.
+func (r *HelperVersionsRepository) List(ctx context.Context, helperID string, limit int, cursor string) ([]types.HelperConfigVersion, string, error) {
+	return queryPage[types.HelperConfigVersion](ctx, r.client, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		KeyConditionExpression: aws.String("helper_id = :helper_id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":helper_id": stringVal(helperID),
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(int32(limit)),
+	}, cursor)
+}
+
+// Commit puts the versions and moves the helper's config to the last one in
+// a single transaction, conditioned on the helper's current config_version.
+func (r *HelperVersionsRepository) Commit(ctx context.Context, expected int, versions ...types.HelperConfigVersion) error {
+	if len(versions) == 0 {
+		return fmt.Errorf("commit helper versions: no versions")
+	}
+	latest := versions[len(versions)-1]
+
+	items := make([]ddbtypes.TransactWriteItem, 0, len(versions)+1)
+	for i := range versions {
+		av, err := attributevalue.MarshalMap(&versions[i])
+		if err != nil {
+			return err
+		}
+		items = append(items, ddbtypes.TransactWriteItem{Put: &ddbtypes.Put{
+			TableName:           &r.tableName,
+			Item:                av,
+			ConditionExpression: aws.String("attribute_not_exists(helper_id)"),
+		}})
+	}
+
+	configAV, err := attributevalue.Marshal(latest.Config)
+	if err != nil {
+		return err
+	}
+	values := map[string]ddbtypes.AttributeValue{
+		":config":     configAV,
+		":version":    numVal(strconv.Itoa(latest.Version)),
+		":updated_at": stringVal(latest.CreatedAt),
+	}
+	condition := "attribute_exists(helper_id) AND attribute_not_exists(config_version)"
+	if expected > 0 {
+		condition = "config_version = :expected"
+		values[":expected"] = numVal(strconv.Itoa(expected))
+	}
+	items = append(items, ddbtypes.TransactWriteItem{Update: &ddbtypes.Update{
+		TableName:                 &r.helpersTableName,
+		Key:                       stringKey("helper_id", latest.HelperID),
+		UpdateExpression:          aws.String("SET config = :config, config_version = :version, updated_at = :updated_at"),
+		ConditionExpression:       &condition,
+		ExpressionAttributeValues: values,
+	}})
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	var cancelled *ddbtypes.TransactionCanceledException
+	if errors.As(err, &cancelled) {
+		for _, reason := range cancelled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return ErrConditionFailed
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("commit helper versions: %w", err)
+	}
+	return nil
+}
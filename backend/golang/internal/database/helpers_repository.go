@@ -27,6 +27,19 @@ func (r *HelpersRepository) GetByID(ctx context.Context, helperID string) (*type
 	return getItem[types.Helper](ctx, r.client, r.tableName, stringKey("helper_id", helperID))
 }
 
+// GetByShortKey fetches a helper by its short_key using the ShortKeyIndex GSI.
+func (r *HelpersRepository) GetByShortKey(ctx context.Context, shortKey string) (*types.Helper, error) {
+	indexName := "ShortKeyIndex"
+	return querySingleItem[types.Helper](ctx, r.client, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              &indexName,
+		KeyConditionExpression: aws.String("short_key = :short_key"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":short_key": stringVal(shortKey),
+		},
+	})
+}
+
 // ListByAccount fetches all helpers for a given account using the AccountIdIndex GSI.
 func (r *HelpersRepository) ListByAccount(ctx context.Context, accountID string) ([]types.Helper, error) {
 	indexName := "AccountIdIndex"
@@ -50,6 +63,38 @@ func (r *HelpersRepository) Update(ctx context.Context, helper *types.Helper) er
 	return putItem(ctx, r.client, r.tableName, helper)
 }
 
+// UpdateSettings writes the user-editable fields of the helper, leaving its
+// config, config_version and execution stats as they are.
+func (r *HelpersRepository) UpdateSettings(ctx context.Context, helper *types.Helper) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key:       stringKey("helper_id", helper.HelperID),
+		UpdateExpression: aws.String("SET #n = :name, description = :description, #s = :status, enabled = :enabled, " +
+			"connection_id = :connection_id, schedule_enabled = :schedule_enabled, cron_expression = :cron, " +
+			"schedule_rule_arn = :rule_arn, updated_at = :updated_at"),
+		ExpressionAttributeNames: map[string]string{
+			"#n": "name",
+			"#s": "status",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":name":             stringVal(helper.Name),
+			":description":      stringVal(helper.Description),
+			":status":           stringVal(helper.Status),
+			":enabled":          &ddbtypes.AttributeValueMemberBOOL{Value: helper.Enabled},
+			":connection_id":    stringVal(helper.ConnectionID),
+			":schedule_enabled": &ddbtypes.AttributeValueMemberBOOL{Value: helper.ScheduleEnabled},
+			":cron":             stringVal(helper.CronExpression),
+			":rule_arn":         stringVal(helper.ScheduleRuleARN),
+			":updated_at":       stringVal(helper.UpdatedAt.UTC().Format(time.RFC3339)),
+		},
+		ConditionExpression: aws.String("attribute_exists(helper_id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("update helper settings: %w", conditionFailed(err))
+	}
+	return nil
+}
+
 // SoftDelete sets the helper status to "deleted".
 func (r *HelpersRepository) SoftDelete(ctx context.Context, helperID string) error {
 	now := time.Now().UTC()
@@ -67,7 +112,7 @@ func (r *HelpersRepository) SoftDelete(ctx context.Context, helperID string) err
 		ConditionExpression: aws.String("attribute_exists(helper_id)"),
 	})
 	if err != nil {
-		return fmt.Errorf("soft delete helper: %w", err)
+		return fmt.Errorf("soft delete helper: %w", conditionFailed(err))
 	}
 	return nil
 }
@@ -89,3 +134,32 @@ func (r *HelpersRepository) UpdateExecutionStats(ctx context.Context, helperID s
 	}
 	return nil
 }
+
+// RecordExecution increments execution_count and sets last_executed_at.
+func (r *HelpersRepository) RecordExecution(ctx context.Context, helperID string, at time.Time) error {
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        &r.tableName,
+		Key:              stringKey("helper_id", helperID),
+		UpdateExpression: aws.String("SET execution_count = if_not_exists(execution_count, :zero) + :one, last_executed_at = :executed_at"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":zero":        numVal("0"),
+			":one":         numVal("1"),
+			":executed_at": stringVal(at.UTC().Format(time.RFC3339)),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("record helper execution: %w", err)
+	}
+	return nil
+}
+
+// ListScheduled scans for helpers with schedule_enabled set.
+func (r *HelpersRepository) ListScheduled(ctx context.Context) ([]types.Helper, error) {
+	return scanAll[types.Helper](ctx, r.client, &dynamodb.ScanInput{
+		TableName:        &r.tableName,
+		FilterExpression: aws.String("schedule_enabled = :enabled"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":enabled": &ddbtypes.AttributeValueMemberBOOL{Value: true},
+		},
+	})
+}
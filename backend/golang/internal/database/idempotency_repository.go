@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// IdempotencyRepository keeps idempotency claims in the rate-limits table,
+// next to the counters: each claim is an item keyed by "key" holding the
+// execution_id, expired through the "ttl" attribute.
+type IdempotencyRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository.
+func NewIdempotencyRepository(client *dynamodb.Client, tableName string) *IdempotencyRepository {
+	return &IdempotencyRepository{client: client, tableName: tableName}
+}
+
+// Claim writes the claim unless an unexpired one exists. DynamoDB removes
+// expired items lazily, so the condition also accepts items whose TTL has passed.
+func (r *IdempotencyRepository) Claim(ctx context.Context, key, executionID string, expiresAt time.Time) (string, bool, error) {
+	now := time.Now()
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item: map[string]ddbtypes.AttributeValue{
+			"key":          &ddbtypes.AttributeValueMemberS{Value: key},
+			"execution_id": &ddbtypes.AttributeValueMemberS{Value: executionID},
+			"ttl":          numVal(strconv.FormatInt(expiresAt.Unix(), 10)),
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(#k) OR #t <= :now"),
+		ExpressionAttributeNames: map[string]string{"#k": "key", "#t": "ttl"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":now": numVal(strconv.FormatInt(now.Unix(), 10)),
+		},
+		ReturnValuesOnConditionCheckFailure: ddbtypes.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err == nil {
+		return executionID, true, nil
+	}
+
+	var conditionErr *ddbtypes.ConditionalCheckFailedException
+	if !errors.As(err, &conditionErr) {
+		return "", false, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	existing, ok := conditionErr.Item["execution_id"].(*ddbtypes.AttributeValueMemberS)
+	if !ok {
+		return "", false, fmt.Errorf("claim idempotency key: existing claim has no execution_id")
+	}
+	return existing.Value, false, nil
+}
+
+// Release deletes the claim if executionID still holds it.
+func (r *IdempotencyRepository) Release(ctx context.Context, key, executionID string) error {
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           &r.tableName,
+		Key:                 stringKey("key", key),
+		ConditionExpression: aws.String("execution_id = :id"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":id": &ddbtypes.AttributeValueMemberS{Value: executionID},
+		},
+	})
+	if err := conditionFailed(err); err != nil && !errors.Is(err, ErrConditionFailed) {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// LeasesRepository keeps leases in the rate-limits table, next to the
+// counters and idempotency claims: each lease is an item keyed by "key"
+// holding its owner, expired through the "ttl" attribute.
+type LeasesRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewLeasesRepository creates a new LeasesRepository.
+func NewLeasesRepository(client *dynamodb.Client, tableName string) *LeasesRepository {
+	return &LeasesRepository{client: client, tableName: tableName}
+}
+
+// Acquire writes the lease unless another owner holds an unexpired one.
+// DynamoDB removes expired items lazily, so the condition also accepts items
+// whose TTL has passed.
+func (r *LeasesRepository) Acquire(ctx context.Context, key, owner string, expiresAt time.Time) (bool, error) {
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item: map[string]ddbtypes.AttributeValue{
+			"key":   &ddbtypes.AttributeValueMemberS{Value: key},
+			"owner": &ddbtypes.AttributeValueMemberS{Value: owner},
+			"ttl":   numVal(strconv.FormatInt(expiresAt.Unix(), 10)),
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(#k) OR #t <= :now OR #o = :owner"),
+		ExpressionAttributeNames: map[string]string{"#k": "key", "#t": "ttl", "#o": "owner"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":now":   numVal(strconv.FormatInt(time.Now().Unix(), 10)),
+			":owner": &ddbtypes.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err := conditionFailed(err); errors.Is(err, ErrConditionFailed) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("acquire lease: %w", err)
+	}
+	return true, nil
+}
+
+// Release deletes the lease if owner still holds it.
+func (r *LeasesRepository) Release(ctx context.Context, key, owner string) error {
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                &r.tableName,
+		Key:                      stringKey("key", key),
+		ConditionExpression:      aws.String("#o = :owner"),
+		ExpressionAttributeNames: map[string]string{"#o": "owner"},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":owner": &ddbtypes.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err := conditionFailed(err); err != nil && !errors.Is(err, ErrConditionFailed) {
+		return fmt.Errorf("release lease: %w", err)
+	}
+	return nil
+}
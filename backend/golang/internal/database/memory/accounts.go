@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Accounts is an in-memory database.AccountStore.
+type Accounts struct {
+	records *table[types.Account]
+}
+
+var _ database.AccountStore = (*Accounts)(nil)
+
+// NewAccounts creates an empty account store.
+func NewAccounts() *Accounts {
+	return &Accounts{records: newTable[types.Account]()}
+}
+
+// GetByID returns the account, or nil if it does not exist.
+func (s *Accounts) GetByID(ctx context.Context, accountID string) (*types.Account, error) {
+	return s.records.get(accountID)
+}
+
+// GetByOwner returns the accounts owned by a user.
+func (s *Accounts) GetByOwner(ctx context.Context, ownerUserID string) ([]types.Account, error) {
+	return s.records.filter(func(a *types.Account) bool { return a.OwnerUserID == ownerUserID })
+}
+
+// Create stores a new account, failing with database.ErrConditionFailed if
+// the account_id is taken.
+func (s *Accounts) Create(ctx context.Context, account *types.Account) error {
+	return s.records.create(account.AccountID, account)
+}
+
+// Update replaces the account record.
+func (s *Accounts) Update(ctx context.Context, account *types.Account) error {
+	return s.records.put(account.AccountID, account)
+}
+
+// IncrementMonthlyExecutions adds delta to usage.monthly_executions. Like the
+// DynamoDB update, it creates the record if the account does not exist.
+func (s *Accounts) IncrementMonthlyExecutions(ctx context.Context, accountID string, delta int) (int, error) {
+	var used int
+	err := s.records.upsert(accountID, &types.Account{AccountID: accountID}, func(a *types.Account) error {
+		a.Usage.MonthlyExecutions += delta
+		used = a.Usage.MonthlyExecutions
+		return nil
+	})
+	return used, err
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// APIKeys is an in-memory database.APIKeyStore.
+type APIKeys struct {
+	records *table[types.APIKey]
+}
+
+var _ database.APIKeyStore = (*APIKeys)(nil)
+
+// NewAPIKeys creates an empty API key store.
+func NewAPIKeys() *APIKeys {
+	return &APIKeys{records: newTable[types.APIKey]()}
+}
+
+// GetByID returns the API key, or nil if it does not exist.
+func (s *APIKeys) GetByID(ctx context.Context, keyID string) (*types.APIKey, error) {
+	return s.records.get(keyID)
+}
+
+// GetByHash returns the API key with the given hash, or nil.
+func (s *APIKeys) GetByHash(ctx context.Context, keyHash string) (*types.APIKey, error) {
+	return s.records.first(func(k *types.APIKey) bool { return k.KeyHash == keyHash })
+}
+
+// ListByAccount returns the API keys of an account.
+func (s *APIKeys) ListByAccount(ctx context.Context, accountID string) ([]types.APIKey, error) {
+	return s.records.filter(func(k *types.APIKey) bool { return k.AccountID == accountID })
+}
+
+// Create stores a new API key, failing with database.ErrConditionFailed if
+// the key_id is taken.
+func (s *APIKeys) Create(ctx context.Context, key *types.APIKey) error {
+	return s.records.create(key.KeyID, key)
+}
+
+// Revoke sets the API key status to "revoked".
+func (s *APIKeys) Revoke(ctx context.Context, keyID string) error {
+	found, err := s.records.update(keyID, func(k *types.APIKey) error {
+		k.Status = "revoked"
+		return nil
+	})
+	if err == nil && !found {
+		err = database.ErrConditionFailed
+	}
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	return nil
+}
+
+// UpdateLastUsed records when the API key last authorized a request.
+func (s *APIKeys) UpdateLastUsed(ctx context.Context, keyID string, at time.Time) error {
+	_, err := s.records.update(keyID, func(k *types.APIKey) error {
+		k.LastUsedAt = &at
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Batches is an in-memory database.BatchStore.
+type Batches struct {
+	records *table[types.Batch]
+	items   *table[types.BatchItem]
+}
+
+var _ database.BatchStore = (*Batches)(nil)
+
+// NewBatches creates an empty batch store.
+func NewBatches() *Batches {
+	return &Batches{records: newTable[types.Batch](), items: newTable[types.BatchItem]()}
+}
+
+// GetByID returns the batch, or nil if it does not exist.
+func (s *Batches) GetByID(ctx context.Context, batchID string) (*types.Batch, error) {
+	return s.records.get(batchID)
+}
+
+// ListByAccount returns an account's batches, newest first. The cursor is
+// the batch_id of the last batch on the previous page.
+func (s *Batches) ListByAccount(ctx context.Context, accountID string, limit int, cursor string) ([]types.Batch, string, error) {
+	batches, err := s.records.filter(func(b *types.Batch) bool { return b.AccountID == accountID })
+	if err != nil {
+		return nil, "", err
+	}
+	sort.SliceStable(batches, func(i, j int) bool {
+		if batches[i].CreatedAt != batches[j].CreatedAt {
+			return batches[i].CreatedAt > batches[j].CreatedAt
+		}
+		return batches[i].BatchID > batches[j].BatchID
+	})
+	return page(batches, func(b *types.Batch) string { return b.BatchID }, limit, cursor)
+}
+
+// ListRunning returns the running batches, oldest first.
+func (s *Batches) ListRunning(ctx context.Context) ([]types.Batch, error) {
+	batches, err := s.records.filter(func(b *types.Batch) bool { return b.Status == "running" })
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(batches, func(i, j int) bool { return batches[i].CreatedAt < batches[j].CreatedAt })
+	return batches, nil
+}
+
+// Create stores a new batch and returns ErrConditionFailed if its ID is taken.
+func (s *Batches) Create(ctx context.Context, batch *types.Batch) error {
+	return s.records.create(batch.BatchID, batch)
+}
+
+// Save writes the batch if its version matches, keeping the stored counts.
+func (s *Batches) Save(ctx context.Context, batch *types.Batch) error {
+	found, err := s.records.update(batch.BatchID, func(stored *types.Batch) error {
+		if stored.Version != batch.Version {
+			return database.ErrConditionFailed
+		}
+		succeeded, failed := stored.Succeeded, stored.Failed
+		*stored = *batch
+		stored.Succeeded, stored.Failed = succeeded, failed
+		stored.Version++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return database.ErrConditionFailed
+	}
+	batch.Version++
+	return nil
+}
+
+// AddItem stores a new item unless the contact already has one in the batch.
+func (s *Batches) AddItem(ctx context.Context, item *types.BatchItem) error {
+	return s.items.create(itemKey(item.BatchID, item.ContactID), item)
+}
+
+// CompleteItem records the outcome of a queued item and counts it on the batch.
+func (s *Batches) CompleteItem(ctx context.Context, batchID, contactID, status, errMsg string, completedAt time.Time) (bool, error) {
+	completed := false
+	_, err := s.items.update(itemKey(batchID, contactID), func(item *types.BatchItem) error {
+		if item.Status != "queued" {
+			return nil
+		}
+		item.Status = status
+		item.Error = errMsg
+		item.CompletedAt = completedAt.UTC().Format(time.RFC3339)
+		completed = true
+		return nil
+	})
+	if err != nil || !completed {
+		return false, err
+	}
+
+	_, err = s.records.update(batchID, func(b *types.Batch) error {
+		if status == "completed" {
+			b.Succeeded++
+		} else {
+			b.Failed++
+		}
+		return nil
+	})
+	return err == nil, err
+}
+
+// ListItems returns a batch's items in contact ID order. The cursor is the
+// contact_id of the last item on the previous page.
+func (s *Batches) ListItems(ctx context.Context, batchID, status string, limit int, cursor string) ([]types.BatchItem, string, error) {
+	items, err := s.items.filter(func(item *types.BatchItem) bool {
+		return item.BatchID == batchID && (status == "" || item.Status == status)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ContactID < items[j].ContactID })
+	return page(items, func(item *types.BatchItem) string { return item.ContactID }, limit, cursor)
+}
+
+func itemKey(batchID, contactID string) string {
+	return batchID + "#" + contactID
+}
+
+// page returns up to limit records following the one whose ID is cursor
+func page[T any](records []T, id func(*T) string, limit int, cursor string) ([]T, string, error) {
+	if cursor != "" {
+		start := -1
+		for i := range records {
+			if id(&records[i]) == cursor {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, "", fmt.Errorf("invalid cursor: %s", cursor)
+		}
+		records = records[start:]
+	}
+
+	var next string
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+		next = id(&records[limit-1])
+	}
+	return records, next, nil
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Breakers is an in-memory database.ConnectionBreakerStore.
+type Breakers struct {
+	records *table[types.ConnectionBreaker]
+}
+
+var _ database.ConnectionBreakerStore = (*Breakers)(nil)
+
+// NewBreakers creates an empty connection breaker store.
+func NewBreakers() *Breakers {
+	return &Breakers{records: newTable[types.ConnectionBreaker]()}
+}
+
+// Get returns the connection's breaker, or nil if it has none.
+func (s *Breakers) Get(ctx context.Context, connectionID string) (*types.ConnectionBreaker, error) {
+	return s.records.get(connectionID)
+}
+
+// RecordFailure counts a failure, creating a closed breaker if needed.
+func (s *Breakers) RecordFailure(ctx context.Context, connectionID, accountID, errMsg string, at time.Time) (*types.ConnectionBreaker, error) {
+	var updated types.ConnectionBreaker
+	init := &types.ConnectionBreaker{ConnectionID: connectionID, State: "closed"}
+	err := s.records.upsert(connectionID, init, func(b *types.ConnectionBreaker) error {
+		b.AccountID = accountID
+		b.ConsecutiveFailures++
+		b.LastError = errMsg
+		b.UpdatedAt = at.UTC().Format(time.RFC3339)
+		updated = *b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ResetFailures zeroes the failure count of an existing breaker.
+func (s *Breakers) ResetFailures(ctx context.Context, connectionID string, at time.Time) error {
+	_, err := s.records.update(connectionID, func(b *types.ConnectionBreaker) error {
+		b.ConsecutiveFailures = 0
+		b.UpdatedAt = at.UTC().Format(time.RFC3339)
+		return nil
+	})
+	return err
+}
+
+// Transition changes the state if it is still one of from.
+func (s *Breakers) Transition(ctx context.Context, connectionID string, from []string, to string, nextProbeAt, at time.Time) error {
+	found, err := s.records.update(connectionID, func(b *types.ConnectionBreaker) error {
+		if !slices.Contains(from, b.State) {
+			return database.ErrConditionFailed
+		}
+		b.State = to
+		b.ConsecutiveFailures = 0
+		b.ProbeAttempts = 0
+		b.UpdatedAt = at.UTC().Format(time.RFC3339)
+		if to == "open" {
+			b.OpenedAt = b.UpdatedAt
+		}
+		b.NextProbeAt = ""
+		if !nextProbeAt.IsZero() {
+			b.NextProbeAt = nextProbeAt.UTC().Format(time.RFC3339)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return database.ErrConditionFailed
+	}
+	return nil
+}
+
+// RecordProbeFailure counts a failed probe of an open breaker.
+func (s *Breakers) RecordProbeFailure(ctx context.Context, connectionID, errMsg string, nextProbeAt, at time.Time) error {
+	found, err := s.records.update(connectionID, func(b *types.ConnectionBreaker) error {
+		if b.State != "open" {
+			return database.ErrConditionFailed
+		}
+		b.ProbeAttempts++
+		b.LastError = errMsg
+		b.NextProbeAt = nextProbeAt.UTC().Format(time.RFC3339)
+		b.UpdatedAt = at.UTC().Format(time.RFC3339)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return database.ErrConditionFailed
+	}
+	return nil
+}
+
+// ListDue returns the open and half-open breakers scheduled by before,
+// earliest first.
+func (s *Breakers) ListDue(ctx context.Context, before time.Time, limit int) ([]types.ConnectionBreaker, error) {
+	cutoff := before.UTC().Format(time.RFC3339)
+	due, err := s.records.filter(func(b *types.ConnectionBreaker) bool {
+		return b.NextProbeAt != "" && b.NextProbeAt <= cutoff
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextProbeAt < due[j].NextProbeAt })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// ConnectionAuths is an in-memory database.ConnectionAuthStore.
+type ConnectionAuths struct {
+	records *table[types.PlatformConnectionAuth]
+}
+
+var _ database.ConnectionAuthStore = (*ConnectionAuths)(nil)
+
+// NewConnectionAuths creates an empty connection auth store.
+func NewConnectionAuths() *ConnectionAuths {
+	return &ConnectionAuths{records: newTable[types.PlatformConnectionAuth]()}
+}
+
+// GetByID returns the auth record, or nil if it does not exist.
+func (s *ConnectionAuths) GetByID(ctx context.Context, authID string) (*types.PlatformConnectionAuth, error) {
+	return s.records.get(authID)
+}
+
+// GetByConnectionID returns the auth record of a connection, or nil.
+func (s *ConnectionAuths) GetByConnectionID(ctx context.Context, connectionID string) (*types.PlatformConnectionAuth, error) {
+	return s.records.first(func(a *types.PlatformConnectionAuth) bool { return a.ConnectionID == connectionID })
+}
+
+// Create stores a new auth record.
+func (s *ConnectionAuths) Create(ctx context.Context, auth *types.PlatformConnectionAuth) error {
+	return s.records.put(auth.AuthID, auth)
+}
+
+// Update replaces the auth record.
+func (s *ConnectionAuths) Update(ctx context.Context, auth *types.PlatformConnectionAuth) error {
+	return s.records.put(auth.AuthID, auth)
+}
+
+// Revoke sets the auth status to "revoked" and records when.
+func (s *ConnectionAuths) Revoke(ctx context.Context, authID string) error {
+	now := time.Now().Unix()
+	return s.records.upsert(authID, &types.PlatformConnectionAuth{AuthID: authID}, func(a *types.PlatformConnectionAuth) error {
+		a.Status = "revoked"
+		a.RevokedAt = &now
+		a.UpdatedAt = now
+		return nil
+	})
+}
+
+// UpdateCredentials stores refreshed credentials if the stored version is
+// still expectedVersion, and returns database.ErrConditionFailed otherwise.
+func (s *ConnectionAuths) UpdateCredentials(ctx context.Context, auth *types.PlatformConnectionAuth, expectedVersion int) error {
+	found, err := s.records.update(auth.AuthID, func(a *types.PlatformConnectionAuth) error {
+		if a.Version != expectedVersion {
+			return database.ErrConditionFailed
+		}
+		a.AccessToken = auth.AccessToken
+		a.RefreshToken = auth.RefreshToken
+		a.ExpiresAt = auth.ExpiresAt
+		a.Version = auth.Version
+		a.Status = auth.Status
+		a.RefreshAttempts = auth.RefreshAttempts
+		a.LastRefreshAt = auth.LastRefreshAt
+		a.LastRefreshError = nil
+		a.UpdatedAt = auth.UpdatedAt
+		return nil
+	})
+	if err == nil && !found {
+		return database.ErrConditionFailed
+	}
+	return err
+}
+
+// RecordRefreshFailure increments refresh_attempts and stores the error.
+func (s *ConnectionAuths) RecordRefreshFailure(ctx context.Context, authID, message string) error {
+	now := time.Now().UTC().Unix()
+	return s.records.upsert(authID, &types.PlatformConnectionAuth{AuthID: authID}, func(a *types.PlatformConnectionAuth) error {
+		a.RefreshAttempts++
+		a.LastRefreshAt = &now
+		a.LastRefreshError = &message
+		a.UpdatedAt = now
+		return nil
+	})
+}
+
+// ListExpiring returns the active auths with a refresh token that expire
+// before the given time.
+func (s *ConnectionAuths) ListExpiring(ctx context.Context, before time.Time) ([]types.PlatformConnectionAuth, error) {
+	return s.records.filter(func(a *types.PlatformConnectionAuth) bool {
+		return a.Status == "active" && a.RefreshToken != "" && a.ExpiresAt > 0 && a.ExpiresAt < before.Unix()
+	})
+}
+
+// MarkExpired sets the status to "expired" and keeps the error.
+func (s *ConnectionAuths) MarkExpired(ctx context.Context, authID, message string) error {
+	now := time.Now().UTC().Unix()
+	return s.records.upsert(authID, &types.PlatformConnectionAuth{AuthID: authID}, func(a *types.PlatformConnectionAuth) error {
+		a.Status = "expired"
+		a.LastRefreshError = &message
+		a.UpdatedAt = now
+		return nil
+	})
+}
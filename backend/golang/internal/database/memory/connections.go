@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Connections is an in-memory database.ConnectionStore.
+type Connections struct {
+	records *table[types.PlatformConnection]
+}
+
+var _ database.ConnectionStore = (*Connections)(nil)
+
+// NewConnections creates an empty connection store.
+func NewConnections() *Connections {
+	return &Connections{records: newTable[types.PlatformConnection]()}
+}
+
+// GetByID returns the connection, or nil if it does not exist.
+func (s *Connections) GetByID(ctx context.Context, connectionID string) (*types.PlatformConnection, error) {
+	return s.records.get(connectionID)
+}
+
+// ListByAccount returns the connections of an account.
+func (s *Connections) ListByAccount(ctx context.Context, accountID string) ([]types.PlatformConnection, error) {
+	return s.records.filter(func(c *types.PlatformConnection) bool { return c.AccountID == accountID })
+}
+
+// Create stores a new connection.
+func (s *Connections) Create(ctx context.Context, conn *types.PlatformConnection) error {
+	return s.records.put(conn.ConnectionID, conn)
+}
+
+// Update replaces the connection record.
+func (s *Connections) Update(ctx context.Context, conn *types.PlatformConnection) error {
+	return s.records.put(conn.ConnectionID, conn)
+}
+
+// Delete removes the connection.
+func (s *Connections) Delete(ctx context.Context, connectionID string) error {
+	s.records.delete(connectionID)
+	return nil
+}
+
+// UpdateSyncStatus sets the sync-related fields of a connection.
+func (s *Connections) UpdateSyncStatus(ctx context.Context, connectionID, status string, counts map[string]int) error {
+	now := time.Now().UTC()
+	return s.records.upsert(connectionID, &types.PlatformConnection{ConnectionID: connectionID}, func(c *types.PlatformConnection) error {
+		c.SyncStatus = status
+		c.LastSyncedAt = &now
+		c.UpdatedAt = now
+		if counts != nil {
+			c.SyncRecordCounts = counts
+		}
+		return nil
+	})
+}
+
+// SetStatus sets the connection's status.
+func (s *Connections) SetStatus(ctx context.Context, connectionID, status string) error {
+	return s.records.upsert(connectionID, &types.PlatformConnection{ConnectionID: connectionID}, func(c *types.PlatformConnection) error {
+		c.Status = status
+		c.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
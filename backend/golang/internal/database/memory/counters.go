@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+)
+
+// Counters is an in-memory database.CounterStore. Expired counters read as 0
+// and restart from 0 when incremented, as if DynamoDB's TTL had removed them.
+type Counters struct {
+	mu       sync.Mutex
+	counters map[string]counter
+	now      func() time.Time
+}
+
+type counter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+var _ database.CounterStore = (*Counters)(nil)
+
+// NewCounters creates an empty counter store.
+func NewCounters() *Counters {
+	return &Counters{counters: make(map[string]counter), now: time.Now}
+}
+
+// Get returns the value of a counter, or 0 if it does not exist or has expired.
+func (s *Counters) Get(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.live(key).count, nil
+}
+
+// Increment adds delta to a counter and returns the new value. A non-zero
+// expiresAt replaces the counter's expiry.
+func (s *Counters) Increment(ctx context.Context, key string, delta int64, expiresAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.live(key)
+	c.count += delta
+	if !expiresAt.IsZero() {
+		c.expiresAt = expiresAt
+	}
+	s.counters[key] = c
+	return c.count, nil
+}
+
+// live returns the counter, or a zero counter if it has expired; the caller
+// holds the lock
+func (s *Counters) live(key string) counter {
+	c, ok := s.counters[key]
+	if !ok || (!c.expiresAt.IsZero() && !s.now().Before(c.expiresAt)) {
+		return counter{}
+	}
+	return c
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Delayed is an in-memory database.DelayedExecutionStore.
+type Delayed struct {
+	records *table[types.DelayedExecution]
+}
+
+var _ database.DelayedExecutionStore = (*Delayed)(nil)
+
+// NewDelayed creates an empty delayed execution store.
+func NewDelayed() *Delayed {
+	return &Delayed{records: newTable[types.DelayedExecution]()}
+}
+
+// Get returns the delayed execution, or nil if it does not exist.
+func (s *Delayed) Get(ctx context.Context, executionID string) (*types.DelayedExecution, error) {
+	return s.records.get(executionID)
+}
+
+// Create stores a new delayed execution and returns ErrConditionFailed if
+// its ID is taken.
+func (s *Delayed) Create(ctx context.Context, delayed *types.DelayedExecution) error {
+	return s.records.create(delayed.ExecutionID, delayed)
+}
+
+// ListDue returns the pending executions due by before, earliest first.
+func (s *Delayed) ListDue(ctx context.Context, before time.Time, limit int) ([]types.DelayedExecution, error) {
+	cutoff := before.UTC().Format(time.RFC3339)
+	due, err := s.list(func(d *types.DelayedExecution) bool {
+		return d.Status == "pending" && d.RunAt <= cutoff
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// ListByContact returns a contact's delayed executions, earliest first. The
+// cursor is the execution_id of the last one on the previous page.
+func (s *Delayed) ListByContact(ctx context.Context, accountID, contactID, status string, limit int, cursor string) ([]types.DelayedExecution, string, error) {
+	matches, err := s.list(func(d *types.DelayedExecution) bool {
+		return d.AccountID == accountID && d.ContactID == contactID && (status == "" || d.Status == status)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return page(matches, func(d *types.DelayedExecution) string { return d.ExecutionID }, limit, cursor)
+}
+
+// ListByHelper returns a helper's delayed executions, earliest first. The
+// cursor is the execution_id of the last one on the previous page.
+func (s *Delayed) ListByHelper(ctx context.Context, helperID, status string, limit int, cursor string) ([]types.DelayedExecution, string, error) {
+	matches, err := s.list(func(d *types.DelayedExecution) bool {
+		return d.HelperID == helperID && (status == "" || d.Status == status)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return page(matches, func(d *types.DelayedExecution) string { return d.ExecutionID }, limit, cursor)
+}
+
+// Transition changes the status if it is still from.
+func (s *Delayed) Transition(ctx context.Context, executionID, from, to, reason string, at time.Time) error {
+	found, err := s.records.update(executionID, func(d *types.DelayedExecution) error {
+		if d.Status != from {
+			return database.ErrConditionFailed
+		}
+		d.Status = to
+		d.StatusReason = reason
+		d.UpdatedAt = at.UTC().Format(time.RFC3339)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return database.ErrConditionFailed
+	}
+	return nil
+}
+
+// list returns the matching delayed executions ordered by run_at
+func (s *Delayed) list(match func(d *types.DelayedExecution) bool) ([]types.DelayedExecution, error) {
+	matches, err := s.records.filter(match)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].RunAt < matches[j].RunAt })
+	return matches, nil
+}
@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// EmailLogs is an in-memory database.EmailLogStore.
+type EmailLogs struct {
+	records *table[types.EmailLog]
+}
+
+var _ database.EmailLogStore = (*EmailLogs)(nil)
+
+// NewEmailLogs creates an empty email log store.
+func NewEmailLogs() *EmailLogs {
+	return &EmailLogs{records: newTable[types.EmailLog]()}
+}
+
+// GetByID returns the email log, or nil if it does not exist.
+func (s *EmailLogs) GetByID(ctx context.Context, emailID string) (*types.EmailLog, error) {
+	return s.records.get(emailID)
+}
+
+// GetByAccountID returns an account's email logs, most recent first.
+func (s *EmailLogs) GetByAccountID(ctx context.Context, accountID string, limit int32) ([]types.EmailLog, error) {
+	return s.recent(func(l *types.EmailLog) bool { return l.AccountID == accountID }, limit)
+}
+
+// GetByRecipientEmail returns the email logs for a recipient, most recent first.
+func (s *EmailLogs) GetByRecipientEmail(ctx context.Context, recipientEmail string, limit int32) ([]types.EmailLog, error) {
+	return s.recent(func(l *types.EmailLog) bool { return l.RecipientEmail == recipientEmail }, limit)
+}
+
+// GetByAccountIDAndStatus returns an account's email logs with a status,
+// most recent first.
+func (s *EmailLogs) GetByAccountIDAndStatus(ctx context.Context, accountID, status string, limit int32) ([]types.EmailLog, error) {
+	return s.recent(func(l *types.EmailLog) bool { return l.AccountID == accountID && l.Status == status }, limit)
+}
+
+func (s *EmailLogs) recent(match func(l *types.EmailLog) bool, limit int32) ([]types.EmailLog, error) {
+	logs, err := s.records.filter(match)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].CreatedAt > logs[j].CreatedAt })
+	if limit > 0 && len(logs) > int(limit) {
+		logs = logs[:limit]
+	}
+	return logs, nil
+}
+
+// Create stores a new email log.
+func (s *EmailLogs) Create(ctx context.Context, log *types.EmailLog) error {
+	return s.records.put(log.EmailID, log)
+}
+
+// Update replaces the email log record.
+func (s *EmailLogs) Update(ctx context.Context, log *types.EmailLog) error {
+	return s.records.put(log.EmailID, log)
+}
+
+// UpdateStatus sets the status of an email log.
+func (s *EmailLogs) UpdateStatus(ctx context.Context, emailID, status string) error {
+	return s.records.upsert(emailID, &types.EmailLog{EmailID: emailID}, func(l *types.EmailLog) error {
+		l.Status = status
+		return nil
+	})
+}
@@ -0,0 +1,223 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Executions is an in-memory database.ExecutionStore.
+type Executions struct {
+	records *table[types.Execution]
+}
+
+var _ database.ExecutionStore = (*Executions)(nil)
+
+// NewExecutions creates an empty execution store.
+func NewExecutions() *Executions {
+	return &Executions{records: newTable[types.Execution]()}
+}
+
+// GetByID returns the execution, or nil if it does not exist.
+func (s *Executions) GetByID(ctx context.Context, executionID string) (*types.Execution, error) {
+	return s.records.get(executionID)
+}
+
+// ListByAccount returns an account's executions, newest first. The cursor is
+// the execution_id of the last execution on the previous page.
+func (s *Executions) ListByAccount(ctx context.Context, accountID, status string, limit int, cursor string) ([]types.Execution, string, error) {
+	return s.list(func(e *types.Execution) bool {
+		return e.AccountID == accountID && (status == "" || e.Status == status)
+	}, limit, cursor)
+}
+
+// ListByHelper returns a helper's executions, newest first.
+func (s *Executions) ListByHelper(ctx context.Context, helperID, status string, limit int, cursor string) ([]types.Execution, string, error) {
+	return s.list(func(e *types.Execution) bool {
+		return e.HelperID == helperID && (status == "" || e.Status == status)
+	}, limit, cursor)
+}
+
+func (s *Executions) list(match func(e *types.Execution) bool, limit int, cursor string) ([]types.Execution, string, error) {
+	execs, err := s.records.filter(match)
+	if err != nil {
+		return nil, "", err
+	}
+	sort.SliceStable(execs, func(i, j int) bool {
+		if execs[i].CreatedAt != execs[j].CreatedAt {
+			return execs[i].CreatedAt > execs[j].CreatedAt
+		}
+		return execs[i].ExecutionID > execs[j].ExecutionID
+	})
+
+	return page(execs, func(e *types.Execution) string { return e.ExecutionID }, limit, cursor)
+}
+
+// Create stores a new execution record unless its ID is taken.
+func (s *Executions) Create(ctx context.Context, exec *types.Execution) error {
+	return s.records.create(exec.ExecutionID, exec)
+}
+
+// UpdateResult sets the status, output and duration of a completed execution.
+func (s *Executions) UpdateResult(ctx context.Context, executionID, status string, output map[string]interface{}, durationMs int64) error {
+	now := time.Now().UTC()
+	return s.records.upsert(executionID, &types.Execution{ExecutionID: executionID}, func(e *types.Execution) error {
+		e.Status = status
+		e.DurationMs = durationMs
+		e.CompletedAt = &now
+		if output != nil {
+			e.Output = output
+		}
+		return nil
+	})
+}
+
+// Transition changes the status if it is still one of from.
+func (s *Executions) Transition(ctx context.Context, executionID string, from []string, t types.StatusTransition) error {
+	found, err := s.records.update(executionID, func(e *types.Execution) error {
+		if !slices.Contains(from, e.Status) {
+			return database.ErrConditionFailed
+		}
+		e.Status = t.Status
+		e.StatusHistory = append(e.StatusHistory, t)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return database.ErrConditionFailed
+	}
+	return nil
+}
+
+// ListPaused returns a connection's paused executions, oldest first.
+func (s *Executions) ListPaused(ctx context.Context, connectionID string, limit int) ([]types.Execution, error) {
+	execs, err := s.records.filter(func(e *types.Execution) bool {
+		return e.Status == "paused" && e.ConnectionID == connectionID
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(execs, func(i, j int) bool { return execs[i].CreatedAt < execs[j].CreatedAt })
+	if limit > 0 && len(execs) > limit {
+		execs = execs[:limit]
+	}
+	return execs, nil
+}
+
+// RecordHandback counts a hand-back of the message, starting over for a new
+// message.
+func (s *Executions) RecordHandback(ctx context.Context, executionID, messageID string) error {
+	found, err := s.records.update(executionID, func(e *types.Execution) error {
+		if e.HandbackMessageID != messageID {
+			e.Handbacks, e.HandbackMessageID = 0, messageID
+		}
+		e.Handbacks++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return database.ErrConditionFailed
+	}
+	return nil
+}
+
+// RecordAttempt sets the attempt number.
+func (s *Executions) RecordAttempt(ctx context.Context, executionID string, attempt int) error {
+	return s.set(executionID, func(e *types.Execution) { e.Attempts = attempt })
+}
+
+// RecordError keeps the error message.
+func (s *Executions) RecordError(ctx context.Context, executionID, message string) error {
+	return s.set(executionID, func(e *types.Execution) { e.ErrorMessage = message })
+}
+
+// RecordOutcome sets the result of a finished execution.
+func (s *Executions) RecordOutcome(ctx context.Context, executionID string, outcome database.ExecutionOutcome) error {
+	return s.set(executionID, func(e *types.Execution) {
+		completedAt := outcome.CompletedAt.UTC()
+		e.CompletedAt = &completedAt
+		e.DurationMs = outcome.DurationMs
+		if outcome.ErrorMessage != "" {
+			e.ErrorMessage = outcome.ErrorMessage
+		}
+		if outcome.ErrorCode != "" {
+			e.ErrorCode = outcome.ErrorCode
+		}
+		if outcome.ErrorStack != "" {
+			e.ErrorStack = outcome.ErrorStack
+		}
+		if outcome.Output != nil {
+			e.Output = outcome.Output
+		}
+	})
+}
+
+// RecordTrace replaces the connector trace and sets a missing TTL.
+func (s *Executions) RecordTrace(ctx context.Context, executionID string, calls []types.ConnectorCall, dropped int, expiresAt time.Time) error {
+	return s.set(executionID, func(e *types.Execution) {
+		e.ConnectorTrace = calls
+		e.ConnectorCallsDropped = dropped
+		if e.TTL == nil {
+			ttl := expiresAt.Unix()
+			e.TTL = &ttl
+		}
+	})
+}
+
+// AppendRetryAttempt adds to the retry history.
+func (s *Executions) AppendRetryAttempt(ctx context.Context, executionID string, attempt types.RetryAttempt) error {
+	return s.set(executionID, func(e *types.Execution) { e.RetryAttempts = append(e.RetryAttempts, attempt) })
+}
+
+// RecordDeliveries sets the action deliveries.
+func (s *Executions) RecordDeliveries(ctx context.Context, executionID string, deliveries []types.ActionDelivery) error {
+	return s.set(executionID, func(e *types.Execution) { e.ActionDeliveries = deliveries })
+}
+
+// RecordStripeUsage marks the execution as reported.
+func (s *Executions) RecordStripeUsage(ctx context.Context, executionID, usageRecordID string) error {
+	return s.set(executionID, func(e *types.Execution) {
+		e.StripeReported = true
+		e.StripeUsageRecordID = usageRecordID
+	})
+}
+
+// ClaimReplay claims a dead-lettered execution that was not replayed yet.
+func (s *Executions) ClaimReplay(ctx context.Context, executionID, replayID string) error {
+	found, err := s.records.update(executionID, func(e *types.Execution) error {
+		if e.Status != "dead_lettered" || e.ReplayedAs != "" {
+			return database.ErrConditionFailed
+		}
+		e.ReplayedAs = replayID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return database.ErrConditionFailed
+	}
+	return nil
+}
+
+// ReleaseReplay removes a replay claim.
+func (s *Executions) ReleaseReplay(ctx context.Context, executionID string) error {
+	return s.set(executionID, func(e *types.Execution) { e.ReplayedAs = "" })
+}
+
+// set applies fn to the execution, creating it if missing the way an
+// unconditional DynamoDB update does
+func (s *Executions) set(executionID string, fn func(e *types.Execution)) error {
+	return s.records.upsert(executionID, &types.Execution{ExecutionID: executionID}, func(e *types.Execution) error {
+		fn(e)
+		return nil
+	})
+}
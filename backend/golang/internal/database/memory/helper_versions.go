@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// HelperVersions is an in-memory database.HelperVersionStore. Commits
+// update the records of the Helpers store it was created with.
+type HelperVersions struct {
+	mu      sync.Mutex // serializes commits
+	records *table[types.HelperConfigVersion]
+	helpers *Helpers
+}
+
+var _ database.HelperVersionStore = (*HelperVersions)(nil)
+
+// NewHelperVersions creates an empty helper version store over helpers.
+func NewHelperVersions(helpers *Helpers) *HelperVersions {
+	return &HelperVersions{records: newTable[types.HelperConfigVersion](), helpers: helpers}
+}
+
+func versionKey(helperID string, version int) string {
+	return fmt.Sprintf("%s#%010d", helperID, version)
+}
+
+// Get returns the version, or nil if it does not exist.
+func (s *HelperVersions) Get(ctx context.Context, helperID string, version int) (*types.HelperConfigVersion, error) {
+	return s.records.get(versionKey(helperID, version))
+}
+
+// List returns a helper's versions, newest first. The cursor is the version
+// number of the last version on the previous page.
+func (s *HelperVersions) List(ctx context.Context, helperID string, limit int, cursor string) ([]types.HelperConfigVersion, string, error) {
+	versions, err := s.records.filter(func(v *types.HelperConfigVersion) bool { return v.HelperID == helperID })
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version > versions[j].Version })
+	return page(versions, func(v *types.HelperConfigVersion) string { return strconv.Itoa(v.Version) }, limit, cursor)
+}
+
+// Commit stores the versions and moves the helper's config to the last one
+// if the helper is still at the expected version.
+func (s *HelperVersions) Commit(ctx context.Context, expected int, versions ...types.HelperConfigVersion) error {
+	if len(versions) == 0 {
+		return fmt.Errorf("commit helper versions: no versions")
+	}
+	latest := versions[len(versions)-1]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range versions {
+		if existing, _ := s.records.get(versionKey(v.HelperID, v.Version)); existing != nil {
+			return database.ErrConditionFailed
+		}
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, latest.CreatedAt)
+	found, err := s.helpers.records.update(latest.HelperID, func(h *types.Helper) error {
+		if h.ConfigVersion != expected {
+			return database.ErrConditionFailed
+		}
+		h.Config = latest.Config
+		h.ConfigVersion = latest.Version
+		h.UpdatedAt = updatedAt
+		return nil
+	})
+	if err == nil && !found {
+		err = database.ErrConditionFailed
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range versions {
+		if err := s.records.create(versionKey(versions[i].HelperID, versions[i].Version), &versions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
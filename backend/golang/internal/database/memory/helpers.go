@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Helpers is an in-memory database.HelperStore.
+type Helpers struct {
+	records *table[types.Helper]
+}
+
+var _ database.HelperStore = (*Helpers)(nil)
+
+// NewHelpers creates an empty helper store.
+func NewHelpers() *Helpers {
+	return &Helpers{records: newTable[types.Helper]()}
+}
+
+// GetByID returns the helper, or nil if it does not exist.
+func (s *Helpers) GetByID(ctx context.Context, helperID string) (*types.Helper, error) {
+	return s.records.get(helperID)
+}
+
+// GetByShortKey returns the helper with the given short_key, or nil.
+func (s *Helpers) GetByShortKey(ctx context.Context, shortKey string) (*types.Helper, error) {
+	return s.records.first(func(h *types.Helper) bool { return h.ShortKey == shortKey })
+}
+
+// ListByAccount returns the helpers of an account.
+func (s *Helpers) ListByAccount(ctx context.Context, accountID string) ([]types.Helper, error) {
+	return s.records.filter(func(h *types.Helper) bool { return h.AccountID == accountID })
+}
+
+// Create stores a new helper, failing with database.ErrConditionFailed if
+// the helper_id is taken.
+func (s *Helpers) Create(ctx context.Context, helper *types.Helper) error {
+	return s.records.create(helper.HelperID, helper)
+}
+
+// Update replaces the helper record.
+func (s *Helpers) Update(ctx context.Context, helper *types.Helper) error {
+	return s.records.put(helper.HelperID, helper)
+}
+
+// UpdateSettings copies the user-editable fields of helper onto the stored
+// helper.
+func (s *Helpers) UpdateSettings(ctx context.Context, helper *types.Helper) error {
+	found, err := s.records.update(helper.HelperID, func(h *types.Helper) error {
+		h.Name = helper.Name
+		h.Description = helper.Description
+		h.Status = helper.Status
+		h.Enabled = helper.Enabled
+		h.ConnectionID = helper.ConnectionID
+		h.ScheduleEnabled = helper.ScheduleEnabled
+		h.CronExpression = helper.CronExpression
+		h.ScheduleRuleARN = helper.ScheduleRuleARN
+		h.UpdatedAt = helper.UpdatedAt
+		return nil
+	})
+	if err == nil && !found {
+		err = database.ErrConditionFailed
+	}
+	if err != nil {
+		return fmt.Errorf("update helper settings: %w", err)
+	}
+	return nil
+}
+
+// SoftDelete sets the helper status to "deleted".
+func (s *Helpers) SoftDelete(ctx context.Context, helperID string) error {
+	found, err := s.records.update(helperID, func(h *types.Helper) error {
+		h.Status = "deleted"
+		h.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+	if err == nil && !found {
+		err = database.ErrConditionFailed
+	}
+	if err != nil {
+		return fmt.Errorf("soft delete helper: %w", err)
+	}
+	return nil
+}
+
+// UpdateExecutionStats sets the execution count and last executed timestamp.
+func (s *Helpers) UpdateExecutionStats(ctx context.Context, helperID string, count int64, lastAt time.Time) error {
+	_, err := s.records.update(helperID, func(h *types.Helper) error {
+		h.ExecutionCount = count
+		h.LastExecutedAt = &lastAt
+		h.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+	return err
+}
+
+// RecordExecution adds one to the execution count and sets the last executed
+// time.
+func (s *Helpers) RecordExecution(ctx context.Context, helperID string, at time.Time) error {
+	_, err := s.records.update(helperID, func(h *types.Helper) error {
+		executedAt := at.UTC()
+		h.ExecutionCount++
+		h.LastExecutedAt = &executedAt
+		return nil
+	})
+	return err
+}
+
+// ListScheduled returns the helpers whose schedule is enabled.
+func (s *Helpers) ListScheduled(ctx context.Context) ([]types.Helper, error) {
+	return s.records.filter(func(h *types.Helper) bool { return h.ScheduleEnabled })
+}
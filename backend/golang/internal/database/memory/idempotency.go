@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+)
+
+// Idempotency is an in-memory database.IdempotencyStore. Expired claims are
+// ignored, as if DynamoDB's TTL had removed them.
+type Idempotency struct {
+	mu     sync.Mutex
+	claims map[string]claim
+	now    func() time.Time
+}
+
+type claim struct {
+	executionID string
+	expiresAt   time.Time
+}
+
+var _ database.IdempotencyStore = (*Idempotency)(nil)
+
+// NewIdempotency creates an empty idempotency store.
+func NewIdempotency() *Idempotency {
+	return &Idempotency{claims: make(map[string]claim), now: time.Now}
+}
+
+// Claim records executionID under key unless an unexpired claim exists.
+func (s *Idempotency) Claim(ctx context.Context, key, executionID string, expiresAt time.Time) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.claims[key]; ok && s.now().Before(c.expiresAt) {
+		return c.executionID, false, nil
+	}
+	s.claims[key] = claim{executionID: executionID, expiresAt: expiresAt}
+	return executionID, true, nil
+}
+
+// Release removes the claim if executionID still holds it.
+func (s *Idempotency) Release(ctx context.Context, key, executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.claims[key]; ok && c.executionID == executionID {
+		delete(s.claims, key)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+)
+
+// Leases is an in-memory database.LeaseStore. Expired leases are ignored, as
+// if DynamoDB's TTL had removed them.
+type Leases struct {
+	mu     sync.Mutex
+	leases map[string]lease
+	now    func() time.Time
+}
+
+type lease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+var _ database.LeaseStore = (*Leases)(nil)
+
+// NewLeases creates an empty lease store.
+func NewLeases() *Leases {
+	return &Leases{leases: make(map[string]lease), now: time.Now}
+}
+
+// Acquire takes the lease unless another owner holds an unexpired one.
+func (s *Leases) Acquire(ctx context.Context, key, owner string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[key]; ok && l.owner != owner && s.now().Before(l.expiresAt) {
+		return false, nil
+	}
+	s.leases[key] = lease{owner: owner, expiresAt: expiresAt}
+	return true, nil
+}
+
+// Release removes the lease if owner still holds it.
+func (s *Leases) Release(ctx context.Context, key, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[key]; ok && l.owner == owner {
+		delete(s.leases, key)
+	}
+	return nil
+}
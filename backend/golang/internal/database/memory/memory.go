@@ -0,0 +1,180 @@
+// Package memory provides thread-safe in-memory implementations of the
+// database store interfaces, for unit tests and offline runs that should not
+// need DynamoDB.
+//
+// Records are kept in their DynamoDB attribute-value form, so values read
+// back go through the same marshaling as the DynamoDB repositories (numbers
+// in free-form maps come back as float64, times as RFC 3339) and callers
+// never share memory with the store.
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/myfusionhelper/api/internal/database"
+)
+
+// NewStores returns a database.Stores backed by empty in-memory stores.
+func NewStores() *database.Stores {
+	helpers := NewHelpers()
+	return &database.Stores{
+		Accounts:        NewAccounts(),
+		Helpers:         helpers,
+		Executions:      NewExecutions(),
+		Connections:     NewConnections(),
+		ConnectionAuths: NewConnectionAuths(),
+		Platforms:       NewPlatforms(),
+		APIKeys:         NewAPIKeys(),
+		Counters:        NewCounters(),
+		Idempotency:     NewIdempotency(),
+		Leases:          NewLeases(),
+		Batches:         NewBatches(),
+		HelperVersions:  NewHelperVersions(helpers),
+		Delayed:         NewDelayed(),
+		Breakers:        NewBreakers(),
+		EmailLogs:       NewEmailLogs(),
+		WorkflowRuns:    NewWorkflowRuns(),
+	}
+}
+
+// table holds the records of one store by primary key
+type table[T any] struct {
+	mu    sync.RWMutex
+	items map[string]map[string]ddbtypes.AttributeValue
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{items: make(map[string]map[string]ddbtypes.AttributeValue)}
+}
+
+// get returns a copy of the record, or nil if there is none
+func (t *table[T]) get(key string) (*T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	av, ok := t.items[key]
+	if !ok {
+		return nil, nil
+	}
+	var item T
+	if err := attributevalue.UnmarshalMap(av, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// put stores a copy of the record, replacing any existing one
+func (t *table[T]) put(key string, item *T) error {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.items[key] = av
+	return nil
+}
+
+// create stores a copy of the record unless one exists with the same key
+func (t *table[T]) create(key string, item *T) error {
+	av, err := attributevalue.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.items[key]; exists {
+		return database.ErrConditionFailed
+	}
+	t.items[key] = av
+	return nil
+}
+
+// update applies fn to the record under the write lock and stores the
+// result. It reports false if there is no record with that key; an error
+// from fn leaves the record unchanged.
+func (t *table[T]) update(key string, fn func(item *T) error) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	av, ok := t.items[key]
+	if !ok {
+		return false, nil
+	}
+	return true, t.apply(key, av, fn)
+}
+
+// upsert is update for a record that may not exist yet, in which case fn is
+// applied to init, the way a DynamoDB UpdateItem creates missing items.
+func (t *table[T]) upsert(key string, init *T, fn func(item *T) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	av, ok := t.items[key]
+	if !ok {
+		var err error
+		if av, err = attributevalue.MarshalMap(init); err != nil {
+			return err
+		}
+	}
+	return t.apply(key, av, fn)
+}
+
+// apply runs fn on a copy of av and stores the result; the caller holds the
+// write lock
+func (t *table[T]) apply(key string, av map[string]ddbtypes.AttributeValue, fn func(item *T) error) error {
+	var item T
+	if err := attributevalue.UnmarshalMap(av, &item); err != nil {
+		return err
+	}
+	if err := fn(&item); err != nil {
+		return err
+	}
+	updated, err := attributevalue.MarshalMap(&item)
+	if err != nil {
+		return err
+	}
+	t.items[key] = updated
+	return nil
+}
+
+// delete removes the record if it exists
+func (t *table[T]) delete(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.items, key)
+}
+
+// filter returns copies of the matching records in primary key order
+func (t *table[T]) filter(match func(item *T) bool) ([]T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	keys := make([]string, 0, len(t.items))
+	for key := range t.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	matches := make([]T, 0)
+	for _, key := range keys {
+		var item T
+		if err := attributevalue.UnmarshalMap(t.items[key], &item); err != nil {
+			return nil, err
+		}
+		if match == nil || match(&item) {
+			matches = append(matches, item)
+		}
+	}
+	return matches, nil
+}
+
+// first returns the first matching record in primary key order, or nil
+func (t *table[T]) first(match func(item *T) bool) (*T, error) {
+	matches, err := t.filter(match)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	return &matches[0], nil
+}
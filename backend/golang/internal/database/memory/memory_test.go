@@ -0,0 +1,523 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+func TestHelpers_CopiesRecords(t *testing.T) {
+	ctx := context.Background()
+	store := NewHelpers()
+
+	helper := &types.Helper{HelperID: "helper:1", ShortKey: "abc", Config: map[string]interface{}{"tag": "a"}}
+	if err := store.Create(ctx, helper); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	helper.Config["tag"] = "changed"
+
+	got, err := store.GetByShortKey(ctx, "abc")
+	if err != nil || got == nil {
+		t.Fatalf("Expected helper by short key, got %v, %v", got, err)
+	}
+	if got.Config["tag"] != "a" {
+		t.Errorf("Expected stored config to be unaffected by caller, got %v", got.Config["tag"])
+	}
+
+	got.Config["tag"] = "changed"
+	again, _ := store.GetByID(ctx, "helper:1")
+	if again.Config["tag"] != "a" {
+		t.Errorf("Expected stored config to be unaffected by reader, got %v", again.Config["tag"])
+	}
+
+	if err := store.Create(ctx, helper); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed on duplicate create, got %v", err)
+	}
+	if missing, err := store.GetByID(ctx, "helper:missing"); missing != nil || err != nil {
+		t.Errorf("Expected nil, nil for a missing helper, got %v, %v", missing, err)
+	}
+	if err := store.SoftDelete(ctx, "helper:missing"); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed deleting a missing helper, got %v", err)
+	}
+}
+
+func TestAccounts_IncrementMonthlyExecutions(t *testing.T) {
+	ctx := context.Background()
+	store := NewAccounts()
+	store.Create(ctx, &types.Account{AccountID: "acc-1", Name: "Acme"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store.IncrementMonthlyExecutions(ctx, "acc-1", 1)
+		}()
+	}
+	wg.Wait()
+
+	used, err := store.IncrementMonthlyExecutions(ctx, "acc-1", -1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if used != 49 {
+		t.Errorf("Expected 49 monthly executions, got %d", used)
+	}
+
+	account, _ := store.GetByID(ctx, "acc-1")
+	if account.Name != "Acme" || account.Usage.MonthlyExecutions != 49 {
+		t.Errorf("Expected Acme with 49 executions, got %+v", account)
+	}
+}
+
+func TestCounters_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, time.January, 2, 10, 0, 0, 0, time.UTC)
+	store := NewCounters()
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		store.Increment(ctx, "limit:a", 1, now.Add(time.Minute))
+	}
+	if got, _ := store.Get(ctx, "limit:a"); got != 3 {
+		t.Errorf("Expected 3, got %d", got)
+	}
+
+	now = now.Add(time.Minute)
+	if got, _ := store.Get(ctx, "limit:a"); got != 0 {
+		t.Errorf("Expected an expired counter to read 0, got %d", got)
+	}
+	if got, _ := store.Increment(ctx, "limit:a", 1, now.Add(time.Minute)); got != 1 {
+		t.Errorf("Expected an expired counter to restart at 1, got %d", got)
+	}
+
+	store.Increment(ctx, "split:a", 1, time.Time{})
+	now = now.Add(365 * 24 * time.Hour)
+	if got, _ := store.Increment(ctx, "split:a", 1, time.Time{}); got != 2 {
+		t.Errorf("Expected a counter without expiry to keep counting, got %d", got)
+	}
+}
+
+func TestIdempotency_Claim(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, time.January, 2, 10, 0, 0, 0, time.UTC)
+	store := NewIdempotency()
+	store.now = func() time.Time { return now }
+
+	if id, claimed, _ := store.Claim(ctx, "idem:a", "exec:1", now.Add(time.Hour)); !claimed || id != "exec:1" {
+		t.Fatalf("Expected the first claim to succeed, got %s %v", id, claimed)
+	}
+	if id, claimed, _ := store.Claim(ctx, "idem:a", "exec:2", now.Add(time.Hour)); claimed || id != "exec:1" {
+		t.Errorf("Expected a duplicate to return exec:1, got %s %v", id, claimed)
+	}
+
+	store.Release(ctx, "idem:a", "exec:2")
+	if id, _, _ := store.Claim(ctx, "idem:a", "exec:3", now.Add(time.Hour)); id != "exec:1" {
+		t.Errorf("Expected a release by another execution to be ignored, got %s", id)
+	}
+	store.Release(ctx, "idem:a", "exec:1")
+	if _, claimed, _ := store.Claim(ctx, "idem:a", "exec:4", now.Add(time.Hour)); !claimed {
+		t.Error("Expected a released key to be claimable")
+	}
+
+	now = now.Add(time.Hour)
+	if id, claimed, _ := store.Claim(ctx, "idem:a", "exec:5", now.Add(time.Hour)); !claimed || id != "exec:5" {
+		t.Errorf("Expected an expired claim to be replaced, got %s %v", id, claimed)
+	}
+}
+
+func TestLeases_Acquire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, time.January, 2, 10, 0, 0, 0, time.UTC)
+	store := NewLeases()
+	store.now = func() time.Time { return now }
+
+	if acquired, _ := store.Acquire(ctx, "lease:a", "exec:1", now.Add(time.Minute)); !acquired {
+		t.Fatal("Expected the first acquire to succeed")
+	}
+	if acquired, _ := store.Acquire(ctx, "lease:a", "exec:2", now.Add(time.Minute)); acquired {
+		t.Error("Expected a held lease to refuse another owner")
+	}
+	if acquired, _ := store.Acquire(ctx, "lease:a", "exec:1", now.Add(2*time.Minute)); !acquired {
+		t.Error("Expected the owner to re-acquire its lease")
+	}
+
+	store.Release(ctx, "lease:a", "exec:2")
+	if acquired, _ := store.Acquire(ctx, "lease:a", "exec:3", now.Add(time.Minute)); acquired {
+		t.Error("Expected a release by another owner to be ignored")
+	}
+	store.Release(ctx, "lease:a", "exec:1")
+	if acquired, _ := store.Acquire(ctx, "lease:a", "exec:3", now.Add(time.Minute)); !acquired {
+		t.Error("Expected a released lease to be available")
+	}
+
+	now = now.Add(time.Minute)
+	if acquired, _ := store.Acquire(ctx, "lease:a", "exec:4", now.Add(time.Minute)); !acquired {
+		t.Error("Expected an expired lease to be taken over")
+	}
+}
+
+func TestExecutions_ListPages(t *testing.T) {
+	ctx := context.Background()
+	store := NewExecutions()
+	base := time.Date(2026, time.January, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		store.Create(ctx, &types.Execution{
+			ExecutionID: fmt.Sprintf("exec:%d", i),
+			AccountID:   "acc-1",
+			HelperID:    "helper:1",
+			Status:      []string{"succeeded", "failed"}[i%2],
+			CreatedAt:   base.Add(time.Duration(i) * time.Minute).Format(time.RFC3339),
+		})
+	}
+	store.Create(ctx, &types.Execution{ExecutionID: "exec:other", AccountID: "acc-2"})
+
+	var ids []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		execs, next, err := store.ListByAccount(ctx, "acc-1", "", 2, cursor)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, e := range execs {
+			ids = append(ids, e.ExecutionID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	want := "[exec:4 exec:3 exec:2 exec:1 exec:0]"
+	if got := fmt.Sprint(ids); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	if failed, _, _ := store.ListByHelper(ctx, "helper:1", "failed", 10, ""); len(failed) != 2 || failed[0].ExecutionID != "exec:3" {
+		t.Errorf("Expected exec:3 and exec:1 to be failed, got %+v", failed)
+	}
+
+	if err := store.UpdateResult(ctx, "exec:0", "completed", map[string]interface{}{"ok": true}, 12); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	exec, _ := store.GetByID(ctx, "exec:0")
+	if exec.Status != "completed" || exec.DurationMs != 12 || exec.CompletedAt == nil || exec.Output["ok"] != true {
+		t.Errorf("Expected completed result, got %+v", exec)
+	}
+}
+
+func TestExecutions_RecordHandback(t *testing.T) {
+	ctx := context.Background()
+	store := NewExecutions()
+	store.Create(ctx, &types.Execution{ExecutionID: "exec:1"})
+
+	store.RecordHandback(ctx, "exec:1", "msg-1")
+	store.RecordHandback(ctx, "exec:1", "msg-1")
+	if e, _ := store.GetByID(ctx, "exec:1"); e.Handbacks != 2 || e.HandbackMessageID != "msg-1" {
+		t.Errorf("Expected 2 hand-backs of msg-1, got %d of %s", e.Handbacks, e.HandbackMessageID)
+	}
+	store.RecordHandback(ctx, "exec:1", "msg-2")
+	if e, _ := store.GetByID(ctx, "exec:1"); e.Handbacks != 1 || e.HandbackMessageID != "msg-2" {
+		t.Errorf("Expected the count to start over for msg-2, got %d of %s", e.Handbacks, e.HandbackMessageID)
+	}
+	if err := store.RecordHandback(ctx, "exec:missing", "msg-1"); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed for a missing execution, got %v", err)
+	}
+}
+
+func TestExecutions_RecordOutcome(t *testing.T) {
+	ctx := context.Background()
+	store := NewExecutions()
+	store.Create(ctx, &types.Execution{ExecutionID: "exec:1", Status: "running"})
+
+	completedAt := time.Now().UTC().Truncate(time.Second)
+	outcome := database.ExecutionOutcome{
+		CompletedAt:  completedAt,
+		ErrorMessage: "crm rejected the update",
+		ErrorCode:    "crm_error",
+		DurationMs:   42,
+		Output:       map[string]interface{}{"success": false},
+	}
+	if err := store.RecordOutcome(ctx, "exec:1", outcome); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	exec, _ := store.GetByID(ctx, "exec:1")
+	if exec.ErrorCode != "crm_error" || exec.DurationMs != 42 || exec.Output["success"] != false {
+		t.Errorf("Expected the outcome to be recorded, got %+v", exec)
+	}
+	if exec.CompletedAt == nil || !exec.CompletedAt.Equal(completedAt) {
+		t.Errorf("Expected completed_at %v, got %v", completedAt, exec.CompletedAt)
+	}
+	if exec.Status != "running" {
+		t.Errorf("Expected the status to be left alone, got %s", exec.Status)
+	}
+}
+
+func TestExecutions_ClaimReplay(t *testing.T) {
+	ctx := context.Background()
+	store := NewExecutions()
+	store.Create(ctx, &types.Execution{ExecutionID: "exec:1", Status: "dead_lettered"})
+	store.Create(ctx, &types.Execution{ExecutionID: "exec:2", Status: "failed"})
+
+	if err := store.Create(ctx, &types.Execution{ExecutionID: "exec:1"}); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed on duplicate create, got %v", err)
+	}
+
+	if err := store.ClaimReplay(ctx, "exec:1", "exec:replay-1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.ClaimReplay(ctx, "exec:1", "exec:replay-2"); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed for a second claim, got %v", err)
+	}
+	if e, _ := store.GetByID(ctx, "exec:1"); e.ReplayedAs != "exec:replay-1" {
+		t.Errorf("Expected replayed_as exec:replay-1, got %q", e.ReplayedAs)
+	}
+
+	if err := store.ReleaseReplay(ctx, "exec:1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.ClaimReplay(ctx, "exec:1", "exec:replay-3"); err != nil {
+		t.Errorf("Expected a released execution to be claimable, got %v", err)
+	}
+
+	if err := store.ClaimReplay(ctx, "exec:2", "exec:replay-4"); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed for an execution that is not dead-lettered, got %v", err)
+	}
+	if err := store.ClaimReplay(ctx, "exec:missing", "exec:replay-5"); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed for a missing execution, got %v", err)
+	}
+}
+
+func TestConnectionAuths_UpdateCredentials(t *testing.T) {
+	ctx := context.Background()
+	store := NewConnectionAuths()
+	store.Create(ctx, &types.PlatformConnectionAuth{AuthID: "auth:1", ConnectionID: "conn:1", AccessToken: "old"})
+
+	refreshed := &types.PlatformConnectionAuth{AuthID: "auth:1", AccessToken: "new", Version: 1, Status: "active"}
+	if err := store.UpdateCredentials(ctx, refreshed, 0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stale := &types.PlatformConnectionAuth{AuthID: "auth:1", AccessToken: "stale", Version: 1}
+	if err := store.UpdateCredentials(ctx, stale, 0); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed for a stale version, got %v", err)
+	}
+
+	auth, _ := store.GetByConnectionID(ctx, "conn:1")
+	if auth.AccessToken != "new" || auth.Version != 1 {
+		t.Errorf("Expected token 'new' at version 1, got %q at %d", auth.AccessToken, auth.Version)
+	}
+
+	store.RecordRefreshFailure(ctx, "auth:1", "invalid_grant")
+	auth, _ = store.GetByID(ctx, "auth:1")
+	if auth.RefreshAttempts != 1 || auth.LastRefreshError == nil || *auth.LastRefreshError != "invalid_grant" {
+		t.Errorf("Expected one recorded failure, got %+v", auth)
+	}
+}
+
+func TestBatches_SaveAndCompleteItem(t *testing.T) {
+	ctx := context.Background()
+	store := NewBatches()
+	if err := store.Create(ctx, &types.Batch{BatchID: "batch:1", AccountID: "acc-1", Status: "running"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	store.AddItem(ctx, &types.BatchItem{BatchID: "batch:1", ContactID: "c1", Status: "queued"})
+	if err := store.AddItem(ctx, &types.BatchItem{BatchID: "batch:1", ContactID: "c1", Status: "queued"}); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected a duplicate item to fail the condition, got %v", err)
+	}
+
+	stale, _ := store.GetByID(ctx, "batch:1")
+	if ok, _ := store.CompleteItem(ctx, "batch:1", "c1", "completed", "", time.Now()); !ok {
+		t.Fatal("Expected the queued item to complete")
+	}
+	if ok, _ := store.CompleteItem(ctx, "batch:1", "c1", "failed", "redelivered", time.Now()); ok {
+		t.Error("Expected a completed item to be left alone")
+	}
+
+	stale.Dispatched = 1
+	if err := store.Save(ctx, stale); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	batch, _ := store.GetByID(ctx, "batch:1")
+	if batch.Dispatched != 1 || batch.Succeeded != 1 || batch.Failed != 0 {
+		t.Errorf("Expected dispatched 1 and succeeded 1 kept by Save, got %+v", batch)
+	}
+
+	stale.Version = 0
+	if err := store.Save(ctx, stale); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected a stale version to fail the condition, got %v", err)
+	}
+
+	items, _, _ := store.ListItems(ctx, "batch:1", "completed", 10, "")
+	if len(items) != 1 || items[0].CompletedAt == "" {
+		t.Errorf("Expected one completed item, got %+v", items)
+	}
+}
+
+func TestHelperVersions_Commit(t *testing.T) {
+	ctx := context.Background()
+	helpers := NewHelpers()
+	store := NewHelperVersions(helpers)
+	helpers.Create(ctx, &types.Helper{HelperID: "helper:1", Config: map[string]interface{}{"tag": "a"}})
+
+	v := func(version int, tag string) types.HelperConfigVersion {
+		return types.HelperConfigVersion{HelperID: "helper:1", Version: version, Config: map[string]interface{}{"tag": tag},
+			CreatedAt: "2026-03-02T09:00:00Z"}
+	}
+	if err := store.Commit(ctx, 0, v(1, "a"), v(2, "b")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.Commit(ctx, 1, v(3, "c")); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected a stale expected version to fail the condition, got %v", err)
+	}
+	if got, _ := store.Get(ctx, "helper:1", 3); got != nil {
+		t.Errorf("Expected nothing stored by a failed commit, got %+v", got)
+	}
+	if err := store.Commit(ctx, 2, v(3, "c")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	helper, _ := helpers.GetByID(ctx, "helper:1")
+	if helper.ConfigVersion != 3 || helper.Config["tag"] != "c" {
+		t.Errorf("Expected the helper at version 3 with tag c, got %d %v", helper.ConfigVersion, helper.Config)
+	}
+
+	first, next, _ := store.List(ctx, "helper:1", 2, "")
+	rest, last, _ := store.List(ctx, "helper:1", 2, next)
+	if len(first) != 2 || first[0].Version != 3 || len(rest) != 1 || rest[0].Version != 1 || last != "" {
+		t.Errorf("Expected versions 3, 2 then 1, got %+v then %+v", first, rest)
+	}
+}
+
+func TestDelayed_ListAndTransition(t *testing.T) {
+	ctx := context.Background()
+	store := NewDelayed()
+	base := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	for i, contact := range []string{"c1", "c2", "c1"} {
+		store.Create(ctx, &types.DelayedExecution{
+			ExecutionID: fmt.Sprintf("exec:%d", i),
+			AccountID:   "acc-1",
+			HelperID:    "helper:1",
+			ContactID:   contact,
+			RunAt:       base.Add(time.Duration(3-i) * time.Minute).Format(time.RFC3339),
+			Status:      "pending",
+		})
+	}
+
+	due, _ := store.ListDue(ctx, base.Add(2*time.Minute), 10)
+	if len(due) != 2 || due[0].ExecutionID != "exec:2" || due[1].ExecutionID != "exec:1" {
+		t.Errorf("Expected exec:2 then exec:1 due, got %+v", due)
+	}
+
+	if err := store.Transition(ctx, "exec:2", "pending", "cancelled", "cancelled by user-1", base); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.Transition(ctx, "exec:2", "pending", "released", "", base); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected a cancelled execution to refuse release, got %v", err)
+	}
+	if err := store.Transition(ctx, "exec:9", "pending", "released", "", base); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected a missing execution to fail the condition, got %v", err)
+	}
+
+	pending, _, _ := store.ListByContact(ctx, "acc-1", "c1", "pending", 10, "")
+	if len(pending) != 1 || pending[0].ExecutionID != "exec:0" {
+		t.Errorf("Expected only exec:0 pending for c1, got %+v", pending)
+	}
+	first, next, _ := store.ListByHelper(ctx, "helper:1", "", 2, "")
+	rest, last, _ := store.ListByHelper(ctx, "helper:1", "", 2, next)
+	if len(first) != 2 || first[0].ExecutionID != "exec:2" || len(rest) != 1 || rest[0].ExecutionID != "exec:0" || last != "" {
+		t.Errorf("Expected exec:2, exec:1 then exec:0, got %+v then %+v", first, rest)
+	}
+}
+
+func TestBreakers_FailuresAndTransition(t *testing.T) {
+	ctx := context.Background()
+	store := NewBreakers()
+	base := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+	store.RecordFailure(ctx, "conn:1", "acc-1", "401 unauthorized", base)
+	b, err := store.RecordFailure(ctx, "conn:1", "acc-1", "401 unauthorized", base)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if b.State != "closed" || b.ConsecutiveFailures != 2 {
+		t.Errorf("Expected a closed breaker with 2 failures, got %+v", b)
+	}
+
+	if err := store.Transition(ctx, "conn:1", []string{"closed"}, "open", base.Add(time.Minute), base); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.Transition(ctx, "conn:1", []string{"closed"}, "open", base.Add(time.Minute), base); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected an open breaker to refuse opening again, got %v", err)
+	}
+	if due, _ := store.ListDue(ctx, base, 10); len(due) != 0 {
+		t.Errorf("Expected no breaker due before its probe, got %+v", due)
+	}
+
+	if err := store.RecordProbeFailure(ctx, "conn:1", "still down", base.Add(3*time.Minute), base.Add(time.Minute)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	due, _ := store.ListDue(ctx, base.Add(3*time.Minute), 10)
+	if len(due) != 1 || due[0].ProbeAttempts != 1 || due[0].ConsecutiveFailures != 0 || due[0].OpenedAt != "2026-03-02T09:00:00Z" {
+		t.Errorf("Expected the open breaker due after one failed probe, got %+v", due)
+	}
+
+	if err := store.Transition(ctx, "conn:1", []string{"open"}, "closed", time.Time{}, base); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if due, _ := store.ListDue(ctx, base.Add(time.Hour), 10); len(due) != 0 {
+		t.Errorf("Expected a closed breaker not to be scheduled, got %+v", due)
+	}
+	if err := store.RecordProbeFailure(ctx, "conn:1", "", base, base); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected a closed breaker to refuse a probe failure, got %v", err)
+	}
+}
+
+func TestWorkflowRuns_SaveAndListDue(t *testing.T) {
+	ctx := context.Background()
+	store := NewWorkflowRuns()
+	now := time.Now().UTC()
+
+	type run struct {
+		State string `dynamodbav:"state"`
+	}
+
+	if err := store.Save(ctx, "run:1", 0, now.Add(-time.Minute).Format(time.RFC3339), run{State: "waiting"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.Save(ctx, "run:1", 0, "", run{State: "duplicate"}); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed on duplicate create, got %v", err)
+	}
+	store.Save(ctx, "run:2", 0, now.Add(time.Hour).Format(time.RFC3339), run{State: "waiting"})
+	store.Save(ctx, "run:3", 0, "", run{State: "running"})
+
+	due, err := store.ListDue(ctx, now)
+	if err != nil || len(due) != 1 || due[0] != "run:1" {
+		t.Errorf("Expected only run:1 to be due, got %v, %v", due, err)
+	}
+
+	if err := store.Save(ctx, "run:1", 1, "", run{State: "running"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := store.Save(ctx, "run:1", 1, "", run{State: "stale"}); !errors.Is(err, database.ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed for a stale version, got %v", err)
+	}
+
+	var got run
+	if found, err := store.Get(ctx, "run:1", &got); err != nil || !found || got.State != "running" {
+		t.Errorf("Expected run:1 running, got %+v, %v, %v", got, found, err)
+	}
+	if due, _ := store.ListDue(ctx, now); len(due) != 0 {
+		t.Errorf("Expected no due runs once run:1 stopped waiting, got %v", due)
+	}
+	if found, _ := store.Get(ctx, "run:missing", &got); found {
+		t.Errorf("Expected a missing run not to be found")
+	}
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Platforms is an in-memory database.PlatformStore.
+type Platforms struct {
+	records *table[types.Platform]
+}
+
+var _ database.PlatformStore = (*Platforms)(nil)
+
+// NewPlatforms creates an empty platform store.
+func NewPlatforms() *Platforms {
+	return &Platforms{records: newTable[types.Platform]()}
+}
+
+// GetByID returns the platform, or nil if it does not exist.
+func (s *Platforms) GetByID(ctx context.Context, platformID string) (*types.Platform, error) {
+	return s.records.get(platformID)
+}
+
+// GetBySlug returns the platform with the given slug, or nil.
+func (s *Platforms) GetBySlug(ctx context.Context, slug string) (*types.Platform, error) {
+	return s.records.first(func(p *types.Platform) bool { return p.Slug == slug })
+}
+
+// ListAll returns every platform.
+func (s *Platforms) ListAll(ctx context.Context) ([]types.Platform, error) {
+	return s.records.filter(nil)
+}
+
+// Create stores a new platform.
+func (s *Platforms) Create(ctx context.Context, platform *types.Platform) error {
+	return s.records.put(platform.PlatformID, platform)
+}
+
+// Update replaces the platform record.
+func (s *Platforms) Update(ctx context.Context, platform *types.Platform) error {
+	return s.records.put(platform.PlatformID, platform)
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/myfusionhelper/api/internal/database"
+)
+
+// WorkflowRuns is an in-memory database.WorkflowRunStore.
+type WorkflowRuns struct {
+	mu   sync.RWMutex
+	runs map[string]workflowRun
+}
+
+// workflowRun is a stored run document with what the store indexes it by
+type workflowRun struct {
+	item    map[string]ddbtypes.AttributeValue
+	version int
+	wakeAt  string
+}
+
+var _ database.WorkflowRunStore = (*WorkflowRuns)(nil)
+
+// NewWorkflowRuns creates an empty workflow run store.
+func NewWorkflowRuns() *WorkflowRuns {
+	return &WorkflowRuns{runs: make(map[string]workflowRun)}
+}
+
+// Get unmarshals the run into run and reports whether it exists.
+func (s *WorkflowRuns) Get(ctx context.Context, runID string, run interface{}) (bool, error) {
+	s.mu.RLock()
+	stored, ok := s.runs[runID]
+	s.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	return true, attributevalue.UnmarshalMap(stored.item, run)
+}
+
+// Save stores the run if its stored version is still version.
+func (s *WorkflowRuns) Save(ctx context.Context, runID string, version int, wakeAt string, run interface{}) error {
+	item, err := attributevalue.MarshalMap(run)
+	if err != nil {
+		return err
+	}
+	item["run_id"] = &ddbtypes.AttributeValueMemberS{Value: runID}
+	item["version"] = &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(version + 1)}
+	if wakeAt != "" {
+		item["next_wake_at"] = &ddbtypes.AttributeValueMemberS{Value: wakeAt}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, exists := s.runs[runID]
+	if exists != (version > 0) || (exists && stored.version != version) {
+		return database.ErrConditionFailed
+	}
+	s.runs[runID] = workflowRun{item: item, version: version + 1, wakeAt: wakeAt}
+	return nil
+}
+
+// ListDue returns the IDs of runs due by before, in run ID order.
+func (s *WorkflowRuns) ListDue(ctx context.Context, before time.Time) ([]string, error) {
+	cutoff := before.UTC().Format(time.RFC3339)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var runIDs []string
+	for runID, stored := range s.runs {
+		if stored.wakeAt != "" && stored.wakeAt <= cutoff {
+			runIDs = append(runIDs, runID)
+		}
+	}
+	sort.Strings(runIDs)
+	return runIDs, nil
+}
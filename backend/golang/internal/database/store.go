@@ -0,0 +1,387 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// ErrConditionFailed is returned by conditional writes whose condition did
+// not hold, such as a versioned credential update that lost a race.
+var ErrConditionFailed = errors.New("condition failed")
+
+// AccountStore reads and writes account records.
+type AccountStore interface {
+	GetByID(ctx context.Context, accountID string) (*types.Account, error)
+	GetByOwner(ctx context.Context, ownerUserID string) ([]types.Account, error)
+	Create(ctx context.Context, account *types.Account) error
+	Update(ctx context.Context, account *types.Account) error
+	// IncrementMonthlyExecutions atomically adds delta to usage.monthly_executions
+	// and returns the new value.
+	IncrementMonthlyExecutions(ctx context.Context, accountID string, delta int) (int, error)
+}
+
+// HelperStore reads and writes helper records.
+type HelperStore interface {
+	GetByID(ctx context.Context, helperID string) (*types.Helper, error)
+	GetByShortKey(ctx context.Context, shortKey string) (*types.Helper, error)
+	ListByAccount(ctx context.Context, accountID string) ([]types.Helper, error)
+	Create(ctx context.Context, helper *types.Helper) error
+	Update(ctx context.Context, helper *types.Helper) error
+	// UpdateSettings writes the helper's name, description, status, enabled
+	// flag, connection and schedule, leaving its config and execution stats
+	// to the writers that own them. It returns ErrConditionFailed if the
+	// helper does not exist.
+	UpdateSettings(ctx context.Context, helper *types.Helper) error
+	SoftDelete(ctx context.Context, helperID string) error
+	UpdateExecutionStats(ctx context.Context, helperID string, count int64, lastAt time.Time) error
+	// RecordExecution adds one to the helper's execution count and sets its
+	// last executed time.
+	RecordExecution(ctx context.Context, helperID string, at time.Time) error
+	// ListScheduled returns every helper whose schedule is enabled.
+	ListScheduled(ctx context.Context) ([]types.Helper, error)
+}
+
+// ExecutionOutcome is what the worker records on an execution that reached
+// a final status.
+type ExecutionOutcome struct {
+	CompletedAt  time.Time
+	ErrorMessage string
+	ErrorCode    string
+	ErrorStack   string
+	DurationMs   int64
+	Output       map[string]interface{}
+}
+
+// ExecutionStore reads and writes execution records. List methods return
+// the newest executions first with an opaque cursor for the next page, only
+// those in status unless it is empty.
+type ExecutionStore interface {
+	GetByID(ctx context.Context, executionID string) (*types.Execution, error)
+	ListByAccount(ctx context.Context, accountID, status string, limit int, cursor string) ([]types.Execution, string, error)
+	ListByHelper(ctx context.Context, helperID, status string, limit int, cursor string) ([]types.Execution, string, error)
+	// Create stores a new execution and returns ErrConditionFailed if its
+	// execution ID is taken.
+	Create(ctx context.Context, exec *types.Execution) error
+	UpdateResult(ctx context.Context, executionID, status string, output map[string]interface{}, durationMs int64) error
+	// Transition sets the status to t.Status and appends t to the status
+	// history if the current status is one of from. It returns
+	// ErrConditionFailed, changing nothing, otherwise or if the execution
+	// does not exist.
+	Transition(ctx context.Context, executionID string, from []string, t types.StatusTransition) error
+	// ListPaused returns up to limit of the executions a connection's
+	// circuit breaker holds as paused, oldest first.
+	ListPaused(ctx context.Context, connectionID string, limit int) ([]types.Execution, error)
+	// RecordHandback counts a receive of the SQS message messageID that was
+	// handed back without running the execution. Counts kept for an earlier
+	// message of the execution are replaced.
+	RecordHandback(ctx context.Context, executionID, messageID string) error
+	// RecordAttempt sets the number of the attempt being run. This and the
+	// other Record methods leave the status alone; it only changes through
+	// Transition.
+	RecordAttempt(ctx context.Context, executionID string, attempt int) error
+	// RecordError keeps the error that sent the execution to a non-final
+	// status, such as retrying.
+	RecordError(ctx context.Context, executionID, message string) error
+	// RecordOutcome sets the result of an execution that reached a final
+	// status. Empty error fields and a nil output are not written.
+	RecordOutcome(ctx context.Context, executionID string, outcome ExecutionOutcome) error
+	// RecordTrace replaces the connector trace of the latest attempt and
+	// sets the execution to expire at expiresAt unless it already expires.
+	RecordTrace(ctx context.Context, executionID string, calls []types.ConnectorCall, dropped int, expiresAt time.Time) error
+	// AppendRetryAttempt adds a failed attempt to the retry history.
+	AppendRetryAttempt(ctx context.Context, executionID string, attempt types.RetryAttempt) error
+	// RecordDeliveries sets the outcome of the post-execution actions.
+	RecordDeliveries(ctx context.Context, executionID string, deliveries []types.ActionDelivery) error
+	// RecordStripeUsage marks the execution as reported to Stripe.
+	RecordStripeUsage(ctx context.Context, executionID, usageRecordID string) error
+	// ClaimReplay records replayID as the replay of a dead-lettered
+	// execution. It returns ErrConditionFailed, changing nothing, if the
+	// execution is not dead-lettered or was already replayed.
+	ClaimReplay(ctx context.Context, executionID, replayID string) error
+	// ReleaseReplay removes a replay claim whose replay could not be stored.
+	ReleaseReplay(ctx context.Context, executionID string) error
+}
+
+// ConnectionStore reads and writes platform connection records.
+type ConnectionStore interface {
+	GetByID(ctx context.Context, connectionID string) (*types.PlatformConnection, error)
+	ListByAccount(ctx context.Context, accountID string) ([]types.PlatformConnection, error)
+	Create(ctx context.Context, conn *types.PlatformConnection) error
+	Update(ctx context.Context, conn *types.PlatformConnection) error
+	Delete(ctx context.Context, connectionID string) error
+	UpdateSyncStatus(ctx context.Context, connectionID, status string, counts map[string]int) error
+	// SetStatus sets the connection's status, such as expired.
+	SetStatus(ctx context.Context, connectionID, status string) error
+}
+
+// ConnectionAuthStore reads and writes platform connection credentials.
+type ConnectionAuthStore interface {
+	GetByID(ctx context.Context, authID string) (*types.PlatformConnectionAuth, error)
+	GetByConnectionID(ctx context.Context, connectionID string) (*types.PlatformConnectionAuth, error)
+	Create(ctx context.Context, auth *types.PlatformConnectionAuth) error
+	Update(ctx context.Context, auth *types.PlatformConnectionAuth) error
+	Revoke(ctx context.Context, authID string) error
+	// UpdateCredentials stores refreshed OAuth credentials if the stored
+	// version still equals expectedVersion, and returns ErrConditionFailed
+	// otherwise.
+	UpdateCredentials(ctx context.Context, auth *types.PlatformConnectionAuth, expectedVersion int) error
+	// RecordRefreshFailure counts a failed token refresh and keeps its error.
+	RecordRefreshFailure(ctx context.Context, authID, message string) error
+	// ListExpiring returns the active OAuth credentials with a refresh
+	// token that expire before the given time.
+	ListExpiring(ctx context.Context, before time.Time) ([]types.PlatformConnectionAuth, error)
+	// MarkExpired sets the status to expired and keeps the refresh error
+	// that gave up on the credentials.
+	MarkExpired(ctx context.Context, authID, message string) error
+}
+
+// PlatformStore reads and writes platform definitions.
+type PlatformStore interface {
+	GetByID(ctx context.Context, platformID string) (*types.Platform, error)
+	GetBySlug(ctx context.Context, slug string) (*types.Platform, error)
+	ListAll(ctx context.Context) ([]types.Platform, error)
+	Create(ctx context.Context, platform *types.Platform) error
+	Update(ctx context.Context, platform *types.Platform) error
+}
+
+// APIKeyStore reads and writes API key records.
+type APIKeyStore interface {
+	GetByID(ctx context.Context, keyID string) (*types.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*types.APIKey, error)
+	ListByAccount(ctx context.Context, accountID string) ([]types.APIKey, error)
+	Create(ctx context.Context, key *types.APIKey) error
+	Revoke(ctx context.Context, keyID string) error
+	UpdateLastUsed(ctx context.Context, keyID string, at time.Time) error
+}
+
+// CounterStore keeps expiring numeric counters, used for rate limits and
+// round-robin rotation.
+type CounterStore interface {
+	// Get returns the current value of a counter, or 0 if it does not exist
+	// or has expired.
+	Get(ctx context.Context, key string) (int64, error)
+	// Increment atomically adds delta to a counter and returns the new value.
+	// A non-zero expiresAt replaces the counter's expiry; a zero one leaves
+	// it unchanged, so counters created that way never expire.
+	Increment(ctx context.Context, key string, delta int64, expiresAt time.Time) (int64, error)
+}
+
+// IdempotencyStore remembers which execution an idempotency key created, so
+// retried requests can be answered with the original execution.
+type IdempotencyStore interface {
+	// Claim records executionID under key until expiresAt unless an
+	// unexpired claim exists. It returns the execution ID now held by the
+	// key and whether this call made the claim.
+	Claim(ctx context.Context, key, executionID string, expiresAt time.Time) (string, bool, error)
+	// Release removes the claim if it is still held by executionID, for
+	// requests that failed before their execution was created.
+	Release(ctx context.Context, key, executionID string) error
+}
+
+// LeaseStore hands out short-lived exclusive leases, used by the workers to
+// run one execution at a time per contact.
+type LeaseStore interface {
+	// Acquire takes the lease on key for owner until expiresAt unless another
+	// owner holds an unexpired one. Acquiring a lease the owner already holds
+	// extends it.
+	Acquire(ctx context.Context, key, owner string, expiresAt time.Time) (bool, error)
+	// Release gives the lease up if owner still holds it.
+	Release(ctx context.Context, key, owner string) error
+}
+
+// BatchStore reads and writes batch runs and their per-contact items.
+type BatchStore interface {
+	GetByID(ctx context.Context, batchID string) (*types.Batch, error)
+	// ListByAccount returns the newest batches first with an opaque cursor
+	// for the next page.
+	ListByAccount(ctx context.Context, accountID string, limit int, cursor string) ([]types.Batch, string, error)
+	// ListRunning returns every batch whose status is running.
+	ListRunning(ctx context.Context) ([]types.Batch, error)
+	Create(ctx context.Context, batch *types.Batch) error
+	// Save writes the batch if the stored version still equals batch.Version,
+	// increments it, and returns ErrConditionFailed otherwise. The succeeded
+	// and failed counts are owned by CompleteItem and are not written.
+	Save(ctx context.Context, batch *types.Batch) error
+	// AddItem stores a new item and returns ErrConditionFailed if the
+	// contact already has one in the batch.
+	AddItem(ctx context.Context, item *types.BatchItem) error
+	// CompleteItem records the outcome of a queued item and counts it on the
+	// batch. It reports false, changing nothing, if the item is not queued.
+	CompleteItem(ctx context.Context, batchID, contactID, status, errMsg string, completedAt time.Time) (bool, error)
+	// ListItems returns a batch's items in contact ID order, optionally only
+	// those with the given status.
+	ListItems(ctx context.Context, batchID, status string, limit int, cursor string) ([]types.BatchItem, string, error)
+}
+
+// HelperVersionStore keeps the immutable config versions of helpers.
+type HelperVersionStore interface {
+	// Get returns the version, or nil if it does not exist.
+	Get(ctx context.Context, helperID string, version int) (*types.HelperConfigVersion, error)
+	// List returns a helper's versions newest first with an opaque cursor
+	// for the next page.
+	List(ctx context.Context, helperID string, limit int, cursor string) ([]types.HelperConfigVersion, string, error)
+	// Commit stores new versions of one helper and makes the last one the
+	// helper's config, provided the helper's config_version still equals
+	// expected (0 for helpers that have never been versioned). It returns
+	// ErrConditionFailed, storing nothing, otherwise.
+	Commit(ctx context.Context, expected int, versions ...types.HelperConfigVersion) error
+}
+
+// DelayedExecutionStore keeps executions held until their run_at time.
+// Lists return the earliest run_at first.
+type DelayedExecutionStore interface {
+	// Get returns the delayed execution, or nil if it does not exist.
+	Get(ctx context.Context, executionID string) (*types.DelayedExecution, error)
+	// Create stores a new delayed execution and returns ErrConditionFailed
+	// if its execution ID is taken.
+	Create(ctx context.Context, delayed *types.DelayedExecution) error
+	// ListDue returns up to limit pending executions whose run_at is not
+	// after before.
+	ListDue(ctx context.Context, before time.Time, limit int) ([]types.DelayedExecution, error)
+	// ListByContact and ListByHelper return a contact's or a helper's
+	// delayed executions, optionally only those with the given status, with
+	// an opaque cursor for the next page.
+	ListByContact(ctx context.Context, accountID, contactID, status string, limit int, cursor string) ([]types.DelayedExecution, string, error)
+	ListByHelper(ctx context.Context, helperID, status string, limit int, cursor string) ([]types.DelayedExecution, string, error)
+	// Transition moves a delayed execution from status from to status to,
+	// recording reason. It returns ErrConditionFailed, changing nothing, if
+	// the execution does not exist or is not in status from.
+	Transition(ctx context.Context, executionID, from, to, reason string, at time.Time) error
+}
+
+// ConnectionBreakerStore keeps the circuit breakers of CRM connections.
+// Connections that never failed have none.
+type ConnectionBreakerStore interface {
+	// Get returns the connection's breaker, or nil if it has none.
+	Get(ctx context.Context, connectionID string) (*types.ConnectionBreaker, error)
+	// RecordFailure adds one to the breaker's consecutive failures and
+	// keeps errMsg, creating a closed breaker if the connection has none,
+	// and returns the updated breaker.
+	RecordFailure(ctx context.Context, connectionID, accountID, errMsg string, at time.Time) (*types.ConnectionBreaker, error)
+	// ResetFailures sets the breaker's consecutive failures back to zero.
+	ResetFailures(ctx context.Context, connectionID string, at time.Time) error
+	// Transition moves the breaker from one of the states from to state to,
+	// zeroing its failures and probe attempts. A non-zero nextProbeAt
+	// schedules it for probing; a zero one unschedules it. It returns
+	// ErrConditionFailed, changing nothing, if the breaker does not exist
+	// or is in any other state.
+	Transition(ctx context.Context, connectionID string, from []string, to string, nextProbeAt, at time.Time) error
+	// RecordProbeFailure counts a failed probe of an open breaker, keeps
+	// errMsg and schedules the next probe. It returns ErrConditionFailed if
+	// the breaker is not open.
+	RecordProbeFailure(ctx context.Context, connectionID, errMsg string, nextProbeAt, at time.Time) error
+	// ListDue returns up to limit open and half-open breakers scheduled no
+	// later than before, earliest first.
+	ListDue(ctx context.Context, before time.Time, limit int) ([]types.ConnectionBreaker, error)
+}
+
+// EmailLogStore reads and writes email delivery logs.
+type EmailLogStore interface {
+	GetByID(ctx context.Context, emailID string) (*types.EmailLog, error)
+	GetByAccountID(ctx context.Context, accountID string, limit int32) ([]types.EmailLog, error)
+	GetByRecipientEmail(ctx context.Context, recipientEmail string, limit int32) ([]types.EmailLog, error)
+	GetByAccountIDAndStatus(ctx context.Context, accountID, status string, limit int32) ([]types.EmailLog, error)
+	Create(ctx context.Context, log *types.EmailLog) error
+	Update(ctx context.Context, log *types.EmailLog) error
+	UpdateStatus(ctx context.Context, emailID, status string) error
+}
+
+// WorkflowRunStore keeps workflow runs. Runs are opaque documents to the
+// store, marshaled from whatever type the workflow engine hands it, so this
+// package does not depend on the engine. Saves are guarded by the run's
+// version.
+type WorkflowRunStore interface {
+	// Get unmarshals the run into run and reports false if it does not exist.
+	Get(ctx context.Context, runID string, run interface{}) (bool, error)
+	// Save writes run with its version set to version+1, provided the stored
+	// version still equals version, or, for version 0, that the run does not
+	// exist yet. It returns ErrConditionFailed, storing nothing, otherwise. A
+	// non-empty wakeAt (RFC 3339) lists the run in ListDue until it is saved
+	// without one.
+	Save(ctx context.Context, runID string, version int, wakeAt string, run interface{}) error
+	// ListDue returns the IDs of the runs whose wakeAt is not after before.
+	ListDue(ctx context.Context, before time.Time) ([]string, error)
+}
+
+// Stores bundles the repositories used by handlers and helpers, so callers
+// can be handed either the DynamoDB implementations or in-memory ones.
+type Stores struct {
+	Accounts        AccountStore
+	Helpers         HelperStore
+	Executions      ExecutionStore
+	Connections     ConnectionStore
+	ConnectionAuths ConnectionAuthStore
+	Platforms       PlatformStore
+	APIKeys         APIKeyStore
+	Counters        CounterStore
+	Idempotency     IdempotencyStore
+	Leases          LeaseStore
+	Batches         BatchStore
+	HelperVersions  HelperVersionStore
+	Delayed         DelayedExecutionStore
+	Breakers        ConnectionBreakerStore
+	EmailLogs       EmailLogStore
+	WorkflowRuns    WorkflowRunStore
+}
+
+// NewDynamoStores builds the DynamoDB-backed stores for the given tables.
+func NewDynamoStores(client *dynamodb.Client, tables TableNames) *Stores {
+	return &Stores{
+		Accounts:        NewAccountsRepository(client, tables.Accounts),
+		Helpers:         NewHelpersRepository(client, tables.Helpers),
+		Executions:      NewExecutionsRepository(client, tables.Executions),
+		Connections:     NewConnectionsRepository(client, tables.Connections),
+		ConnectionAuths: NewConnectionAuthsRepository(client, tables.PlatformConnectionAuths),
+		Platforms:       NewPlatformsRepository(client, tables.Platforms),
+		APIKeys:         NewAPIKeysRepository(client, tables.APIKeys),
+		Counters:        NewCountersRepository(client, tables.RateLimits),
+		Idempotency:     NewIdempotencyRepository(client, tables.RateLimits),
+		Leases:          NewLeasesRepository(client, tables.RateLimits),
+		Batches:         NewBatchesRepository(client, tables.Batches, tables.BatchItems),
+		HelperVersions:  NewHelperVersionsRepository(client, tables.HelperVersions, tables.Helpers),
+		Delayed:         NewDelayedExecutionsRepository(client, tables.DelayedExecutions),
+		Breakers:        NewConnectionBreakersRepository(client, tables.ConnectionBreakers),
+		EmailLogs:       NewEmailLogsRepository(client, tables.EmailLogs),
+		WorkflowRuns:    NewWorkflowRunsRepository(client, tables.WorkflowRuns),
+	}
+}
+
+// NewDynamoStoresFromEnv builds the DynamoDB-backed stores using the table
+// names in the environment.
+func NewDynamoStoresFromEnv(client *dynamodb.Client) *Stores {
+	return NewDynamoStores(client, NewTableNames())
+}
+
+// conditionFailed maps a DynamoDB conditional check failure to ErrConditionFailed.
+func conditionFailed(err error) error {
+	var conditionErr *ddbtypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return ErrConditionFailed
+	}
+	return err
+}
+
+// Compile-time checks that the DynamoDB repositories satisfy the store interfaces.
+var (
+	_ AccountStore           = (*AccountsRepository)(nil)
+	_ HelperStore            = (*HelpersRepository)(nil)
+	_ ExecutionStore         = (*ExecutionsRepository)(nil)
+	_ ConnectionStore        = (*ConnectionsRepository)(nil)
+	_ ConnectionAuthStore    = (*ConnectionAuthsRepository)(nil)
+	_ PlatformStore          = (*PlatformsRepository)(nil)
+	_ APIKeyStore            = (*APIKeysRepository)(nil)
+	_ CounterStore           = (*CountersRepository)(nil)
+	_ IdempotencyStore       = (*IdempotencyRepository)(nil)
+	_ LeaseStore             = (*LeasesRepository)(nil)
+	_ BatchStore             = (*BatchesRepository)(nil)
+	_ HelperVersionStore     = (*HelperVersionsRepository)(nil)
+	_ DelayedExecutionStore  = (*DelayedExecutionsRepository)(nil)
+	_ ConnectionBreakerStore = (*ConnectionBreakersRepository)(nil)
+	_ EmailLogStore          = (*EmailLogsRepository)(nil)
+	_ WorkflowRunStore       = (*WorkflowRunsRepository)(nil)
+)
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// workflowWakeShards is the number of partitions of the WakeIndex GSI;
+// waiting runs are spread across them by run ID
+const workflowWakeShards = 4
+
+// WorkflowRunsRepository provides access to the workflow runs DynamoDB table.
+type WorkflowRunsRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewWorkflowRunsRepository creates a new WorkflowRunsRepository.
+func NewWorkflowRunsRepository(client *dynamodb.Client, tableName string) *WorkflowRunsRepository {
+	return &WorkflowRunsRepository{client: client, tableName: tableName}
+}
+
+// Get reads the run with a strongly consistent read, so a run saved a
+// moment ago is never read at an older version.
+func (r *WorkflowRunsRepository) Get(ctx context.Context, runID string, run interface{}) (bool, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      &r.tableName,
+		Key:            stringKey("run_id", runID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, fmt.Errorf("get workflow run: %w", err)
+	}
+	if result.Item == nil {
+		return false, nil
+	}
+	if err := attributevalue.UnmarshalMap(result.Item, run); err != nil {
+		return false, fmt.Errorf("unmarshal workflow run: %w", err)
+	}
+	return true, nil
+}
+
+// Save puts the run under a condition on its version. Runs with a wakeAt
+// get next_wake_at and a wake_shard, which project them into the sparse
+// WakeIndex GSI.
+func (r *WorkflowRunsRepository) Save(ctx context.Context, runID string, version int, wakeAt string, run interface{}) error {
+	item, err := attributevalue.MarshalMap(run)
+	if err != nil {
+		return fmt.Errorf("marshal workflow run: %w", err)
+	}
+	item["run_id"] = stringVal(runID)
+	item["version"] = numVal(strconv.Itoa(version + 1))
+	if wakeAt != "" {
+		item["next_wake_at"] = stringVal(wakeAt)
+		item["wake_shard"] = stringVal(workflowWakeShard(runID))
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(run_id)"),
+	}
+	if version > 0 {
+		input.ConditionExpression = aws.String("version = :version")
+		input.ExpressionAttributeValues = map[string]ddbtypes.AttributeValue{
+			":version": numVal(strconv.Itoa(version)),
+		}
+	}
+
+	_, err = r.client.PutItem(ctx, input)
+	return conditionFailed(err)
+}
+
+// ListDue queries every WakeIndex shard for runs with next_wake_at up to
+// before.
+func (r *WorkflowRunsRepository) ListDue(ctx context.Context, before time.Time) ([]string, error) {
+	var runIDs []string
+	for shard := 0; shard < workflowWakeShards; shard++ {
+		var startKey map[string]ddbtypes.AttributeValue
+		for {
+			result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+				TableName:              &r.tableName,
+				IndexName:              aws.String("WakeIndex"),
+				KeyConditionExpression: aws.String("wake_shard = :shard AND next_wake_at <= :before"),
+				ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+					":shard":  stringVal(strconv.Itoa(shard)),
+					":before": stringVal(before.UTC().Format(time.RFC3339)),
+				},
+				ExclusiveStartKey: startKey,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("list due workflow runs: %w", err)
+			}
+			for _, item := range result.Items {
+				if runID, ok := item["run_id"].(*ddbtypes.AttributeValueMemberS); ok {
+					runIDs = append(runIDs, runID.Value)
+				}
+			}
+			if result.LastEvaluatedKey == nil {
+				break
+			}
+			startKey = result.LastEvaluatedKey
+		}
+	}
+	return runIDs, nil
+}
+
+func workflowWakeShard(runID string) string {
+	h := fnv.New32a()
+	h.Write([]byte(runID))
+	return strconv.Itoa(int(h.Sum32() % workflowWakeShards))
+}
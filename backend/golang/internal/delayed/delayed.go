@@ -0,0 +1,217 @@
+// Package delayed holds helper executions until a given time. The execute
+// APIs and helpers such as drip_it schedule a delayed execution with a run_at
+// time; the releaser turns it into a queued execution, which the stream
+// router hands to the helper workers as usual, once it is due. Until then it
+// can be listed and cancelled by contact or helper.
+package delayed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Delayed execution statuses
+const (
+	StatusPending   = "pending"
+	StatusReleased  = "released"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// ExecutionStatus is the status the execute APIs report for an execution
+// that is held until its run_at time
+const ExecutionStatus = execution.StatusDelayed
+
+// MaxDelay bounds how far ahead an execution can be scheduled
+const MaxDelay = 365 * 24 * time.Hour
+
+// recordTTL is how long a delayed execution is kept after its run_at time
+const recordTTL = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidSchedule is returned for run_at and delay values that
+	// cannot be scheduled
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrNotFound is returned when a delayed execution does not exist in
+	// the account
+	ErrNotFound = errors.New("delayed execution not found")
+	// ErrNotPending is returned when cancelling an execution that was
+	// already released or cancelled
+	ErrNotPending = errors.New("delayed execution is not pending")
+)
+
+// ParseRunAt returns the time an execution requested with runAt (RFC 3339)
+// or delay should run, or the zero time if neither is set. A delay is a
+// number of seconds, given as a number or a string, or a duration string
+// such as "90m", "36h" or "3d".
+func ParseRunAt(runAt string, delay interface{}, now time.Time) (time.Time, error) {
+	hasDelay := delay != nil && delay != ""
+	if runAt == "" && !hasDelay {
+		return time.Time{}, nil
+	}
+	if runAt != "" && hasDelay {
+		return time.Time{}, fmt.Errorf("%w: set run_at or delay, not both", ErrInvalidSchedule)
+	}
+
+	var at time.Time
+	if runAt != "" {
+		parsed, err := time.Parse(time.RFC3339, runAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: run_at must be an RFC 3339 time", ErrInvalidSchedule)
+		}
+		if !parsed.After(now) {
+			return time.Time{}, fmt.Errorf("%w: run_at must be in the future", ErrInvalidSchedule)
+		}
+		at = parsed
+	} else {
+		d, err := parseDelay(delay)
+		if err != nil {
+			return time.Time{}, err
+		}
+		at = now.Add(d)
+	}
+
+	if at.Sub(now) > MaxDelay {
+		return time.Time{}, fmt.Errorf("%w: executions can be delayed by at most %d days", ErrInvalidSchedule, int(MaxDelay.Hours()/24))
+	}
+	return at.UTC().Truncate(time.Second), nil
+}
+
+// parseDelay reads a positive delay in seconds or as a duration string
+func parseDelay(delay interface{}) (time.Duration, error) {
+	var d time.Duration
+	switch v := delay.(type) {
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case int:
+		d = time.Duration(v) * time.Second
+	case string:
+		v = strings.TrimSpace(v)
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			d = time.Duration(seconds * float64(time.Second))
+		} else if days, ok := strings.CutSuffix(v, "d"); ok {
+			n, err := strconv.Atoi(days)
+			if err != nil {
+				return 0, fmt.Errorf("%w: invalid delay %q", ErrInvalidSchedule, v)
+			}
+			d = time.Duration(n) * 24 * time.Hour
+		} else if parsed, err := time.ParseDuration(v); err == nil {
+			d = parsed
+		} else {
+			return 0, fmt.Errorf("%w: invalid delay %q", ErrInvalidSchedule, v)
+		}
+	default:
+		return 0, fmt.Errorf("%w: delay must be a number of seconds or a duration", ErrInvalidSchedule)
+	}
+	if d < time.Second {
+		return 0, fmt.Errorf("%w: delay must be at least one second", ErrInvalidSchedule)
+	}
+	return d, nil
+}
+
+// NewID returns a new execution ID. A delayed execution keeps its ID when it
+// is released, so callers can report it straight away.
+func NewID() string {
+	return "exec:" + uuid.Must(uuid.NewV7()).String()
+}
+
+// Schedule stores d as pending until runAt, giving it an execution ID if it
+// has none
+func Schedule(ctx context.Context, store database.DelayedExecutionStore, d *types.DelayedExecution, runAt, now time.Time) error {
+	if d.ExecutionID == "" {
+		d.ExecutionID = NewID()
+	}
+	expires := runAt.Add(recordTTL).Unix()
+	d.RunAt = runAt.UTC().Format(time.RFC3339)
+	d.Status = StatusPending
+	d.CreatedAt = now.UTC().Format(time.RFC3339)
+	d.UpdatedAt = d.CreatedAt
+	d.TTL = &expires
+
+	if err := store.Create(ctx, d); err != nil {
+		return fmt.Errorf("failed to store delayed execution: %w", err)
+	}
+	return nil
+}
+
+// Cancel cancels a pending execution of the account and returns its new state
+func Cancel(ctx context.Context, store database.DelayedExecutionStore, accountID, executionID, reason string, now time.Time) (*types.DelayedExecution, error) {
+	d, err := store.Get(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delayed execution: %w", err)
+	}
+	if d == nil || d.AccountID != accountID {
+		return nil, ErrNotFound
+	}
+	if d.Status != StatusPending {
+		return nil, fmt.Errorf("%w: it is %s", ErrNotPending, d.Status)
+	}
+
+	if err := store.Transition(ctx, executionID, StatusPending, StatusCancelled, reason, now); errors.Is(err, database.ErrConditionFailed) {
+		return nil, fmt.Errorf("%w: it was released or cancelled concurrently", ErrNotPending)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to cancel delayed execution: %w", err)
+	}
+	d.Status = StatusCancelled
+	d.StatusReason = reason
+	d.UpdatedAt = now.UTC().Format(time.RFC3339)
+	return d, nil
+}
+
+// CancelForContact cancels every pending execution of a contact and returns
+// how many were cancelled
+func CancelForContact(ctx context.Context, store database.DelayedExecutionStore, accountID, contactID, reason string, now time.Time) (int, error) {
+	return cancelAll(ctx, store, reason, now, func(cursor string) ([]types.DelayedExecution, string, error) {
+		return store.ListByContact(ctx, accountID, contactID, "", cancelPageSize, cursor)
+	})
+}
+
+// CancelForHelper cancels every pending execution of a helper and returns
+// how many were cancelled
+func CancelForHelper(ctx context.Context, store database.DelayedExecutionStore, helperID, reason string, now time.Time) (int, error) {
+	return cancelAll(ctx, store, reason, now, func(cursor string) ([]types.DelayedExecution, string, error) {
+		return store.ListByHelper(ctx, helperID, "", cancelPageSize, cursor)
+	})
+}
+
+// cancelPageSize is the page size cancelAll lists with
+const cancelPageSize = 100
+
+// cancelAll cancels the pending executions on every page of list. Pages are
+// listed without a status filter, so cancelling does not move the cursor.
+func cancelAll(ctx context.Context, store database.DelayedExecutionStore, reason string, now time.Time, list func(cursor string) ([]types.DelayedExecution, string, error)) (int, error) {
+	cancelled := 0
+	cursor := ""
+	for {
+		page, next, err := list(cursor)
+		if err != nil {
+			return cancelled, fmt.Errorf("failed to list delayed executions: %w", err)
+		}
+		for _, d := range page {
+			if d.Status != StatusPending {
+				continue
+			}
+			err := store.Transition(ctx, d.ExecutionID, StatusPending, StatusCancelled, reason, now)
+			if errors.Is(err, database.ErrConditionFailed) {
+				continue
+			} else if err != nil {
+				return cancelled, fmt.Errorf("failed to cancel delayed execution %s: %w", d.ExecutionID, err)
+			}
+			cancelled++
+		}
+		if next == "" {
+			return cancelled, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,150 @@
+package delayed
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+var testNow = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+func TestParseRunAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		runAt    string
+		delay    interface{}
+		expected time.Time
+		wantErr  bool
+	}{
+		{name: "neither", expected: time.Time{}},
+		{name: "run_at", runAt: "2026-03-05T14:00:00+01:00", expected: time.Date(2026, time.March, 5, 13, 0, 0, 0, time.UTC)},
+		{name: "seconds", delay: float64(90), expected: testNow.Add(90 * time.Second)},
+		{name: "seconds string", delay: "3600", expected: testNow.Add(time.Hour)},
+		{name: "days", delay: "3d", expected: testNow.Add(72 * time.Hour)},
+		{name: "duration", delay: "1h30m", expected: testNow.Add(90 * time.Minute)},
+		{name: "both", runAt: "2026-03-05T14:00:00Z", delay: float64(60), wantErr: true},
+		{name: "past run_at", runAt: "2026-03-01T09:00:00Z", wantErr: true},
+		{name: "bad run_at", runAt: "tomorrow", wantErr: true},
+		{name: "zero delay", delay: float64(0), wantErr: true},
+		{name: "bad delay", delay: "soon", wantErr: true},
+		{name: "too far", delay: "400d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRunAt(tt.runAt, tt.delay, testNow)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSchedule) {
+					t.Errorf("Expected ErrInvalidSchedule, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func schedule(t *testing.T, stores *database.Stores, helperID, contactID string, runAt time.Time) *types.DelayedExecution {
+	t.Helper()
+	d := &types.DelayedExecution{AccountID: "acc-1", HelperID: helperID, ContactID: contactID, TriggerType: "api"}
+	if err := Schedule(context.Background(), stores.Delayed, d, runAt, testNow); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return d
+}
+
+func TestCancel(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	first := schedule(t, stores, "helper:1", "c1", testNow.Add(time.Hour))
+	schedule(t, stores, "helper:1", "c1", testNow.Add(2*time.Hour))
+	schedule(t, stores, "helper:2", "c1", testNow.Add(time.Hour))
+	schedule(t, stores, "helper:1", "c2", testNow.Add(time.Hour))
+
+	if _, err := Cancel(ctx, stores.Delayed, "acc-2", first.ExecutionID, "", testNow); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another account, got %v", err)
+	}
+	cancelled, err := Cancel(ctx, stores.Delayed, "acc-1", first.ExecutionID, "cancelled by user-1", testNow)
+	if err != nil || cancelled.Status != StatusCancelled {
+		t.Fatalf("Expected the execution cancelled, got %+v, %v", cancelled, err)
+	}
+	if _, err := Cancel(ctx, stores.Delayed, "acc-1", first.ExecutionID, "", testNow); !errors.Is(err, ErrNotPending) {
+		t.Errorf("Expected ErrNotPending cancelling twice, got %v", err)
+	}
+
+	if n, err := CancelForContact(ctx, stores.Delayed, "acc-1", "c1", "", testNow); err != nil || n != 2 {
+		t.Errorf("Expected 2 more executions of c1 cancelled, got %d, %v", n, err)
+	}
+	if n, err := CancelForHelper(ctx, stores.Delayed, "helper:1", "", testNow); err != nil || n != 1 {
+		t.Errorf("Expected the execution of c2 cancelled, got %d, %v", n, err)
+	}
+	if due, _ := stores.Delayed.ListDue(ctx, testNow.Add(3*time.Hour), 10); len(due) != 0 {
+		t.Errorf("Expected nothing left pending, got %+v", due)
+	}
+}
+
+func TestReleaser_ReleaseDue(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Helpers.Create(ctx, &types.Helper{HelperID: "helper:1", AccountID: "acc-1", HelperType: "tag_it", Enabled: true, Status: "active",
+		Config: map[string]interface{}{"tag_id": "1"}, ConfigVersion: 2})
+	stores.Helpers.Create(ctx, &types.Helper{HelperID: "helper:2", AccountID: "acc-1", HelperType: "tag_it", Enabled: false, Status: "active"})
+
+	onTime := schedule(t, stores, "helper:1", "c1", testNow.Add(3*time.Second))
+	later := schedule(t, stores, "helper:1", "c2", testNow.Add(time.Minute))
+	disabled := schedule(t, stores, "helper:2", "c1", testNow.Add(time.Second))
+
+	clock := testNow
+	var releasedAt time.Time
+	releaser := &Releaser{
+		Stores: stores,
+		Now:    func() time.Time { return clock },
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			clock = clock.Add(d)
+			if releasedAt.IsZero() {
+				if exec, _ := stores.Executions.GetByID(ctx, onTime.ExecutionID); exec != nil {
+					releasedAt = clock
+				}
+			}
+			return nil
+		},
+	}
+
+	released, err := releaser.ReleaseDue(ctx, 10*time.Second)
+	if err != nil || released != 1 {
+		t.Fatalf("Expected 1 execution released, got %d, %v", released, err)
+	}
+	if want := testNow.Add(4 * time.Second); !releasedAt.Equal(want) {
+		t.Errorf("Expected the execution released within a poll of its run_at (by %s), got %s", want, releasedAt)
+	}
+
+	exec, _ := stores.Executions.GetByID(ctx, onTime.ExecutionID)
+	if exec.Status != "queued" || exec.HelperType != "tag_it" || exec.ContactID != "c1" || exec.ConfigVersion != 2 || exec.TriggerType != "api" {
+		t.Errorf("Expected a queued tag_it execution for c1 at config version 2, got %+v", exec)
+	}
+	if got, _ := stores.Delayed.Get(ctx, onTime.ExecutionID); got.Status != StatusReleased {
+		t.Errorf("Expected the delayed execution released, got %s", got.Status)
+	}
+	if got, _ := stores.Delayed.Get(ctx, disabled.ExecutionID); got.Status != StatusFailed || got.StatusReason != "helper is disabled" {
+		t.Errorf("Expected the disabled helper's execution failed, got %s %q", got.Status, got.StatusReason)
+	}
+	if got, _ := stores.Delayed.Get(ctx, later.ExecutionID); got.Status != StatusPending {
+		t.Errorf("Expected the later execution still pending, got %s", got.Status)
+	}
+
+	// A second, overlapping run does not release it again
+	clock = testNow.Add(2 * time.Minute)
+	if released, _ := releaser.ReleaseDue(ctx, 0); released != 1 {
+		t.Errorf("Expected only the later execution released, got %d", released)
+	}
+}
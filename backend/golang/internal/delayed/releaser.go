@@ -0,0 +1,171 @@
+package delayed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// PollInterval is how often the releaser looks for due executions, which is
+// the precision executions are released with
+const PollInterval = time.Second
+
+// maxPerPass bounds the executions released by one poll; a full pass is
+// followed by another without waiting
+const maxPerPass = 100
+
+// Releaser creates the queued executions of delayed executions that are due
+type Releaser struct {
+	Stores *database.Stores
+	Now    func() time.Time
+	Sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// NewReleaser creates a releaser on the wall clock
+func NewReleaser(stores *database.Stores) *Releaser {
+	return &Releaser{Stores: stores, Now: time.Now, Sleep: sleep}
+}
+
+// ReleaseDue releases executions as they fall due, polling every
+// PollInterval until window has passed or ctx is about to expire, and
+// returns how many were released. A zero window makes a single pass.
+//
+// Runs may overlap: an execution is only released by the run that moves it
+// out of pending.
+func (r *Releaser) ReleaseDue(ctx context.Context, window time.Duration) (int, error) {
+	until := r.Now().Add(window)
+	released := 0
+	for {
+		now := r.Now()
+		n, more, err := r.releasePass(ctx, now)
+		released += n
+		if err != nil {
+			return released, err
+		}
+		if more {
+			continue
+		}
+
+		next := now.Add(PollInterval)
+		if next.After(until) {
+			return released, nil
+		}
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(next.Add(PollInterval)) {
+			return released, nil
+		}
+		if err := r.Sleep(ctx, PollInterval); err != nil {
+			return released, nil
+		}
+	}
+}
+
+// releasePass releases the executions due at now. more reports a full pass
+// that made progress, after which more executions may be due.
+func (r *Releaser) releasePass(ctx context.Context, now time.Time) (int, bool, error) {
+	due, err := r.Stores.Delayed.ListDue(ctx, now, maxPerPass)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to list due delayed executions: %w", err)
+	}
+
+	released, settled := 0, 0
+	for i := range due {
+		ok, err := r.release(ctx, &due[i], now)
+		if err != nil {
+			log.Printf("Failed to release delayed execution %s: %v", due[i].ExecutionID, err)
+			continue
+		}
+		settled++
+		if ok {
+			released++
+		}
+	}
+	return released, len(due) == maxPerPass && settled > 0, nil
+}
+
+// release moves d out of pending and creates its queued execution with the
+// helper's current config, unless d overrides it. It reports false if d was
+// failed because its helper is gone or disabled, or was released or
+// cancelled by someone else.
+func (r *Releaser) release(ctx context.Context, d *types.DelayedExecution, now time.Time) (bool, error) {
+	helper, err := r.Stores.Helpers.GetByID(ctx, d.HelperID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load helper: %w", err)
+	}
+	switch {
+	case helper == nil || helper.AccountID != d.AccountID || helper.Status == "deleted":
+		return false, r.fail(ctx, d, "helper not found", now)
+	case !helper.Enabled:
+		return false, r.fail(ctx, d, "helper is disabled", now)
+	}
+
+	err = r.Stores.Delayed.Transition(ctx, d.ExecutionID, StatusPending, StatusReleased, "", now)
+	if errors.Is(err, database.ErrConditionFailed) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to mark released: %w", err)
+	}
+
+	config, configVersion := helper.Config, helper.ConfigVersion
+	if d.Config != nil {
+		config, configVersion = d.Config, 0
+	}
+	ttl := now.Add(7 * 24 * time.Hour).Unix()
+	exec := &types.Execution{
+		ExecutionID:   d.ExecutionID,
+		HelperID:      helper.HelperID,
+		HelperType:    helper.HelperType,
+		AccountID:     d.AccountID,
+		UserID:        d.UserID,
+		APIKeyID:      d.APIKeyID,
+		APIKey:        d.APIKey,
+		ConnectionID:  helper.ConnectionID,
+		ContactID:     d.ContactID,
+		Config:        config,
+		ConfigVersion: configVersion,
+		Status:        execution.StatusQueued,
+		TriggerType:   d.TriggerType,
+		Input:         d.Input,
+		QueryParams:   d.QueryParams,
+		CreatedAt:     now.UTC().Format(time.RFC3339),
+		StartedAt:     now.UTC(),
+		TTL:           &ttl,
+	}
+	if err := r.Stores.Executions.Create(ctx, exec); err != nil {
+		// Put it back so the next pass retries it
+		if err := r.Stores.Delayed.Transition(ctx, d.ExecutionID, StatusReleased, StatusPending, "", now); err != nil {
+			log.Printf("Failed to return delayed execution %s to pending: %v", d.ExecutionID, err)
+		}
+		return false, fmt.Errorf("failed to create execution: %w", err)
+	}
+
+	log.Printf("Released delayed execution %s of helper %s (due %s)", d.ExecutionID, d.HelperID, d.RunAt)
+	return true, nil
+}
+
+// fail records why d could not be released
+func (r *Releaser) fail(ctx context.Context, d *types.DelayedExecution, reason string, now time.Time) error {
+	err := r.Stores.Delayed.Transition(ctx, d.ExecutionID, StatusPending, StatusFailed, reason, now)
+	if err != nil && !errors.Is(err, database.ErrConditionFailed) {
+		return fmt.Errorf("failed to mark failed: %w", err)
+	}
+	log.Printf("Delayed execution %s of helper %s failed: %s", d.ExecutionID, d.HelperID, reason)
+	return nil
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+// Package execution is the state machine of helper execution records. An
+// execution is queued when it is created, dispatched when the stream router
+// hands it to its helper's worker queue and running while a worker executes
+// it, then ends succeeded, failed, dead-lettered, cancelled or skipped.
+// Executions scheduled for later are delayed until released, failed
+// attempts that will be retried wait as retrying, and executions held by an
+// open connection circuit breaker are paused until the connection recovers.
+//
+// Every status change goes through Transition, a conditional write that
+// rejects changes the graph does not allow and records when the execution
+// entered each status.
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// Execution statuses
+const (
+	StatusDelayed    = "delayed"
+	StatusQueued     = "queued"
+	StatusDispatched = "dispatched"
+	StatusRunning    = "running"
+	StatusRetrying   = "retrying"
+	// StatusPaused is an execution held while its connection's circuit
+	// breaker is open; it is queued again once the connection recovers
+	StatusPaused    = "paused"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	// StatusDeadLettered is a failure whose transient errors exhausted the
+	// helper's retry policy; it can be replayed
+	StatusDeadLettered = "dead_lettered"
+	StatusCancelled    = "cancelled"
+	// StatusSkipped is an execution that was dispatched but no longer had
+	// anything to do, such as a step of a cancelled workflow run
+	StatusSkipped = "skipped"
+)
+
+// transitions lists the statuses each status can move to. Statuses without
+// an entry are terminal.
+var transitions = map[string][]string{
+	StatusDelayed: {StatusQueued, StatusCancelled},
+	StatusQueued:  {StatusDispatched, StatusCancelled},
+	// Dispatched goes back to queued when the worker queue refused it, fails
+	// without running when the account is over its limit, and is
+	// dead-lettered when the worker handed it back until SQS gave up on it
+	StatusDispatched: {StatusQueued, StatusRunning, StatusPaused, StatusFailed, StatusDeadLettered, StatusCancelled, StatusSkipped},
+	// Running to running is a redelivery after a worker died mid-attempt
+	StatusRunning:  {StatusRunning, StatusRetrying, StatusPaused, StatusSucceeded, StatusFailed, StatusDeadLettered},
+	StatusRetrying: {StatusRunning, StatusPaused, StatusFailed, StatusDeadLettered, StatusCancelled},
+	StatusPaused:   {StatusQueued, StatusCancelled},
+}
+
+var (
+	// ErrIllegalTransition is returned when an execution is not in a status
+	// that can move to the requested one
+	ErrIllegalTransition = errors.New("illegal execution status transition")
+	// ErrNotFound is returned when an execution does not exist in the account
+	ErrNotFound = errors.New("execution not found")
+)
+
+// CanTransition reports whether an execution in status from may move to to
+func CanTransition(from, to string) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// IsTerminal reports whether no status can follow status. Statuses this
+// package does not know, such as the "completed" older records carry, are
+// terminal.
+func IsTerminal(status string) bool {
+	return len(transitions[status]) == 0
+}
+
+// sources returns the statuses that may move to to, in a stable order
+func sources(to string) []string {
+	var from []string
+	for status, targets := range transitions {
+		if slices.Contains(targets, to) {
+			from = append(from, status)
+		}
+	}
+	slices.Sort(from)
+	return from
+}
+
+// Transition moves an execution to status to from any status allowed to
+// precede it, recording the time and reason in its status history. It
+// returns an error wrapping ErrIllegalTransition, changing nothing, if the
+// execution is in any other status or does not exist.
+func Transition(ctx context.Context, store database.ExecutionStore, executionID, to, reason string, at time.Time) error {
+	from := sources(to)
+	if len(from) == 0 {
+		return fmt.Errorf("%w: no status moves to %s", ErrIllegalTransition, to)
+	}
+
+	err := store.Transition(ctx, executionID, from, types.StatusTransition{
+		Status: to,
+		At:     at.UTC().Format(time.RFC3339),
+		Reason: reason,
+	})
+	if errors.Is(err, database.ErrConditionFailed) {
+		return fmt.Errorf("%w: execution %s cannot move to %s", ErrIllegalTransition, executionID, to)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move execution %s to %s: %w", executionID, to, err)
+	}
+	return nil
+}
+
+// Dispatch marks a queued execution as handed to its worker queue. It
+// reports false, changing nothing, if the execution is no longer queued,
+// for example because it was cancelled or already dispatched.
+func Dispatch(ctx context.Context, store database.ExecutionStore, executionID string, at time.Time) (bool, error) {
+	err := Transition(ctx, store, executionID, StatusDispatched, "", at)
+	if errors.Is(err, ErrIllegalTransition) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Cancel cancels an execution of the account that has not started running,
+// or is waiting to retry or paused, and returns its new state
+func Cancel(ctx context.Context, store database.ExecutionStore, accountID, executionID, reason string, at time.Time) (*types.Execution, error) {
+	exec, err := store.GetByID(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution: %w", err)
+	}
+	if exec == nil || exec.AccountID != accountID {
+		return nil, ErrNotFound
+	}
+	if !CanTransition(exec.Status, StatusCancelled) {
+		return nil, fmt.Errorf("%w: execution is %s", ErrIllegalTransition, exec.Status)
+	}
+
+	if err := Transition(ctx, store, executionID, StatusCancelled, reason, at); err != nil {
+		return nil, err
+	}
+	return store.GetByID(ctx, executionID)
+}
@@ -0,0 +1,123 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+var testNow = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+
+func newExecution(t *testing.T, store *memory.Executions, id, status string) {
+	t.Helper()
+	if err := store.Create(context.Background(), &types.Execution{ExecutionID: id, AccountID: "acc-1", Status: status}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestTransition_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewExecutions()
+	newExecution(t, store, "exec:1", StatusQueued)
+
+	steps := []string{StatusDispatched, StatusRunning, StatusRetrying, StatusRunning, StatusSucceeded}
+	for i, status := range steps {
+		if err := Transition(ctx, store, "exec:1", status, "", testNow.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("Expected move to %s, got %v", status, err)
+		}
+	}
+
+	exec, _ := store.GetByID(ctx, "exec:1")
+	if exec.Status != StatusSucceeded {
+		t.Errorf("Expected succeeded, got %s", exec.Status)
+	}
+	if len(exec.StatusHistory) != len(steps) {
+		t.Fatalf("Expected %d history entries, got %+v", len(steps), exec.StatusHistory)
+	}
+	if h := exec.StatusHistory[0]; h.Status != StatusDispatched || h.At != "2026-03-02T09:00:00Z" {
+		t.Errorf("Expected dispatched at 09:00:00, got %+v", h)
+	}
+}
+
+func TestTransition_RejectsIllegal(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewExecutions()
+	newExecution(t, store, "exec:1", StatusQueued)
+	newExecution(t, store, "exec:2", StatusSucceeded)
+
+	tests := []struct {
+		name string
+		id   string
+		to   string
+	}{
+		{name: "skip dispatch", id: "exec:1", to: StatusRunning},
+		{name: "finish unstarted", id: "exec:1", to: StatusSucceeded},
+		{name: "leave terminal", id: "exec:2", to: StatusRunning},
+		{name: "missing", id: "exec:3", to: StatusDispatched},
+		{name: "to initial", id: "exec:1", to: StatusDelayed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Transition(ctx, store, tt.id, tt.to, "", testNow); !errors.Is(err, ErrIllegalTransition) {
+				t.Errorf("Expected ErrIllegalTransition, got %v", err)
+			}
+		})
+	}
+
+	exec, _ := store.GetByID(ctx, "exec:1")
+	if exec.Status != StatusQueued || len(exec.StatusHistory) != 0 {
+		t.Errorf("Expected untouched queued execution, got %+v", exec)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewExecutions()
+	newExecution(t, store, "exec:1", StatusQueued)
+
+	if ok, err := Dispatch(ctx, store, "exec:1", testNow); !ok || err != nil {
+		t.Fatalf("Expected dispatch, got %v, %v", ok, err)
+	}
+	if ok, err := Dispatch(ctx, store, "exec:1", testNow); ok || err != nil {
+		t.Errorf("Expected a second dispatch to be refused without error, got %v, %v", ok, err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewExecutions()
+	newExecution(t, store, "exec:1", StatusQueued)
+	newExecution(t, store, "exec:2", StatusRunning)
+
+	exec, err := Cancel(ctx, store, "acc-1", "exec:1", "no longer needed", testNow)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if exec.Status != StatusCancelled || exec.StatusHistory[0].Reason != "no longer needed" {
+		t.Errorf("Expected cancelled with reason, got %+v", exec)
+	}
+
+	if _, err := Cancel(ctx, store, "acc-1", "exec:2", "", testNow); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("Expected a running execution to refuse cancellation, got %v", err)
+	}
+	if _, err := Cancel(ctx, store, "acc-2", "exec:1", "", testNow); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another account, got %v", err)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	for _, status := range []string{StatusSucceeded, StatusFailed, StatusDeadLettered, StatusCancelled, StatusSkipped, "completed"} {
+		if !IsTerminal(status) {
+			t.Errorf("Expected %s to be terminal", status)
+		}
+	}
+	for _, status := range []string{StatusDelayed, StatusQueued, StatusDispatched, StatusRunning, StatusRetrying, StatusPaused} {
+		if IsTerminal(status) {
+			t.Errorf("Expected %s not to be terminal", status)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type node interface {
+	eval(env *Env) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(*Env) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(env *Env) (interface{}, error) {
+	values := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+type fieldNode struct {
+	root string
+	path []string
+}
+
+func (n *fieldNode) eval(env *Env) (interface{}, error) {
+	switch n.root {
+	case "custom":
+		if env.Contact == nil {
+			return nil, nil
+		}
+		return env.Contact.CustomFields[n.path[0]], nil
+	case "query":
+		value, ok := env.QueryParams[n.path[0]]
+		if !ok {
+			return nil, nil
+		}
+		return value, nil
+	case "input":
+		var current interface{} = env.Input
+		for _, key := range n.path {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, nil
+			}
+			current = m[key]
+		}
+		return current, nil
+	default:
+		return ContactField(env.Contact, n.root), nil
+	}
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(env *Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) eval(env *Env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit
+	if truthy(left) == n.or {
+		return n.or, nil
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right), nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+	re          *regexp.Regexp // matches with a literal pattern
+}
+
+func (n *compareNode) eval(env *Env) (interface{}, error) {
+	a, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(a, b), nil
+	case "!=":
+		return !equal(a, b), nil
+	case "<", "<=", ">", ">=":
+		c, ok := order(a, b)
+		if !ok {
+			return false, nil
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "contains":
+		return contains(a, b), nil
+	case "in":
+		return contains(b, a), nil
+	case "starts_with":
+		return a != nil && b != nil && strings.HasPrefix(toString(a), toString(b)), nil
+	case "ends_with":
+		return a != nil && b != nil && strings.HasSuffix(toString(a), toString(b)), nil
+	case "matches":
+		if a == nil {
+			return false, nil
+		}
+		re := n.re
+		if re == nil {
+			re, err = regexp.Compile(toString(b))
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %w", toString(b), err)
+			}
+		}
+		return re.MatchString(toString(a)), nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q", n.op)
+	}
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n *callNode) eval(env *Env) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(env, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+// function is a built-in function. check validates literal arguments at
+// compile time.
+type function struct {
+	arity int
+	call  func(env *Env, args []interface{}) (interface{}, error)
+	check func(args []node) error
+}
+
+var functions = map[string]function{
+	"has_tag": {arity: 1, call: func(env *Env, args []interface{}) (interface{}, error) {
+		if env.Contact == nil || args[0] == nil {
+			return false, nil
+		}
+		want := toString(args[0])
+		for _, tag := range env.Contact.Tags {
+			if tag.ID == want || strings.EqualFold(tag.Name, want) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	"exists": {arity: 1, call: func(env *Env, args []interface{}) (interface{}, error) {
+		return !isEmpty(args[0]), nil
+	}},
+	"lower": {arity: 1, call: stringFunc(strings.ToLower)},
+	"upper": {arity: 1, call: stringFunc(strings.ToUpper)},
+	"trim":  {arity: 1, call: stringFunc(strings.TrimSpace)},
+	"len": {arity: 1, call: func(env *Env, args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return float64(0), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case []string:
+			return float64(len(v)), nil
+		default:
+			return float64(len([]rune(toString(v)))), nil
+		}
+	}},
+	"date": {arity: 1, call: func(env *Env, args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		t, ok := toTime(args[0])
+		if !ok {
+			return nil, fmt.Errorf("cannot parse %q as a date", toString(args[0]))
+		}
+		return t, nil
+	}, check: func(args []node) error {
+		if lit, ok := args[0].(*literalNode); ok {
+			if _, ok := toTime(lit.value); !ok {
+				return fmt.Errorf("cannot parse %v as a date", lit.value)
+			}
+		}
+		return nil
+	}},
+	"now": {arity: 0, call: func(env *Env, args []interface{}) (interface{}, error) {
+		return env.now(), nil
+	}},
+	"days_ago": {arity: 1, call: func(env *Env, args []interface{}) (interface{}, error) {
+		days, ok := toNumber(args[0])
+		if !ok {
+			return nil, fmt.Errorf("needs a number of days")
+		}
+		return env.now().Add(-time.Duration(days * float64(24*time.Hour))), nil
+	}},
+	"days_from_now": {arity: 1, call: func(env *Env, args []interface{}) (interface{}, error) {
+		days, ok := toNumber(args[0])
+		if !ok {
+			return nil, fmt.Errorf("needs a number of days")
+		}
+		return env.now().Add(time.Duration(days * float64(24*time.Hour))), nil
+	}},
+}
+
+func stringFunc(f func(string) string) func(env *Env, args []interface{}) (interface{}, error) {
+	return func(env *Env, args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return f(toString(args[0])), nil
+	}
+}
+
+// equal compares two values, numerically or as dates when either side is a
+// number or date, and as strings otherwise. nil equals the empty string.
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return isEmpty(a) && isEmpty(b)
+	}
+	if isTime(a) || isTime(b) {
+		ta, okA := toTime(a)
+		tb, okB := toTime(b)
+		return okA && okB && ta.Equal(tb)
+	}
+	if _, ok := a.(bool); ok {
+		return toString(a) == strings.ToLower(toString(b))
+	}
+	if _, ok := b.(bool); ok {
+		return strings.ToLower(toString(a)) == toString(b)
+	}
+	if isNumber(a) || isNumber(b) {
+		na, okA := toNumber(a)
+		nb, okB := toNumber(b)
+		if okA && okB {
+			return na == nb
+		}
+	}
+	return toString(a) == toString(b)
+}
+
+// order returns -1, 0 or 1 as a is less than, equal to or greater than b.
+// ok is false when the values cannot be ordered.
+func order(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if isTime(a) || isTime(b) {
+		ta, okA := toTime(a)
+		tb, okB := toTime(b)
+		if !okA || !okB {
+			return 0, false
+		}
+		switch {
+		case ta.Before(tb):
+			return -1, true
+		case ta.After(tb):
+			return 1, true
+		}
+		return 0, true
+	}
+	if na, ok := toNumber(a); ok {
+		if nb, ok := toNumber(b); ok {
+			switch {
+			case na < nb:
+				return -1, true
+			case na > nb:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return strings.Compare(toString(a), toString(b)), true
+}
+
+// contains reports whether a list holds item, or a string holds item as a
+// substring
+func contains(container, item interface{}) bool {
+	switch c := container.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, v := range c {
+			if equal(v, item) {
+				return true
+			}
+		}
+		return false
+	case []string:
+		for _, v := range c {
+			if equal(v, item) {
+				return true
+			}
+		}
+		return false
+	default:
+		if item == nil {
+			return false
+		}
+		return strings.Contains(toString(c), toString(item))
+	}
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case nil:
+		return false
+	case string:
+		return t != ""
+	case []interface{}:
+		return len(t) > 0
+	}
+	if n, ok := toNumber(v); ok && isNumber(v) {
+		return n != 0
+	}
+	return true
+}
+
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case []interface{}:
+		return len(t) == 0
+	case []string:
+		return len(t) == 0
+	}
+	return false
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case float64, float32, int, int32, int64, json.Number:
+		return true
+	}
+	return false
+}
+
+func isTime(v interface{}) bool {
+	_, ok := v.(time.Time)
+	return ok
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// dateLayouts are the string formats accepted as dates
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		for _, layout := range dateLayouts {
+			if parsed, err := time.Parse(layout, strings.TrimSpace(t)); err == nil {
+				return parsed, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case time.Time:
+		return t.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
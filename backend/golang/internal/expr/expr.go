@@ -0,0 +1,166 @@
+// Package expr parses and evaluates the boolean condition language used by
+// routing and branching helpers.
+//
+// An expression compares contact fields, execution input and query
+// parameters:
+//
+//	email ends_with "@example.com" and not has_tag("Customer")
+//	custom.LeadScore >= 80 or input.plan in ["pro", "enterprise"]
+//	created_at < days_ago(30) and query.utm_source matches "^(google|bing)$"
+//
+// Operands are standard contact fields (id, email, first_name, last_name,
+// phone, company, job_title, source_crm, source_id, created_at, updated_at),
+// custom.<key> or custom["Key With Spaces"], input.<key>[.<key>...] and
+// query.<key>, plus string, number, boolean, null and [list] literals.
+// Comparisons are ==, !=, <, <=, >, >=, contains, starts_with, ends_with,
+// matches (regular expression) and in; conditions combine with and, or, not
+// and parentheses. Functions are has_tag, exists, lower, upper, trim, len,
+// date, now, days_ago and days_from_now.
+//
+// Values compare as numbers when either side is a number, as dates when
+// either side is a date, and as strings otherwise. A missing field equals
+// null and the empty string.
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+// Error is a syntax or validation error in an expression
+type Error struct {
+	Pos int // byte offset into the source
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at column %d", e.Msg, e.Pos+1)
+}
+
+func errorAt(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Env is the data an expression is evaluated against
+type Env struct {
+	Contact     *connectors.NormalizedContact
+	Input       map[string]interface{}
+	QueryParams map[string]string
+	Now         time.Time // zero means the current time
+}
+
+func (e *Env) now() time.Time {
+	if e.Now.IsZero() {
+		return time.Now()
+	}
+	return e.Now
+}
+
+// Expr is a compiled expression, safe for concurrent use
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses and validates src
+func Compile(src string) (*Expr, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, errorAt(0, "empty expression")
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the expression source
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against env. Non-boolean results are
+// converted by truthiness: null, "", 0 and empty lists are false.
+func (e *Expr) Eval(env Env) (bool, error) {
+	value, err := e.root.eval(&env)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// Quote returns s as a double-quoted string literal
+func Quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// Comparison builds the expression for a single field comparison in the
+// older field/operator/value config form. Operators are equals (the
+// default), not_equals, contains, not_contains, exists and not_exists; a
+// field that is not a standard contact field is read from custom fields.
+func Comparison(field, operator, value string) (string, error) {
+	ref := field
+	if _, ok := standardFields[field]; !ok {
+		ref = "custom[" + Quote(field) + "]"
+	}
+
+	switch operator {
+	case "", "equals":
+		return ref + " == " + Quote(value), nil
+	case "not_equals":
+		return ref + " != " + Quote(value), nil
+	case "contains":
+		return ref + " contains " + Quote(value), nil
+	case "not_contains":
+		return "not (" + ref + " contains " + Quote(value) + ")", nil
+	case "exists":
+		return "exists(" + ref + ")", nil
+	case "not_exists":
+		return "not exists(" + ref + ")", nil
+	default:
+		return "", fmt.Errorf("unknown operator %q", operator)
+	}
+}
+
+// ContactField returns a contact's value for a standard field name, or the
+// custom field of that name. Missing values are nil.
+func ContactField(contact *connectors.NormalizedContact, name string) interface{} {
+	if contact == nil {
+		return nil
+	}
+	if get, ok := standardFields[name]; ok {
+		return get(contact)
+	}
+	return contact.CustomFields[name]
+}
+
+// standardFields reads the fields every normalized contact carries
+var standardFields = map[string]func(c *connectors.NormalizedContact) interface{}{
+	"id":         func(c *connectors.NormalizedContact) interface{} { return c.ID },
+	"email":      func(c *connectors.NormalizedContact) interface{} { return c.Email },
+	"first_name": func(c *connectors.NormalizedContact) interface{} { return c.FirstName },
+	"last_name":  func(c *connectors.NormalizedContact) interface{} { return c.LastName },
+	"phone":      func(c *connectors.NormalizedContact) interface{} { return c.Phone },
+	"company":    func(c *connectors.NormalizedContact) interface{} { return c.Company },
+	"job_title":  func(c *connectors.NormalizedContact) interface{} { return c.JobTitle },
+	"source_crm": func(c *connectors.NormalizedContact) interface{} { return c.SourceCRM },
+	"source_id":  func(c *connectors.NormalizedContact) interface{} { return c.SourceID },
+	"created_at": func(c *connectors.NormalizedContact) interface{} { return timeValue(c.CreatedAt) },
+	"updated_at": func(c *connectors.NormalizedContact) interface{} { return timeValue(c.UpdatedAt) },
+}
+
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
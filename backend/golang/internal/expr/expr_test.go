@@ -0,0 +1,182 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+func testEnv() Env {
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	return Env{
+		Contact: &connectors.NormalizedContact{
+			ID:        "123",
+			FirstName: "John",
+			LastName:  "Doe",
+			Email:     "john@example.com",
+			Company:   "Acme",
+			Tags:      []connectors.TagRef{{ID: "42", Name: "Customer"}},
+			CustomFields: map[string]interface{}{
+				"LeadScore":  float64(85),
+				"Plan Type":  "pro",
+				"RenewalOn":  "2026-03-01",
+				"Interests":  []interface{}{"golf", "tennis"},
+				"IsVerified": true,
+			},
+			CreatedAt: &created,
+		},
+		Input:       map[string]interface{}{"plan": "enterprise", "order": map[string]interface{}{"total": "250.50"}},
+		QueryParams: map[string]string{"utm_source": "google"},
+		Now:         time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`email == "john@example.com"`, true},
+		{`email ends_with "@example.com" and first_name starts_with "Jo"`, true},
+		{`first_name == "john"`, false},
+		{`lower(first_name) == "john"`, true},
+		{`custom.LeadScore >= 80`, true},
+		{`custom.LeadScore > "90"`, false},
+		{`custom.LeadScore == "85"`, true},
+		{`custom["Plan Type"] in ["pro", "enterprise"]`, true},
+		{`custom.Interests contains "golf"`, true},
+		{`custom.IsVerified == true`, true},
+		{`custom.Missing == null and custom.Missing == ""`, true},
+		{`exists(custom.Missing) or not exists(company)`, false},
+		{`custom.Missing > 10`, false},
+		{`has_tag("Customer") and has_tag("42") and not has_tag("VIP")`, true},
+		{`has_tag("customer")`, true},
+		{`created_at < days_ago(7)`, true},
+		{`created_at > date("2026-01-15")`, false},
+		{`date(custom.RenewalOn) < days_from_now(30)`, true},
+		{`custom.RenewalOn > "2026-02-15"`, true},
+		{`input.plan == "enterprise" and input.order.total > 200`, true},
+		{`input.order.missing.deeper == null`, true},
+		{`query.utm_source matches "^(google|bing)$"`, true},
+		{`query.utm_campaign matches ".*"`, false},
+		{`(company == "Other" or company == "Acme") and not (phone != "")`, true},
+		{`len(custom.Interests) == 2 and len(first_name) == 4`, true},
+		{`custom.LeadScore > -1`, true},
+		{`custom["Plan Type"]`, true},
+	}
+
+	env := testEnv()
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src)
+			if err != nil {
+				t.Fatalf("Expected no compile error, got %v", err)
+			}
+			got, err := e.Eval(env)
+			if err != nil {
+				t.Fatalf("Expected no eval error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEval_NoContact(t *testing.T) {
+	e, err := Compile(`has_tag("42") or exists(email) or custom.LeadScore > 0`)
+	if err != nil {
+		t.Fatalf("Expected no compile error, got %v", err)
+	}
+	got, err := e.Eval(Env{})
+	if err != nil || got {
+		t.Errorf("Expected false with no contact, got %v, %v", got, err)
+	}
+}
+
+func TestEval_DynamicRegexError(t *testing.T) {
+	e, err := Compile(`email matches input.pattern`)
+	if err != nil {
+		t.Fatalf("Expected no compile error, got %v", err)
+	}
+	env := testEnv()
+	env.Input = map[string]interface{}{"pattern": "("}
+	if _, err := e.Eval(env); err == nil {
+		t.Error("Expected error for invalid regular expression")
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{``, "empty expression at column 1"},
+		{`email = "x"`, `unexpected "=", use == or != at column 7`},
+		{`email == "x`, "unterminated string at column 10"},
+		{`score > 5`, `unknown field "score"; custom fields are referenced as custom.score at column 1`},
+		{`custom > 5`, "custom needs exactly one key, e.g. custom.name at column 1"},
+		{`email.domain == "x"`, "email has no sub-fields at column 1"},
+		{`has_tags("x")`, `unknown function "has_tags" at column 1`},
+		{`has_tag()`, "has_tag takes 1 argument(s), got 0 at column 1"},
+		{`email matches "("`, "invalid regular expression: error parsing regexp: missing closing ): `(` at column 15"},
+		{`created_at > date("soon")`, "date: cannot parse soon as a date at column 14"},
+		{`email == "x" and`, "unexpected end of expression at column 17"},
+		{`(email == "x"`, `expected ")", got end of expression at column 14`},
+		{`email == "x" phone`, `unexpected "phone" at column 14`},
+		{`email in "x"`, "in needs a list or field at column 10"},
+		{`email == #`, `unexpected character '#' at column 10`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestComparison(t *testing.T) {
+	tests := []struct {
+		field    string
+		operator string
+		value    string
+		want     string
+		match    bool
+	}{
+		{"email", "", "john@example.com", `email == "john@example.com"`, true},
+		{"first_name", "not_equals", "Jane", `first_name != "Jane"`, true},
+		{"Plan Type", "contains", "pr", `custom["Plan Type"] contains "pr"`, true},
+		{"Plan Type", "not_contains", `"x"`, `not (custom["Plan Type"] contains "\"x\"")`, true},
+		{"LeadScore", "exists", "", `exists(custom["LeadScore"])`, true},
+		{"phone", "not_exists", "", `not exists(phone)`, true},
+	}
+
+	env := testEnv()
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			src, err := Comparison(tt.field, tt.operator, tt.value)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if src != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, src)
+			}
+			e, err := Compile(src)
+			if err != nil {
+				t.Fatalf("Expected no compile error, got %v", err)
+			}
+			if got, _ := e.Eval(env); got != tt.match {
+				t.Errorf("Expected %v, got %v", tt.match, got)
+			}
+		})
+	}
+
+	if _, err := Comparison("email", "matches", "x"); err == nil || !strings.Contains(err.Error(), "unknown operator") {
+		t.Errorf("Expected unknown operator error, got %v", err)
+	}
+}
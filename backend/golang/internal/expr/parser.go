@@ -0,0 +1,322 @@
+package expr
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// comparisonWords are the comparison operators spelled as words
+var comparisonWords = map[string]bool{
+	"contains":    true,
+	"starts_with": true,
+	"ends_with":   true,
+	"matches":     true,
+	"in":          true,
+}
+
+// keywords cannot be used as field names
+var keywords = map[string]bool{
+	"and": true, "or": true, "not": true,
+	"contains": true, "starts_with": true, "ends_with": true, "matches": true, "in": true,
+}
+
+// parser is a recursive-descent parser over the token stream:
+//
+//	or         = and { "or" and }
+//	and        = not { "and" not }
+//	not        = "not" not | comparison
+//	comparison = operand [ op operand ]
+//	operand    = literal | list | path | call | "(" or ")"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isWord(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokIdent && tok.text == word
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		if tok.kind == tokEOF {
+			return errorAt(tok.pos, "expected %q, got end of expression", text)
+		}
+		return errorAt(tok.pos, "expected %q, got %q", text, tok.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isWord("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	isOp := tok.kind == tokOp && tok.text != "-"
+	if !isOp && !(tok.kind == tokIdent && comparisonWords[tok.text]) {
+		return left, nil
+	}
+	p.next()
+
+	rightPos := p.peek().pos
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &compareNode{op: tok.text, left: left, right: right}
+	switch tok.text {
+	case "matches":
+		// Literal patterns are compiled once, here
+		if lit, ok := right.(*literalNode); ok {
+			pattern, ok := lit.value.(string)
+			if !ok {
+				return nil, errorAt(rightPos, "matches needs a string pattern")
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, errorAt(rightPos, "invalid regular expression: %v", err)
+			}
+			cmp.re = re
+		}
+	case "in":
+		if _, ok := right.(*literalNode); ok {
+			return nil, errorAt(rightPos, "in needs a list or field")
+		}
+	}
+	return cmp, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	case tokLBracket:
+		list := &listNode{}
+		if p.peek().kind == tokRBracket {
+			p.next()
+			return list, nil
+		}
+		for {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(tokRBracket, "]"); err != nil {
+			return nil, err
+		}
+		return list, nil
+
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+
+	case tokNumber:
+		return parseNumber(tok, "")
+
+	case tokOp:
+		if tok.text == "-" && p.peek().kind == tokNumber {
+			return parseNumber(p.next(), "-")
+		}
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if keywords[tok.text] {
+			return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok)
+		}
+		return p.parsePath(tok)
+
+	case tokEOF:
+		return nil, errorAt(tok.pos, "unexpected end of expression")
+	default:
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+	}
+}
+
+func parseNumber(tok token, sign string) (node, error) {
+	f, err := strconv.ParseFloat(sign+tok.text, 64)
+	if err != nil {
+		return nil, errorAt(tok.pos, "invalid number %q", tok.text)
+	}
+	return &literalNode{value: f}, nil
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, errorAt(name.pos, "unknown function %q", name.text)
+	}
+	p.next() // (
+
+	call := &callNode{name: name.text, fn: fn}
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expect(tokRParen, ")"); err != nil {
+		return nil, err
+	}
+
+	if len(call.args) != fn.arity {
+		return nil, errorAt(name.pos, "%s takes %d argument(s), got %d", name.text, fn.arity, len(call.args))
+	}
+	if fn.check != nil {
+		if err := fn.check(call.args); err != nil {
+			return nil, errorAt(name.pos, "%s: %v", name.text, err)
+		}
+	}
+	return call, nil
+}
+
+// parsePath reads a field reference: a standard field, or custom, input or
+// query followed by .key or ["key"] segments
+func (p *parser) parsePath(root token) (node, error) {
+	ref := &fieldNode{root: root.text}
+	for {
+		switch p.peek().kind {
+		case tokDot:
+			p.next()
+			seg := p.next()
+			if seg.kind != tokIdent {
+				return nil, errorAt(seg.pos, "expected a key after \".\"")
+			}
+			ref.path = append(ref.path, seg.text)
+			continue
+		case tokLBracket:
+			p.next()
+			seg := p.next()
+			if seg.kind != tokString {
+				return nil, errorAt(seg.pos, "expected a quoted key after \"[\"")
+			}
+			if err := p.expect(tokRBracket, "]"); err != nil {
+				return nil, err
+			}
+			ref.path = append(ref.path, seg.text)
+			continue
+		}
+		break
+	}
+
+	switch root.text {
+	case "custom", "query":
+		if len(ref.path) != 1 {
+			return nil, errorAt(root.pos, "%s needs exactly one key, e.g. %s.name", root.text, root.text)
+		}
+	case "input":
+		if len(ref.path) == 0 {
+			return nil, errorAt(root.pos, "input needs a key, e.g. input.name")
+		}
+	default:
+		if _, ok := standardFields[root.text]; !ok {
+			return nil, errorAt(root.pos, "unknown field %q; custom fields are referenced as custom.%s", root.text, root.text)
+		}
+		if len(ref.path) > 0 {
+			return nil, errorAt(root.pos, "%s has no sub-fields", root.text)
+		}
+	}
+	return ref, nil
+}
@@ -3,17 +3,12 @@ package automation
 import (
 	"context"
 	"fmt"
-	"os"
 	"strconv"
-	"time"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/google/uuid"
+	"strings"
+
+	"github.com/myfusionhelper/api/internal/expr"
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/workflow"
 )
 
 // NewChainIt creates a new ChainIt helper instance
@@ -24,7 +19,8 @@ func init() {
 }
 
 // ChainIt chains multiple helper executions together with conditional logic and timing control.
-// Makes HTTP POST requests to execute each helper in sequence.
+// It compiles its config into a workflow definition; the worker runs it as a persisted
+// workflow run, passing step outputs forward and honoring waits.
 type ChainIt struct{}
 
 // helperConfig represents a helper to execute in the chain
@@ -61,9 +57,13 @@ func (h *ChainIt) GetConfigSchema() map[string]interface{} {
 				},
 				"description": "List of helper IDs/short keys to chain. Can be strings or objects with 'id' and optional 'config'",
 			},
+			"condition": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: expression the contact must match for the chain to run, e.g. custom.LeadScore >= 80 and not has_tag(\"Customer\")",
+			},
 			"conditional_field": map[string]interface{}{
 				"type":        "string",
-				"description": "Optional: field name to check for conditional execution",
+				"description": "Optional: field name to check for conditional execution (superseded by condition)",
 			},
 			"conditional_value": map[string]interface{}{
 				"type":        "string",
@@ -88,15 +88,45 @@ func (h *ChainIt) GetConfigSchema() map[string]interface{} {
 				"items":       map[string]interface{}{"type": "string"},
 				"description": "Optional: helpers to execute if any primary helper fails",
 			},
+			"steps": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id":           map[string]interface{}{"type": "string"},
+						"helper_id":    map[string]interface{}{"type": "string"},
+						"config":       map[string]interface{}{"type": "object"},
+						"depends_on":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+						"when":         map[string]interface{}{"type": "string", "enum": []string{"success", "failure", "always"}},
+						"condition":    map[string]interface{}{"type": "object"},
+						"wait_seconds": map[string]interface{}{"type": "number"},
+						"inputs":       map[string]interface{}{"type": "object"},
+					},
+					"required": []string{"id", "helper_id"},
+				},
+				"description": "Optional: workflow DAG used instead of helpers. Steps run once their depends_on steps settle; inputs map step input keys to references like steps.<id>.output.<key>",
+			},
+		},
+		"anyOf": []interface{}{
+			map[string]interface{}{"required": []string{"helpers"}},
+			map[string]interface{}{"required": []string{"steps"}},
 		},
-		"required": []string{"helpers"},
 	}
 }
 
 func (h *ChainIt) ValidateConfig(config map[string]interface{}) error {
+	if raw, ok := config["steps"]; ok {
+		steps, err := workflow.ParseSteps(raw)
+		if err != nil {
+			return err
+		}
+		def := workflow.Definition{Steps: steps}
+		return def.Validate()
+	}
+
 	helpersList, ok := config["helpers"]
 	if !ok {
-		return fmt.Errorf("helpers is required")
+		return fmt.Errorf("helpers or steps is required")
 	}
 
 	switch v := helpersList.(type) {
@@ -112,19 +142,9 @@ func (h *ChainIt) ValidateConfig(config map[string]interface{}) error {
 		return fmt.Errorf("helpers must be an array of strings")
 	}
 
-	// Validate conditional operator if provided
-	if operator, ok := config["conditional_operator"].(string); ok {
-		validOperators := map[string]bool{
-			"equals":       true,
-			"not_equals":   true,
-			"contains":     true,
-			"not_contains": true,
-			"exists":       true,
-			"not_exists":   true,
-		}
-		if !validOperators[operator] {
-			return fmt.Errorf("invalid conditional_operator: %s", operator)
-		}
+	// Validate the condition, whichever form it takes
+	if _, err := h.compileCondition(config); err != nil {
+		return err
 	}
 
 	// Validate delay_seconds if provided
@@ -147,19 +167,16 @@ func (h *ChainIt) ValidateConfig(config map[string]interface{}) error {
 }
 
 func (h *ChainIt) Execute(ctx context.Context, input helpers.HelperInput) (*helpers.HelperOutput, error) {
-	// Parse helpers config (can be strings or objects)
-	helpersChain, err := h.parseHelpersConfig(input.Config["helpers"])
-	if err != nil {
-		return nil, fmt.Errorf("invalid helpers configuration: %w", err)
-	}
-
 	output := &helpers.HelperOutput{
 		Actions: make([]helpers.HelperAction, 0),
 		Logs:    make([]string, 0),
 	}
 
 	// Check conditional execution if specified
-	shouldExecute, conditionLog := h.evaluateCondition(input)
+	shouldExecute, conditionLog, err := h.evaluateCondition(input)
+	if err != nil {
+		return nil, err
+	}
 	if conditionLog != "" {
 		output.Logs = append(output.Logs, conditionLog)
 	}
@@ -170,143 +187,123 @@ func (h *ChainIt) Execute(ctx context.Context, input helpers.HelperInput) (*help
 		return output, nil
 	}
 
-	// Extract delay configuration
-	delaySeconds := h.getDelaySeconds(input.Config)
-
-	// Get DynamoDB client
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
+	def, err := h.buildWorkflow(input.Config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		return nil, err
 	}
-	db := dynamodb.NewFromConfig(cfg)
-	executionsTable := os.Getenv("EXECUTIONS_TABLE")
-
-	if executionsTable == "" {
-		return nil, fmt.Errorf("EXECUTIONS_TABLE environment variable not set")
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid workflow: %w", err)
 	}
 
-	// Look up helper table name
-	helpersTable := os.Getenv("HELPERS_TABLE")
-
-	// Create execution records for each chained helper
-	now := time.Now().UTC()
-	var executionIDs []string
-	var queuedHelpers []string
-
-	for i, helperCfg := range helpersChain {
-		// Calculate start time with delay
-		startTime := now
-		if i > 0 && delaySeconds > 0 {
-			startTime = startTime.Add(time.Duration(delaySeconds*i) * time.Second)
-		}
-
-		// Look up the downstream helper to freeze its data
-		var downstreamHelperType string
-		var downstreamConnectionID string
-		var downstreamConfig map[string]interface{}
+	// The worker starts a workflow run from the definition once this execution completes
+	for i, step := range def.Steps {
+		output.Actions = append(output.Actions, helpers.HelperAction{
+			Type:   "helper_chain",
+			Target: step.HelperID,
+			Value:  i,
+		})
 
-		if helpersTable != "" {
-			helperResult, err := db.GetItem(ctx, &dynamodb.GetItemInput{
-				TableName: aws.String(helpersTable),
-				Key: map[string]ddbtypes.AttributeValue{
-					"helper_id": &ddbtypes.AttributeValueMemberS{Value: helperCfg.ID},
-				},
-			})
-			if err == nil && helperResult.Item != nil {
-				if v, ok := helperResult.Item["helper_type"]; ok {
-					if s, ok := v.(*ddbtypes.AttributeValueMemberS); ok {
-						downstreamHelperType = s.Value
-					}
-				}
-				if v, ok := helperResult.Item["connection_id"]; ok {
-					if s, ok := v.(*ddbtypes.AttributeValueMemberS); ok {
-						downstreamConnectionID = s.Value
-					}
-				}
-				if v, ok := helperResult.Item["config"]; ok {
-					if m, ok := v.(*ddbtypes.AttributeValueMemberM); ok {
-						downstreamConfig = make(map[string]interface{})
-						_ = attributevalue.UnmarshalMap(m.Value, &downstreamConfig)
-					}
-				}
-			} else {
-				output.Logs = append(output.Logs, fmt.Sprintf("Helper %d (%s): Failed to look up helper, skipping", i+1, helperCfg.ID))
-				continue
+		logLine := fmt.Sprintf("Step %s: %s", step.ID, step.HelperID)
+		if len(step.DependsOn) > 0 {
+			logLine += fmt.Sprintf(" (after %s", strings.Join(step.DependsOn, ", "))
+			if step.When != "" && step.When != workflow.WhenSuccess {
+				logLine += ", when " + step.When
 			}
+			logLine += ")"
 		}
-
-		// Use chain override config if provided, otherwise use the downstream helper's own config
-		configToUse := downstreamConfig
-		if helperCfg.Config != nil {
-			configToUse = helperCfg.Config
+		if step.WaitSeconds > 0 {
+			logLine += fmt.Sprintf(" (wait %d seconds)", step.WaitSeconds)
 		}
+		output.Logs = append(output.Logs, logLine)
+	}
 
-		// Create execution record with frozen helper data
-		executionID := "exec:" + uuid.Must(uuid.NewV7()).String()
-		ttl := now.Add(7 * 24 * time.Hour).Unix()
-
-		execution := map[string]interface{}{
-			"execution_id":  executionID,
-			"helper_id":     helperCfg.ID,
-			"helper_type":   downstreamHelperType,
-			"account_id":    input.AccountID,
-			"user_id":       input.UserID,
-			"connection_id": downstreamConnectionID,
-			"contact_id":    input.ContactID,
-			"config":        configToUse,
-			"status":        "queued",
-			"trigger_type":  "chain",
-			"input":         input.Input,
-			"created_at":    now.Format(time.RFC3339),
-			"started_at":    startTime.Format(time.RFC3339),
-			"ttl":           ttl,
-			"parent_exec":   input.HelperID,
-		}
+	output.Success = true
+	if _, ok := input.Config["steps"]; ok {
+		output.Message = fmt.Sprintf("Chained %d workflow step(s)", len(def.Steps))
+	} else {
+		output.Message = fmt.Sprintf("Chained %d helper(s) for sequential execution", h.countPrimary(def))
+	}
+	output.ModifiedData = map[string]interface{}{
+		workflow.OutputKey: def,
+		"step_count":       len(def.Steps),
+	}
 
-		item, err := attributevalue.MarshalMap(execution)
-		if err != nil {
-			output.Logs = append(output.Logs, fmt.Sprintf("Helper %d (%s): Failed to marshal execution: %v", i+1, helperCfg.ID, err))
-			continue
-		}
+	return output, nil
+}
 
-		_, err = db.PutItem(ctx, &dynamodb.PutItemInput{
-			TableName: aws.String(executionsTable),
-			Item:      item,
-		})
+// buildWorkflow turns the chain config into a workflow definition. An explicit
+// steps DAG is used as-is; otherwise helpers run in order (each after the
+// previous settles, delay_seconds apart), followed by the on_success_helpers
+// or on_failure_helpers depending on how the primary helpers finished.
+func (h *ChainIt) buildWorkflow(config map[string]interface{}) (*workflow.Definition, error) {
+	if raw, ok := config["steps"]; ok {
+		steps, err := workflow.ParseSteps(raw)
 		if err != nil {
-			output.Logs = append(output.Logs, fmt.Sprintf("Helper %d (%s): Failed to create execution: %v", i+1, helperCfg.ID, err))
-			continue
+			return nil, err
 		}
+		return &workflow.Definition{Steps: steps}, nil
+	}
 
-		executionIDs = append(executionIDs, executionID)
-		queuedHelpers = append(queuedHelpers, helperCfg.ID)
+	helpersChain, err := h.parseHelpersConfig(config["helpers"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid helpers configuration: %w", err)
+	}
+	delaySeconds := h.getDelaySeconds(config)
 
-		if delaySeconds > 0 && i > 0 {
-			output.Logs = append(output.Logs, fmt.Sprintf("Queued helper %d: %s (delayed %d seconds)", i+1, helperCfg.ID, delaySeconds*i))
-		} else {
-			output.Logs = append(output.Logs, fmt.Sprintf("Queued helper %d: %s", i+1, helperCfg.ID))
+	def := &workflow.Definition{}
+	var primary []string
+	for i, helperCfg := range helpersChain {
+		step := workflow.Step{
+			ID:       fmt.Sprintf("step_%d", i+1),
+			HelperID: helperCfg.ID,
+			Config:   helperCfg.Config,
 		}
+		if i > 0 {
+			// Later helpers run whatever the outcome of earlier ones
+			step.DependsOn = []string{primary[i-1]}
+			step.When = workflow.WhenAlways
+			step.WaitSeconds = delaySeconds
+		}
+		def.Steps = append(def.Steps, step)
+		primary = append(primary, step.ID)
 	}
 
-	// Queue success/failure handlers (note: these won't auto-execute, would need worker enhancement)
-	// For now, just log them
-	if successHelpers, err := h.parseHelpersConfig(input.Config["on_success_helpers"]); err == nil && len(successHelpers) > 0 {
-		output.Logs = append(output.Logs, fmt.Sprintf("Success handlers configured: %d (not yet auto-executed)", len(successHelpers)))
+	handlers := []struct {
+		key    string
+		prefix string
+		when   string
+	}{
+		{"on_success_helpers", "on_success", workflow.WhenSuccess},
+		{"on_failure_helpers", "on_failure", workflow.WhenFailure},
 	}
-	if failureHelpers, err := h.parseHelpersConfig(input.Config["on_failure_helpers"]); err == nil && len(failureHelpers) > 0 {
-		output.Logs = append(output.Logs, fmt.Sprintf("Failure handlers configured: %d (not yet auto-executed)", len(failureHelpers)))
+	for _, handler := range handlers {
+		handlerHelpers, err := h.parseHelpersConfig(config[handler.key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s configuration: %w", handler.key, err)
+		}
+		for i, helperCfg := range handlerHelpers {
+			def.Steps = append(def.Steps, workflow.Step{
+				ID:        fmt.Sprintf("%s_%d", handler.prefix, i+1),
+				HelperID:  helperCfg.ID,
+				Config:    helperCfg.Config,
+				DependsOn: primary,
+				When:      handler.when,
+			})
+		}
 	}
 
-	output.Success = true
-	output.Message = fmt.Sprintf("Queued %d helper(s) for execution", len(executionIDs))
-	output.ModifiedData = map[string]interface{}{
-		"execution_ids":  executionIDs,
-		"queued_helpers": queuedHelpers,
-		"queued_count":   len(executionIDs),
-		"delay_seconds":  delaySeconds,
-	}
+	return def, nil
+}
 
-	return output, nil
+// countPrimary counts the chained helpers, excluding success/failure handlers
+func (h *ChainIt) countPrimary(def *workflow.Definition) int {
+	count := 0
+	for _, step := range def.Steps {
+		if strings.HasPrefix(step.ID, "step_") {
+			count++
+		}
+	}
+	return count
 }
 
 // parseHelpersConfig parses the helpers configuration which can be strings or objects
@@ -346,77 +343,46 @@ func (h *ChainIt) parseHelpersConfig(val interface{}) ([]helperConfig, error) {
 	}
 }
 
-// evaluateCondition checks if the chain should execute based on conditional configuration
-func (h *ChainIt) evaluateCondition(input helpers.HelperInput) (bool, string) {
-	conditionalField, hasField := input.Config["conditional_field"].(string)
-	if !hasField || conditionalField == "" {
-		return true, "" // No condition specified, execute unconditionally
+// compileCondition compiles the chain's condition: the condition expression,
+// or else the conditional_field/operator/value comparison. It returns nil
+// when the chain is unconditional.
+func (h *ChainIt) compileCondition(config map[string]interface{}) (*expr.Expr, error) {
+	if condition, _ := config["condition"].(string); condition != "" {
+		e, err := expr.Compile(condition)
+		if err != nil {
+			return nil, fmt.Errorf("condition: %w", err)
+		}
+		return e, nil
 	}
 
-	operator := "equals" // default
-	if op, ok := input.Config["conditional_operator"].(string); ok {
-		operator = op
+	conditionalField, _ := config["conditional_field"].(string)
+	if conditionalField == "" {
+		return nil, nil
 	}
-
-	conditionalValue, _ := input.Config["conditional_value"].(string)
-
-	// Get actual field value from contact data
-	var actualValue string
-	var fieldExists bool
-
-	if input.ContactData != nil {
-		// Check standard fields
-		switch conditionalField {
-		case "email":
-			actualValue = input.ContactData.Email
-			fieldExists = actualValue != ""
-		case "first_name":
-			actualValue = input.ContactData.FirstName
-			fieldExists = actualValue != ""
-		case "last_name":
-			actualValue = input.ContactData.LastName
-			fieldExists = actualValue != ""
-		case "phone":
-			actualValue = input.ContactData.Phone
-			fieldExists = actualValue != ""
-		default:
-			// Check custom fields
-			if val, ok := input.ContactData.CustomFields[conditionalField]; ok {
-				actualValue = fmt.Sprintf("%v", val)
-				fieldExists = true
-			}
-		}
+	operator, _ := config["conditional_operator"].(string)
+	conditionalValue, _ := config["conditional_value"].(string)
+	src, err := expr.Comparison(conditionalField, operator, conditionalValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid conditional_operator: %s", operator)
 	}
+	return expr.Compile(src)
+}
 
-	// Evaluate condition based on operator
-	var result bool
-	var logMessage string
-
-	switch operator {
-	case "equals":
-		result = actualValue == conditionalValue
-		logMessage = fmt.Sprintf("Condition: %s == %s (actual: %s) = %v", conditionalField, conditionalValue, actualValue, result)
-	case "not_equals":
-		result = actualValue != conditionalValue
-		logMessage = fmt.Sprintf("Condition: %s != %s (actual: %s) = %v", conditionalField, conditionalValue, actualValue, result)
-	case "contains":
-		result = fieldExists && len(actualValue) > 0 && len(conditionalValue) > 0 && stringContains(actualValue, conditionalValue)
-		logMessage = fmt.Sprintf("Condition: %s contains %s (actual: %s) = %v", conditionalField, conditionalValue, actualValue, result)
-	case "not_contains":
-		result = !fieldExists || !stringContains(actualValue, conditionalValue)
-		logMessage = fmt.Sprintf("Condition: %s not contains %s (actual: %s) = %v", conditionalField, conditionalValue, actualValue, result)
-	case "exists":
-		result = fieldExists
-		logMessage = fmt.Sprintf("Condition: %s exists = %v", conditionalField, result)
-	case "not_exists":
-		result = !fieldExists
-		logMessage = fmt.Sprintf("Condition: %s not exists = %v", conditionalField, result)
-	default:
-		result = true
-		logMessage = fmt.Sprintf("Unknown operator: %s, executing unconditionally", operator)
+// evaluateCondition checks if the chain should execute based on conditional configuration
+func (h *ChainIt) evaluateCondition(input helpers.HelperInput) (bool, string, error) {
+	e, err := h.compileCondition(input.Config)
+	if err != nil {
+		return false, "", err
+	}
+	if e == nil {
+		return true, "", nil // No condition specified, execute unconditionally
 	}
 
-	return result, logMessage
+	result, err := e.Eval(expr.Env{Contact: input.ContactData, Input: input.Input, QueryParams: input.QueryParams})
+	if err != nil {
+		return false, "", fmt.Errorf("condition: %w", err)
+	}
+	return result, fmt.Sprintf("Condition: %s = %v", e, result), nil
 }
 
 // getDelaySeconds extracts and converts delay_seconds from config
@@ -439,24 +405,3 @@ func (h *ChainIt) getDelaySeconds(config map[string]interface{}) int {
 
 	return 0
 }
-
-// stringContains checks if a string contains a substring (case-sensitive)
-func stringContains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || containsSubstring(s, substr))
-}
-
-// containsSubstring is a simple substring check
-func containsSubstring(s, substr string) bool {
-	if len(substr) == 0 {
-		return true
-	}
-	if len(s) < len(substr) {
-		return false
-	}
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
@@ -3,10 +3,12 @@ package automation
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/myfusionhelper/api/internal/connectors"
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/workflow"
 )
 
 // mockConnectorForChainIt - minimal mock since chain_it doesn't require CRM
@@ -304,3 +306,156 @@ func TestChainIt_Execute_HelperOrder(t *testing.T) {
 		t.Errorf("Expected third action target 'third', got '%s'", output.Actions[2].Target)
 	}
 }
+
+func TestChainIt_Execute_BuildsWorkflow(t *testing.T) {
+	helper := &ChainIt{}
+	ctx := context.Background()
+
+	input := helpers.HelperInput{
+		ContactID: "contact-123",
+		Config: map[string]interface{}{
+			"helpers":            []interface{}{"first", map[string]interface{}{"id": "second", "config": map[string]interface{}{"tag": "vip"}}},
+			"delay_seconds":      float64(60),
+			"on_success_helpers": []interface{}{"celebrate"},
+			"on_failure_helpers": []interface{}{"alert"},
+		},
+	}
+
+	output, err := helper.Execute(ctx, input)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if output.Message != "Chained 2 helper(s) for sequential execution" {
+		t.Errorf("Unexpected message: %s", output.Message)
+	}
+
+	def, ok := output.ModifiedData[workflow.OutputKey].(*workflow.Definition)
+	if !ok {
+		t.Fatalf("Expected workflow definition in modified data, got %T", output.ModifiedData[workflow.OutputKey])
+	}
+	if len(def.Steps) != 4 {
+		t.Fatalf("Expected 4 steps, got %d", len(def.Steps))
+	}
+
+	second := def.Steps[1]
+	if second.DependsOn[0] != "step_1" || second.When != workflow.WhenAlways || second.WaitSeconds != 60 || second.Config["tag"] != "vip" {
+		t.Errorf("Unexpected second step: %+v", second)
+	}
+	onSuccess, onFailure := def.Steps[2], def.Steps[3]
+	if onSuccess.HelperID != "celebrate" || onSuccess.When != workflow.WhenSuccess || len(onSuccess.DependsOn) != 2 {
+		t.Errorf("Unexpected success handler step: %+v", onSuccess)
+	}
+	if onFailure.HelperID != "alert" || onFailure.When != workflow.WhenFailure || len(onFailure.DependsOn) != 2 {
+		t.Errorf("Unexpected failure handler step: %+v", onFailure)
+	}
+}
+
+func TestChainIt_ValidateConfig_Steps(t *testing.T) {
+	helper := &ChainIt{}
+
+	err := helper.ValidateConfig(map[string]interface{}{
+		"steps": []interface{}{
+			map[string]interface{}{"id": "a", "helper_id": "h1"},
+			map[string]interface{}{"id": "b", "helper_id": "h2", "depends_on": []interface{}{"a"}},
+		},
+	})
+	if err != nil {
+		t.Errorf("Expected no error for valid steps, got: %v", err)
+	}
+
+	err = helper.ValidateConfig(map[string]interface{}{
+		"steps": []interface{}{
+			map[string]interface{}{"id": "a", "helper_id": "h1", "depends_on": []interface{}{"a"}},
+		},
+	})
+	if err == nil {
+		t.Error("Expected error for cyclic steps")
+	}
+}
+
+func TestChainIt_ValidateSchema(t *testing.T) {
+	helper := &ChainIt{}
+
+	err := helpers.ValidateHelperConfig(helper, map[string]interface{}{
+		"helpers":       []interface{}{"helper-1", map[string]interface{}{"id": "helper-2"}},
+		"delay_seconds": float64(5),
+	})
+	if err != nil {
+		t.Errorf("Expected no error for valid config, got: %v", err)
+	}
+
+	err = helpers.ValidateHelperConfig(helper, map[string]interface{}{
+		"helpers": []interface{}{"helper-1", "helper-2", map[string]interface{}{"config": map[string]interface{}{}}},
+	})
+	if err == nil || err.Error() != "helpers[2].id: required" {
+		t.Errorf("Expected helpers[2].id: required, got: %v", err)
+	}
+
+	err = helpers.ValidateHelperConfig(helper, map[string]interface{}{
+		"helpers":              []interface{}{"helper-1"},
+		"conditional_operator": "matches",
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "conditional_operator: must be one of") {
+		t.Errorf("Expected conditional_operator enum error, got: %v", err)
+	}
+}
+
+func TestChainIt_Execute_Condition(t *testing.T) {
+	helper := &ChainIt{}
+	contact := &connectors.NormalizedContact{
+		ID:           "contact-1",
+		Email:        "jane@example.com",
+		Tags:         []connectors.TagRef{{ID: "7", Name: "Customer"}},
+		CustomFields: map[string]interface{}{"LeadScore": float64(40)},
+	}
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		skipped bool
+	}{
+		{
+			name:   "expression matches",
+			config: map[string]interface{}{"helpers": []interface{}{"helper-1"}, "condition": `has_tag("Customer") and email ends_with "@example.com"`},
+		},
+		{
+			name:    "expression does not match",
+			config:  map[string]interface{}{"helpers": []interface{}{"helper-1"}, "condition": `custom.LeadScore >= 80`},
+			skipped: true,
+		},
+		{
+			name:   "legacy comparison on a custom field",
+			config: map[string]interface{}{"helpers": []interface{}{"helper-1"}, "conditional_field": "LeadScore", "conditional_value": "40"},
+		},
+		{
+			name:    "legacy not_exists",
+			config:  map[string]interface{}{"helpers": []interface{}{"helper-1"}, "conditional_field": "email", "conditional_operator": "not_exists"},
+			skipped: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := helper.Execute(context.Background(), helpers.HelperInput{Config: tt.config, ContactData: contact})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			skipped := output.Message == "Chain skipped due to conditional check"
+			if skipped != tt.skipped {
+				t.Errorf("Expected skipped=%v, got message %q", tt.skipped, output.Message)
+			}
+		})
+	}
+}
+
+func TestChainIt_ValidateConfig_Condition(t *testing.T) {
+	helper := &ChainIt{}
+
+	err := helper.ValidateConfig(map[string]interface{}{
+		"helpers":   []interface{}{"helper-1"},
+		"condition": `has_tag("VIP") and`,
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "condition: unexpected end of expression") {
+		t.Errorf("Expected condition syntax error, got: %v", err)
+	}
+}
@@ -38,6 +38,11 @@ func (h *DripIt) GetConfigSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Field to track the current step index",
 			},
+			"interval_seconds": map[string]interface{}{
+				"type":        "number",
+				"description": "Seconds to wait before running the next step for the contact; 0 leaves the next step to the next trigger",
+				"default":     0,
+			},
 		},
 		"required": []string{"steps", "state_field"},
 	}
@@ -66,6 +71,22 @@ func (h *DripIt) ValidateConfig(config map[string]interface{}) error {
 		return fmt.Errorf("state_field is required")
 	}
 
+	// Validate interval_seconds if provided
+	if interval, ok := config["interval_seconds"]; ok {
+		switch v := interval.(type) {
+		case float64:
+			if v < 0 {
+				return fmt.Errorf("interval_seconds must be non-negative")
+			}
+		case int:
+			if v < 0 {
+				return fmt.Errorf("interval_seconds must be non-negative")
+			}
+		default:
+			return fmt.Errorf("interval_seconds must be a number")
+		}
+	}
+
 	return nil
 }
 
@@ -129,9 +150,30 @@ func (h *DripIt) Execute(ctx context.Context, input helpers.HelperInput) (*helpe
 		output.ModifiedData[stateField] = nextStepStr
 	}
 
+	// Schedule this helper again for the contact to run the next step
+	if interval := dripInterval(input.Config); interval > 0 && nextStep < len(steps) && err == nil {
+		output.Actions = append(output.Actions, helpers.HelperAction{
+			Type:   "execution_scheduled",
+			Target: input.HelperID,
+			Value:  map[string]interface{}{"delay_seconds": interval},
+		})
+		output.Logs = append(output.Logs, fmt.Sprintf("Step %d scheduled in %.0f seconds", nextStep+1, interval))
+	}
+
 	output.Success = true
 	output.Message = fmt.Sprintf("Drip step %d of %d: triggered automation %s", currentStep+1, len(steps), automationID)
 	output.Logs = append(output.Logs, fmt.Sprintf("Drip campaign for contact %s: executed step %d/%d (automation %s)", input.ContactID, currentStep+1, len(steps), automationID))
 
 	return output, nil
 }
+
+// dripInterval returns the configured interval_seconds, or 0
+func dripInterval(config map[string]interface{}) float64 {
+	switch v := config["interval_seconds"].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
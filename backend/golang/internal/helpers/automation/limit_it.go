@@ -3,14 +3,8 @@ package automation
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/myfusionhelper/api/internal/helpers"
 )
 
@@ -110,8 +104,8 @@ func (h *LimitIt) Execute(ctx context.Context, input helpers.HelperInput) (*help
 
 	output.Logs = append(output.Logs, fmt.Sprintf("Checking rate limit: %s (max: %d)", rateLimitKey, maxExecutions))
 
-	// Check current count in DynamoDB rate-limits table
-	count, err := h.getRateLimitCount(ctx, rateLimitKey)
+	// Check current count in the rate-limit counters
+	count, err := h.getRateLimitCount(ctx, input, rateLimitKey)
 	if err != nil {
 		output.Logs = append(output.Logs, fmt.Sprintf("Warning: Failed to check rate limit: %v", err))
 		// On error, allow execution (fail open)
@@ -151,7 +145,7 @@ func (h *LimitIt) Execute(ctx context.Context, input helpers.HelperInput) (*help
 	}
 
 	// Increment counter
-	newCount, err := h.incrementRateLimitCount(ctx, rateLimitKey, limitType)
+	newCount, err := h.incrementRateLimitCount(ctx, input, rateLimitKey, limitType)
 	if err != nil {
 		output.Logs = append(output.Logs, fmt.Sprintf("Warning: Failed to increment rate limit counter: %v", err))
 	} else {
@@ -178,102 +172,45 @@ func (h *LimitIt) Execute(ctx context.Context, input helpers.HelperInput) (*help
 	return output, nil
 }
 
-// getRateLimitCount retrieves the current count from DynamoDB
-func (h *LimitIt) getRateLimitCount(ctx context.Context, key string) (int, error) {
-	tableName := os.Getenv("RATE_LIMITS_TABLE")
-	if tableName == "" {
-		return 0, fmt.Errorf("RATE_LIMITS_TABLE environment variable not set")
+// getRateLimitCount retrieves the current count from the counter store
+func (h *LimitIt) getRateLimitCount(ctx context.Context, input helpers.HelperInput, key string) (int, error) {
+	if input.Stores == nil || input.Stores.Counters == nil {
+		return 0, fmt.Errorf("counter store not configured")
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx)
+	count, err := input.Stores.Counters.Get(ctx, key)
 	if err != nil {
-		return 0, fmt.Errorf("failed to load AWS config: %w", err)
+		return 0, fmt.Errorf("failed to get rate limit: %w", err)
 	}
-
-	client := dynamodb.NewFromConfig(cfg)
-
-	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(tableName),
-		Key: map[string]ddbtypes.AttributeValue{
-			"key": &ddbtypes.AttributeValueMemberS{Value: key},
-		},
-	})
-	if err != nil {
-		return 0, fmt.Errorf("failed to get rate limit from DynamoDB: %w", err)
-	}
-
-	if result.Item == nil {
-		return 0, nil // No record yet, count is 0
-	}
-
-	var record struct {
-		Key   string `dynamodbav:"key"`
-		Count int    `dynamodbav:"count"`
-	}
-	if err := attributevalue.UnmarshalMap(result.Item, &record); err != nil {
-		return 0, fmt.Errorf("failed to unmarshal rate limit record: %w", err)
-	}
-
-	return record.Count, nil
+	return int(count), nil
 }
 
-// incrementRateLimitCount atomically increments the counter in DynamoDB
-func (h *LimitIt) incrementRateLimitCount(ctx context.Context, key string, limitType string) (int, error) {
-	tableName := os.Getenv("RATE_LIMITS_TABLE")
-	if tableName == "" {
-		return 0, fmt.Errorf("RATE_LIMITS_TABLE environment variable not set")
+// incrementRateLimitCount atomically increments the counter, expiring it
+// after the limit window
+func (h *LimitIt) incrementRateLimitCount(ctx context.Context, input helpers.HelperInput, key string, limitType string) (int, error) {
+	if input.Stores == nil || input.Stores.Counters == nil {
+		return 0, fmt.Errorf("counter store not configured")
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("failed to load AWS config: %w", err)
-	}
-
-	client := dynamodb.NewFromConfig(cfg)
-
 	// Calculate TTL based on limit type
-	var ttl int64
+	var ttl time.Time
 	now := time.Now()
 	switch limitType {
 	case "per_hour":
-		ttl = now.Add(1 * time.Hour).Unix()
+		ttl = now.Add(1 * time.Hour)
 	case "per_day":
-		ttl = now.Add(24 * time.Hour).Unix()
+		ttl = now.Add(24 * time.Hour)
 	case "per_week":
-		ttl = now.Add(7 * 24 * time.Hour).Unix()
+		ttl = now.Add(7 * 24 * time.Hour)
 	case "per_month":
-		ttl = now.Add(30 * 24 * time.Hour).Unix()
+		ttl = now.Add(30 * 24 * time.Hour)
 	default:
-		ttl = now.Add(24 * time.Hour).Unix()
+		ttl = now.Add(24 * time.Hour)
 	}
 
-	// Atomic increment with UpdateItem
-	result, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(tableName),
-		Key: map[string]ddbtypes.AttributeValue{
-			"key": &ddbtypes.AttributeValueMemberS{Value: key},
-		},
-		UpdateExpression: aws.String("ADD #count :inc SET #ttl = :ttl"),
-		ExpressionAttributeNames: map[string]string{
-			"#count": "count",
-			"#ttl":   "ttl",
-		},
-		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
-			":inc": &ddbtypes.AttributeValueMemberN{Value: "1"},
-			":ttl": &ddbtypes.AttributeValueMemberN{Value: fmt.Sprintf("%d", ttl)},
-		},
-		ReturnValues: ddbtypes.ReturnValueAllNew,
-	})
+	count, err := input.Stores.Counters.Increment(ctx, key, 1, ttl)
 	if err != nil {
 		return 0, fmt.Errorf("failed to increment rate limit: %w", err)
 	}
-
-	var record struct {
-		Count int `dynamodbav:"count"`
-	}
-	if err := attributevalue.UnmarshalMap(result.Attributes, &record); err != nil {
-		return 0, fmt.Errorf("failed to unmarshal updated record: %w", err)
-	}
-
-	return record.Count, nil
+	return int(count), nil
 }
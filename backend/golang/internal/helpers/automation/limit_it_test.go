@@ -0,0 +1,101 @@
+package automation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/helpers"
+)
+
+func TestLimitIt_Execute(t *testing.T) {
+	helper := &LimitIt{}
+	stores := memory.NewStores()
+
+	newInput := func(contactID, action string) helpers.HelperInput {
+		return helpers.HelperInput{
+			ContactID: contactID,
+			HelperID:  "helper:limit",
+			Config: map[string]interface{}{
+				"limit_type":      "per_day",
+				"max_executions":  float64(2),
+				"scope":           "contact",
+				"action_on_limit": action,
+			},
+			Stores: stores,
+		}
+	}
+
+	for i := 1; i <= 2; i++ {
+		output, err := helper.Execute(context.Background(), newInput("123", "skip"))
+		if err != nil {
+			t.Fatalf("Execution %d: expected no error, got %v", i, err)
+		}
+		if output.ModifiedData["limit_reached"] != false || output.ModifiedData["current_count"] != i {
+			t.Errorf("Execution %d: expected count %d under the limit, got %v", i, i, output.ModifiedData)
+		}
+	}
+
+	t.Run("skip when limit reached", func(t *testing.T) {
+		output, err := helper.Execute(context.Background(), newInput("123", "skip"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !output.Success || output.ModifiedData["limit_reached"] != true {
+			t.Errorf("Expected a successful skip, got %+v", output)
+		}
+	})
+
+	t.Run("error when limit reached", func(t *testing.T) {
+		output, err := helper.Execute(context.Background(), newInput("123", "error"))
+		if err == nil || output.Success {
+			t.Errorf("Expected a rate limit error, got %v", err)
+		}
+	})
+
+	t.Run("other contacts have their own limit", func(t *testing.T) {
+		output, _ := helper.Execute(context.Background(), newInput("456", "error"))
+		if output.ModifiedData["current_count"] != 1 {
+			t.Errorf("Expected count 1, got %v", output.ModifiedData["current_count"])
+		}
+	})
+
+	t.Run("fails open without stores", func(t *testing.T) {
+		input := newInput("123", "error")
+		input.Stores = nil
+		output, err := helper.Execute(context.Background(), input)
+		if err != nil || !output.Success {
+			t.Errorf("Expected execution to be allowed, got %v", err)
+		}
+	})
+}
+
+func TestLimitIt_DryRunLeavesCounterAlone(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	key := "limit:helper:limit:123:per_day"
+	stores.Counters.Increment(ctx, key, 1, time.Now().Add(time.Hour))
+
+	req := helpers.ExecutionRequest{
+		HelperType: "limit_it",
+		ContactID:  "123",
+		HelperID:   "helper:limit",
+		Config:     map[string]interface{}{"limit_type": "per_day", "max_executions": float64(2)},
+		Stores:     stores,
+		DryRun:     true,
+	}
+	for i := 0; i < 2; i++ {
+		result, err := helpers.NewExecutor().Execute(ctx, req, nil)
+		if err != nil || !result.Success {
+			t.Fatalf("Expected a successful dry run, got %+v, %v", result, err)
+		}
+		if result.Output.ModifiedData["current_count"] != 2 {
+			t.Errorf("Expected the dry run to count from the live value, got %v", result.Output.ModifiedData)
+		}
+	}
+
+	if count, _ := stores.Counters.Get(ctx, key); count != 1 {
+		t.Errorf("Expected the live counter to stay at 1, got %d", count)
+	}
+}
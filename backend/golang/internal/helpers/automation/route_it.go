@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/myfusionhelper/api/internal/expr"
 	"github.com/myfusionhelper/api/internal/helpers"
 )
 
@@ -45,6 +46,10 @@ func (h *RouteIt) GetConfigSchema() map[string]interface{} {
 							"type":        "string",
 							"description": "URL to redirect to if conditions match",
 						},
+						"condition": map[string]interface{}{
+							"type":        "string",
+							"description": "Optional: expression the contact must match, e.g. custom.LeadScore >= 80 and has_tag(\"Customer\"). Routes without one always match",
+						},
 					},
 					"required": []string{"redirectUrl"},
 				},
@@ -86,6 +91,12 @@ func (h *RouteIt) ValidateConfig(config map[string]interface{}) error {
 		if !ok || redirectUrl == "" {
 			return fmt.Errorf("routes[%d].redirectUrl is required", i)
 		}
+
+		if condition, _ := routeMap["condition"].(string); condition != "" {
+			if _, err := expr.Compile(condition); err != nil {
+				return fmt.Errorf("routes[%d].condition: %w", i, err)
+			}
+		}
 	}
 
 	return nil
@@ -101,45 +112,48 @@ func (h *RouteIt) Execute(ctx context.Context, input helpers.HelperInput) (*help
 		Logs: make([]string, 0),
 	}
 
-	// For the base route_it, we'll use a simple sequential evaluation
 	// Routes are evaluated in order, first match wins
-	// This provides a foundation for more complex condition evaluation later
-
 	var selectedURL string
 	var routingReason string
 	var matchedLabel string
 
-	// Evaluate routes in order
+	env := expr.Env{Contact: input.ContactData, Input: input.Input, QueryParams: input.QueryParams}
 	for i, routeInterface := range routes {
 		route := routeInterface.(map[string]interface{})
 		redirectUrl := route["redirectUrl"].(string)
 		label, _ := route["label"].(string)
 
-		// For now, we'll select the first route in the array
-		// This can be extended with condition evaluation (geo, score, source, etc.)
-		// Future enhancement: add "condition" field to route config and evaluate it
-
-		if i == 0 {
-			selectedURL = redirectUrl
-			matchedLabel = label
-			if label != "" {
-				routingReason = fmt.Sprintf("route_matched=%s", label)
-			} else {
-				routingReason = fmt.Sprintf("route_index=%d", i)
+		if condition, _ := route["condition"].(string); condition != "" {
+			matched, err := h.evaluateRoute(condition, env)
+			if err != nil {
+				output.Logs = append(output.Logs, fmt.Sprintf("Warning: Route %d condition failed: %v", i, err))
+				continue
+			}
+			output.Logs = append(output.Logs, fmt.Sprintf("Route %d condition %s = %v", i, condition, matched))
+			if !matched {
+				continue
 			}
-			output.Logs = append(output.Logs, fmt.Sprintf("Matched route: %s", redirectUrl))
-			break
 		}
+
+		selectedURL = redirectUrl
+		matchedLabel = label
+		if label != "" {
+			routingReason = fmt.Sprintf("route_matched=%s", label)
+		} else {
+			routingReason = fmt.Sprintf("route_index=%d", i)
+		}
+		output.Logs = append(output.Logs, fmt.Sprintf("Matched route: %s", redirectUrl))
+		break
 	}
 
-	// Fall back if no route selected (shouldn't happen with current logic, but safety check)
+	// Fall back if no route matched
 	if selectedURL == "" {
 		if fallbackURL != "" {
 			selectedURL = fallbackURL
 			routingReason = "fallback"
 			output.Logs = append(output.Logs, "No route matched, using fallback URL")
 		} else {
-			output.Message = "No routes configured and no fallback URL"
+			output.Message = "No route matched and no fallback URL"
 			return output, fmt.Errorf("no route found")
 		}
 	}
@@ -187,3 +201,12 @@ func (h *RouteIt) Execute(ctx context.Context, input helpers.HelperInput) (*help
 
 	return output, nil
 }
+
+// evaluateRoute compiles and evaluates a route condition against the contact
+func (h *RouteIt) evaluateRoute(condition string, env expr.Env) (bool, error) {
+	e, err := expr.Compile(condition)
+	if err != nil {
+		return false, err
+	}
+	return e.Eval(env)
+}
@@ -2,8 +2,10 @@ package automation
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/myfusionhelper/api/internal/connectors"
 	"github.com/myfusionhelper/api/internal/helpers"
 )
 
@@ -403,3 +405,101 @@ func TestRouteIt_Execute_SavesRoutedAtTimestamp(t *testing.T) {
 		t.Error("expected routed_at timestamp to be present")
 	}
 }
+
+func TestRouteIt_Execute_Conditions(t *testing.T) {
+	helper := &RouteIt{}
+
+	config := map[string]interface{}{
+		"routes": []interface{}{
+			map[string]interface{}{
+				"label":       "VIP",
+				"redirectUrl": "https://example.com/vip",
+				"condition":   `has_tag("VIP")`,
+			},
+			map[string]interface{}{
+				"label":       "Hot lead",
+				"redirectUrl": "https://example.com/hot",
+				"condition":   `custom.LeadScore >= 80 and query.utm_source == "google"`,
+			},
+			map[string]interface{}{
+				"label":       "Everyone else",
+				"redirectUrl": "https://example.com/default",
+			},
+		},
+	}
+
+	contact := &connectors.NormalizedContact{
+		ID:           "contact123",
+		Tags:         []connectors.TagRef{{ID: "1", Name: "Customer"}},
+		CustomFields: map[string]interface{}{"LeadScore": float64(92)},
+	}
+
+	tests := []struct {
+		name        string
+		query       map[string]string
+		expectedURL string
+	}{
+		{"second route matches", map[string]string{"utm_source": "google"}, "https://example.com/hot"},
+		{"unconditional route catches the rest", map[string]string{"utm_source": "bing"}, "https://example.com/default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := helpers.HelperInput{
+				Config:      config,
+				ContactID:   "contact123",
+				ContactData: contact,
+				QueryParams: tt.query,
+			}
+
+			output, err := helper.Execute(context.Background(), input)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if url := output.ModifiedData["redirect_url"]; url != tt.expectedURL {
+				t.Errorf("expected redirect_url '%s', got '%v'", tt.expectedURL, url)
+			}
+		})
+	}
+}
+
+func TestRouteIt_Execute_NoConditionMatches(t *testing.T) {
+	helper := &RouteIt{}
+
+	config := map[string]interface{}{
+		"routes": []interface{}{
+			map[string]interface{}{
+				"redirectUrl": "https://example.com/vip",
+				"condition":   `has_tag("VIP")`,
+			},
+		},
+		"fallback_url": "https://example.com/fallback",
+	}
+
+	output, err := helper.Execute(context.Background(), helpers.HelperInput{Config: config, ContactID: "contact123"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if output.ModifiedData["redirect_url"] != "https://example.com/fallback" || output.ModifiedData["routing_reason"] != "fallback" {
+		t.Errorf("expected fallback route, got %v", output.ModifiedData)
+	}
+
+	delete(config, "fallback_url")
+	if _, err := helper.Execute(context.Background(), helpers.HelperInput{Config: config, ContactID: "contact123"}); err == nil {
+		t.Error("expected error when no route matches and there is no fallback")
+	}
+}
+
+func TestRouteIt_ValidateConfig_InvalidCondition(t *testing.T) {
+	helper := &RouteIt{}
+
+	err := helper.ValidateConfig(map[string]interface{}{
+		"routes": []interface{}{
+			map[string]interface{}{"redirectUrl": "https://example.com/a"},
+			map[string]interface{}{"redirectUrl": "https://example.com/b", "condition": "score > 5"},
+		},
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "routes[1].condition: unknown field \"score\"") {
+		t.Errorf("expected routes[1].condition error, got: %v", err)
+	}
+}
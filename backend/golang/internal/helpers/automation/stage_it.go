@@ -59,6 +59,9 @@ func (h *StageIt) GetConfigSchema() map[string]interface{} {
 	}
 }
 
+// ValidateConfig rejects every config for now: no connector lists or updates
+// opportunities, so the "_related.lead.stage" lookup would always come back
+// empty and the not_found goal would fire for contacts that do have a match.
 func (h *StageIt) ValidateConfig(config map[string]interface{}) error {
 	if _, ok := config["basic_match"].(string); !ok || config["basic_match"] == "" {
 		return fmt.Errorf("basic_match (stage ID to match) is required")
@@ -66,7 +69,7 @@ func (h *StageIt) ValidateConfig(config map[string]interface{}) error {
 	if _, ok := config["to_stage"].(string); !ok || config["to_stage"] == "" {
 		return fmt.Errorf("to_stage is required")
 	}
-	return nil
+	return fmt.Errorf("stage_it is not available yet: opportunity stages cannot be read or updated through the CRM connectors")
 }
 
 func (h *StageIt) Execute(ctx context.Context, input helpers.HelperInput) (*helpers.HelperOutput, error) {
@@ -90,7 +93,9 @@ func (h *StageIt) Execute(ctx context.Context, input helpers.HelperInput) (*help
 	queryKey := fmt.Sprintf("_related.lead.stage.%s", matchStage)
 	oppValue, err := input.Connector.GetContactFieldValue(ctx, input.ContactID, queryKey)
 	if err != nil {
-		output.Logs = append(output.Logs, fmt.Sprintf("Opportunity query via field lookup: %v", err))
+		output.Message = fmt.Sprintf("Failed to query opportunities: %v", err)
+		output.Logs = append(output.Logs, output.Message)
+		return output, err
 	}
 
 	// Determine if opportunities were found
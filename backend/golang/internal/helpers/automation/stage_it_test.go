@@ -79,13 +79,29 @@ func TestStageIt_ValidateConfig_MissingToStage(t *testing.T) {
 	}
 }
 
-func TestStageIt_ValidateConfig_Valid(t *testing.T) {
+func TestStageIt_ValidateConfig_NotAvailable(t *testing.T) {
 	h := &StageIt{}
-	if err := h.ValidateConfig(map[string]interface{}{"basic_match": "stage1", "to_stage": "stage2"}); err != nil {
-		t.Errorf("Unexpected error: %v", err)
+	if err := h.ValidateConfig(map[string]interface{}{"basic_match": "stage1", "to_stage": "stage2"}); err == nil {
+		t.Error("Expected error: opportunity stages are not supported by any connector")
 	}
 }
 
+func TestStageIt_Execute_QueryError(t *testing.T) {
+	h := &StageIt{}
+	mockConn := &mockConnectorForStageIt{getFieldError: fmt.Errorf("related record type \"lead\" is not supported")}
+	_, err := h.Execute(context.Background(), helpers.HelperInput{
+		ContactID: "c1",
+		Config: map[string]interface{}{
+			"basic_match":    "stage1",
+			"to_stage":       "stage2",
+			"not_found_goal": "none",
+		},
+		Connector: mockConn,
+	})
+	if err == nil { t.Error("Expected query error") }
+	if len(mockConn.goalCalls) != 0 { t.Errorf("Expected no goals, got %v", mockConn.goalCalls) }
+}
+
 func TestStageIt_Execute_OpportunitiesFound(t *testing.T) {
 	h := &StageIt{}
 	mockConn := &mockConnectorForStageIt{
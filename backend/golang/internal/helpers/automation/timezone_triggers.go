@@ -3,9 +3,12 @@ package automation
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/timezone"
 )
 
 // NewTimezoneTriggers creates a new TimezoneTriggers helper instance
@@ -16,11 +19,25 @@ func init() {
 }
 
 // TimezoneTriggers resolves a contact's timezone from their address data and schedules
-// a time-zone-aware automation trigger. It geocodes the contact's address, determines
-// their timezone, and optionally saves timezone data to contact fields.
+// a time-zone-aware automation trigger. The timezone is resolved offline from the
+// country, state, postal code and city, optionally saved to contact fields, and the
+// trigger goal is scheduled as a delayed execution of this helper at the configured
+// day and time in the contact's timezone.
 // Ported from legacy PHP timezone_triggers helper.
 type TimezoneTriggers struct{}
 
+// timezoneTriggerFireKey marks the delayed execution that fires the trigger goal
+const timezoneTriggerFireKey = "timezone_trigger_fire"
+
+// timezoneAddressFieldSets are the contact address fields, tried in order
+// (billing, then shipping, then other). The last four of each set are the
+// city, state, postal code and country.
+var timezoneAddressFieldSets = [][]string{
+	{"StreetAddress1", "StreetAddress2", "City", "State", "PostalCode", "Country"},
+	{"Address2Street1", "Address2Street2", "City2", "State2", "PostalCode2", "Country2"},
+	{"Address3Street1", "Address3Street2", "City3", "State3", "PostalCode3", "Country3"},
+}
+
 func (h *TimezoneTriggers) GetName() string     { return "Timezone Triggers" }
 func (h *TimezoneTriggers) GetType() string     { return "timezone_triggers" }
 func (h *TimezoneTriggers) GetCategory() string { return "automation" }
@@ -36,7 +53,7 @@ func (h *TimezoneTriggers) GetConfigSchema() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"day": map[string]interface{}{
 				"type":        "string",
-				"description": "Day of the week for the trigger (e.g., Monday, Tuesday)",
+				"description": "Day of the week for the trigger (e.g., Monday, Tuesday), or Any for the next occurrence of the time",
 			},
 			"time": map[string]interface{}{
 				"type":        "string",
@@ -60,7 +77,7 @@ func (h *TimezoneTriggers) GetConfigSchema() map[string]interface{} {
 			},
 			"failed_goal": map[string]interface{}{
 				"type":        "string",
-				"description": "Goal call name to achieve when no address is found",
+				"description": "Goal call name to achieve when no address is found or its timezone cannot be resolved",
 			},
 		},
 		"required": []string{"day", "time"},
@@ -74,17 +91,31 @@ func (h *TimezoneTriggers) ValidateConfig(config map[string]interface{}) error {
 	if _, ok := config["time"].(string); !ok || config["time"] == "" {
 		return fmt.Errorf("time is required")
 	}
+	if _, err := timezone.ParseWeekly(config["day"].(string), config["time"].(string)); err != nil {
+		return err
+	}
 	return nil
 }
 
 func (h *TimezoneTriggers) Execute(ctx context.Context, input helpers.HelperInput) (*helpers.HelperOutput, error) {
 	day := input.Config["day"].(string)
 	triggerTime := input.Config["time"].(string)
-	integration := "myfusionhelper"
 
 	output := &helpers.HelperOutput{
-		Actions: make([]helpers.HelperAction, 0),
-		Logs:    make([]string, 0),
+		Actions:      make([]helpers.HelperAction, 0),
+		ModifiedData: make(map[string]interface{}),
+		Logs:         make([]string, 0),
+	}
+
+	// The delayed execution scheduled below fires the goal
+	if fire, _ := input.Input[timezoneTriggerFireKey].(bool); fire {
+		return h.fire(ctx, input, output)
+	}
+
+	weekly, err := timezone.ParseWeekly(day, triggerTime)
+	if err != nil {
+		output.Message = fmt.Sprintf("Invalid trigger schedule: %v", err)
+		return output, err
 	}
 
 	// Get contact data for address resolution
@@ -94,81 +125,139 @@ func (h *TimezoneTriggers) Execute(ctx context.Context, input helpers.HelperInpu
 		return output, err
 	}
 
-	// Build address from contact fields (try billing, then shipping, then other)
-	address := buildAddress(contact.CustomFields, [][]string{
-		{"StreetAddress1", "StreetAddress2", "City", "State", "PostalCode", "Country"},
-		{"Address2Street1", "Address2Street2", "City2", "State2", "PostalCode2", "Country2"},
-		{"Address3Street1", "Address3Street2", "City3", "State3", "PostalCode3", "Country3"},
-	})
-
+	addr, address := contactAddress(contact.CustomFields)
 	if address == "" {
 		output.Logs = append(output.Logs, "No address found on contact")
-
-		// Fire failed goal if configured
-		if failedGoal, ok := input.Config["failed_goal"].(string); ok && failedGoal != "" {
-			goalErr := input.Connector.AchieveGoal(ctx, input.ContactID, failedGoal, integration)
-			if goalErr != nil {
-				output.Logs = append(output.Logs, fmt.Sprintf("Failed to achieve failed goal: %v", goalErr))
-			} else {
-				output.Actions = append(output.Actions, helpers.HelperAction{
-					Type:   "goal_achieved",
-					Target: input.ContactID,
-					Value:  failedGoal,
-				})
-			}
-		}
-
+		h.achieveFailedGoal(ctx, input, output)
 		output.Success = true
 		output.Message = "No address found for timezone resolution"
 		return output, nil
 	}
 
 	output.Logs = append(output.Logs, fmt.Sprintf("Resolved address: %s", address))
+	output.ModifiedData["address"] = address
 
-	// Store timezone resolution request as output data for downstream processing
-	// The actual geocoding and timezone resolution is handled by the execution layer
-	timezoneRequest := map[string]interface{}{
-		"address":    address,
-		"day":        day,
-		"time":       triggerTime,
-		"contact_id": input.ContactID,
-		"helper_id":  input.HelperID,
+	resolved, err := timezone.Resolve(addr)
+	if err != nil {
+		output.Logs = append(output.Logs, fmt.Sprintf("Could not resolve timezone: %v", err))
+		h.achieveFailedGoal(ctx, input, output)
+		output.Success = true
+		output.Message = "No timezone found for contact address"
+		return output, nil
 	}
 
+	now := time.Now()
+	offset := strconv.FormatFloat(resolved.Offset(now), 'f', -1, 64)
+	latLng := strconv.FormatFloat(resolved.Latitude, 'f', -1, 64) + "," + strconv.FormatFloat(resolved.Longitude, 'f', -1, 64)
+	output.ModifiedData["time_zone"] = resolved.Zone
+	output.ModifiedData["time_zone_offset"] = offset
+	output.ModifiedData["lat_lng"] = latLng
+	output.Logs = append(output.Logs, fmt.Sprintf("Resolved timezone %s (UTC%s) by %s", resolved.Zone, offset, resolved.Precision))
+
 	// Save timezone data fields if configured
-	updateFields := map[string]interface{}{}
+	h.saveField(ctx, input, output, "save_time_zone", resolved.Zone)
+	h.saveField(ctx, input, output, "save_lat_lng", latLng)
+	h.saveField(ctx, input, output, "save_time_zone_offset", offset)
+
+	output.Success = true
+	output.Message = fmt.Sprintf("Resolved timezone %s for contact", resolved.Zone)
 
-	if saveTimezone, ok := input.Config["save_time_zone"].(string); ok && saveTimezone != "" && saveTimezone != "no_select" {
-		updateFields[saveTimezone] = "" // Will be populated by execution layer after geocoding
-		timezoneRequest["save_time_zone_field"] = saveTimezone
+	// Schedule the trigger goal for the day and time in the contact's timezone
+	if triggerGoal, ok := input.Config["trigger_goal"].(string); ok && triggerGoal != "" {
+		runAt := weekly.Next(resolved.Location, now)
+		output.Actions = append(output.Actions, helpers.HelperAction{
+			Type:   "execution_scheduled",
+			Target: input.HelperID,
+			Value: map[string]interface{}{
+				"run_at": runAt.UTC().Format(time.RFC3339),
+				"input":  map[string]interface{}{timezoneTriggerFireKey: true},
+			},
+		})
+		output.ModifiedData["trigger_goal"] = triggerGoal
+		output.ModifiedData["scheduled_for"] = runAt.Format(time.RFC3339)
+		output.Message = fmt.Sprintf("Timezone trigger scheduled for %s %s based on contact address", day, triggerTime)
+		output.Logs = append(output.Logs, fmt.Sprintf("Timezone trigger for contact %s: goal %s at %s", input.ContactID, triggerGoal, runAt.Format("Mon Jan 2 15:04 MST")))
 	}
 
-	if saveLatLng, ok := input.Config["save_lat_lng"].(string); ok && saveLatLng != "" && saveLatLng != "no_select" {
-		timezoneRequest["save_lat_lng_field"] = saveLatLng
+	return output, nil
+}
+
+// fire achieves the trigger goal once the scheduled time has come
+func (h *TimezoneTriggers) fire(ctx context.Context, input helpers.HelperInput, output *helpers.HelperOutput) (*helpers.HelperOutput, error) {
+	triggerGoal, _ := input.Config["trigger_goal"].(string)
+	if triggerGoal == "" {
+		output.Success = true
+		output.Message = "No trigger goal configured"
+		return output, nil
 	}
 
-	if saveOffset, ok := input.Config["save_time_zone_offset"].(string); ok && saveOffset != "" && saveOffset != "no_select" {
-		timezoneRequest["save_time_zone_offset_field"] = saveOffset
+	if err := input.Connector.AchieveGoal(ctx, input.ContactID, triggerGoal, "myfusionhelper"); err != nil {
+		output.Message = fmt.Sprintf("Failed to achieve trigger goal '%s': %v", triggerGoal, err)
+		return output, err
 	}
+	output.Actions = append(output.Actions, helpers.HelperAction{
+		Type:   "goal_achieved",
+		Target: input.ContactID,
+		Value:  triggerGoal,
+	})
+	output.Success = true
+	output.Message = fmt.Sprintf("Timezone trigger fired: achieved goal '%s'", triggerGoal)
+	output.Logs = append(output.Logs, output.Message)
+	return output, nil
+}
 
-	// Fire the trigger goal
-	if triggerGoal, ok := input.Config["trigger_goal"].(string); ok && triggerGoal != "" {
-		timezoneRequest["trigger_goal"] = triggerGoal
+// achieveFailedGoal fires the failed goal, if configured, for contacts whose
+// timezone cannot be resolved
+func (h *TimezoneTriggers) achieveFailedGoal(ctx context.Context, input helpers.HelperInput, output *helpers.HelperOutput) {
+	failedGoal, ok := input.Config["failed_goal"].(string)
+	if !ok || failedGoal == "" {
+		return
+	}
+	if err := input.Connector.AchieveGoal(ctx, input.ContactID, failedGoal, "myfusionhelper"); err != nil {
+		output.Logs = append(output.Logs, fmt.Sprintf("Failed to achieve failed goal: %v", err))
+		return
 	}
+	output.Actions = append(output.Actions, helpers.HelperAction{
+		Type:   "goal_achieved",
+		Target: input.ContactID,
+		Value:  failedGoal,
+	})
+}
 
-	output.Success = true
-	output.Message = fmt.Sprintf("Timezone trigger scheduled for %s %s based on contact address", day, triggerTime)
-	output.Actions = []helpers.HelperAction{
-		{
-			Type:   "timezone_trigger_scheduled",
-			Target: input.ContactID,
-			Value:  timezoneRequest,
-		},
+// saveField writes value to the contact field named by the config key, if set
+func (h *TimezoneTriggers) saveField(ctx context.Context, input helpers.HelperInput, output *helpers.HelperOutput, configKey, value string) {
+	field, ok := input.Config[configKey].(string)
+	if !ok || field == "" || field == "no_select" {
+		return
+	}
+	if err := input.Connector.SetContactFieldValue(ctx, input.ContactID, field, value); err != nil {
+		output.Logs = append(output.Logs, fmt.Sprintf("Warning: failed to save '%s' to field '%s': %v", value, field, err))
+		return
 	}
-	output.ModifiedData = timezoneRequest
-	output.Logs = append(output.Logs, fmt.Sprintf("Timezone trigger for contact %s: %s %s", input.ContactID, day, triggerTime))
+	output.Actions = append(output.Actions, helpers.HelperAction{
+		Type:   "field_updated",
+		Target: field,
+		Value:  value,
+	})
+}
 
-	return output, nil
+// contactAddress returns the first address set on the contact, as the parts
+// the timezone depends on and as a single line
+func contactAddress(fields map[string]interface{}) (timezone.Address, string) {
+	for _, set := range timezoneAddressFieldSets {
+		address := buildAddress(fields, [][]string{set})
+		if address == "" {
+			continue
+		}
+		part := func(i int) string {
+			if v, ok := fields[set[i]]; ok && v != nil {
+				return fmt.Sprintf("%v", v)
+			}
+			return ""
+		}
+		return timezone.Address{City: part(2), Region: part(3), PostalCode: part(4), Country: part(5)}, address
+	}
+	return timezone.Address{}, ""
 }
 
 // buildAddress tries multiple address field sets and returns the first non-empty one.
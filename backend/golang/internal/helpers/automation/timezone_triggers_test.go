@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/myfusionhelper/api/internal/connectors"
 	"github.com/myfusionhelper/api/internal/helpers"
@@ -14,6 +15,7 @@ type mockConnectorForTimezoneTriggers struct {
 	getContactError  error
 	achieveGoalCalls []struct{ contactID, goalName, integration string }
 	achieveGoalError error
+	setFieldCalls    map[string]interface{}
 }
 
 func (m *mockConnectorForTimezoneTriggers) GetContact(ctx context.Context, contactID string) (*connectors.NormalizedContact, error) {
@@ -69,7 +71,11 @@ func (m *mockConnectorForTimezoneTriggers) GetContactFieldValue(ctx context.Cont
 	return nil, fmt.Errorf("not implemented")
 }
 func (m *mockConnectorForTimezoneTriggers) SetContactFieldValue(ctx context.Context, contactID, fieldKey string, value interface{}) error {
-	return fmt.Errorf("not implemented")
+	if m.setFieldCalls == nil {
+		m.setFieldCalls = make(map[string]interface{})
+	}
+	m.setFieldCalls[fieldKey] = value
+	return nil
 }
 func (m *mockConnectorForTimezoneTriggers) TriggerAutomation(ctx context.Context, contactID string, automationID string) error {
 	return fmt.Errorf("not implemented")
@@ -173,9 +179,11 @@ func TestTimezoneTriggers_Execute_WithAddress(t *testing.T) {
 
 	output, err := h.Execute(context.Background(), helpers.HelperInput{
 		ContactID: "c123",
+		HelperID:  "helper:tz",
 		Config: map[string]interface{}{
-			"day":  "Monday",
-			"time": "9:00 AM",
+			"day":          "Monday",
+			"time":         "9:00 AM",
+			"trigger_goal": "Monday Morning",
 		},
 		Connector: mockConn,
 	})
@@ -189,9 +197,30 @@ func TestTimezoneTriggers_Execute_WithAddress(t *testing.T) {
 	if len(output.Actions) != 1 {
 		t.Fatal("Expected 1 action")
 	}
-	if output.Actions[0].Type != "timezone_trigger_scheduled" {
+	if output.Actions[0].Type != "execution_scheduled" {
 		t.Error("Wrong action type")
 	}
+	if output.ModifiedData["time_zone"] != "America/Los_Angeles" {
+		t.Errorf("Expected America/Los_Angeles, got %v", output.ModifiedData["time_zone"])
+	}
+
+	// The goal fires at 9:00 AM on a Monday in San Francisco, DST or not
+	value := output.Actions[0].Value.(map[string]interface{})
+	runAt, err := time.Parse(time.RFC3339, value["run_at"].(string))
+	if err != nil {
+		t.Fatalf("Expected an RFC 3339 run_at, got %v", value["run_at"])
+	}
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	local := runAt.In(la)
+	if local.Weekday() != time.Monday || local.Hour() != 9 || local.Minute() != 0 {
+		t.Errorf("Expected Monday 09:00 in Los Angeles, got %s", local)
+	}
+	if !runAt.After(time.Now()) || runAt.After(time.Now().Add(7*24*time.Hour)) {
+		t.Errorf("Expected run_at within the next week, got %s", runAt)
+	}
+	if input, _ := value["input"].(map[string]interface{}); input[timezoneTriggerFireKey] != true {
+		t.Errorf("Expected the scheduled execution to fire the goal, got %v", value["input"])
+	}
 }
 
 func TestTimezoneTriggers_Execute_NoAddress_FailedGoal(t *testing.T) {
@@ -299,8 +328,9 @@ func TestTimezoneTriggers_Execute_AlternateAddress(t *testing.T) {
 	output, err := h.Execute(context.Background(), helpers.HelperInput{
 		ContactID: "c123",
 		Config: map[string]interface{}{
-			"day":  "Tuesday",
-			"time": "14:00",
+			"day":          "Tuesday",
+			"time":         "14:00",
+			"trigger_goal": "Tuesday Afternoon",
 		},
 		Connector: mockConn,
 	})
@@ -314,6 +344,9 @@ func TestTimezoneTriggers_Execute_AlternateAddress(t *testing.T) {
 	if len(output.Actions) != 1 {
 		t.Fatal("Expected 1 action")
 	}
+	if output.ModifiedData["time_zone"] != "America/Los_Angeles" {
+		t.Errorf("Expected America/Los_Angeles, got %v", output.ModifiedData["time_zone"])
+	}
 }
 
 func TestTimezoneTriggers_Execute_WithOptionalFields(t *testing.T) {
@@ -349,22 +382,22 @@ func TestTimezoneTriggers_Execute_WithOptionalFields(t *testing.T) {
 		t.Error("Should succeed")
 	}
 
-	// Verify modified data contains timezone request
-	if output.ModifiedData == nil {
-		t.Fatal("ModifiedData should not be nil")
+	// Verify the resolved timezone data was saved to the configured fields
+	if mockConn.setFieldCalls["timezone_field"] != "America/Chicago" {
+		t.Errorf("Expected America/Chicago saved, got %v", mockConn.setFieldCalls["timezone_field"])
 	}
-	if output.ModifiedData["save_time_zone_field"] != "timezone_field" {
-		t.Error("Should include save_time_zone_field")
+	if mockConn.setFieldCalls["latlng_field"] != "29.76,-95.37" {
+		t.Errorf("Expected Texas coordinates saved, got %v", mockConn.setFieldCalls["latlng_field"])
 	}
-	if output.ModifiedData["save_lat_lng_field"] != "latlng_field" {
-		t.Error("Should include save_lat_lng_field")
-	}
-	if output.ModifiedData["save_time_zone_offset_field"] != "offset_field" {
-		t.Error("Should include save_time_zone_offset_field")
+	if offset := mockConn.setFieldCalls["offset_field"]; offset != "-5" && offset != "-6" {
+		t.Errorf("Expected a Central offset saved, got %v", offset)
 	}
 	if output.ModifiedData["trigger_goal"] != "Timezone Resolved" {
 		t.Error("Should include trigger_goal")
 	}
+	if len(output.Actions) != 4 {
+		t.Errorf("Expected 3 field updates and the scheduled trigger, got %d actions", len(output.Actions))
+	}
 }
 
 func TestTimezoneTriggers_Execute_ActionsRecorded(t *testing.T) {
@@ -375,15 +408,18 @@ func TestTimezoneTriggers_Execute_ActionsRecorded(t *testing.T) {
 			CustomFields: map[string]interface{}{
 				"StreetAddress1": "123 Test St",
 				"City":           "Portland",
+				"State":          "Oregon",
 			},
 		},
 	}
 
 	output, err := h.Execute(context.Background(), helpers.HelperInput{
 		ContactID: "c123",
+		HelperID:  "helper:tz",
 		Config: map[string]interface{}{
-			"day":  "Friday",
-			"time": "15:00",
+			"day":          "Friday",
+			"time":         "15:00",
+			"trigger_goal": "Friday Afternoon",
 		},
 		Connector: mockConn,
 	})
@@ -394,10 +430,10 @@ func TestTimezoneTriggers_Execute_ActionsRecorded(t *testing.T) {
 	if len(output.Actions) != 1 {
 		t.Fatalf("Expected 1 action, got %d", len(output.Actions))
 	}
-	if output.Actions[0].Type != "timezone_trigger_scheduled" {
+	if output.Actions[0].Type != "execution_scheduled" {
 		t.Error("Wrong action type")
 	}
-	if output.Actions[0].Target != "c123" {
+	if output.Actions[0].Target != "helper:tz" {
 		t.Error("Wrong action target")
 	}
 }
@@ -439,6 +475,7 @@ func TestTimezoneTriggers_Execute_MessageFormat(t *testing.T) {
 			CustomFields: map[string]interface{}{
 				"StreetAddress1": "100 Broadway",
 				"City":           "New York",
+				"State":          "NY",
 			},
 		},
 	}
@@ -446,8 +483,9 @@ func TestTimezoneTriggers_Execute_MessageFormat(t *testing.T) {
 	output, err := h.Execute(context.Background(), helpers.HelperInput{
 		ContactID: "c123",
 		Config: map[string]interface{}{
-			"day":  "Thursday",
-			"time": "11:45 AM",
+			"day":          "Thursday",
+			"time":         "11:45 AM",
+			"trigger_goal": "Thursday Lunch",
 		},
 		Connector: mockConn,
 	})
@@ -460,3 +498,81 @@ func TestTimezoneTriggers_Execute_MessageFormat(t *testing.T) {
 		t.Errorf("Expected message '%s', got '%s'", expected, output.Message)
 	}
 }
+
+func TestTimezoneTriggers_ValidateConfig_InvalidSchedule(t *testing.T) {
+	h := &TimezoneTriggers{}
+	if err := h.ValidateConfig(map[string]interface{}{"day": "Someday", "time": "9:00 AM"}); err == nil {
+		t.Error("Expected error for an invalid day")
+	}
+	if err := h.ValidateConfig(map[string]interface{}{"day": "Monday", "time": "late"}); err == nil {
+		t.Error("Expected error for an invalid time")
+	}
+}
+
+func TestTimezoneTriggers_Execute_Unresolved_FailedGoal(t *testing.T) {
+	h := &TimezoneTriggers{}
+	mockConn := &mockConnectorForTimezoneTriggers{
+		contact: &connectors.NormalizedContact{
+			ID: "c123",
+			CustomFields: map[string]interface{}{
+				"StreetAddress1": "1 Unknown Rd",
+				"City":           "Springfield",
+			},
+		},
+	}
+
+	output, err := h.Execute(context.Background(), helpers.HelperInput{
+		ContactID: "c123",
+		Config: map[string]interface{}{
+			"day":          "Monday",
+			"time":         "9:00 AM",
+			"trigger_goal": "Monday Morning",
+			"failed_goal":  "No Timezone",
+		},
+		Connector: mockConn,
+	})
+
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !output.Success {
+		t.Error("Should succeed")
+	}
+	if len(mockConn.achieveGoalCalls) != 1 || mockConn.achieveGoalCalls[0].goalName != "No Timezone" {
+		t.Errorf("Expected the failed goal achieved, got %+v", mockConn.achieveGoalCalls)
+	}
+	for _, action := range output.Actions {
+		if action.Type == "execution_scheduled" {
+			t.Error("Should not schedule the trigger goal")
+		}
+	}
+}
+
+func TestTimezoneTriggers_Execute_Fire(t *testing.T) {
+	h := &TimezoneTriggers{}
+	mockConn := &mockConnectorForTimezoneTriggers{}
+
+	output, err := h.Execute(context.Background(), helpers.HelperInput{
+		ContactID: "c123",
+		Config: map[string]interface{}{
+			"day":          "Monday",
+			"time":         "9:00 AM",
+			"trigger_goal": "Monday Morning",
+		},
+		Input:     map[string]interface{}{timezoneTriggerFireKey: true},
+		Connector: mockConn,
+	})
+
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !output.Success {
+		t.Error("Should succeed")
+	}
+	if len(mockConn.achieveGoalCalls) != 1 || mockConn.achieveGoalCalls[0].goalName != "Monday Morning" {
+		t.Errorf("Expected the trigger goal achieved, got %+v", mockConn.achieveGoalCalls)
+	}
+	if len(output.Actions) != 1 || output.Actions[0].Type != "goal_achieved" {
+		t.Errorf("Expected a goal_achieved action, got %+v", output.Actions)
+	}
+}
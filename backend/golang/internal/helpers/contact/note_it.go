@@ -3,9 +3,9 @@ package contact
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/templating"
 )
 
 // NewNoteIt creates a new NoteIt helper instance
@@ -54,6 +54,11 @@ func (h *NoteIt) ValidateConfig(config map[string]interface{}) error {
 	if _, ok := config["body"].(string); !ok || config["body"] == "" {
 		return fmt.Errorf("body is required")
 	}
+	for _, key := range []string{"subject", "body"} {
+		if err := templating.Validate(config[key].(string)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
 	return nil
 }
 
@@ -76,27 +81,24 @@ func (h *NoteIt) Execute(ctx context.Context, input helpers.HelperInput) (*helpe
 		return output, err
 	}
 
-	// Build field data for templates
-	fieldData := map[string]string{
-		"contact_id": contact.ID,
-		"first_name": contact.FirstName,
-		"last_name":  contact.LastName,
-		"email":      contact.Email,
-		"phone":      contact.Phone,
-		"company":    contact.Company,
-		"full_name":  strings.TrimSpace(contact.FirstName + " " + contact.LastName),
+	// Interpolate templates
+	data := &templating.Data{
+		Contact:     contact,
+		Input:       input.Input,
+		QueryParams: input.QueryParams,
+		Connector:   input.Connector,
+		ContactID:   input.ContactID,
 	}
-
-	// Add custom fields
-	if contact.CustomFields != nil {
-		for key, val := range contact.CustomFields {
-			fieldData[key] = fmt.Sprintf("%v", val)
-		}
+	subject, err = templating.Render(ctx, subject, templating.Text, data)
+	if err != nil {
+		output.Message = fmt.Sprintf("Failed to render subject: %v", err)
+		return output, err
+	}
+	body, err = templating.Render(ctx, body, templating.Text, data)
+	if err != nil {
+		output.Message = fmt.Sprintf("Failed to render body: %v", err)
+		return output, err
 	}
-
-	// Interpolate templates
-	subject = interpolateNoteTemplate(subject, fieldData)
-	body = interpolateNoteTemplate(body, fieldData)
 
 	// Build note data
 	noteData := map[string]interface{}{
@@ -120,11 +122,3 @@ func (h *NoteIt) Execute(ctx context.Context, input helpers.HelperInput) (*helpe
 
 	return output, nil
 }
-
-func interpolateNoteTemplate(template string, data map[string]string) string {
-	result := template
-	for key, value := range data {
-		result = strings.ReplaceAll(result, "{{"+key+"}}", value)
-	}
-	return result
-}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/myfusionhelper/api/internal/connectors"
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/templating"
 )
 
 // mockConnectorForNoteIt implements connectors.CRMConnector for testing
@@ -428,7 +429,7 @@ func TestNoteIt_Execute_ActionLogging(t *testing.T) {
 	}
 }
 
-func Test_interpolateNoteTemplate(t *testing.T) {
+func TestNoteIt_TemplateRendering(t *testing.T) {
 	tests := []struct {
 		name     string
 		template string
@@ -457,7 +458,7 @@ func Test_interpolateNoteTemplate(t *testing.T) {
 			name:     "unused placeholders",
 			template: "Hello {{name}}, {{age}}",
 			data:     map[string]string{"name": "Bob"},
-			want:     "Hello Bob, {{age}}",
+			want:     "Hello Bob, ",
 		},
 		{
 			name:     "repeated placeholder",
@@ -469,9 +470,17 @@ func Test_interpolateNoteTemplate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := interpolateNoteTemplate(tt.template, tt.data)
+			fields := make(map[string]interface{})
+			for k, v := range tt.data {
+				fields[k] = v
+			}
+			data := &templating.Data{Contact: &connectors.NormalizedContact{CustomFields: fields}}
+			got, err := templating.Render(context.Background(), tt.template, templating.Text, data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
 			if got != tt.want {
-				t.Errorf("interpolateNoteTemplate() = %v, want %v", got, tt.want)
+				t.Errorf("Render() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/myfusionhelper/api/internal/helpers"
 )
@@ -19,11 +20,27 @@ func init() {
 // Ported from legacy PHP get_the_first helper.
 type GetTheFirst struct{}
 
+// relatedRecordTypes are the record types connectors expose through
+// RelatedRecordsConnector. "job" is Keap's legacy name for orders.
+var relatedRecordTypes = []string{"invoice", "order", "job", "subscription"}
+
+// validateRelatedRecordType rejects record types no connector can list, such
+// as Keap's leads, credit cards and payments, which would otherwise always
+// read as empty.
+func validateRelatedRecordType(recordType string) error {
+	for _, t := range relatedRecordTypes {
+		if recordType == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid type: %s (supported: %s)", recordType, strings.Join(relatedRecordTypes, ", "))
+}
+
 func (h *GetTheFirst) GetName() string     { return "Get The First" }
 func (h *GetTheFirst) GetType() string     { return "get_the_first" }
 func (h *GetTheFirst) GetCategory() string { return "data" }
 func (h *GetTheFirst) GetDescription() string {
-	return "Retrieve the first (oldest) invoice, order, subscription, or opportunity record and store a field value"
+	return "Retrieve the first (oldest) invoice, order, or subscription record and store a field value"
 }
 func (h *GetTheFirst) RequiresCRM() bool       { return true }
 func (h *GetTheFirst) SupportedCRMs() []string { return nil }
@@ -34,7 +51,7 @@ func (h *GetTheFirst) GetConfigSchema() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"type": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"invoice", "job", "subscription", "lead", "creditcard", "payment"},
+				"enum":        relatedRecordTypes,
 				"description": "The record type to query for the first entry",
 			},
 			"from_field": map[string]interface{}{
@@ -55,12 +72,8 @@ func (h *GetTheFirst) ValidateConfig(config map[string]interface{}) error {
 	if !ok || recordType == "" {
 		return fmt.Errorf("type is required")
 	}
-	validTypes := map[string]bool{
-		"invoice": true, "job": true, "subscription": true,
-		"lead": true, "creditcard": true, "payment": true,
-	}
-	if !validTypes[recordType] {
-		return fmt.Errorf("invalid type: %s", recordType)
+	if err := validateRelatedRecordType(recordType); err != nil {
+		return err
 	}
 	if _, ok := config["from_field"].(string); !ok || config["from_field"] == "" {
 		return fmt.Errorf("from_field is required")
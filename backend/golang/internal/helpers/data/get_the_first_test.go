@@ -124,6 +124,22 @@ func TestGetTheFirst_ValidateConfig_MissingType(t *testing.T) {
 	}
 }
 
+// Test validation - record types no connector can list
+func TestGetTheFirst_ValidateConfig_UnsupportedType(t *testing.T) {
+	helper := &GetTheFirst{}
+
+	for _, recordType := range []string{"lead", "creditcard", "payment"} {
+		err := helper.ValidateConfig(map[string]interface{}{
+			"type":       recordType,
+			"from_field": "id",
+			"to_field":   "first_id",
+		})
+		if err == nil {
+			t.Errorf("Expected validation error for %s", recordType)
+		}
+	}
+}
+
 // Test validation - invalid type
 func TestGetTheFirst_ValidateConfig_InvalidType(t *testing.T) {
 	helper := &GetTheFirst{}
@@ -197,19 +213,9 @@ func TestGetTheFirst_ValidateConfig_Valid(t *testing.T) {
 			"to_field":   "first_subscription_plan",
 		},
 		{
-			"type":       "lead",
-			"from_field": "source",
-			"to_field":   "first_lead_source",
-		},
-		{
-			"type":       "creditcard",
-			"from_field": "last_four",
-			"to_field":   "first_card_last_four",
-		},
-		{
-			"type":       "payment",
-			"from_field": "amount",
-			"to_field":   "first_payment_amount",
+			"type":       "order",
+			"from_field": "total",
+			"to_field":   "first_order_total",
 		},
 	}
 
@@ -332,7 +338,7 @@ func TestGetTheFirst_Execute_NoRecordsFound(t *testing.T) {
 
 	mockConn := &mockConnectorForGetFirst{
 		fieldValues: map[string]interface{}{
-			"_related.payment.first.amount": nil,
+			"_related.subscription.first.amount": nil,
 		},
 	}
 
@@ -340,9 +346,9 @@ func TestGetTheFirst_Execute_NoRecordsFound(t *testing.T) {
 		ContactID: "123",
 		Connector: mockConn,
 		Config: map[string]interface{}{
-			"type":       "payment",
+			"type":       "subscription",
 			"from_field": "amount",
-			"to_field":   "first_payment_amount",
+			"to_field":   "first_subscription_amount",
 		},
 	}
 
@@ -355,12 +361,12 @@ func TestGetTheFirst_Execute_NoRecordsFound(t *testing.T) {
 		t.Error("Expected success=true")
 	}
 
-	if !strings.Contains(output.Message, "No payment records found") {
+	if !strings.Contains(output.Message, "No subscription records found") {
 		t.Errorf("Expected message about no records, got: %s", output.Message)
 	}
 
 	// Should not have updated the field
-	if _, ok := mockConn.updatedFields["first_payment_amount"]; ok {
+	if _, ok := mockConn.updatedFields["first_subscription_amount"]; ok {
 		t.Error("Should not update field when no records found")
 	}
 }
@@ -420,9 +426,9 @@ func TestGetTheFirst_Execute_FallbackNoField(t *testing.T) {
 		ContactID: "123",
 		Connector: mockConn,
 		Config: map[string]interface{}{
-			"type":       "lead",
-			"from_field": "source",
-			"to_field":   "first_lead_source",
+			"type":       "order",
+			"from_field": "status",
+			"to_field":   "first_order_status",
 		},
 	}
 
@@ -436,7 +442,7 @@ func TestGetTheFirst_Execute_FallbackNoField(t *testing.T) {
 	}
 
 	// Should not have updated the field
-	if _, ok := mockConn.updatedFields["first_lead_source"]; ok {
+	if _, ok := mockConn.updatedFields["first_order_status"]; ok {
 		t.Error("Should not update field when no value found")
 	}
 }
@@ -447,7 +453,7 @@ func TestGetTheFirst_Execute_ActionLogging(t *testing.T) {
 
 	mockConn := &mockConnectorForGetFirst{
 		fieldValues: map[string]interface{}{
-			"_related.creditcard.first.last_four": "1234",
+			"_related.invoice.first.number": "1234",
 		},
 	}
 
@@ -455,9 +461,9 @@ func TestGetTheFirst_Execute_ActionLogging(t *testing.T) {
 		ContactID: "123",
 		Connector: mockConn,
 		Config: map[string]interface{}{
-			"type":       "creditcard",
-			"from_field": "last_four",
-			"to_field":   "first_card_last_four",
+			"type":       "invoice",
+			"from_field": "number",
+			"to_field":   "first_invoice_number",
 		},
 	}
 
@@ -475,8 +481,8 @@ func TestGetTheFirst_Execute_ActionLogging(t *testing.T) {
 	if action.Type != "field_updated" {
 		t.Errorf("Expected action type 'field_updated', got '%s'", action.Type)
 	}
-	if action.Target != "first_card_last_four" {
-		t.Errorf("Expected action target 'first_card_last_four', got '%s'", action.Target)
+	if action.Target != "first_invoice_number" {
+		t.Errorf("Expected action target 'first_invoice_number', got '%s'", action.Target)
 	}
 
 	// Verify logs
@@ -488,7 +494,7 @@ func TestGetTheFirst_Execute_ActionLogging(t *testing.T) {
 	if output.ModifiedData == nil {
 		t.Fatal("Expected ModifiedData to be set")
 	}
-	if output.ModifiedData["first_card_last_four"] != "1234" {
-		t.Errorf("Expected ModifiedData['first_card_last_four'] = '1234', got: %v", output.ModifiedData["first_card_last_four"])
+	if output.ModifiedData["first_invoice_number"] != "1234" {
+		t.Errorf("Expected ModifiedData['first_invoice_number'] = '1234', got: %v", output.ModifiedData["first_invoice_number"])
 	}
 }
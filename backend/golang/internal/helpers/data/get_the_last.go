@@ -23,7 +23,7 @@ func (h *GetTheLast) GetName() string     { return "Get The Last" }
 func (h *GetTheLast) GetType() string     { return "get_the_last" }
 func (h *GetTheLast) GetCategory() string { return "data" }
 func (h *GetTheLast) GetDescription() string {
-	return "Retrieve the last (newest) invoice, order, subscription, or opportunity record and store a field value"
+	return "Retrieve the last (newest) invoice, order, or subscription record and store a field value"
 }
 func (h *GetTheLast) RequiresCRM() bool       { return true }
 func (h *GetTheLast) SupportedCRMs() []string { return nil }
@@ -34,7 +34,7 @@ func (h *GetTheLast) GetConfigSchema() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"type": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"invoice", "job", "subscription", "lead", "creditcard", "payment"},
+				"enum":        relatedRecordTypes,
 				"description": "The record type to query for the last entry",
 			},
 			"from_field": map[string]interface{}{
@@ -55,12 +55,8 @@ func (h *GetTheLast) ValidateConfig(config map[string]interface{}) error {
 	if !ok || recordType == "" {
 		return fmt.Errorf("type is required")
 	}
-	validTypes := map[string]bool{
-		"invoice": true, "job": true, "subscription": true,
-		"lead": true, "creditcard": true, "payment": true,
-	}
-	if !validTypes[recordType] {
-		return fmt.Errorf("invalid type: %s", recordType)
+	if err := validateRelatedRecordType(recordType); err != nil {
+		return err
 	}
 	if _, ok := config["from_field"].(string); !ok || config["from_field"] == "" {
 		return fmt.Errorf("from_field is required")
@@ -124,6 +124,22 @@ func TestGetTheLast_ValidateConfig_MissingType(t *testing.T) {
 	}
 }
 
+// Test validation - record types no connector can list
+func TestGetTheLast_ValidateConfig_UnsupportedType(t *testing.T) {
+	helper := &GetTheLast{}
+
+	for _, recordType := range []string{"lead", "creditcard", "payment"} {
+		err := helper.ValidateConfig(map[string]interface{}{
+			"type":       recordType,
+			"from_field": "id",
+			"to_field":   "last_id",
+		})
+		if err == nil {
+			t.Errorf("Expected validation error for %s", recordType)
+		}
+	}
+}
+
 // Test validation - invalid type
 func TestGetTheLast_ValidateConfig_InvalidType(t *testing.T) {
 	helper := &GetTheLast{}
@@ -197,19 +213,9 @@ func TestGetTheLast_ValidateConfig_Valid(t *testing.T) {
 			"to_field":   "last_subscription_plan",
 		},
 		{
-			"type":       "lead",
-			"from_field": "source",
-			"to_field":   "last_lead_source",
-		},
-		{
-			"type":       "creditcard",
-			"from_field": "last_four",
-			"to_field":   "last_card_last_four",
-		},
-		{
-			"type":       "payment",
-			"from_field": "amount",
-			"to_field":   "last_payment_amount",
+			"type":       "order",
+			"from_field": "total",
+			"to_field":   "last_order_total",
 		},
 	}
 
@@ -291,13 +297,13 @@ func TestGetTheLast_Execute_SubscriptionFound(t *testing.T) {
 	}
 }
 
-// Test execution - payment record found (newest)
-func TestGetTheLast_Execute_PaymentFound(t *testing.T) {
+// Test execution - subscription record found (newest)
+func TestGetTheLast_Execute_SubscriptionAmountFound(t *testing.T) {
 	helper := &GetTheLast{}
 
 	mockConn := &mockConnectorForGetLast{
 		fieldValues: map[string]interface{}{
-			"_related.payment.last.amount": "125.50",
+			"_related.subscription.last.amount": "125.50",
 		},
 	}
 
@@ -305,9 +311,9 @@ func TestGetTheLast_Execute_PaymentFound(t *testing.T) {
 		ContactID: "123",
 		Connector: mockConn,
 		Config: map[string]interface{}{
-			"type":       "payment",
+			"type":       "subscription",
 			"from_field": "amount",
-			"to_field":   "last_payment_amount",
+			"to_field":   "last_subscription_amount",
 		},
 	}
 
@@ -320,7 +326,7 @@ func TestGetTheLast_Execute_PaymentFound(t *testing.T) {
 		t.Error("Expected success=true")
 	}
 
-	result := mockConn.updatedFields["last_payment_amount"]
+	result := mockConn.updatedFields["last_subscription_amount"]
 	if result != "125.50" {
 		t.Errorf("Expected '125.50', got: %v", result)
 	}
@@ -332,7 +338,7 @@ func TestGetTheLast_Execute_NoRecordsFound(t *testing.T) {
 
 	mockConn := &mockConnectorForGetLast{
 		fieldValues: map[string]interface{}{
-			"_related.lead.last.source": nil,
+			"_related.order.last.status": nil,
 		},
 	}
 
@@ -340,9 +346,9 @@ func TestGetTheLast_Execute_NoRecordsFound(t *testing.T) {
 		ContactID: "123",
 		Connector: mockConn,
 		Config: map[string]interface{}{
-			"type":       "lead",
-			"from_field": "source",
-			"to_field":   "last_lead_source",
+			"type":       "order",
+			"from_field": "status",
+			"to_field":   "last_order_status",
 		},
 	}
 
@@ -355,12 +361,12 @@ func TestGetTheLast_Execute_NoRecordsFound(t *testing.T) {
 		t.Error("Expected success=true")
 	}
 
-	if !strings.Contains(output.Message, "No lead records found") {
+	if !strings.Contains(output.Message, "No order records found") {
 		t.Errorf("Expected message about no records, got: %s", output.Message)
 	}
 
 	// Should not have updated the field
-	if _, ok := mockConn.updatedFields["last_lead_source"]; ok {
+	if _, ok := mockConn.updatedFields["last_order_status"]; ok {
 		t.Error("Should not update field when no records found")
 	}
 }
@@ -420,9 +426,9 @@ func TestGetTheLast_Execute_FallbackNoField(t *testing.T) {
 		ContactID: "123",
 		Connector: mockConn,
 		Config: map[string]interface{}{
-			"type":       "creditcard",
-			"from_field": "last_four",
-			"to_field":   "last_card_last_four",
+			"type":       "invoice",
+			"from_field": "number",
+			"to_field":   "last_invoice_number",
 		},
 	}
 
@@ -436,7 +442,7 @@ func TestGetTheLast_Execute_FallbackNoField(t *testing.T) {
 	}
 
 	// Should not have updated the field
-	if _, ok := mockConn.updatedFields["last_card_last_four"]; ok {
+	if _, ok := mockConn.updatedFields["last_invoice_number"]; ok {
 		t.Error("Should not update field when no value found")
 	}
 }
@@ -3,13 +3,8 @@ package data
 import (
 	"context"
 	"fmt"
-	"os"
-	"strconv"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/myfusionhelper/api/internal/helpers"
 )
 
@@ -278,50 +273,24 @@ func (h *SplitIt) executeStateFieldMethod(ctx context.Context, input helpers.Hel
 	return currentIndex, currentChoice, currentOption
 }
 
-// executeCounterMethod uses DynamoDB atomic counter to determine split
+// executeCounterMethod uses an atomic counter to determine split
 func (h *SplitIt) executeCounterMethod(ctx context.Context, input helpers.HelperInput, options []string, splitCount int) (int, string, string, error) {
 	choiceLabels := []string{"A", "B", "C", "D", "E"}
 
-	// Load DynamoDB client
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		return 0, "", "", fmt.Errorf("failed to load AWS config: %w", err)
-	}
-
-	client := dynamodb.NewFromConfig(cfg)
-	tableName := os.Getenv("RATE_LIMITS_TABLE")
-	if tableName == "" {
-		return 0, "", "", fmt.Errorf("RATE_LIMITS_TABLE environment variable not set")
+	if input.Stores == nil || input.Stores.Counters == nil {
+		return 0, "", "", fmt.Errorf("counter store not configured")
 	}
 
-	// Counter key: split:{helper_id}
+	// Counter key: split:{helper_id}. It never expires so the rotation
+	// continues across executions.
 	counterKey := fmt.Sprintf("split:%s", input.HelperID)
 
 	// Atomic increment
-	result, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(tableName),
-		Key: map[string]ddbtypes.AttributeValue{
-			"rate_key": &ddbtypes.AttributeValueMemberS{Value: counterKey},
-		},
-		UpdateExpression: aws.String("ADD #count :inc"),
-		ExpressionAttributeNames: map[string]string{
-			"#count": "count",
-		},
-		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
-			":inc": &ddbtypes.AttributeValueMemberN{Value: "1"},
-		},
-		ReturnValues: ddbtypes.ReturnValueAllNew,
-	})
+	counterValue, err := input.Stores.Counters.Increment(ctx, counterKey, 1, time.Time{})
 	if err != nil {
 		return 0, "", "", fmt.Errorf("failed to increment counter: %w", err)
 	}
 
-	// Extract counter value
-	counterValue := int64(0)
-	if countAttr, ok := result.Attributes["count"].(*ddbtypes.AttributeValueMemberN); ok {
-		counterValue, _ = strconv.ParseInt(countAttr.Value, 10, 64)
-	}
-
 	// Modulo to determine split choice
 	currentIndex := int(counterValue % int64(splitCount))
 	currentChoice := choiceLabels[currentIndex]
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/database/memory"
 	"github.com/myfusionhelper/api/internal/helpers"
 )
 
@@ -506,3 +507,46 @@ func TestSplitIt_Execute_MultipleExecutions(t *testing.T) {
 		}
 	}
 }
+
+// Test counter method rotates through options using the shared counter store
+func TestSplitIt_Execute_CounterMethod(t *testing.T) {
+	helper := &SplitIt{}
+	stores := memory.NewStores()
+	mockConn := &mockConnectorForSplit{}
+
+	config := map[string]interface{}{
+		"split_method": "counter",
+		"split_count":  float64(3),
+		"mode":         "tag",
+		"option_a":     "tag-a",
+		"option_b":     "tag-b",
+		"option_c":     "tag-c",
+	}
+
+	// Counter starts at 1, so the rotation begins with option B
+	expectedSequence := []string{"tag-b", "tag-c", "tag-a", "tag-b"}
+	for i, expected := range expectedSequence {
+		mockConn.appliedTags = nil
+		input := helpers.HelperInput{
+			ContactID: fmt.Sprintf("contact-%d", i),
+			HelperID:  "helper:split",
+			Connector: mockConn,
+			Config:    config,
+			Stores:    stores,
+		}
+
+		if _, err := helper.Execute(context.Background(), input); err != nil {
+			t.Fatalf("Execution %d failed: %v", i+1, err)
+		}
+		if len(mockConn.appliedTags) != 1 || mockConn.appliedTags[0] != expected {
+			t.Errorf("Execution %d: expected tag '%s', got: %v", i+1, expected, mockConn.appliedTags)
+		}
+	}
+
+	t.Run("without stores", func(t *testing.T) {
+		input := helpers.HelperInput{ContactID: "123", HelperID: "helper:split", Connector: mockConn, Config: config}
+		if _, err := helper.Execute(context.Background(), input); err == nil {
+			t.Error("Expected an error without a counter store")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+// DryRunHelper runs a stored helper synchronously against its CRM connection
+// with CRM writes recorded instead of performed. Nothing is written to the
+// executions table and the run does not count toward monthly_executions.
+func DryRunHelper(ctx context.Context, stores *database.Stores, helper *apitypes.Helper, contactID string, input map[string]interface{}, queryParams map[string]string, userID string) (*ExecutionResult, error) {
+	var connector connectors.CRMConnector
+	if helper.ConnectionID != "" {
+		var err error
+		connector, err = loader.LoadConnectorWithTranslation(ctx, stores, helper.ConnectionID, helper.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load connection: %w", err)
+		}
+	}
+
+	req := ExecutionRequest{
+		HelperType:   helper.HelperType,
+		ContactID:    contactID,
+		Config:       helper.Config,
+		Input:        input,
+		QueryParams:  queryParams,
+		UserID:       userID,
+		AccountID:    helper.AccountID,
+		HelperID:     helper.HelperID,
+		ConnectionID: helper.ConnectionID,
+		Stores:       stores,
+		DryRun:       true,
+	}
+
+	// Helper failures are part of the dry run result, not an error of the run itself
+	result, _ := NewExecutor().Execute(ctx, req, connector)
+	return result, nil
+}
+
+// dryRunStores returns throwaway stores for a dry run, so helpers that keep
+// state write nothing. Counters read the live values and keep increments to
+// themselves, so limit_it and split_it decide as a real run would.
+func dryRunStores(stores *database.Stores) *database.Stores {
+	if stores == nil {
+		return nil
+	}
+	dry := memory.NewStores()
+	if stores.Counters != nil {
+		dry.Counters = &dryRunCounters{live: stores.Counters, deltas: make(map[string]int64)}
+	}
+	return dry
+}
+
+// dryRunCounters overlays a dry run's increments on the live counters
+type dryRunCounters struct {
+	live database.CounterStore
+
+	mu     sync.Mutex
+	deltas map[string]int64
+}
+
+func (c *dryRunCounters) Get(ctx context.Context, key string) (int64, error) {
+	value, err := c.live.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return value + c.deltas[key], nil
+}
+
+func (c *dryRunCounters) Increment(ctx context.Context, key string, delta int64, expiresAt time.Time) (int64, error) {
+	c.mu.Lock()
+	c.deltas[key] += delta
+	c.mu.Unlock()
+	return c.Get(ctx, key)
+}
+
+// DryRunResponse shapes a dry run result for API responses: the intended CRM
+// changes, the post-execution actions that would be queued, and the helper logs.
+func DryRunResponse(result *ExecutionResult) map[string]interface{} {
+	response := map[string]interface{}{
+		"dry_run":     true,
+		"helper_type": result.HelperType,
+		"contact_id":  result.ContactID,
+		"success":     result.Success,
+		"error":       result.Error,
+		"error_code":  result.ErrorCode,
+		"duration_ms": result.DurationMs,
+		"changes":     result.Changes,
+		"message":     "",
+		"actions":     []HelperAction{},
+		"logs":        []string{},
+	}
+	if result.Changes == nil {
+		response["changes"] = []connectors.RecordedChange{}
+	}
+	if result.Output != nil {
+		response["message"] = result.Output.Message
+		if result.Output.Actions != nil {
+			response["actions"] = result.Output.Actions
+		}
+		if result.Output.Logs != nil {
+			response["logs"] = result.Output.Logs
+		}
+		if result.Output.ModifiedData != nil {
+			response["modified_data"] = result.Output.ModifiedData
+		}
+	}
+	return response
+}
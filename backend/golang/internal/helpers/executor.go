@@ -2,11 +2,13 @@ package helpers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/database"
 )
 
 // ExecutionRequest represents a request to execute a helper
@@ -22,6 +24,8 @@ type ExecutionRequest struct {
 	ConnectionID string                                  `json:"connection_id"`
 	ServiceAuths map[string]*connectors.ConnectorConfig   `json:"-"` // pre-loaded service connection credentials
 	APIKey       string                                  `json:"-"` // Original x-api-key header for relay helpers
+	Stores       *database.Stores                         `json:"-"` // Record stores for helpers that keep state (limit_it, split_it)
+	DryRun       bool                                    `json:"dry_run,omitempty"` // Record CRM writes instead of performing them
 }
 
 // ExecutionResult represents the full result of a helper execution
@@ -33,6 +37,10 @@ type ExecutionResult struct {
 	ContactID    string                 `json:"contact_id"`
 	DurationMs   int64                  `json:"duration_ms"`
 	ExecutedAt   time.Time              `json:"executed_at"`
+	DryRun       bool                        `json:"dry_run,omitempty"`
+	Changes      []connectors.RecordedChange `json:"changes,omitempty"` // CRM writes captured during a dry run
+	ErrorCode    string                 `json:"error_code,omitempty"`  // ErrCode* classification of a failure
+	ErrorStack   string                 `json:"error_stack,omitempty"` // truncated stack trace of a panic
 }
 
 // Executor handles the execution of helpers
@@ -43,36 +51,60 @@ func NewExecutor() *Executor {
 	return &Executor{}
 }
 
-// Execute runs a helper with the given request and connector
-func (e *Executor) Execute(ctx context.Context, req ExecutionRequest, connector connectors.CRMConnector) (*ExecutionResult, error) {
+// Execute runs a helper with the given request and connector.
+// With req.DryRun set, CRM reads go to the real connector but writes are
+// recorded into result.Changes instead of being performed, and helpers that
+// keep state get throwaway stores.
+// Failures are classified into result.ErrorCode; a panicking helper is
+// recovered and reported as an ErrCodePanic failure with its stack.
+func (e *Executor) Execute(ctx context.Context, req ExecutionRequest, connector connectors.CRMConnector) (result *ExecutionResult, err error) {
 	start := time.Now()
 
-	result := &ExecutionResult{
+	result = &ExecutionResult{
 		HelperType: req.HelperType,
 		ContactID:  req.ContactID,
 		ExecutedAt: start,
+		DryRun:     req.DryRun,
+	}
+
+	// A panic fails this execution only, not the caller's whole batch
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := PanicError(r)
+			log.Printf("Helper %s panicked: %v\n%s", req.HelperType, r, panicErr.Stack)
+			err = fail(result, start, panicErr)
+		}
+	}()
+
+	if req.DryRun {
+		// Service integrations and relays act outside the CRM where their
+		// effects cannot be recorded, so they get no credentials in a dry run
+		req.ServiceAuths = nil
+		req.APIKey = ""
+		// Stateful helpers count against throwaway stores
+		req.Stores = dryRunStores(req.Stores)
+
+		if connector != nil {
+			recorder := connectors.NewRecordingConnector(connector)
+			connector = recorder
+			defer func() { result.Changes = recorder.Changes() }()
+		}
 	}
 
 	// Look up the helper implementation
 	helper, err := NewHelper(req.HelperType)
 	if err != nil {
-		result.Error = fmt.Sprintf("unknown helper type: %s", req.HelperType)
-		result.DurationMs = time.Since(start).Milliseconds()
-		return result, err
+		return result, fail(result, start, NewExecutionError(ErrCodeConfig, err))
 	}
 
-	// Validate config
-	if err := helper.ValidateConfig(req.Config); err != nil {
-		result.Error = fmt.Sprintf("invalid config: %v", err)
-		result.DurationMs = time.Since(start).Milliseconds()
-		return result, err
+	// Validate config against the helper's schema, then its own cross-field rules
+	if err := ValidateHelperConfig(helper, req.Config); err != nil {
+		return result, fail(result, start, NewExecutionError(ErrCodeConfig, fmt.Errorf("invalid config: %w", err)))
 	}
 
 	// Check if CRM connector is required but not provided
 	if helper.RequiresCRM() && connector == nil {
-		result.Error = "helper requires a CRM connection but none was provided"
-		result.DurationMs = time.Since(start).Milliseconds()
-		return result, fmt.Errorf("%s", result.Error)
+		return result, fail(result, start, NewExecutionError(ErrCodeConfig, fmt.Errorf("helper requires a CRM connection but none was provided")))
 	}
 
 	// Fetch contact data if connector is available and contact ID is provided
@@ -100,19 +132,34 @@ func (e *Executor) Execute(ctx context.Context, req ExecutionRequest, connector
 		AccountID:    req.AccountID,
 		HelperID:     req.HelperID,
 		APIKey:       req.APIKey,
+		Stores:       req.Stores,
 	}
 
 	// Execute the helper
 	output, err := helper.Execute(ctx, input)
-	result.DurationMs = time.Since(start).Milliseconds()
+	result.Output = output
 
 	if err != nil {
-		result.Error = err.Error()
-		result.Output = output
-		return result, err
+		// Helpers often flatten errors into messages, so check the deadline directly
+		if ctx.Err() == context.DeadlineExceeded && ClassifyError(err) != ErrCodeTimeout {
+			err = NewExecutionError(ErrCodeTimeout, fmt.Errorf("execution deadline exceeded: %w", err))
+		}
+		return result, fail(result, start, err)
 	}
 
+	result.DurationMs = time.Since(start).Milliseconds()
 	result.Success = output.Success
-	result.Output = output
 	return result, nil
 }
+
+// fail records a classified failure on the result and returns err
+func fail(result *ExecutionResult, start time.Time, err error) error {
+	result.Error = err.Error()
+	result.ErrorCode = ClassifyError(err)
+	var execErr *ExecutionError
+	if errors.As(err, &execErr) {
+		result.ErrorStack = execErr.Stack
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	return err
+}
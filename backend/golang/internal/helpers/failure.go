@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+// Error codes recorded on failed executions
+const (
+	ErrCodeConfig  = "config_error" // invalid config or missing connection; fails the same way on every attempt
+	ErrCodeCRM     = "crm_error"    // the CRM or connector returned an error
+	ErrCodeTimeout = "timeout"      // the execution deadline passed
+	ErrCodePanic   = "panic"        // the helper panicked
+	ErrCodeLimit   = "limit"        // the account reached its execution limit
+	ErrCodeHelper  = "helper_error" // any other failure reported by the helper
+)
+
+// maxStackBytes bounds the stack trace stored on an execution
+const maxStackBytes = 4096
+
+// ExecutionError is a classified execution failure. Stack is set for panics.
+type ExecutionError struct {
+	Code  string
+	Err   error
+	Stack string
+}
+
+func (e *ExecutionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// NewExecutionError wraps err with an error code
+func NewExecutionError(code string, err error) *ExecutionError {
+	return &ExecutionError{Code: code, Err: err}
+}
+
+// PanicError converts a recovered panic value into an ExecutionError carrying
+// the current goroutine's stack. Call it from the deferred recover.
+func PanicError(recovered interface{}) *ExecutionError {
+	return &ExecutionError{
+		Code:  ErrCodePanic,
+		Err:   fmt.Errorf("helper panicked: %v", recovered),
+		Stack: TruncateStack(string(debug.Stack())),
+	}
+}
+
+// TruncateStack limits a stack trace to maxStackBytes
+func TruncateStack(stack string) string {
+	if len(stack) <= maxStackBytes {
+		return stack
+	}
+	return stack[:maxStackBytes] + "\n...truncated"
+}
+
+// ClassifyError returns the error code for an execution failure
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	var execErr *ExecutionError
+	if errors.As(err, &execErr) {
+		return execErr.Code
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrCodeTimeout
+	}
+	var connErr *connectors.ConnectorError
+	if errors.As(err, &connErr) {
+		return ErrCodeCRM
+	}
+	return ErrCodeHelper
+}
@@ -0,0 +1,108 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+// stubHelper is a CRM-free helper whose Execute is supplied by the test
+type stubHelper struct {
+	execute func(ctx context.Context, input HelperInput) (*HelperOutput, error)
+}
+
+func (h *stubHelper) GetName() string                                    { return "Stub" }
+func (h *stubHelper) GetType() string                                    { return "stub" }
+func (h *stubHelper) GetCategory() string                                { return "test" }
+func (h *stubHelper) GetDescription() string                             { return "" }
+func (h *stubHelper) GetConfigSchema() map[string]interface{}            { return nil }
+func (h *stubHelper) ValidateConfig(config map[string]interface{}) error { return nil }
+func (h *stubHelper) RequiresCRM() bool                                  { return false }
+func (h *stubHelper) SupportedCRMs() []string                            { return nil }
+func (h *stubHelper) Execute(ctx context.Context, input HelperInput) (*HelperOutput, error) {
+	return h.execute(ctx, input)
+}
+
+func registerStub(t *testing.T, helperType string, execute func(ctx context.Context, input HelperInput) (*HelperOutput, error)) {
+	Register(helperType, func() Helper { return &stubHelper{execute: execute} })
+	t.Cleanup(func() {
+		defaultRegistry.mu.Lock()
+		delete(defaultRegistry.factories, helperType)
+		defaultRegistry.mu.Unlock()
+	})
+}
+
+func TestExecutor_Execute_RecoversPanic(t *testing.T) {
+	registerStub(t, "test_panic", func(ctx context.Context, input HelperInput) (*HelperOutput, error) {
+		_ = input.Config["missing"].(string)
+		return nil, nil
+	})
+
+	result, err := NewExecutor().Execute(context.Background(), ExecutionRequest{HelperType: "test_panic"}, nil)
+	if err == nil {
+		t.Fatal("Expected error from panicking helper")
+	}
+	if result.Success || result.ErrorCode != ErrCodePanic {
+		t.Errorf("Expected failed result with code %s, got success=%v code=%s", ErrCodePanic, result.Success, result.ErrorCode)
+	}
+	if !strings.HasPrefix(result.Error, "helper panicked:") {
+		t.Errorf("Expected panic message, got %q", result.Error)
+	}
+	if !strings.Contains(result.ErrorStack, "goroutine") || len(result.ErrorStack) > maxStackBytes+len("\n...truncated") {
+		t.Errorf("Expected truncated stack trace, got %d bytes", len(result.ErrorStack))
+	}
+}
+
+func TestExecutor_Execute_Timeout(t *testing.T) {
+	registerStub(t, "test_slow", func(ctx context.Context, input HelperInput) (*HelperOutput, error) {
+		<-ctx.Done()
+		// Helpers often lose the wrapped error when building messages
+		return &HelperOutput{}, fmt.Errorf("failed to update contact: %v", ctx.Err())
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result, err := NewExecutor().Execute(ctx, ExecutionRequest{HelperType: "test_slow"}, nil)
+	if ClassifyError(err) != ErrCodeTimeout {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+	if result.ErrorCode != ErrCodeTimeout {
+		t.Errorf("Expected code %s, got %s", ErrCodeTimeout, result.ErrorCode)
+	}
+}
+
+func TestExecutor_Execute_ConfigError(t *testing.T) {
+	result, err := NewExecutor().Execute(context.Background(), ExecutionRequest{HelperType: "no_such_helper"}, nil)
+	if err == nil || result.ErrorCode != ErrCodeConfig {
+		t.Errorf("Expected config_error for unknown helper, got %v (%s)", err, result.ErrorCode)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"classified", NewExecutionError(ErrCodeLimit, errors.New("limit reached")), ErrCodeLimit},
+		{"wrapped classified", fmt.Errorf("run: %w", NewExecutionError(ErrCodeConfig, errors.New("bad"))), ErrCodeConfig},
+		{"deadline", fmt.Errorf("get contact: %w", context.DeadlineExceeded), ErrCodeTimeout},
+		{"connector", fmt.Errorf("apply tag: %w", connectors.NewConnectorError("keap", 500, "boom", true)), ErrCodeCRM},
+		{"other", errors.New("something else"), ErrCodeHelper},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
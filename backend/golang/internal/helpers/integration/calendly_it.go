@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/templating"
 )
 
 // NewCalendlyIt creates a new CalendlyIt helper instance
@@ -140,40 +141,23 @@ func (h *CalendlyIt) Execute(ctx context.Context, input helpers.HelperInput) (*h
 		return output, err
 	}
 
-	// Build field data map
-	fieldData := map[string]string{
-		"Id":        contact.ID,
-		"FirstName": contact.FirstName,
-		"LastName":  contact.LastName,
-		"Email":     contact.Email,
-		"Phone1":    contact.Phone,
-		"Company":   contact.Company,
-		"JobTitle":  contact.JobTitle,
-		"full_name": strings.TrimSpace(contact.FirstName + " " + contact.LastName),
-	}
-
-	// Add custom fields
-	if contact.CustomFields != nil {
-		for key, value := range contact.CustomFields {
-			fieldData[key] = fmt.Sprintf("%v", value)
-		}
+	data := &templating.Data{
+		Contact:     contact,
+		Input:       input.Input,
+		QueryParams: input.QueryParams,
+		Connector:   input.Connector,
+		ContactID:   input.ContactID,
 	}
 
 	// Resolve email
-	email := ""
-	if val, exists := fieldData[emailField]; exists && val != "" {
-		email = val
-	}
+	email := data.LookupString(ctx, emailField)
 	if email == "" {
 		output.Message = fmt.Sprintf("Email field '%s' is empty for contact %s", emailField, input.ContactID)
 		return output, fmt.Errorf("email field '%s' is empty", emailField)
 	}
 
 	// Resolve name
-	name := ""
-	if val, exists := fieldData[nameField]; exists && val != "" {
-		name = val
-	}
+	name := data.LookupString(ctx, nameField)
 	if name == "" {
 		name = strings.TrimSpace(contact.FirstName + " " + contact.LastName)
 	}
@@ -14,7 +14,9 @@ import (
 	"time"
 
 	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/expr"
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/templating"
 )
 
 // NewHookIt creates a new HookIt helper instance
@@ -147,6 +149,7 @@ func (h *HookIt) GetConfigSchema() map[string]interface{} {
 				"items": map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
+						"condition":       map[string]interface{}{"type": "string", "description": "Expression the contact must match, e.g. custom.LeadScore >= 80 (replaces condition_field/op/value)"},
 						"condition_field": map[string]interface{}{"type": "string", "description": "Contact field to check"},
 						"condition_op":    map[string]interface{}{"type": "string", "enum": []string{"equals", "not_equals", "contains", "not_contains", "exists", "not_exists"}, "description": "Comparison operator"},
 						"condition_value": map[string]interface{}{"type": "string", "description": "Value to compare against"},
@@ -235,6 +238,22 @@ func (h *HookIt) ValidateConfig(config map[string]interface{}) error {
 				return fmt.Errorf("%s mode requires webhook_url", mode)
 			}
 		}
+		if mode == "v3" {
+			rules, _ := config["payload_rules"].([]interface{})
+			for i, r := range rules {
+				ruleMap, ok := r.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				condition, err := h.ruleCondition(ruleMap)
+				if err == nil {
+					_, err = expr.Compile(condition)
+				}
+				if err != nil {
+					return fmt.Errorf("payload_rules[%d].condition: %w", i, err)
+				}
+			}
+		}
 	case "by_tag":
 		// Tag mode requires tag_event and at least one action (goal or webhook)
 		if _, hasTagEvent := config["tag_event"]; !hasTagEvent {
@@ -247,6 +266,24 @@ func (h *HookIt) ValidateConfig(config map[string]interface{}) error {
 		}
 	}
 
+	// URLs may carry {{field}} merge fields
+	for _, key := range []string{"webhook_url", "tag_action_webhook"} {
+		if url, ok := config[key].(string); ok {
+			if err := templating.Validate(url); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+	batchWebhooks, _ := config["batch_webhooks"].([]interface{})
+	for i, wh := range batchWebhooks {
+		whMap, _ := wh.(map[string]interface{})
+		if url, ok := whMap["url"].(string); ok {
+			if err := templating.Validate(url); err != nil {
+				return fmt.Errorf("batch_webhooks[%d].url: %w", i, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -412,7 +449,12 @@ func (h *HookIt) executeV2(ctx context.Context, input helpers.HelperInput, outpu
 	}
 
 	// Interpolate webhook URL with contact data
-	interpolatedURL := h.interpolateString(webhookURL, input.ContactData)
+	interpolatedURL, err := h.interpolateString(ctx, webhookURL, input)
+	if err != nil {
+		output.Success = false
+		output.Message = fmt.Sprintf("Failed to render webhook_url: %v", err)
+		return output, err
+	}
 
 	// Build payload from contact data
 	payload, err := json.Marshal(map[string]interface{}{
@@ -545,17 +587,19 @@ func (h *HookIt) executeV3(ctx context.Context, input helpers.HelperInput, outpu
 					continue
 				}
 
-				conditionField, _ := ruleMap["condition_field"].(string)
-				conditionOp, _ := ruleMap["condition_op"].(string)
-				conditionValue, _ := ruleMap["condition_value"].(string)
 				payloadData, _ := ruleMap["payload_data"].(map[string]interface{})
 
-				if h.evaluateCondition(input.ContactData, conditionField, conditionOp, conditionValue) {
+				condition, matched, err := h.evaluateCondition(input, ruleMap)
+				if err != nil {
+					output.Logs = append(output.Logs, fmt.Sprintf("Skipping conditional rule: %v", err))
+					continue
+				}
+				if matched {
 					// First match wins, merge payload data
 					for k, v := range payloadData {
 						payload[k] = v
 					}
-					output.Logs = append(output.Logs, fmt.Sprintf("Matched conditional rule: %s %s %s", conditionField, conditionOp, conditionValue))
+					output.Logs = append(output.Logs, fmt.Sprintf("Matched conditional rule: %s", condition))
 					break
 				}
 			}
@@ -575,7 +619,12 @@ func (h *HookIt) executeV3(ctx context.Context, input helpers.HelperInput, outpu
 
 	webhookURL := h.getString(input.Config, "webhook_url", "")
 	method := h.getString(input.Config, "webhook_method", "POST")
-	interpolatedURL := h.interpolateString(webhookURL, input.ContactData)
+	interpolatedURL, err := h.interpolateString(ctx, webhookURL, input)
+	if err != nil {
+		output.Success = false
+		output.Message = fmt.Sprintf("Failed to render webhook_url: %v", err)
+		return output, err
+	}
 
 	resp, err := h.callWebhook(ctx, method, interpolatedURL, payloadBytes, input.Config)
 	if err != nil {
@@ -687,8 +736,11 @@ func (h *HookIt) executeBatchWebhooks(ctx context.Context, input helpers.HelperI
 			continue
 		}
 
-		interpolatedURL := h.interpolateString(url, input.ContactData)
-		resp, err := h.callWebhook(ctx, method, interpolatedURL, payload, input.Config)
+		interpolatedURL, err := h.interpolateString(ctx, url, input)
+		var resp *http.Response
+		if err == nil {
+			resp, err = h.callWebhook(ctx, method, interpolatedURL, payload, input.Config)
+		}
 
 		result := map[string]interface{}{
 			"index":  i,
@@ -808,8 +860,11 @@ func (h *HookIt) executeByTag(ctx context.Context, input helpers.HelperInput, ou
 			"timestamp":   time.Now().Unix(),
 		})
 
-		interpolatedURL := h.interpolateString(tagWebhook, input.ContactData)
-		resp, err := h.callWebhook(ctx, "POST", interpolatedURL, payload, input.Config)
+		interpolatedURL, err := h.interpolateString(ctx, tagWebhook, input)
+		var resp *http.Response
+		if err == nil {
+			resp, err = h.callWebhook(ctx, "POST", interpolatedURL, payload, input.Config)
+		}
 		if err != nil {
 			output.Logs = append(output.Logs, fmt.Sprintf("Tag webhook failed: %v", err))
 		} else {
@@ -941,15 +996,20 @@ func (h *HookIt) parseAndMapResponse(ctx context.Context, respBody []byte, input
 
 // Utility methods
 
-func (h *HookIt) interpolateString(template string, contact interface{}) string {
-	// Simple {{field}} interpolation
-	// TODO: Implement proper template interpolation with contact data
-	return template
+// interpolateString renders {{field}} merge fields in a webhook URL, escaping
+// values for their place in the URL
+func (h *HookIt) interpolateString(ctx context.Context, template string, input helpers.HelperInput) (string, error) {
+	return templating.Render(ctx, template, templating.URL, &templating.Data{
+		Contact:     input.ContactData,
+		Input:       input.Input,
+		QueryParams: input.QueryParams,
+		Connector:   input.Connector,
+		ContactID:   input.ContactID,
+	})
 }
 
-func (h *HookIt) getContactField(contact interface{}, field string) interface{} {
-	// TODO: Implement contact field extraction
-	return nil
+func (h *HookIt) getContactField(contact *connectors.NormalizedContact, field string) interface{} {
+	return expr.ContactField(contact, field)
 }
 
 func (h *HookIt) applyTransform(value interface{}, transform string) interface{} {
@@ -973,9 +1033,34 @@ func (h *HookIt) applyTransform(value interface{}, transform string) interface{}
 	}
 }
 
-func (h *HookIt) evaluateCondition(contact interface{}, field, op, value string) bool {
-	// TODO: Implement condition evaluation
-	return false
+// ruleCondition returns the expression of a payload rule: its condition, or
+// else the condition_field/op/value comparison
+func (h *HookIt) ruleCondition(rule map[string]interface{}) (string, error) {
+	if condition, _ := rule["condition"].(string); condition != "" {
+		return condition, nil
+	}
+	field, _ := rule["condition_field"].(string)
+	if field == "" {
+		return "", fmt.Errorf("rule has no condition")
+	}
+	op, _ := rule["condition_op"].(string)
+	value, _ := rule["condition_value"].(string)
+	return expr.Comparison(field, op, value)
+}
+
+// evaluateCondition evaluates a payload rule's condition against the contact
+// and execution input, returning the expression it evaluated
+func (h *HookIt) evaluateCondition(input helpers.HelperInput, rule map[string]interface{}) (string, bool, error) {
+	condition, err := h.ruleCondition(rule)
+	if err != nil {
+		return "", false, err
+	}
+	e, err := expr.Compile(condition)
+	if err != nil {
+		return condition, false, err
+	}
+	matched, err := e.Eval(expr.Env{Contact: input.ContactData, Input: input.Input, QueryParams: input.QueryParams})
+	return condition, matched, err
 }
 
 func (h *HookIt) extractJSONPath(data map[string]interface{}, path string) interface{} {
@@ -3,9 +3,9 @@ package integration
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/templating"
 )
 
 // NewMailIt creates a new MailIt helper instance
@@ -82,6 +82,11 @@ func (h *MailIt) ValidateConfig(config map[string]interface{}) error {
 	if _, ok := config["from_email"].(string); !ok || config["from_email"] == "" {
 		return fmt.Errorf("from_email is required")
 	}
+	for _, key := range []string{"subject_template", "body_template"} {
+		if err := templating.Validate(config[key].(string)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
 	return nil
 }
 
@@ -117,41 +122,35 @@ func (h *MailIt) Execute(ctx context.Context, input helpers.HelperInput) (*helpe
 		return output, err
 	}
 
-	// Build field data map for merge field replacement
-	fieldData := map[string]string{
-		"Id":        contact.ID,
-		"FirstName": contact.FirstName,
-		"LastName":  contact.LastName,
-		"Email":     contact.Email,
-		"Phone1":    contact.Phone,
-		"Company":   contact.Company,
-		"JobTitle":  contact.JobTitle,
-		"full_name": strings.TrimSpace(contact.FirstName + " " + contact.LastName),
-	}
-
-	// Add custom fields
-	if contact.CustomFields != nil {
-		for key, value := range contact.CustomFields {
-			fieldData[key] = fmt.Sprintf("%v", value)
-		}
+	data := &templating.Data{
+		Contact:     contact,
+		Input:       input.Input,
+		QueryParams: input.QueryParams,
+		Connector:   input.Connector,
+		ContactID:   input.ContactID,
 	}
 
 	// Resolve the recipient email from the contact field
-	toEmail := ""
-	if val, exists := fieldData[toField]; exists && val != "" {
-		toEmail = val
-	}
+	toEmail := data.LookupString(ctx, toField)
 	if toEmail == "" {
 		output.Message = fmt.Sprintf("Recipient email field '%s' is empty for contact %s", toField, input.ContactID)
 		return output, fmt.Errorf("recipient email field '%s' is empty", toField)
 	}
 
-	// Replace {{field}} syntax in subject and body
-	subject := subjectTemplate
-	body := bodyTemplate
-	for key, value := range fieldData {
-		subject = strings.ReplaceAll(subject, "{{"+key+"}}", value)
-		body = strings.ReplaceAll(body, "{{"+key+"}}", value)
+	// Render the subject as text and the body for its content type
+	subject, err := templating.Render(ctx, subjectTemplate, templating.Text, data)
+	if err != nil {
+		output.Message = fmt.Sprintf("Failed to render subject: %v", err)
+		return output, err
+	}
+	bodyMode := templating.Text
+	if contentType == "text/html" {
+		bodyMode = templating.HTML
+	}
+	body, err := templating.Render(ctx, bodyTemplate, bodyMode, data)
+	if err != nil {
+		output.Message = fmt.Sprintf("Failed to render body: %v", err)
+		return output, err
 	}
 
 	// Build the email payload
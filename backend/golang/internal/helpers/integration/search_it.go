@@ -120,7 +120,11 @@ func (h *SearchIt) Execute(ctx context.Context, input helpers.HelperInput) (*hel
 		output.Logs = append(output.Logs, fmt.Sprintf("Filtering by tag: %s", tagID))
 	}
 
-	// Get contacts (we'll filter client-side for now; future: add search to connector interface)
+	// An exact email search can be answered by the CRM; other modes match client-side
+	if matchMode == "exact" && len(searchFields) == 1 && searchFields[0] == "email" {
+		queryOpts.Filters = map[string]string{connectors.FilterEmail: searchTerm}
+	}
+
 	results, err := input.Connector.GetContacts(ctx, queryOpts)
 	if err != nil {
 		output.Message = fmt.Sprintf("Search failed: %v", err)
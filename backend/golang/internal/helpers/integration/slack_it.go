@@ -3,11 +3,15 @@ package integration
 import (
 	"context"
 	"fmt"
-	"strings"
+	"regexp"
 
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/templating"
 )
 
+// atField matches the legacy @field merge syntax
+var atField = regexp.MustCompile(`@[A-Za-z_][A-Za-z0-9_]*`)
+
 // NewSlackIt creates a new SlackIt helper instance
 func NewSlackIt() helpers.Helper { return &SlackIt{} }
 
@@ -68,6 +72,9 @@ func (h *SlackIt) ValidateConfig(config map[string]interface{}) error {
 	if _, ok := config["username"].(string); !ok || config["username"] == "" {
 		return fmt.Errorf("username is required")
 	}
+	if err := templating.Validate(config["message"].(string)); err != nil {
+		return fmt.Errorf("message: %w", err)
+	}
 	return nil
 }
 
@@ -97,33 +104,26 @@ func (h *SlackIt) Execute(ctx context.Context, input helpers.HelperInput) (*help
 		return output, err
 	}
 
-	// Build field data map for merge field replacement
-	fieldData := map[string]string{
-		"Id":        contact.ID,
-		"FirstName": contact.FirstName,
-		"LastName":  contact.LastName,
-		"Email":     contact.Email,
-		"Phone1":    contact.Phone,
-		"Company":   contact.Company,
-		"JobTitle":  contact.JobTitle,
-		"full_name": strings.TrimSpace(contact.FirstName + " " + contact.LastName),
+	data := &templating.Data{
+		Contact:     contact,
+		Input:       input.Input,
+		QueryParams: input.QueryParams,
+		Connector:   input.Connector,
+		ContactID:   input.ContactID,
 	}
 
-	// Add custom fields
-	if contact.CustomFields != nil {
-		for key, value := range contact.CustomFields {
-			fieldData[key] = fmt.Sprintf("%v", value)
+	// Turn @field merge fields the contact has into {{field}}
+	message = atField.ReplaceAllStringFunc(message, func(match string) string {
+		if data.Has(match[1:]) {
+			return "{{" + match[1:] + "}}"
 		}
-	}
+		return match
+	})
 
-	// Replace {{field}} syntax
-	for key, value := range fieldData {
-		message = strings.ReplaceAll(message, "{{"+key+"}}", value)
-	}
-
-	// Replace @field syntax
-	for key, value := range fieldData {
-		message = strings.ReplaceAll(message, "@"+key, value)
+	message, err = templating.Render(ctx, message, templating.Text, data)
+	if err != nil {
+		output.Message = fmt.Sprintf("Failed to render message: %v", err)
+		return output, err
 	}
 
 	// Build the Slack payload
@@ -7,11 +7,17 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/templating"
 )
 
+// trelloPlaceholder matches the single-brace placeholders older card
+// templates use, e.g. {first_name}
+var trelloPlaceholder = regexp.MustCompile(`\{+(first_name|last_name|email|phone|company)\}+`)
+
 // NewTrelloIt creates a new TrelloIt helper instance
 func NewTrelloIt() helpers.Helper { return &TrelloIt{} }
 
@@ -47,7 +53,7 @@ func (h *TrelloIt) GetConfigSchema() map[string]interface{} {
 			},
 			"card_name_template": map[string]interface{}{
 				"type":        "string",
-				"description": "Template for the card name. Supports {first_name}, {last_name}, {email}, {phone}, {company} placeholders",
+				"description": "Template for the card name. Supports {{field_name}} merge fields; {first_name}, {last_name}, {email}, {phone} and {company} still work",
 			},
 			"card_description_template": map[string]interface{}{
 				"type":        "string",
@@ -82,6 +88,13 @@ func (h *TrelloIt) ValidateConfig(config map[string]interface{}) error {
 	if _, ok := config["card_name_template"].(string); !ok || config["card_name_template"] == "" {
 		return fmt.Errorf("card_name_template is required")
 	}
+	for _, key := range []string{"card_name_template", "card_description_template"} {
+		if tmpl, ok := config[key].(string); ok {
+			if err := templating.Validate(h.upgradePlaceholders(tmpl)); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -119,16 +132,23 @@ func (h *TrelloIt) Execute(ctx context.Context, input helpers.HelperInput) (*hel
 	}
 
 	// 3. Interpolate templates with contact data
-	replacer := strings.NewReplacer(
-		"{first_name}", contact.FirstName,
-		"{last_name}", contact.LastName,
-		"{email}", contact.Email,
-		"{phone}", contact.Phone,
-		"{company}", contact.Company,
-	)
-
-	cardName := replacer.Replace(cardNameTemplate)
-	cardDesc := replacer.Replace(cardDescTemplate)
+	data := &templating.Data{
+		Contact:     contact,
+		Input:       input.Input,
+		QueryParams: input.QueryParams,
+		Connector:   input.Connector,
+		ContactID:   input.ContactID,
+	}
+	cardName, err := templating.Render(ctx, h.upgradePlaceholders(cardNameTemplate), templating.Text, data)
+	if err != nil {
+		output.Message = fmt.Sprintf("Failed to render card name: %v", err)
+		return output, err
+	}
+	cardDesc, err := templating.Render(ctx, h.upgradePlaceholders(cardDescTemplate), templating.Text, data)
+	if err != nil {
+		output.Message = fmt.Sprintf("Failed to render card description: %v", err)
+		return output, err
+	}
 
 	output.Logs = append(output.Logs, fmt.Sprintf("Creating Trello card '%s' on board %s, list %s", cardName, boardID, listID))
 
@@ -218,3 +238,13 @@ func (h *TrelloIt) Execute(ctx context.Context, input helpers.HelperInput) (*hel
 
 	return output, nil
 }
+
+// upgradePlaceholders rewrites single-brace placeholders to {{field}} merge fields
+func (h *TrelloIt) upgradePlaceholders(tmpl string) string {
+	return trelloPlaceholder.ReplaceAllStringFunc(tmpl, func(match string) string {
+		if strings.HasPrefix(match, "{{") {
+			return match
+		}
+		return "{{" + strings.Trim(match, "{}") + "}}"
+	})
+}
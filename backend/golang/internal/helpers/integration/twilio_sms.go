@@ -3,9 +3,9 @@ package integration
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/templating"
 )
 
 // NewTwilioSMS creates a new TwilioSMS helper instance
@@ -72,6 +72,9 @@ func (h *TwilioSMS) ValidateConfig(config map[string]interface{}) error {
 	if _, ok := config["message_template"].(string); !ok || config["message_template"] == "" {
 		return fmt.Errorf("message_template is required")
 	}
+	if err := templating.Validate(config["message_template"].(string)); err != nil {
+		return fmt.Errorf("message_template: %w", err)
+	}
 	return nil
 }
 
@@ -97,39 +100,25 @@ func (h *TwilioSMS) Execute(ctx context.Context, input helpers.HelperInput) (*he
 		return output, err
 	}
 
-	// Build field data map for merge field replacement
-	fieldData := map[string]string{
-		"Id":        contact.ID,
-		"FirstName": contact.FirstName,
-		"LastName":  contact.LastName,
-		"Email":     contact.Email,
-		"Phone1":    contact.Phone,
-		"Company":   contact.Company,
-		"JobTitle":  contact.JobTitle,
-		"full_name": strings.TrimSpace(contact.FirstName + " " + contact.LastName),
-	}
-
-	// Add custom fields
-	if contact.CustomFields != nil {
-		for key, value := range contact.CustomFields {
-			fieldData[key] = fmt.Sprintf("%v", value)
-		}
+	data := &templating.Data{
+		Contact:     contact,
+		Input:       input.Input,
+		QueryParams: input.QueryParams,
+		Connector:   input.Connector,
+		ContactID:   input.ContactID,
 	}
 
 	// Resolve the recipient phone number from the contact field
-	toNumber := ""
-	if val, exists := fieldData[toField]; exists && val != "" {
-		toNumber = val
-	}
+	toNumber := data.LookupString(ctx, toField)
 	if toNumber == "" {
 		output.Message = fmt.Sprintf("Phone field '%s' is empty for contact %s", toField, input.ContactID)
 		return output, fmt.Errorf("phone field '%s' is empty", toField)
 	}
 
-	// Replace {{field}} syntax in message template
-	message := messageTemplate
-	for key, value := range fieldData {
-		message = strings.ReplaceAll(message, "{{"+key+"}}", value)
+	message, err := templating.Render(ctx, messageTemplate, templating.Text, data)
+	if err != nil {
+		output.Message = fmt.Sprintf("Failed to render message: %v", err)
+		return output, err
 	}
 
 	// Build the Twilio API request
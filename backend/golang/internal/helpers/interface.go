@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/database"
 )
 
 // Helper defines the interface for all automation helpers.
@@ -40,6 +41,7 @@ type HelperInput struct {
 	AccountID    string                                  `json:"account_id"`
 	HelperID     string                                  `json:"helper_id"`
 	APIKey       string                                  `json:"-"` // Original x-api-key header for relay helpers
+	Stores       *database.Stores                         `json:"-"` // Record stores for helpers that keep state (limit_it, split_it)
 }
 
 // HelperOutput represents the result of a helper execution
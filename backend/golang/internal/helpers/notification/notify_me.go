@@ -3,6 +3,7 @@ package notification
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/myfusionhelper/api/internal/helpers"
@@ -46,7 +47,7 @@ func (h *NotifyMe) GetConfigSchema() map[string]interface{} {
 			},
 			"recipient": map[string]interface{}{
 				"type":        "string",
-				"description": "Recipient email, Slack channel, or webhook URL",
+				"description": "Recipient email address, Slack incoming webhook URL (https://hooks.slack.com/...), or webhook URL",
 			},
 			"include_fields": map[string]interface{}{
 				"type":        "array",
@@ -73,6 +74,15 @@ func (h *NotifyMe) ValidateConfig(config map[string]interface{}) error {
 		return fmt.Errorf("message is required")
 	}
 
+	// Slack messages are posted to an incoming webhook; channel names
+	// cannot be resolved without a Slack connection
+	if channel == "slack" {
+		recipient, _ := config["recipient"].(string)
+		if u, err := url.Parse(recipient); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("slack recipient must be an https incoming webhook URL")
+		}
+	}
+
 	return nil
 }
 
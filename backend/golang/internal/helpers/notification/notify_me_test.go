@@ -91,16 +91,32 @@ func TestNotifyMe_ValidateConfig_InvalidChannel(t *testing.T) {
 }
 
 func TestNotifyMe_ValidateConfig_Valid(t *testing.T) {
-	channels := []string{"email", "slack", "webhook"}
-	for _, ch := range channels {
+	recipients := map[string]string{
+		"email":   "admin@example.com",
+		"slack":   "https://hooks.slack.com/services/T000/B000/XXXX",
+		"webhook": "https://example.com/hook",
+	}
+	for ch, recipient := range recipients {
 		err := (&NotifyMe{}).ValidateConfig(map[string]interface{}{
-			"channel": ch,
-			"message": "test",
+			"channel":   ch,
+			"message":   "test",
+			"recipient": recipient,
 		})
 		if err != nil { t.Errorf("should accept %s: %v", ch, err) }
 	}
 }
 
+func TestNotifyMe_ValidateConfig_SlackRecipient(t *testing.T) {
+	for _, recipient := range []string{"", "#sales", "http://hooks.slack.com/services/T000/B000/XXXX"} {
+		err := (&NotifyMe{}).ValidateConfig(map[string]interface{}{
+			"channel":   "slack",
+			"message":   "test",
+			"recipient": recipient,
+		})
+		if err == nil { t.Errorf("should reject slack recipient %q", recipient) }
+	}
+}
+
 func TestNotifyMe_Execute_EmailNotification(t *testing.T) {
 	mock := &mockConnectorForNotifyMe{}
 
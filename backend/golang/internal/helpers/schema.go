@@ -0,0 +1,361 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldError is a config validation failure at a path such as "helpers[2].id"
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return "config: " + e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors collects every schema violation found in a config
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateHelperConfig checks config against the helper's declared
+// GetConfigSchema, then runs the helper's own ValidateConfig for the rules a
+// schema cannot express.
+func ValidateHelperConfig(helper Helper, config map[string]interface{}) error {
+	if err := ValidateSchema(helper.GetConfigSchema(), config); err != nil {
+		return err
+	}
+	return helper.ValidateConfig(config)
+}
+
+// ValidateSchema checks a config against a JSON-Schema-style definition as
+// returned by GetConfigSchema. It supports type, enum, required, properties,
+// additionalProperties, items, oneOf, anyOf and the numeric, length and item
+// count bounds; other keywords (format, description, default) are ignored.
+// Null and empty-string property values are treated as unset, matching how the
+// helper forms save optional fields. Returns nil or ValidationErrors.
+func ValidateSchema(schema, config map[string]interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	var value interface{} = config
+	if config == nil {
+		value = map[string]interface{}{}
+	}
+	errs := validateValue(schema, value, "")
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateValue checks one value against a schema node
+func validateValue(schema map[string]interface{}, value interface{}, path string) ValidationErrors {
+	if t, ok := schema["type"].(string); ok && !matchesType(t, value) {
+		return ValidationErrors{{Path: path, Message: fmt.Sprintf("must be %s %s, got %s", article(t), t, typeName(value))}}
+	}
+
+	var errs ValidationErrors
+
+	if enum := toSlice(schema["enum"]); enum != nil {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allowed := make([]string, len(enum))
+			for i, a := range enum {
+				allowed[i] = fmt.Sprint(a)
+			}
+			errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("must be one of [%s], got %v", strings.Join(allowed, ", "), value)})
+		}
+	}
+
+	if n, ok := toFloat(value); ok {
+		if min, ok := toFloat(schema["minimum"]); ok && n < min {
+			errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("must be at least %v", schema["minimum"])})
+		}
+		if max, ok := toFloat(schema["maximum"]); ok && n > max {
+			errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("must be at most %v", schema["maximum"])})
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		if min, ok := toFloat(schema["minLength"]); ok && float64(len(s)) < min {
+			errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("must be at least %v characters", schema["minLength"])})
+		}
+		if max, ok := toFloat(schema["maxLength"]); ok && float64(len(s)) > max {
+			errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("must be at most %v characters", schema["maxLength"])})
+		}
+	}
+
+	if isSlice(value) {
+		items := toSlice(value)
+		if min, ok := toFloat(schema["minItems"]); ok && float64(len(items)) < min {
+			errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("must have at least %v item(s)", schema["minItems"])})
+		}
+		if max, ok := toFloat(schema["maxItems"]); ok && float64(len(items)) > max {
+			errs = append(errs, FieldError{Path: path, Message: fmt.Sprintf("must have at most %v item(s)", schema["maxItems"])})
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				errs = append(errs, validateValue(itemSchema, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	if obj := toMap(value); obj != nil {
+		errs = append(errs, validateObject(schema, obj, path)...)
+	}
+
+	if branches := toSlice(schema["oneOf"]); branches != nil {
+		errs = append(errs, validateBranches(branches, value, path, true)...)
+	}
+	if branches := toSlice(schema["anyOf"]); branches != nil {
+		errs = append(errs, validateBranches(branches, value, path, false)...)
+	}
+
+	return errs
+}
+
+// validateObject checks required keys, declared properties and
+// additionalProperties
+func validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) ValidationErrors {
+	var errs ValidationErrors
+
+	for _, key := range toSlice(schema["required"]) {
+		name := fmt.Sprint(key)
+		if isUnset(obj[name]) {
+			errs = append(errs, FieldError{Path: joinPath(path, name), Message: "required"})
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := obj[key]
+		if isUnset(value) {
+			continue
+		}
+		if propSchema, ok := properties[key].(map[string]interface{}); ok {
+			errs = append(errs, validateValue(propSchema, value, joinPath(path, key))...)
+			continue
+		}
+		if _, declared := properties[key]; declared {
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				errs = append(errs, FieldError{Path: joinPath(path, key), Message: "unknown property"})
+			}
+		case map[string]interface{}:
+			errs = append(errs, validateValue(extra, value, joinPath(path, key))...)
+		}
+	}
+
+	return errs
+}
+
+// validateBranches checks oneOf (exactly one branch) or anyOf (at least one).
+// When no branch matches and only one branch accepts the value's type, that
+// branch's errors are reported, since they point at the actual problem.
+func validateBranches(branches []interface{}, value interface{}, path string, exactlyOne bool) ValidationErrors {
+	matched := 0
+	var typed []ValidationErrors
+	for _, b := range branches {
+		branch, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		branchErrs := validateValue(branch, value, path)
+		if len(branchErrs) == 0 {
+			matched++
+			continue
+		}
+		if t, ok := branch["type"].(string); !ok || matchesType(t, value) {
+			typed = append(typed, branchErrs)
+		}
+	}
+
+	switch {
+	case exactlyOne && matched == 1, !exactlyOne && matched > 0:
+		return nil
+	case matched > 1:
+		return ValidationErrors{{Path: path, Message: "must match exactly one allowed schema, matched several"}}
+	case len(typed) == 1:
+		return typed[0]
+	}
+
+	message := "must match at least one allowed schema"
+	if exactlyOne {
+		message = "must match exactly one allowed schema"
+	}
+	if len(typed) > 0 {
+		reasons := make([]string, len(typed))
+		for i, branchErrs := range typed {
+			reasons[i] = branchErrs.Error()
+		}
+		message += " (" + strings.Join(reasons, " or ") + ")"
+	}
+	return ValidationErrors{{Path: path, Message: message}}
+}
+
+// matchesType reports whether value has the given JSON type. Unknown type
+// names are not enforced.
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		n, ok := toFloat(value)
+		return ok && n == math.Trunc(n)
+	case "array":
+		return isSlice(value)
+	case "object":
+		return toMap(value) != nil
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func typeName(value interface{}) string {
+	switch {
+	case value == nil:
+		return "null"
+	case isSlice(value):
+		return "array"
+	case toMap(value) != nil:
+		return "object"
+	}
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func article(t string) string {
+	if strings.ContainsAny(t[:1], "aeiou") {
+		return "an"
+	}
+	return "a"
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func isUnset(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && s == ""
+}
+
+// toFloat converts any Go numeric value (including json.Number) to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := strconv.ParseFloat(string(n), 64)
+		return f, err == nil
+	case nil, bool, string:
+		return 0, false
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func isSlice(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	return reflect.TypeOf(value).Kind() == reflect.Slice
+}
+
+// toSlice returns the elements of any slice type, such as the []string used
+// for "required" and "enum" in schemas
+func toSlice(value interface{}) []interface{} {
+	if s, ok := value.([]interface{}); ok {
+		return s
+	}
+	if !isSlice(value) {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+// toMap returns value as a string-keyed map, converting typed maps such as
+// map[string]string
+func toMap(value interface{}) map[string]interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m
+	}
+	if value == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+	out := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		out[iter.Key().String()] = iter.Value().Interface()
+	}
+	return out
+}
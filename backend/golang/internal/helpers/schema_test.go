@@ -0,0 +1,167 @@
+package helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"name":    map[string]interface{}{"type": "string", "minLength": 2},
+		"mode":    map[string]interface{}{"type": "string", "enum": []string{"append", "replace"}},
+		"retries": map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 5},
+		"enabled": map[string]interface{}{"type": "boolean"},
+		"mapping": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		},
+		"items": map[string]interface{}{
+			"type":     "array",
+			"minItems": 1,
+			"items": map[string]interface{}{
+				"oneOf": []interface{}{
+					map[string]interface{}{"type": "string"},
+					map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"id": map[string]interface{}{"type": "string"},
+						},
+						"required": []string{"id"},
+					},
+				},
+			},
+		},
+	},
+	"required": []string{"name", "items"},
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "valid config",
+			config: map[string]interface{}{
+				"name":    "chain",
+				"mode":    "append",
+				"retries": float64(3),
+				"enabled": true,
+				"mapping": map[string]interface{}{"a": "b"},
+				"items":   []interface{}{"h1", map[string]interface{}{"id": "h2"}},
+			},
+		},
+		{
+			name:    "null and empty optional values are unset",
+			config:  map[string]interface{}{"name": "chain", "items": []interface{}{"h1"}, "mode": "", "retries": nil},
+			wantErr: "",
+		},
+		{
+			name:    "missing required",
+			config:  map[string]interface{}{"items": []interface{}{"h1"}},
+			wantErr: "name: required",
+		},
+		{
+			name:    "empty required string",
+			config:  map[string]interface{}{"name": "", "items": []interface{}{"h1"}},
+			wantErr: "name: required",
+		},
+		{
+			name:    "wrong type",
+			config:  map[string]interface{}{"name": float64(5), "items": []interface{}{"h1"}},
+			wantErr: "name: must be a string, got number",
+		},
+		{
+			name:    "enum",
+			config:  map[string]interface{}{"name": "chain", "items": []interface{}{"h1"}, "mode": "merge"},
+			wantErr: "mode: must be one of [append, replace], got merge",
+		},
+		{
+			name:    "integer",
+			config:  map[string]interface{}{"name": "chain", "items": []interface{}{"h1"}, "retries": 1.5},
+			wantErr: "retries: must be an integer, got number",
+		},
+		{
+			name:    "maximum",
+			config:  map[string]interface{}{"name": "chain", "items": []interface{}{"h1"}, "retries": 9},
+			wantErr: "retries: must be at most 5",
+		},
+		{
+			name:    "minLength",
+			config:  map[string]interface{}{"name": "c", "items": []interface{}{"h1"}},
+			wantErr: "name: must be at least 2 characters",
+		},
+		{
+			name:    "minItems",
+			config:  map[string]interface{}{"name": "chain", "items": []interface{}{}},
+			wantErr: "items: must have at least 1 item(s)",
+		},
+		{
+			name:    "nested oneOf reports the matching branch",
+			config:  map[string]interface{}{"name": "chain", "items": []interface{}{"h1", "h2", map[string]interface{}{"config": "x"}}},
+			wantErr: "items[2].id: required",
+		},
+		{
+			name:    "oneOf with no candidate branch",
+			config:  map[string]interface{}{"name": "chain", "items": []interface{}{true}},
+			wantErr: "items[0]: must match exactly one allowed schema",
+		},
+		{
+			name:    "additionalProperties",
+			config:  map[string]interface{}{"name": "chain", "items": []interface{}{"h1"}, "mapping": map[string]interface{}{"a": 1}},
+			wantErr: "mapping.a: must be a string, got number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchema(testSchema, tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateSchema_CollectsAllErrors(t *testing.T) {
+	err := ValidateSchema(testSchema, map[string]interface{}{"mode": "merge", "enabled": "yes"})
+
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Expected ValidationErrors, got %T", err)
+	}
+	paths := make([]string, len(verrs))
+	for i, e := range verrs {
+		paths[i] = e.Path
+	}
+	if got := strings.Join(paths, ","); got != "name,items,enabled,mode" {
+		t.Errorf("Expected paths name,items,enabled,mode, got %s", got)
+	}
+}
+
+func TestValidateSchema_AnyOf(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"anyOf": []interface{}{
+			map[string]interface{}{"required": []string{"helpers"}},
+			map[string]interface{}{"required": []string{"steps"}},
+		},
+	}
+
+	if err := ValidateSchema(schema, map[string]interface{}{"steps": []interface{}{}}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	err := ValidateSchema(schema, nil)
+	if err == nil || !strings.Contains(err.Error(), "helpers: required or steps: required") {
+		t.Errorf("Expected anyOf error listing both branches, got %v", err)
+	}
+}
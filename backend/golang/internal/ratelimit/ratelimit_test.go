@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+func TestCheckMonthlyLimit(t *testing.T) {
+	ctx := context.Background()
+	accounts := memory.NewAccounts()
+	accounts.Create(ctx, &types.Account{AccountID: "acc-1", Usage: types.AccountUsage{MonthlyExecutions: 8}})
+	limiter := New(accounts, memory.NewCounters())
+
+	for _, want := range []int{9, 10} {
+		result, err := limiter.CheckMonthlyLimit(ctx, "acc-1", 10)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !result.Allowed || result.Used != want {
+			t.Errorf("Expected allowed with %d used, got %+v", want, result)
+		}
+	}
+
+	result, err := limiter.CheckMonthlyLimit(ctx, "acc-1", 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Allowed || result.Used != 10 || result.ResetAt == "" {
+		t.Errorf("Expected denied with 10 used and a reset time, got %+v", result)
+	}
+
+	account, _ := accounts.GetByID(ctx, "acc-1")
+	if account.Usage.MonthlyExecutions != 10 {
+		t.Errorf("Expected a denied check to be rolled back to 10, got %d", account.Usage.MonthlyExecutions)
+	}
+
+	t.Run("no limit", func(t *testing.T) {
+		result, _ := limiter.CheckMonthlyLimit(ctx, "acc-1", 0)
+		if !result.Allowed {
+			t.Errorf("Expected an unlimited plan to be allowed, got %+v", result)
+		}
+	})
+}
+
+func TestCheckBurstLimit(t *testing.T) {
+	ctx := context.Background()
+	limiter := New(memory.NewAccounts(), memory.NewCounters())
+
+	for i := 1; i <= 3; i++ {
+		result, err := limiter.CheckBurstLimit(ctx, "helper:1", 3)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !result.Allowed || result.Used != i {
+			t.Errorf("Expected call %d to be allowed, got %+v", i, result)
+		}
+	}
+
+	result, _ := limiter.CheckBurstLimit(ctx, "helper:1", 3)
+	if result.Allowed || result.Used != 4 {
+		t.Errorf("Expected the fourth call to be denied, got %+v", result)
+	}
+
+	result, _ = limiter.CheckBurstLimit(ctx, "helper:2", 3)
+	if !result.Allowed {
+		t.Errorf("Expected other helpers to have their own budget, got %+v", result)
+	}
+}
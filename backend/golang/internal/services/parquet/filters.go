@@ -0,0 +1,73 @@
+package parquet
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// BuildWhereClause constructs a parameterised DuckDB WHERE clause from data
+// explorer filters and a search term matched against every string column.
+func BuildWhereClause(filters []types.DataFilter, search string, stringColumns []string) (string, []interface{}) {
+	var clauses []string
+	var params []interface{}
+
+	for _, f := range filters {
+		if f.Column == "" {
+			continue
+		}
+		col := fmt.Sprintf("\"%s\"", f.Column)
+
+		switch f.Operator {
+		case "eq":
+			clauses = append(clauses, col+" = ?")
+			params = append(params, f.Value)
+		case "neq":
+			clauses = append(clauses, col+" <> ?")
+			params = append(params, f.Value)
+		case "contains":
+			clauses = append(clauses, col+" ILIKE '%' || ? || '%'")
+			params = append(params, f.Value)
+		case "startswith":
+			clauses = append(clauses, col+" ILIKE ? || '%'")
+			params = append(params, f.Value)
+		case "gt":
+			clauses = append(clauses, col+" > ?")
+			params = append(params, f.Value)
+		case "gte":
+			clauses = append(clauses, col+" >= ?")
+			params = append(params, f.Value)
+		case "lt":
+			clauses = append(clauses, col+" < ?")
+			params = append(params, f.Value)
+		case "lte":
+			clauses = append(clauses, col+" <= ?")
+			params = append(params, f.Value)
+		case "between":
+			clauses = append(clauses, col+" BETWEEN ? AND ?")
+			params = append(params, f.Value, f.Value2)
+		case "in":
+			if values, ok := f.Value.([]interface{}); ok && len(values) > 0 {
+				placeholders := make([]string, len(values))
+				for i, v := range values {
+					placeholders[i] = "?"
+					params = append(params, v)
+				}
+				clauses = append(clauses, col+" IN ("+strings.Join(placeholders, ", ")+")")
+			}
+		}
+	}
+
+	// Global search across all string columns
+	if search != "" && len(stringColumns) > 0 {
+		var searchParts []string
+		for _, sc := range stringColumns {
+			searchParts = append(searchParts, fmt.Sprintf("\"%s\" ILIKE '%%' || ? || '%%'", sc))
+			params = append(params, search)
+		}
+		clauses = append(clauses, "("+strings.Join(searchParts, " OR ")+")")
+	}
+
+	return strings.Join(clauses, " AND "), params
+}
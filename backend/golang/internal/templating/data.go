@@ -0,0 +1,141 @@
+package templating
+
+import (
+	"context"
+	"strings"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/expr"
+)
+
+// legacyAliases are the Keap-style merge field names older helper configs
+// use, mapped to the standard field they read
+var legacyAliases = map[string]string{
+	"Id":        "id",
+	"FirstName": "first_name",
+	"LastName":  "last_name",
+	"Email":     "email",
+	"Phone1":    "phone",
+	"Company":   "company",
+	"JobTitle":  "job_title",
+}
+
+// Data is what templates render against. Fields that the contact record does
+// not carry are read through Connector, so a translating connector resolves
+// CRM-specific keys, custom field labels and related-record fields. Lookups
+// through the connector are cached per Data.
+type Data struct {
+	Contact     *connectors.NormalizedContact
+	Input       map[string]interface{}
+	QueryParams map[string]string
+
+	// Connector and ContactID are optional; without them unknown fields are empty
+	Connector connectors.CRMConnector
+	ContactID string
+
+	fetched map[string]interface{}
+}
+
+// Has reports whether name is a field the contact record carries, without
+// asking the connector
+func (d *Data) Has(name string) bool {
+	_, ok := d.local(name)
+	return ok
+}
+
+// Lookup resolves a merge field name:
+//
+//   - input.<key>[.<key>...] and query.<key> read the execution input
+//   - custom.<key> reads a custom field
+//   - tags lists the contact's tags, each with id and name
+//   - full_name, contact_id and the legacy Keap names (FirstName, Phone1, ...)
+//   - standard contact fields (first_name, email, ...) and custom field keys
+//   - anything else is read through the connector
+func (d *Data) Lookup(ctx context.Context, name string) (interface{}, bool) {
+	if rest, ok := strings.CutPrefix(name, "input."); ok {
+		var current interface{} = d.Input
+		for _, key := range strings.Split(rest, ".") {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = m[key]; !ok {
+				return nil, false
+			}
+		}
+		return current, true
+	}
+	if key, ok := strings.CutPrefix(name, "query."); ok {
+		value, ok := d.QueryParams[key]
+		return value, ok
+	}
+	if key, ok := strings.CutPrefix(name, "custom."); ok {
+		name = key
+	}
+
+	if value, ok := d.local(name); ok {
+		return value, true
+	}
+	return d.fetch(ctx, name)
+}
+
+// LookupString resolves name like Lookup and formats the value as a template
+// would render it; missing fields are ""
+func (d *Data) LookupString(ctx context.Context, name string) string {
+	value, _ := d.Lookup(ctx, name)
+	return toString(value)
+}
+
+// local resolves name from the contact record alone
+func (d *Data) local(name string) (interface{}, bool) {
+	if d.Contact == nil {
+		return nil, false
+	}
+	c := d.Contact
+
+	switch name {
+	case "tags":
+		tags := make([]interface{}, len(c.Tags))
+		for i, tag := range c.Tags {
+			tags[i] = map[string]interface{}{"id": tag.ID, "name": tag.Name}
+		}
+		return tags, true
+	case "full_name":
+		return strings.TrimSpace(c.FirstName + " " + c.LastName), true
+	case "contact_id":
+		return c.ID, true
+	}
+	if standard, ok := legacyAliases[name]; ok {
+		name = standard
+	}
+
+	if value := expr.ContactField(c, name); value != nil {
+		return value, true
+	}
+	value, ok := c.CustomFields[name]
+	return value, ok
+}
+
+// fetch reads a field through the connector
+func (d *Data) fetch(ctx context.Context, name string) (interface{}, bool) {
+	if d.Connector == nil || d.ContactID == "" {
+		return nil, false
+	}
+	if value, ok := d.fetched[name]; ok {
+		return value, value != nil
+	}
+
+	value, err := d.Connector.GetContactFieldValue(ctx, d.ContactID, name)
+	if err != nil {
+		value = nil
+	}
+	if d.fetched == nil {
+		d.fetched = make(map[string]interface{})
+	}
+	d.fetched[name] = value
+	return value, value != nil
+}
+
+func (d *Data) env() expr.Env {
+	return expr.Env{Contact: d.Contact, Input: d.Input, QueryParams: d.QueryParams}
+}
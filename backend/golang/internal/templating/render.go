@@ -0,0 +1,299 @@
+package templating
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filterCall is one filter in a value's pipeline
+type filterCall struct {
+	name string
+	args []string
+	filter
+}
+
+// filter transforms a value. safeIn lists the modes whose escaping the
+// filter's output already satisfies.
+type filter struct {
+	arity  int
+	apply  func(value interface{}, args []string) interface{}
+	safeIn []Mode
+}
+
+var filters = map[string]filter{
+	"default": {arity: 1, apply: func(value interface{}, args []string) interface{} {
+		if toString(value) == "" {
+			return args[0]
+		}
+		return value
+	}},
+	"upper": {apply: stringFilter(strings.ToUpper)},
+	"lower": {apply: stringFilter(strings.ToLower)},
+	"trim":  {apply: stringFilter(strings.TrimSpace)},
+	"date": {arity: 1, apply: func(value interface{}, args []string) interface{} {
+		if t, ok := toTime(value); ok {
+			return t.Format(args[0])
+		}
+		return value
+	}},
+	"urlencode": {apply: func(value interface{}, args []string) interface{} {
+		return url.QueryEscape(toString(value))
+	}, safeIn: []Mode{URL}},
+	"json": {apply: func(value interface{}, args []string) interface{} {
+		return encodeJSON(value)
+	}, safeIn: []Mode{JSON}},
+	"raw": {apply: func(value interface{}, args []string) interface{} {
+		return value
+	}, safeIn: []Mode{Text, URL, JSON, HTML}},
+}
+
+func stringFilter(f func(string) string) func(value interface{}, args []string) interface{} {
+	return func(value interface{}, args []string) interface{} {
+		return f(toString(value))
+	}
+}
+
+// parseFilter parses `name "arg" ...`
+func parseFilter(s string) (filterCall, error) {
+	s = strings.TrimSpace(s)
+	name := s
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		name = s[:i]
+	}
+	f, ok := filters[name]
+	if !ok {
+		if name == "" {
+			return filterCall{}, fmt.Errorf("missing filter after |")
+		}
+		return filterCall{}, fmt.Errorf("unknown filter %q", name)
+	}
+
+	args, err := parseArgs(strings.TrimSpace(s[len(name):]))
+	if err != nil {
+		return filterCall{}, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(args) != f.arity {
+		return filterCall{}, fmt.Errorf("%s takes %d argument(s), got %d", name, f.arity, len(args))
+	}
+	return filterCall{name: name, args: args, filter: f}, nil
+}
+
+// parseArgs splits filter arguments: quoted strings or bare words
+func parseArgs(s string) ([]string, error) {
+	var args []string
+	for s != "" {
+		if quote := s[0]; quote == '"' || quote == '\'' {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				sb.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			args = append(args, sb.String())
+			s = strings.TrimSpace(s[i+1:])
+			continue
+		}
+		word := s
+		if i := strings.IndexAny(s, " \t"); i >= 0 {
+			word = s[:i]
+		}
+		args = append(args, word)
+		s = strings.TrimSpace(s[len(word):])
+	}
+	return args, nil
+}
+
+// scope is the current item of an {{#each}}
+type scope struct {
+	item  interface{}
+	index int
+}
+
+type renderer struct {
+	ctx    context.Context
+	data   *Data
+	mode   Mode
+	out    *strings.Builder
+	scopes []scope
+}
+
+func (r *renderer) render(nodes []node) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *textNode:
+			r.out.WriteString(n.text)
+
+		case *valueNode:
+			value, _ := r.lookup(n.name)
+			safe := false
+			for _, call := range n.filters {
+				value = call.apply(value, call.args)
+				safe = false
+				for _, mode := range call.safeIn {
+					safe = safe || mode == r.mode
+				}
+			}
+			s := toString(value)
+			if !safe {
+				s = r.escape(s, n.inQuery)
+			}
+			r.out.WriteString(s)
+
+		case *ifNode:
+			matched, err := n.cond.Eval(r.data.env())
+			if err != nil {
+				return fmt.Errorf("{{#if %s}}: %w", n.cond, err)
+			}
+			branch := n.orElse
+			if matched {
+				branch = n.then
+			}
+			if err := r.render(branch); err != nil {
+				return err
+			}
+
+		case *eachNode:
+			value, _ := r.lookup(n.name)
+			for i, item := range toList(value) {
+				r.scopes = append(r.scopes, scope{item: item, index: i})
+				err := r.render(n.body)
+				r.scopes = r.scopes[:len(r.scopes)-1]
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// lookup resolves a name against the innermost {{#each}} item, then data.
+// Inside a loop, this is the item, @index its position, and bare names are
+// read from the item when it has them.
+func (r *renderer) lookup(name string) (interface{}, bool) {
+	if len(r.scopes) > 0 {
+		s := r.scopes[len(r.scopes)-1]
+		switch {
+		case name == "this":
+			return s.item, true
+		case name == "@index":
+			return s.index, true
+		case strings.HasPrefix(name, "this."):
+			return dig(s.item, strings.Split(strings.TrimPrefix(name, "this."), "."))
+		}
+		if value, ok := dig(s.item, strings.Split(name, ".")); ok {
+			return value, true
+		}
+	}
+	if name == "this" || strings.HasPrefix(name, "@") {
+		return nil, false
+	}
+	return r.data.Lookup(r.ctx, name)
+}
+
+func (r *renderer) escape(s string, inQuery bool) string {
+	switch r.mode {
+	case URL:
+		if inQuery {
+			return url.QueryEscape(s)
+		}
+		return url.PathEscape(s)
+	case JSON:
+		encoded := encodeJSON(s)
+		return encoded[1 : len(encoded)-1]
+	case HTML:
+		return html.EscapeString(s)
+	}
+	return s
+}
+
+// dig follows keys through nested maps
+func dig(value interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func toList(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list
+	case nil:
+		return nil
+	}
+	return []interface{}{value}
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []interface{}, []string, map[string]interface{}:
+		return encodeJSON(v)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// encodeJSON encodes value without escaping <, > and &
+func encodeJSON(value interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// dateLayouts are the string formats the date filter parses
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
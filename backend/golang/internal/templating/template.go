@@ -0,0 +1,294 @@
+// Package templating renders merge-field templates for message-producing
+// helpers: notes, emails, SMS, chat messages, card titles and webhook URLs.
+//
+//	Hi {{first_name | default "there"}}, your plan renews {{custom.RenewalDate | date "Jan 2, 2006"}}.
+//	{{#if has_tag("VIP")}}Thanks for being a VIP!{{else}}Upgrade today.{{/if}}
+//	Tags: {{#each tags}}{{@index}}. {{name}} {{/each}}
+//	{{! comments are dropped }}
+//
+// Fields are resolved by Data.Lookup. Filters are default, upper, lower,
+// trim, date, urlencode, json and raw. #if takes a condition in the
+// internal/expr language. Missing fields render as the empty string.
+//
+// Output is escaped for the context the template is rendered into, chosen by
+// its Mode: query or path escaping in URLs, string escaping in JSON bodies,
+// entity escaping in HTML. The json filter's output is inserted into JSON
+// bodies as-is, and raw skips escaping altogether.
+package templating
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/myfusionhelper/api/internal/expr"
+)
+
+// Mode is the context a template's output is inserted into
+type Mode int
+
+const (
+	Text Mode = iota // no escaping
+	URL              // query values are query-escaped, anything before ? path-escaped
+	JSON             // values are escaped for use inside a JSON string
+	HTML             // values are HTML-escaped
+)
+
+// Error is a template syntax error
+type Error struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", e.Msg, e.Line, e.Column)
+}
+
+// errorAt builds an Error for byte offset pos of src
+func errorAt(src string, pos int, format string, args ...interface{}) *Error {
+	line := 1 + strings.Count(src[:pos], "\n")
+	column := pos - strings.LastIndex(src[:pos], "\n")
+	return &Error{Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Template is a parsed template, safe for concurrent use
+type Template struct {
+	mode  Mode
+	nodes []node
+}
+
+// Parse parses src for rendering in mode
+func Parse(src string, mode Mode) (*Template, error) {
+	p := &parser{src: src, mode: mode}
+	nodes, closer, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if closer != nil {
+		return nil, errorAt(src, closer.pos, "unexpected {{%s}}", closer.text)
+	}
+	return &Template{mode: mode, nodes: nodes}, nil
+}
+
+// Validate reports whether src is a valid template
+func Validate(src string) error {
+	_, err := Parse(src, Text)
+	return err
+}
+
+// Render parses src and renders it against data
+func Render(ctx context.Context, src string, mode Mode, data *Data) (string, error) {
+	t, err := Parse(src, mode)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(ctx, data)
+}
+
+// Render renders the template against data
+func (t *Template) Render(ctx context.Context, data *Data) (string, error) {
+	if data == nil {
+		data = &Data{}
+	}
+	var sb strings.Builder
+	r := &renderer{ctx: ctx, data: data, mode: t.mode, out: &sb}
+	if err := r.render(t.nodes); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+type node interface{}
+
+type textNode struct {
+	text string
+}
+
+type valueNode struct {
+	name    string
+	filters []filterCall
+	inQuery bool // URL mode: the value sits after the ?
+}
+
+type ifNode struct {
+	cond   *expr.Expr
+	then   []node
+	orElse []node
+}
+
+type eachNode struct {
+	name string
+	body []node
+}
+
+// tag is one {{...}} in the source
+type tag struct {
+	text string // trimmed content
+	pos  int    // offset of the opening braces
+}
+
+type parser struct {
+	src     string
+	mode    Mode
+	pos     int
+	inQuery bool
+}
+
+// parseNodes parses until the end of the source or a closing tag ({{else}},
+// {{/if}}, {{/each}}), which it returns for the enclosing block to handle
+func (p *parser) parseNodes() ([]node, *tag, error) {
+	var nodes []node
+	for p.pos < len(p.src) {
+		start := strings.Index(p.src[p.pos:], "{{")
+		if start < 0 {
+			nodes = append(nodes, p.text(p.src[p.pos:]))
+			p.pos = len(p.src)
+			break
+		}
+		if start > 0 {
+			nodes = append(nodes, p.text(p.src[p.pos:p.pos+start]))
+		}
+		open := p.pos + start
+		end := strings.Index(p.src[open+2:], "}}")
+		if end < 0 {
+			return nil, nil, errorAt(p.src, open, "unclosed {{")
+		}
+		t := &tag{text: strings.TrimSpace(p.src[open+2 : open+2+end]), pos: open}
+		p.pos = open + 2 + end + 2
+
+		switch {
+		case t.text == "":
+			return nil, nil, errorAt(p.src, t.pos, "empty {{}}")
+		case strings.HasPrefix(t.text, "!"):
+			// comment
+		case t.text == "else" || strings.HasPrefix(t.text, "/"):
+			return nodes, t, nil
+		case strings.HasPrefix(t.text, "#if ") || t.text == "#if":
+			n, err := p.parseIf(t)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+		case strings.HasPrefix(t.text, "#each ") || t.text == "#each":
+			n, err := p.parseEach(t)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+		case strings.HasPrefix(t.text, "#"):
+			return nil, nil, errorAt(p.src, t.pos, "unknown block {{%s}}", t.text)
+		default:
+			n, err := p.parseValue(t)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil, nil
+}
+
+func (p *parser) text(s string) node {
+	if p.mode == URL && strings.Contains(s, "?") {
+		p.inQuery = true
+	}
+	return &textNode{text: s}
+}
+
+func (p *parser) parseIf(t *tag) (node, error) {
+	src := strings.TrimSpace(strings.TrimPrefix(t.text, "#if"))
+	if src == "" {
+		return nil, errorAt(p.src, t.pos, "{{#if}} needs a condition")
+	}
+	cond, err := expr.Compile(src)
+	if err != nil {
+		return nil, errorAt(p.src, t.pos, "invalid {{#if}} condition: %v", err)
+	}
+
+	n := &ifNode{cond: cond}
+	var closer *tag
+	n.then, closer, err = p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if closer != nil && closer.text == "else" {
+		n.orElse, closer, err = p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if closer == nil {
+		return nil, errorAt(p.src, t.pos, "{{#if}} is not closed")
+	}
+	if closer.text != "/if" {
+		return nil, errorAt(p.src, closer.pos, "expected {{/if}}, got {{%s}}", closer.text)
+	}
+	return n, nil
+}
+
+func (p *parser) parseEach(t *tag) (node, error) {
+	name := strings.TrimSpace(strings.TrimPrefix(t.text, "#each"))
+	if name == "" {
+		return nil, errorAt(p.src, t.pos, "{{#each}} needs a list field")
+	}
+
+	body, closer, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if closer == nil {
+		return nil, errorAt(p.src, t.pos, "{{#each}} is not closed")
+	}
+	if closer.text != "/each" {
+		return nil, errorAt(p.src, closer.pos, "expected {{/each}}, got {{%s}}", closer.text)
+	}
+	return &eachNode{name: name, body: body}, nil
+}
+
+// parseValue parses a field followed by filters: name | filter "arg" | filter
+func (p *parser) parseValue(t *tag) (node, error) {
+	parts, err := splitPipeline(t.text)
+	if err != nil {
+		return nil, errorAt(p.src, t.pos, "%v", err)
+	}
+	n := &valueNode{name: strings.TrimSpace(parts[0]), inQuery: p.inQuery}
+	if n.name == "" {
+		return nil, errorAt(p.src, t.pos, "missing field name")
+	}
+	for _, part := range parts[1:] {
+		call, err := parseFilter(part)
+		if err != nil {
+			return nil, errorAt(p.src, t.pos, "%v", err)
+		}
+		n.filters = append(n.filters, call)
+	}
+	return n, nil
+}
+
+// splitPipeline splits s on | outside quoted strings
+func splitPipeline(s string) ([]string, error) {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '|':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	return append(parts, s[start:]), nil
+}
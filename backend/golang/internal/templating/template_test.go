@@ -0,0 +1,134 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+)
+
+// fieldConnector serves GetContactFieldValue from a map; other methods are unused
+type fieldConnector struct {
+	connectors.CRMConnector
+	fields map[string]interface{}
+	calls  int
+}
+
+func (c *fieldConnector) GetContactFieldValue(ctx context.Context, contactID, fieldKey string) (interface{}, error) {
+	c.calls++
+	value, ok := c.fields[fieldKey]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s", fieldKey)
+	}
+	return value, nil
+}
+
+func testData() *Data {
+	created := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	return &Data{
+		Contact: &connectors.NormalizedContact{
+			ID:        "123",
+			FirstName: "John",
+			LastName:  "O'Brien",
+			Email:     "john+vip@example.com",
+			Tags:      []connectors.TagRef{{ID: "1", Name: "VIP"}, {ID: "2", Name: "Newsletter"}},
+			CustomFields: map[string]interface{}{
+				"LeadScore":   float64(85),
+				"RenewalDate": "2026-03-01",
+			},
+			CreatedAt: &created,
+		},
+		Input:       map[string]interface{}{"order": map[string]interface{}{"id": "A-1", "items": []interface{}{"Hat", "Scarf"}}},
+		QueryParams: map[string]string{"ref": "spring sale"},
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		mode Mode
+		want string
+	}{
+		{"standard fields", "Hi {{first_name}} {{last_name}}", Text, "Hi John O'Brien"},
+		{"legacy names and full_name", "{{FirstName}} / {{full_name}} / {{Id}}", Text, "John / John O'Brien / 123"},
+		{"custom fields", "{{LeadScore}} {{custom.LeadScore}}", Text, "85 85"},
+		{"input and query", "{{input.order.id}} via {{query.ref}}", Text, "A-1 via spring sale"},
+		{"missing renders empty", "[{{Nickname}}]", Text, "[]"},
+		{"default", "Hi {{Nickname | default \"there\"}}", Text, "Hi there"},
+		{"chained filters", "{{ first_name | lower | upper }}", Text, "JOHN"},
+		{"date", "{{RenewalDate | date \"Jan 2, 2006\"}} {{created_at | date \"2006-01-02\"}}", Text, "Mar 1, 2026 2026-01-10"},
+		{"if", "{{#if has_tag(\"VIP\")}}VIP{{else}}regular{{/if}}", Text, "VIP"},
+		{"else", "{{#if custom.LeadScore > 90}}hot{{else}}warm{{/if}}", Text, "warm"},
+		{"each tag", "{{#each tags}}{{@index}}:{{name}} {{/each}}", Text, "0:VIP 1:Newsletter "},
+		{"each input list", "{{#each input.order.items}}<{{this}}>{{/each}}", Text, "<Hat><Scarf>"},
+		{"nested blocks", "{{#each tags}}{{#if has_tag(\"VIP\")}}{{id}}{{/if}}{{/each}}", Text, "12"},
+		{"comment", "a{{! ignored }}b", Text, "ab"},
+		{"url path and query", "https://x.test/c/{{last_name}}/?email={{email}}&ref={{query.ref}}", URL, "https://x.test/c/O%27Brien/?email=john%2Bvip%40example.com&ref=spring+sale"},
+		{"urlencode is not escaped twice", "https://x.test/?e={{email | urlencode}}", URL, "https://x.test/?e=john%2Bvip%40example.com"},
+		{"json string", `{"name": "{{last_name}} \"{{query.ref}}\""}`, JSON, `{"name": "O'Brien \"spring sale\""}`},
+		{"json filter", `{"items": {{input.order.items | json}}, "score": {{LeadScore | json}}}`, JSON, `{"items": ["Hat","Scarf"], "score": 85}`},
+		{"html", "<p>{{last_name}} &amp; {{input.order.items | json}}</p>", HTML, "<p>O&#39;Brien &amp; [&#34;Hat&#34;,&#34;Scarf&#34;]</p>"},
+		{"raw", "<p>{{query.ref | raw}}</p>", HTML, "<p>spring sale</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(context.Background(), tt.src, tt.mode, testData())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRender_ConnectorFallback(t *testing.T) {
+	conn := &fieldConnector{fields: map[string]interface{}{"city": "Boise"}}
+	data := testData()
+	data.Connector = conn
+	data.ContactID = "123"
+
+	got, err := Render(context.Background(), "{{city}} {{city}} {{first_name}} [{{Unknown}}]", Text, data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got != "Boise Boise John []" {
+		t.Errorf("Expected \"Boise Boise John []\", got %q", got)
+	}
+	if conn.calls != 2 {
+		t.Errorf("Expected 2 connector lookups (city once, Unknown once), got %d", conn.calls)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{"Hi {{first_name", "unclosed {{ at line 1, column 4"},
+		{"Hi {{ }}", "empty {{}} at line 1, column 4"},
+		{"line\n{{first_name | shout}}", `unknown filter "shout" at line 2, column 1`},
+		{"{{first_name | default}}", "default takes 1 argument(s), got 0 at line 1, column 1"},
+		{"{{first_name | date \"2006}}", "unterminated string at line 1, column 1"},
+		{"{{#if}}x{{/if}}", "{{#if}} needs a condition at line 1, column 1"},
+		{"{{#if score > 5}}x{{/if}}", `invalid {{#if}} condition: unknown field "score"; custom fields are referenced as custom.score at column 1 at line 1, column 1`},
+		{"{{#if has_tag(\"VIP\")}}x", "{{#if}} is not closed at line 1, column 1"},
+		{"{{#each tags}}x{{/if}}", "expected {{/each}}, got {{/if}} at line 1, column 16"},
+		{"x{{/each}}", "unexpected {{/each}} at line 1, column 2"},
+		{"{{#unless x}}{{/unless}}", "unknown block {{#unless x}} at line 1, column 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src, Text)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
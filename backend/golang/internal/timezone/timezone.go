@@ -0,0 +1,385 @@
+// Package timezone resolves a postal address to an IANA time zone without
+// calling out to a geocoder. Countries, states and provinces, postal code
+// ranges and the cities that sit across a zone line are embedded as CSV
+// tables; zone rules come from the embedded tzdata, so time.LoadLocation
+// works on hosts without zoneinfo.
+package timezone
+
+import (
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata"
+)
+
+//go:embed data/*.csv
+var data embed.FS
+
+// Resolution precisions, from the most to the least specific
+const (
+	PrecisionCity    = "city"
+	PrecisionPostal  = "postal"
+	PrecisionRegion  = "region"
+	PrecisionCountry = "country"
+)
+
+// ErrUnresolved is returned when an address does not identify a time zone
+var ErrUnresolved = errors.New("time zone could not be resolved")
+
+// Address is the part of a postal address the time zone depends on. Country
+// is a name or ISO 3166 code; Region is a state or province name or code.
+type Address struct {
+	Country    string
+	Region     string
+	PostalCode string
+	City       string
+}
+
+// Resolution is the time zone of an address. The coordinates are those of
+// the city, or the region's or country's main city, the zone was resolved by.
+type Resolution struct {
+	Zone      string
+	Location  *time.Location
+	Country   string // ISO 3166-1 alpha-2
+	Region    string
+	Latitude  float64
+	Longitude float64
+	Precision string
+}
+
+// Offset returns the UTC offset at t, in hours
+func (r *Resolution) Offset(t time.Time) float64 {
+	_, seconds := t.In(r.Location).Zone()
+	return float64(seconds) / 3600
+}
+
+// Resolve finds the time zone of addr. The country may be left out for US,
+// Canadian, Australian, Mexican and Brazilian addresses with a state or a
+// US or Canadian postal code. In countries that span several zones, the
+// region and postal code pick the zone, and the city where a region is split
+// and there is no postal code.
+func Resolve(addr Address) (*Resolution, error) {
+	t, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	regionKey := normalize(addr.Region)
+	postal := normalizePostal(addr.PostalCode)
+	cityKey := normalize(addr.City)
+
+	var c *country
+	if name := normalize(addr.Country); name != "" {
+		if c = t.countries[name]; c == nil {
+			return nil, fmt.Errorf("%w: unknown country %q", ErrUnresolved, addr.Country)
+		}
+	} else if c = t.inferCountry(regionKey, postal); c == nil {
+		return nil, fmt.Errorf("%w: the address has no country", ErrUnresolved)
+	}
+
+	res := &Resolution{Zone: c.zone, Country: c.code, Latitude: c.lat, Longitude: c.lng, Precision: PrecisionCountry}
+	resolved := !c.multi
+
+	reg := t.regions[c.code+"|"+regionKey]
+	match := t.matchPostal(c.code, postal)
+	if reg == nil && match.region != "" {
+		reg = t.regions[c.code+"|"+match.region]
+	}
+	if reg != nil {
+		res.Region, res.Zone, res.Latitude, res.Longitude = reg.code, reg.zone, reg.lat, reg.lng
+		res.Precision = PrecisionRegion
+		resolved = true
+	}
+
+	// A postal code from another region than the one given is ignored
+	postalApplies := match.found && (reg == nil || match.region == "" || match.region == reg.code)
+	switch {
+	case postalApplies && match.zone != "":
+		res.Zone = match.zone
+		res.Precision = PrecisionPostal
+		resolved = true
+	case postalApplies:
+		// The postal tables list every split, so the region's zone stands
+	default:
+		if city := t.matchCity(c.code, res.Region, cityKey); city != nil {
+			res.Region = city.region
+			res.Zone, res.Latitude, res.Longitude = city.zone, city.lat, city.lng
+			res.Precision = PrecisionCity
+			resolved = true
+		}
+	}
+
+	if !resolved {
+		return nil, fmt.Errorf("%w: %s spans several time zones; a state, postal code or city is needed", ErrUnresolved, c.code)
+	}
+
+	res.Location, err = time.LoadLocation(res.Zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time zone %s: %w", res.Zone, err)
+	}
+	return res, nil
+}
+
+// ========== TABLES ==========
+
+type country struct {
+	code     string
+	zone     string
+	lat, lng float64
+	multi    bool // spans several zones, so the country alone does not resolve
+}
+
+type region struct {
+	country, code, zone string
+	lat, lng            float64
+}
+
+type postalRange struct {
+	from, to, region, zone string
+}
+
+type city struct {
+	country, region, zone string
+	lat, lng              float64
+}
+
+type postalMatch struct {
+	found        bool
+	region, zone string
+}
+
+type tables struct {
+	countries map[string]*country      // by ISO alpha-2, alpha-3 and names
+	regions   map[string]*region       // by country|code and country|name
+	postal    map[string][]postalRange // by country
+	cities    map[string][]*city       // by country|name
+}
+
+// inferCountryOrder is the order countries are tried in for addresses
+// without one, which settles codes such as WA or NT that several use
+var inferCountryOrder = []string{"US", "CA", "AU", "MX", "BR"}
+
+var (
+	usZIP        = regexp.MustCompile(`^[0-9]{5}([0-9]{4})?$`)
+	canadianPost = regexp.MustCompile(`^[A-Z][0-9][A-Z][0-9][A-Z][0-9]$`)
+)
+
+func (t *tables) inferCountry(regionKey, postal string) *country {
+	if regionKey != "" {
+		for _, code := range inferCountryOrder {
+			if t.regions[code+"|"+regionKey] != nil {
+				return t.countries[code]
+			}
+		}
+	}
+	switch {
+	case usZIP.MatchString(postal):
+		return t.countries["US"]
+	case canadianPost.MatchString(postal):
+		return t.countries["CA"]
+	}
+	return nil
+}
+
+// matchPostal finds the region of a postal code from the longest matching
+// prefix range, and the zone from the longest matching zone override
+func (t *tables) matchPostal(countryCode, postal string) postalMatch {
+	var match postalMatch
+	if postal == "" {
+		return match
+	}
+	regionLen, zoneLen := 0, 0
+	for _, r := range t.postal[countryCode] {
+		n := len(r.from)
+		if len(postal) < n {
+			continue
+		}
+		prefix := postal[:n]
+		if prefix < r.from || prefix > r.to {
+			continue
+		}
+		match.found = true
+		if r.region != "" && n > regionLen {
+			match.region, regionLen = r.region, n
+		}
+		if r.zone != "" && n > zoneLen {
+			match.zone, zoneLen = r.zone, n
+		}
+	}
+	return match
+}
+
+// matchCity finds a listed city in the region, or anywhere in the country
+// when the region is unknown
+func (t *tables) matchCity(countryCode, regionCode, cityKey string) *city {
+	if cityKey == "" {
+		return nil
+	}
+	for _, c := range t.cities[countryCode+"|"+cityKey] {
+		if regionCode == "" || c.region == "" || c.region == regionCode {
+			return c
+		}
+	}
+	return nil
+}
+
+var (
+	loadOnce   sync.Once
+	loaded     *tables
+	loadFailed error
+)
+
+func load() (*tables, error) {
+	loadOnce.Do(func() {
+		loaded, loadFailed = loadTables()
+	})
+	return loaded, loadFailed
+}
+
+func loadTables() (*tables, error) {
+	t := &tables{
+		countries: make(map[string]*country),
+		regions:   make(map[string]*region),
+		postal:    make(map[string][]postalRange),
+		cities:    make(map[string][]*city),
+	}
+
+	err := readTable("countries.csv", 7, func(row []string) error {
+		lat, lng, err := coordinates(row[3], row[4])
+		if err != nil {
+			return err
+		}
+		c := &country{code: row[0], zone: row[2], lat: lat, lng: lng, multi: row[5] == "1"}
+		for _, key := range append([]string{row[0], row[1]}, strings.Split(row[6], "|")...) {
+			t.countries[normalize(key)] = c
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readTable("regions.csv", 6, func(row []string) error {
+		lat, lng, err := coordinates(row[3], row[4])
+		if err != nil {
+			return err
+		}
+		r := &region{country: row[0], code: row[1], zone: row[2], lat: lat, lng: lng}
+		for _, key := range append([]string{row[1]}, strings.Split(row[5], "|")...) {
+			t.regions[row[0]+"|"+normalize(key)] = r
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readTable("postal.csv", 5, func(row []string) error {
+		if len(row[1]) != len(row[2]) {
+			return fmt.Errorf("postal range %s-%s has bounds of different lengths", row[1], row[2])
+		}
+		t.postal[row[0]] = append(t.postal[row[0]], postalRange{from: row[1], to: row[2], region: row[3], zone: row[4]})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readTable("cities.csv", 6, func(row []string) error {
+		lat, lng, err := coordinates(row[3], row[4])
+		if err != nil {
+			return err
+		}
+		c := &city{country: row[0], region: row[1], zone: row[2], lat: lat, lng: lng}
+		for _, name := range strings.Split(row[5], "|") {
+			key := row[0] + "|" + normalize(name)
+			t.cities[key] = append(t.cities[key], c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// readTable calls add for every row after the header of an embedded table
+func readTable(name string, columns int, add func(row []string) error) error {
+	f, err := data.Open("data/" + name)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = columns
+	rows, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	for i, row := range rows[1:] {
+		if err := add(row); err != nil {
+			return fmt.Errorf("%s line %d: %w", name, i+2, err)
+		}
+	}
+	return nil
+}
+
+func coordinates(lat, lng string) (float64, float64, error) {
+	la, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid latitude %q", lat)
+	}
+	lo, err := strconv.ParseFloat(lng, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid longitude %q", lng)
+	}
+	return la, lo, nil
+}
+
+// ========== NORMALIZATION ==========
+
+var foldAccents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n", "ß", "ss",
+	"Á", "A", "À", "A", "Â", "A", "Ä", "A", "Ã", "A", "Å", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Ö", "O", "Õ", "O", "Ø", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+// normalize makes names comparable: accents folded, upper case, dots and
+// apostrophes dropped and other punctuation turned into single spaces
+func normalize(s string) string {
+	s = strings.ToUpper(foldAccents.Replace(s))
+	s = strings.NewReplacer(".", "", "'", "", "’", "").Replace(s)
+	s = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == ',' || r == '/' {
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// normalizePostal upper-cases a postal code and drops spaces and dashes
+func normalizePostal(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(s)))
+}
@@ -0,0 +1,104 @@
+package timezone
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		addr      Address
+		zone      string
+		region    string
+		precision string
+	}{
+		{name: "state", addr: Address{Country: "USA", Region: "CA", PostalCode: "94105", City: "San Francisco"}, zone: "America/Los_Angeles", region: "CA", precision: PrecisionRegion},
+		{name: "split state by zip", addr: Address{Country: "United States", Region: "FL", PostalCode: "32501"}, zone: "America/Chicago", region: "FL", precision: PrecisionPostal},
+		{name: "split state by city", addr: Address{Region: "Florida", City: "Pensacola"}, zone: "America/Chicago", region: "FL", precision: PrecisionCity},
+		{name: "zip wins over city", addr: Address{Region: "TN", PostalCode: "37902-1234", City: "Nashville"}, zone: "America/New_York", region: "TN", precision: PrecisionPostal},
+		{name: "zip only", addr: Address{PostalCode: "97914"}, zone: "America/Boise", region: "OR", precision: PrecisionPostal},
+		{name: "canadian postal code", addr: Address{Country: "Canada", PostalCode: "k1a 0b1"}, zone: "America/Toronto", region: "ON", precision: PrecisionRegion},
+		{name: "canadian split province", addr: Address{Country: "CA", Region: "BC", PostalCode: "V1G 4H8"}, zone: "America/Dawson_Creek", region: "BC", precision: PrecisionPostal},
+		{name: "australian postcode", addr: Address{Country: "Australia", Region: "NSW", PostalCode: "2880"}, zone: "Australia/Broken_Hill", region: "NSW", precision: PrecisionPostal},
+		{name: "region without country", addr: Address{Region: "Queensland"}, zone: "Australia/Brisbane", region: "QLD", precision: PrecisionRegion},
+		{name: "accents", addr: Address{Country: "México", Region: "Quintana Roo"}, zone: "America/Cancun", region: "ROO", precision: PrecisionRegion},
+		{name: "city in a country without regions", addr: Address{Country: "Russian Federation", City: "Novosibirsk"}, zone: "Asia/Novosibirsk", precision: PrecisionCity},
+		{name: "single zone country", addr: Address{Country: "Deutschland", City: "Berlin"}, zone: "Europe/Berlin", precision: PrecisionCountry},
+		{name: "islands by postal code", addr: Address{Country: "ESP", PostalCode: "35001"}, zone: "Atlantic/Canary", precision: PrecisionPostal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Resolve(tt.addr)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if res.Zone != tt.zone || res.Region != tt.region || res.Precision != tt.precision {
+				t.Errorf("Expected %s in %q by %s, got %s in %q by %s", tt.zone, tt.region, tt.precision, res.Zone, res.Region, res.Precision)
+			}
+			if res.Location == nil || res.Location.String() != tt.zone {
+				t.Errorf("Expected location %s, got %v", tt.zone, res.Location)
+			}
+		})
+	}
+}
+
+func TestResolve_Unresolved(t *testing.T) {
+	for _, addr := range []Address{
+		{Country: "USA"},
+		{Country: "Russia", City: "Nowhere"},
+		{Country: "Narnia", Region: "CA"},
+		{City: "Springfield"},
+	} {
+		if res, err := Resolve(addr); !errors.Is(err, ErrUnresolved) {
+			t.Errorf("Expected ErrUnresolved for %+v, got %+v, %v", addr, res, err)
+		}
+	}
+}
+
+func TestResolution_Offset(t *testing.T) {
+	res, err := Resolve(Address{Country: "US", Region: "NY"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := res.Offset(time.Date(2026, time.January, 15, 12, 0, 0, 0, time.UTC)); got != -5 {
+		t.Errorf("Expected -5 in winter, got %v", got)
+	}
+	if got := res.Offset(time.Date(2026, time.July, 15, 12, 0, 0, 0, time.UTC)); got != -4 {
+		t.Errorf("Expected -4 in summer, got %v", got)
+	}
+}
+
+func TestTables_ZonesLoad(t *testing.T) {
+	tables, err := load()
+	if err != nil {
+		t.Fatalf("Expected the embedded tables to load, got %v", err)
+	}
+
+	zones := map[string]bool{}
+	for _, c := range tables.countries {
+		zones[c.zone] = true
+	}
+	for _, r := range tables.regions {
+		zones[r.zone] = true
+	}
+	for _, ranges := range tables.postal {
+		for _, r := range ranges {
+			if r.zone != "" {
+				zones[r.zone] = true
+			}
+		}
+	}
+	for _, cities := range tables.cities {
+		for _, c := range cities {
+			zones[c.zone] = true
+		}
+	}
+	for zone := range zones {
+		if _, err := time.LoadLocation(zone); err != nil {
+			t.Errorf("Expected zone %s to load, got %v", zone, err)
+		}
+	}
+}
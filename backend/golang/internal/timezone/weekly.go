@@ -0,0 +1,96 @@
+package timezone
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Weekly is a local wall-clock time on one day of the week, or on every day
+type Weekly struct {
+	AnyDay bool
+	Day    time.Weekday
+	Hour   int
+	Minute int
+}
+
+var weekdays = map[string]time.Weekday{
+	"SUNDAY": time.Sunday, "SUN": time.Sunday,
+	"MONDAY": time.Monday, "MON": time.Monday,
+	"TUESDAY": time.Tuesday, "TUE": time.Tuesday, "TUES": time.Tuesday,
+	"WEDNESDAY": time.Wednesday, "WED": time.Wednesday,
+	"THURSDAY": time.Thursday, "THU": time.Thursday, "THURS": time.Thursday,
+	"FRIDAY": time.Friday, "FRI": time.Friday,
+	"SATURDAY": time.Saturday, "SAT": time.Saturday,
+}
+
+var anyDay = map[string]bool{"ANY": true, "DAILY": true, "EVERY DAY": true, "EVERYDAY": true}
+
+// clockLayouts are the accepted time of day formats, after upper-casing and
+// removing spaces
+var clockLayouts = []string{"15:04", "15:04:05", "3:04PM", "3PM", "3:04:05PM"}
+
+// ParseWeekly reads a day of the week (a name such as "Monday" or "Mon", or
+// "any" or "daily") and a time of day such as "9:00 AM" or "14:30"
+func ParseWeekly(day, clock string) (Weekly, error) {
+	var w Weekly
+	name := strings.ToUpper(strings.Join(strings.Fields(day), " "))
+	if d, ok := weekdays[name]; ok {
+		w.Day = d
+	} else if anyDay[name] {
+		w.AnyDay = true
+	} else {
+		return w, fmt.Errorf("invalid day %q", day)
+	}
+
+	value := strings.ReplaceAll(strings.ToUpper(clock), " ", "")
+	value = strings.NewReplacer("A.M.", "AM", "P.M.", "PM").Replace(value)
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			w.Hour, w.Minute = t.Hour(), t.Minute()
+			return w, nil
+		}
+	}
+	return w, fmt.Errorf("invalid time %q", clock)
+}
+
+// Next returns the first occurrence in loc strictly after after. A time
+// skipped by a daylight saving change happens when the clocks have gone
+// forward, e.g. 2:30 AM becomes 3:30 AM; a time that occurs twice when they
+// go back happens the first time.
+func (w Weekly) Next(loc *time.Location, after time.Time) time.Time {
+	year, month, day := after.In(loc).Date()
+	for i := 0; i <= 7; i++ {
+		date := time.Date(year, month, day+i, 12, 0, 0, 0, loc)
+		if !w.AnyDay && date.Weekday() != w.Day {
+			continue
+		}
+		if t := wallClock(date, w.Hour, w.Minute); t.After(after) {
+			return t
+		}
+	}
+	// Unreachable: the same weekday a week later is always after
+	return after
+}
+
+// String describes the schedule, e.g. "Monday 09:00"
+func (w Weekly) String() string {
+	day := w.Day.String()
+	if w.AnyDay {
+		day = "Every day"
+	}
+	return fmt.Sprintf("%s %02d:%02d", day, w.Hour, w.Minute)
+}
+
+// wallClock returns the time hour:minute shows on the clock on date's day in
+// date's location, moved past the gap if the clocks skip it
+func wallClock(date time.Time, hour, minute int) time.Time {
+	year, month, day := date.Date()
+	t := time.Date(year, month, day, hour, minute, 0, 0, date.Location())
+	if t.Hour() != hour || t.Minute() != minute {
+		_, before := t.Zone()
+		_, after := t.Add(24 * time.Hour).Zone()
+		t = t.Add(time.Duration(after-before) * time.Second)
+	}
+	return t
+}
@@ -0,0 +1,77 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWeekly(t *testing.T) {
+	tests := []struct {
+		day, clock string
+		expected   Weekly
+		wantErr    bool
+	}{
+		{day: "Monday", clock: "9:00 AM", expected: Weekly{Day: time.Monday, Hour: 9}},
+		{day: "tue", clock: "14:30", expected: Weekly{Day: time.Tuesday, Hour: 14, Minute: 30}},
+		{day: "Friday", clock: "3pm", expected: Weekly{Day: time.Friday, Hour: 15}},
+		{day: "Every day", clock: "12:15 a.m.", expected: Weekly{AnyDay: true, Minute: 15}},
+		{day: "Someday", clock: "9:00", wantErr: true},
+		{day: "Monday", clock: "noonish", wantErr: true},
+		{day: "Monday", clock: "25:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseWeekly(tt.day, tt.clock)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected an error for %q %q", tt.day, tt.clock)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("Expected %+v for %q %q, got %+v, %v", tt.expected, tt.day, tt.clock, got, err)
+		}
+	}
+}
+
+func TestWeekly_Next(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	at := func(s string) time.Time {
+		t.Helper()
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name     string
+		weekly   Weekly
+		after    string
+		expected string
+	}{
+		{name: "across spring forward", weekly: Weekly{Day: time.Monday, Hour: 9}, after: "2026-03-06T15:00:00Z", expected: "2026-03-09T13:00:00Z"},
+		{name: "skipped time", weekly: Weekly{Day: time.Sunday, Hour: 2, Minute: 30}, after: "2026-03-06T15:00:00Z", expected: "2026-03-08T07:30:00Z"},
+		{name: "repeated time", weekly: Weekly{Day: time.Sunday, Hour: 1, Minute: 30}, after: "2026-10-30T15:00:00Z", expected: "2026-11-01T05:30:00Z"},
+		{name: "later the same day", weekly: Weekly{Day: time.Monday, Hour: 9}, after: "2026-03-09T12:00:00Z", expected: "2026-03-09T13:00:00Z"},
+		{name: "exactly now", weekly: Weekly{Day: time.Monday, Hour: 9}, after: "2026-03-09T13:00:00Z", expected: "2026-03-16T13:00:00Z"},
+		{name: "local date differs from UTC", weekly: Weekly{Day: time.Sunday, Hour: 22}, after: "2026-06-08T01:00:00Z", expected: "2026-06-08T02:00:00Z"},
+		{name: "any day", weekly: Weekly{AnyDay: true, Hour: 8}, after: "2026-07-01T13:00:00Z", expected: "2026-07-02T12:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.weekly.Next(ny, at(tt.after))
+			if !got.Equal(at(tt.expected)) {
+				t.Errorf("Expected %s, got %s", tt.expected, got.UTC().Format(time.RFC3339))
+			}
+			if got.Location() != ny {
+				t.Errorf("Expected the time in %s, got %s", ny, got.Location())
+			}
+		})
+	}
+}
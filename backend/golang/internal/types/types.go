@@ -265,6 +265,22 @@ type PlatformConnectionAuth struct {
 	TTL *int64 `json:"ttl,omitempty" dynamodbav:"ttl,omitempty"`
 }
 
+// ConnectionBreaker is the circuit breaker of a CRM connection. It opens
+// after consecutive auth or server errors from the connection, holding the
+// connection's executions as paused, and probes the connection until it is
+// healthy again.
+type ConnectionBreaker struct {
+	ConnectionID        string `json:"connection_id" dynamodbav:"connection_id"`
+	AccountID           string `json:"account_id" dynamodbav:"account_id"`
+	State               string `json:"state" dynamodbav:"state"` // closed, open, half_open
+	ConsecutiveFailures int    `json:"consecutive_failures" dynamodbav:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty" dynamodbav:"last_error,omitempty"`
+	OpenedAt            string `json:"opened_at,omitempty" dynamodbav:"opened_at,omitempty"`
+	ProbeAttempts       int    `json:"probe_attempts" dynamodbav:"probe_attempts"`
+	NextProbeAt         string `json:"next_probe_at,omitempty" dynamodbav:"next_probe_at,omitempty"` // only while open or half-open
+	UpdatedAt           string `json:"updated_at" dynamodbav:"updated_at"`
+}
+
 // OAuthState represents a temporary OAuth state token for CSRF protection
 type OAuthState struct {
 	State      string                 `json:"state" dynamodbav:"state_id"`
@@ -294,6 +310,7 @@ type Helper struct {
 	Status       string                 `json:"status" dynamodbav:"status"`
 	Config       map[string]interface{} `json:"config" dynamodbav:"config"`
 	ConfigSchema map[string]interface{} `json:"config_schema,omitempty" dynamodbav:"config_schema,omitempty"`
+	ConfigVersion    int                    `json:"config_version" dynamodbav:"config_version,omitempty"` // 0 for helpers whose config predates versioning
 	Enabled          bool                   `json:"enabled" dynamodbav:"enabled"`
 	ExecutionCount   int64                  `json:"execution_count" dynamodbav:"execution_count"`
 	LastExecutedAt   *time.Time             `json:"last_executed_at,omitempty" dynamodbav:"last_executed_at,omitempty"`
@@ -306,6 +323,28 @@ type Helper struct {
 	UpdatedAt        time.Time              `json:"updated_at" dynamodbav:"updated_at"`
 }
 
+// HelperConfigVersion is an immutable snapshot of a helper's config. Every
+// config update and rollback adds one; versions are numbered from 1.
+type HelperConfigVersion struct {
+	HelperID   string                 `json:"helper_id" dynamodbav:"helper_id"`
+	Version    int                    `json:"version" dynamodbav:"version"`
+	AccountID  string                 `json:"account_id" dynamodbav:"account_id"`
+	Config     map[string]interface{} `json:"config" dynamodbav:"config"`
+	Changes    []ConfigChange         `json:"changes" dynamodbav:"changes"` // diff against the previous version
+	CreatedBy  string                 `json:"created_by,omitempty" dynamodbav:"created_by,omitempty"`
+	CreatedAt  string                 `json:"created_at" dynamodbav:"created_at"`
+	RollbackOf int                    `json:"rollback_of,omitempty" dynamodbav:"rollback_of,omitempty"` // the version restored by a rollback
+}
+
+// ConfigChange is one difference between two helper configs. Path is the
+// dot-separated key of the changed value; list elements are compared whole.
+type ConfigChange struct {
+	Path string      `json:"path" dynamodbav:"path"`
+	Op   string      `json:"op" dynamodbav:"op"` // added, removed, changed
+	Old  interface{} `json:"old,omitempty" dynamodbav:"old,omitempty"`
+	New  interface{} `json:"new,omitempty" dynamodbav:"new,omitempty"`
+}
+
 // HelperTemplate represents a predefined helper template from the library
 type HelperTemplate struct {
 	TemplateID   string                 `json:"template_id" dynamodbav:"template_id"`
@@ -334,6 +373,7 @@ type Execution struct {
 	AccountID    string                 `json:"account_id" dynamodbav:"account_id"`
 	UserID       string                 `json:"user_id,omitempty" dynamodbav:"user_id,omitempty"`
 	APIKeyID     string                 `json:"api_key_id,omitempty" dynamodbav:"api_key_id,omitempty"`
+	APIKey       string                 `json:"-" dynamodbav:"api_key,omitempty"` // raw x-api-key forwarded to relay helpers; never returned by the API
 	ConnectionID string                 `json:"connection_id,omitempty" dynamodbav:"connection_id,omitempty"`
 	ContactID    string                 `json:"contact_id,omitempty" dynamodbav:"contact_id,omitempty"`
 	Config       map[string]interface{} `json:"config,omitempty" dynamodbav:"config,omitempty"`
@@ -343,6 +383,8 @@ type Execution struct {
 	QueryParams  map[string]string      `json:"query_params,omitempty" dynamodbav:"query_params,omitempty"`
 	Output       map[string]interface{} `json:"output,omitempty" dynamodbav:"output,omitempty"`
 	ErrorMessage string                 `json:"error_message,omitempty" dynamodbav:"error_message,omitempty"`
+	ErrorCode    string                 `json:"error_code,omitempty" dynamodbav:"error_code,omitempty"`   // config_error, crm_error, timeout, panic, limit, helper_error
+	ErrorStack   string                 `json:"error_stack,omitempty" dynamodbav:"error_stack,omitempty"` // truncated panic stack, for support
 	DurationMs   int64                  `json:"duration_ms" dynamodbav:"duration_ms"`
 	CreatedAt    string                 `json:"created_at" dynamodbav:"created_at"`
 	StartedAt    time.Time              `json:"started_at" dynamodbav:"started_at"`
@@ -350,6 +392,164 @@ type Execution struct {
 	TTL                  *int64                 `json:"ttl,omitempty" dynamodbav:"ttl,omitempty"`
 	StripeReported       bool                   `json:"stripe_reported,omitempty" dynamodbav:"stripe_reported,omitempty"`
 	StripeUsageRecordID  string                 `json:"stripe_usage_record_id,omitempty" dynamodbav:"stripe_usage_record_id,omitempty"`
+	ActionDeliveries     []ActionDelivery       `json:"action_deliveries,omitempty" dynamodbav:"action_deliveries,omitempty"`
+	Attempts             int                    `json:"attempts,omitempty" dynamodbav:"attempts,omitempty"`
+	RetryAttempts        []RetryAttempt         `json:"retry_attempts,omitempty" dynamodbav:"retry_attempts,omitempty"`
+	ReplayOf             string                 `json:"replay_of,omitempty" dynamodbav:"replay_of,omitempty"`
+	ReplayedAs           string                 `json:"replayed_as,omitempty" dynamodbav:"replayed_as,omitempty"`
+	WorkflowRunID        string                 `json:"workflow_run_id,omitempty" dynamodbav:"workflow_run_id,omitempty"`
+	WorkflowStepID       string                 `json:"workflow_step_id,omitempty" dynamodbav:"workflow_step_id,omitempty"`
+	ConfigVersion        int                    `json:"config_version,omitempty" dynamodbav:"config_version,omitempty"`
+	BatchID              string                 `json:"batch_id,omitempty" dynamodbav:"batch_id,omitempty"`
+	ConnectorTrace       []ConnectorCall        `json:"connector_trace,omitempty" dynamodbav:"connector_trace,omitempty"`
+	ConnectorCallsDropped int                   `json:"connector_calls_dropped,omitempty" dynamodbav:"connector_calls_dropped,omitempty"`
+	StatusHistory        []StatusTransition     `json:"status_history,omitempty" dynamodbav:"status_history,omitempty"`
+	// Receives of the SQS message HandbackMessageID that the worker handed
+	// back without running the execution; they are not attempts
+	Handbacks         int    `json:"-" dynamodbav:"handbacks,omitempty"`
+	HandbackMessageID string `json:"-" dynamodbav:"handback_message_id,omitempty"`
+}
+
+// StatusTransition records an execution entering a status. Reason is set for
+// cancellations and failures.
+type StatusTransition struct {
+	Status string `json:"status" dynamodbav:"status"`
+	At     string `json:"at" dynamodbav:"at"`
+	Reason string `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+}
+
+// ConnectorCall records one CRM call made by the latest attempt of an
+// execution. Target is the field key, tag ID, automation or goal as sent to
+// the CRM after translation.
+type ConnectorCall struct {
+	Method     string `json:"method" dynamodbav:"method"`
+	ContactID  string `json:"contact_id,omitempty" dynamodbav:"contact_id,omitempty"`
+	Target     string `json:"target,omitempty" dynamodbav:"target,omitempty"`
+	StatusCode int    `json:"status_code,omitempty" dynamodbav:"status_code,omitempty"`
+	Requests   int    `json:"requests,omitempty" dynamodbav:"requests,omitempty"`
+	Retries    int    `json:"retries,omitempty" dynamodbav:"retries,omitempty"`
+	LatencyMs  int64  `json:"latency_ms" dynamodbav:"latency_ms"`
+	Error      string `json:"error,omitempty" dynamodbav:"error,omitempty"`
+}
+
+// RetryAttempt records one failed attempt of an execution. RetryAt is empty
+// when the failure was final (not retryable, or retries exhausted).
+type RetryAttempt struct {
+	Attempt   int    `json:"attempt" dynamodbav:"attempt"`
+	Error     string `json:"error" dynamodbav:"error"`
+	ErrorCode string `json:"error_code,omitempty" dynamodbav:"error_code,omitempty"`
+	FailedAt  string `json:"failed_at" dynamodbav:"failed_at"`
+	RetryAt   string `json:"retry_at,omitempty" dynamodbav:"retry_at,omitempty"`
+}
+
+// ActionDelivery records the delivery outcome of a queued post-execution action
+// (webhook_queued, email_queued, notification_queued, export_queued, etc.)
+type ActionDelivery struct {
+	Type        string `json:"type" dynamodbav:"type"`
+	Target      string `json:"target" dynamodbav:"target"`
+	Status      string `json:"status" dynamodbav:"status"` // "delivered", "failed", "skipped"
+	StatusCode  int    `json:"status_code,omitempty" dynamodbav:"status_code,omitempty"`
+	Detail      string `json:"detail,omitempty" dynamodbav:"detail,omitempty"`
+	Error       string `json:"error,omitempty" dynamodbav:"error,omitempty"`
+	DurationMs  int64  `json:"duration_ms" dynamodbav:"duration_ms"`
+	DeliveredAt string `json:"delivered_at" dynamodbav:"delivered_at"`
+}
+
+// ========== BATCH TYPES ==========
+
+// Batch is a run of one helper over a set of contacts. The batch runner
+// dispatches one execution per contact at RatePerMinute; the helper workers
+// count each outcome in Succeeded or Failed.
+type Batch struct {
+	BatchID        string                 `json:"batch_id" dynamodbav:"batch_id"`
+	AccountID      string                 `json:"account_id" dynamodbav:"account_id"`
+	UserID         string                 `json:"user_id,omitempty" dynamodbav:"user_id,omitempty"`
+	HelperID       string                 `json:"helper_id" dynamodbav:"helper_id"`
+	ConnectionID   string                 `json:"connection_id,omitempty" dynamodbav:"connection_id,omitempty"`
+	Source         BatchSource            `json:"source" dynamodbav:"source"`
+	Input          map[string]interface{} `json:"input,omitempty" dynamodbav:"input,omitempty"`
+	Status         string                 `json:"status" dynamodbav:"status"` // running, paused, completed, cancelled, failed
+	StatusReason   string                 `json:"status_reason,omitempty" dynamodbav:"status_reason,omitempty"`
+	RatePerMinute  int                    `json:"rate_per_minute" dynamodbav:"rate_per_minute"`
+	Total          int                    `json:"total,omitempty" dynamodbav:"total,omitempty"` // known up front for contact ID lists and snapshots
+	Dispatched     int                    `json:"dispatched" dynamodbav:"dispatched"`
+	Succeeded      int                    `json:"succeeded" dynamodbav:"succeeded"`
+	Failed         int                    `json:"failed" dynamodbav:"failed"`
+	Cursor         string                 `json:"-" dynamodbav:"cursor,omitempty"`  // source position after Pending
+	Pending        []string               `json:"-" dynamodbav:"pending,omitempty"` // contacts read from the source, not yet dispatched
+	SourceDone     bool                   `json:"source_done" dynamodbav:"source_done"`
+	LastDispatchAt string                 `json:"last_dispatch_at,omitempty" dynamodbav:"last_dispatch_at,omitempty"`
+	CreatedAt      string                 `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt      string                 `json:"updated_at" dynamodbav:"updated_at"`
+	CompletedAt    string                 `json:"completed_at,omitempty" dynamodbav:"completed_at,omitempty"`
+	Version        int                    `json:"-" dynamodbav:"version"`
+}
+
+// BatchSource selects the contacts of a batch. Type is contact_ids, tag,
+// query or data_explorer.
+type BatchSource struct {
+	Type       string      `json:"type" dynamodbav:"type"`
+	ContactIDs []string    `json:"contact_ids,omitempty" dynamodbav:"contact_ids,omitempty"`
+	TagID      string      `json:"tag_id,omitempty" dynamodbav:"tag_id,omitempty"`
+	Query      *BatchQuery `json:"query,omitempty" dynamodbav:"query,omitempty"`
+	// Data explorer sources are resolved to a contact ID snapshot in the
+	// analytics bucket when the batch is created
+	DataFilters []DataFilter `json:"data_filters,omitempty" dynamodbav:"data_filters,omitempty"`
+	Search      string       `json:"search,omitempty" dynamodbav:"search,omitempty"`
+	SnapshotKey string       `json:"-" dynamodbav:"snapshot_key,omitempty"`
+}
+
+// BatchQuery is the filter part of a connectors.QueryOptions, with the same
+// JSON field names
+type BatchQuery struct {
+	Filters map[string]string `json:"filters,omitempty" dynamodbav:"filters,omitempty"`
+	TagID   string            `json:"tag_id,omitempty" dynamodbav:"tag_id,omitempty"`
+	Email   string            `json:"email,omitempty" dynamodbav:"email,omitempty"`
+	OrderBy string            `json:"order_by,omitempty" dynamodbav:"order_by,omitempty"`
+}
+
+// DataFilter is a data explorer filter condition over a synced parquet column
+type DataFilter struct {
+	Column   string      `json:"column" dynamodbav:"column"`
+	Operator string      `json:"operator" dynamodbav:"operator"`
+	Value    interface{} `json:"value" dynamodbav:"value"`
+	Value2   interface{} `json:"value2,omitempty" dynamodbav:"value2,omitempty"`
+}
+
+// BatchItem is the per-contact result of a batch
+type BatchItem struct {
+	BatchID     string `json:"batch_id" dynamodbav:"batch_id"`
+	ContactID   string `json:"contact_id" dynamodbav:"contact_id"`
+	ExecutionID string `json:"execution_id" dynamodbav:"execution_id"`
+	Status      string `json:"status" dynamodbav:"status"` // queued, completed, failed
+	Error       string `json:"error,omitempty" dynamodbav:"error,omitempty"`
+	CreatedAt   string `json:"created_at" dynamodbav:"created_at"`
+	CompletedAt string `json:"completed_at,omitempty" dynamodbav:"completed_at,omitempty"`
+	TTL         *int64 `json:"-" dynamodbav:"ttl,omitempty"`
+}
+
+// DelayedExecution is a helper execution held until RunAt. The delayed
+// execution sweeper releases it by creating a queued execution with the same
+// ExecutionID; until then it can be listed and cancelled by contact or helper.
+type DelayedExecution struct {
+	ExecutionID  string                 `json:"execution_id" dynamodbav:"execution_id"`
+	AccountID    string                 `json:"account_id" dynamodbav:"account_id"`
+	UserID       string                 `json:"user_id,omitempty" dynamodbav:"user_id,omitempty"`
+	APIKeyID     string                 `json:"api_key_id,omitempty" dynamodbav:"api_key_id,omitempty"`
+	APIKey       string                 `json:"-" dynamodbav:"api_key,omitempty"` // forwarded to the execution for relay helpers
+	HelperID     string                 `json:"helper_id" dynamodbav:"helper_id"`
+	ContactID    string                 `json:"contact_id,omitempty" dynamodbav:"contact_id,omitempty"`
+	Config       map[string]interface{} `json:"config,omitempty" dynamodbav:"config,omitempty"` // overrides the helper's config at release
+	Input        map[string]interface{} `json:"input,omitempty" dynamodbav:"input,omitempty"`
+	QueryParams  map[string]string      `json:"query_params,omitempty" dynamodbav:"query_params,omitempty"`
+	TriggerType  string                 `json:"trigger_type" dynamodbav:"trigger_type"`
+	ScheduledBy  string                 `json:"scheduled_by,omitempty" dynamodbav:"scheduled_by,omitempty"` // the execution that asked for it, for helper-scheduled runs
+	RunAt        string                 `json:"run_at" dynamodbav:"run_at"`
+	Status       string                 `json:"status" dynamodbav:"status"` // pending, released, cancelled, failed
+	StatusReason string                 `json:"status_reason,omitempty" dynamodbav:"status_reason,omitempty"`
+	CreatedAt    string                 `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt    string                 `json:"updated_at" dynamodbav:"updated_at"`
+	TTL          *int64                 `json:"-" dynamodbav:"ttl,omitempty"`
 }
 
 // ========== API KEY TYPES ==========
@@ -0,0 +1,517 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/delayed"
+	"github.com/myfusionhelper/api/internal/email"
+	helperEngine "github.com/myfusionhelper/api/internal/helpers"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+var (
+	exportsBucket = os.Getenv("EXPORTS_BUCKET")
+
+	actionHTTPClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// maxResponseSnippet bounds how much of a failed response body ends up in the
+// delivery error stored on the execution record.
+const maxResponseSnippet = 512
+
+// ========== WEBHOOK ==========
+
+// handleWebhookAction delivers webhook_queued actions. The action value
+// describes the request: method, url, payload, headers, auth_type and
+// content_type (JSON by default, form-encoded for Twilio). See
+// setWebhookAuth for the auth types.
+func handleWebhookAction(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error) {
+	spec, ok := action.Value.(map[string]interface{})
+	if !ok {
+		// hook_it v4 with async_execution queues only its helper ID; deliver
+		// by running the helper again synchronously in the worker.
+		if action.Target == "async_worker" {
+			return runAsyncHook(ctx, actx)
+		}
+		return nil, fmt.Errorf("invalid webhook action value")
+	}
+
+	method := strings.ToUpper(stringValue(spec, "method"))
+	if method == "" {
+		method = "POST"
+	}
+	targetURL := stringValue(spec, "url")
+	if targetURL == "" {
+		targetURL = action.Target
+	}
+	if targetURL == "" {
+		return nil, fmt.Errorf("%w: webhook URL is empty", ErrActionSkipped)
+	}
+
+	contentType := stringValue(spec, "content_type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	var bodyReader io.Reader
+	if payload, ok := spec["payload"]; ok && payload != nil && method != "GET" {
+		body, err := encodeWebhookBody(payload, contentType)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook request: %w", redactURLError(err))
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "MyFusionHelper/1.0")
+
+	for key, value := range stringMap(spec["headers"]) {
+		req.Header.Set(key, value)
+	}
+
+	if err := setWebhookAuth(req, spec); err != nil {
+		return nil, err
+	}
+
+	resp, err := actionHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webhook request failed: %w", redactURLError(err))
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	result := &ActionResult{StatusCode: resp.StatusCode}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return result, nil
+}
+
+// setWebhookAuth applies the auth_type of a webhook spec to req:
+//   - "" or "none" sends no credentials
+//   - "basic" sends auth_user and auth_pass as HTTP basic auth
+//   - "bearer" sends auth_token as a bearer token, or relies on a bearer
+//     Authorization header the helper already set
+//   - "api_key_in_body" relies on the api_key field of the payload
+//
+// Any other auth type, or missing credentials, fails the delivery rather
+// than sending the request unauthenticated.
+func setWebhookAuth(req *http.Request, spec map[string]interface{}) error {
+	switch authType := stringValue(spec, "auth_type"); authType {
+	case "", "none":
+		return nil
+	case "basic":
+		req.SetBasicAuth(stringValue(spec, "auth_user"), stringValue(spec, "auth_pass"))
+		return nil
+	case "bearer":
+		if token := stringValue(spec, "auth_token"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if !strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+			return fmt.Errorf("bearer webhook auth needs an auth_token or a bearer Authorization header")
+		}
+		return nil
+	case "api_key_in_body":
+		payload, _ := spec["payload"].(map[string]interface{})
+		if key, _ := payload["api_key"].(string); key == "" || req.Method == "GET" {
+			return fmt.Errorf("api_key_in_body webhook auth needs an api_key in the payload")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported webhook auth_type %q", authType)
+	}
+}
+
+// redactURLError strips the query string from the URL a *url.Error quotes
+// so API keys passed as query parameters are not persisted with the
+// delivery error.
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redactTarget(urlErr.URL)
+	}
+	return err
+}
+
+func encodeWebhookBody(payload interface{}, contentType string) ([]byte, error) {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form := url.Values{}
+		if m, ok := payload.(map[string]interface{}); ok {
+			for key, value := range m {
+				form.Set(key, fmt.Sprintf("%v", value))
+			}
+		}
+		return []byte(form.Encode()), nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	return body, nil
+}
+
+// runAsyncHook re-executes the job's hook_it helper with async execution
+// disabled so the webhook is sent from the worker instead of the request path.
+func runAsyncHook(ctx context.Context, actx *ActionContext) (*ActionResult, error) {
+	job := actx.Job
+
+	cfg := make(map[string]interface{}, len(job.Config)+1)
+	for k, v := range job.Config {
+		cfg[k] = v
+	}
+	cfg["async_execution"] = false
+
+	result, err := helperEngine.NewExecutor().Execute(ctx, helperEngine.ExecutionRequest{
+		HelperType:   job.HelperType,
+		ContactID:    job.ContactID,
+		Config:       cfg,
+		Input:        job.Input,
+		QueryParams:  job.QueryParams,
+		UserID:       job.UserID,
+		AccountID:    job.AccountID,
+		HelperID:     job.HelperID,
+		ConnectionID: job.ConnectionID,
+		ServiceAuths: actx.ServiceAuths,
+		APIKey:       job.APIKey,
+	}, actx.Connector)
+	if err != nil {
+		return nil, fmt.Errorf("async webhook execution failed: %w", err)
+	}
+	if !result.Success {
+		msg := result.Error
+		if msg == "" && result.Output != nil {
+			msg = result.Output.Message
+		}
+		return nil, fmt.Errorf("async webhook execution failed: %s", msg)
+	}
+
+	detail := ""
+	if result.Output != nil {
+		detail = result.Output.Message
+	}
+	return &ActionResult{Detail: detail}, nil
+}
+
+// ========== EMAIL ==========
+
+// handleEmailAction delivers email_queued actions (mail_it) through SES
+func handleEmailAction(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error) {
+	spec, ok := action.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid email action value")
+	}
+
+	to := stringValue(spec, "to")
+	if to == "" {
+		to = action.Target
+	}
+	if to == "" {
+		return nil, fmt.Errorf("%w: recipient is empty", ErrActionSkipped)
+	}
+
+	message := email.EmailMessage{
+		To:        []string{to},
+		Subject:   stringValue(spec, "subject"),
+		FromEmail: stringValue(spec, "from_email"),
+		FromName:  stringValue(spec, "from_name"),
+		Tags: map[string]string{
+			"helper_id": sanitizeTagValue(actx.Job.HelperID),
+		},
+	}
+	if stringValue(spec, "content_type") == "text/plain" {
+		message.TextBody = stringValue(spec, "body")
+	} else {
+		message.HTMLBody = stringValue(spec, "body")
+	}
+	if replyTo := stringValue(spec, "reply_to"); replyTo != "" {
+		message.ReplyTo = []string{replyTo}
+	}
+
+	return sendEmail(ctx, message)
+}
+
+func sendEmail(ctx context.Context, message email.EmailMessage) (*ActionResult, error) {
+	sesClient, err := email.NewSESClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := sesClient.SendEmail(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return &ActionResult{Detail: result.MessageID}, nil
+}
+
+// sanitizeTagValue keeps SES message tag values within the allowed charset
+func sanitizeTagValue(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, v)
+}
+
+// ========== NOTIFICATION ==========
+
+// handleNotificationAction delivers notification_queued actions. notify_me
+// sets a channel (email, slack, webhook) with a recipient; note_it has no
+// channel and is written to the contact as a CRM note.
+func handleNotificationAction(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error) {
+	spec, ok := action.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid notification action value")
+	}
+
+	channel := stringValue(spec, "channel")
+	recipient := stringValue(spec, "recipient")
+	subject := stringValue(spec, "subject")
+	message := stringValue(spec, "message")
+
+	switch channel {
+	case "":
+		return createContactNote(ctx, actx, spec)
+
+	case "email":
+		if recipient == "" {
+			return nil, fmt.Errorf("%w: email notification has no recipient", ErrActionSkipped)
+		}
+		return sendEmail(ctx, email.EmailMessage{
+			To:       []string{recipient},
+			Subject:  subject,
+			TextBody: message,
+		})
+
+	case "slack":
+		if recipient == "" {
+			return nil, fmt.Errorf("%w: slack notification has no webhook URL", ErrActionSkipped)
+		}
+		// Helpers saved before recipients were validated may name a channel
+		if !strings.HasPrefix(recipient, "https://") {
+			return nil, fmt.Errorf("slack recipient %q is not an incoming webhook URL", recipient)
+		}
+		text := message
+		if subject != "" {
+			text = "*" + subject + "*\n" + message
+		}
+		return handleWebhookAction(ctx, actx, helperEngine.HelperAction{
+			Type:   action.Type,
+			Target: recipient,
+			Value: map[string]interface{}{
+				"method":  "POST",
+				"url":     recipient,
+				"payload": map[string]interface{}{"text": text},
+			},
+		})
+
+	case "webhook":
+		if recipient == "" {
+			return nil, fmt.Errorf("%w: webhook notification has no URL", ErrActionSkipped)
+		}
+		return handleWebhookAction(ctx, actx, helperEngine.HelperAction{
+			Type:   action.Type,
+			Target: recipient,
+			Value: map[string]interface{}{
+				"method":  "POST",
+				"url":     recipient,
+				"payload": spec,
+			},
+		})
+
+	default:
+		return nil, fmt.Errorf("%w: unsupported notification channel %q", ErrActionSkipped, channel)
+	}
+}
+
+func createContactNote(ctx context.Context, actx *ActionContext, spec map[string]interface{}) (*ActionResult, error) {
+	if actx.Connector == nil {
+		return nil, fmt.Errorf("%w: no CRM connection for note", ErrActionSkipped)
+	}
+
+	noteConnector, ok := actx.Connector.(connectors.NoteConnector)
+	if !ok {
+		return nil, fmt.Errorf("%w: connector does not support notes", ErrActionSkipped)
+	}
+
+	contactID := stringValue(spec, "contact_id")
+	if contactID == "" {
+		contactID = actx.Job.ContactID
+	}
+
+	err := noteConnector.CreateNote(ctx, contactID, connectors.NoteInput{
+		Title: stringValue(spec, "subject"),
+		Body:  stringValue(spec, "body"),
+		Type:  stringValue(spec, "note_type"),
+	})
+	if err != nil {
+		if connErr, ok := err.(*connectors.ConnectorError); ok && connErr.StatusCode == 501 {
+			return nil, fmt.Errorf("%w: %s", ErrActionSkipped, connErr.Message)
+		}
+		return nil, err
+	}
+	return &ActionResult{Detail: "note created on contact " + contactID}, nil
+}
+
+// ========== EXPORT ==========
+
+// handleExportAction delivers export_queued actions (excel_it) by writing the
+// prepared rows to the exports bucket under the account and helper prefix.
+func handleExportAction(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error) {
+	spec, ok := action.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid export action value")
+	}
+	if exportsBucket == "" {
+		return nil, fmt.Errorf("%w: EXPORTS_BUCKET not configured", ErrActionSkipped)
+	}
+
+	var buf strings.Builder
+	if header := stringValue(spec, "header_row"); header != "" {
+		buf.WriteString(header)
+		buf.WriteString("\n")
+	}
+	buf.WriteString(stringValue(spec, "data_row"))
+	buf.WriteString("\n")
+
+	// Rows are always delimited text; xlsx exports are served as CSV which
+	// Excel opens natively.
+	key := fmt.Sprintf("exports/%s/%s/%s.csv",
+		actx.Job.AccountID, actx.Job.HelperID, strings.TrimPrefix(actx.Job.ExecutionID, "exec:"))
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	_, err = s3.NewFromConfig(cfg).PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(exportsBucket),
+		Key:         aws.String(key),
+		Body:        strings.NewReader(buf.String()),
+		ContentType: aws.String("text/csv"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload export: %w", err)
+	}
+
+	log.Printf("Uploaded export to s3://%s/%s", exportsBucket, key)
+	return &ActionResult{Detail: "s3://" + exportsBucket + "/" + key}, nil
+}
+
+// ========== DELAYED EXECUTION ==========
+
+// delayedIDNamespace derives the IDs of executions scheduled by a job, so a
+// redelivered job schedules them once
+var delayedIDNamespace = uuid.MustParse("6f1c3a52-8d0e-4b7a-9c1f-2e5d7a4b8c30")
+
+// handleExecutionScheduled delivers execution_scheduled actions (drip_it) by
+// scheduling a delayed execution of the target helper, the job's own by
+// default, for the job's contact. The action value sets run_at (RFC 3339) or
+// delay_seconds, and optionally contact_id and input.
+func handleExecutionScheduled(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error) {
+	spec, _ := action.Value.(map[string]interface{})
+	if spec == nil {
+		return nil, fmt.Errorf("invalid execution_scheduled action value")
+	}
+	if actx.Stores == nil {
+		return nil, fmt.Errorf("no stores to schedule the execution in")
+	}
+
+	job := actx.Job
+	helperID := action.Target
+	if helperID == "" {
+		helperID = job.HelperID
+	}
+	contactID := stringValue(spec, "contact_id")
+	if contactID == "" {
+		contactID = job.ContactID
+	}
+
+	runAt, err := delayed.ParseRunAt(stringValue(spec, "run_at"), spec["delay_seconds"], time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	if runAt.IsZero() {
+		return nil, fmt.Errorf("%w: run_at or delay_seconds is required", delayed.ErrInvalidSchedule)
+	}
+
+	helper, err := actx.Stores.Helpers.GetByID(ctx, helperID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load helper %s: %w", helperID, err)
+	}
+	if helper == nil || helper.AccountID != job.AccountID {
+		return nil, fmt.Errorf("%w: helper %s not found", ErrActionSkipped, helperID)
+	}
+
+	input, _ := spec["input"].(map[string]interface{})
+	pending := &apitypes.DelayedExecution{
+		ExecutionID: "exec:" + uuid.NewSHA1(delayedIDNamespace, []byte(job.ExecutionID+"#"+helperID+"#"+contactID)).String(),
+		AccountID:   job.AccountID,
+		UserID:      job.UserID,
+		HelperID:    helperID,
+		ContactID:   contactID,
+		Input:       input,
+		TriggerType: "helper",
+		ScheduledBy: job.ExecutionID,
+	}
+	err = delayed.Schedule(ctx, actx.Stores.Delayed, pending, runAt, time.Now().UTC())
+	if errors.Is(err, database.ErrConditionFailed) {
+		return &ActionResult{Detail: pending.ExecutionID + " already scheduled"}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Scheduled execution %s of helper %s for contact %s at %s", pending.ExecutionID, helperID, contactID, pending.RunAt)
+	return &ActionResult{Detail: pending.ExecutionID + " at " + pending.RunAt}, nil
+}
+
+// ========== VALUE HELPERS ==========
+
+func stringValue(m map[string]interface{}, key string) string {
+	if v, ok := m[key]; ok && v != nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+func stringMap(v interface{}) map[string]string {
+	switch m := v.(type) {
+	case map[string]string:
+		return m
+	case map[string]interface{}:
+		result := make(map[string]string, len(m))
+		for k, val := range m {
+			result[k] = fmt.Sprintf("%v", val)
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/myfusionhelper/api/internal/breaker"
+	"github.com/myfusionhelper/api/internal/database"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+// connectionPausedReason is recorded on executions held by an open breaker
+const connectionPausedReason = "connection circuit open"
+
+// loadConnectionBreaker returns the breaker of the job's connection. Jobs
+// without a connection, a nil store and store errors get nil, which holds
+// nothing.
+func loadConnectionBreaker(ctx context.Context, breakers database.ConnectionBreakerStore, job HelperExecutionJob) *apitypes.ConnectionBreaker {
+	if breakers == nil || job.ConnectionID == "" {
+		return nil
+	}
+	b, err := breakers.Get(ctx, job.ConnectionID)
+	if err != nil {
+		log.Printf("Failed to read circuit breaker of connection %s, running without it: %v", job.ConnectionID, err)
+		return nil
+	}
+	return b
+}
+
+// holdOnConnectionFailure counts a failed attempt against the job's
+// connection breaker and reports whether the execution should be paused
+// instead of retried or failed, because the breaker is open. The account is
+// notified when this failure opened it.
+func holdOnConnectionFailure(ctx context.Context, stores *database.Stores, breakers database.ConnectionBreakerStore, sqsClient *sqs.Client, job HelperExecutionJob, execErr error) bool {
+	if breakers == nil || job.ConnectionID == "" || !breaker.Counts(execErr) {
+		return false
+	}
+
+	held, opened, err := breaker.DefaultPolicy.RecordFailure(ctx, breakers, job.ConnectionID, job.AccountID, execErr, time.Now().UTC())
+	if err != nil {
+		log.Printf("Failed to count failure of connection %s: %v", job.ConnectionID, err)
+		return false
+	}
+	if opened {
+		log.Printf("Circuit breaker of connection %s opened: %v", job.ConnectionID, execErr)
+		sendConnectionIssue(ctx, stores, sqsClient, job, execErr.Error())
+	}
+	return held
+}
+
+// sendConnectionIssue tells the connection's owner that its executions are
+// paused until it works again
+func sendConnectionIssue(ctx context.Context, stores *database.Stores, sqsClient *sqs.Client, job HelperExecutionJob, errorMsg string) {
+	if notificationQueueURL == "" {
+		log.Printf("NOTIFICATION_QUEUE_URL not set, skipping connection issue notification")
+		return
+	}
+
+	userID, connectionName, platformName := job.UserID, job.ConnectionID, ""
+	if conn, err := stores.Connections.GetByID(ctx, job.ConnectionID); err == nil && conn != nil {
+		userID, connectionName = conn.UserID, conn.Name
+		if platform, err := stores.Platforms.GetByID(ctx, conn.PlatformID); err == nil && platform != nil {
+			platformName = platform.Name
+		}
+	}
+
+	notification := map[string]interface{}{
+		"type":       "connection_issue",
+		"user_id":    userID,
+		"account_id": job.AccountID,
+		"data": map[string]interface{}{
+			"connection_name": connectionName,
+			"connection_id":   job.ConnectionID,
+			"platform_name":   platformName,
+			"reason":          "circuit_open",
+			"error_message":   errorMsg,
+		},
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Failed to marshal notification: %v", err)
+		return
+	}
+
+	_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:       aws.String(notificationQueueURL),
+		MessageBody:    aws.String(string(body)),
+		MessageGroupId: aws.String(job.AccountID),
+	})
+	if err != nil {
+		log.Printf("Failed to send connection issue notification: %v", err)
+	} else {
+		log.Printf("Sent connection issue notification for connection %s", job.ConnectionID)
+	}
+}
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/myfusionhelper/api/internal/breaker"
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/database/memory"
+)
+
+func TestHoldOnConnectionFailure(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	job := HelperExecutionJob{ExecutionID: "exec:1", AccountID: "acc-1", ConnectionID: "conn:1"}
+	revoked := connectors.NewConnectorError("keap", 401, "token revoked", false)
+
+	if holdOnConnectionFailure(ctx, stores, stores.Breakers, nil, job, errors.New("missing config")) {
+		t.Error("expected helper errors not to hold the execution")
+	}
+	if holdOnConnectionFailure(ctx, stores, stores.Breakers, nil, HelperExecutionJob{ExecutionID: "exec:2"}, revoked) {
+		t.Error("expected executions without a connection not to be held")
+	}
+	if holdOnConnectionFailure(ctx, stores, nil, nil, job, revoked) {
+		t.Error("expected workers without a breakers table not to hold executions")
+	}
+
+	for i := 1; i < breaker.DefaultPolicy.Threshold; i++ {
+		if holdOnConnectionFailure(ctx, stores, stores.Breakers, nil, job, revoked) {
+			t.Fatalf("expected failure %d to fail normally", i)
+		}
+	}
+	if !holdOnConnectionFailure(ctx, stores, stores.Breakers, nil, job, revoked) {
+		t.Fatal("expected the failure reaching the threshold to hold the execution")
+	}
+	if b := loadConnectionBreaker(ctx, stores.Breakers, job); !breaker.Holds(b) {
+		t.Errorf("expected the connection's breaker open, got %+v", b)
+	}
+}
@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+// resultReserve is held back from the Lambda's remaining time so a job that
+// hits its deadline can still record its result before the invocation ends
+const resultReserve = 10 * time.Second
+
+// minJobTime is the least time a job is started with; records reached later
+// in a batch are handed back to SQS unprocessed
+const minJobTime = 5 * time.Second
+
+// jobContext derives a job's context from the invocation's, with a deadline
+// resultReserve before the Lambda's. Without a Lambda deadline it returns ctx.
+func jobContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline.Add(-resultReserve))
+}
+
+// hasTimeForJob reports whether enough of the invocation remains to start a job
+func hasTimeForJob(ctx context.Context, now time.Time) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || deadline.Sub(now) >= resultReserve+minJobTime
+}
@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestJobContext(t *testing.T) {
+	lambdaDeadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), lambdaDeadline)
+	defer cancel()
+
+	jobCtx, jobCancel := jobContext(ctx)
+	defer jobCancel()
+	deadline, ok := jobCtx.Deadline()
+	if !ok || !deadline.Equal(lambdaDeadline.Add(-resultReserve)) {
+		t.Errorf("expected job deadline %v, got %v", lambdaDeadline.Add(-resultReserve), deadline)
+	}
+
+	noDeadline, noCancel := jobContext(context.Background())
+	defer noCancel()
+	if _, ok := noDeadline.Deadline(); ok {
+		t.Error("expected no job deadline without a Lambda deadline")
+	}
+}
+
+func TestHasTimeForJob(t *testing.T) {
+	now := time.Now()
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(resultReserve+minJobTime))
+	defer cancel()
+	if !hasTimeForJob(ctx, now) {
+		t.Error("expected enough time at the threshold")
+	}
+	if hasTimeForJob(ctx, now.Add(time.Second)) {
+		t.Error("expected too little time past the threshold")
+	}
+	if !hasTimeForJob(context.Background(), now) {
+		t.Error("expected time for jobs without a Lambda deadline")
+	}
+}
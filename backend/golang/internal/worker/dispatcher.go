@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	"github.com/myfusionhelper/api/internal/database"
+	helperEngine "github.com/myfusionhelper/api/internal/helpers"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+// Delivery statuses recorded on the execution record for each dispatched action
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+	DeliverySkipped   = "skipped"
+)
+
+// ErrActionSkipped is returned (optionally wrapped) by an action handler when
+// the action cannot be delivered for a non-error reason, e.g. a missing
+// recipient or an unsupported CRM capability.
+var ErrActionSkipped = errors.New("action skipped")
+
+// ActionContext carries everything an action handler needs to deliver a
+// queued post-execution action for a single job.
+type ActionContext struct {
+	Stores       *database.Stores
+	Job          HelperExecutionJob
+	Connector    connectors.CRMConnector
+	ServiceAuths map[string]*connectors.ConnectorConfig
+}
+
+// ActionResult describes a successful delivery
+type ActionResult struct {
+	StatusCode int
+	Detail     string
+}
+
+// ActionHandler delivers a single post-execution action
+type ActionHandler func(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error)
+
+// Dispatcher routes post-execution actions to the handler registered for
+// their type. Actions without a registered handler are informational
+// (tag_applied, field_updated, ...) and are ignored.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	handlers map[string]ActionHandler
+}
+
+// NewDispatcher creates a dispatcher with the built-in action handlers registered
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{
+		handlers: make(map[string]ActionHandler),
+	}
+	d.Register("webhook_queued", handleWebhookAction)
+	d.Register("email_queued", handleEmailAction)
+	d.Register("notification_queued", handleNotificationAction)
+	d.Register("export_queued", handleExportAction)
+	d.Register("google_sheet_sync_queued", handleGoogleSheetSync)
+	d.Register("execution_scheduled", handleExecutionScheduled)
+	return d
+}
+
+// Register adds or replaces the handler for an action type
+func (d *Dispatcher) Register(actionType string, handler ActionHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[actionType] = handler
+}
+
+// Handles reports whether a handler is registered for the action type
+func (d *Dispatcher) Handles(actionType string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.handlers[actionType]
+	return ok
+}
+
+// Dispatch delivers every action that has a registered handler and returns
+// one delivery record per dispatched action. A failing action never stops
+// the remaining actions from being delivered.
+func (d *Dispatcher) Dispatch(ctx context.Context, actx *ActionContext, actions []helperEngine.HelperAction) []apitypes.ActionDelivery {
+	var deliveries []apitypes.ActionDelivery
+
+	for _, action := range actions {
+		d.mu.RLock()
+		handler, ok := d.handlers[action.Type]
+		d.mu.RUnlock()
+
+		if !ok {
+			if strings.HasSuffix(action.Type, "_queued") {
+				log.Printf("Skipping unknown post-execution action type: %s", action.Type)
+			}
+			continue
+		}
+
+		deliveries = append(deliveries, d.deliver(ctx, actx, action, handler))
+	}
+
+	return deliveries
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction, handler ActionHandler) (delivery apitypes.ActionDelivery) {
+	start := time.Now()
+	delivery = apitypes.ActionDelivery{
+		Type:   action.Type,
+		Target: redactTarget(action.Target),
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Action %s for execution %s panicked: %v", action.Type, actx.Job.ExecutionID, r)
+			delivery.Status = DeliveryFailed
+			delivery.Error = fmt.Sprintf("panic: %v", r)
+		}
+		delivery.DurationMs = time.Since(start).Milliseconds()
+		delivery.DeliveredAt = time.Now().UTC().Format(time.RFC3339)
+	}()
+
+	result, err := handler(ctx, actx, action)
+	if result != nil {
+		delivery.StatusCode = result.StatusCode
+		delivery.Detail = result.Detail
+	}
+
+	switch {
+	case errors.Is(err, ErrActionSkipped):
+		delivery.Status = DeliverySkipped
+		delivery.Error = err.Error()
+		log.Printf("Action %s for execution %s skipped: %v", action.Type, actx.Job.ExecutionID, err)
+	case err != nil:
+		delivery.Status = DeliveryFailed
+		delivery.Error = err.Error()
+		log.Printf("Action %s for execution %s failed: %v", action.Type, actx.Job.ExecutionID, err)
+	default:
+		delivery.Status = DeliveryDelivered
+		log.Printf("Action %s for execution %s delivered", action.Type, actx.Job.ExecutionID)
+	}
+
+	return delivery
+}
+
+// redactTarget strips query strings from URL targets so API keys passed as
+// query parameters are not persisted on the execution record.
+func redactTarget(target string) string {
+	if i := strings.Index(target, "?"); i >= 0 && strings.HasPrefix(target, "http") {
+		return target[:i]
+	}
+	return target
+}
@@ -0,0 +1,208 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database/memory"
+	helperEngine "github.com/myfusionhelper/api/internal/helpers"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+func TestDispatcher_WebhookDelivered(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher()
+	actx := &ActionContext{Job: HelperExecutionJob{ExecutionID: "exec:test"}}
+	deliveries := d.Dispatch(context.Background(), actx, []helperEngine.HelperAction{
+		{Type: "tag_applied", Target: "123"},
+		{Type: "webhook_queued", Target: server.URL + "?api_key=secret", Value: map[string]interface{}{
+			"url":     server.URL + "?api_key=secret",
+			"payload": map[string]interface{}{"email": "jane@example.com"},
+		}},
+	})
+
+	if len(deliveries) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(deliveries))
+	}
+	if deliveries[0].Status != DeliveryDelivered {
+		t.Errorf("expected delivered, got %s (%s)", deliveries[0].Status, deliveries[0].Error)
+	}
+	if deliveries[0].StatusCode != http.StatusAccepted {
+		t.Errorf("expected status 202, got %d", deliveries[0].StatusCode)
+	}
+	if deliveries[0].Target != server.URL {
+		t.Errorf("expected query string to be redacted, got %s", deliveries[0].Target)
+	}
+	if received["email"] != "jane@example.com" {
+		t.Errorf("expected payload to be posted, got %v", received)
+	}
+}
+
+func TestDispatcher_WebhookFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher()
+	deliveries := d.Dispatch(context.Background(), &ActionContext{}, []helperEngine.HelperAction{
+		{Type: "webhook_queued", Target: server.URL, Value: map[string]interface{}{"url": server.URL}},
+	})
+
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryFailed {
+		t.Fatalf("expected a failed delivery, got %+v", deliveries)
+	}
+	if deliveries[0].StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", deliveries[0].StatusCode)
+	}
+}
+
+func TestDispatcher_WebhookErrorRedactsQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target := server.URL + "/hook?token=secret"
+	server.Close()
+
+	d := NewDispatcher()
+	deliveries := d.Dispatch(context.Background(), &ActionContext{}, []helperEngine.HelperAction{
+		{Type: "webhook_queued", Target: target, Value: map[string]interface{}{"url": target}},
+	})
+
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryFailed {
+		t.Fatalf("expected a failed delivery, got %+v", deliveries)
+	}
+	if strings.Contains(deliveries[0].Error, "secret") || !strings.Contains(deliveries[0].Error, server.URL+"/hook") {
+		t.Errorf("expected the error to quote the URL without its query, got %q", deliveries[0].Error)
+	}
+}
+
+func TestDispatcher_WebhookAuth(t *testing.T) {
+	var authorization []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	webhook := func(spec map[string]interface{}) helperEngine.HelperAction {
+		spec["url"] = server.URL
+		return helperEngine.HelperAction{Type: "webhook_queued", Target: server.URL, Value: spec}
+	}
+	d := NewDispatcher()
+	deliveries := d.Dispatch(context.Background(), &ActionContext{}, []helperEngine.HelperAction{
+		webhook(map[string]interface{}{"auth_type": "bearer", "auth_token": "t1"}),
+		webhook(map[string]interface{}{"auth_type": "bearer", "headers": map[string]interface{}{"Authorization": "Bearer t2"}}),
+		webhook(map[string]interface{}{"auth_type": "api_key_in_body", "payload": map[string]interface{}{"api_key": "k1"}}),
+		webhook(map[string]interface{}{"auth_type": "bearer"}),
+		webhook(map[string]interface{}{"auth_type": "api_key_in_body", "payload": map[string]interface{}{"email": "jane@example.com"}}),
+		webhook(map[string]interface{}{"auth_type": "hmac"}),
+	})
+
+	var statuses []string
+	for _, delivery := range deliveries {
+		statuses = append(statuses, delivery.Status)
+	}
+	want := fmt.Sprint([]string{DeliveryDelivered, DeliveryDelivered, DeliveryDelivered, DeliveryFailed, DeliveryFailed, DeliveryFailed})
+	if got := fmt.Sprint(statuses); got != want {
+		t.Fatalf("expected %s, got %s (%+v)", want, got, deliveries)
+	}
+	if got := fmt.Sprint(authorization); got != "[Bearer t1 Bearer t2 ]" {
+		t.Errorf("expected only the authenticated requests to be sent, got %q", got)
+	}
+}
+
+func TestDispatcher_SlackChannelRecipient(t *testing.T) {
+	d := NewDispatcher()
+	deliveries := d.Dispatch(context.Background(), &ActionContext{}, []helperEngine.HelperAction{
+		{Type: "notification_queued", Target: "slack", Value: map[string]interface{}{
+			"channel":   "slack",
+			"recipient": "#sales",
+			"message":   "New lead",
+		}},
+	})
+
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryFailed || !strings.Contains(deliveries[0].Error, "not an incoming webhook URL") {
+		t.Errorf("expected a channel name to fail the delivery, got %+v", deliveries)
+	}
+}
+
+func TestDispatcher_SkippedAndPanics(t *testing.T) {
+	d := &Dispatcher{handlers: make(map[string]ActionHandler)}
+	d.Register("skip_queued", func(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error) {
+		return nil, fmt.Errorf("no recipient: %w", ErrActionSkipped)
+	})
+	d.Register("panic_queued", func(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error) {
+		panic("boom")
+	})
+
+	deliveries := d.Dispatch(context.Background(), &ActionContext{}, []helperEngine.HelperAction{
+		{Type: "skip_queued"},
+		{Type: "panic_queued"},
+		{Type: "unknown_queued"},
+	})
+
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(deliveries))
+	}
+	if deliveries[0].Status != DeliverySkipped {
+		t.Errorf("expected skipped, got %s", deliveries[0].Status)
+	}
+	if deliveries[1].Status != DeliveryFailed || deliveries[1].Error != "panic: boom" {
+		t.Errorf("expected recovered panic, got %+v", deliveries[1])
+	}
+}
+
+func TestDispatcher_ExecutionScheduled(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Helpers.Create(ctx, &apitypes.Helper{HelperID: "helper:drip", AccountID: "acc-1", HelperType: "drip_it", Enabled: true})
+	stores.Helpers.Create(ctx, &apitypes.Helper{HelperID: "helper:other", AccountID: "acc-2", HelperType: "tag_it", Enabled: true})
+
+	d := NewDispatcher()
+	actx := &ActionContext{Stores: stores, Job: HelperExecutionJob{
+		ExecutionID: "exec:step-1", HelperID: "helper:drip", AccountID: "acc-1", UserID: "user-1", ContactID: "42",
+	}}
+	scheduled := helperEngine.HelperAction{Type: "execution_scheduled", Value: map[string]interface{}{"delay_seconds": float64(3600)}}
+
+	deliveries := d.Dispatch(ctx, actx, []helperEngine.HelperAction{scheduled})
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryDelivered {
+		t.Fatalf("expected a delivered action, got %+v", deliveries)
+	}
+	pending, _, _ := stores.Delayed.ListByContact(ctx, "acc-1", "42", "pending", 10, "")
+	if len(pending) != 1 || pending[0].HelperID != "helper:drip" || pending[0].ScheduledBy != "exec:step-1" || pending[0].TriggerType != "helper" {
+		t.Fatalf("expected the drip helper scheduled for contact 42, got %+v", pending)
+	}
+	if runAt, _ := time.Parse(time.RFC3339, pending[0].RunAt); time.Until(runAt) < 59*time.Minute {
+		t.Errorf("expected run_at an hour out, got %s", pending[0].RunAt)
+	}
+
+	// A redelivered job does not schedule the next step twice
+	d.Dispatch(ctx, actx, []helperEngine.HelperAction{scheduled})
+	if pending, _, _ := stores.Delayed.ListByContact(ctx, "acc-1", "42", "", 10, ""); len(pending) != 1 {
+		t.Errorf("expected 1 delayed execution after redelivery, got %d", len(pending))
+	}
+
+	deliveries = d.Dispatch(ctx, actx, []helperEngine.HelperAction{
+		{Type: "execution_scheduled", Target: "helper:other", Value: map[string]interface{}{"delay_seconds": float64(60)}},
+		{Type: "execution_scheduled", Value: map[string]interface{}{}},
+	})
+	if deliveries[0].Status != DeliverySkipped {
+		t.Errorf("expected another account's helper skipped, got %s", deliveries[0].Status)
+	}
+	if deliveries[1].Status != DeliveryFailed {
+		t.Errorf("expected an action without a delay failed, got %s", deliveries[1].Status)
+	}
+}
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
+	helperEngine "github.com/myfusionhelper/api/internal/helpers"
+)
+
+// handBack reports whether a record can go back to SQS without running its
+// execution, say because its contact is busy. The receive is recorded on the
+// execution so it does not count as an attempt (see attemptNumber).
+//
+// On the record's final receive SQS would move it to the DLQ and leave the
+// execution unfinished for good, so the execution is dead-lettered instead,
+// to be replayed, and the record acknowledged.
+func handBack(ctx context.Context, stores *database.Stores, sqsClient *sqs.Client, record events.SQSMessage, job HelperExecutionJob, reason string) bool {
+	if job.ExecutionID == "" {
+		return true
+	}
+
+	if !finalReceive(record) {
+		if err := stores.Executions.RecordHandback(ctx, job.ExecutionID, record.MessageId); err != nil {
+			log.Printf("Failed to record hand-back of execution %s: %v", job.ExecutionID, err)
+		}
+		return true
+	}
+
+	errMsg := fmt.Sprintf("handed back on all %d deliveries: %s", receiveCount(record), reason)
+	log.Printf("Execution %s was %s, dead-lettering it", job.ExecutionID, errMsg)
+	now := time.Now().UTC()
+	if updateExecutionResult(ctx, stores, job.ExecutionID, execution.StatusDeadLettered, helperEngine.ErrCodeTimeout, errMsg, nil, &now) {
+		sendFailureNotification(ctx, sqsClient, job, errMsg)
+		reportOutcome(ctx, stores, job, nil, errMsg)
+	}
+	return false
+}
+
+// recordJob decodes the job of a record handed back before it is handled. A
+// malformed body gives an empty job.
+func recordJob(record events.SQSMessage) HelperExecutionJob {
+	var job HelperExecutionJob
+	_ = json.Unmarshal([]byte(record.Body), &job)
+	return job
+}
@@ -6,29 +6,45 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 
+	"github.com/myfusionhelper/api/internal/batch"
 	"github.com/myfusionhelper/api/internal/billing"
+	"github.com/myfusionhelper/api/internal/breaker"
 	"github.com/myfusionhelper/api/internal/connectors"
 	"github.com/myfusionhelper/api/internal/connectors/loader"
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
 	"github.com/myfusionhelper/api/internal/google"
 	helperEngine "github.com/myfusionhelper/api/internal/helpers"
 	stripeusage "github.com/myfusionhelper/api/internal/stripe"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+	"github.com/myfusionhelper/api/internal/workflow"
 )
 
+// executionRetention matches the TTL the execute endpoint sets on executions
+const executionRetention = 7 * 24 * time.Hour
+
 var (
-	executionsTable      = os.Getenv("EXECUTIONS_TABLE")
-	helpersTable         = os.Getenv("HELPERS_TABLE")
 	notificationQueueURL = os.Getenv("NOTIFICATION_QUEUE_URL")
+
+	// actionDispatcher delivers queued post-execution actions. Worker mains may
+	// register additional handlers on it before starting the Lambda.
+	actionDispatcher = NewDispatcher()
 )
 
+// RegisterActionHandler adds a post-execution action handler to the worker's dispatcher
+func RegisterActionHandler(actionType string, handler ActionHandler) {
+	actionDispatcher.Register(actionType, handler)
+}
+
 // HelperExecutionJob represents a job from the SQS queue.
 // All fields are populated at execution time by the execute endpoint
 // and forwarded through the DynamoDB Stream → Stream Router → SQS pipeline.
@@ -45,93 +61,279 @@ type HelperExecutionJob struct {
 	QueryParams  map[string]string      `json:"query_params"`
 	APIKey       string                 `json:"api_key"`
 	RetryCount   int                    `json:"retry_count"`
+	// Set on executions dispatched for a workflow step
+	WorkflowRunID  string `json:"workflow_run_id,omitempty"`
+	WorkflowStepID string `json:"workflow_step_id,omitempty"`
+	// Set on executions dispatched for a batch
+	BatchID string `json:"batch_id,omitempty"`
+}
+
+// Worker runs helper execution jobs against a set of stores. SQS sends
+// failure notifications and delays redeliveries; it is not used when
+// NOTIFICATION_QUEUE_URL is unset and records carry no queue ARN, so
+// in-process runs can leave it nil.
+type Worker struct {
+	Stores *database.Stores
+	SQS    *sqs.Client
+}
+
+// NewWorker creates a worker on stores. Circuit breakers and contact leases
+// are turned off for workers not configured with their tables, without
+// changing stores.
+func NewWorker(stores *database.Stores, sqsClient *sqs.Client) *Worker {
+	own := *stores
+	if os.Getenv("CONNECTION_BREAKERS_TABLE") == "" {
+		own.Breakers = nil
+	}
+	if os.Getenv("RATE_LIMITS_TABLE") == "" {
+		own.Leases = nil
+	}
+	return &Worker{Stores: &own, SQS: sqsClient}
 }
 
 // HandleSQSEvent processes SQS messages containing helper execution jobs.
 // This is the shared handler used by all individual helper worker Lambdas.
-func HandleSQSEvent(ctx context.Context, event events.SQSEvent) error {
-	log.Printf("Processing %d SQS messages", len(event.Records))
-
+func HandleSQSEvent(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(os.Getenv("COGNITO_REGION")))
 	if err != nil {
 		log.Printf("Failed to load AWS config: %v", err)
-		return err
+		return events.SQSEventResponse{}, err
 	}
-	db := dynamodb.NewFromConfig(cfg)
-	sqsClient := sqs.NewFromConfig(cfg)
+	stores := database.NewDynamoStoresFromEnv(dynamodb.NewFromConfig(cfg))
+	return NewWorker(stores, sqs.NewFromConfig(cfg)).HandleSQSEvent(ctx, event)
+}
+
+// HandleSQSEvent processes a batch of helper execution jobs. Records that
+// failed with a retryable error are reported as batch item failures so SQS
+// redelivers them once their visibility timeout expires. Each job runs with
+// a deadline short of the Lambda's and a panic fails only its own execution;
+// records left when the invocation runs low on time are handed back
+// unprocessed.
+func (w *Worker) HandleSQSEvent(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	log.Printf("Processing %d SQS messages", len(event.Records))
+
+	var response events.SQSEventResponse
+
+	// FIFO ordering: once a record is handed back, later records in the same
+	// message group must be handed back too, unprocessed
+	failedGroups := make(map[string]bool)
 
 	for _, record := range event.Records {
-		var job HelperExecutionJob
-		if err := json.Unmarshal([]byte(record.Body), &job); err != nil {
-			log.Printf("Failed to unmarshal SQS message: %v", err)
+		groupID := record.Attributes["MessageGroupId"]
+		if groupID != "" && failedGroups[groupID] {
+			if handBack(ctx, w.Stores, w.SQS, record, recordJob(record), "an earlier message of its group was handed back") {
+				response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+			}
 			continue
 		}
-
-		log.Printf("Processing execution %s (helper: %s, type: %s)", job.ExecutionID, job.HelperID, job.HelperType)
-
-		// Check execution limit for sandbox (free) accounts
-		accountsTable := os.Getenv("ACCOUNTS_TABLE")
-		if accountsTable != "" {
-			if err := billing.CheckExecutionLimit(ctx, db, accountsTable, job.AccountID); err != nil {
-				if limitErr, ok := err.(*billing.LimitExceededError); ok {
-					log.Printf("Execution %s blocked: %s", job.ExecutionID, limitErr.Message)
-					updateExecutionResult(ctx, db, job.ExecutionID, "failed", limitErr.Message, nil, nil)
-					continue
+		if !hasTimeForJob(ctx, time.Now()) {
+			log.Printf("Not enough time left in invocation, handing back message %s", record.MessageId)
+			if handBack(ctx, w.Stores, w.SQS, record, recordJob(record), "not enough time left in the worker invocation") {
+				response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+				if groupID != "" {
+					failedGroups[groupID] = true
 				}
 			}
+			continue
 		}
 
-		// Update execution status to running
-		updateExecutionStatus(ctx, db, job.ExecutionID, "running", "", 0)
-
-		// Execute the helper
-		result, execErr := processJob(ctx, db, job)
-
-		// Update execution record with results
-		now := time.Now().UTC()
-		if execErr != nil {
-			log.Printf("Execution %s failed: %v", job.ExecutionID, execErr)
-			updateExecutionResult(ctx, db, job.ExecutionID, "failed", execErr.Error(), result, &now)
-			sendFailureNotification(ctx, sqsClient, job, execErr.Error())
-		} else if result != nil && result.Success {
-			log.Printf("Execution %s completed successfully", job.ExecutionID)
-			updateExecutionResult(ctx, db, job.ExecutionID, "completed", "", result, &now)
-			// Increment account-level execution count (best-effort)
-			if accountsTable != "" {
-				billing.IncrementUsage(ctx, db, accountsTable, job.AccountID, "monthly_executions", 1)
-			}
-			// Report usage to Stripe (best-effort, non-blocking)
-			go stripeusage.ReportExecution(ctx, db, job.ExecutionID, job.AccountID, now.Unix())
-		} else {
-			errMsg := "execution returned unsuccessful result"
-			if result != nil && result.Error != "" {
-				errMsg = result.Error
+		retryDelay, retry := handleRecord(ctx, w.Stores, w.SQS, record)
+		if !retry {
+			continue
+		}
+
+		response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+		if groupID != "" {
+			failedGroups[groupID] = true
+		}
+		delayRedelivery(ctx, w.SQS, record, retryDelay)
+	}
+
+	return response, nil
+}
+
+// handleRecord runs one job. It returns retry=true when the record should be
+// redelivered after retryDelay.
+func handleRecord(ctx context.Context, stores *database.Stores, sqsClient *sqs.Client, record events.SQSMessage) (retryDelay time.Duration, retry bool) {
+	var job HelperExecutionJob
+	if err := json.Unmarshal([]byte(record.Body), &job); err != nil {
+		// A malformed message fails the same way on every delivery
+		log.Printf("Failed to unmarshal SQS message: %v", err)
+		return 0, false
+	}
+
+	// Panics outside the executor (connector loading, post-execution actions)
+	// fail this execution instead of the whole batch
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := helperEngine.PanicError(r)
+			log.Printf("Execution %s panicked: %v\n%s", job.ExecutionID, r, panicErr.Stack)
+			now := time.Now().UTC()
+			result := &helperEngine.ExecutionResult{
+				HelperType: job.HelperType,
+				ContactID:  job.ContactID,
+				Error:      panicErr.Error(),
+				ErrorCode:  panicErr.Code,
+				ErrorStack: panicErr.Stack,
 			}
-			log.Printf("Execution %s completed with errors: %s", job.ExecutionID, errMsg)
-			updateExecutionResult(ctx, db, job.ExecutionID, "failed", errMsg, result, &now)
-			sendFailureNotification(ctx, sqsClient, job, errMsg)
+			updateExecutionResult(ctx, stores, job.ExecutionID, execution.StatusFailed, panicErr.Code, panicErr.Error(), result, &now)
+			reportOutcome(ctx, stores, job, nil, panicErr.Error())
+			updateHelperStats(ctx, stores, job.HelperID, &now)
+			retryDelay, retry = 0, false
+		}
+	}()
+
+	// Cancelled and finished executions are acknowledged without running
+	exec, ok := runnable(ctx, stores, job)
+	if !ok {
+		return 0, false
+	}
+
+	attempt := attemptNumber(record, job, exec)
+	policy := RetryPolicyFor(job.HelperType)
+
+	log.Printf("Processing execution %s (helper: %s, type: %s, attempt: %d)", job.ExecutionID, job.HelperID, job.HelperType, attempt)
+
+	// Check execution limit for sandbox (free) accounts
+	if err := billing.CheckExecutionLimit(ctx, stores.Accounts, job.AccountID); err != nil {
+		if limitErr, ok := err.(*billing.LimitExceededError); ok {
+			log.Printf("Execution %s blocked: %s", job.ExecutionID, limitErr.Message)
+			now := time.Now().UTC()
+			updateExecutionResult(ctx, stores, job.ExecutionID, execution.StatusFailed, helperEngine.ErrCodeLimit, limitErr.Message, nil, &now)
+			reportOutcome(ctx, stores, job, nil, limitErr.Message)
+			return 0, false
+		}
+	}
+
+	// Executions of a connection whose circuit breaker is open are held
+	// until the connection prober finds it healthy again
+	breakers := stores.Breakers
+	connBreaker := loadConnectionBreaker(ctx, breakers, job)
+	if breaker.Holds(connBreaker) {
+		log.Printf("Circuit breaker of connection %s is open, pausing execution %s", job.ConnectionID, job.ExecutionID)
+		transitionExecution(ctx, stores, job.ExecutionID, execution.StatusPaused, connectionPausedReason)
+		return 0, false
+	}
+
+	// Executions for the same contact run one at a time, whichever queue
+	// they came from
+	release, acquired := acquireContactLease(ctx, stores.Leases, job)
+	if !acquired {
+		log.Printf("Contact %s is busy with another execution, handing back execution %s", job.ContactID, job.ExecutionID)
+		if !handBack(ctx, stores, sqsClient, record, job, "contact busy with another execution") {
+			return 0, false
+		}
+		return contactLeaseRetryDelay, true
+	}
+	defer release()
+
+	// Update execution status to running, unless it was cancelled meanwhile
+	if !startExecutionAttempt(ctx, stores, job.ExecutionID, attempt) {
+		return 0, false
+	}
+
+	// Execute the helper. Results are recorded on the invocation's context, so
+	// they are still written when the job's own deadline has passed.
+	trace := connectors.NewTrace()
+	jobCtx, cancel := jobContext(connectors.WithTrace(ctx, trace))
+	result, execErr := processJob(jobCtx, stores, job)
+	cancel()
+	updateConnectorTrace(ctx, stores, job.ExecutionID, trace)
+
+	// Update execution record with results
+	now := time.Now().UTC()
+	if execErr != nil {
+		// Failures of a connection that keeps failing pause the execution
+		// rather than burning it
+		if holdOnConnectionFailure(ctx, stores, breakers, sqsClient, job, execErr) {
+			log.Printf("Execution %s attempt %d failed on an open circuit, pausing: %v", job.ExecutionID, attempt, execErr)
+			recordRetryAttempt(ctx, stores, job.ExecutionID, apitypes.RetryAttempt{
+				Attempt:   attempt,
+				Error:     execErr.Error(),
+				ErrorCode: helperEngine.ClassifyError(execErr),
+				FailedAt:  now.Format(time.RFC3339),
+			})
+			transitionExecution(ctx, stores, job.ExecutionID, execution.StatusPaused, connectionPausedReason)
+			return 0, false
 		}
 
-		// Update helper execution count
-		updateHelperStats(ctx, db, job.HelperID, &now)
+		// A record on its final receive cannot be redelivered
+		if policy.ShouldRetry(execErr, attempt) && !finalReceive(record) {
+			retryDelay = policy.Delay(attempt)
+			log.Printf("Execution %s attempt %d/%d failed, retrying in %v: %v", job.ExecutionID, attempt, policy.MaxAttempts, retryDelay, execErr)
+			recordRetryAttempt(ctx, stores, job.ExecutionID, apitypes.RetryAttempt{
+				Attempt:   attempt,
+				Error:     execErr.Error(),
+				ErrorCode: helperEngine.ClassifyError(execErr),
+				FailedAt:  now.Format(time.RFC3339),
+				RetryAt:   now.Add(retryDelay).Format(time.RFC3339),
+			})
+			updateExecutionStatus(ctx, stores, job.ExecutionID, execution.StatusRetrying, execErr.Error())
+			return retryDelay, true
+		}
+
+		// Transient failures that ran out of attempts can be replayed later
+		status := execution.StatusFailed
+		if IsRetryable(execErr) {
+			status = execution.StatusDeadLettered
+		}
+		log.Printf("Execution %s failed after %d attempt(s): %v", job.ExecutionID, attempt, execErr)
+		errCode := helperEngine.ClassifyError(execErr)
+		recordRetryAttempt(ctx, stores, job.ExecutionID, apitypes.RetryAttempt{
+			Attempt:   attempt,
+			Error:     execErr.Error(),
+			ErrorCode: errCode,
+			FailedAt:  now.Format(time.RFC3339),
+		})
+		updateExecutionResult(ctx, stores, job.ExecutionID, status, errCode, execErr.Error(), result, &now)
+		sendFailureNotification(ctx, sqsClient, job, execErr.Error())
+		reportOutcome(ctx, stores, job, result, execErr.Error())
+	} else if result != nil && result.Success {
+		log.Printf("Execution %s completed successfully", job.ExecutionID)
+		updateExecutionResult(ctx, stores, job.ExecutionID, execution.StatusSucceeded, "", "", result, &now)
+		if err := breaker.RecordSuccess(ctx, breakers, connBreaker, now); err != nil {
+			log.Printf("Execution %s: %v", job.ExecutionID, err)
+		}
+		// Increment account-level execution count (best-effort)
+		if _, err := stores.Accounts.IncrementMonthlyExecutions(ctx, job.AccountID, 1); err != nil {
+			log.Printf("Failed to increment monthly executions of account %s: %v", job.AccountID, err)
+		}
+		// Report usage to Stripe (best-effort, non-blocking)
+		go stripeusage.ReportExecution(ctx, stores, job.ExecutionID, job.AccountID, now.Unix())
+		reportOutcome(ctx, stores, job, result, "")
+	} else {
+		errMsg := "execution returned unsuccessful result"
+		if result != nil && result.Error != "" {
+			errMsg = result.Error
+		}
+		errCode := helperEngine.ErrCodeHelper
+		if result != nil && result.ErrorCode != "" {
+			errCode = result.ErrorCode
+		}
+		log.Printf("Execution %s completed with errors: %s", job.ExecutionID, errMsg)
+		updateExecutionResult(ctx, stores, job.ExecutionID, execution.StatusFailed, errCode, errMsg, result, &now)
+		sendFailureNotification(ctx, sqsClient, job, errMsg)
+		reportOutcome(ctx, stores, job, result, errMsg)
 	}
 
-	return nil
+	// Update helper execution count once the execution reaches a final status
+	updateHelperStats(ctx, stores, job.HelperID, &now)
+	return 0, false
 }
 
-func processJob(ctx context.Context, db *dynamodb.Client, job HelperExecutionJob) (*helperEngine.ExecutionResult, error) {
+func processJob(ctx context.Context, stores *database.Stores, job HelperExecutionJob) (*helperEngine.ExecutionResult, error) {
 	// Load CRM connector if connection ID is specified
 	var connector connectors.CRMConnector
 	if job.ConnectionID != "" {
 		var err error
-		connector, err = loader.LoadConnectorWithTranslation(ctx, db, job.ConnectionID, job.AccountID)
+		connector, err = loader.LoadConnectorWithTranslation(ctx, stores, job.ConnectionID, job.AccountID)
 		if err != nil {
-			return nil, err
+			return nil, helperEngine.NewExecutionError(helperEngine.ErrCodeCRM, err)
 		}
 	}
 
 	// Pre-load service connection credentials (for non-CRM integrations like Zoom, Trello, etc.)
-	serviceAuths := loadServiceAuths(ctx, db, job.Config, job.AccountID)
+	serviceAuths := loadServiceAuths(ctx, stores, job.Config, job.AccountID)
 
 	// Execute via the helper engine
 	executor := helperEngine.NewExecutor()
@@ -147,19 +349,91 @@ func processJob(ctx context.Context, db *dynamodb.Client, job HelperExecutionJob
 		ConnectionID: job.ConnectionID,
 		ServiceAuths: serviceAuths,
 		APIKey:       job.APIKey,
+		Stores:       stores,
 	}
 
 	result, err := executor.Execute(ctx, execReq, connector)
 
-	// Process post-execution actions (e.g., google_sheet_sync_queued)
+	// Deliver queued post-execution actions (webhooks, emails, notifications, exports)
 	if err == nil && result != nil && result.Output != nil && len(result.Output.Actions) > 0 {
-		processPostExecutionActions(ctx, db, result.Output.Actions, job, connector, serviceAuths)
+		processPostExecutionActions(ctx, stores, result.Output.Actions, job, connector, serviceAuths)
+	}
+
+	// Helpers such as chain_it hand back a workflow to run after they complete
+	if err == nil && result != nil && result.Success && result.Output != nil {
+		if def, ok := result.Output.ModifiedData[workflow.OutputKey].(*workflow.Definition); ok {
+			run, startErr := workflow.StartRun(ctx, stores, *def, workflow.Trigger{
+				AccountID:   job.AccountID,
+				UserID:      job.UserID,
+				ContactID:   job.ContactID,
+				HelperID:    job.HelperID,
+				ExecutionID: job.ExecutionID,
+				Input:       job.Input,
+			})
+			if startErr != nil {
+				return result, fmt.Errorf("failed to start workflow run: %w", startErr)
+			}
+			result.Output.ModifiedData["workflow_run_id"] = run.RunID
+		}
 	}
 
 	return result, err
 }
 
-func loadServiceAuths(ctx context.Context, db *dynamodb.Client, cfg map[string]interface{}, accountID string) map[string]*connectors.ConnectorConfig {
+// reportOutcome passes the final outcome of an execution to the workflow run
+// or batch that dispatched it. errMsg is empty on success.
+func reportOutcome(ctx context.Context, stores *database.Stores, job HelperExecutionJob, result *helperEngine.ExecutionResult, errMsg string) {
+	completeWorkflowStep(ctx, stores, job, result, errMsg)
+	completeBatchItem(ctx, stores, job, errMsg)
+}
+
+// ReportCancelled reports a cancelled execution to the workflow run or batch
+// that dispatched it as failed with reason. The worker never runs a
+// cancelled execution, so its outcome would otherwise never be reported.
+func ReportCancelled(ctx context.Context, stores *database.Stores, exec *apitypes.Execution, reason string) {
+	job := HelperExecutionJob{
+		ExecutionID:    exec.ExecutionID,
+		ContactID:      exec.ContactID,
+		WorkflowRunID:  exec.WorkflowRunID,
+		WorkflowStepID: exec.WorkflowStepID,
+		BatchID:        exec.BatchID,
+	}
+	reportOutcome(ctx, stores, job, nil, reason)
+}
+
+// completeBatchItem records the contact's result on its batch. Redelivered
+// results are counted once.
+func completeBatchItem(ctx context.Context, stores *database.Stores, job HelperExecutionJob, errMsg string) {
+	if job.BatchID == "" {
+		return
+	}
+
+	status := batch.ItemCompleted
+	if errMsg != "" {
+		status = batch.ItemFailed
+	}
+	if _, err := stores.Batches.CompleteItem(ctx, job.BatchID, job.ContactID, status, errMsg, time.Now().UTC()); err != nil {
+		log.Printf("Failed to record batch %s result for contact %s: %v", job.BatchID, job.ContactID, err)
+	}
+}
+
+// completeWorkflowStep reports the final outcome of a workflow step's
+// execution to its run, which dispatches the steps that depend on it
+func completeWorkflowStep(ctx context.Context, stores *database.Stores, job HelperExecutionJob, result *helperEngine.ExecutionResult, errMsg string) {
+	if job.WorkflowRunID == "" {
+		return
+	}
+
+	stepResult := workflow.StepResult{Success: errMsg == "", Error: errMsg}
+	if result != nil && result.Output != nil {
+		stepResult.Output = result.Output.ModifiedData
+	}
+	if _, err := workflow.CompleteStep(ctx, stores, job.WorkflowRunID, job.WorkflowStepID, stepResult); err != nil {
+		log.Printf("Failed to advance workflow run %s after step %s: %v", job.WorkflowRunID, job.WorkflowStepID, err)
+	}
+}
+
+func loadServiceAuths(ctx context.Context, stores *database.Stores, cfg map[string]interface{}, accountID string) map[string]*connectors.ConnectorConfig {
 	raw, ok := cfg["service_connection_ids"]
 	if !ok {
 		return nil
@@ -177,7 +451,7 @@ func loadServiceAuths(ctx context.Context, db *dynamodb.Client, cfg map[string]i
 			continue
 		}
 
-		auth, err := loader.LoadServiceAuth(ctx, db, connID, accountID)
+		auth, err := loader.LoadServiceAuth(ctx, stores, connID, accountID)
 		if err != nil {
 			log.Printf("Warning: failed to load service auth for %s (connection %s): %v", slug, connID, err)
 			continue
@@ -191,74 +465,169 @@ func loadServiceAuths(ctx context.Context, db *dynamodb.Client, cfg map[string]i
 	return auths
 }
 
-func updateExecutionStatus(ctx context.Context, db *dynamodb.Client, executionID, status, errorMsg string, durationMs int64) {
-	updateExpr := "SET #s = :status"
-	exprNames := map[string]string{"#s": "status"}
-	exprValues := map[string]ddbtypes.AttributeValue{
-		":status": &ddbtypes.AttributeValueMemberS{Value: status},
+// runnable reports whether the job's execution should run, and returns the
+// execution as read. Executions that were cancelled or already finished, say
+// on a redelivery, are not; steps of a cancelled workflow run are marked
+// skipped instead. An execution that cannot be read is run, since starting
+// it is a conditional write anyway.
+func runnable(ctx context.Context, stores *database.Stores, job HelperExecutionJob) (*apitypes.Execution, bool) {
+	exec, err := stores.Executions.GetByID(ctx, job.ExecutionID)
+	if err != nil {
+		log.Printf("Failed to read execution %s status: %v", job.ExecutionID, err)
+		return nil, true
+	}
+	if exec == nil {
+		return nil, true
+	}
+	if execution.IsTerminal(exec.Status) {
+		log.Printf("Execution %s is %s, not running it", job.ExecutionID, exec.Status)
+		return exec, false
+	}
+
+	if job.WorkflowRunID != "" && exec.Status == execution.StatusDispatched {
+		run, err := workflow.GetRun(ctx, stores, job.WorkflowRunID)
+		if err == nil && run != nil && run.Status == workflow.RunCancelled {
+			log.Printf("Workflow run %s was cancelled, skipping execution %s", job.WorkflowRunID, job.ExecutionID)
+			transitionExecution(ctx, stores, job.ExecutionID, execution.StatusSkipped, "workflow run cancelled")
+			return exec, false
+		}
+	}
+	return exec, true
+}
+
+// transitionExecution moves the execution to status through the state
+// machine and reports whether it did. Rejected transitions are logged.
+func transitionExecution(ctx context.Context, stores *database.Stores, executionID, status, reason string) bool {
+	if err := execution.Transition(ctx, stores.Executions, executionID, status, reason, time.Now().UTC()); err != nil {
+		log.Printf("Failed to update execution status: %v", err)
+		return false
 	}
+	return true
+}
 
-	if errorMsg != "" {
-		updateExpr += ", error_message = :error"
-		exprValues[":error"] = &ddbtypes.AttributeValueMemberS{Value: errorMsg}
+// updateExecutionStatus moves the execution to a non-final status and
+// records the error that led there
+func updateExecutionStatus(ctx context.Context, stores *database.Stores, executionID, status, errorMsg string) {
+	if !transitionExecution(ctx, stores, executionID, status, "") || errorMsg == "" {
+		return
 	}
-	if durationMs > 0 {
-		updateExpr += ", duration_ms = :duration"
-		exprValues[":duration"] = &ddbtypes.AttributeValueMemberN{Value: fmt.Sprintf("%d", durationMs)}
+	if err := stores.Executions.RecordError(ctx, executionID, errorMsg); err != nil {
+		log.Printf("Failed to update execution status: %v", err)
 	}
+}
 
-	_, err := db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(executionsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"execution_id": &ddbtypes.AttributeValueMemberS{Value: executionID},
-		},
-		UpdateExpression:          aws.String(updateExpr),
-		ExpressionAttributeNames:  exprNames,
-		ExpressionAttributeValues: exprValues,
+// startExecutionAttempt marks the execution running and records which
+// attempt this is. It reports false if the execution may not run, because it
+// was cancelled after the worker read it.
+func startExecutionAttempt(ctx context.Context, stores *database.Stores, executionID string, attempt int) bool {
+	if !transitionExecution(ctx, stores, executionID, execution.StatusRunning, "") {
+		return false
+	}
+	if err := stores.Executions.RecordAttempt(ctx, executionID, attempt); err != nil {
+		log.Printf("Failed to record execution attempt: %v", err)
+	}
+	return true
+}
+
+// updateConnectorTrace stores the CRM calls of this attempt on the execution,
+// replacing an earlier attempt's. The execution's TTL is set if missing so the
+// trace never outlives the default execution retention.
+func updateConnectorTrace(ctx context.Context, stores *database.Stores, executionID string, trace *connectors.Trace) {
+	calls := trace.Calls()
+	if len(calls) == 0 {
+		return
+	}
+
+	records := make([]apitypes.ConnectorCall, len(calls))
+	for i, call := range calls {
+		records[i] = apitypes.ConnectorCall(call)
+	}
+	if err := stores.Executions.RecordTrace(ctx, executionID, records, trace.Dropped(), time.Now().Add(executionRetention)); err != nil {
+		log.Printf("Failed to record connector trace: %v", err)
+	}
+}
+
+// recordRetryAttempt appends a failed attempt to the execution's retry history
+func recordRetryAttempt(ctx context.Context, stores *database.Stores, executionID string, attempt apitypes.RetryAttempt) {
+	if err := stores.Executions.AppendRetryAttempt(ctx, executionID, attempt); err != nil {
+		log.Printf("Failed to record retry attempt: %v", err)
+	}
+}
+
+// delayRedelivery hides a record handed back to SQS for the retry delay. The
+// queue's own visibility timeout applies if this fails.
+func delayRedelivery(ctx context.Context, sqsClient *sqs.Client, record events.SQSMessage, delay time.Duration) {
+	queueURL := queueURLFromARN(record.EventSourceARN)
+	if queueURL == "" || record.ReceiptHandle == "" {
+		return
+	}
+	if delay > maxVisibilityTimeout {
+		delay = maxVisibilityTimeout
+	}
+
+	_, err := sqsClient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queueURL),
+		ReceiptHandle:     aws.String(record.ReceiptHandle),
+		VisibilityTimeout: int32(delay.Seconds()),
 	})
 	if err != nil {
-		log.Printf("Failed to update execution status: %v", err)
+		log.Printf("Failed to delay redelivery of message %s: %v", record.MessageId, err)
 	}
 }
 
-func updateExecutionResult(ctx context.Context, db *dynamodb.Client, executionID, status, errorMsg string, result *helperEngine.ExecutionResult, completedAt *time.Time) {
-	updateExpr := "SET #s = :status, completed_at = :completed_at"
-	exprNames := map[string]string{"#s": "status"}
-	exprValues := map[string]ddbtypes.AttributeValue{
-		":status":       &ddbtypes.AttributeValueMemberS{Value: status},
-		":completed_at": &ddbtypes.AttributeValueMemberS{Value: completedAt.Format(time.RFC3339)},
+// queueURLFromARN converts arn:aws:sqs:<region>:<account>:<name> to the queue URL
+func queueURLFromARN(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) != 6 || parts[2] != "sqs" {
+		return ""
 	}
+	return fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/%s", parts[3], parts[4], parts[5])
+}
 
-	if errorMsg != "" {
-		updateExpr += ", error_message = :error"
-		exprValues[":error"] = &ddbtypes.AttributeValueMemberS{Value: errorMsg}
+// updateExecutionResult records a final status and reports whether the
+// execution moved to it. errorCode classifies failures and is kept as the
+// transition's reason; a panic's stack is taken from the result.
+func updateExecutionResult(ctx context.Context, stores *database.Stores, executionID, status, errorCode, errorMsg string, result *helperEngine.ExecutionResult, completedAt *time.Time) bool {
+	if !transitionExecution(ctx, stores, executionID, status, errorCode) {
+		return false
 	}
 
+	outcome := database.ExecutionOutcome{
+		CompletedAt:  *completedAt,
+		ErrorMessage: errorMsg,
+		ErrorCode:    errorCode,
+	}
 	if result != nil {
-		updateExpr += ", duration_ms = :duration"
-		exprValues[":duration"] = &ddbtypes.AttributeValueMemberN{Value: fmt.Sprintf("%d", result.DurationMs)}
-
-		if result.Output != nil {
-			outputJSON, err := json.Marshal(result.Output)
-			if err == nil {
-				updateExpr += ", output = :output"
-				exprValues[":output"] = &ddbtypes.AttributeValueMemberS{Value: string(outputJSON)}
-			}
+		outcome.ErrorStack = result.ErrorStack
+		outcome.DurationMs = result.DurationMs
+		if output, err := outputMap(result.Output); err == nil {
+			outcome.Output = output
+		} else {
+			log.Printf("Failed to convert output of execution %s: %v", executionID, err)
 		}
 	}
 
-	_, err := db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(executionsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"execution_id": &ddbtypes.AttributeValueMemberS{Value: executionID},
-		},
-		UpdateExpression:          aws.String(updateExpr),
-		ExpressionAttributeNames:  exprNames,
-		ExpressionAttributeValues: exprValues,
-	})
-	if err != nil {
+	if err := stores.Executions.RecordOutcome(ctx, executionID, outcome); err != nil {
 		log.Printf("Failed to update execution result: %v", err)
 	}
+	return true
+}
+
+// outputMap converts a helper's output to the map kept on the execution, in
+// its JSON form
+func outputMap(output *helperEngine.HelperOutput) (map[string]interface{}, error) {
+	if output == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 func sendFailureNotification(ctx context.Context, sqsClient *sqs.Client, job HelperExecutionJob, errorMsg string) {
@@ -297,56 +666,41 @@ func sendFailureNotification(ctx context.Context, sqsClient *sqs.Client, job Hel
 	}
 }
 
-func updateHelperStats(ctx context.Context, db *dynamodb.Client, helperID string, executedAt *time.Time) {
-	_, err := db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(helpersTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"helper_id": &ddbtypes.AttributeValueMemberS{Value: helperID},
-		},
-		UpdateExpression: aws.String("SET execution_count = if_not_exists(execution_count, :zero) + :one, last_executed_at = :executed_at"),
-		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
-			":zero":        &ddbtypes.AttributeValueMemberN{Value: "0"},
-			":one":         &ddbtypes.AttributeValueMemberN{Value: "1"},
-			":executed_at": &ddbtypes.AttributeValueMemberS{Value: executedAt.Format(time.RFC3339)},
-		},
-	})
-	if err != nil {
+func updateHelperStats(ctx context.Context, stores *database.Stores, helperID string, executedAt *time.Time) {
+	if err := stores.Helpers.RecordExecution(ctx, helperID, *executedAt); err != nil {
 		log.Printf("Failed to update helper stats: %v", err)
 	}
 }
 
 func processPostExecutionActions(
 	ctx context.Context,
-	db *dynamodb.Client,
+	stores *database.Stores,
 	actions []helperEngine.HelperAction,
 	job HelperExecutionJob,
 	connector connectors.CRMConnector,
 	serviceAuths map[string]*connectors.ConnectorConfig,
 ) {
-	for _, action := range actions {
-		switch action.Type {
-		case "google_sheet_sync_queued":
-			handleGoogleSheetSync(ctx, db, action, job, connector, serviceAuths)
-		default:
-			log.Printf("Skipping unknown post-execution action type: %s", action.Type)
+	actx := &ActionContext{
+		Stores:       stores,
+		Job:          job,
+		Connector:    connector,
+		ServiceAuths: serviceAuths,
+	}
+
+	deliveries := actionDispatcher.Dispatch(ctx, actx, actions)
+	if len(deliveries) > 0 {
+		if err := stores.Executions.RecordDeliveries(ctx, job.ExecutionID, deliveries); err != nil {
+			log.Printf("Failed to update action deliveries: %v", err)
 		}
 	}
 }
 
-func handleGoogleSheetSync(
-	ctx context.Context,
-	db *dynamodb.Client,
-	action helperEngine.HelperAction,
-	job HelperExecutionJob,
-	connector connectors.CRMConnector,
-	serviceAuths map[string]*connectors.ConnectorConfig,
-) {
+func handleGoogleSheetSync(ctx context.Context, actx *ActionContext, action helperEngine.HelperAction) (*ActionResult, error) {
 	log.Printf("Processing Google Sheet sync for spreadsheet %s", action.Target)
 
 	syncRequest, ok := action.Value.(map[string]interface{})
 	if !ok {
-		log.Printf("Invalid sync request format for Google Sheet action")
-		return
+		return nil, fmt.Errorf("invalid sync request format for Google Sheet action")
 	}
 
 	spreadsheetID, _ := syncRequest["spreadsheet_id"].(string)
@@ -354,20 +708,17 @@ func handleGoogleSheetSync(
 	mode, _ := syncRequest["mode"].(string)
 
 	if spreadsheetID == "" || sheetID == "" {
-		log.Printf("Missing required fields in sync request")
-		return
+		return nil, fmt.Errorf("missing required fields in sync request")
 	}
 
-	googleAuth, ok := serviceAuths["google_sheets"]
+	googleAuth, ok := actx.ServiceAuths["google_sheets"]
 	if !ok || googleAuth == nil {
-		log.Printf("Google Sheets authentication not found in ServiceAuths")
-		return
+		return nil, fmt.Errorf("%w: Google Sheets authentication not found in ServiceAuths", ErrActionSkipped)
 	}
 
 	accessToken := googleAuth.AccessToken
 	if accessToken == "" {
-		log.Printf("Google Sheets access token is empty")
-		return
+		return nil, fmt.Errorf("%w: Google Sheets access token is empty", ErrActionSkipped)
 	}
 
 	sheetsClient := google.NewSheetsClient(accessToken)
@@ -375,8 +726,7 @@ func handleGoogleSheetSync(
 	if mode == "replace" {
 		log.Printf("Clearing worksheet %s in spreadsheet %s", sheetID, spreadsheetID)
 		if err := sheetsClient.ClearWorksheet(ctx, spreadsheetID, sheetID); err != nil {
-			log.Printf("Failed to clear worksheet: %v", err)
-			return
+			return nil, fmt.Errorf("failed to clear worksheet: %w", err)
 		}
 	}
 
@@ -386,13 +736,15 @@ func handleGoogleSheetSync(
 	contactData, hasContact := syncRequest["contact_data"].(map[string]interface{})
 
 	if hasSearch && searchID != "" {
+		if actx.Connector == nil {
+			return nil, fmt.Errorf("%w: CRM search requires a connection", ErrActionSkipped)
+		}
 		log.Printf("Executing CRM search for search_id: %s", searchID)
-		contactList, err := connector.GetContacts(ctx, connectors.QueryOptions{
+		contactList, err := actx.Connector.GetContacts(ctx, connectors.QueryOptions{
 			Limit: 1000,
 		})
 		if err != nil {
-			log.Printf("Failed to fetch contacts from CRM: %v", err)
-			return
+			return nil, fmt.Errorf("failed to fetch contacts from CRM: %w", err)
 		}
 
 		contactPtrs := make([]*connectors.NormalizedContact, len(contactList.Contacts))
@@ -407,22 +759,20 @@ func handleGoogleSheetSync(
 			contactToRow(contactData),
 		}
 	} else {
-		log.Printf("No contact data or search query provided")
-		return
+		return nil, fmt.Errorf("%w: no contact data or search query provided", ErrActionSkipped)
 	}
 
 	if len(rows) == 0 {
-		log.Printf("No rows to write to Google Sheet")
-		return
+		return nil, fmt.Errorf("%w: no rows to write to Google Sheet", ErrActionSkipped)
 	}
 
 	log.Printf("Writing %d rows to worksheet %s", len(rows), sheetID)
 	if err := sheetsClient.WriteRows(ctx, spreadsheetID, sheetID, rows); err != nil {
-		log.Printf("Failed to write rows to worksheet: %v", err)
-		return
+		return nil, fmt.Errorf("failed to write rows to worksheet: %w", err)
 	}
 
 	log.Printf("Successfully synced %d rows to Google Sheet %s", len(rows), spreadsheetID)
+	return &ActionResult{Detail: fmt.Sprintf("%d rows written", len(rows))}, nil
 }
 
 func contactsToRows(contacts []*connectors.NormalizedContact, syncRequest map[string]interface{}) [][]interface{} {
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/execution"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+	"github.com/myfusionhelper/api/internal/workflow"
+)
+
+func TestReportCancelled(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Helpers.Create(ctx, &apitypes.Helper{HelperID: "helper:a", AccountID: "acc-1", Enabled: true})
+
+	run, err := workflow.StartRun(ctx, stores, workflow.Definition{Steps: []workflow.Step{{ID: "a", HelperID: "helper:a"}}}, workflow.Trigger{AccountID: "acc-1"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	exec, err := execution.Cancel(ctx, stores.Executions, "acc-1", run.Steps["a"].ExecutionID, "cancelled by user-1", time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ReportCancelled(ctx, stores, exec, "cancelled by user-1")
+	run, _ = workflow.GetRun(ctx, stores, run.RunID)
+	if run.Steps["a"].Status != workflow.StepFailed || run.Steps["a"].Error != "cancelled by user-1" || run.Status != workflow.RunFailed {
+		t.Errorf("expected the cancelled step to fail the run, got step %+v and run %s", run.Steps["a"], run.Status)
+	}
+
+	stores.Batches.Create(ctx, &apitypes.Batch{BatchID: "batch:1", AccountID: "acc-1", Status: "running"})
+	stores.Batches.AddItem(ctx, &apitypes.BatchItem{BatchID: "batch:1", ContactID: "c1", Status: "queued"})
+	ReportCancelled(ctx, stores, &apitypes.Execution{ExecutionID: "exec:2", BatchID: "batch:1", ContactID: "c1"}, "cancelled by user-1")
+	if batch, _ := stores.Batches.GetByID(ctx, "batch:1"); batch.Failed != 1 {
+		t.Errorf("expected the cancelled item counted as failed, got %+v", batch)
+	}
+}
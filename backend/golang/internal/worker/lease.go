@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+)
+
+var (
+	// contactLeaseWait is how long a job waits for another execution to
+	// finish with its contact before it is handed back to SQS
+	contactLeaseWait = 20 * time.Second
+	// contactLeasePoll is how often a waiting job retries the lease
+	contactLeasePoll = 500 * time.Millisecond
+)
+
+// contactLeaseRetryDelay hides a job handed back for a busy contact
+const contactLeaseRetryDelay = 5 * time.Second
+
+// defaultContactLease is the lease taken when the invocation has no deadline
+const defaultContactLease = 5 * time.Minute
+
+// contactLeaseKey scopes a contact's lease to its account
+func contactLeaseKey(job HelperExecutionJob) string {
+	return "lease:contact:" + job.AccountID + ":" + job.ContactID
+}
+
+// acquireContactLease makes job the only execution running against its
+// contact. Helpers such as math_it, drip_it and score_it read a contact
+// field, compute and write it back, so a contact's executions must not
+// overlap. Each worker queue delivers a contact's executions in order; the
+// lease extends that across helper types, whose queues are consumed
+// independently.
+//
+// A lease held by another execution is waited on for contactLeaseWait;
+// acquired is false if it is still held. Leases last until the invocation's
+// deadline, so a crashed worker's lease expires with it. Jobs without a
+// contact, a nil store and store errors don't block the job.
+func acquireContactLease(ctx context.Context, leases database.LeaseStore, job HelperExecutionJob) (release func(), acquired bool) {
+	release = func() {}
+	if leases == nil || job.ContactID == "" {
+		return release, true
+	}
+
+	key := contactLeaseKey(job)
+	expiresAt, ok := ctx.Deadline()
+	if !ok {
+		expiresAt = time.Now().Add(defaultContactLease)
+	}
+
+	waitUntil := time.Now().Add(contactLeaseWait)
+	for {
+		acquired, err := leases.Acquire(ctx, key, job.ExecutionID, expiresAt)
+		if err != nil {
+			log.Printf("Failed to acquire contact lease for execution %s, running without it: %v", job.ExecutionID, err)
+			return release, true
+		}
+		if acquired {
+			return func() {
+				if err := leases.Release(ctx, key, job.ExecutionID); err != nil {
+					log.Printf("Failed to release contact lease for execution %s: %v", job.ExecutionID, err)
+				}
+			}, true
+		}
+
+		now := time.Now()
+		if !now.Add(contactLeasePoll).Before(waitUntil) || !hasTimeForJob(ctx, now.Add(contactLeasePoll)) {
+			return release, false
+		}
+		select {
+		case <-ctx.Done():
+			return release, false
+		case <-time.After(contactLeasePoll):
+		}
+	}
+}
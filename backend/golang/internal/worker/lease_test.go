@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database/memory"
+)
+
+func TestAcquireContactLease(t *testing.T) {
+	defer func(wait, poll time.Duration) { contactLeaseWait, contactLeasePoll = wait, poll }(contactLeaseWait, contactLeasePoll)
+	contactLeaseWait, contactLeasePoll = 50*time.Millisecond, 10*time.Millisecond
+
+	ctx := context.Background()
+	leases := memory.NewLeases()
+	first := HelperExecutionJob{ExecutionID: "exec:1", AccountID: "acc-1", ContactID: "42"}
+	second := HelperExecutionJob{ExecutionID: "exec:2", AccountID: "acc-1", ContactID: "42"}
+
+	release, acquired := acquireContactLease(ctx, leases, first)
+	if !acquired {
+		t.Fatal("expected the first execution to get the lease")
+	}
+	if _, acquired := acquireContactLease(ctx, leases, second); acquired {
+		t.Error("expected a second execution for the contact to wait and give up")
+	}
+	if _, acquired := acquireContactLease(ctx, leases, HelperExecutionJob{ExecutionID: "exec:3", AccountID: "acc-2", ContactID: "42"}); !acquired {
+		t.Error("expected another account's contact not to share the lease")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+	}()
+	if _, acquired := acquireContactLease(ctx, leases, second); !acquired {
+		t.Error("expected the second execution to get the lease once it was released")
+	}
+
+	if _, acquired := acquireContactLease(ctx, leases, HelperExecutionJob{ExecutionID: "exec:4"}); !acquired {
+		t.Error("expected executions without a contact to run without a lease")
+	}
+}
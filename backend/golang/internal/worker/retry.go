@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	helperEngine "github.com/myfusionhelper/api/internal/helpers"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+// maxVisibilityTimeout is the longest SQS allows a message to stay hidden
+const maxVisibilityTimeout = 12 * time.Hour
+
+// maxReceiveCount matches the maxReceiveCount of the worker queues' redrive
+// policy: SQS moves a message received this many times to the DLQ instead
+// of delivering it again
+const maxReceiveCount = 6
+
+// RetryPolicy controls how a helper type's transient failures are retried.
+// MaxAttempts counts the first run, so 1 disables retries. Attempts and
+// hand-backs share the worker queue's maxReceiveCount receives; an execution
+// whose message is on its final receive is dead-lettered rather than retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy applies to helper types without a registered policy
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    15 * time.Minute,
+}
+
+var (
+	retryPoliciesMu sync.RWMutex
+	retryPolicies   = map[string]RetryPolicy{
+		// Notifications are time-sensitive and a late duplicate is worse than none
+		"notify_me":        {MaxAttempts: 2, BaseDelay: 10 * time.Second, MaxDelay: time.Minute},
+		"email_engagement": {MaxAttempts: 2, BaseDelay: 10 * time.Second, MaxDelay: time.Minute},
+		// Relays into other helpers, which retry on their own queues
+		"chain_it": {MaxAttempts: 1},
+	}
+)
+
+// RegisterRetryPolicy sets the retry policy for a helper type. Worker mains may
+// call it before starting the Lambda.
+func RegisterRetryPolicy(helperType string, policy RetryPolicy) {
+	retryPoliciesMu.Lock()
+	defer retryPoliciesMu.Unlock()
+	retryPolicies[helperType] = policy
+}
+
+// RetryPolicyFor returns the retry policy registered for a helper type, or
+// DefaultRetryPolicy
+func RetryPolicyFor(helperType string) RetryPolicy {
+	retryPoliciesMu.RLock()
+	defer retryPoliciesMu.RUnlock()
+	if policy, ok := retryPolicies[helperType]; ok {
+		return policy
+	}
+	return DefaultRetryPolicy
+}
+
+// Delay returns the backoff before the attempt following attempt n: BaseDelay
+// doubled per previous attempt, capped at MaxDelay.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 || maxDelay > maxVisibilityTimeout {
+		maxDelay = maxVisibilityTimeout
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// ShouldRetry reports whether a failure on attempt n should be retried
+func (p RetryPolicy) ShouldRetry(err error, attempt int) bool {
+	return IsRetryable(err) && attempt < p.MaxAttempts
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// connector errors flagged Retryable (throttling, 5xx, network) and jobs that
+// ran out of time. Bad config and missing records fail the same way on every
+// attempt.
+func IsRetryable(err error) bool {
+	var connErr *connectors.ConnectorError
+	if errors.As(err, &connErr) && connErr.Retryable {
+		return true
+	}
+	return helperEngine.ClassifyError(err) == helperEngine.ErrCodeTimeout
+}
+
+// attemptNumber returns which attempt of the job this record is. SQS counts
+// receives of this message, less those the worker handed back without
+// running the execution (see handBack); RetryCount, when a producer sets it,
+// counts attempts made before the message was enqueued.
+func attemptNumber(record events.SQSMessage, job HelperExecutionJob, exec *apitypes.Execution) int {
+	attempt := receiveCount(record)
+	if exec != nil && exec.HandbackMessageID == record.MessageId {
+		attempt -= exec.Handbacks
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	return attempt + job.RetryCount
+}
+
+// receiveCount returns how many times SQS has delivered the record
+func receiveCount(record events.SQSMessage) int {
+	if count, err := strconv.Atoi(record.Attributes["ApproximateReceiveCount"]); err == nil && count > 0 {
+		return count
+	}
+	return 1
+}
+
+// finalReceive reports whether SQS moves the record to the DLQ rather than
+// deliver it again if it is handed back
+func finalReceive(record events.SQSMessage) bool {
+	return receiveCount(record) >= maxReceiveCount
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/myfusionhelper/api/internal/connectors"
+	apitypes "github.com/myfusionhelper/api/internal/types"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 2 * time.Minute}
+
+	expected := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 2 * time.Minute}
+	for i, want := range expected {
+		if got := policy.Delay(i + 1); got != want {
+			t.Errorf("attempt %d: expected delay %v, got %v", i+1, want, got)
+		}
+	}
+
+	unbounded := RetryPolicy{BaseDelay: time.Hour}
+	if got := unbounded.Delay(10); got != maxVisibilityTimeout {
+		t.Errorf("expected delay capped at %v, got %v", maxVisibilityTimeout, got)
+	}
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	transient := fmt.Errorf("failed to apply tag: %w", connectors.NewConnectorError("keap", 503, "unavailable", true))
+	permanent := connectors.NewConnectorError("keap", 404, "contact not found", false)
+
+	if !policy.ShouldRetry(transient, 1) || !policy.ShouldRetry(transient, 2) {
+		t.Error("expected wrapped retryable connector error to be retried")
+	}
+	if policy.ShouldRetry(transient, 3) {
+		t.Error("expected no retry once max attempts is reached")
+	}
+	if policy.ShouldRetry(permanent, 1) {
+		t.Error("expected non-retryable connector error not to be retried")
+	}
+	if policy.ShouldRetry(errors.New("invalid config"), 1) {
+		t.Error("expected plain error not to be retried")
+	}
+	if !policy.ShouldRetry(fmt.Errorf("failed to get contact: %w", context.DeadlineExceeded), 1) {
+		t.Error("expected timed-out job to be retried")
+	}
+}
+
+func TestRetryPolicyFor(t *testing.T) {
+	if got := RetryPolicyFor("unregistered_helper"); got != DefaultRetryPolicy {
+		t.Errorf("expected default policy, got %+v", got)
+	}
+
+	RegisterRetryPolicy("test_helper", RetryPolicy{MaxAttempts: 5})
+	defer func() {
+		retryPoliciesMu.Lock()
+		delete(retryPolicies, "test_helper")
+		retryPoliciesMu.Unlock()
+	}()
+	if got := RetryPolicyFor("test_helper"); got.MaxAttempts != 5 {
+		t.Errorf("expected registered policy, got %+v", got)
+	}
+}
+
+func TestAttemptNumber(t *testing.T) {
+	record := events.SQSMessage{MessageId: "msg-1", Attributes: map[string]string{"ApproximateReceiveCount": "2"}}
+	if got := attemptNumber(record, HelperExecutionJob{}, nil); got != 2 {
+		t.Errorf("expected attempt 2, got %d", got)
+	}
+	if got := attemptNumber(record, HelperExecutionJob{RetryCount: 1}, nil); got != 3 {
+		t.Errorf("expected attempt 3 with prior retry count, got %d", got)
+	}
+	if got := attemptNumber(events.SQSMessage{}, HelperExecutionJob{}, nil); got != 1 {
+		t.Errorf("expected attempt 1 without receive count, got %d", got)
+	}
+
+	record.Attributes["ApproximateReceiveCount"] = "5"
+	exec := &apitypes.Execution{Handbacks: 3, HandbackMessageID: "msg-1"}
+	if got := attemptNumber(record, HelperExecutionJob{}, exec); got != 2 {
+		t.Errorf("expected hand-backs not to count as attempts, got %d", got)
+	}
+	exec.HandbackMessageID = "msg-0"
+	if got := attemptNumber(record, HelperExecutionJob{}, exec); got != 5 {
+		t.Errorf("expected hand-backs of another message to be ignored, got %d", got)
+	}
+}
+
+func TestFinalReceive(t *testing.T) {
+	record := events.SQSMessage{Attributes: map[string]string{"ApproximateReceiveCount": "5"}}
+	if finalReceive(record) {
+		t.Error("expected receive 5 not to be the last")
+	}
+	record.Attributes["ApproximateReceiveCount"] = "6"
+	if !finalReceive(record) {
+		t.Error("expected receive 6 to be the last")
+	}
+}
+
+func TestQueueURLFromARN(t *testing.T) {
+	got := queueURLFromARN("arn:aws:sqs:us-west-2:123456789012:mfh-dev-tag-it-executions.fifo")
+	want := "https://sqs.us-west-2.amazonaws.com/123456789012/mfh-dev-tag-it-executions.fifo"
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if got := queueURLFromARN("not-an-arn"); got != "" {
+		t.Errorf("expected empty URL for invalid ARN, got %s", got)
+	}
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// StreamImageToJSON converts a DynamoDB Stream NewImage of an execution to
+// the JSON message body HandleSQSEvent reads as a HelperExecutionJob.
+func StreamImageToJSON(image map[string]events.DynamoDBAttributeValue) (string, error) {
+	m := convertStreamImage(image)
+	bytes, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stream image: %w", err)
+	}
+	return string(bytes), nil
+}
+
+// maxMessageGroupID is the longest MessageGroupId SQS accepts
+const maxMessageGroupID = 128
+
+// MessageGroupID returns the SQS FIFO message group for an execution's stream
+// image: its account and contact, so a worker queue delivers a contact's
+// executions one at a time and in order. Executions without a contact get a
+// group of their own. Contact IDs that would make an invalid group ID are
+// hashed.
+func MessageGroupID(image map[string]events.DynamoDBAttributeValue) string {
+	contactID := streamString(image, "contact_id")
+	if contactID == "" {
+		return streamString(image, "execution_id")
+	}
+
+	groupID := streamString(image, "account_id") + ":" + contactID
+	if len(groupID) > maxMessageGroupID || !validMessageGroupID(groupID) {
+		sum := sha256.Sum256([]byte(groupID))
+		return "contact:" + hex.EncodeToString(sum[:])
+	}
+	return groupID
+}
+
+// validMessageGroupID reports whether id only has the alphanumeric and
+// punctuation characters SQS allows in a MessageGroupId
+func validMessageGroupID(id string) bool {
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// streamString returns a string attribute of a stream image, or "" if it is
+// missing or not a string
+func streamString(image map[string]events.DynamoDBAttributeValue, name string) string {
+	v, ok := image[name]
+	if !ok || v.DataType() != events.DataTypeString {
+		return ""
+	}
+	return v.String()
+}
+
+func convertStreamImage(image map[string]events.DynamoDBAttributeValue) map[string]interface{} {
+	result := make(map[string]interface{}, len(image))
+	for k, v := range image {
+		result[k] = convertStreamValue(v)
+	}
+	return result
+}
+
+func convertStreamValue(v events.DynamoDBAttributeValue) interface{} {
+	switch v.DataType() {
+	case events.DataTypeString:
+		return v.String()
+	case events.DataTypeNumber:
+		n := v.Number()
+		if i, err := strconv.ParseInt(n, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(n, 64); err == nil {
+			return f
+		}
+		return n
+	case events.DataTypeBoolean:
+		return v.Boolean()
+	case events.DataTypeMap:
+		return convertStreamImage(v.Map())
+	case events.DataTypeList:
+		list := v.List()
+		result := make([]interface{}, len(list))
+		for i, item := range list {
+			result[i] = convertStreamValue(item)
+		}
+		return result
+	case events.DataTypeStringSet:
+		return v.StringSet()
+	case events.DataTypeNumberSet:
+		return v.NumberSet()
+	case events.DataTypeNull:
+		return nil
+	default:
+		return nil
+	}
+}
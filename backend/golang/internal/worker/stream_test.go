@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestMessageGroupID(t *testing.T) {
+	image := func(contactID string) map[string]events.DynamoDBAttributeValue {
+		img := map[string]events.DynamoDBAttributeValue{
+			"execution_id": events.NewStringAttribute("exec:1"),
+			"account_id":   events.NewStringAttribute("acc-1"),
+			"helper_type":  events.NewStringAttribute("math_it"),
+		}
+		if contactID != "" {
+			img["contact_id"] = events.NewStringAttribute(contactID)
+		}
+		return img
+	}
+
+	if got := MessageGroupID(image("42")); got != "acc-1:42" {
+		t.Errorf("expected acc-1:42, got %s", got)
+	}
+	if got := MessageGroupID(image("")); got != "exec:1" {
+		t.Errorf("expected executions without a contact grouped alone, got %s", got)
+	}
+
+	for _, contactID := range []string{"jane doe@example.com", strings.Repeat("9", 200)} {
+		got := MessageGroupID(image(contactID))
+		if !strings.HasPrefix(got, "contact:") || len(got) > maxMessageGroupID || got != MessageGroupID(image(contactID)) {
+			t.Errorf("expected a stable hashed group for %q, got %s", contactID, got)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package workflow runs DAGs of helper executions. A Definition lists the
+// steps; a Run persists per-step state and is advanced by the helper workers
+// each time a step's execution finishes.
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OutputKey is the HelperOutput.ModifiedData key under which a helper hands
+// the worker a *Definition to start as a run
+const OutputKey = "workflow"
+
+// When values control which dependency outcomes let a step run
+const (
+	// WhenSuccess runs the step once every dependency completed successfully (default)
+	WhenSuccess = "success"
+	// WhenFailure runs the step when any dependency failed
+	WhenFailure = "failure"
+	// WhenAlways runs the step once every dependency settled, whatever the outcome
+	WhenAlways = "always"
+)
+
+// Condition operators
+const (
+	OpEquals      = "equals"
+	OpNotEquals   = "not_equals"
+	OpContains    = "contains"
+	OpNotContains = "not_contains"
+	OpExists      = "exists"
+	OpNotExists   = "not_exists"
+	OpGreaterThan = "greater_than"
+	OpLessThan    = "less_than"
+)
+
+// maxWaitSeconds bounds a single step wait (30 days)
+const maxWaitSeconds = 30 * 24 * 60 * 60
+
+// Definition is a DAG of helper steps
+type Definition struct {
+	Name  string `json:"name,omitempty" dynamodbav:"name,omitempty"`
+	Steps []Step `json:"steps" dynamodbav:"steps"`
+}
+
+// Step runs one stored helper. Inputs maps an input key to a reference such as
+// "steps.score.output.total" or "input.source"; the resolved values are merged
+// over the run's trigger input.
+type Step struct {
+	ID          string                 `json:"id" dynamodbav:"id"`
+	HelperID    string                 `json:"helper_id" dynamodbav:"helper_id"`
+	Config      map[string]interface{} `json:"config,omitempty" dynamodbav:"config,omitempty"` // overrides the helper's own config
+	DependsOn   []string               `json:"depends_on,omitempty" dynamodbav:"depends_on,omitempty"`
+	When        string                 `json:"when,omitempty" dynamodbav:"when,omitempty"`
+	Condition   *Condition             `json:"condition,omitempty" dynamodbav:"condition,omitempty"`
+	WaitSeconds int                    `json:"wait_seconds,omitempty" dynamodbav:"wait_seconds,omitempty"`
+	Inputs      map[string]string      `json:"inputs,omitempty" dynamodbav:"inputs,omitempty"`
+}
+
+// Condition gates a step on a value from the run. Path takes the same
+// references as Step.Inputs, plus "steps.<id>.status".
+type Condition struct {
+	Path     string `json:"path" dynamodbav:"path"`
+	Operator string `json:"operator" dynamodbav:"operator"`
+	Value    string `json:"value,omitempty" dynamodbav:"value,omitempty"`
+}
+
+// Validate checks step IDs, references and operators, and that the steps form
+// a DAG.
+func (d *Definition) Validate() error {
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("workflow must have at least one step")
+	}
+
+	ids := make(map[string]bool, len(d.Steps))
+	for i, step := range d.Steps {
+		if step.ID == "" {
+			return fmt.Errorf("step %d: id is required", i+1)
+		}
+		if strings.Contains(step.ID, ".") {
+			return fmt.Errorf("step %s: id must not contain '.'", step.ID)
+		}
+		if ids[step.ID] {
+			return fmt.Errorf("duplicate step id %q", step.ID)
+		}
+		ids[step.ID] = true
+	}
+
+	for _, step := range d.Steps {
+		if step.HelperID == "" {
+			return fmt.Errorf("step %s: helper_id is required", step.ID)
+		}
+		for _, dep := range step.DependsOn {
+			if !ids[dep] {
+				return fmt.Errorf("step %s: depends on unknown step %q", step.ID, dep)
+			}
+		}
+		switch step.When {
+		case "", WhenSuccess, WhenFailure, WhenAlways:
+		default:
+			return fmt.Errorf("step %s: invalid when %q", step.ID, step.When)
+		}
+		if step.WaitSeconds < 0 || step.WaitSeconds > maxWaitSeconds {
+			return fmt.Errorf("step %s: wait_seconds must be between 0 and %d", step.ID, maxWaitSeconds)
+		}
+		if step.Condition != nil {
+			if err := validateReference(step.Condition.Path, ids); err != nil {
+				return fmt.Errorf("step %s: condition: %w", step.ID, err)
+			}
+			switch step.Condition.Operator {
+			case OpEquals, OpNotEquals, OpContains, OpNotContains, OpExists, OpNotExists, OpGreaterThan, OpLessThan:
+			default:
+				return fmt.Errorf("step %s: invalid condition operator %q", step.ID, step.Condition.Operator)
+			}
+		}
+		for key, ref := range step.Inputs {
+			if err := validateReference(ref, ids); err != nil {
+				return fmt.Errorf("step %s: input %s: %w", step.ID, key, err)
+			}
+		}
+	}
+
+	if _, err := d.order(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseSteps decodes a steps list from helper config
+func ParseSteps(raw interface{}) ([]Step, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid steps: %w", err)
+	}
+	var steps []Step
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("steps must be an array of step objects: %w", err)
+	}
+	return steps, nil
+}
+
+// Step returns the step with the given ID
+func (d *Definition) Step(id string) (Step, bool) {
+	for _, step := range d.Steps {
+		if step.ID == id {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+// order returns the step IDs in dependency order, failing on a cycle
+func (d *Definition) order() ([]string, error) {
+	remaining := make(map[string]int, len(d.Steps))
+	dependents := make(map[string][]string)
+	for _, step := range d.Steps {
+		remaining[step.ID] = len(step.DependsOn)
+		for _, dep := range step.DependsOn {
+			dependents[dep] = append(dependents[dep], step.ID)
+		}
+	}
+
+	var queue, order []string
+	for _, step := range d.Steps {
+		if remaining[step.ID] == 0 {
+			queue = append(queue, step.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, id)
+		for _, next := range dependents[id] {
+			remaining[next]--
+			if remaining[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	if len(order) != len(d.Steps) {
+		return nil, fmt.Errorf("workflow steps contain a dependency cycle")
+	}
+	return order, nil
+}
+
+// validateReference checks a "steps.<id>.output.<key>", "steps.<id>.status"
+// or "input.<key>" reference
+func validateReference(ref string, ids map[string]bool) error {
+	parts := strings.Split(ref, ".")
+	switch {
+	case len(parts) >= 2 && parts[0] == "input" && parts[1] != "":
+		return nil
+	case len(parts) >= 3 && parts[0] == "steps":
+		if !ids[parts[1]] {
+			return fmt.Errorf("unknown step %q in %q", parts[1], ref)
+		}
+		if (parts[2] == "status" && len(parts) == 3) || (parts[2] == "output" && len(parts) >= 4) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid reference %q: expected steps.<id>.output.<key>, steps.<id>.status or input.<key>", ref)
+}
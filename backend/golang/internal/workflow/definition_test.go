@@ -0,0 +1,92 @@
+package workflow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefinitionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []Step
+		wantErr string
+	}{
+		{
+			name: "valid DAG",
+			steps: []Step{
+				{ID: "a", HelperID: "h1"},
+				{ID: "b", HelperID: "h2", DependsOn: []string{"a"}, Inputs: map[string]string{"x": "steps.a.output.x"}},
+				{ID: "c", HelperID: "h3", DependsOn: []string{"a", "b"}, When: WhenFailure},
+			},
+		},
+		{name: "no steps", wantErr: "at least one step"},
+		{
+			name:    "duplicate id",
+			steps:   []Step{{ID: "a", HelperID: "h1"}, {ID: "a", HelperID: "h2"}},
+			wantErr: "duplicate step id",
+		},
+		{
+			name:    "unknown dependency",
+			steps:   []Step{{ID: "a", HelperID: "h1", DependsOn: []string{"missing"}}},
+			wantErr: "unknown step",
+		},
+		{
+			name: "cycle",
+			steps: []Step{
+				{ID: "a", HelperID: "h1", DependsOn: []string{"b"}},
+				{ID: "b", HelperID: "h2", DependsOn: []string{"a"}},
+			},
+			wantErr: "cycle",
+		},
+		{
+			name:    "invalid when",
+			steps:   []Step{{ID: "a", HelperID: "h1", When: "sometimes"}},
+			wantErr: "invalid when",
+		},
+		{
+			name:    "invalid input reference",
+			steps:   []Step{{ID: "a", HelperID: "h1", Inputs: map[string]string{"x": "contact.email"}}},
+			wantErr: "invalid reference",
+		},
+		{
+			name:    "invalid operator",
+			steps:   []Step{{ID: "a", HelperID: "h1", Condition: &Condition{Path: "input.x", Operator: "matches"}}},
+			wantErr: "invalid condition operator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := Definition{Steps: tt.steps}
+			err := def.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseSteps(t *testing.T) {
+	raw := []interface{}{
+		map[string]interface{}{"id": "a", "helper_id": "h1", "wait_seconds": float64(60)},
+		map[string]interface{}{"id": "b", "helper_id": "h2", "depends_on": []interface{}{"a"}},
+	}
+
+	steps, err := ParseSteps(raw)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(steps) != 2 || steps[0].WaitSeconds != 60 || steps[1].DependsOn[0] != "a" {
+		t.Errorf("Expected parsed steps, got %+v", steps)
+	}
+
+	if _, err := ParseSteps("not-a-list"); err == nil {
+		t.Error("Expected error for non-array steps")
+	}
+}
@@ -0,0 +1,308 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/helpers"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+// TriggerType is recorded on executions dispatched for workflow steps
+const TriggerType = "workflow"
+
+// maxSaveAttempts bounds optimistic-lock retries when steps finish concurrently
+const maxSaveAttempts = 5
+
+// ErrRunNotFound is returned when a run does not exist
+var ErrRunNotFound = errors.New("workflow run not found")
+
+// Trigger identifies what started a run
+type Trigger struct {
+	AccountID   string
+	UserID      string
+	ContactID   string
+	HelperID    string
+	ExecutionID string
+	Input       map[string]interface{}
+}
+
+// StepResult is the final outcome of a step's execution
+type StepResult struct {
+	Success bool
+	Output  map[string]interface{}
+	Error   string
+}
+
+// StartRun validates the definition, persists a new run and dispatches its
+// root steps. A run started from an execution takes its ID from that
+// execution, so a redelivered trigger does not start a second run.
+func StartRun(ctx context.Context, stores *database.Stores, def Definition, trigger Trigger) (*Run, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+
+	runID := "run:" + uuid.Must(uuid.NewV7()).String()
+	if trigger.ExecutionID != "" {
+		runID = "run:" + strings.TrimPrefix(trigger.ExecutionID, "exec:")
+	}
+
+	now := time.Now().UTC()
+	run := NewRun(runID, def, now)
+	run.AccountID = trigger.AccountID
+	run.UserID = trigger.UserID
+	run.ContactID = trigger.ContactID
+	run.HelperID = trigger.HelperID
+	run.ParentExecutionID = trigger.ExecutionID
+	run.Input = trigger.Input
+
+	dispatch := run.Advance(now)
+	assignExecutionIDs(run, dispatch)
+
+	if err := saveRun(ctx, stores, run); err != nil {
+		if errors.Is(err, database.ErrConditionFailed) {
+			return GetRun(ctx, stores, runID)
+		}
+		return nil, err
+	}
+
+	return failUndispatched(ctx, stores, run, dispatchSteps(ctx, stores, run, dispatch)), nil
+}
+
+// CompleteStep records a step's final result and dispatches whatever became
+// runnable. Results for cancelled runs are recorded without advancing.
+func CompleteStep(ctx context.Context, stores *database.Stores, runID, stepID string, result StepResult) (*Run, error) {
+	return update(ctx, stores, runID, func(run *Run, now time.Time) error {
+		return run.CompleteStep(stepID, result.Success, result.Output, result.Error, now)
+	})
+}
+
+// Cancel stops a run; steps already executing are left to finish
+func Cancel(ctx context.Context, stores *database.Stores, runID string) (*Run, error) {
+	return update(ctx, stores, runID, func(run *Run, now time.Time) error {
+		return run.Cancel(now)
+	})
+}
+
+// Resume restarts a failed or cancelled run from the steps that did not complete
+func Resume(ctx context.Context, stores *database.Stores, runID string) (*Run, error) {
+	return update(ctx, stores, runID, func(run *Run, now time.Time) error {
+		return run.Resume(now)
+	})
+}
+
+// WakeDue advances runs whose earliest waiting step is due, dispatches again
+// the queued steps whose executions were never created, and returns how
+// many runs were woken
+func WakeDue(ctx context.Context, stores *database.Stores, now time.Time) (int, error) {
+	runIDs, err := stores.WorkflowRuns.ListDue(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due runs: %w", err)
+	}
+
+	woken := 0
+	for _, runID := range runIDs {
+		if _, err := update(ctx, stores, runID, func(run *Run, _ time.Time) error {
+			return confirmDispatches(ctx, stores, run, now)
+		}); err != nil {
+			log.Printf("Failed to wake workflow run %s: %v", runID, err)
+			continue
+		}
+		woken++
+	}
+	return woken, nil
+}
+
+// GetRun loads a run by ID
+func GetRun(ctx context.Context, stores *database.Stores, runID string) (*Run, error) {
+	var run Run
+	found, err := stores.WorkflowRuns.Get(ctx, runID, &run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow run: %w", err)
+	}
+	if !found {
+		return nil, ErrRunNotFound
+	}
+	return &run, nil
+}
+
+// update applies change to the latest run state, advances it and saves it
+// under an optimistic lock, then dispatches the steps that became runnable.
+// Steps that cannot be dispatched are failed, which may unblock more steps.
+func update(ctx context.Context, stores *database.Stores, runID string, change func(run *Run, now time.Time) error) (*Run, error) {
+	for attempt := 1; ; attempt++ {
+		run, err := GetRun(ctx, stores, runID)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		if err := change(run, now); err != nil {
+			return nil, err
+		}
+		dispatch := run.Advance(now)
+		assignExecutionIDs(run, dispatch)
+
+		if err := saveRun(ctx, stores, run); err != nil {
+			if errors.Is(err, database.ErrConditionFailed) && attempt < maxSaveAttempts {
+				continue
+			}
+			return nil, err
+		}
+
+		return failUndispatched(ctx, stores, run, dispatchSteps(ctx, stores, run, dispatch)), nil
+	}
+}
+
+// failUndispatched fails the steps whose executions could not be created and
+// returns the latest run state
+func failUndispatched(ctx context.Context, stores *database.Stores, run *Run, failures map[string]string) *Run {
+	for stepID, errMsg := range failures {
+		next, err := CompleteStep(ctx, stores, run.RunID, stepID, StepResult{Success: false, Error: errMsg})
+		if err != nil {
+			log.Printf("Failed to record dispatch failure for workflow run %s step %s: %v", run.RunID, stepID, err)
+			continue
+		}
+		run = next
+	}
+	return run
+}
+
+// saveRun writes the run, requiring the version it was read at
+func saveRun(ctx context.Context, stores *database.Stores, run *Run) error {
+	// Only running runs with a waiting step are listed for waking
+	wakeAt := ""
+	if run.Status == RunRunning {
+		wakeAt = run.NextWakeAt
+	}
+
+	if err := stores.WorkflowRuns.Save(ctx, run.RunID, run.Version, wakeAt, run); err != nil {
+		return err
+	}
+	run.Version++
+	return nil
+}
+
+// assignExecutionIDs gives each step about to be dispatched its execution ID,
+// so the ID is persisted with the run before the execution is created
+func assignExecutionIDs(run *Run, stepIDs []string) {
+	for _, stepID := range stepIDs {
+		run.Steps[stepID].ExecutionID = "exec:" + uuid.Must(uuid.NewV7()).String()
+	}
+}
+
+// confirmDispatches marks the unconfirmed queued steps whose executions
+// exist as dispatched and dispatches the others again. A step that still
+// cannot be dispatched is failed, and a step whose execution already
+// finished without its result reaching the run takes the execution's
+// outcome.
+func confirmDispatches(ctx context.Context, stores *database.Stores, run *Run, now time.Time) error {
+	for _, stepID := range run.UnconfirmedDispatches(now) {
+		state := run.Steps[stepID]
+		exec, err := stores.Executions.GetByID(ctx, state.ExecutionID)
+		if err != nil {
+			return fmt.Errorf("failed to get execution of step %s: %w", stepID, err)
+		}
+		if exec != nil && execution.IsTerminal(exec.Status) {
+			log.Printf("Workflow run %s step %s missed the result of execution %s (%s), recording it", run.RunID, stepID, exec.ExecutionID, exec.Status)
+			result := finishedStepResult(exec)
+			if err := run.CompleteStep(stepID, result.Success, result.Output, result.Error, now); err != nil {
+				return err
+			}
+			continue
+		}
+		if exec == nil {
+			log.Printf("Workflow run %s step %s has no execution %s, dispatching again", run.RunID, stepID, state.ExecutionID)
+			step, _ := run.Definition.Step(stepID)
+			if err := dispatchStep(ctx, stores, run, step); err != nil {
+				if err := run.CompleteStep(stepID, false, nil, err.Error(), now); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		state.Dispatched = true
+	}
+	return nil
+}
+
+// finishedStepResult is the step result of a finished execution
+func finishedStepResult(exec *types.Execution) StepResult {
+	if exec.Status == execution.StatusSucceeded {
+		output, _ := exec.Output["modified_data"].(map[string]interface{})
+		return StepResult{Success: true, Output: output}
+	}
+	errMsg := exec.ErrorMessage
+	if errMsg == "" {
+		errMsg = "execution " + exec.Status
+	}
+	return StepResult{Success: false, Error: errMsg}
+}
+
+// dispatchSteps creates a queued execution for each step; the stream router
+// forwards them to the helper workers. It returns an error message for each
+// step that could not be dispatched. Steps lost to a crash before this runs
+// are dispatched by WakeDue once their dispatchGrace has passed.
+func dispatchSteps(ctx context.Context, stores *database.Stores, run *Run, stepIDs []string) map[string]string {
+	failures := make(map[string]string)
+	for _, stepID := range stepIDs {
+		step, _ := run.Definition.Step(stepID)
+		if err := dispatchStep(ctx, stores, run, step); err != nil {
+			log.Printf("Failed to dispatch workflow run %s step %s: %v", run.RunID, stepID, err)
+			failures[stepID] = err.Error()
+		}
+	}
+	return failures
+}
+
+func dispatchStep(ctx context.Context, stores *database.Stores, run *Run, step Step) error {
+	helper, err := helpers.ResolveHelper(ctx, stores.Helpers, step.HelperID)
+	if err != nil || helper.AccountID != run.AccountID {
+		return fmt.Errorf("helper %s not found", step.HelperID)
+	}
+	if !helper.Enabled {
+		return fmt.Errorf("helper %s is disabled", step.HelperID)
+	}
+
+	// A step's own config is not a helper config version
+	config, configVersion := helper.Config, helper.ConfigVersion
+	if step.Config != nil {
+		config, configVersion = step.Config, 0
+	}
+
+	now := time.Now().UTC()
+	ttl := now.Add(7 * 24 * time.Hour).Unix()
+	exec := &types.Execution{
+		ExecutionID:    run.Steps[step.ID].ExecutionID,
+		HelperID:       helper.HelperID,
+		HelperType:     helper.HelperType,
+		AccountID:      run.AccountID,
+		UserID:         run.UserID,
+		ConnectionID:   helper.ConnectionID,
+		ContactID:      run.ContactID,
+		Config:         config,
+		ConfigVersion:  configVersion,
+		Input:          run.ResolveInputs(step),
+		Status:         execution.StatusQueued,
+		TriggerType:    TriggerType,
+		WorkflowRunID:  run.RunID,
+		WorkflowStepID: step.ID,
+		CreatedAt:      now.Format(time.RFC3339),
+		StartedAt:      now,
+		TTL:            &ttl,
+	}
+	// The execution ID was saved with the run, so an existing execution
+	// means the waker or a concurrent dispatch already created it
+	if err := stores.Executions.Create(ctx, exec); err != nil && !errors.Is(err, database.ErrConditionFailed) {
+		return fmt.Errorf("failed to create execution: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/database/memory"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/types"
+)
+
+func TestEngine_StartAndCompleteSteps(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Helpers.Create(ctx, &types.Helper{HelperID: "helper:a", AccountID: "account:1", HelperType: "tag_it", Enabled: true})
+	stores.Helpers.Create(ctx, &types.Helper{HelperID: "helper:b", AccountID: "account:1", HelperType: "copy_it", Enabled: true})
+
+	def := Definition{Steps: []Step{
+		{ID: "a", HelperID: "helper:a"},
+		{ID: "b", HelperID: "helper:b", DependsOn: []string{"a"}},
+	}}
+	run, err := StartRun(ctx, stores, def, Trigger{AccountID: "account:1", ExecutionID: "exec:trigger"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if run.RunID != "run:trigger" || run.Steps["a"].Status != StepQueued {
+		t.Fatalf("Expected run:trigger with a queued, got %s with a %s", run.RunID, run.Steps["a"].Status)
+	}
+
+	exec, _ := stores.Executions.GetByID(ctx, run.Steps["a"].ExecutionID)
+	if exec == nil || exec.Status != execution.StatusQueued || exec.WorkflowRunID != run.RunID || exec.HelperType != "tag_it" {
+		t.Fatalf("Expected a queued execution for step a, got %+v", exec)
+	}
+
+	// A redelivered trigger returns the existing run instead of starting another
+	again, err := StartRun(ctx, stores, def, Trigger{AccountID: "account:1", ExecutionID: "exec:trigger"})
+	if err != nil || again.Steps["a"].ExecutionID != run.Steps["a"].ExecutionID {
+		t.Fatalf("Expected the existing run, got %+v, %v", again, err)
+	}
+
+	run, err = CompleteStep(ctx, stores, run.RunID, "a", StepResult{Success: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if run.Steps["b"].Status != StepQueued || run.Steps["b"].ExecutionID == "" {
+		t.Fatalf("Expected b queued once a completed, got %+v", run.Steps["b"])
+	}
+
+	run, err = CompleteStep(ctx, stores, run.RunID, "b", StepResult{Success: true})
+	if err != nil || run.Status != RunCompleted {
+		t.Fatalf("Expected the run to complete, got %+v, %v", run, err)
+	}
+
+	stored, err := GetRun(ctx, stores, run.RunID)
+	if err != nil || stored.Status != RunCompleted || stored.Version != run.Version {
+		t.Errorf("Expected the completed run to be stored at version %d, got %+v, %v", run.Version, stored, err)
+	}
+}
+
+func TestEngine_FailsStepsOfMissingHelpers(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+
+	run, err := StartRun(ctx, stores, Definition{Steps: []Step{{ID: "a", HelperID: "helper:missing"}}}, Trigger{AccountID: "account:1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if run.Steps["a"].Status != StepFailed || run.Status != RunFailed {
+		t.Errorf("Expected step a and the run to fail, got step %s and run %s", run.Steps["a"].Status, run.Status)
+	}
+}
+
+func TestEngine_WakeDueDispatchesLostSteps(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Helpers.Create(ctx, &types.Helper{HelperID: "helper:a", AccountID: "account:1", Enabled: true})
+	stores.Helpers.Create(ctx, &types.Helper{HelperID: "helper:b", AccountID: "account:1", Enabled: true})
+
+	// Save runs with queued steps without creating their executions, as a
+	// crash between the save and the dispatch would
+	now := time.Now().UTC()
+	lost := NewRun("run:lost", Definition{Steps: []Step{{ID: "a", HelperID: "helper:a"}}}, now)
+	lost.AccountID = "account:1"
+	assignExecutionIDs(lost, lost.Advance(now))
+	if err := saveRun(ctx, stores, lost); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	slow := NewRun("run:slow", Definition{Steps: []Step{{ID: "b", HelperID: "helper:b"}}}, now)
+	slow.AccountID = "account:1"
+	assignExecutionIDs(slow, slow.Advance(now))
+	saveRun(ctx, stores, slow)
+	dispatchSteps(ctx, stores, slow, []string{"b"})
+
+	if woken, _ := WakeDue(ctx, stores, now); woken != 0 {
+		t.Errorf("Expected no runs woken within the grace period, got %d", woken)
+	}
+
+	later := now.Add(dispatchGrace + time.Second)
+	woken, err := WakeDue(ctx, stores, later)
+	if err != nil || woken != 2 {
+		t.Fatalf("Expected both runs woken, got %d, %v", woken, err)
+	}
+
+	run, _ := GetRun(ctx, stores, "run:lost")
+	if !run.Steps["a"].Dispatched || run.Steps["a"].Status != StepQueued {
+		t.Errorf("Expected a dispatched and still queued, got %+v", run.Steps["a"])
+	}
+	if exec, _ := stores.Executions.GetByID(ctx, run.Steps["a"].ExecutionID); exec == nil || exec.WorkflowStepID != "a" {
+		t.Errorf("Expected the lost execution to be created, got %+v", exec)
+	}
+	if run, _ := GetRun(ctx, stores, "run:slow"); !run.Steps["b"].Dispatched {
+		t.Errorf("Expected b confirmed as dispatched, got %+v", run.Steps["b"])
+	}
+
+	if woken, _ := WakeDue(ctx, stores, later); woken != 0 {
+		t.Errorf("Expected confirmed runs not to be woken again, got %d", woken)
+	}
+}
+
+func TestEngine_WakeDueRecordsMissedResults(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.NewStores()
+	stores.Helpers.Create(ctx, &types.Helper{HelperID: "helper:a", AccountID: "account:1", Enabled: true})
+
+	// Dispatch runs whose executions finish without the worker reporting
+	// their results
+	now := time.Now().UTC()
+	start := func(runID string, steps ...Step) *Run {
+		run := NewRun(runID, Definition{Steps: steps}, now)
+		run.AccountID = "account:1"
+		dispatch := run.Advance(now)
+		assignExecutionIDs(run, dispatch)
+		if err := saveRun(ctx, stores, run); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		dispatchSteps(ctx, stores, run, dispatch)
+		return run
+	}
+	cancelled := start("run:cancelled", Step{ID: "a", HelperID: "helper:a"})
+	execution.Cancel(ctx, stores.Executions, "account:1", cancelled.Steps["a"].ExecutionID, "cancelled by user", now)
+
+	succeeded := start("run:succeeded",
+		Step{ID: "a", HelperID: "helper:a"},
+		Step{ID: "b", HelperID: "helper:a", DependsOn: []string{"a"}},
+	)
+	execID := succeeded.Steps["a"].ExecutionID
+	for _, status := range []string{execution.StatusDispatched, execution.StatusRunning, execution.StatusSucceeded} {
+		if err := execution.Transition(ctx, stores.Executions, execID, status, "", now); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	stores.Executions.RecordOutcome(ctx, execID, database.ExecutionOutcome{
+		CompletedAt: now,
+		Output:      map[string]interface{}{"success": true, "modified_data": map[string]interface{}{"total": 3}},
+	})
+
+	if woken, err := WakeDue(ctx, stores, now.Add(dispatchGrace+time.Second)); err != nil || woken != 2 {
+		t.Fatalf("Expected both runs woken, got %d, %v", woken, err)
+	}
+
+	run, _ := GetRun(ctx, stores, "run:cancelled")
+	if run.Status != RunFailed || run.Steps["a"].Status != StepFailed || run.Steps["a"].Error != "execution cancelled" {
+		t.Errorf("Expected the cancelled execution to fail the run, got run %s and step %+v", run.Status, run.Steps["a"])
+	}
+
+	run, _ = GetRun(ctx, stores, "run:succeeded")
+	if run.Steps["a"].Status != StepCompleted || run.Steps["a"].Output["total"] != float64(3) {
+		t.Errorf("Expected a completed with its output, got %+v", run.Steps["a"])
+	}
+	if run.Steps["b"].Status != StepQueued {
+		t.Errorf("Expected b dispatched once a's result was recorded, got %s", run.Steps["b"].Status)
+	}
+}
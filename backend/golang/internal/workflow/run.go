@@ -0,0 +1,391 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Run statuses
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+)
+
+// Step statuses. Pending steps wait on their dependencies, waiting steps on
+// their wait_seconds timer; queued steps have an execution in flight.
+const (
+	StepPending   = "pending"
+	StepWaiting   = "waiting"
+	StepQueued    = "queued"
+	StepCompleted = "completed"
+	StepFailed    = "failed"
+	StepSkipped   = "skipped"
+	StepCancelled = "cancelled"
+)
+
+// dispatchGrace is how long a queued step's execution has to be created
+// before the waker checks for it and dispatches the step again
+const dispatchGrace = 5 * time.Minute
+
+// ErrInvalidTransition is returned when a run cannot be cancelled or resumed
+// from its current status
+var ErrInvalidTransition = errors.New("invalid workflow run transition")
+
+// Run is the persisted state of one workflow execution
+type Run struct {
+	RunID             string                 `json:"run_id" dynamodbav:"run_id"`
+	AccountID         string                 `json:"account_id" dynamodbav:"account_id"`
+	UserID            string                 `json:"user_id" dynamodbav:"user_id"`
+	ContactID         string                 `json:"contact_id,omitempty" dynamodbav:"contact_id,omitempty"`
+	HelperID          string                 `json:"helper_id,omitempty" dynamodbav:"helper_id,omitempty"`
+	ParentExecutionID string                 `json:"parent_execution_id,omitempty" dynamodbav:"parent_execution_id,omitempty"`
+	Status            string                 `json:"status" dynamodbav:"status"`
+	Definition        Definition             `json:"definition" dynamodbav:"definition"`
+	Input             map[string]interface{} `json:"input,omitempty" dynamodbav:"input,omitempty"`
+	Steps             map[string]*StepState  `json:"steps" dynamodbav:"steps"`
+	NextWakeAt        string                 `json:"next_wake_at,omitempty" dynamodbav:"next_wake_at,omitempty"`
+	CreatedAt         string                 `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt         string                 `json:"updated_at" dynamodbav:"updated_at"`
+	CompletedAt       string                 `json:"completed_at,omitempty" dynamodbav:"completed_at,omitempty"`
+	Version           int                    `json:"version" dynamodbav:"version"`
+}
+
+// StepState tracks one step of a run
+type StepState struct {
+	Status      string                 `json:"status" dynamodbav:"status"`
+	ExecutionID string                 `json:"execution_id,omitempty" dynamodbav:"execution_id,omitempty"`
+	Output      map[string]interface{} `json:"output,omitempty" dynamodbav:"output,omitempty"`
+	Error       string                 `json:"error,omitempty" dynamodbav:"error,omitempty"`
+	Reason      string                 `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+	ReadyAt     string                 `json:"ready_at,omitempty" dynamodbav:"ready_at,omitempty"`
+	StartedAt   string                 `json:"started_at,omitempty" dynamodbav:"started_at,omitempty"`
+	CompletedAt string                 `json:"completed_at,omitempty" dynamodbav:"completed_at,omitempty"`
+	// Dispatched is set once the waker has found the step's execution
+	Dispatched bool `json:"dispatched,omitempty" dynamodbav:"dispatched,omitempty"`
+}
+
+// NewRun creates a run with every step pending
+func NewRun(runID string, def Definition, now time.Time) *Run {
+	run := &Run{
+		RunID:      runID,
+		Status:     RunRunning,
+		Definition: def,
+		Steps:      make(map[string]*StepState, len(def.Steps)),
+		CreatedAt:  now.UTC().Format(time.RFC3339),
+		UpdatedAt:  now.UTC().Format(time.RFC3339),
+	}
+	for _, step := range def.Steps {
+		run.Steps[step.ID] = &StepState{Status: StepPending}
+	}
+	return run
+}
+
+// IsTerminal reports whether the run will not advance any further
+func (r *Run) IsTerminal() bool {
+	return r.Status == RunCompleted || r.Status == RunFailed || r.Status == RunCancelled
+}
+
+// Advance settles every step whose dependencies have finished and returns the
+// IDs of steps that must be dispatched now; those are marked queued. Callers
+// persist the run before dispatching.
+func (r *Run) Advance(now time.Time) []string {
+	if r.Status != RunRunning {
+		return nil
+	}
+
+	order, err := r.Definition.order()
+	if err != nil {
+		return nil
+	}
+	timestamp := now.UTC().Format(time.RFC3339)
+
+	var dispatch []string
+	// A skip can unblock (and skip) later steps, so iterate to a fixed point
+	for changed := true; changed; {
+		changed = false
+		for _, id := range order {
+			step, _ := r.Definition.Step(id)
+			state := r.Steps[id]
+
+			if state.Status == StepPending {
+				ready, run, reason := r.dependenciesAllow(step)
+				if !ready {
+					continue
+				}
+				changed = true
+				if run && step.Condition != nil && !r.evaluate(*step.Condition) {
+					run, reason = false, "condition not met"
+				}
+				if !run {
+					state.Status = StepSkipped
+					state.Reason = reason
+					state.CompletedAt = timestamp
+					continue
+				}
+				state.Status = StepWaiting
+				state.ReadyAt = now.Add(time.Duration(step.WaitSeconds) * time.Second).UTC().Format(time.RFC3339)
+			}
+
+			if state.Status == StepWaiting && state.ReadyAt <= timestamp {
+				state.Status = StepQueued
+				state.StartedAt = timestamp
+				dispatch = append(dispatch, id)
+			}
+		}
+	}
+
+	r.settle(now)
+	return dispatch
+}
+
+// CompleteStep records the outcome of a step's execution. Results for steps
+// that are not in flight (e.g. a redelivered message) are ignored.
+func (r *Run) CompleteStep(stepID string, success bool, output map[string]interface{}, errMsg string, now time.Time) error {
+	state, ok := r.Steps[stepID]
+	if !ok {
+		return fmt.Errorf("unknown step %q", stepID)
+	}
+	if state.Status != StepQueued {
+		return nil
+	}
+
+	state.Status = StepCompleted
+	if !success {
+		state.Status = StepFailed
+	}
+	state.Output = output
+	state.Error = errMsg
+	state.CompletedAt = now.UTC().Format(time.RFC3339)
+	r.UpdatedAt = state.CompletedAt
+	return nil
+}
+
+// Cancel stops the run. Steps not yet dispatched are cancelled; executions
+// already in flight finish but nothing runs after them.
+func (r *Run) Cancel(now time.Time) error {
+	if r.IsTerminal() {
+		return fmt.Errorf("%w: run is already %s", ErrInvalidTransition, r.Status)
+	}
+	timestamp := now.UTC().Format(time.RFC3339)
+	for _, state := range r.Steps {
+		if state.Status == StepPending || state.Status == StepWaiting {
+			state.Status = StepCancelled
+			state.ReadyAt = ""
+			state.CompletedAt = timestamp
+		}
+	}
+	r.Status = RunCancelled
+	r.NextWakeAt = ""
+	r.UpdatedAt = timestamp
+	r.CompletedAt = timestamp
+	return nil
+}
+
+// Resume reopens a failed or cancelled run. Failed, skipped and cancelled
+// steps go back to pending and are re-evaluated; completed steps keep their
+// outputs. Call Advance afterwards to dispatch.
+func (r *Run) Resume(now time.Time) error {
+	if r.Status != RunFailed && r.Status != RunCancelled {
+		return fmt.Errorf("%w: only failed or cancelled runs can be resumed, run is %s", ErrInvalidTransition, r.Status)
+	}
+	for _, state := range r.Steps {
+		switch state.Status {
+		case StepFailed, StepSkipped, StepCancelled:
+			*state = StepState{Status: StepPending}
+		}
+	}
+	r.Status = RunRunning
+	r.CompletedAt = ""
+	r.UpdatedAt = now.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// dependenciesAllow reports whether all dependencies have settled and, if so,
+// whether the step's when clause lets it run
+func (r *Run) dependenciesAllow(step Step) (ready bool, run bool, reason string) {
+	anyFailed := false
+	allSucceeded := true
+	for _, dep := range step.DependsOn {
+		switch r.Steps[dep].Status {
+		case StepCompleted:
+		case StepFailed:
+			anyFailed = true
+			allSucceeded = false
+		case StepSkipped, StepCancelled:
+			allSucceeded = false
+		default:
+			return false, false, ""
+		}
+	}
+
+	switch step.When {
+	case WhenFailure:
+		if !anyFailed {
+			return true, false, "no dependency failed"
+		}
+	case WhenAlways:
+	default:
+		if !allSucceeded {
+			return true, false, "dependency did not complete"
+		}
+	}
+	return true, true, ""
+}
+
+// settle derives the run status and next wake time from the step states
+func (r *Run) settle(now time.Time) {
+	r.UpdatedAt = now.UTC().Format(time.RFC3339)
+	r.NextWakeAt = ""
+
+	done := true
+	failed := false
+	for _, state := range r.Steps {
+		switch state.Status {
+		case StepCompleted, StepSkipped, StepCancelled:
+		case StepFailed:
+			failed = true
+		case StepWaiting:
+			done = false
+			r.wakeBy(state.ReadyAt)
+		case StepQueued:
+			done = false
+			if !state.Dispatched {
+				r.wakeBy(dispatchCheckAt(state))
+			}
+		default:
+			done = false
+		}
+	}
+
+	if !done {
+		return
+	}
+	r.Status = RunCompleted
+	if failed {
+		r.Status = RunFailed
+	}
+	r.CompletedAt = r.UpdatedAt
+}
+
+// wakeBy moves the next wake time up to at
+func (r *Run) wakeBy(at string) {
+	if r.NextWakeAt == "" || at < r.NextWakeAt {
+		r.NextWakeAt = at
+	}
+}
+
+// UnconfirmedDispatches returns the queued steps whose executions have not
+// been found yet and were queued at least dispatchGrace before now. Their
+// dispatch may have been lost to a crash between saving the run and
+// creating the execution.
+func (r *Run) UnconfirmedDispatches(now time.Time) []string {
+	timestamp := now.UTC().Format(time.RFC3339)
+	var stepIDs []string
+	for id, state := range r.Steps {
+		if state.Status == StepQueued && !state.Dispatched && dispatchCheckAt(state) <= timestamp {
+			stepIDs = append(stepIDs, id)
+		}
+	}
+	sort.Strings(stepIDs)
+	return stepIDs
+}
+
+// dispatchCheckAt is when a queued step's execution should exist by
+func dispatchCheckAt(state *StepState) string {
+	startedAt, err := time.Parse(time.RFC3339, state.StartedAt)
+	if err != nil {
+		return state.StartedAt
+	}
+	return startedAt.Add(dispatchGrace).UTC().Format(time.RFC3339)
+}
+
+// ResolveInputs builds the input for a step: the run's trigger input with the
+// step's mapped references merged over it. Unresolvable references are left out.
+func (r *Run) ResolveInputs(step Step) map[string]interface{} {
+	input := make(map[string]interface{}, len(r.Input)+len(step.Inputs))
+	for key, value := range r.Input {
+		input[key] = value
+	}
+	for key, ref := range step.Inputs {
+		if value, ok := r.lookup(ref); ok {
+			input[key] = value
+		}
+	}
+	return input
+}
+
+// lookup resolves an input.<key>, steps.<id>.status or steps.<id>.output.<path> reference
+func (r *Run) lookup(ref string) (interface{}, bool) {
+	parts := strings.Split(ref, ".")
+	var current interface{}
+	switch {
+	case len(parts) >= 2 && parts[0] == "input":
+		current, parts = r.Input, parts[1:]
+	case len(parts) == 3 && parts[0] == "steps" && parts[2] == "status":
+		state, ok := r.Steps[parts[1]]
+		if !ok {
+			return nil, false
+		}
+		return state.Status, true
+	case len(parts) >= 4 && parts[0] == "steps" && parts[2] == "output":
+		state, ok := r.Steps[parts[1]]
+		if !ok {
+			return nil, false
+		}
+		current, parts = state.Output, parts[3:]
+	default:
+		return nil, false
+	}
+
+	for _, key := range parts {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// evaluate checks a step condition against the run
+func (r *Run) evaluate(cond Condition) bool {
+	value, found := r.lookup(cond.Path)
+	actual := ""
+	if found && value != nil {
+		actual = fmt.Sprintf("%v", value)
+	}
+
+	switch cond.Operator {
+	case OpExists:
+		return found && actual != ""
+	case OpNotExists:
+		return !found || actual == ""
+	case OpEquals:
+		return actual == cond.Value
+	case OpNotEquals:
+		return actual != cond.Value
+	case OpContains:
+		return strings.Contains(actual, cond.Value)
+	case OpNotContains:
+		return !strings.Contains(actual, cond.Value)
+	case OpGreaterThan, OpLessThan:
+		a, errA := strconv.ParseFloat(actual, 64)
+		b, errB := strconv.ParseFloat(cond.Value, 64)
+		if errA != nil || errB != nil {
+			return false
+		}
+		if cond.Operator == OpGreaterThan {
+			return a > b
+		}
+		return a < b
+	}
+	return false
+}
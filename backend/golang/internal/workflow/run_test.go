@@ -0,0 +1,222 @@
+package workflow
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func newTestRun(steps ...Step) *Run {
+	return NewRun("run:test", Definition{Steps: steps}, time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+}
+
+func advance(run *Run, now time.Time) []string {
+	dispatch := run.Advance(now)
+	sort.Strings(dispatch)
+	return dispatch
+}
+
+func TestRunAdvance_Linear(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := newTestRun(
+		Step{ID: "a", HelperID: "h1"},
+		Step{ID: "b", HelperID: "h2", DependsOn: []string{"a"}},
+	)
+
+	if got := advance(run, now); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("Expected [a] dispatched, got %v", got)
+	}
+	if got := advance(run, now); len(got) != 0 {
+		t.Fatalf("Expected nothing dispatched while a is in flight, got %v", got)
+	}
+
+	run.CompleteStep("a", true, map[string]interface{}{"total": 3}, "", now)
+	if got := advance(run, now); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("Expected [b] dispatched, got %v", got)
+	}
+
+	run.CompleteStep("b", true, nil, "", now)
+	advance(run, now)
+	if run.Status != RunCompleted {
+		t.Errorf("Expected run completed, got %s", run.Status)
+	}
+}
+
+func TestRunAdvance_Branches(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := newTestRun(
+		Step{ID: "a", HelperID: "h1"},
+		Step{ID: "ok", HelperID: "h2", DependsOn: []string{"a"}},
+		Step{ID: "after_ok", HelperID: "h3", DependsOn: []string{"ok"}},
+		Step{ID: "on_fail", HelperID: "h4", DependsOn: []string{"a"}, When: WhenFailure},
+		Step{ID: "finally", HelperID: "h5", DependsOn: []string{"ok", "on_fail"}, When: WhenAlways},
+	)
+
+	advance(run, now)
+	run.CompleteStep("a", false, nil, "boom", now)
+	if got := advance(run, now); !reflect.DeepEqual(got, []string{"on_fail"}) {
+		t.Fatalf("Expected [on_fail] dispatched, got %v", got)
+	}
+	if run.Steps["ok"].Status != StepSkipped || run.Steps["after_ok"].Status != StepSkipped {
+		t.Errorf("Expected success branch skipped, got ok=%s after_ok=%s", run.Steps["ok"].Status, run.Steps["after_ok"].Status)
+	}
+
+	run.CompleteStep("on_fail", true, nil, "", now)
+	if got := advance(run, now); !reflect.DeepEqual(got, []string{"finally"}) {
+		t.Fatalf("Expected [finally] dispatched, got %v", got)
+	}
+
+	run.CompleteStep("finally", true, nil, "", now)
+	advance(run, now)
+	if run.Status != RunFailed {
+		t.Errorf("Expected run failed, got %s", run.Status)
+	}
+}
+
+func TestRunAdvance_ConditionAndInputs(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := newTestRun(
+		Step{ID: "score", HelperID: "h1"},
+		Step{
+			ID:        "hot",
+			HelperID:  "h2",
+			DependsOn: []string{"score"},
+			Condition: &Condition{Path: "steps.score.output.score", Operator: OpGreaterThan, Value: "80"},
+			Inputs:    map[string]string{"score": "steps.score.output.score", "list": "input.list"},
+		},
+		Step{
+			ID:        "cold",
+			HelperID:  "h3",
+			DependsOn: []string{"score"},
+			Condition: &Condition{Path: "steps.score.output.score", Operator: OpLessThan, Value: "80"},
+		},
+	)
+	run.Input = map[string]interface{}{"list": "vip", "source": "form"}
+
+	advance(run, now)
+	run.CompleteStep("score", true, map[string]interface{}{"score": float64(92)}, "", now)
+	if got := advance(run, now); !reflect.DeepEqual(got, []string{"hot"}) {
+		t.Fatalf("Expected [hot] dispatched, got %v", got)
+	}
+	if run.Steps["cold"].Status != StepSkipped || run.Steps["cold"].Reason != "condition not met" {
+		t.Errorf("Expected cold skipped on condition, got %+v", run.Steps["cold"])
+	}
+
+	step, _ := run.Definition.Step("hot")
+	input := run.ResolveInputs(step)
+	expected := map[string]interface{}{"score": float64(92), "list": "vip", "source": "form"}
+	if !reflect.DeepEqual(input, expected) {
+		t.Errorf("Expected input %v, got %v", expected, input)
+	}
+}
+
+func TestRunAdvance_Wait(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := newTestRun(
+		Step{ID: "a", HelperID: "h1"},
+		Step{ID: "b", HelperID: "h2", DependsOn: []string{"a"}, WaitSeconds: 3600},
+	)
+
+	advance(run, start)
+	run.CompleteStep("a", true, nil, "", start)
+	if got := advance(run, start); len(got) != 0 {
+		t.Fatalf("Expected nothing dispatched before the wait, got %v", got)
+	}
+	if run.Steps["b"].Status != StepWaiting || run.NextWakeAt != "2025-01-01T13:00:00Z" {
+		t.Fatalf("Expected b waiting until 13:00, got %s (next wake %s)", run.Steps["b"].Status, run.NextWakeAt)
+	}
+
+	if got := advance(run, start.Add(30*time.Minute)); len(got) != 0 {
+		t.Fatalf("Expected nothing dispatched mid-wait, got %v", got)
+	}
+	if got := advance(run, start.Add(time.Hour)); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("Expected [b] dispatched after the wait, got %v", got)
+	}
+	if run.NextWakeAt != "2025-01-01T13:05:00Z" {
+		t.Errorf("Expected the next wake to check b's dispatch at 13:05, got %s", run.NextWakeAt)
+	}
+}
+
+func TestRunCancelAndResume(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := newTestRun(
+		Step{ID: "a", HelperID: "h1"},
+		Step{ID: "b", HelperID: "h2", DependsOn: []string{"a"}},
+	)
+	advance(run, now)
+
+	if err := run.Resume(now); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected invalid transition resuming a running run, got %v", err)
+	}
+	if err := run.Cancel(now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if run.Status != RunCancelled || run.Steps["b"].Status != StepCancelled {
+		t.Fatalf("Expected run and pending step cancelled, got %s / %s", run.Status, run.Steps["b"].Status)
+	}
+
+	// The in-flight step still reports, but nothing advances
+	run.CompleteStep("a", true, nil, "", now)
+	if got := advance(run, now); len(got) != 0 {
+		t.Fatalf("Expected nothing dispatched on a cancelled run, got %v", got)
+	}
+
+	if err := run.Resume(now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got := advance(run, now); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("Expected [b] dispatched after resume, got %v", got)
+	}
+	if run.Steps["a"].Status != StepCompleted {
+		t.Errorf("Expected completed step kept, got %s", run.Steps["a"].Status)
+	}
+	if err := run.Cancel(now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := run.Cancel(now); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected invalid transition cancelling twice, got %v", err)
+	}
+}
+
+func TestRunCompleteStep_IgnoresDuplicates(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := newTestRun(Step{ID: "a", HelperID: "h1"})
+	advance(run, now)
+
+	run.CompleteStep("a", true, map[string]interface{}{"n": 1}, "", now)
+	run.CompleteStep("a", false, nil, "late duplicate", now)
+	if run.Steps["a"].Status != StepCompleted || run.Steps["a"].Error != "" {
+		t.Errorf("Expected first result kept, got %+v", run.Steps["a"])
+	}
+	if err := run.CompleteStep("missing", true, nil, "", now); err == nil {
+		t.Error("Expected error for unknown step")
+	}
+}
+
+func TestRunUnconfirmedDispatches(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := newTestRun(
+		Step{ID: "a", HelperID: "h1"},
+		Step{ID: "b", HelperID: "h2", WaitSeconds: 3600},
+	)
+	advance(run, now)
+
+	checkAt := now.Add(dispatchGrace).Format(time.RFC3339)
+	if run.NextWakeAt != checkAt {
+		t.Errorf("Expected the run to wake at %s to check a's dispatch, got %s", checkAt, run.NextWakeAt)
+	}
+	if got := run.UnconfirmedDispatches(now.Add(time.Minute)); len(got) != 0 {
+		t.Errorf("Expected no unconfirmed dispatches within the grace period, got %v", got)
+	}
+	if got := run.UnconfirmedDispatches(now.Add(dispatchGrace)); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("Expected [a] unconfirmed after the grace period, got %v", got)
+	}
+
+	run.Steps["a"].Dispatched = true
+	advance(run, now.Add(dispatchGrace))
+	if want := now.Add(time.Hour).Format(time.RFC3339); run.NextWakeAt != want {
+		t.Errorf("Expected the run to wake for b at %s once a is confirmed, got %s", want, run.NextWakeAt)
+	}
+}
@@ -2,25 +2,30 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/myfusionhelper/api/internal/database"
+	"github.com/myfusionhelper/api/internal/execution"
+	"github.com/myfusionhelper/api/internal/worker"
 )
 
 var (
-	sqsClient *sqs.Client
-	stage     string
-	region    string
-	accountID string
+	sqsClient  *sqs.Client
+	executions database.ExecutionStore
+	stage      string
+	region     string
+	accountID  string
 )
 
 func init() {
@@ -29,6 +34,7 @@ func init() {
 		log.Fatalf("Failed to load AWS config: %v", err)
 	}
 	sqsClient = sqs.NewFromConfig(cfg)
+	executions = database.NewExecutionsRepository(dynamodb.NewFromConfig(cfg), os.Getenv("EXECUTIONS_TABLE"))
 
 	stage = os.Getenv("STAGE")
 	if stage == "" {
@@ -43,8 +49,15 @@ func init() {
 
 func Handle(ctx context.Context, event events.DynamoDBEvent) error {
 	for _, record := range event.Records {
-		// Only process INSERT events (new executions)
-		if record.EventName != "INSERT" {
+		// Route new executions, and executions queued again, like those
+		// drained from a paused connection
+		switch record.EventName {
+		case "INSERT":
+		case "MODIFY":
+			if record.Change.NewImage["status"].String() != execution.StatusQueued {
+				continue
+			}
+		default:
 			continue
 		}
 
@@ -74,18 +87,28 @@ func Handle(ctx context.Context, event events.DynamoDBEvent) error {
 		// Convert the full DynamoDB Stream record to JSON for the worker.
 		// The execution record contains all data frozen at execution time
 		// (helper_type, connection_id, config, input, query_params, etc.)
-		messageBody, err := streamImageToJSON(record.Change.NewImage)
+		messageBody, err := worker.StreamImageToJSON(record.Change.NewImage)
 		if err != nil {
 			log.Printf("ERROR: Failed to convert stream image to JSON for execution %s: %v", executionID, err)
 			return err
 		}
 
-		// Use first 8 chars of execution_id as MessageGroupId for FIFO queue ordering
-		groupID := executionID
-		if len(groupID) > 8 {
-			groupID = groupID[:8]
+		// Mark the execution dispatched before sending it. One that is no
+		// longer queued was cancelled, or sent by an earlier delivery of
+		// this record.
+		dispatched, err := execution.Dispatch(ctx, executions, executionID, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Failed to mark execution %s dispatched: %v", executionID, err)
+			return err
+		}
+		if !dispatched {
+			log.Printf("Execution %s is no longer queued, not routing it", executionID)
+			continue
 		}
 
+		// Group by account and contact, so a contact's executions run in order
+		groupID := worker.MessageGroupID(record.Change.NewImage)
+
 		_, err = sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
 			QueueUrl:       aws.String(queueURL),
 			MessageBody:    aws.String(messageBody),
@@ -94,6 +117,10 @@ func Handle(ctx context.Context, event events.DynamoDBEvent) error {
 
 		if err != nil {
 			log.Printf("ERROR: Failed to send message to queue %s for execution %s: %v", queueURL, executionID, err)
+			// Put it back so the retried stream batch dispatches it again
+			if requeueErr := execution.Transition(ctx, executions, executionID, execution.StatusQueued, "queue send failed", time.Now()); requeueErr != nil {
+				log.Printf("ERROR: Failed to return execution %s to queued: %v", executionID, requeueErr)
+			}
 			return err
 		}
 
@@ -118,60 +145,6 @@ func buildQueueURL(helperType string) string {
 		region, accountID, stage, queueName)
 }
 
-// streamImageToJSON converts a DynamoDB Stream NewImage to a JSON string.
-// This allows the full execution record to be forwarded to the worker via SQS.
-func streamImageToJSON(image map[string]events.DynamoDBAttributeValue) (string, error) {
-	m := convertStreamImage(image)
-	bytes, err := json.Marshal(m)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal stream image: %w", err)
-	}
-	return string(bytes), nil
-}
-
-func convertStreamImage(image map[string]events.DynamoDBAttributeValue) map[string]interface{} {
-	result := make(map[string]interface{}, len(image))
-	for k, v := range image {
-		result[k] = convertStreamValue(v)
-	}
-	return result
-}
-
-func convertStreamValue(v events.DynamoDBAttributeValue) interface{} {
-	switch v.DataType() {
-	case events.DataTypeString:
-		return v.String()
-	case events.DataTypeNumber:
-		n := v.Number()
-		if i, err := strconv.ParseInt(n, 10, 64); err == nil {
-			return i
-		}
-		if f, err := strconv.ParseFloat(n, 64); err == nil {
-			return f
-		}
-		return n
-	case events.DataTypeBoolean:
-		return v.Boolean()
-	case events.DataTypeMap:
-		return convertStreamImage(v.Map())
-	case events.DataTypeList:
-		list := v.List()
-		result := make([]interface{}, len(list))
-		for i, item := range list {
-			result[i] = convertStreamValue(item)
-		}
-		return result
-	case events.DataTypeStringSet:
-		return v.StringSet()
-	case events.DataTypeNumberSet:
-		return v.NumberSet()
-	case events.DataTypeNull:
-		return nil
-	default:
-		return nil
-	}
-}
-
 func main() {
 	lambda.Start(Handle)
 }
@@ -151,7 +151,7 @@ export interface HelperExecution {
   apiKeyId?: string
   connectionId?: string
   contactId?: string
-  status: 'pending' | 'running' | 'completed' | 'failed'
+  status: 'delayed' | 'queued' | 'dispatched' | 'running' | 'retrying' | 'paused' | 'succeeded' | 'failed' | 'dead_lettered' | 'cancelled' | 'skipped'
   triggerType: string
   input?: Record<string, unknown>
   output?: Record<string, unknown>